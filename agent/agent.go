@@ -2,23 +2,40 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/types"
 )
 
-// Agent defines the interface for content processing agents.
-// Agents can perform various transformations on content such as
-// summarization, translation, formatting, or content generation.
-type Agent interface {
-	// Process takes content and returns processed markdown
-	Process(ctx context.Context, content string) (string, error)
+// Re-export types for convenience
+type Agent = types.Agent
+type Options = types.Options
 
-	// Name returns the agent identifier (e.g., "summary")
-	Name() string
-}
+// KeyPoints is the agent condensing content into 3-5 bullet key takeaways
+// instead of the prose of the summary agent
+const KeyPoints = "keypoints"
+
+// Relevance is the agent scoring content against the interests of the reader
+// for min_score. It runs on the content instead of being chained.
+const Relevance = "relevance"
+
+// Discussion is the agent summarizing comment threads. It runs on the comments
+// of an item instead of being chained after the other agents.
+const Discussion = "discussion"
+
+// Describe is the agent appending a description of the photos of an item to
+// its content, so picture-only posts of Telegram channels get text
+const Describe = "describe"
 
 // RetryConfig defines retry behavior for agent operations
 type RetryConfig struct {
@@ -55,7 +72,7 @@ func (r *retryAgent) Name() string {
 	return r.underlying.Name()
 }
 
-func (r *retryAgent) Process(ctx context.Context, content string) (string, error) {
+func (r *retryAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
 	// Create a context with overall timeout
 	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
 	defer cancel()
@@ -72,7 +89,7 @@ func (r *retryAgent) Process(ctx context.Context, content string) (string, error
 		}
 
 		// Try processing
-		result, err := r.underlying.Process(ctx, content)
+		result, err := r.underlying.Process(ctx, content, opts)
 		if err == nil {
 			if attempt > 0 {
 				slog.Info("agent succeeded after retries",
@@ -127,12 +144,66 @@ func (r *retryAgent) Process(ctx context.Context, content string) (string, error
 	return "", fmt.Errorf("max retries (%d) exceeded: %w", r.config.MaxRetries, lastErr)
 }
 
+// WithFallback chains agents answering through the providers of the
+// backends, each one answers once the previous one failed for good, e.g.,
+// its quota is exhausted for the day
+func WithFallback(agents []Agent, backends []provider.Backend) Agent {
+	if len(agents) == 1 {
+		return agents[0]
+	}
+	return &fallbackAgent{agents: agents, backends: backends}
+}
+
+type fallbackAgent struct {
+	agents   []Agent
+	backends []provider.Backend
+}
+
+func (f *fallbackAgent) Name() string {
+	return f.agents[0].Name()
+}
+
+func (f *fallbackAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	var err error
+	for i, a := range f.agents {
+		if i > 0 {
+			slog.Warn("agent provider failed, falling back",
+				"agent", f.Name(),
+				"failed", f.backends[i-1].Provider,
+				"provider", f.backends[i].Provider,
+				"error", err)
+		}
+		var result string
+		result, err = a.Process(ctx, content, opts)
+		if err == nil || !canFallBack(ctx, err) {
+			return result, err
+		}
+	}
+	return "", err
+}
+
+// canFallBack reports whether another provider may answer after the error,
+// errors of the request itself, e.g., a blocked prompt, fail the same way
+func canFallBack(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return isRetryable(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
 // isRetryable determines if an error should trigger a retry
 func isRetryable(err error) bool {
 	if err == nil {
 		return false
 	}
 
+	if apiErr, ok := asAPIError(err); ok {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusInternalServerError:
+			return true
+		}
+	}
+
 	errStr := err.Error()
 
 	// Quota and rate limit errors are retryable
@@ -141,8 +212,9 @@ func isRetryable(err error) bool {
 		"quota exceeded",
 		"rate limit",
 		"429",
-		"503", // Service unavailable
-		"500", // Internal server error (sometimes transient)
+		"503",        // Service unavailable
+		"500",        // Internal server error (sometimes transient)
+		"overloaded", // Claude is temporarily over capacity (529)
 	}
 
 	errLower := strings.ToLower(errStr)
@@ -155,12 +227,88 @@ func isRetryable(err error) bool {
 	return false
 }
 
-// extractRetryDelay attempts to extract suggested retry delay from error message
+// RetryAfterError carries the delay asked for by a Retry-After header
+type RetryAfterError struct {
+	Err   error
+	Delay time.Duration
+}
+
+func (e *RetryAfterError) Error() string { return e.Err.Error() }
+func (e *RetryAfterError) Unwrap() error { return e.Err }
+
+// RetryAfter returns the delay to wait before the next attempt
+func (e *RetryAfterError) RetryAfter() time.Duration { return e.Delay }
+
+// ParseRetryAfter parses a Retry-After header value, either delay seconds or
+// an HTTP date, returning 0 when it is missing or in the past
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, at.Sub(now))
+	}
+	return 0
+}
+
+// asAPIError finds a Gemini API error in the chain
+func asAPIError(err error) (genai.APIError, bool) {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	var apiErrPtr *genai.APIError
+	if errors.As(err, &apiErrPtr) && apiErrPtr != nil {
+		return *apiErrPtr, true
+	}
+	return genai.APIError{}, false
+}
+
+// apiRetryDelay reads the RetryInfo detail of a Gemini API error
+func apiRetryDelay(apiErr genai.APIError) time.Duration {
+	for _, detail := range apiErr.Details {
+		kind, _ := detail["@type"].(string)
+		if !strings.HasSuffix(kind, "google.rpc.RetryInfo") {
+			continue
+		}
+		switch delay := detail["retryDelay"].(type) {
+		case string:
+			if d, err := time.ParseDuration(delay); err == nil {
+				return d
+			}
+		case map[string]any:
+			// Duration messages may come as {"seconds": 12, "nanos": 0}
+			seconds, _ := delay["seconds"].(float64)
+			nanos, _ := delay["nanos"].(float64)
+			return time.Duration(seconds)*time.Second + time.Duration(nanos)
+		}
+	}
+	return 0
+}
+
+// extractRetryDelay extracts the suggested retry delay from a Retry-After
+// header, the RetryInfo of a Gemini API error or, failing those, the message
 func extractRetryDelay(err error) time.Duration {
 	if err == nil {
 		return 0
 	}
 
+	var retryAfter interface{ RetryAfter() time.Duration }
+	if errors.As(err, &retryAfter) {
+		if d := retryAfter.RetryAfter(); d > 0 {
+			return d
+		}
+	}
+	if apiErr, ok := asAPIError(err); ok {
+		if d := apiRetryDelay(apiErr); d > 0 {
+			return d
+		}
+	}
+
 	errStr := err.Error()
 
 	// Look for "retry in X.Xs" or "retryDelay:Xs" patterns
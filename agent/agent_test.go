@@ -7,6 +7,10 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"google.golang.org/genai"
+
+	"github.com/scipunch/myfeed/agent/provider"
 )
 
 // mockAgent is a test agent that can be configured to fail
@@ -21,7 +25,7 @@ func (m *mockAgent) Name() string {
 	return m.name
 }
 
-func (m *mockAgent) Process(ctx context.Context, content string) (string, error) {
+func (m *mockAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
 	if m.processDelay > 0 {
 		time.Sleep(m.processDelay)
 	}
@@ -45,7 +49,7 @@ func TestWithRetry_Success(t *testing.T) {
 
 	agent := WithRetry(mock, config)
 
-	result, err := agent.Process(context.Background(), "test content")
+	result, err := agent.Process(context.Background(), "test content", Options{})
 	if err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
@@ -67,7 +71,7 @@ func TestWithRetry_SuccessAfterRetries(t *testing.T) {
 	agent := WithRetry(mock, config)
 
 	start := time.Now()
-	result, err := agent.Process(context.Background(), "test content")
+	result, err := agent.Process(context.Background(), "test content", Options{})
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -99,7 +103,7 @@ func TestWithRetry_ExceedsMaxRetries(t *testing.T) {
 
 	agent := WithRetry(mock, config)
 
-	_, err := agent.Process(context.Background(), "test content")
+	_, err := agent.Process(context.Background(), "test content", Options{})
 	if err == nil {
 		t.Fatal("expected error after max retries, got nil")
 	}
@@ -130,7 +134,7 @@ func TestWithRetry_Timeout(t *testing.T) {
 	agent := WithRetry(mock, config)
 
 	start := time.Now()
-	_, err := agent.Process(context.Background(), "test content")
+	_, err := agent.Process(context.Background(), "test content", Options{})
 	elapsed := time.Since(start)
 
 	if err == nil {
@@ -166,7 +170,7 @@ func TestWithRetry_ContextCancellation(t *testing.T) {
 		cancel()
 	}()
 
-	_, err := agent.Process(ctx, "test content")
+	_, err := agent.Process(ctx, "test content", Options{})
 	if err == nil {
 		t.Fatal("expected error after context cancellation, got nil")
 	}
@@ -185,7 +189,7 @@ func (m *mockNonRetryableAgent) Name() string {
 	return m.name
 }
 
-func (m *mockNonRetryableAgent) Process(ctx context.Context, content string) (string, error) {
+func (m *mockNonRetryableAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
 	return "", errors.New("invalid input: malformed content")
 }
 
@@ -200,7 +204,7 @@ func TestWithRetry_NonRetryableError(t *testing.T) {
 
 	agent := WithRetry(mock, config)
 
-	_, err := agent.Process(context.Background(), "test content")
+	_, err := agent.Process(context.Background(), "test content", Options{})
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -210,6 +214,33 @@ func TestWithRetry_NonRetryableError(t *testing.T) {
 	}
 }
 
+func TestWithFallback(t *testing.T) {
+	config := RetryConfig{MaxRetries: 1, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Timeout: time.Second}
+	exhausted := &mockAgent{name: "summary", failCount: 100}
+	local := &mockAgent{name: "summary"}
+	backends := []provider.Backend{{Provider: provider.Gemini}, {Provider: provider.Ollama}}
+
+	agent := WithFallback([]Agent{WithRetry(exhausted, config), WithRetry(local, config)}, backends)
+	result, err := agent.Process(context.Background(), "content", Options{})
+	if err != nil || result != "processed: content" {
+		t.Fatalf("expected the fallback to answer, got %q, %v", result, err)
+	}
+	if exhausted.currentFails != 2 {
+		t.Errorf("expected retries of the first provider exhausted first, got %d attempts", exhausted.currentFails)
+	}
+
+	// Failures of the request itself don't fall back
+	blocked := &mockNonRetryableAgent{name: "summary"}
+	local = &mockAgent{name: "summary", failCount: 1}
+	agent = WithFallback([]Agent{WithRetry(blocked, config), WithRetry(local, config)}, backends)
+	if _, err := agent.Process(context.Background(), "content", Options{}); err == nil {
+		t.Error("expected the non-retryable error")
+	}
+	if local.currentFails != 0 {
+		t.Error("expected no fallback")
+	}
+}
+
 func TestIsRetryable(t *testing.T) {
 	tests := []struct {
 		err       error
@@ -221,6 +252,7 @@ func TestIsRetryable(t *testing.T) {
 		{errors.New("Error 429"), true},
 		{errors.New("Error 503"), true},
 		{errors.New("rate limit exceeded"), true},
+		{errors.New(`529 Overloaded {"type":"overloaded_error"}`), true},
 		{errors.New("invalid input"), false},
 		{errors.New("authentication failed"), false},
 	}
@@ -245,6 +277,18 @@ func TestExtractRetryDelay(t *testing.T) {
 		{errors.New("retryDelay:10s"), 10 * time.Second},
 		{errors.New("no delay info"), 0},
 		{errors.New("retry in 1.5s, then check status"), 1500 * time.Millisecond},
+		{fmt.Errorf("failed to generate contents: %w", genai.APIError{
+			Code:   429,
+			Status: "RESOURCE_EXHAUSTED",
+			Details: []map[string]any{
+				{"@type": "type.googleapis.com/google.rpc.QuotaFailure"},
+				{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "42s"},
+			},
+		}), 42 * time.Second},
+		{&genai.APIError{Code: 429, Details: []map[string]any{
+			{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": map[string]any{"seconds": float64(3), "nanos": float64(5e8)}},
+		}}, 3500 * time.Millisecond},
+		{fmt.Errorf("model call: %w", &RetryAfterError{Err: errors.New("Error 429, retry in 5s"), Delay: 20 * time.Second}), 20 * time.Second},
 	}
 
 	for _, tt := range tests {
@@ -257,6 +301,36 @@ func TestExtractRetryDelay(t *testing.T) {
 	}
 }
 
+func TestIsRetryable_APIError(t *testing.T) {
+	if !isRetryable(fmt.Errorf("generate: %w", genai.APIError{Code: 429, Message: "Too many requests"})) {
+		t.Error("expected a wrapped 429 API error to be retryable")
+	}
+	if isRetryable(genai.APIError{Code: 400, Message: "Invalid argument"}) {
+		t.Error("expected a 400 API error not to be retryable")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 7 ", 7 * time.Second},
+		{"Thu, 01 Jan 2026 12:00:30 GMT", 30 * time.Second},
+		{"Thu, 01 Jan 2026 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.value, now); got != tt.expected {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
 func TestDefaultRetryConfig(t *testing.T) {
 	config := DefaultRetryConfig()
 
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// chunkSeparators split content into chunks, paragraphs first, words last
+var chunkSeparators = []string{"\n\n", "\n", " "}
+
+// WithChunking wraps a condensing agent so content longer than size
+// characters is processed in chunks whose answers are processed together
+// again, e.g., summaries of parts of a multi-hour transcript are summarized
+func WithChunking(agent Agent, size int) Agent {
+	return &chunkingAgent{underlying: agent, size: size}
+}
+
+type chunkingAgent struct {
+	underlying Agent
+	size       int
+}
+
+func (c *chunkingAgent) Name() string {
+	return c.underlying.Name()
+}
+
+func (c *chunkingAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	length := utf8.RuneCountInString(content)
+	if length <= c.size {
+		return c.underlying.Process(ctx, content, opts)
+	}
+
+	chunks := splitChunks(content, c.size, chunkSeparators...)
+	slog.Info("content exceeds the context of the model, processing it in chunks",
+		"agent", c.Name(),
+		"length", length,
+		"chunks", len(chunks))
+
+	// Corrective feedback is about the final answer
+	chunkOpts := opts
+	chunkOpts.Feedback = ""
+	answers := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		answer, err := c.underlying.Process(ctx, chunk, chunkOpts)
+		if err != nil {
+			return "", fmt.Errorf("chunk %d of %d failed: %w", i+1, len(chunks), err)
+		}
+		answers = append(answers, strings.TrimSpace(answer))
+	}
+
+	combined := strings.Join(answers, "\n\n")
+	if utf8.RuneCountInString(combined) >= length {
+		return "", fmt.Errorf("answers to %d chunks are no shorter than the content", len(chunks))
+	}
+	return c.Process(ctx, combined, opts)
+}
+
+// splitChunks splits text into chunks of at most size characters at the
+// first of the separators, parts still too long are split at the next ones
+func splitChunks(text string, size int, separators ...string) []string {
+	if utf8.RuneCountInString(text) <= size {
+		return []string{text}
+	}
+	if len(separators) == 0 {
+		var chunks []string
+		runes := []rune(text)
+		for start := 0; start < len(runes); start += size {
+			chunks = append(chunks, string(runes[start:min(start+size, len(runes))]))
+		}
+		return chunks
+	}
+
+	sep := separators[0]
+	var chunks []string
+	current := ""
+	for _, part := range strings.Split(text, sep) {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		for _, piece := range splitChunks(part, size, separators[1:]...) {
+			if current != "" && utf8.RuneCountInString(current)+len(sep)+utf8.RuneCountInString(piece) <= size {
+				current += sep + piece
+				continue
+			}
+			if current != "" {
+				chunks = append(chunks, current)
+			}
+			current = piece
+		}
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
+
+// isCondensing reports whether answers of the agent to parts of content
+// can be condensed again by the agent, unlike, e.g., a translation
+func isCondensing(name string) bool {
+	switch name {
+	case "summary", KeyPoints, Discussion:
+		return true
+	}
+	return false
+}
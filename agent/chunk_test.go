@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// condensingMockAgent answers with the first word of every paragraph
+type condensingMockAgent struct {
+	inputs []string
+}
+
+func (m *condensingMockAgent) Name() string {
+	return "summary"
+}
+
+func (m *condensingMockAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	m.inputs = append(m.inputs, content)
+	var firsts []string
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		firsts = append(firsts, strings.Fields(paragraph)[0])
+	}
+	return strings.Join(firsts, " "), nil
+}
+
+func TestWithChunking_ShortContent(t *testing.T) {
+	mock := &condensingMockAgent{}
+	agent := WithChunking(mock, 100)
+
+	if _, err := agent.Process(context.Background(), "short content", Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mock.inputs) != 1 || mock.inputs[0] != "short content" {
+		t.Errorf("expected content passed as is, got %q", mock.inputs)
+	}
+}
+
+func TestWithChunking_MapReduce(t *testing.T) {
+	mock := &condensingMockAgent{}
+	agent := WithChunking(mock, 40)
+
+	var paragraphs []string
+	for _, word := range []string{"alpha", "beta", "gamma", "delta"} {
+		paragraphs = append(paragraphs, word+" "+strings.Repeat("x ", 12))
+	}
+	result, err := agent.Process(context.Background(), strings.Join(paragraphs, "\n\n"), Options{Feedback: "shorter"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Every paragraph is a chunk, their answers are condensed once more
+	if len(mock.inputs) != 5 {
+		t.Fatalf("expected 4 chunks and a final call, got %q", mock.inputs)
+	}
+	if mock.inputs[4] != "alpha\n\nbeta\n\ngamma\n\ndelta" || result != "alpha beta gamma delta" {
+		t.Errorf("expected the answer to the joined answers, got %q", result)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	text := "one two three\n\nfour five\nsix seven eight nine ten\n\n" + strings.Repeat("й", 25)
+	chunks := splitChunks(text, 10, chunkSeparators...)
+	for _, chunk := range chunks {
+		if n := utf8.RuneCountInString(chunk); n > 10 {
+			t.Errorf("chunk %q has %d characters, expected at most 10", chunk, n)
+		}
+	}
+	if chunks[0] != "one two" || chunks[1] != "three" {
+		t.Errorf("expected words packed into chunks, got %q", chunks)
+	}
+	if got := strings.Join(chunks, ""); strings.Count(got, "й") != 25 {
+		t.Errorf("expected no characters lost, got %q", chunks)
+	}
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/scipunch/myfeed/agent/quality"
+	"github.com/scipunch/myfeed/config"
+)
+
+var (
+	// bulletLine matches top-level list items, e.g., "- item" or "2. item"
+	bulletLine = regexp.MustCompile(`^([-*+]|\d+[.)])\s+\S`)
+
+	// htmlTag matches an HTML tag, e.g., "<b>" or "</p>"
+	htmlTag = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9-]*(\s[^<>]*)?/?>`)
+
+	// inlineCode matches code spans, which may show tags
+	inlineCode = regexp.MustCompile("`[^`\n]*`")
+)
+
+// WithContract wraps an agent so that answers are checked against the
+// contract. Breaking answers are re-prompted with what was wrong.
+func WithContract(agent Agent, contract config.Contract) Agent {
+	return &contractAgent{underlying: agent, contract: contract}
+}
+
+type contractAgent struct {
+	underlying Agent
+	contract   config.Contract
+}
+
+func (c *contractAgent) Name() string {
+	return c.underlying.Name()
+}
+
+func (c *contractAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	feedback := opts.Feedback
+	for attempt := 0; ; attempt++ {
+		result, err := c.underlying.Process(ctx, content, opts)
+		if err != nil {
+			return "", err
+		}
+		violations := Violations(c.contract, result)
+		if len(violations) == 0 {
+			return result, nil
+		}
+		if attempt == 0 {
+			quality.ContractViolation(c.Name())
+		}
+		if attempt == c.contract.MaxRetries() {
+			if c.contract.Strict {
+				return "", fmt.Errorf("agent '%s' broke its output contract: %s", c.Name(), strings.Join(violations, "; "))
+			}
+			slog.Warn("agent keeps breaking its output contract, keeping the answer",
+				"agent", c.Name(),
+				"violations", violations)
+			return result, nil
+		}
+
+		slog.Warn("agent broke its output contract, retrying",
+			"agent", c.Name(),
+			"attempt", attempt+1,
+			"violations", violations)
+		opts.Feedback = strings.TrimSpace(feedback + "\n" +
+			"Your previous answer broke the required format: " + strings.Join(violations, "; ") +
+			". Answer again following the format exactly.")
+	}
+}
+
+// Violations lists the ways the answer breaks the contract, none when it follows it
+func Violations(contract config.Contract, answer string) []string {
+	var violations []string
+	answer = strings.TrimSpace(answer)
+
+	if contract.MaxLength > 0 {
+		if n := utf8.RuneCountInString(answer); n > contract.MaxLength {
+			violations = append(violations, fmt.Sprintf("it is %d characters long, it MUST be at most %d", n, contract.MaxLength))
+		}
+	}
+
+	if contract.Markdown && strings.HasPrefix(answer, "```") && strings.HasSuffix(answer, "```") {
+		violations = append(violations, "it is wrapped in a code block, it MUST be plain Markdown")
+	}
+
+	bullets, tags, dots := 0, 0, 0
+	fenced := false
+	for _, line := range strings.Split(answer, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		if bulletLine.MatchString(line) {
+			bullets++
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "•") {
+			dots++
+		}
+		tags += len(htmlTag.FindAllString(inlineCode.ReplaceAllString(line, ""), -1))
+	}
+	if contract.Markdown && tags > 0 {
+		violations = append(violations, "it contains HTML tags, it MUST use Markdown only")
+	}
+	if contract.Markdown && dots > 0 {
+		violations = append(violations, `it uses "•" for lists, it MUST use "- " Markdown list items`)
+	}
+
+	switch {
+	case contract.MinBullets > 0 && contract.MinBullets == contract.MaxBullets && bullets != contract.MinBullets:
+		violations = append(violations, fmt.Sprintf("it has %d top-level bullet points, it MUST have exactly %d", bullets, contract.MinBullets))
+	case contract.MinBullets > 0 && bullets < contract.MinBullets:
+		violations = append(violations, fmt.Sprintf("it has %d top-level bullet points, it MUST have at least %d", bullets, contract.MinBullets))
+	case contract.MaxBullets > 0 && bullets > contract.MaxBullets:
+		violations = append(violations, fmt.Sprintf("it has %d top-level bullet points, it MUST have at most %d", bullets, contract.MaxBullets))
+	}
+	return violations
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// scriptedAgent answers with the next of its answers, recording the feedback it got
+type scriptedAgent struct {
+	answers  []string
+	feedback []string
+}
+
+func (s *scriptedAgent) Name() string {
+	return "scripted"
+}
+
+func (s *scriptedAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	s.feedback = append(s.feedback, opts.Feedback)
+	answer := s.answers[0]
+	if len(s.answers) > 1 {
+		s.answers = s.answers[1:]
+	}
+	return answer, nil
+}
+
+func TestViolations(t *testing.T) {
+	contract := config.Contract{Markdown: true, MinBullets: 2, MaxBullets: 3, MaxLength: 60}
+	tests := []struct {
+		name   string
+		answer string
+		want   []string
+	}{
+		{
+			name:   "follows the contract",
+			answer: "Summary:\n\n- One\n  - nested\n- Two `<b>`",
+		},
+		{
+			name:   "too long with too many bullets",
+			answer: "- One\n- Two\n- Three\n- Four, which makes the whole answer too long",
+			want:   []string{"65 characters long", "4 top-level bullet points, it MUST have at most 3"},
+		},
+		{
+			name:   "HTML and dots",
+			answer: "<p>Summary</p>\n• One\n• Two",
+			want:   []string{"HTML tags", `uses "•"`, "0 top-level bullet points, it MUST have at least 2"},
+		},
+		{
+			name:   "wrapped in a code block",
+			answer: "```markdown\n- One\n- Two\n```",
+			want:   []string{"code block", "at least 2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Violations(contract, tt.answer)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %q, want %d violations", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("violation %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+
+	if got := Violations(config.Contract{MinBullets: 3, MaxBullets: 3}, "- One\n- Two"); len(got) != 1 || !strings.Contains(got[0], "exactly 3") {
+		t.Errorf("expected an exact bullet count, got %q", got)
+	}
+}
+
+func TestWithContract_Reprompts(t *testing.T) {
+	mock := &scriptedAgent{answers: []string{"<b>Summary</b>", "- One\n- Two"}}
+	agent := WithContract(mock, config.Contract{Markdown: true, MinBullets: 2})
+
+	result, err := agent.Process(context.Background(), "content", Options{Feedback: "Be brief."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "- One\n- Two" || len(mock.feedback) != 2 {
+		t.Fatalf("expected the second answer after a re-prompt, got %q after %d calls", result, len(mock.feedback))
+	}
+	if !strings.HasPrefix(mock.feedback[1], "Be brief.\n") || !strings.Contains(mock.feedback[1], "HTML tags") {
+		t.Errorf("expected the violations to be added to the feedback, got %q", mock.feedback[1])
+	}
+}
+
+func TestWithContract_GivesUp(t *testing.T) {
+	retries := 1
+	contract := config.Contract{MaxLength: 3, Retries: &retries}
+
+	mock := &scriptedAgent{answers: []string{"too long"}}
+	result, err := WithContract(mock, contract).Process(context.Background(), "content", Options{})
+	if err != nil || result != "too long" || len(mock.feedback) != 2 {
+		t.Errorf("expected the last answer to be kept after 2 calls, got %q, %v after %d calls", result, err, len(mock.feedback))
+	}
+
+	contract.Strict = true
+	mock = &scriptedAgent{answers: []string{"too long"}}
+	if _, err := WithContract(mock, contract).Process(context.Background(), "content", Options{}); err == nil {
+		t.Error("expected a strict contract to fail the agent")
+	}
+}
+
+func TestDefaultContracts(t *testing.T) {
+	keyPoints := defaultContracts[KeyPoints]
+	if err := keyPoints.Validate(); err != nil {
+		t.Fatalf("invalid default contract: %v", err)
+	}
+	if got := Violations(keyPoints, "- One takeaway\n- Another one\n- And the last"); len(got) > 0 {
+		t.Errorf("expected bullet points to follow the contract, got %q", got)
+	}
+	if got := Violations(keyPoints, "A paragraph of prose instead of takeaways."); len(got) == 0 {
+		t.Error("expected prose to break the contract")
+	}
+}
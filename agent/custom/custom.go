@@ -0,0 +1,164 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"google.golang.org/genai"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/types"
+	"github.com/scipunch/myfeed/agent/usage"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/ratelimit"
+)
+
+// languageBlock asks for the output language when the prompt doesn't mention it
+const languageBlock = `
+{{#if language}}
+
+Write the whole answer in the language "{{language}}", regardless of the source language.
+{{/if}}`
+
+// feedbackBlock carries corrective re-prompts when the prompt doesn't place them
+const feedbackBlock = `
+{{#if feedback}}
+
+{{feedback}}
+{{/if}}`
+
+// CustomAgent uses the model of its provider with a prompt defined in the config
+type CustomAgent struct {
+	name     string
+	prompt   *ai.Prompt
+	g        *genkit.Genkit
+	provider string
+}
+
+// New creates an agent from its config definition with its own genkit instance.
+// The backend already carries the model of the definition. It fails fast if
+// the prompt doesn't render or the model of the backend is not set.
+func New(ctx context.Context, backend provider.Backend, name string, def config.CustomAgent) (*CustomAgent, error) {
+	if backend.Model == "" {
+		return nil, fmt.Errorf("model of the %s provider must be set", backend.Provider)
+	}
+	if def.Temperature != nil && backend.Provider != provider.Gemini {
+		return nil, fmt.Errorf("temperature of agent '%s' is supported by the %s provider only", name, provider.Gemini)
+	}
+
+	// Initialize genkit with the plugin of the provider
+	g := provider.Init(ctx, backend)
+
+	prompt, err := definePrompt(ctx, g, name, def)
+	if err != nil {
+		return nil, err
+	}
+	return &CustomAgent{
+		name:     name,
+		prompt:   &prompt,
+		g:        g,
+		provider: backend.Provider,
+	}, nil
+}
+
+// definePrompt registers the prompt of the definition and renders it once,
+// so broken templates fail before any item is processed
+func definePrompt(ctx context.Context, g *genkit.Genkit, name string, def config.CustomAgent) (ai.Prompt, error) {
+	text, err := Template(def.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	text += SchemaBlock(def.Schema)
+	opts := []ai.PromptOption{
+		// The template is used as it is, WithPrompt would format it
+		ai.WithPromptFn(func(context.Context, any) (string, error) {
+			return text, nil
+		}),
+	}
+	if def.Temperature != nil {
+		temperature := float32(*def.Temperature)
+		opts = append(opts, ai.WithConfig(&genai.GenerateContentConfig{Temperature: &temperature}))
+	}
+
+	prompt := genkit.DefinePrompt(g, name, opts...)
+	if _, err := prompt.Render(ctx, map[string]any{"content": "", "language": "en", "feedback": "", "title": "", "author": ""}); err != nil {
+		return nil, fmt.Errorf("failed to render prompt of agent '%s': %w", name, err)
+	}
+	return prompt, nil
+}
+
+// Template completes the prompt of a definition with the language and
+// feedback blocks it lacks, the content must be placed by the prompt
+func Template(prompt string) (string, error) {
+	if !strings.Contains(prompt, "{{content}}") {
+		return "", fmt.Errorf("prompt must contain {{content}}")
+	}
+	text := strings.TrimSpace(prompt)
+	if !strings.Contains(text, "{{language}}") {
+		text += languageBlock
+	}
+	if !strings.Contains(text, "{{feedback}}") {
+		text += feedbackBlock
+	}
+	return text, nil
+}
+
+// SchemaBlock asks for a JSON object with the fields of the schema, empty
+// for agents answering with free text
+func SchemaBlock(schema map[string]string) string {
+	if len(schema) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("\n\nAnswer with a single JSON object only, without any text or code block around it, with the fields:")
+	for _, name := range names {
+		t := schema[name]
+		if t == "list" {
+			t = "list of strings"
+		}
+		fmt.Fprintf(&b, "\n- %q: %s", name, t)
+	}
+	return b.String()
+}
+
+// Name returns the agent identifier, its name in the config
+func (a *CustomAgent) Name() string {
+	return a.name
+}
+
+// Process transforms the provided content with the configured prompt using the model of its provider
+func (a *CustomAgent) Process(ctx context.Context, content string, opts types.Options) (string, error) {
+	release, err := ratelimit.Acquire(ctx, a.provider)
+	if err != nil {
+		return "", fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+	defer release()
+
+	resp, err := (*a.prompt).Execute(ctx,
+		ai.WithInput(map[string]any{
+			"content":  content,
+			"language": opts.Language,
+			"feedback": opts.Feedback,
+			"title":    opts.Title,
+			"author":   opts.Author,
+		}))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute prompt of agent '%s': %w", a.name, err)
+	}
+	if resp.Usage != nil {
+		usage.Add(resp.Usage.InputTokens, resp.Usage.OutputTokens)
+		ratelimit.Spend(a.provider, resp.Usage.InputTokens+resp.Usage.OutputTokens)
+	}
+
+	return resp.Text(), nil
+}
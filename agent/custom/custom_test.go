@@ -0,0 +1,53 @@
+package custom
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestTemplate(t *testing.T) {
+	if _, err := Template("Rewrite the text as a haiku"); err == nil {
+		t.Error("expected a prompt without {{content}} to be rejected")
+	}
+
+	text, err := Template("Rewrite as a haiku:\n{{content}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, "Rewrite as a haiku:\n{{content}}\n{{#if language}}") || !strings.Contains(text, "{{feedback}}") {
+		t.Errorf("expected language and feedback blocks to be added, got %q", text)
+	}
+
+	placed := "{{#if feedback}}{{feedback}}{{/if}} Answer in {{language}}: {{content}}"
+	if text, _ := Template(placed); text != placed {
+		t.Errorf("expected blocks placed by the prompt to be kept, got %q", text)
+	}
+}
+
+func TestNew(t *testing.T) {
+	backend := provider.Backend{Provider: provider.Gemini, APIKey: "test", Model: "googleai/gemini-2.0-flash"}
+	temperature := 0.2
+	a, err := New(context.Background(), backend, "haiku", config.CustomAgent{Prompt: "Haiku of {{content}}", Temperature: &temperature})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.Name() != "haiku" {
+		t.Errorf("unexpected name %q", a.Name())
+	}
+
+	if _, err := New(context.Background(), backend, "broken", config.CustomAgent{Prompt: "{{#if title}}{{content}}"}); err == nil {
+		t.Error("expected a broken template to fail")
+	}
+
+	local := provider.Backend{Provider: provider.Ollama, URL: "http://localhost:11434", Model: "llama3.1"}
+	if _, err := New(context.Background(), local, "haiku", config.CustomAgent{Prompt: "Haiku of {{content}}"}); err != nil {
+		t.Errorf("unexpected error with Ollama: %v", err)
+	}
+	if _, err := New(context.Background(), local, "haiku", config.CustomAgent{Prompt: "Haiku of {{content}}", Temperature: &temperature}); err == nil {
+		t.Error("expected temperature to be rejected with Ollama")
+	}
+}
@@ -0,0 +1,126 @@
+package describe
+
+import (
+	"context"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/types"
+	"github.com/scipunch/myfeed/agent/usage"
+	"github.com/scipunch/myfeed/ratelimit"
+)
+
+//go:embed *.prompt
+var prompts embed.FS
+
+const (
+	agentName  = "describe"
+	promptName = "describe"
+)
+
+// maxPhotos are the photos of an item sent to the model, e.g., of an album
+const maxPhotos = 10
+
+// DescribeAgent uses the vision of Gemini models to describe the photos of items
+type DescribeAgent struct {
+	prompt   *ai.Prompt
+	g        *genkit.Genkit
+	provider string
+}
+
+// New creates a new describe agent with its own genkit instance.
+// It fails fast if the prompt is not found, the model of the backend is not
+// set or the provider doesn't see images.
+func New(ctx context.Context, backend provider.Backend) (*DescribeAgent, error) {
+	if backend.Model == "" {
+		return nil, fmt.Errorf("model of the %s provider must be set", backend.Provider)
+	}
+	if backend.Provider != provider.Gemini {
+		return nil, fmt.Errorf("describing photos is supported by the %s provider only", provider.Gemini)
+	}
+
+	// Initialize genkit with the plugin of the provider
+	g := provider.Init(ctx, backend,
+		genkit.WithPromptFS(prompts),
+		genkit.WithPromptDir("."),
+	)
+
+	// Fail fast if prompt wasn't found
+	prompt := genkit.LookupPrompt(g, promptName)
+	if prompt == nil {
+		return nil, fmt.Errorf("prompt '%s' not found in embedded files", promptName)
+	}
+
+	return &DescribeAgent{
+		prompt:   &prompt,
+		g:        g,
+		provider: backend.Provider,
+	}, nil
+}
+
+// Name returns the agent identifier
+func (a *DescribeAgent) Name() string {
+	return agentName
+}
+
+// Process appends a description of the photos of the item to the content,
+// content of items without photos is returned as it is
+func (a *DescribeAgent) Process(ctx context.Context, content string, opts types.Options) (string, error) {
+	if len(opts.Images) == 0 {
+		return content, nil
+	}
+	var photos []string
+	for _, path := range opts.Images[:min(len(opts.Images), maxPhotos)] {
+		photo, err := dataURL(path)
+		if err != nil {
+			return "", err
+		}
+		photos = append(photos, photo)
+	}
+
+	release, err := ratelimit.Acquire(ctx, a.provider)
+	if err != nil {
+		return "", fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+	defer release()
+
+	resp, err := (*a.prompt).Execute(ctx,
+		ai.WithInput(map[string]any{
+			"content":  content,
+			"photos":   photos,
+			"language": opts.Language,
+			"feedback": opts.Feedback,
+			"title":    opts.Title,
+			"author":   opts.Author,
+		}))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute describe prompt: %w", err)
+	}
+	if resp.Usage != nil {
+		usage.Add(resp.Usage.InputTokens, resp.Usage.OutputTokens)
+		ratelimit.Spend(a.provider, resp.Usage.InputTokens+resp.Usage.OutputTokens)
+	}
+
+	return strings.TrimSpace(content + "\n\n" + resp.Text()), nil
+}
+
+// dataURL reads the photo at path into a data URL, e.g., "data:image/jpeg;base64,..."
+func dataURL(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read photo: %w", err)
+	}
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("photo '%s' is not an image, got %s", path, mimeType)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,45 @@
+package describe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/types"
+)
+
+func TestDataURL(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "photo.png")
+	if err := os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := dataURL(png); err != nil || !strings.HasPrefix(got, "data:image/png;base64,") {
+		t.Errorf("expected a PNG data URL, got %q, %v", got, err)
+	}
+
+	text := filepath.Join(dir, "note.txt")
+	if err := os.WriteFile(text, []byte("not a photo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dataURL(text); err == nil {
+		t.Error("expected files other than images to fail")
+	}
+}
+
+func TestNew_GeminiOnly(t *testing.T) {
+	if _, err := New(context.Background(), provider.Backend{Provider: provider.Claude, Model: "claude-haiku-4-5"}); err == nil {
+		t.Error("expected providers without vision support to fail")
+	}
+}
+
+func TestProcess_NoPhotos(t *testing.T) {
+	a := &DescribeAgent{}
+	got, err := a.Process(context.Background(), "Text of the post", types.Options{})
+	if err != nil || got != "Text of the post" {
+		t.Errorf("expected content without photos as it is, got %q, %v", got, err)
+	}
+}
@@ -4,40 +4,185 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/scipunch/myfeed/agent/custom"
+	"github.com/scipunch/myfeed/agent/describe"
+	"github.com/scipunch/myfeed/agent/discussion"
+	"github.com/scipunch/myfeed/agent/keypoints"
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/quality"
+	"github.com/scipunch/myfeed/agent/relevance"
 	"github.com/scipunch/myfeed/agent/summary"
 	"github.com/scipunch/myfeed/config"
 )
 
+// defaultContracts hold the format of agents without a configured contract
+var defaultContracts = map[string]config.Contract{
+	KeyPoints: {Markdown: true, MinBullets: 3, MaxBullets: 5},
+}
+
 // InitAgents creates agents based on the requested agent types.
 // It fails fast if any agent initialization fails (e.g., missing credentials, invalid prompts).
 // Returns a map of agent name -> agent instance.
-// All agents are automatically wrapped with retry logic (exponential backoff, 5-minute timeout).
-func InitAgents(ctx context.Context, agentTypes []string, creds config.GeminiCredentials) (map[string]Agent, error) {
+// All agents are automatically wrapped with retry logic (exponential backoff, 5-minute timeout)
+// and output language validation, agents with a contract also with its validation.
+// Answers are accounted for quality drift metrics per model.
+// The relevance agent answers with a score and is wrapped with retry logic only,
+// like the describe agent keeping the content in its language.
+// Agents defined in the config are created from their prompts. Every agent
+// may choose a provider and model of its own, the others use the default provider.
+// Fallback providers answer in order once retries of the previous one are exhausted.
+// Content too long for the providers is condensed in chunks by summary,
+// key points and discussion agents. Agents defined with a schema answer
+// with JSON objects validated against it.
+func InitAgents(ctx context.Context, agentTypes []string, providers provider.Settings, contracts map[string]config.Contract, interests []string, defined map[string]config.CustomAgent) (map[string]Agent, error) {
 	agents := make(map[string]Agent)
 	retryConfig := DefaultRetryConfig()
 
+	for name, def := range defined {
+		if isBuiltIn(name) {
+			return nil, fmt.Errorf("agent '%s' defined in the config shadows a built-in agent", name)
+		}
+		if err := def.ValidateSchema(); err != nil {
+			return nil, fmt.Errorf("invalid schema of agent '%s': %w", name, err)
+		}
+	}
+	for name := range providers.Models {
+		if _, ok := defined[name]; ok {
+			return nil, fmt.Errorf("model of agent '%s' defined in the config is set in its definition", name)
+		}
+		if !isBuiltIn(name) {
+			return nil, fmt.Errorf("model for unknown agent: %s", name)
+		}
+	}
+	for name, contract := range contracts {
+		if _, ok := defined[name]; !ok && (!isBuiltIn(name) || name == Relevance || name == Describe) {
+			return nil, fmt.Errorf("contract for unknown agent: %s", name)
+		}
+		if err := contract.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid contract of agent '%s': %w", name, err)
+		}
+	}
+
 	for _, agentType := range agentTypes {
-		var baseAgent Agent
-		var err error
+		def, isDefined := defined[agentType]
+		backend, err := providers.AgentBackend(agentType)
+		fallback := providers.Models[agentType].Fallback
+		if isDefined {
+			backend, err = providers.Backend(def.Provider, def.Model)
+			fallback = def.Fallback
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize agent '%s': %w", agentType, err)
+		}
+		if !isDefined && !isBuiltIn(agentType) {
+			return nil, fmt.Errorf("unknown agent type: %s", agentType)
+		}
+		chain, err := providers.Chain(backend, fallback)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize fallback of agent '%s': %w", agentType, err)
+		}
+		if agentType == Describe {
+			// Photos are seen by Gemini only, the agent's provider
+			chain = chain[:1]
+		}
+		provider.Set(agentType, backend.Provider)
 
-		switch agentType {
-		case "summary":
-			baseAgent, err = summary.New(ctx, creds)
+		// Every provider of the chain is retried before the next one answers
+		var retried []Agent
+		for i, b := range chain {
+			d := def
+			if i > 0 && b.Provider != provider.Gemini {
+				d.Temperature = nil
+			}
+			baseAgent, err := newAgent(ctx, agentType, b, d, interests)
 			if err != nil {
-				return nil, fmt.Errorf("failed to initialize summary agent: %w", err)
+				return nil, err
 			}
-		default:
-			return nil, fmt.Errorf("unknown agent type: %s", agentType)
+			if i == 0 {
+				quality.SetModel(baseAgent.Name(), b.ModelName())
+			}
+			retried = append(retried, WithRetry(baseAgent, retryConfig))
+		}
+		agents[agentType] = WithFallback(retried, chain)
+		if agentType == Relevance || agentType == Describe {
+			continue
+		}
+		if isCondensing(agentType) {
+			agents[agentType] = WithChunking(agents[agentType], providers.ChunkSize(chain))
+		}
+		if len(def.Schema) > 0 {
+			agents[agentType] = WithSchema(agents[agentType], def.Schema)
 		}
 
-		// Wrap with retry logic
-		agents[agentType] = WithRetry(baseAgent, retryConfig)
+		// Wrap with output language validation, the contract re-prompts
+		// answers in the right language only
+		agents[agentType] = WithLanguageCheck(agents[agentType])
+		contract, ok := contracts[agentType]
+		if !ok {
+			contract = defaultContracts[agentType]
+		}
+		if !contract.IsZero() {
+			agents[agentType] = WithContract(agents[agentType], contract)
+		}
+		agents[agentType] = WithQuality(agents[agentType])
 	}
 
 	return agents, nil
 }
 
-// CollectUniqueAgentTypes extracts unique agent types from enabled resource configurations
+// newAgent creates the agent of the type answering through the backend,
+// def is the definition of agents defined in the config
+func newAgent(ctx context.Context, agentType string, backend provider.Backend, def config.CustomAgent, interests []string) (Agent, error) {
+	switch agentType {
+	case "summary":
+		a, err := summary.New(ctx, backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize summary agent: %w", err)
+		}
+		return a, nil
+	case KeyPoints:
+		a, err := keypoints.New(ctx, backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize key points agent: %w", err)
+		}
+		return a, nil
+	case Discussion:
+		a, err := discussion.New(ctx, backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize discussion agent: %w", err)
+		}
+		return a, nil
+	case Describe:
+		a, err := describe.New(ctx, backend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize describe agent: %w", err)
+		}
+		return a, nil
+	case Relevance:
+		a, err := relevance.New(ctx, backend, interests)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize relevance agent: %w", err)
+		}
+		return a, nil
+	}
+	a, err := custom.New(ctx, backend, agentType, def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize agent '%s': %w", agentType, err)
+	}
+	return a, nil
+}
+
+// isBuiltIn reports whether the agent is implemented by myfeed
+func isBuiltIn(name string) bool {
+	switch name {
+	case "summary", KeyPoints, Discussion, Relevance, Describe:
+		return true
+	}
+	return false
+}
+
+// CollectUniqueAgentTypes extracts unique agent types from enabled resource
+// configurations, resources with min_score need the relevance agent
 func CollectUniqueAgentTypes(resources []config.ResourceConfig) []string {
 	typeSet := make(map[string]bool)
 	for _, resource := range resources {
@@ -47,6 +192,14 @@ func CollectUniqueAgentTypes(resources []config.ResourceConfig) []string {
 		for _, agentType := range resource.Agents {
 			typeSet[agentType] = true
 		}
+		for _, route := range resource.Routes {
+			for _, agentType := range route.Agents {
+				typeSet[agentType] = true
+			}
+		}
+		if resource.MinScore > 0 && !resource.IsHeadline() {
+			typeSet[Relevance] = true
+		}
 	}
 
 	var types []string
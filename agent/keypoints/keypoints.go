@@ -0,0 +1,88 @@
+package keypoints
+
+import (
+	"context"
+	"embed"
+	"fmt"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/types"
+	"github.com/scipunch/myfeed/agent/usage"
+	"github.com/scipunch/myfeed/ratelimit"
+)
+
+//go:embed *.prompt
+var prompts embed.FS
+
+const (
+	agentName  = "keypoints"
+	promptName = "keypoints"
+)
+
+// KeyPointsAgent uses the model of its provider to condense content into bullet key takeaways
+type KeyPointsAgent struct {
+	prompt   *ai.Prompt
+	g        *genkit.Genkit
+	provider string
+}
+
+// New creates a new key points agent with its own genkit instance.
+// It fails fast if the prompt is not found or the model of the backend is not set.
+func New(ctx context.Context, backend provider.Backend) (*KeyPointsAgent, error) {
+	if backend.Model == "" {
+		return nil, fmt.Errorf("model of the %s provider must be set", backend.Provider)
+	}
+
+	// Initialize genkit with the plugin of the provider
+	g := provider.Init(ctx, backend,
+		genkit.WithPromptFS(prompts),
+		genkit.WithPromptDir("."),
+	)
+
+	// Fail fast if prompt wasn't found
+	prompt := genkit.LookupPrompt(g, promptName)
+	if prompt == nil {
+		return nil, fmt.Errorf("prompt '%s' not found in embedded files", promptName)
+	}
+
+	return &KeyPointsAgent{
+		prompt:   &prompt,
+		g:        g,
+		provider: backend.Provider,
+	}, nil
+}
+
+// Name returns the agent identifier
+func (a *KeyPointsAgent) Name() string {
+	return agentName
+}
+
+// Process lists the key takeaways of the provided content using the model of its provider
+func (a *KeyPointsAgent) Process(ctx context.Context, content string, opts types.Options) (string, error) {
+	release, err := ratelimit.Acquire(ctx, a.provider)
+	if err != nil {
+		return "", fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+	defer release()
+
+	resp, err := (*a.prompt).Execute(ctx,
+		ai.WithInput(map[string]any{
+			"content":  content,
+			"language": opts.Language,
+			"feedback": opts.Feedback,
+			"title":    opts.Title,
+			"author":   opts.Author,
+		}))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute key points prompt: %w", err)
+	}
+	if resp.Usage != nil {
+		usage.Add(resp.Usage.InputTokens, resp.Usage.OutputTokens)
+		ratelimit.Spend(a.provider, resp.Usage.InputTokens+resp.Usage.OutputTokens)
+	}
+
+	return resp.Text(), nil
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/scipunch/myfeed/agent/quality"
+	"github.com/scipunch/myfeed/lang"
+)
+
+// WithLanguageCheck wraps an agent so that output is validated against
+// the requested language. On mismatch a single corrective retry is made.
+func WithLanguageCheck(agent Agent) Agent {
+	return &languageAgent{underlying: agent}
+}
+
+type languageAgent struct {
+	underlying Agent
+}
+
+func (l *languageAgent) Name() string {
+	return l.underlying.Name()
+}
+
+func (l *languageAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	result, err := l.underlying.Process(ctx, content, opts)
+	if err != nil || opts.Language == "" || lang.Matches(result, opts.Language) {
+		return result, err
+	}
+
+	quality.LanguageMismatch(l.Name())
+	detected := lang.Detect(result)
+	slog.Warn("agent answered in a wrong language, retrying",
+		"agent", l.Name(),
+		"expected", opts.Language,
+		"detected", detected)
+
+	opts.Feedback = strings.TrimSpace(opts.Feedback + "\n" + fmt.Sprintf(
+		"Your previous answer was written in '%s'. The whole answer MUST be written in '%s'.",
+		detected, opts.Language))
+	result, err = l.underlying.Process(ctx, content, opts)
+	if err != nil {
+		return "", err
+	}
+	if !lang.Matches(result, opts.Language) {
+		return "", fmt.Errorf("agent '%s' answered in '%s' instead of '%s'", l.Name(), lang.Detect(result), opts.Language)
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"testing"
+)
+
+// languageMockAgent answers in English until it receives feedback
+type languageMockAgent struct {
+	calls        int
+	ignoreAlways bool
+}
+
+func (m *languageMockAgent) Name() string {
+	return "language-mock"
+}
+
+func (m *languageMockAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	m.calls++
+	if opts.Feedback == "" || m.ignoreAlways {
+		return "This is a summary of the article and it is written in the English language.", nil
+	}
+	return "Это краткое содержание статьи, и оно написано на русском языке.", nil
+}
+
+func TestWithLanguageCheck_CorrectiveRetry(t *testing.T) {
+	mock := &languageMockAgent{}
+	agent := WithLanguageCheck(mock)
+
+	result, err := agent.Process(context.Background(), "content", Options{Language: "ru"})
+	if err != nil {
+		t.Fatalf("expected success after corrective retry, got error: %v", err)
+	}
+	if mock.calls != 2 {
+		t.Errorf("expected 2 calls, got %d", mock.calls)
+	}
+	if result != "Это краткое содержание статьи, и оно написано на русском языке." {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
+
+func TestWithLanguageCheck_FailsAfterSingleRetry(t *testing.T) {
+	mock := &languageMockAgent{ignoreAlways: true}
+	agent := WithLanguageCheck(mock)
+
+	if _, err := agent.Process(context.Background(), "content", Options{Language: "ru"}); err == nil {
+		t.Fatal("expected error when agent keeps answering in a wrong language")
+	}
+	if mock.calls != 2 {
+		t.Errorf("expected exactly 2 calls, got %d", mock.calls)
+	}
+}
+
+func TestWithLanguageCheck_NoLanguage(t *testing.T) {
+	mock := &languageMockAgent{}
+	agent := WithLanguageCheck(mock)
+
+	if _, err := agent.Process(context.Background(), "content", Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mock.calls != 1 {
+		t.Errorf("expected 1 call, got %d", mock.calls)
+	}
+}
@@ -0,0 +1,188 @@
+// Package provider creates the genkit instances agents call their model
+// through: Gemini, Claude or a local Ollama server
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/firebase/genkit/go/plugins/compat_oai"
+	"github.com/firebase/genkit/go/plugins/googlegenai"
+	"github.com/firebase/genkit/go/plugins/ollama"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// Providers of agents, also their rate limit and circuit breaker keys
+const (
+	Gemini = "gemini"
+	Claude = "claude"
+	Ollama = "ollama"
+)
+
+// anthropicURL is the OpenAI compatible API of Claude, models are resolved
+// by name, so new ones work without an update of the plugin
+const anthropicURL = "https://api.anthropic.com/v1"
+
+// Settings are the configured providers agents choose from
+type Settings struct {
+	Default  string // Provider of agents not choosing one (defaults to Gemini)
+	Gemini   config.GeminiCredentials
+	Claude   config.ClaudeCredentials
+	Ollama   config.Ollama
+	Models   map[string]config.AgentModel // Choices of built-in agents by name
+	Fallback []string                     // Providers of agents without fallbacks of their own
+	Chunk    int                          // Characters of content condensed in one call (0 = the context of the providers)
+}
+
+// Backend is the provider and model answering for an agent
+type Backend struct {
+	Provider string
+	Model    string
+	APIKey   string        // Gemini and Claude only
+	URL      string        // Ollama only
+	Timeout  time.Duration // Ollama only
+}
+
+// Backend returns the backend of the provider, the default one when empty.
+// The model overrides the provider's when set.
+func (s Settings) Backend(name, model string) (Backend, error) {
+	if name == "" {
+		name = s.Default
+	}
+	switch name {
+	case "", Gemini:
+		b := Backend{Provider: Gemini, Model: s.Gemini.Model, APIKey: s.Gemini.APIKey}
+		if model != "" {
+			b.Model = model
+		}
+		if b.APIKey == "" || b.Model == "" {
+			return Backend{}, fmt.Errorf("Gemini API key and model required for agents but not found in creds.toml")
+		}
+		return b, nil
+	case Claude:
+		b := Backend{Provider: Claude, Model: s.Claude.Model, APIKey: s.Claude.APIKey}
+		if model != "" {
+			b.Model = model
+		}
+		if b.APIKey == "" || b.Model == "" {
+			return Backend{}, fmt.Errorf("Claude API key and model required for agents but not found in creds.toml")
+		}
+		return b, nil
+	case Ollama:
+		b := Backend{Provider: Ollama, Model: s.Ollama.Model, URL: s.Ollama.Address(), Timeout: s.Ollama.AnswerTimeout()}
+		if model != "" {
+			b.Model = model
+		}
+		if b.Model == "" {
+			return Backend{}, fmt.Errorf("Ollama model required for agents, set model in [ollama] of the config")
+		}
+		return b, nil
+	}
+	return Backend{}, fmt.Errorf("unknown provider %q, expected %q, %q or %q", name, Gemini, Claude, Ollama)
+}
+
+// AgentBackend returns the backend of a built-in agent
+func (s Settings) AgentBackend(agent string) (Backend, error) {
+	choice := s.Models[agent]
+	return s.Backend(choice.Provider, choice.Model)
+}
+
+// Chain returns the backend followed by the backends of the fallback
+// providers in order, with their default models. Agents without fallback
+// use the default ones, the provider of the backend is skipped.
+func (s Settings) Chain(b Backend, fallback []string) ([]Backend, error) {
+	if fallback == nil {
+		fallback = s.Fallback
+	}
+	chain := []Backend{b}
+	for _, name := range fallback {
+		if slices.ContainsFunc(chain, func(c Backend) bool { return c.Provider == name }) {
+			continue
+		}
+		next, err := s.Backend(name, "")
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, next)
+	}
+	return chain, nil
+}
+
+// contextChars are the characters of content the models of a provider take
+// in one call, leaving room for the prompt and the answer
+var contextChars = map[string]int{
+	Gemini: 2_000_000,
+	Claude: 400_000,
+	Ollama: 8_000,
+}
+
+// ChunkSize returns the characters of content agents answering through the
+// chain condense in one call, the configured size or the smallest context
+func (s Settings) ChunkSize(chain []Backend) int {
+	if s.Chunk > 0 {
+		return s.Chunk
+	}
+	size := 0
+	for _, b := range chain {
+		if c := contextChars[b.Provider]; size == 0 || c < size {
+			size = c
+		}
+	}
+	return size
+}
+
+// ModelName returns the name genkit knows the model by, e.g., "ollama/llama3.1"
+func (b Backend) ModelName() string {
+	switch b.Provider {
+	case Claude:
+		return "anthropic/" + strings.TrimPrefix(b.Model, "anthropic/")
+	case Ollama:
+		return Ollama + "/" + strings.TrimPrefix(b.Model, Ollama+"/")
+	}
+	return b.Model
+}
+
+// Init creates a genkit instance calling the model of the backend by default
+func Init(ctx context.Context, b Backend, opts ...genkit.GenkitOption) *genkit.Genkit {
+	opts = append(opts, genkit.WithDefaultModel(b.ModelName()))
+	switch b.Provider {
+	case Claude:
+		plugin := &compat_oai.OpenAICompatible{Provider: "anthropic", APIKey: b.APIKey, BaseURL: anthropicURL}
+		return genkit.Init(ctx, append(opts, genkit.WithPlugins(plugin))...)
+	case Ollama:
+		// Models of a local server aren't known upfront, the plugin needs them defined
+		plugin := &ollama.Ollama{ServerAddress: b.URL, Timeout: int(b.Timeout.Seconds())}
+		g := genkit.Init(ctx, append(opts, genkit.WithPlugins(plugin))...)
+		plugin.DefineModel(g, ollama.ModelDefinition{Name: strings.TrimPrefix(b.Model, Ollama+"/"), Type: "chat"}, nil)
+		return g
+	}
+	return genkit.Init(ctx, append(opts, genkit.WithPlugins(&googlegenai.GoogleAI{APIKey: b.APIKey}))...)
+}
+
+var (
+	mu        sync.Mutex
+	providers = make(map[string]string)
+)
+
+// Set records the provider answering for the agent
+func Set(agent, provider string) {
+	mu.Lock()
+	defer mu.Unlock()
+	providers[agent] = provider
+}
+
+// Of returns the provider answering for the agent, Gemini when unknown
+func Of(agent string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	if p, ok := providers[agent]; ok {
+		return p
+	}
+	return Gemini
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestBackend(t *testing.T) {
+	settings := Settings{
+		Gemini: config.GeminiCredentials{APIKey: "key", Model: "googleai/gemini-2.0-flash"},
+		Ollama: config.Ollama{Model: "llama3.1"},
+	}
+
+	b, err := settings.Backend("", "")
+	if err != nil || b.Provider != Gemini || b.ModelName() != "googleai/gemini-2.0-flash" {
+		t.Errorf("expected Gemini by default, got %+v, %v", b, err)
+	}
+
+	settings.Default = Ollama
+	b, err = settings.Backend("", "")
+	if err != nil || b.URL != config.DefaultOllamaURL || b.Timeout != 5*time.Minute || b.ModelName() != "ollama/llama3.1" {
+		t.Errorf("expected the local server, got %+v, %v", b, err)
+	}
+	if b, _ := settings.Backend(Gemini, "googleai/gemini-1.5-pro"); b.Provider != Gemini || b.Model != "googleai/gemini-1.5-pro" {
+		t.Errorf("expected the agent's provider and model to win, got %+v", b)
+	}
+
+	settings.Claude = config.ClaudeCredentials{APIKey: "key", Model: "claude-sonnet-4-5"}
+	settings.Models = map[string]config.AgentModel{"relevance": {Provider: Claude, Model: "claude-haiku-4-5"}}
+	if b, err := settings.AgentBackend("relevance"); err != nil || b.APIKey != "key" || b.ModelName() != "anthropic/claude-haiku-4-5" {
+		t.Errorf("expected the agent's Claude model, got %+v, %v", b, err)
+	}
+	if b, _ := settings.AgentBackend("summary"); b.Provider != Ollama {
+		t.Errorf("expected agents without a choice on the default provider, got %+v", b)
+	}
+
+	if _, err := (Settings{Default: Gemini}).Backend("", ""); err == nil {
+		t.Error("expected missing Gemini credentials to fail")
+	}
+	if _, err := (Settings{}).Backend(Claude, "claude-sonnet-4-5"); err == nil {
+		t.Error("expected a missing Claude API key to fail")
+	}
+	if _, err := (Settings{}).Backend(Ollama, ""); err == nil {
+		t.Error("expected a missing Ollama model to fail")
+	}
+	if _, err := settings.Backend("openai", ""); err == nil {
+		t.Error("expected an unknown provider to fail")
+	}
+}
+
+func TestChain(t *testing.T) {
+	settings := Settings{
+		Gemini:   config.GeminiCredentials{APIKey: "key", Model: "googleai/gemini-2.0-flash"},
+		Claude:   config.ClaudeCredentials{APIKey: "key", Model: "claude-sonnet-4-5"},
+		Ollama:   config.Ollama{Model: "llama3.1"},
+		Fallback: []string{Gemini, Claude, Ollama},
+	}
+	primary, _ := settings.Backend(Gemini, "googleai/gemini-1.5-pro")
+
+	chain, err := settings.Chain(primary, nil)
+	if err != nil || len(chain) != 3 || chain[0].Model != "googleai/gemini-1.5-pro" || chain[1].Provider != Claude || chain[2].Provider != Ollama {
+		t.Errorf("expected the top-level fallback after the agent's provider, got %+v, %v", chain, err)
+	}
+	if chain, _ := settings.Chain(primary, []string{}); len(chain) != 1 {
+		t.Errorf("expected an agent to opt out of the fallback, got %+v", chain)
+	}
+	if _, err := (Settings{}).Chain(primary, []string{Claude}); err == nil {
+		t.Error("expected a fallback without credentials to fail")
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	chain := []Backend{{Provider: Gemini}, {Provider: Ollama}}
+	if got := (Settings{}).ChunkSize(chain); got != contextChars[Ollama] {
+		t.Errorf("expected the smallest context of the chain, got %d", got)
+	}
+	if got := (Settings{Chunk: 1000}).ChunkSize(chain); got != 1000 {
+		t.Errorf("expected the configured chunk size, got %d", got)
+	}
+}
@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/scipunch/myfeed/agent/quality"
+)
+
+// WithQuality wraps an agent so that lengths of its answers and its
+// failures are accounted for quality drift metrics
+func WithQuality(agent Agent) Agent {
+	return &qualityAgent{underlying: agent}
+}
+
+type qualityAgent struct {
+	underlying Agent
+}
+
+func (q *qualityAgent) Name() string {
+	return q.underlying.Name()
+}
+
+func (q *qualityAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	result, err := q.underlying.Process(ctx, content, opts)
+	if err != nil {
+		// Cancelled runs say nothing about the model
+		if ctx.Err() == nil {
+			quality.Failure(q.Name())
+		}
+		return "", err
+	}
+	quality.Answer(q.Name(), utf8.RuneCountInString(content), utf8.RuneCountInString(result))
+	return result, nil
+}
@@ -0,0 +1,88 @@
+// Package quality accounts the answers of agents during the run, so that a
+// model quietly degrading them shows up over time
+package quality
+
+import "sync"
+
+// Counts describe the answers of an agent
+type Counts struct {
+	Model              string // Model answering for the agent
+	Calls              int    // Items the agent was asked to process
+	InputChars         int    // Characters of answered content
+	OutputChars        int    // Characters of answers
+	LanguageMismatches int    // Answers first written in a wrong language
+	ContractViolations int    // Answers first breaking the output contract
+	Failures           int    // Calls ending without an answer
+}
+
+// LengthRatio returns characters of answers per character of content, 0 without answers
+func (c Counts) LengthRatio() float64 {
+	if c.InputChars == 0 {
+		return 0
+	}
+	return float64(c.OutputChars) / float64(c.InputChars)
+}
+
+var (
+	mu     sync.Mutex
+	models = make(map[string]string)
+	counts = make(map[string]*Counts)
+)
+
+// SetModel records the model answering for the agent
+func SetModel(agent, model string) {
+	mu.Lock()
+	defer mu.Unlock()
+	models[agent] = model
+}
+
+// Answer records an answer of the agent, lengths are in characters
+func Answer(agent string, input, output int) {
+	update(agent, func(c *Counts) {
+		c.Calls++
+		c.InputChars += input
+		c.OutputChars += output
+	})
+}
+
+// Failure records a call of the agent ending without an answer
+func Failure(agent string) {
+	update(agent, func(c *Counts) {
+		c.Calls++
+		c.Failures++
+	})
+}
+
+// LanguageMismatch records an answer written in a wrong language
+func LanguageMismatch(agent string) {
+	update(agent, func(c *Counts) { c.LanguageMismatches++ })
+}
+
+// ContractViolation records an answer breaking the output contract
+func ContractViolation(agent string) {
+	update(agent, func(c *Counts) { c.ContractViolations++ })
+}
+
+// Take returns counts of agents since the last call and starts over
+func Take() map[string]Counts {
+	mu.Lock()
+	defer mu.Unlock()
+	taken := make(map[string]Counts, len(counts))
+	for agent, c := range counts {
+		c.Model = models[agent]
+		taken[agent] = *c
+	}
+	counts = make(map[string]*Counts)
+	return taken
+}
+
+func update(agent string, fn func(*Counts)) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := counts[agent]
+	if !ok {
+		c = &Counts{}
+		counts[agent] = c
+	}
+	fn(c)
+}
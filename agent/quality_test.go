@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/scipunch/myfeed/agent/quality"
+	"github.com/scipunch/myfeed/config"
+)
+
+// failingAgent fails every call
+type failingAgent struct{}
+
+func (failingAgent) Name() string {
+	return "failing"
+}
+
+func (failingAgent) Process(context.Context, string, Options) (string, error) {
+	return "", errors.New("model unavailable")
+}
+
+func TestWithQuality(t *testing.T) {
+	quality.Take()
+	quality.SetModel("scripted", "gemini-test")
+
+	mock := &scriptedAgent{answers: []string{"<b>Summary</b>", "- One\n- Two"}}
+	agent := WithQuality(WithContract(mock, config.Contract{Markdown: true, MinBullets: 2}))
+	if _, err := agent.Process(context.Background(), "content of ten", Options{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := WithQuality(failingAgent{}).Process(context.Background(), "content", Options{}); err == nil {
+		t.Fatal("expected the failure to be returned")
+	}
+
+	counts := quality.Take()
+	want := quality.Counts{Model: "gemini-test", Calls: 1, InputChars: 14, OutputChars: 11, ContractViolations: 1}
+	if counts["scripted"] != want {
+		t.Errorf("got %+v, want %+v", counts["scripted"], want)
+	}
+	if got := counts["failing"]; got.Calls != 1 || got.Failures != 1 {
+		t.Errorf("expected a failed call, got %+v", got)
+	}
+	if len(quality.Take()) != 0 {
+		t.Error("expected counts to start over")
+	}
+}
@@ -0,0 +1,95 @@
+package relevance
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/types"
+	"github.com/scipunch/myfeed/agent/usage"
+	"github.com/scipunch/myfeed/ratelimit"
+)
+
+//go:embed *.prompt
+var prompts embed.FS
+
+const (
+	agentName  = "relevance"
+	promptName = "relevance"
+)
+
+// RelevanceAgent uses the model of its provider to score content from 0 to 10 against the interests of the reader
+type RelevanceAgent struct {
+	prompt    *ai.Prompt
+	g         *genkit.Genkit
+	provider  string
+	interests string
+}
+
+// New creates a new relevance agent with its own genkit instance.
+// It fails fast if the prompt is not found, the model of the backend is not set
+// or there are no interests to score against.
+func New(ctx context.Context, backend provider.Backend, interests []string) (*RelevanceAgent, error) {
+	if backend.Model == "" {
+		return nil, fmt.Errorf("model of the %s provider must be set", backend.Provider)
+	}
+	if len(interests) == 0 {
+		return nil, fmt.Errorf("no interests to score against, set interests in the config")
+	}
+
+	// Initialize genkit with the plugin of the provider
+	g := provider.Init(ctx, backend,
+		genkit.WithPromptFS(prompts),
+		genkit.WithPromptDir("."),
+	)
+
+	// Fail fast if prompt wasn't found
+	prompt := genkit.LookupPrompt(g, promptName)
+	if prompt == nil {
+		return nil, fmt.Errorf("prompt '%s' not found in embedded files", promptName)
+	}
+
+	return &RelevanceAgent{
+		prompt:    &prompt,
+		g:         g,
+		provider:  backend.Provider,
+		interests: "- " + strings.Join(interests, "\n- "),
+	}, nil
+}
+
+// Name returns the agent identifier
+func (a *RelevanceAgent) Name() string {
+	return agentName
+}
+
+// Process scores the provided content using the model of its provider, the answer is the score only
+func (a *RelevanceAgent) Process(ctx context.Context, content string, opts types.Options) (string, error) {
+	release, err := ratelimit.Acquire(ctx, a.provider)
+	if err != nil {
+		return "", fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+	defer release()
+
+	resp, err := (*a.prompt).Execute(ctx,
+		ai.WithInput(map[string]any{
+			"content":   content,
+			"interests": a.interests,
+			"feedback":  opts.Feedback,
+			"title":     opts.Title,
+			"author":    opts.Author,
+		}))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute relevance prompt: %w", err)
+	}
+	if resp.Usage != nil {
+		usage.Add(resp.Usage.InputTokens, resp.Usage.OutputTokens)
+		ratelimit.Spend(a.provider, resp.Usage.InputTokens+resp.Usage.OutputTokens)
+	}
+
+	return strings.TrimSpace(resp.Text()), nil
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/scipunch/myfeed/agent/quality"
+)
+
+// schemaRetries are the re-prompts after an answer breaking the schema
+const schemaRetries = 2
+
+// WithSchema wraps an agent answering with a JSON object, e.g.,
+// {"summary": "...", "tags": ["go"]}. Answers breaking the schema are
+// re-prompted with what was wrong, valid ones are returned as compact JSON
+// with the fields of the schema only.
+func WithSchema(agent Agent, schema map[string]string) Agent {
+	return &schemaAgent{underlying: agent, schema: schema}
+}
+
+type schemaAgent struct {
+	underlying Agent
+	schema     map[string]string
+}
+
+func (s *schemaAgent) Name() string {
+	return s.underlying.Name()
+}
+
+func (s *schemaAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	feedback := opts.Feedback
+	for attempt := 0; ; attempt++ {
+		result, err := s.underlying.Process(ctx, content, opts)
+		if err != nil {
+			return "", err
+		}
+		fields, violations := SchemaViolations(s.schema, result)
+		if len(violations) == 0 {
+			data, err := json.Marshal(fields)
+			if err != nil {
+				return "", fmt.Errorf("failed to encode answer of agent '%s': %w", s.Name(), err)
+			}
+			return string(data), nil
+		}
+		if attempt == 0 {
+			quality.ContractViolation(s.Name())
+		}
+		if attempt == schemaRetries {
+			return "", fmt.Errorf("agent '%s' broke its output schema: %s", s.Name(), strings.Join(violations, "; "))
+		}
+
+		slog.Warn("agent broke its output schema, retrying",
+			"agent", s.Name(),
+			"attempt", attempt+1,
+			"violations", violations)
+		opts.Feedback = strings.TrimSpace(feedback + "\n" +
+			"Your previous answer broke the required JSON format: " + strings.Join(violations, "; ") +
+			". Answer again with the JSON object only.")
+	}
+}
+
+// SchemaViolations decodes the JSON object of the answer, a code block
+// around it is allowed, and lists the ways it breaks the schema. Fields
+// outside of the schema are dropped.
+func SchemaViolations(schema map[string]string, answer string) (map[string]any, []string) {
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(answer, "```") {
+		answer = strings.TrimPrefix(answer, "```json")
+		answer = strings.TrimPrefix(answer, "```")
+		answer = strings.TrimSpace(strings.TrimSuffix(answer, "```"))
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(answer), &decoded); err != nil {
+		return nil, []string{fmt.Sprintf("it is not a JSON object (%s)", err)}
+	}
+
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make(map[string]any, len(schema))
+	var violations []string
+	for _, name := range names {
+		value, ok := decoded[name]
+		if !ok {
+			violations = append(violations, fmt.Sprintf("field %q is missing", name))
+			continue
+		}
+		if !hasSchemaType(value, schema[name]) {
+			violations = append(violations, fmt.Sprintf("field %q MUST be a %s", name, schemaTypeName(schema[name])))
+			continue
+		}
+		fields[name] = value
+	}
+	return fields, violations
+}
+
+// hasSchemaType reports whether the decoded JSON value is of the type
+func hasSchemaType(value any, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "list":
+		items, ok := value.([]any)
+		return ok && !slices.ContainsFunc(items, func(item any) bool {
+			_, ok := item.(string)
+			return !ok
+		})
+	}
+	return false
+}
+
+// schemaTypeName describes the type of a field to a model, e.g., "list of strings"
+func schemaTypeName(t string) string {
+	if t == "list" {
+		return "list of strings"
+	}
+	return t
+}
+
+// DecodeFields decodes the JSON answer of an agent with a schema into the
+// text field and all of the fields
+func DecodeFields(answer, text string) (string, map[string]any, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(answer), &fields); err != nil {
+		return "", nil, fmt.Errorf("failed to decode answer: %w", err)
+	}
+	value, ok := fields[text].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("answer has no string field %q", text)
+	}
+	return value, fields, nil
+}
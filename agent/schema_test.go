@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// jsonMockAgent answers with its answers in order, the last one repeatedly
+type jsonMockAgent struct {
+	answers   []string
+	calls     int
+	feedbacks []string
+}
+
+func (m *jsonMockAgent) Name() string {
+	return "tagger"
+}
+
+func (m *jsonMockAgent) Process(ctx context.Context, content string, opts Options) (string, error) {
+	m.feedbacks = append(m.feedbacks, opts.Feedback)
+	answer := m.answers[min(m.calls, len(m.answers)-1)]
+	m.calls++
+	return answer, nil
+}
+
+var taggerSchema = map[string]string{"summary": "string", "tags": "list", "score": "number"}
+
+func TestSchemaViolations(t *testing.T) {
+	tests := []struct {
+		name       string
+		answer     string
+		violations []string
+	}{
+		{"valid", `{"summary": "Text", "tags": ["go"], "score": 7, "extra": true}`, nil},
+		{"code block", "```json\n{\"summary\": \"Text\", \"tags\": [], \"score\": 7}\n```", nil},
+		{"free text", "Here is the summary", []string{"it is not a JSON object"}},
+		{"missing field", `{"summary": "Text", "tags": []}`, []string{`field "score" is missing`}},
+		{"wrong types", `{"summary": 1, "tags": [1], "score": 7}`, []string{`field "summary" MUST be a string`, `field "tags" MUST be a list of strings`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, violations := SchemaViolations(taggerSchema, tt.answer)
+			if len(violations) != len(tt.violations) {
+				t.Fatalf("expected %q, got %q", tt.violations, violations)
+			}
+			for i, v := range tt.violations {
+				if !strings.HasPrefix(violations[i], v) {
+					t.Errorf("expected %q, got %q", v, violations[i])
+				}
+			}
+			if len(violations) == 0 && len(fields) != len(taggerSchema) {
+				t.Errorf("expected only the fields of the schema, got %v", fields)
+			}
+		})
+	}
+}
+
+func TestWithSchema_Reprompts(t *testing.T) {
+	mock := &jsonMockAgent{answers: []string{"Not JSON", `{"summary": "Text", "tags": ["go"], "score": 7, "extra": 1}`}}
+	result, err := WithSchema(mock, taggerSchema).Process(context.Background(), "content", Options{})
+	if err != nil {
+		t.Fatalf("expected success after a re-prompt, got error: %v", err)
+	}
+	if result != `{"score":7,"summary":"Text","tags":["go"]}` {
+		t.Errorf("expected compact JSON of the schema fields, got %s", result)
+	}
+	if mock.calls != 2 || !strings.Contains(mock.feedbacks[1], "not a JSON object") {
+		t.Errorf("expected a re-prompt with the violation, got %q", mock.feedbacks)
+	}
+
+	text, fields, err := DecodeFields(result, "summary")
+	if err != nil || text != "Text" || fields["score"] != 7.0 {
+		t.Errorf("expected the text and fields decoded, got %q, %v, %v", text, fields, err)
+	}
+}
+
+func TestWithSchema_GivesUp(t *testing.T) {
+	mock := &jsonMockAgent{answers: []string{"Not JSON"}}
+	if _, err := WithSchema(mock, taggerSchema).Process(context.Background(), "content", Options{}); err == nil {
+		t.Fatal("expected an error for answers breaking the schema")
+	}
+	if mock.calls != schemaRetries+1 {
+		t.Errorf("expected %d calls, got %d", schemaRetries+1, mock.calls)
+	}
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// MaxScore is the score of content matching the interests exactly
+const MaxScore = 10
+
+// scoreNumber matches the first number of an answer, e.g., "7" or "Score: 7.5/10"
+var scoreNumber = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// ParseScore reads the score out of an answer of the relevance agent
+func ParseScore(answer string) (int, error) {
+	match := scoreNumber.FindString(answer)
+	if match == "" {
+		return 0, fmt.Errorf("no score in answer %q", answer)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil || score > MaxScore {
+		return 0, fmt.Errorf("invalid score in answer %q", answer)
+	}
+	return int(math.Round(score)), nil
+}
@@ -0,0 +1,22 @@
+package agent
+
+import "testing"
+
+func TestParseScore(t *testing.T) {
+	for answer, want := range map[string]int{
+		"7":                 7,
+		" 10\n":             10,
+		"Score: 3/10":       3,
+		"6.5":               7,
+		"**0** - off topic": 0,
+	} {
+		if got, err := ParseScore(answer); err != nil || got != want {
+			t.Errorf("%q: expected %d, got %d, %v", answer, want, got, err)
+		}
+	}
+	for _, answer := range []string{"", "Very relevant", "42"} {
+		if _, err := ParseScore(answer); err == nil {
+			t.Errorf("%q: expected an error", answer)
+		}
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"embed"
 	"fmt"
-	"log"
 
 	"github.com/firebase/genkit/go/ai"
 	"github.com/firebase/genkit/go/genkit"
-	"github.com/firebase/genkit/go/plugins/googlegenai"
 
-	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/agent/types"
+	"github.com/scipunch/myfeed/agent/usage"
+	"github.com/scipunch/myfeed/ratelimit"
 )
 
 //go:embed *.prompt
@@ -21,38 +22,36 @@ const (
 	promptName = "summary"
 )
 
-// SummaryAgent uses Gemini to summarize content
+// SummaryAgent uses the model of its provider to summarize content
 type SummaryAgent struct {
-	prompt *ai.Prompt
-	g      *genkit.Genkit
+	prompt   *ai.Prompt
+	g        *genkit.Genkit
+	provider string
 }
 
 // New creates a new summary agent with its own genkit instance.
-// It fails fast if the prompt is not found or Gemini credentials are invalid.
-func New(ctx context.Context, creds config.GeminiCredentials) (*SummaryAgent, error) {
-	if !creds.IsValid() {
-		return nil, fmt.Errorf("invalid Gemini credentials: API key and model must be set")
+// It fails fast if the prompt is not found or the model of the backend is not set.
+func New(ctx context.Context, backend provider.Backend) (*SummaryAgent, error) {
+	if backend.Model == "" {
+		return nil, fmt.Errorf("model of the %s provider must be set", backend.Provider)
 	}
 
-	// Initialize genkit with Google Generative AI plugin
-	g := genkit.Init(ctx,
-		genkit.WithPlugins(&googlegenai.GoogleAI{
-			APIKey: creds.APIKey,
-		}),
+	// Initialize genkit with the plugin of the provider
+	g := provider.Init(ctx, backend,
 		genkit.WithPromptFS(prompts),
 		genkit.WithPromptDir("."),
-		genkit.WithDefaultModel(creds.Model),
 	)
 
 	// Fail fast if prompt wasn't found
 	prompt := genkit.LookupPrompt(g, promptName)
 	if prompt == nil {
-		log.Fatalf("prompt '%s' not found in embedded files", promptName)
+		return nil, fmt.Errorf("prompt '%s' not found in embedded files", promptName)
 	}
 
 	return &SummaryAgent{
-		prompt: &prompt,
-		g:      g,
+		prompt:   &prompt,
+		g:        g,
+		provider: backend.Provider,
 	}, nil
 }
 
@@ -61,13 +60,29 @@ func (a *SummaryAgent) Name() string {
 	return agentName
 }
 
-// Process summarizes the provided content using Gemini
-func (a *SummaryAgent) Process(ctx context.Context, content string) (string, error) {
+// Process summarizes the provided content using the model of its provider
+func (a *SummaryAgent) Process(ctx context.Context, content string, opts types.Options) (string, error) {
+	release, err := ratelimit.Acquire(ctx, a.provider)
+	if err != nil {
+		return "", fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+	defer release()
+
 	resp, err := (*a.prompt).Execute(ctx,
-		ai.WithInput(map[string]any{"content": content}))
+		ai.WithInput(map[string]any{
+			"content":  content,
+			"language": opts.Language,
+			"feedback": opts.Feedback,
+			"title":    opts.Title,
+			"author":   opts.Author,
+		}))
 	if err != nil {
 		return "", fmt.Errorf("failed to execute summary prompt: %w", err)
 	}
+	if resp.Usage != nil {
+		usage.Add(resp.Usage.InputTokens, resp.Usage.OutputTokens)
+		ratelimit.Spend(a.provider, resp.Usage.InputTokens+resp.Usage.OutputTokens)
+	}
 
 	return resp.Text(), nil
 }
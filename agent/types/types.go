@@ -0,0 +1,23 @@
+package types
+
+import "context"
+
+// Agent defines the interface for content processing agents.
+// Agents can perform various transformations on content such as
+// summarization, translation, formatting, or content generation.
+type Agent interface {
+	// Process takes content and returns processed markdown
+	Process(ctx context.Context, content string, opts Options) (string, error)
+
+	// Name returns the agent identifier (e.g., "summary")
+	Name() string
+}
+
+// Options holds per-resource settings passed to every agent call
+type Options struct {
+	Language string   // Language the output must be written in (e.g., "ru"), empty to keep the agent default
+	Feedback string   // Extra instruction appended to the prompt, used for corrective retries
+	Title    string   // Title of the item, empty if unknown
+	Author   string   // Author of the item, empty if unknown
+	Images   []string // Local paths of the photos of the item, described by the describe agent
+}
@@ -0,0 +1,35 @@
+// Package usage accounts tokens spent by agents during the run
+package usage
+
+import "sync"
+
+// Tokens counts model tokens
+type Tokens struct {
+	Input  int
+	Output int
+}
+
+// Sum returns input and output tokens together
+func (t Tokens) Sum() int {
+	return t.Input + t.Output
+}
+
+var (
+	mu    sync.Mutex
+	total Tokens
+)
+
+// Add records tokens of a single model call
+func Add(input, output int) {
+	mu.Lock()
+	defer mu.Unlock()
+	total.Input += input
+	total.Output += output
+}
+
+// Total returns tokens spent since the process started
+func Total() Tokens {
+	mu.Lock()
+	defer mu.Unlock()
+	return total
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+)
+
+// needsBackfill returns true if the feed history was never archived
+func needsBackfill(ctx context.Context, queries *db.Queries, feedURL string) bool {
+	count, err := queries.CountArchiveItems(ctx, feedURL)
+	if err != nil {
+		slog.Warn("failed to count archived items", "url", feedURL, "error", err)
+		return false
+	}
+	return count == 0
+}
+
+// archiveItems stores feed items in the archive, skipping already archived ones
+func archiveItems(ctx context.Context, queries *db.Queries, feedURL string, items []fetcher.FeedItem) error {
+	archivedAt := time.Now().Unix()
+	for _, item := range items {
+		guid := item.GUID
+		if guid == "" {
+			guid = item.Link
+		}
+		if guid == "" {
+			continue
+		}
+		var published int64
+		if !item.Published.IsZero() {
+			published = item.Published.Unix()
+		}
+		err := queries.SaveArchiveItem(ctx, db.SaveArchiveItemParams{
+			FeedUrl:     feedURL,
+			Guid:        guid,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PublishedAt: published,
+			ArchivedAt:  archivedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive '%s' with %w", guid, err)
+		}
+	}
+	return nil
+}
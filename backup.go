@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/backup"
+	"github.com/scipunch/myfeed/config"
+)
+
+// Names of the state files inside a backup
+const (
+	backupConfig   = "config.toml"
+	backupRules    = "rules.toml"
+	backupCreds    = "creds"
+	backupSession  = "creds/telegram-session.json"
+	backupDatabase = "data.db"
+	backupOutputs  = "outputs"
+)
+
+var tableRe = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+
+// runBackup handles `backup create <file>` and `backup restore <file>`
+func runBackup(ctx context.Context, cfgPath string, args []string) error {
+	usage := errors.New("usage: myfeed backup create [-creds] [-outputs 30d] <file.tar.zst> | myfeed backup restore [-force] <file.tar.zst>")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("backup create", flag.ExitOnError)
+		creds := flags.Bool("creds", false, "include credentials and the Telegram session")
+		outputs := config.Duration{Duration: 30 * 24 * time.Hour}
+		flags.TextVar(&outputs, "outputs", outputs, "include issues generated within this period, e.g. '7d' (0 = none)")
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			return usage
+		}
+		return createBackup(ctx, cfgPath, flags.Arg(0), *creds, outputs.Duration)
+	case "restore":
+		flags := flag.NewFlagSet("backup restore", flag.ExitOnError)
+		force := flags.Bool("force", false, "replace existing config, credentials and database")
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			return usage
+		}
+		return restoreBackup(cfgPath, flags.Arg(0), *force)
+	}
+	return usage
+}
+
+// createBackup writes the config, source rules, database with the cache,
+// recent issues and optionally credentials into a zstd-compressed tarball
+func createBackup(ctx context.Context, cfgPath, file string, creds bool, outputs time.Duration) error {
+	conf, err := config.Read(cfgPath)
+	if err != nil {
+		return err
+	}
+	m := backup.Manifest{
+		Format:      backup.Format,
+		CreatedAt:   time.Now(),
+		Credentials: creds,
+		Entries:     []backup.Entry{{Name: backupConfig, Path: cfgPath}},
+	}
+	if rules := sourceRulesPath(cfgPath, conf); exists(rules) {
+		m.Entries = append(m.Entries, backup.Entry{Name: backupRules, Path: rules})
+	}
+	if creds {
+		credPath, err := config.DefaultCredentialsPath()
+		if err != nil {
+			return fmt.Errorf("failed to locate credentials with %w", err)
+		}
+		if exists(credPath) {
+			m.Entries = append(m.Entries, backup.Entry{Name: backupCreds + "/" + filepath.Base(credPath), Path: credPath})
+		}
+		if session := telegramSessionPath(cfgPath); exists(session) {
+			m.Entries = append(m.Entries, backup.Entry{Name: backupSession, Path: session})
+		}
+	}
+
+	// Copy the database through SQLite so writes of a running daemon are
+	// either fully in the backup or not at all
+	if exists(conf.DatabasePath) {
+		tmp, err := os.MkdirTemp("", "myfeed-backup")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		snapshot := filepath.Join(tmp, backupDatabase)
+		if m.Tables, err = snapshotDatabase(ctx, conf.DatabasePath, snapshot); err != nil {
+			return err
+		}
+		m.Entries = append(m.Entries, backup.Entry{Name: backupDatabase, Path: conf.DatabasePath, Source: snapshot})
+	}
+
+	if outputs > 0 {
+		issues, err := recentIssues(conf.OutputDirectory, time.Now().Add(-outputs))
+		if err != nil {
+			return err
+		}
+		for _, dir := range issues {
+			m.Entries = append(m.Entries, backup.Entry{
+				Name: backupOutputs + "/" + dir,
+				Path: filepath.Join(conf.OutputDirectory, dir),
+			})
+		}
+	}
+
+	out, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup with %w", err)
+	}
+	if err := backup.Create(out, m); err != nil {
+		out.Close()
+		os.Remove(file)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	slog.Info("backup created", "path", file, "entries", len(m.Entries), "credentials", creds)
+	return nil
+}
+
+// snapshotDatabase copies the database into dst and returns its tables
+func snapshotDatabase(ctx context.Context, src, dst string) ([]string, error) {
+	database, err := sql.Open("sqlite", src+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database at '%s' with %w", src, err)
+	}
+	defer database.Close()
+	if _, err := database.ExecContext(ctx, "VACUUM INTO ?", dst); err != nil {
+		return nil, fmt.Errorf("failed to copy database with %w", err)
+	}
+
+	rows, err := database.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables with %w", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// recentIssues lists the dated issue directories generated since
+func recentIssues(outputDir string, since time.Time) ([]string, error) {
+	entries, err := os.ReadDir(outputDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list output directory with %w", err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		date, err := time.ParseInLocation("2006_01_02", entry.Name(), time.Local)
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		if !date.AddDate(0, 0, 1).Before(since) {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	return dirs, nil
+}
+
+// restoreBackup puts the files of a backup where the restored config expects
+// them. Existing files are only replaced with force.
+func restoreBackup(cfgPath, file string, force bool) error {
+	// The config is read first to locate the database and the issues
+	var conf config.Config
+	var m backup.Manifest
+	err := readBackup(file, func(r *backup.Reader, manifest backup.Manifest) error {
+		if err := manifest.Check(knownTables()); err != nil {
+			return err
+		}
+		data, err := r.ReadFile(backupConfig)
+		if err != nil {
+			return err
+		}
+		if conf, err = config.Parse(data); err != nil {
+			return fmt.Errorf("failed to decode config of the backup with %w", err)
+		}
+		m = manifest
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if conf.OutputDirectory == "" {
+		conf.OutputDirectory = filepath.Join(os.Getenv("HOME"), "myfeed")
+	}
+	credPath, err := config.DefaultCredentialsPath()
+	if err != nil {
+		return fmt.Errorf("failed to locate credentials with %w", err)
+	}
+
+	// The session is listed before the credentials directory containing it
+	entries := []backup.Entry{
+		{Name: backupConfig, Path: cfgPath},
+		{Name: backupRules, Path: sourceRulesPath(cfgPath, conf)},
+		{Name: backupSession, Path: telegramSessionPath(cfgPath)},
+		{Name: backupCreds, Path: filepath.Dir(credPath)},
+		{Name: backupDatabase, Path: conf.DatabasePath},
+		{Name: backupOutputs, Path: conf.OutputDirectory},
+	}
+	if !force {
+		var existing []string
+		for _, e := range m.Entries {
+			target, ok := backup.Target(entries, e.Name)
+			if ok && !strings.HasPrefix(e.Name, backupOutputs+"/") && exists(target) {
+				existing = append(existing, target)
+			}
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("refusing to replace %s, pass -force to restore anyway", strings.Join(existing, ", "))
+		}
+	}
+
+	return readBackup(file, func(r *backup.Reader, _ backup.Manifest) error {
+		written, err := r.Extract(entries)
+		if err != nil {
+			return err
+		}
+		slog.Info("backup restored",
+			"files", len(written),
+			"created_at", m.CreatedAt.Format(time.DateTime),
+			"credentials", m.Credentials)
+		return nil
+	})
+}
+
+// readBackup opens the backup and passes it to read
+func readBackup(file string, read func(*backup.Reader, backup.Manifest) error) error {
+	in, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open backup with %w", err)
+	}
+	defer in.Close()
+	r, m, err := backup.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return read(r, m)
+}
+
+// knownTables lists the tables of the database schema
+func knownTables() []string {
+	var tables []string
+	for _, match := range tableRe.FindAllStringSubmatch(ddl, -1) {
+		tables = append(tables, match[1])
+	}
+	return tables
+}
+
+// sourceRulesPath returns the per-domain cleanup rules file of the config
+func sourceRulesPath(cfgPath string, conf config.Config) string {
+	rulesPath := conf.SourceRules
+	if rulesPath == "" {
+		rulesPath = "rules.toml"
+	}
+	if !filepath.IsAbs(rulesPath) {
+		rulesPath = filepath.Join(filepath.Dir(cfgPath), rulesPath)
+	}
+	return rulesPath
+}
+
+// telegramSessionPath returns the Telegram session file next to the config
+func telegramSessionPath(cfgPath string) string {
+	return filepath.Join(filepath.Dir(cfgPath), "telegram-session.json")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
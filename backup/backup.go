@@ -0,0 +1,239 @@
+// Package backup bundles the state of myfeed into a zstd-compressed tarball
+// and restores it on another host.
+package backup
+
+import (
+	"archive/tar"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format is the version of the archive layout, archives of a newer format
+// are not restored
+const Format = 1
+
+// manifestName is the first file of every archive
+const manifestName = "manifest.json"
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	Format      int       `json:"format"`
+	CreatedAt   time.Time `json:"created_at"`
+	Tables      []string  `json:"tables"`      // Database tables, newer versions of myfeed may add more
+	Credentials bool      `json:"credentials"` // Whether credentials are included
+	Entries     []Entry   `json:"entries"`
+}
+
+// Entry is a file or directory of the archive
+type Entry struct {
+	Name   string `json:"name"` // Path inside the archive, e.g., "config.toml" or "outputs"
+	Path   string `json:"path"` // Location on the host it was taken from
+	Source string `json:"-"`    // File read instead of Path, e.g., a snapshot of the database
+}
+
+// Check returns an error when the archive can't be restored by this version
+// of myfeed, known lists the database tables it knows about
+func (m Manifest) Check(known []string) error {
+	if m.Format > Format {
+		return fmt.Errorf("backup has format %d, this version of myfeed restores up to %d, update myfeed first", m.Format, Format)
+	}
+	var unknown []string
+	for _, table := range m.Tables {
+		if !slices.Contains(known, table) {
+			unknown = append(unknown, table)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("backup was made by a newer version of myfeed with tables %s, update myfeed first", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Create writes the manifest and its entries to w, directories are added
+// with everything inside them
+func Create(w io.Writer, m Manifest) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("failed to create zstd writer with %w", err)
+	}
+	tw := tar.NewWriter(zw)
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest with %w", err)
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    manifestName,
+		Mode:    0o644,
+		Size:    int64(len(manifest)),
+		ModTime: m.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+
+	for _, entry := range m.Entries {
+		source := cmp.Or(entry.Source, entry.Path)
+		err := filepath.WalkDir(source, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(source, p)
+			if err != nil {
+				return err
+			}
+			return addFile(tw, path.Join(entry.Name, filepath.ToSlash(rel)), p)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add '%s' with %w", entry.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func addFile(tw *tar.Writer, name, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Reader reads an archive written by Create
+type Reader struct {
+	zr *zstd.Decoder
+	tr *tar.Reader
+}
+
+// NewReader reads the archive from r, the manifest comes first
+func NewReader(r io.Reader) (*Reader, Manifest, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, Manifest{}, fmt.Errorf("failed to create zstd reader with %w", err)
+	}
+	tr := tar.NewReader(zr)
+	header, err := tr.Next()
+	if err != nil {
+		zr.Close()
+		return nil, Manifest{}, fmt.Errorf("failed to read backup with %w", err)
+	}
+	if header.Name != manifestName {
+		zr.Close()
+		return nil, Manifest{}, fmt.Errorf("not a myfeed backup, it starts with '%s'", header.Name)
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		zr.Close()
+		return nil, Manifest{}, fmt.Errorf("failed to decode manifest with %w", err)
+	}
+	return &Reader{zr: zr, tr: tr}, m, nil
+}
+
+// Close releases the decoder
+func (r *Reader) Close() {
+	r.zr.Close()
+}
+
+// ReadFile returns the content of the named file, files before it are skipped
+func (r *Reader) ReadFile(name string) ([]byte, error) {
+	for {
+		header, err := r.tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("'%s' is not in the backup", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name == name {
+			return io.ReadAll(r.tr)
+		}
+	}
+}
+
+// Extract writes the remaining files to the locations entries map them to,
+// replacing existing ones. Files without an entry are skipped.
+func (r *Reader) Extract(entries []Entry) ([]string, error) {
+	var written []string
+	for {
+		header, err := r.tr.Next()
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, ok := Target(entries, header.Name)
+		if !ok {
+			continue
+		}
+		if err := extractFile(r.tr, target, header.FileInfo().Mode().Perm()); err != nil {
+			return written, fmt.Errorf("failed to restore '%s' with %w", target, err)
+		}
+		written = append(written, target)
+	}
+}
+
+// Target returns where the named file of the archive is restored to
+func Target(entries []Entry, name string) (string, bool) {
+	for _, entry := range entries {
+		if name == entry.Name {
+			return entry.Path, true
+		}
+		rel, ok := strings.CutPrefix(name, entry.Name+"/")
+		if ok && filepath.IsLocal(rel) {
+			return filepath.Join(entry.Path, filepath.FromSlash(rel)), true
+		}
+	}
+	return "", false
+}
+
+func extractFile(r io.Reader, target string, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
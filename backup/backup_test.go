@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	cfg := write("config.toml", "output_directory = '/tmp'")
+	snapshot := write("snapshot.db", "db")
+	write("out/2025_03_01/issue.html", "<html>")
+	write("out/2025_03_01/media/a.png", "png")
+
+	m := Manifest{
+		Format:    Format,
+		CreatedAt: time.Now(),
+		Tables:    []string{"feed"},
+		Entries: []Entry{
+			{Name: "config.toml", Path: cfg},
+			{Name: "data.db", Path: "/var/lib/myfeed/data.db", Source: snapshot},
+			{Name: "outputs/2025_03_01", Path: filepath.Join(src, "out", "2025_03_01")},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Create(&buf, m); err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+
+	r, got, err := NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	defer r.Close()
+	if got.Format != Format || len(got.Entries) != 3 || got.Entries[1].Path != "/var/lib/myfeed/data.db" || got.Entries[1].Source != "" {
+		t.Errorf("unexpected manifest %+v", got)
+	}
+	data, err := r.ReadFile("config.toml")
+	if err != nil || string(data) != "output_directory = '/tmp'" {
+		t.Fatalf("expected the config, got %q: %v", data, err)
+	}
+
+	dst := t.TempDir()
+	written, err := r.Extract([]Entry{
+		{Name: "data.db", Path: filepath.Join(dst, "db", "data.db")},
+		{Name: "outputs", Path: filepath.Join(dst, "issues")},
+	})
+	if err != nil {
+		t.Fatalf("failed to extract: %v", err)
+	}
+	if len(written) != 3 {
+		t.Errorf("expected the database and two issue files, got %v", written)
+	}
+	for name, want := range map[string]string{
+		"db/data.db":                    "db",
+		"issues/2025_03_01/issue.html":  "<html>",
+		"issues/2025_03_01/media/a.png": "png",
+	} {
+		got, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s: expected %q, got %q: %v", name, want, got, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dst, "db", "data.db")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected permissions to be kept, got %v", info.Mode())
+	}
+}
+
+func TestNewReader_NotABackup(t *testing.T) {
+	if _, _, err := NewReader(strings.NewReader("plain text")); err == nil {
+		t.Error("expected an error for a file that isn't a backup")
+	}
+}
+
+func TestManifestCheck(t *testing.T) {
+	known := []string{"feed", "queue_item"}
+	tests := []struct {
+		name    string
+		m       Manifest
+		wantErr string
+	}{
+		{"same version", Manifest{Format: Format, Tables: known}, ""},
+		{"older version", Manifest{Format: Format, Tables: known[:1]}, ""},
+		{"newer format", Manifest{Format: Format + 1}, "update myfeed"},
+		{"unknown tables", Manifest{Format: Format, Tables: []string{"feed", "shiny"}}, "shiny"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.m.Check(known)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTarget(t *testing.T) {
+	entries := []Entry{
+		{Name: "creds/telegram-session.json", Path: "/home/me/.config/myfeed/telegram-session.json"},
+		{Name: "creds", Path: "/home/me/.config/myfeed"},
+	}
+	for name, want := range map[string]string{
+		"creds/telegram-session.json": "/home/me/.config/myfeed/telegram-session.json",
+		"creds/creds.toml.age":        "/home/me/.config/myfeed/creds.toml.age",
+		"creds/../../.bashrc":         "",
+		"unknown.txt":                 "",
+	} {
+		got, ok := Target(entries, name)
+		if got != want || ok != (want != "") {
+			t.Errorf("Target(%q) = %q, %v, want %q", name, got, ok, want)
+		}
+	}
+}
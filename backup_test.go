@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/db"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	cfgDir := filepath.Join(dir, "config", "myfeed")
+	dbPath := filepath.Join(dir, "data", "data.db")
+	outputDir := filepath.Join(dir, "issues")
+	today := time.Now().Format("2006_01_02")
+	for path, content := range map[string]string{
+		filepath.Join(cfgDir, "config.toml"):                             fmt.Sprintf("database_path = %q\noutput_directory = %q\n", dbPath, outputDir),
+		filepath.Join(cfgDir, "creds.toml"):                              "[gemini]",
+		filepath.Join(cfgDir, "telegram-session.json"):                   "{}",
+		filepath.Join(outputDir, today, "myfeed_"+today+".html"):         "<html>",
+		filepath.Join(outputDir, "2020_01_01", "myfeed_2020_01_01.html"): "<html>",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	database, err := initDB(ctx, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.New(database).SavePushLease(ctx, db.SavePushLeaseParams{FeedUrl: "https://a.example/feed", ExpiresAt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	database.Close()
+
+	cfgPath := filepath.Join(cfgDir, "config.toml")
+	file := filepath.Join(t.TempDir(), "backup.tar.zst")
+	if err := runBackup(ctx, cfgPath, []string{"create", "-creds", "-outputs", "7d", file}); err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	if err := runBackup(ctx, cfgPath, []string{"restore", file}); err == nil || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("expected existing files not to be replaced, got %v", err)
+	}
+
+	// Lose everything and restore it
+	for _, path := range []string{cfgDir, filepath.Dir(dbPath), outputDir} {
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := runBackup(ctx, cfgPath, []string{"restore", file}); err != nil {
+		t.Fatalf("failed to restore backup: %v", err)
+	}
+	for _, path := range []string{
+		cfgPath,
+		filepath.Join(cfgDir, "creds.toml"),
+		filepath.Join(cfgDir, "telegram-session.json"),
+		filepath.Join(outputDir, today, "myfeed_"+today+".html"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be restored: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "2020_01_01")); err == nil {
+		t.Error("expected issues older than -outputs not to be backed up")
+	}
+
+	database, err = initDB(ctx, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if expires, err := db.New(database).GetPushLease(ctx, "https://a.example/feed"); err != nil || expires != 1 {
+		t.Errorf("expected the database to be restored, got %d: %v", expires, err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package breaker stops calling providers that keep failing within a run,
+// so the remaining items don't spend the whole retry budget on each of them.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrOpen is returned for calls to a provider with an open circuit
+var ErrOpen = errors.New("circuit open")
+
+// OpenError tells which provider is skipped and why
+type OpenError struct {
+	Key string
+	Err error // Last failure before the circuit opened
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit of '%s' is open after repeated failures: %v", e.Key, e.Err)
+}
+
+func (e *OpenError) Is(target error) bool { return target == ErrOpen }
+
+// Breaker counts consecutive failures by provider key, e.g., ratelimit keys
+// like "gemini" or "host:example.com". A circuit stays open until the
+// breaker is discarded, i.e., for the rest of the run.
+type Breaker struct {
+	threshold int
+
+	mu       sync.Mutex
+	failures map[string]int
+	open     map[string]error
+}
+
+// New creates a breaker opening after threshold consecutive failures,
+// a threshold of 0 or less never opens
+func New(threshold int) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		failures:  make(map[string]int),
+		open:      make(map[string]error),
+	}
+}
+
+// Allow returns an *OpenError when calls to the provider are skipped
+func (b *Breaker) Allow(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err, ok := b.open[key]; ok {
+		return &OpenError{Key: key, Err: err}
+	}
+	return nil
+}
+
+// Record counts the outcome of a call, it returns true when the failure opened the circuit
+func (b *Breaker) Record(key string, err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		delete(b.failures, key)
+		return false
+	}
+	if b.threshold <= 0 || errors.Is(err, ErrOpen) {
+		return false
+	}
+	if _, ok := b.open[key]; ok {
+		return false
+	}
+	b.failures[key]++
+	if b.failures[key] < b.threshold {
+		return false
+	}
+	b.open[key] = err
+	return true
+}
+
+// Open returns the last failure by key of every open circuit
+func (b *Breaker) Open() map[string]error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	open := make(map[string]error, len(b.open))
+	for key, err := range b.open {
+		open[key] = err
+	}
+	return open
+}
@@ -0,0 +1,55 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBreaker(t *testing.T) {
+	b := New(3)
+	failure := errors.New("503 service unavailable")
+
+	// A success in between resets the count
+	b.Record("gemini", failure)
+	b.Record("gemini", failure)
+	b.Record("gemini", nil)
+	if b.Record("gemini", failure) || b.Record("gemini", failure) {
+		t.Fatal("expected the circuit to stay closed below the threshold")
+	}
+	if err := b.Allow("gemini"); err != nil {
+		t.Fatalf("expected calls to be allowed, got %v", err)
+	}
+
+	if !b.Record("gemini", failure) {
+		t.Fatal("expected the third failure in a row to open the circuit")
+	}
+	err := b.Allow("gemini")
+	if !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+	var openErr *OpenError
+	if !errors.As(err, &openErr) || openErr.Key != "gemini" || openErr.Err != failure {
+		t.Errorf("expected the key and last failure, got %+v", openErr)
+	}
+
+	// Refused calls don't count, other providers are not affected
+	if b.Record("gemini", err) {
+		t.Error("expected refused calls not to reopen the circuit")
+	}
+	if err := b.Allow("host:example.com"); err != nil {
+		t.Errorf("expected other providers to be allowed, got %v", err)
+	}
+	if open := b.Open(); len(open) != 1 || open["gemini"] != failure {
+		t.Errorf("expected only gemini open, got %v", open)
+	}
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := New(0)
+	for range 10 {
+		b.Record("gemini", errors.New("failure"))
+	}
+	if err := b.Allow("gemini"); err != nil {
+		t.Errorf("expected a disabled breaker to never open, got %v", err)
+	}
+}
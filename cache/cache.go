@@ -11,6 +11,8 @@ import (
 	"time"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/scipunch/myfeed/urlnorm"
 )
 
 // Cache provides caching for parser and agent outputs using sqlc-generated queries
@@ -61,15 +63,24 @@ func NewCacheFromDB(db *sql.DB) (*Cache, error) {
 	}, nil
 }
 
-// GetParserOutput retrieves cached parser output
+// GetParserOutput retrieves cached parser output, keyed by the canonical URL
 // Returns: (output, found, error)
 func (c *Cache) GetParserOutput(url, parserType string) ([]byte, bool, error) {
 	ctx := context.Background()
 
+	key := urlnorm.Canonical(url)
 	output, err := c.queries.GetParserOutput(ctx, GetParserOutputParams{
-		Url:        url,
+		Url:        key,
 		ParserType: parserType,
 	})
+	if err == sql.ErrNoRows && key != url {
+		// Entries cached before keys were canonical
+		key = url
+		output, err = c.queries.GetParserOutput(ctx, GetParserOutputParams{
+			Url:        key,
+			ParserType: parserType,
+		})
+	}
 
 	if err == sql.ErrNoRows {
 		return nil, false, nil
@@ -83,7 +94,7 @@ func (c *Cache) GetParserOutput(url, parserType string) ([]byte, bool, error) {
 	accessedAt := time.Now().Unix()
 	_ = c.queries.UpdateParserAccessTime(ctx, UpdateParserAccessTimeParams{
 		AccessedAt: accessedAt,
-		Url:        url,
+		Url:        key,
 		ParserType: parserType,
 	})
 
@@ -96,7 +107,7 @@ func (c *Cache) SetParserOutput(url, parserType string, output []byte) error {
 	now := time.Now().Unix()
 
 	err := c.queries.SetParserOutput(ctx, SetParserOutputParams{
-		Url:        url,
+		Url:        urlnorm.Canonical(url),
 		ParserType: parserType,
 		OutputData: string(output),
 		CreatedAt:  now,
@@ -111,17 +122,27 @@ func (c *Cache) SetParserOutput(url, parserType string, output []byte) error {
 	return nil
 }
 
-// GetAgentOutput retrieves cached agent output
+// GetAgentOutput retrieves cached agent output, keyed by the canonical URL
 // agentPipeline should be slice of agent names (e.g., ["summary", "translate"])
 func (c *Cache) GetAgentOutput(url, parserType string, agentPipeline []string) (string, bool, error) {
 	ctx := context.Background()
-	pipeline := strings.Join(agentPipeline, ",")
+	pipeline := pipelineKey(parserType, agentPipeline)
 
+	key := urlnorm.Canonical(url)
 	output, err := c.queries.GetAgentOutput(ctx, GetAgentOutputParams{
-		Url:           url,
+		Url:           key,
 		ParserType:    parserType,
 		AgentPipeline: pipeline,
 	})
+	if err == sql.ErrNoRows && key != url {
+		// Entries cached before keys were canonical
+		key = url
+		output, err = c.queries.GetAgentOutput(ctx, GetAgentOutputParams{
+			Url:           key,
+			ParserType:    parserType,
+			AgentPipeline: pipeline,
+		})
+	}
 
 	if err == sql.ErrNoRows {
 		return "", false, nil
@@ -135,7 +156,7 @@ func (c *Cache) GetAgentOutput(url, parserType string, agentPipeline []string) (
 	accessedAt := time.Now().Unix()
 	_ = c.queries.UpdateAgentAccessTime(ctx, UpdateAgentAccessTimeParams{
 		AccessedAt:    accessedAt,
-		Url:           url,
+		Url:           key,
 		ParserType:    parserType,
 		AgentPipeline: pipeline,
 	})
@@ -147,10 +168,10 @@ func (c *Cache) GetAgentOutput(url, parserType string, agentPipeline []string) (
 func (c *Cache) SetAgentOutput(url, parserType string, agentPipeline []string, output string) error {
 	ctx := context.Background()
 	now := time.Now().Unix()
-	pipeline := strings.Join(agentPipeline, ",")
+	pipeline := pipelineKey(parserType, agentPipeline)
 
 	err := c.queries.SetAgentOutput(ctx, SetAgentOutputParams{
-		Url:           url,
+		Url:           urlnorm.Canonical(url),
 		ParserType:    parserType,
 		AgentPipeline: pipeline,
 		OutputData:    output,
@@ -166,6 +187,17 @@ func (c *Cache) SetAgentOutput(url, parserType string, agentPipeline []string, o
 	return nil
 }
 
+// pipelineKey identifies the agent pipeline together with the parser
+// version, so agent outputs follow parser output invalidation. Version 1
+// is omitted to keep keys of entries written before versioning.
+func pipelineKey(parserType string, agentPipeline []string) string {
+	key := strings.Join(agentPipeline, ",")
+	if version := ParserVersion(parserType); version > 1 {
+		key += fmt.Sprintf(",parser=v%d", version)
+	}
+	return key
+}
+
 // Clear removes all cache entries
 func (c *Cache) Clear() error {
 	ctx := context.Background()
@@ -179,6 +211,38 @@ func (c *Cache) Clear() error {
 	return nil
 }
 
+// Prune removes entries not accessed since before.
+// With dryRun set nothing is deleted and only counts are returned.
+func (c *Cache) Prune(before time.Time, dryRun bool) (CacheStats, error) {
+	ctx := context.Background()
+	var stats CacheStats
+	cutoff := before.Unix()
+
+	parserCount, err := c.queries.CountParserEntriesBefore(ctx, cutoff)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count parser cache entries: %w", err)
+	}
+	stats.ParserEntries = int(parserCount)
+
+	agentCount, err := c.queries.CountAgentEntriesBefore(ctx, cutoff)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count agent cache entries: %w", err)
+	}
+	stats.AgentEntries = int(agentCount)
+
+	if dryRun {
+		return stats, nil
+	}
+
+	if err := c.queries.DeleteParserEntriesBefore(ctx, cutoff); err != nil {
+		return stats, fmt.Errorf("failed to prune parser cache: %w", err)
+	}
+	if err := c.queries.DeleteAgentEntriesBefore(ctx, cutoff); err != nil {
+		return stats, fmt.Errorf("failed to prune agent cache: %w", err)
+	}
+	return stats, nil
+}
+
 // Stats returns cache statistics
 func (c *Cache) Stats() (CacheStats, error) {
 	ctx := context.Background()
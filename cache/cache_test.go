@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonicalKeys(t *testing.T) {
+	c, err := NewCache(filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.db.Exec(`CREATE TABLE parser_cache (
+		url TEXT NOT NULL, parser_type TEXT NOT NULL, output_data TEXT NOT NULL,
+		created_at INTEGER NOT NULL, accessed_at INTEGER NOT NULL, UNIQUE(url, parser_type))`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SetParserOutput("http://www.example.com/post/?utm_source=rss", "web", []byte("parsed")); err != nil {
+		t.Fatal(err)
+	}
+	if out, hit, _ := c.GetParserOutput("https://example.com/post#comments", "web"); !hit || string(out) != "parsed" {
+		t.Errorf("expected the same article under another URL to hit, got %q, %v", out, hit)
+	}
+
+	// Entries cached before keys were canonical are still found
+	if _, err := c.db.Exec(`INSERT INTO parser_cache VALUES ('https://old.example/a/', 'web', 'legacy', 0, 0)`); err != nil {
+		t.Fatal(err)
+	}
+	if out, hit, _ := c.GetParserOutput("https://old.example/a/", "web"); !hit || string(out) != "legacy" {
+		t.Errorf("expected the legacy entry, got %q, %v", out, hit)
+	}
+}
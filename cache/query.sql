@@ -55,3 +55,22 @@ FROM (
     UNION ALL
     SELECT created_at FROM agent_cache
 );
+
+
+-- Retention Queries
+
+-- name: CountParserEntriesBefore :one
+SELECT COUNT(*) FROM parser_cache
+WHERE accessed_at < ?;
+
+-- name: DeleteParserEntriesBefore :exec
+DELETE FROM parser_cache
+WHERE accessed_at < ?;
+
+-- name: CountAgentEntriesBefore :one
+SELECT COUNT(*) FROM agent_cache
+WHERE accessed_at < ?;
+
+-- name: DeleteAgentEntriesBefore :exec
+DELETE FROM agent_cache
+WHERE accessed_at < ?;
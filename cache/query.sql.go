@@ -20,6 +20,18 @@ func (q *Queries) CountAgentEntries(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const countAgentEntriesBefore = `-- name: CountAgentEntriesBefore :one
+SELECT COUNT(*) FROM agent_cache
+WHERE accessed_at < ?
+`
+
+func (q *Queries) CountAgentEntriesBefore(ctx context.Context, accessedAt int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAgentEntriesBefore, accessedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countParserEntries = `-- name: CountParserEntries :one
 SELECT COUNT(*) FROM parser_cache
 `
@@ -31,6 +43,18 @@ func (q *Queries) CountParserEntries(ctx context.Context) (int64, error) {
 	return count, err
 }
 
+const countParserEntriesBefore = `-- name: CountParserEntriesBefore :one
+SELECT COUNT(*) FROM parser_cache
+WHERE accessed_at < ?
+`
+
+func (q *Queries) CountParserEntriesBefore(ctx context.Context, accessedAt int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countParserEntriesBefore, accessedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deleteAgentCache = `-- name: DeleteAgentCache :exec
 DELETE FROM agent_cache
 `
@@ -40,6 +64,16 @@ func (q *Queries) DeleteAgentCache(ctx context.Context) error {
 	return err
 }
 
+const deleteAgentEntriesBefore = `-- name: DeleteAgentEntriesBefore :exec
+DELETE FROM agent_cache
+WHERE accessed_at < ?
+`
+
+func (q *Queries) DeleteAgentEntriesBefore(ctx context.Context, accessedAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteAgentEntriesBefore, accessedAt)
+	return err
+}
+
 const deleteParserCache = `-- name: DeleteParserCache :exec
 DELETE FROM parser_cache
 `
@@ -49,6 +83,16 @@ func (q *Queries) DeleteParserCache(ctx context.Context) error {
 	return err
 }
 
+const deleteParserEntriesBefore = `-- name: DeleteParserEntriesBefore :exec
+DELETE FROM parser_cache
+WHERE accessed_at < ?
+`
+
+func (q *Queries) DeleteParserEntriesBefore(ctx context.Context, accessedAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteParserEntriesBefore, accessedAt)
+	return err
+}
+
 const getAgentOutput = `-- name: GetAgentOutput :one
 
 SELECT output_data
@@ -2,20 +2,42 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/parser/command"
 	"github.com/scipunch/myfeed/parser/telegram"
 	"github.com/scipunch/myfeed/parser/web"
 	"github.com/scipunch/myfeed/parser/youtube"
 )
 
+// ErrStaleVersion is returned for cached outputs of an earlier parser version
+var ErrStaleVersion = errors.New("cached output of an earlier parser version")
+
 // CachedResponse wraps parser responses for serialization
 type CachedResponse struct {
 	ParserType string          `json:"parser_type"`
+	Version    int             `json:"version,omitempty"` // Parser version, entries without one are version 1
 	Data       json.RawMessage `json:"data"`
 }
 
+// ParserVersion returns the current output version of the parser
+func ParserVersion(parserType string) int {
+	switch parserType {
+	case parser.Web:
+		return web.Version
+	case parser.YouTube:
+		return youtube.Version
+	case parser.Telegram:
+		return telegram.Version
+	case parser.Command:
+		return command.Version
+	default:
+		return 1
+	}
+}
+
 // SerializeParserResponse converts parser.Response to JSON bytes
 func SerializeParserResponse(parserType string, resp parser.Response) ([]byte, error) {
 	var data []byte
@@ -43,6 +65,13 @@ func SerializeParserResponse(parserType string, resp parser.Response) ([]byte, e
 		}
 		data, err = json.Marshal(tgResp)
 
+	case parser.Command:
+		cmdResp, ok := resp.(command.Response)
+		if !ok {
+			return nil, fmt.Errorf("expected command.Response, got %T", resp)
+		}
+		data, err = json.Marshal(cmdResp)
+
 	default:
 		return nil, fmt.Errorf("unknown parser type: %s", parserType)
 	}
@@ -53,6 +82,7 @@ func SerializeParserResponse(parserType string, resp parser.Response) ([]byte, e
 
 	cached := CachedResponse{
 		ParserType: parserType,
+		Version:    ParserVersion(parserType),
 		Data:       data,
 	}
 
@@ -70,6 +100,10 @@ func DeserializeParserResponse(parserType string, data []byte) (parser.Response,
 		return nil, fmt.Errorf("parser type mismatch: cached=%s, expected=%s", cached.ParserType, parserType)
 	}
 
+	if version := max(cached.Version, 1); version != ParserVersion(parserType) {
+		return nil, fmt.Errorf("%w: cached=%d, current=%d", ErrStaleVersion, version, ParserVersion(parserType))
+	}
+
 	switch parserType {
 	case parser.Web:
 		var resp web.Response
@@ -92,6 +126,13 @@ func DeserializeParserResponse(parserType string, data []byte) (parser.Response,
 		}
 		return resp, nil
 
+	case parser.Command:
+		var resp command.Response
+		if err := json.Unmarshal(cached.Data, &resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal command response: %w", err)
+		}
+		return resp, nil
+
 	default:
 		return nil, fmt.Errorf("unknown parser type: %s", parserType)
 	}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/parser/telegram"
+	"github.com/scipunch/myfeed/parser/web"
+)
+
+func TestDeserializeParserResponseVersion(t *testing.T) {
+	data, err := SerializeParserResponse(parser.Telegram, telegram.Response{HTML: "<p>hi</p>"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := DeserializeParserResponse(parser.Telegram, data)
+	if r, ok := resp.(telegram.Response); err != nil || !ok || r.HTML != "<p>hi</p>" {
+		t.Fatalf("expected round trip, got %v, %v", resp, err)
+	}
+
+	// Entries written before versioning are version 1
+	for _, parserType := range []parser.Type{parser.Web, parser.Telegram, parser.YouTube} {
+		legacy := fmt.Sprintf(`{"parser_type":"%s","data":{}}`, parserType)
+		_, err := DeserializeParserResponse(parserType, []byte(legacy))
+		if wantStale := ParserVersion(parserType) > 1; errors.Is(err, ErrStaleVersion) != wantStale {
+			t.Errorf("%s: legacy entry stale = %v, got %v", parserType, wantStale, err)
+		}
+	}
+
+	stale := `{"parser_type":"telegram","version":999,"data":{"HTML":"<p>old</p>"}}`
+	if _, err := DeserializeParserResponse(parser.Telegram, []byte(stale)); !errors.Is(err, ErrStaleVersion) {
+		t.Errorf("expected ErrStaleVersion, got %v", err)
+	}
+}
+
+func BenchmarkSerializeParserResponse(b *testing.B) {
+	// Long reads with embedded markup are the largest cached outputs
+	resp := web.Response{HTML: strings.Repeat(`<p>Paragraph with <a href="https://example.com/?a=1&b=2">"quoted" link</a> & text</p>`, 20000)}
+	data, err := SerializeParserResponse(parser.Web, resp)
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+
+	b.Run("serialize", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			if _, err := SerializeParserResponse(parser.Web, resp); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("deserialize", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for b.Loop() {
+			if _, err := DeserializeParserResponse(parser.Web, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/breaker"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/ratelimit"
+)
+
+// guardedAgent skips calls of the agent's provider once its circuit is open
+type guardedAgent struct {
+	agent.Agent
+	breaker *breaker.Breaker
+}
+
+func (a guardedAgent) Process(ctx context.Context, content string, opts agent.Options) (string, error) {
+	key := provider.Of(a.Name())
+	if err := a.breaker.Allow(key); err != nil {
+		return "", err
+	}
+	out, err := a.Agent.Process(ctx, content, opts)
+	if ctx.Err() == nil {
+		record(a.breaker, key, err)
+	}
+	return out, err
+}
+
+// guardAgents wraps every agent with the breaker
+func guardAgents(agents map[string]agent.Agent, b *breaker.Breaker) map[string]agent.Agent {
+	guarded := make(map[string]agent.Agent, len(agents))
+	for name, a := range agents {
+		guarded[name] = guardedAgent{Agent: a, breaker: b}
+	}
+	return guarded
+}
+
+// guardedParser skips loading links of hosts whose circuit is open
+type guardedParser struct {
+	parser.Parser
+	t       parser.Type
+	breaker *breaker.Breaker
+}
+
+func (p guardedParser) Parse(ctx context.Context, item fetcher.FeedItem) (parser.Response, error) {
+	key := parserProvider(p.t, item.Link)
+	if err := p.breaker.Allow(key); err != nil {
+		return nil, err
+	}
+	resp, err := p.Parser.Parse(ctx, item)
+	record(p.breaker, key, err)
+	return resp, err
+}
+
+// parserProvider returns the service a parser calls to load the link
+func parserProvider(t parser.Type, link string) string {
+	if t == parser.YouTube {
+		return ratelimit.YouTube
+	}
+	return ratelimit.HostKey(link)
+}
+
+func record(b *breaker.Breaker, key string, err error) {
+	if b.Record(key, err) {
+		slog.Warn("provider keeps failing, skipping it for the rest of the run", "provider", key, "error", err)
+	}
+}
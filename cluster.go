@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/urlnorm"
+)
+
+// minTitleWords are the fewest significant words of titles compared for
+// similarity, shorter titles, e.g., "Weekly update", match by link only
+const minTitleWords = 3
+
+// Coverage is another source of the story of a page
+type Coverage struct {
+	Source string
+	Title  string
+	Link   string
+}
+
+// clusterPages merges pages of different feeds linking to the same URL or
+// with similar titles into the first of them, in the order of the
+// resources. Merged pages are listed by the page they were merged into,
+// resources left without pages are removed. Returns the merged page count.
+func clusterPages(resources []Resource, clustering config.Clustering) ([]Resource, int) {
+	if !clustering.Enabled {
+		return resources, 0
+	}
+	type story struct {
+		res, page int
+		feed      int
+		link      string
+		words     map[string]bool
+	}
+	var stories []story
+	merged := 0
+	clustered := make([]Resource, 0, len(resources))
+	for _, res := range resources {
+		kept := res
+		kept.Pages = nil
+		for _, page := range res.Pages {
+			link, words := urlnorm.Canonical(page.Link), titleWords(page.Title)
+			match := -1
+			for k, s := range stories {
+				if s.feed == res.feed {
+					continue
+				}
+				if (page.Link != "" && s.link == link) || titleSimilarity(s.words, words) >= clustering.TitleSimilarity() {
+					match = k
+					break
+				}
+			}
+			if match < 0 {
+				stories = append(stories, story{res: len(clustered), page: len(kept.Pages), feed: res.feed, link: link, words: words})
+				kept.Pages = append(kept.Pages, page)
+				continue
+			}
+
+			s := stories[match]
+			coverage := Coverage{Source: res.Name, Title: page.Title, Link: page.Link}
+			if s.res == len(clustered) {
+				kept.Pages[s.page].Coverage = append(kept.Pages[s.page].Coverage, coverage)
+			} else {
+				clustered[s.res].Pages[s.page].Coverage = append(clustered[s.res].Pages[s.page].Coverage, coverage)
+			}
+			merged++
+		}
+		if len(kept.Pages) > 0 {
+			clustered = append(clustered, kept)
+		}
+	}
+	return clustered, merged
+}
+
+// titleWords returns the lowercased words of a title, skipping short ones
+// like articles and prepositions
+func titleWords(title string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if utf8.RuneCountInString(word) >= 3 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// titleSimilarity returns the share of words two titles have in common,
+// 0 when either has too few words to tell
+func titleSimilarity(a, b map[string]bool) float64 {
+	if len(a) < minTitleWords || len(b) < minTitleWords {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestClusterPages(t *testing.T) {
+	resources := []Resource{
+		{Name: "Blog", feed: 0, Pages: []Page{
+			{Title: "Faster builds with the new Go linker", Link: "https://blog.example/linker"},
+			{Title: "Weekly update", Link: "https://blog.example/weekly"},
+		}},
+		{Name: "Hacker News", feed: 1, Pages: []Page{
+			{Title: "Show HN: a tiny database", Link: "https://db.example/?utm_source=hn"},
+			{Title: "Faster Go builds with the new linker", Link: "https://news.example/item?id=1"},
+		}},
+		{Name: "Channel", feed: 2, Pages: []Page{
+			{Title: "Weekly update", Link: "https://t.me/channel/7"},
+			{Title: "A tiny database", Link: "https://db.example"},
+		}},
+	}
+
+	if got, merged := clusterPages(resources, config.Clustering{}); merged != 0 || len(got) != 3 {
+		t.Fatalf("expected clustering to be off by default, got %d merged", merged)
+	}
+
+	got, merged := clusterPages(resources, config.Clustering{Enabled: true})
+	if merged != 2 {
+		t.Fatalf("expected 2 merged pages, got %d", merged)
+	}
+	if len(got) != 3 || len(got[2].Pages) != 1 || got[2].Pages[0].Title != "Weekly update" {
+		t.Fatalf("expected short titles to match by link only, got %+v", got)
+	}
+	linker := got[0].Pages[0].Coverage
+	if len(linker) != 1 || linker[0].Source != "Hacker News" || linker[0].Link != "https://news.example/item?id=1" {
+		t.Errorf("expected the similar title merged into the first page, got %+v", linker)
+	}
+	database := got[1].Pages[0].Coverage
+	if len(database) != 1 || database[0].Source != "Channel" {
+		t.Errorf("expected the same link merged, got %+v", database)
+	}
+	if len(resources[0].Pages[0].Coverage) != 0 {
+		t.Error("expected the resources not to be modified")
+	}
+}
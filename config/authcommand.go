@@ -0,0 +1,82 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// AuthCommands runs auth commands of resources and caches their headers
+// for the run, so resources sharing a command log in once
+type AuthCommands struct {
+	mu      sync.Mutex
+	results map[string]authResult
+}
+
+type authResult struct {
+	header http.Header
+	err    error
+}
+
+// NewAuthCommands creates an empty auth command cache
+func NewAuthCommands() *AuthCommands {
+	return &AuthCommands{results: make(map[string]authResult)}
+}
+
+// Header runs the command with sh on its first use and returns the request
+// headers printed to its stdout. Failures are cached as well.
+func (c *AuthCommands) Header(ctx context.Context, command string) (http.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if res, ok := c.results[command]; ok {
+		return res.header.Clone(), res.err
+	}
+
+	var res authResult
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		res.err = fmt.Errorf("auth command failed with %w: %s", err, strings.TrimSpace(stderr.String()))
+	} else {
+		res.header, res.err = parseAuthOutput(out)
+	}
+	c.results[command] = res
+	return res.header.Clone(), res.err
+}
+
+// parseAuthOutput reads "Name: value" header lines. Blank lines and lines
+// starting with "#" are skipped, several Cookie lines are joined.
+func parseAuthOutput(out []byte) (http.Header, error) {
+	header := make(http.Header)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("auth command printed '%s', expected a 'Name: value' header", line)
+		}
+		value = strings.TrimSpace(value)
+		if prev := header.Get(name); prev != "" && http.CanonicalHeaderKey(name) == "Cookie" {
+			value = prev + "; " + value
+		}
+		header.Set(name, value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read auth command output with %w", err)
+	}
+	if len(header) == 0 {
+		return nil, fmt.Errorf("auth command printed no headers")
+	}
+	return header, nil
+}
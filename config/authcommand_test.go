@@ -0,0 +1,54 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAuthOutput(t *testing.T) {
+	header, err := parseAuthOutput([]byte("# session\nCookie: session=abc\ncookie: sso=1\n\nX-Token: t:1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := header.Get("Cookie"); got != "session=abc; sso=1" {
+		t.Errorf("expected joined cookies, got %q", got)
+	}
+	if got := header.Get("X-Token"); got != "t:1" {
+		t.Errorf("expected value after the first colon, got %q", got)
+	}
+
+	for _, out := range []string{"", "session=abc\n", "Bad Name: x\n"} {
+		if _, err := parseAuthOutput([]byte(out)); err == nil {
+			t.Errorf("expected error for %q", out)
+		}
+	}
+}
+
+func TestAuthCommandsCached(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	command := "echo run >> " + counter + "; echo 'Cookie: session=abc'"
+
+	commands := NewAuthCommands()
+	for range 2 {
+		header, err := commands.Header(context.Background(), command)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if header.Get("Cookie") != "session=abc" {
+			t.Errorf("unexpected header %v", header)
+		}
+	}
+	runs, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(runs) != "run\n" {
+		t.Errorf("expected the command to run once, got %q", runs)
+	}
+
+	if _, err := commands.Header(context.Background(), "echo oops >&2; exit 1"); err == nil {
+		t.Error("expected failing command to return an error")
+	}
+}
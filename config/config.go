@@ -1,10 +1,20 @@
 package config
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"path"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 
@@ -20,20 +30,394 @@ var (
 
 const baseCfgPath = "myfeed/config.toml"
 
+// ErrNoConfigHome is returned when neither XDG_CONFIG_HOME nor HOME is set
+var ErrNoConfigHome = errors.New("unable to locate config directory: set XDG_CONFIG_HOME or HOME, or pass the path explicitly")
+
 type Config struct {
-	Resources       []ResourceConfig  `toml:"resources"`
-	DatabasePath    string            `toml:"database_path"`
-	OutputDirectory string            `toml:"output_directory"` // Directory for generated files (defaults to $HOME/myfeed)
-	Filters         map[string]Filter `toml:"filters"`          // Named filters that can be referenced by resources
+	Resources        []ResourceConfig       `toml:"resources"`         // Feeds and channels of the issue
+	DatabasePath     string                 `toml:"database_path"`     // SQLite database of history, queue and caches (defaults to ~/.local/share/myfeed/data.db)
+	OutputDirectory  string                 `toml:"output_directory"`  // Directory for generated files (defaults to $HOME/myfeed)
+	Filters          map[string]Filter      `toml:"filters"`           // Named filters that can be referenced by resources
+	Limits           Limits                 `toml:"limits"`            // Issue size guardrails applied before PDF generation
+	HistoryRetention Duration               `toml:"history_retention"` // Prune runs, items, cache and media older than this, e.g., "180d" (0 = keep forever)
+	Proxy            string                 `toml:"proxy"`             // Default HTTP/SOCKS5 proxy for fetchers and parsers, e.g., "socks5://127.0.0.1:1080"
+	Daemon           Daemon                 `toml:"daemon"`            // Settings for `myfeed daemon`
+	Fetch            FetchPolicy            `toml:"fetch"`             // Default timeouts and retries for feed requests
+	RateLimits       map[string]RateLimit   `toml:"rate_limits"`       // Limits by service: "telegram", "gemini", "claude", "ollama", "youtube", "host" or "host:<name>"
+	SourceRules      string                 `toml:"source_rules"`      // Per-domain cleanup rules file (defaults to rules.toml next to the config)
+	Politeness       Politeness             `toml:"politeness"`        // Request spacing per host for fetchers and the web parser
+	UserAgent        string                 `toml:"user_agent"`        // User-Agent for RSS requests and web pages (defaults to one identifying myfeed)
+	Email            Email                  `toml:"email"`             // SMTP delivery of every generated issue
+	GroupBy          GroupBy                `toml:"group_by"`          // Sections of the issue: "resource" (default), "time_of_day" or "day"
+	TelegramLogin    TelegramLogin          `toml:"telegram_login"`    // Logging in when the Telegram session is missing or expired
+	Fonts            []Font                 `toml:"fonts"`             // Font files embedded into the PDF, e.g., for CJK scripts
+	Templates        string                 `toml:"templates"`         // Directory of templates overriding partials of the issue, e.g., item.html (relative to the config)
+	Whisper          Whisper                `toml:"whisper"`           // Transcription of YouTube videos without captions
+	CircuitBreaker   *int                   `toml:"circuit_breaker"`   // Failures in a row of an agent provider or a host skipping it for the rest of the run (defaults to 3, 0 disables)
+	Widgets          Widgets                `toml:"widgets"`           // Blocks above the items of the issue, e.g., today's weather
+	Contracts        map[string]Contract    `toml:"contracts"`         // Output format agents must follow by agent name, e.g., "summary"
+	BlockedDomains   []string               `toml:"blocked_domains"`   // Domains never fetched, parsed or summarized, subdomains included
+	AllowedDomains   []string               `toml:"allowed_domains"`   // Only these domains are fetched when set, subdomains included
+	Interests        []string               `toml:"interests"`         // Criteria the relevance agent scores items against for min_score, e.g., "Go performance work"
+	Agents           map[string]CustomAgent `toml:"agents"`            // Agents defined by their prompt by name, used in agents of resources like the built-in ones
+	Provider         string                 `toml:"provider"`          // Backend of agents: "gemini" (default), "claude" (both with credentials in creds.toml) or "ollama"
+	Ollama           Ollama                 `toml:"ollama"`            // Local Ollama server answering for agents with provider "ollama"
+	Models           map[string]AgentModel  `toml:"models"`            // Provider and model of built-in agents by name, e.g., "summary" (defaults to the top-level provider)
+	Fallback         []string               `toml:"fallback"`          // Providers agents try in order once theirs keeps failing, e.g., ["claude", "ollama"] (empty = none)
+	Workers          int                    `toml:"workers"`           // Items parsed and passed through agents at once, their calls share the rate limits (defaults to 4)
+	Clustering       Clustering             `toml:"clustering"`        // Merging items of different sources about the same story into one
+	ChunkSize        int                    `toml:"chunk_size"`        // Characters of content agents condense in one call, longer content is condensed in chunks first (defaults to the context of the provider)
+}
+
+// ItemWorkers returns the number of items processed at once
+func (c Config) ItemWorkers() int {
+	if c.Workers <= 0 {
+		return 4
+	}
+	return c.Workers
+}
+
+// BreakerThreshold returns the failures in a row opening a provider's circuit, 0 when disabled
+func (c Config) BreakerThreshold() int {
+	if c.CircuitBreaker == nil {
+		return 3
+	}
+	return max(0, *c.CircuitBreaker)
+}
+
+// Whisper configures faster-whisper, used by the youtube parser for videos
+// without captions
+type Whisper struct {
+	Model       string   `toml:"model"`        // Model size: "tiny" (default), "base", "small", "medium" or "large-v3"
+	Device      string   `toml:"device"`       // "cpu" (default), "cuda" or "auto"
+	Language    string   `toml:"language"`     // Spoken language hint, e.g., "en" (detected when empty)
+	MaxDuration Duration `toml:"max_duration"` // Longer videos are not transcribed, e.g., "1h" (0 = no limit)
+}
+
+// Font is a font file embedded into the issue instead of relying on the
+// fonts installed on the host
+type Font struct {
+	Path      string   `toml:"path"`      // TTF, OTF, WOFF or WOFF2 file
+	Family    string   `toml:"family"`    // Family name in CSS (defaults to the file name)
+	Weight    string   `toml:"weight"`    // "normal" (default), "bold" or a number, e.g., "700"
+	Style     string   `toml:"style"`     // "normal" (default) or "italic"
+	Languages []string `toml:"languages"` // Articles in these languages prefer the font, e.g., ["ja", "zh"] (all when empty)
+}
+
+// TelegramLogin configures logging in when the Telegram session is missing or expired
+type TelegramLogin struct {
+	NonInteractive  bool   `toml:"non_interactive"`  // Fail instead of prompting, implied when stdin is not a terminal
+	CodeCommand     string `toml:"code_command"`     // Shell command printing the login code, e.g., "cat /run/myfeed/code"
+	PasswordCommand string `toml:"password_command"` // Shell command printing the 2FA password, e.g., "pass show telegram"
+}
+
+// GroupBy defines how items are split into sections of the issue
+type GroupBy string
+
+var (
+	GroupByResource  = GroupBy("resource")    // A section per feed
+	GroupByTimeOfDay = GroupBy("time_of_day") // Morning, afternoon, evening and night sections
+	GroupByDay       = GroupBy("day")         // A section per publication day, e.g., for weekly issues
+)
+
+// UnmarshalText rejects unknown grouping modes
+func (g *GroupBy) UnmarshalText(text []byte) error {
+	switch v := GroupBy(text); v {
+	case "", GroupByResource, GroupByTimeOfDay, GroupByDay:
+		*g = v
+		return nil
+	default:
+		return fmt.Errorf("invalid group_by '%s', expected 'resource', 'time_of_day' or 'day'", text)
+	}
+}
+
+// Email configures delivery of issues over SMTP
+type Email struct {
+	Host       string      `toml:"host"`        // SMTP server, delivery is disabled when empty
+	Port       int         `toml:"port"`        // Submission port (defaults to 587, STARTTLS is used when offered)
+	Username   string      `toml:"username"`    // Login for PLAIN authentication, empty to send without it
+	Password   string      `toml:"password"`    // Password or app-specific password
+	From       string      `toml:"from"`        // Sender address
+	To         []string    `toml:"to"`          // Recipients of the full issue
+	MaxSize    float64     `toml:"max_size_mb"` // Message size limit of the provider in MB, larger issues are split (defaults to 25)
+	ReplyTo    string      `toml:"reply_to"`    // Address replies go to, e.g., when sending from a no-reply mailbox
+	DKIM       DKIM        `toml:"dkim"`        // Sign messages with a local key so they pass DMARC
+	Retries    *int        `toml:"retries"`     // Delivery retries after a temporary failure (defaults to 3, 0 disables retrying)
+	Backoff    Duration    `toml:"backoff"`     // Delay before the first retry, doubled on every next one (defaults to 30s)
+	Recipients []Recipient `toml:"recipients"`  // Additional recipients getting their own edition of the issue
+}
+
+// Recipient receives an edition of the issue limited to some of its content
+type Recipient struct {
+	To         []string `toml:"to"`         // Recipient addresses
+	Categories []string `toml:"categories"` // Only resources in these categories, nested ones included (all when empty)
+	Filters    []string `toml:"filters"`    // Named filters applied to the items of this edition
+}
+
+// DKIM configures signing of outgoing messages. The public key must be
+// published in DNS at <selector>._domainkey.<domain>.
+type DKIM struct {
+	Domain     string `toml:"domain"`      // Signing domain, usually the domain of the From address
+	Selector   string `toml:"selector"`    // Selector of the DNS record, e.g., "myfeed"
+	PrivateKey string `toml:"private_key"` // PEM encoded RSA or Ed25519 private key file
+}
+
+// Enabled reports whether messages should be signed
+func (d DKIM) Enabled() bool {
+	return d.PrivateKey != ""
+}
+
+// MaxRetries returns the configured delivery retry count
+func (e Email) MaxRetries() int {
+	if e.Retries == nil {
+		return 3
+	}
+	return max(0, *e.Retries)
+}
+
+// RetryBackoff returns the delay before the first delivery retry
+func (e Email) RetryBackoff() time.Duration {
+	if e.Backoff.Duration > 0 {
+		return e.Backoff.Duration
+	}
+	return 30 * time.Second
+}
+
+// DefaultEmailMaxSize is the message size limit of common providers, e.g., Gmail
+const DefaultEmailMaxSize = 25
+
+// MaxSizeBytes returns the message size limit in bytes
+func (e Email) MaxSizeBytes() int {
+	size := e.MaxSize
+	if size <= 0 {
+		size = DefaultEmailMaxSize
+	}
+	return int(size * 1024 * 1024)
+}
+
+// Enabled reports whether issues should be sent by email
+func (e Email) Enabled() bool {
+	return e.Host != "" && (len(e.To) > 0 || len(e.Recipients) > 0)
+}
+
+// Politeness spaces requests to the same host so heavy runs don't get banned
+type Politeness struct {
+	MinDelay      Duration `toml:"min_delay"`      // Minimum delay between requests to the same host, e.g., "1s"
+	RespectRobots bool     `toml:"respect_robots"` // Honor robots.txt Crawl-delay when it is longer than min_delay
+}
+
+// RateLimit restricts requests to an external service across all workers
+type RateLimit struct {
+	RequestsPerMinute float64 `toml:"requests_per_minute"` // Sustained request rate (0 = unlimited)
+	Burst             int     `toml:"burst"`               // Requests allowed at once before the rate applies (defaults to 1)
+	Concurrency       int     `toml:"concurrency"`         // Maximum requests in flight (0 = unlimited)
+	TokensPerMinute   float64 `toml:"tokens_per_minute"`   // Model tokens of agent calls, input included, requests wait once more were spent (0 = unlimited)
+}
+
+// Daemon configures periodic generation and the HTTP endpoints of daemon mode
+type Daemon struct {
+	Bind            string   `toml:"bind"`             // Listen address (defaults to 127.0.0.1:8080)
+	Interval        Duration `toml:"interval"`         // Time between generations (defaults to 24h)
+	APIToken        string   `toml:"api_token"`        // Bearer token required for all endpoints except /healthz, browsers log in with ?token=
+	TLSCert         string   `toml:"tls_cert"`         // PEM certificate file, enables HTTPS together with tls_key
+	TLSKey          string   `toml:"tls_key"`          // PEM private key file
+	ClientCA        string   `toml:"client_ca"`        // PEM CA bundle, enables mutual TLS
+	ACMEDomains     []string `toml:"acme_domains"`     // Obtain certificates automatically via ACME (Let's Encrypt)
+	ACMECache       string   `toml:"acme_cache"`       // Directory for ACME certificates (defaults to ~/.cache/myfeed/acme)
+	At              string   `toml:"at"`               // Local time of the daily generation, e.g., "07:00" (overrides interval)
+	SkipWeekdays    []string `toml:"skip_weekdays"`    // Days without an issue, e.g., ["saturday", "sunday"]
+	SkipDates       []string `toml:"skip_dates"`       // Dates without an issue, e.g., ["2025-12-25"]
+	Holidays        string   `toml:"holidays"`         // iCalendar file whose all-day events are skipped, e.g., public holidays
+	StateFile       string   `toml:"state_file"`       // Last run time kept for catch-up after downtime (defaults to ~/.cache/myfeed/daemon.json)
+	PublicURL       string   `toml:"public_url"`       // Base URL hubs reach the daemon at, enables WebSub for feeds advertising a hub
+	TelegramUpdates bool     `toml:"telegram_updates"` // Receive new posts of joined Telegram channels in real time instead of polling them
+	Approval        bool     `toml:"approval"`         // Hold issues as drafts until they are approved on the dashboard or with `myfeed approve`
+	AutoApprove     Duration `toml:"auto_approve"`     // Deliver drafts still pending after this long, e.g., "4h" (0 = wait for a decision)
+	TrackClicks     bool     `toml:"track_clicks"`     // Record links opened in issues served by the daemon, for `myfeed suggest`
+}
+
+// DefaultDaemonBind is the listen address of the daemon when bind is empty
+const DefaultDaemonBind = "127.0.0.1:8080"
+
+// Address returns the listen address of the daemon
+func (d Daemon) Address() string {
+	if d.Bind == "" {
+		return DefaultDaemonBind
+	}
+	return d.Bind
+}
+
+// BaseURL returns the URL the dashboard is reached at, public_url when set
+func (d Daemon) BaseURL() string {
+	if d.PublicURL != "" {
+		return strings.TrimSuffix(d.PublicURL, "/")
+	}
+	scheme := "http"
+	if d.TLSCert != "" || len(d.ACMEDomains) > 0 {
+		scheme = "https"
+	}
+	return scheme + "://" + d.Address()
 }
 
 type ResourceConfig struct {
-	FeedURL     string       `toml:"feed_url"`
-	ParserT     parser.Type  `toml:"parser"`
-	T           ResourceType `toml:"type"`
-	Agents      []string     `toml:"agents"`  // Post-processing agents, e.g., ["summary"]
-	Enabled     *bool        `toml:"enabled"` // Whether this resource is active (defaults to true if not set)
-	FilterNames []string     `toml:"filters"` // Names of filters to apply (pipeline)
+	FeedURL         string         `toml:"feed_url"`          // RSS/Atom feed URL or Telegram channel name
+	ParserT         parser.Type    `toml:"parser"`            // Parser of items: "web", "telegram", "youtube" or "command"
+	T               ResourceType   `toml:"type"`              // Fetcher of the feed: "rss" or "telegram_channel"
+	Agents          []string       `toml:"agents"`            // Post-processing agents, e.g., ["summary"]
+	Enabled         *bool          `toml:"enabled"`           // Whether this resource is active (defaults to true if not set)
+	FilterNames     []string       `toml:"filters"`           // Names of filters to apply (pipeline)
+	Category        string         `toml:"category"`          // Optional grouping, e.g., folder name from OPML import
+	OutputLang      string         `toml:"output_language"`   // Language all agents must answer in, e.g., "ru"
+	Auth            HTTPAuth       `toml:"auth"`              // Credentials attached to HTTP requests of this resource
+	Proxy           string         `toml:"proxy"`             // Proxy overriding the global one, "direct" disables it
+	Fetch           FetchPolicy    `toml:"fetch"`             // Timeouts and retries overriding the global ones
+	Backfill        bool           `toml:"backfill"`          // Pull the whole feed history into the archive on first run (RFC 5005)
+	UserAgent       string         `toml:"user_agent"`        // User-Agent overriding the global one
+	Comments        int            `toml:"comments"`          // Top replies appended from the discussion group of a Telegram channel or the HN/Reddit thread of a web item (0 = none)
+	AuthCommand     string         `toml:"auth_command"`      // Shell command printing "Name: value" request headers, run once per run
+	ParserOptions   parser.Options `toml:"parser_options"`    // Extraction tuning of the web parser, e.g., content selectors
+	MaxVideoMinutes int            `toml:"max_video_minutes"` // Longer YouTube videos use only their captions, without captions they become a stub (0 = no limit)
+	ParserCommand   string         `toml:"parser_command"`    // Shell command of the command parser, reads the item as JSON on stdin and prints the parsed JSON
+	Mode            string         `toml:"mode"`              // "headline" shows only the title, link and a line of the description, without parsing or agents (empty = full)
+	ExplodeLinks    bool           `toml:"explode_links"`     // Queue every outbound link of an item as an item of its own, e.g., for weekly link roundups
+	MinScore        int            `toml:"min_score"`         // Items the relevance agent scores lower out of 10 against interests are dropped (0 = no scoring)
+	Routes          []Route        `toml:"routes"`            // Items matching the expression of a route get its parser, agents or category, the first matching route wins
+}
+
+// Route overrides how matching items of a mixed-content resource are
+// processed, see the route package for the expressions
+type Route struct {
+	When     string      `toml:"when"`     // Expression on the item, e.g., 'link contains "youtube.com" && words < 50'
+	Parser   parser.Type `toml:"parser"`   // Parser of matching items, settings of the resource's parser don't apply to another one (empty = the resource's)
+	Agents   []string    `toml:"agents"`   // Agents of matching items, [] for none (unset = the resource's)
+	Category string      `toml:"category"` // Category of matching items (empty = the resource's)
+	Drop     bool        `toml:"drop"`     // Skip matching items
+}
+
+// Routed returns the resource as it processes items matching its i-th route
+func (r ResourceConfig) Routed(i int) ResourceConfig {
+	route := r.Routes[i]
+	if route.Parser != "" && route.Parser != r.ParserT {
+		r.ParserT = route.Parser
+		r.ParserOptions = parser.Options{}
+		r.ParserCommand = ""
+		r.Comments = 0
+		r.MaxVideoMinutes = 0
+	}
+	if route.Agents != nil {
+		r.Agents = route.Agents
+	}
+	r.Category = cmp.Or(route.Category, r.Category)
+	return r
+}
+
+// ModeHeadline skips parsing and agents of a resource, see ResourceConfig.Mode
+const ModeHeadline = "headline"
+
+// DirectProxy disables the global proxy for a resource
+const DirectProxy = "direct"
+
+// ProxyURL returns the proxy to use for the resource given the global default
+func (r ResourceConfig) ProxyURL(global string) string {
+	switch r.Proxy {
+	case "":
+		return global
+	case DirectProxy:
+		return ""
+	default:
+		return r.Proxy
+	}
+}
+
+// UserAgentFor returns the user agent to use for the resource given the global default
+func (r ResourceConfig) UserAgentFor(global string) string {
+	if r.UserAgent != "" {
+		return r.UserAgent
+	}
+	return global
+}
+
+// FetchPolicy defines timeouts and retries of feed requests.
+// Unset fields fall back to the global policy and then to DefaultFetchPolicy.
+type FetchPolicy struct {
+	Timeout    Duration `toml:"timeout"`     // Timeout of a single attempt, e.g., "30s"
+	Retries    *int     `toml:"retries"`     // Retries after a transient failure (0 disables retrying)
+	Backoff    Duration `toml:"backoff"`     // Delay before the first retry, doubled on every next one
+	MaxBackoff Duration `toml:"max_backoff"` // Upper bound for the delay between retries
+	MaxSize    float64  `toml:"max_size_mb"` // Largest feed response in MB, longer ones are cut after the last complete item
+}
+
+// DefaultFetchPolicy returns the policy used when nothing is configured
+func DefaultFetchPolicy() FetchPolicy {
+	retries := 3
+	return FetchPolicy{
+		Timeout:    Duration{30 * time.Second},
+		Retries:    &retries,
+		Backoff:    Duration{2 * time.Second},
+		MaxBackoff: Duration{30 * time.Second},
+		MaxSize:    10,
+	}
+}
+
+// Override returns the policy with fields set in o taking precedence
+func (p FetchPolicy) Override(o FetchPolicy) FetchPolicy {
+	if o.Timeout.Duration > 0 {
+		p.Timeout = o.Timeout
+	}
+	if o.Retries != nil {
+		p.Retries = o.Retries
+	}
+	if o.Backoff.Duration > 0 {
+		p.Backoff = o.Backoff
+	}
+	if o.MaxBackoff.Duration > 0 {
+		p.MaxBackoff = o.MaxBackoff
+	}
+	if o.MaxSize > 0 {
+		p.MaxSize = o.MaxSize
+	}
+	return p
+}
+
+// MaxRetries returns the configured retry count, 0 if unset
+func (p FetchPolicy) MaxRetries() int {
+	if p.Retries == nil || *p.Retries < 0 {
+		return 0
+	}
+	return *p.Retries
+}
+
+// MaxSizeBytes returns the response size limit in bytes, 0 if unset
+func (p FetchPolicy) MaxSizeBytes() int64 {
+	return int64(p.MaxSize * 1024 * 1024)
+}
+
+// HTTPAuth defines credentials attached to HTTP requests of a resource
+type HTTPAuth struct {
+	Username    string            `toml:"username"`     // Basic auth username
+	Password    string            `toml:"password"`     // Basic auth password
+	BearerToken string            `toml:"bearer_token"` // Sent as "Authorization: Bearer <token>"
+	Cookie      string            `toml:"cookie"`       // Raw Cookie header, e.g., "session=abc; theme=dark"
+	Headers     map[string]string `toml:"headers"`      // Any additional request headers
+}
+
+// Header builds request headers carrying the configured credentials
+func (a HTTPAuth) Header() http.Header {
+	header := make(http.Header)
+	for key, value := range a.Headers {
+		header.Set(key, value)
+	}
+	if a.Username != "" || a.Password != "" {
+		creds := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))
+		header.Set("Authorization", "Basic "+creds)
+	}
+	if a.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+a.BearerToken)
+	}
+	if a.Cookie != "" {
+		header.Set("Cookie", a.Cookie)
+	}
+	return header
 }
 
 // Filter defines rules for filtering feed items
@@ -42,6 +426,272 @@ type Filter struct {
 	MinWords          int      `toml:"min_words"`          // Minimum word count (0 = no limit)
 	ExcludePatterns   []string `toml:"exclude_patterns"`   // Regex patterns to exclude
 	RequireParagraphs bool     `toml:"require_paragraphs"` // Must have multiple lines/paragraphs
+	MinViews          int      `toml:"min_views"`          // Minimum views of Telegram posts (0 = no limit)
+	MinForwards       int      `toml:"min_forwards"`       // Minimum forwards of Telegram posts (0 = no limit)
+	MinReactions      int      `toml:"min_reactions"`      // Minimum total reactions of Telegram posts (0 = no limit)
+	Languages         []string `toml:"languages"`          // Only items in these languages, e.g., ["en", "ru"] (empty = any)
+	MinArticleWords   int      `toml:"min_article_words"`  // Minimum word count of the parsed article (0 = no limit)
+	ExcludeAuthors    []string `toml:"exclude_authors"`    // Authors of parsed items to exclude, case-insensitive
+}
+
+// Contract constrains the answers of an agent, answers breaking it are
+// re-prompted with what was wrong
+type Contract struct {
+	Markdown   bool `toml:"markdown"`    // Answers must be plain Markdown: no HTML tags, "•" bullets or a code block around everything
+	MinBullets int  `toml:"min_bullets"` // Fewest top-level bullet points (0 = any)
+	MaxBullets int  `toml:"max_bullets"` // Most top-level bullet points (0 = any)
+	MaxLength  int  `toml:"max_length"`  // Longest answer in characters (0 = no limit)
+	Retries    *int `toml:"retries"`     // Re-prompts after a breaking answer (defaults to 2)
+	Strict     bool `toml:"strict"`      // Fail the agent when the last answer still breaks the contract instead of keeping it
+}
+
+// IsZero reports whether the contract constrains nothing
+func (c Contract) IsZero() bool {
+	return !c.Markdown && c.MinBullets == 0 && c.MaxBullets == 0 && c.MaxLength == 0
+}
+
+// MaxRetries returns the configured re-prompt count
+func (c Contract) MaxRetries() int {
+	if c.Retries == nil {
+		return 2
+	}
+	return max(0, *c.Retries)
+}
+
+// Validate rejects contracts no answer can follow
+func (c Contract) Validate() error {
+	if c.MinBullets < 0 || c.MaxBullets < 0 || c.MaxLength < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if c.MaxBullets > 0 && c.MinBullets > c.MaxBullets {
+		return fmt.Errorf("min_bullets %d is more than max_bullets %d", c.MinBullets, c.MaxBullets)
+	}
+	return nil
+}
+
+// CustomAgent is an agent defined by its prompt instead of Go code
+type CustomAgent struct {
+	Prompt      string            `toml:"prompt"`      // Handlebars template, {{content}} is the text to transform, {{title}}, {{author}} and {{language}} are available too
+	Provider    string            `toml:"provider"`    // Backend of the agent, "gemini", "claude" or "ollama" (defaults to the top-level provider)
+	Model       string            `toml:"model"`       // Model of the provider, e.g., "googleai/gemini-1.5-pro", "claude-haiku-4-5" or "llama3.1" (defaults to the provider's)
+	Temperature *float64          `toml:"temperature"` // Sampling temperature, e.g., 0.2 (defaults to the model's, Gemini only)
+	Fallback    []string          `toml:"fallback"`    // Providers tried in order once the agent's keeps failing, [] for none (unset = the top-level fallback)
+	Schema      map[string]string `toml:"schema"`      // Fields of a JSON answer by name: "string", "number", "boolean" or "list", e.g., { summary = "string", tags = "list" } (empty = free text)
+	Text        string            `toml:"text"`        // String field of the schema shown as the content of the item, e.g., "summary"
+}
+
+// SchemaTypes are the types of fields of a JSON answer
+var SchemaTypes = []string{"string", "number", "boolean", "list"}
+
+// ValidateSchema rejects schemas with unknown types and a text field
+// that isn't a string of the schema
+func (a CustomAgent) ValidateSchema() error {
+	if len(a.Schema) == 0 {
+		if a.Text != "" {
+			return fmt.Errorf("text is set without a schema")
+		}
+		return nil
+	}
+	for name, t := range a.Schema {
+		if !slices.Contains(SchemaTypes, t) {
+			return fmt.Errorf("field '%s' has unknown type %q, expected one of %q", name, t, SchemaTypes)
+		}
+	}
+	if a.Schema[a.Text] != "string" {
+		return fmt.Errorf("text must name a string field of the schema, got %q", a.Text)
+	}
+	return nil
+}
+
+// AgentModel chooses the backend of a built-in agent, e.g., a cheaper model
+// for the relevance scores than for the summaries
+type AgentModel struct {
+	Provider string   `toml:"provider"` // "gemini", "claude" or "ollama" (defaults to the top-level provider)
+	Model    string   `toml:"model"`    // Model of the provider, e.g., "claude-haiku-4-5" (defaults to the provider's)
+	Fallback []string `toml:"fallback"` // Providers tried in order once the agent's keeps failing, [] for none (unset = the top-level fallback)
+}
+
+// Ollama points agents at a local Ollama server, so they run offline
+// without API keys or quotas
+type Ollama struct {
+	URL     string   `toml:"url"`     // Address of the server (defaults to http://localhost:11434)
+	Model   string   `toml:"model"`   // Pulled model answering for agents, e.g., "llama3.1"
+	Timeout Duration `toml:"timeout"` // Limit of a single answer, local models are slow on long content (defaults to 5m)
+}
+
+// DefaultOllamaURL is the address of the Ollama server when url is empty
+const DefaultOllamaURL = "http://localhost:11434"
+
+// Address returns the address of the Ollama server
+func (o Ollama) Address() string {
+	if o.URL == "" {
+		return DefaultOllamaURL
+	}
+	return strings.TrimSuffix(o.URL, "/")
+}
+
+// AnswerTimeout returns the limit of a single answer
+func (o Ollama) AnswerTimeout() time.Duration {
+	if o.Timeout.Duration > 0 {
+		return o.Timeout.Duration
+	}
+	return 5 * time.Minute
+}
+
+// Widgets configures blocks shown above the items of the issue which don't
+// come from feeds. They are shown in the order of the fields, unset ones are off.
+type Widgets struct {
+	Weather  *WeatherWidget  `toml:"weather"`  // Today's forecast from Open-Meteo
+	Calendar *CalendarWidget `toml:"calendar"` // Today's events of an iCalendar feed
+	Quote    *QuoteWidget    `toml:"quote"`    // A quote of the day
+}
+
+// WeatherWidget shows the forecast for the day of the issue
+type WeatherWidget struct {
+	Latitude  float64  `toml:"latitude"`  // Location of the forecast, e.g., 52.52
+	Longitude float64  `toml:"longitude"` // e.g., 13.41
+	Location  string   `toml:"location"`  // Name shown in the title, e.g., "Berlin"
+	Units     string   `toml:"units"`     // "metric" (default) or "imperial"
+	Cache     Duration `toml:"cache"`     // How long a forecast is reused (defaults to 1h)
+}
+
+// CalendarWidget lists the events of the day of the issue
+type CalendarWidget struct {
+	URL   string   `toml:"url"`   // iCalendar feed, e.g., the secret address of a calendar, or a file (relative to the config)
+	Title string   `toml:"title"` // Title of the block (defaults to "Today")
+	Cache Duration `toml:"cache"` // How long the feed is reused (defaults to 15m)
+}
+
+// QuoteWidget shows a quote of the day picked from a collection
+type QuoteWidget struct {
+	Source string `toml:"source"` // File or URL of quotes separated by blank lines, a last line starting with "—" names the author
+	Title  string `toml:"title"`  // Title of the block (defaults to "Quote of the day")
+}
+
+// Clustering merges items of different sources about the same story, e.g.,
+// a blog post and its Hacker News thread, into the first of them
+type Clustering struct {
+	Enabled    bool    `toml:"enabled"`    // Merge items linking to the same URL or with similar titles, listing the others as "also covered by"
+	Similarity float64 `toml:"similarity"` // Share of title words similar items have in common, from 0 to 1 (defaults to 0.6)
+}
+
+// TitleSimilarity returns the share of title words merging items
+func (c Clustering) TitleSimilarity() float64 {
+	if c.Similarity <= 0 {
+		return 0.6
+	}
+	return min(c.Similarity, 1)
+}
+
+// Limits defines hard caps on the generated issue size.
+// Items exceeding any limit are moved into a separate appendix issue.
+type Limits struct {
+	MaxPages  int `toml:"max_pages"`  // Maximum estimated PDF pages (0 = no limit)
+	MaxImages int `toml:"max_images"` // Maximum total images across all items (0 = no limit)
+	MaxItems  int `toml:"max_items"`  // Maximum item count (0 = no limit)
+}
+
+// ParserCacheKey returns the parser identity used for caching. Parser
+// options, comments of web items, the video length limit and the parser
+// command change the output, so they are part of it.
+func (r ResourceConfig) ParserCacheKey() string {
+	key := string(r.ParserT)
+	if !r.ParserOptions.IsZero() {
+		opts, _ := json.Marshal(r.ParserOptions)
+		sum := sha256.Sum256(opts)
+		key += "+" + hex.EncodeToString(sum[:4])
+	}
+	if r.Comments > 0 && r.ParserT == parser.Web {
+		key += fmt.Sprintf("+comments=%d", r.Comments)
+	}
+	if r.MaxVideoMinutes > 0 && r.ParserT == parser.YouTube {
+		key += fmt.Sprintf("+max_minutes=%d", r.MaxVideoMinutes)
+	}
+	if r.ParserCommand != "" && r.ParserT == parser.Command {
+		sum := sha256.Sum256([]byte(r.ParserCommand))
+		key += "+cmd=" + hex.EncodeToString(sum[:4])
+	}
+	return key
+}
+
+// AgentPipeline returns the agent pipeline identity used for caching.
+// Settings that change agent output are part of it.
+func (r ResourceConfig) AgentPipeline() []string {
+	pipeline := append([]string{}, r.Agents...)
+	if r.OutputLang != "" {
+		pipeline = append(pipeline, "lang="+r.OutputLang)
+	}
+	return pipeline
+}
+
+// StagePipeline returns the cache identity of the output of the first n
+// agents applied to the content. The discussion agent runs on comments and
+// the relevance agent scores the content, neither is a stage.
+func (r ResourceConfig) StagePipeline(n int) []string {
+	pipeline := []string{}
+	for _, name := range r.Agents {
+		if len(pipeline) == n {
+			break
+		}
+		if isStage(name) {
+			pipeline = append(pipeline, name)
+		}
+	}
+	if r.OutputLang != "" {
+		pipeline = append(pipeline, "lang="+r.OutputLang)
+	}
+	return pipeline
+}
+
+// Stages returns the number of agents applied to the content
+func (r ResourceConfig) Stages() int {
+	n := 0
+	for _, name := range r.Agents {
+		if isStage(name) {
+			n++
+		}
+	}
+	return n
+}
+
+// Stage returns the name of the n-th agent applied to the content, empty
+// for the parsed content
+func (r ResourceConfig) Stage(n int) string {
+	for _, name := range r.Agents {
+		if !isStage(name) {
+			continue
+		}
+		if n--; n == 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+// isStage reports whether the agent transforms the content of the chain
+func isStage(name string) bool {
+	return name != "discussion" && name != "relevance"
+}
+
+// RelevancePipeline returns the cache identity of the relevance score,
+// changing interests score items again
+func (r ResourceConfig) RelevancePipeline(interests []string) []string {
+	sum := sha256.Sum256([]byte(strings.Join(interests, "\n")))
+	return []string{"relevance", "interests=" + hex.EncodeToString(sum[:4])}
+}
+
+// DiscussionPipeline returns the cache identity of the discussion summary
+func (r ResourceConfig) DiscussionPipeline() []string {
+	pipeline := []string{"discussion"}
+	if r.OutputLang != "" {
+		pipeline = append(pipeline, "lang="+r.OutputLang)
+	}
+	return pipeline
+}
+
+// IsHeadline returns true if items of the resource are shown as headlines only
+func (r ResourceConfig) IsHeadline() bool {
+	return r.Mode == ModeHeadline
 }
 
 // IsEnabled returns true if the resource is enabled (defaults to true if not explicitly set)
@@ -53,18 +703,24 @@ func (r ResourceConfig) IsEnabled() bool {
 }
 
 func Read(path string) (Config, error) {
-	conf := Default()
 	dat, err := os.ReadFile(path)
 	if err != nil {
-		return conf, err
+		return Default(), err
 	}
-	_, err = toml.Decode(string(dat), &conf)
+	conf, err := Parse(dat)
 	if err != nil {
 		return conf, fmt.Errorf("failed to decode config at %s with %w", path, err)
 	}
 	return conf, nil
 }
 
+// Parse decodes a config on top of the defaults
+func Parse(data []byte) (Config, error) {
+	conf := Default()
+	_, err := toml.Decode(string(data), &conf)
+	return conf, err
+}
+
 func Write(cfgPath string, cfg Config) error {
 	blob, err := toml.Marshal(cfg)
 	if err != nil {
@@ -91,19 +747,24 @@ func Default() Config {
 		DatabasePath:    path.Join(dbBase, "data.db"),
 		OutputDirectory: outputDir,
 		Resources:       []ResourceConfig{},
+		Daemon: Daemon{
+			Bind:     "127.0.0.1:8080",
+			Interval: Duration{24 * time.Hour},
+		},
 	}
 }
 
-func DefaultPath() string {
+// DefaultPath returns the default config path based on XDG_CONFIG_HOME or HOME
+func DefaultPath() (string, error) {
 	var xdgHome = os.Getenv("XDG_CONFIG_HOME")
 	if xdgHome != "" {
-		return path.Join(xdgHome, baseCfgPath)
+		return path.Join(xdgHome, baseCfgPath), nil
 	}
 
 	var home = os.Getenv("HOME")
 	if home != "" {
-		return path.Join(home, ".config", baseCfgPath)
+		return path.Join(home, ".config", baseCfgPath), nil
 	}
 
-	panic("unclear where to search for the config fie")
+	return "", ErrNoConfigHome
 }
@@ -2,8 +2,10 @@ package config
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -12,10 +14,20 @@ import (
 
 const baseCredPath = "myfeed/creds.toml"
 
+// Suffixes of encrypted credentials files
+const (
+	AgeSuffix = ".age"
+	GPGSuffix = ".gpg"
+)
+
+// ageIdentityEnv points to the age identity file used to decrypt credentials
+const ageIdentityEnv = "MYFEED_AGE_IDENTITY"
+
 // Credentials holds all application credentials
 type Credentials struct {
 	Telegram TelegramCredentials `toml:"telegram"`
 	Gemini   GeminiCredentials   `toml:"gemini"`
+	Claude   ClaudeCredentials   `toml:"claude"`
 }
 
 // TelegramCredentials holds Telegram API credentials
@@ -41,11 +53,23 @@ func (gc GeminiCredentials) IsValid() bool {
 	return gc.APIKey != "" && gc.Model != ""
 }
 
-// ReadCredentials reads credentials from the specified path
+// ClaudeCredentials holds Anthropic API credentials
+type ClaudeCredentials struct {
+	APIKey string `toml:"api_key"`
+	Model  string `toml:"model"` // e.g., "claude-sonnet-4-5"
+}
+
+// IsValid checks if Claude credentials are fully populated
+func (cc ClaudeCredentials) IsValid() bool {
+	return cc.APIKey != "" && cc.Model != ""
+}
+
+// ReadCredentials reads credentials from the specified path.
+// Files ending with .age or .gpg are decrypted first.
 func ReadCredentials(path string) (Credentials, error) {
 	var creds Credentials
 
-	data, err := os.ReadFile(path)
+	data, err := readCredentialsFile(path)
 	if err != nil {
 		return creds, err
 	}
@@ -59,6 +83,10 @@ func ReadCredentials(path string) (Credentials, error) {
 
 // WriteCredentials writes credentials to the specified path
 func WriteCredentials(path string, creds Credentials) error {
+	if IsEncrypted(path) {
+		return fmt.Errorf("credentials file at '%s' is encrypted, decrypt it, add the credentials manually and encrypt it again", path)
+	}
+
 	blob, err := toml.Marshal(creds)
 	if err != nil {
 		return fmt.Errorf("failed to encode credentials: %w", err)
@@ -77,19 +105,63 @@ func WriteCredentials(path string, creds Credentials) error {
 	return nil
 }
 
-// DefaultCredentialsPath returns the default path for credentials file
-func DefaultCredentialsPath() string {
-	var xdgHome = os.Getenv("XDG_CONFIG_HOME")
-	if xdgHome != "" {
-		return filepath.Join(xdgHome, baseCredPath)
+// DefaultCredentialsPath returns the default path for credentials file.
+// When only an encrypted creds.toml.age or creds.toml.gpg exists, its path is returned.
+func DefaultCredentialsPath() (string, error) {
+	var path string
+	if xdgHome := os.Getenv("XDG_CONFIG_HOME"); xdgHome != "" {
+		path = filepath.Join(xdgHome, baseCredPath)
+	} else if home := os.Getenv("HOME"); home != "" {
+		path = filepath.Join(home, ".config", baseCredPath)
+	} else {
+		return "", ErrNoConfigHome
 	}
 
-	var home = os.Getenv("HOME")
-	if home != "" {
-		return filepath.Join(home, ".config", baseCredPath)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	for _, suffix := range []string{AgeSuffix, GPGSuffix} {
+		if _, err := os.Stat(path + suffix); err == nil {
+			return path + suffix, nil
+		}
 	}
+	return path, nil
+}
 
-	panic("unable to determine credentials file path")
+// IsEncrypted returns true if the credentials file at path is encrypted
+func IsEncrypted(path string) bool {
+	return strings.HasSuffix(path, AgeSuffix) || strings.HasSuffix(path, GPGSuffix)
+}
+
+// readCredentialsFile reads a credentials file, decrypting it with
+// age (identity from $MYFEED_AGE_IDENTITY or identity.txt next to the file)
+// or gpg (keys from gpg-agent) when needed
+func readCredentialsFile(path string) ([]byte, error) {
+	if !IsEncrypted(path) {
+		return os.ReadFile(path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if strings.HasSuffix(path, AgeSuffix) {
+		identity := os.Getenv(ageIdentityEnv)
+		if identity == "" {
+			identity = filepath.Join(filepath.Dir(path), "identity.txt")
+		}
+		cmd = exec.Command("age", "--decrypt", "--identity", identity, path)
+	} else {
+		cmd = exec.Command("gpg", "--quiet", "--batch", "--decrypt", path)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials at %s with %s: %w: %s", path, cmd.Args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return data, nil
 }
 
 // PromptTelegramCredentials prompts the user for Telegram credentials
@@ -155,6 +227,14 @@ func LoadOrPromptTelegramCredentials(credPath string) (TelegramCredentials, erro
 		return creds.Telegram, nil
 	}
 
+	// Encrypted files can't be updated, so there is no point in prompting
+	if IsEncrypted(credPath) {
+		if err != nil {
+			return TelegramCredentials{}, err
+		}
+		return TelegramCredentials{}, fmt.Errorf("telegram credentials are missing in encrypted file %s", credPath)
+	}
+
 	// Credentials not found or invalid, prompt user
 	telegramCreds, err := PromptTelegramCredentials()
 	if err != nil {
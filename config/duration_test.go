@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"180d", 180 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"12h30m", 12*time.Hour + 30*time.Minute, false},
+		{"abc", 0, true},
+		{"xd", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseDuration(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDuration_RoundTrip(t *testing.T) {
+	for _, input := range []string{"180d", "1h30m0s", ""} {
+		var d Duration
+		if err := d.UnmarshalText([]byte(input)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		text, err := d.MarshalText()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(text) != input {
+			t.Errorf("expected %q, got %q", input, text)
+		}
+	}
+}
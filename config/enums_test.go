@@ -0,0 +1,132 @@
+// The validators live in packages importing config, so this test is external
+package config_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/agent/provider"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/parser/factory"
+)
+
+// enumRe matches documented values of an option, e.g., "a" (default), "b" or "c"
+var (
+	enumRe  = regexp.MustCompile(`"[^"]+"( \([^)]*\))?(, "[^"]+"( \([^)]*\))?)* or "[^"]+"`)
+	valueRe = regexp.MustCompile(`"([^"]+)"`)
+)
+
+// enums returns the documented values of options by their path in the
+// schema, e.g., "resources[].parser"
+func enums(t *testing.T) map[string][]string {
+	data, err := config.JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string][]string)
+	var walk func(s map[string]any, path string)
+	walk = func(s map[string]any, path string) {
+		if doc, ok := s["description"].(string); ok {
+			doc, _, _ = strings.Cut(doc, "e.g.")
+			for _, m := range valueRe.FindAllStringSubmatch(enumRe.FindString(doc), -1) {
+				found[path] = append(found[path], m[1])
+			}
+		}
+		if properties, ok := s["properties"].(map[string]any); ok {
+			for key, property := range properties {
+				walk(property.(map[string]any), strings.TrimPrefix(path+"."+key, "."))
+			}
+		}
+		if items, ok := s["items"].(map[string]any); ok {
+			walk(items, path+"[]")
+		}
+		if values, ok := s["additionalProperties"].(map[string]any); ok {
+			walk(values, path+".*")
+		}
+	}
+	walk(schema, "")
+	return found
+}
+
+// resource returns a resource of the type read by the parser, with the
+// settings the parser requires
+func resource(parserT, resourceT string) config.ResourceConfig {
+	r := config.ResourceConfig{ParserT: parserT, T: resourceT}
+	if c, ok := factory.Capabilities(parserT); ok && c.Command {
+		r.ParserCommand = "cat"
+	}
+	return r
+}
+
+func TestDocumentedEnumsAreAccepted(t *testing.T) {
+	providers := provider.Settings{
+		Gemini: config.GeminiCredentials{APIKey: "key", Model: "googleai/gemini-2.0-flash"},
+		Claude: config.ClaudeCredentials{APIKey: "key", Model: "claude-sonnet-4-5"},
+		Ollama: config.Ollama{Model: "llama3.1"},
+	}
+	acceptProvider := func(v string) error {
+		_, err := providers.Backend(v, "")
+		return err
+	}
+	found := enums(t)
+
+	validators := map[string]func(v string) error{
+		"provider":          acceptProvider,
+		"agents.*.provider": acceptProvider,
+		"models.*.provider": acceptProvider,
+		"agents.*.schema": func(v string) error {
+			return config.CustomAgent{Schema: map[string]string{"field": v, "text": "string"}, Text: "text"}.ValidateSchema()
+		},
+		"group_by": func(v string) error {
+			var g config.GroupBy
+			return g.UnmarshalText([]byte(v))
+		},
+		"resources[].parser": func(v string) error {
+			c, _ := factory.Capabilities(v)
+			return factory.Check(resource(v, c.ResourceTypes[0]))
+		},
+		"resources[].type": func(v string) error {
+			var err error
+			for _, parserT := range found["resources[].parser"] {
+				if err = factory.Check(resource(parserT, v)); err == nil {
+					return nil
+				}
+			}
+			return err
+		},
+	}
+	// Values of these options are passed on as they are
+	unchecked := []string{"fonts[].style", "rate_limits", "whisper.device", "whisper.model", "widgets.weather.units"}
+
+	for path, values := range found {
+		if slices.Contains(unchecked, path) {
+			continue
+		}
+		validate, ok := validators[path]
+		if !ok {
+			t.Errorf("%s documents %q, but the test has no validator for it", path, values)
+			continue
+		}
+		for _, v := range values {
+			if _, ok := factory.Capabilities(v); path == "resources[].parser" && !ok {
+				t.Errorf("%s documents %q, but there is no such parser", path, v)
+				continue
+			}
+			if err := validate(v); err != nil {
+				t.Errorf("%s documents %q, but it is rejected with %v", path, v, err)
+			}
+		}
+	}
+	for path := range validators {
+		if _, ok := found[path]; !ok {
+			t.Errorf("expected %s to document its values", path)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	_ "embed"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/scipunch/myfeed/parser"
+)
+
+// source declares the config, comments of its fields document the options
+//
+//go:embed config.go
+var source string
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// docs maps "<package>.<Type>.<Field>" and "<package>.<Type>" to their comments
+var docs = sync.OnceValues(func() (map[string]string, error) {
+	return parseDocs(map[string]string{"config": source, "parser": parser.Source})
+})
+
+// parseDocs collects the comments of types and their fields in the sources
+// of packages by name
+func parseDocs(sources map[string]string) (map[string]string, error) {
+	d := make(map[string]string)
+	for pkg, src := range sources {
+		file, err := goparser.ParseFile(token.NewFileSet(), pkg+".go", src, goparser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("embedded source of %s doesn't parse: %w", pkg, err)
+		}
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				d[pkg+"."+typeSpec.Name.Name] = commentText(gen.Doc)
+				st, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range st.Fields.List {
+					for _, name := range field.Names {
+						d[pkg+"."+typeSpec.Name.Name+"."+name.Name] = commentText(field.Comment, field.Doc)
+					}
+				}
+			}
+		}
+	}
+	return d, nil
+}
+
+// commentText joins the lines of the first non-empty comment
+func commentText(groups ...*ast.CommentGroup) string {
+	for _, g := range groups {
+		if text := strings.Join(strings.Fields(g.Text()), " "); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// option is a key of the config
+type option struct {
+	key string
+	doc string
+	t   reflect.Type // Pointers are dereferenced
+}
+
+// options lists the keys of a config table in declaration order
+func options(t reflect.Type) ([]option, error) {
+	d, err := docs()
+	if err != nil {
+		return nil, err
+	}
+	var opts []option
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		key, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = f.Name
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		doc := d[path.Base(t.PkgPath())+"."+t.Name()+"."+f.Name]
+		if doc == "" {
+			doc = d[path.Base(ft.PkgPath())+"."+ft.Name()]
+		}
+		opts = append(opts, option{key: key, doc: doc, t: ft})
+	}
+	return opts, nil
+}
+
+// isValue reports whether the type is written as a value instead of a table
+func isValue(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return false
+	case reflect.Slice, reflect.Map:
+		return isValue(t.Elem())
+	}
+	return true
+}
+
+// WriteExample writes an example config with every option commented out
+// and documented by the comment of its field
+func WriteExample(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Example myfeed config, every option is commented out with its zero value.\n")
+	b.WriteString("# Generated by `myfeed config schema`, uncomment what you need.\n\n")
+	if err := writeTable(&b, reflect.TypeFor[Config](), ""); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeTable writes values of the table before its subtables, as TOML requires
+func writeTable(b *strings.Builder, t reflect.Type, prefix string) error {
+	opts, err := options(t)
+	if err != nil {
+		return err
+	}
+	for _, o := range opts {
+		if isValue(o.t) {
+			writeDoc(b, o.doc)
+			fmt.Fprintf(b, "# %s = %s\n\n", o.key, exampleValue(o.t))
+		}
+	}
+	for _, o := range opts {
+		if isValue(o.t) {
+			continue
+		}
+		name := prefix + o.key
+		elem, header := o.t, "["+name+"]"
+		switch o.t.Kind() {
+		case reflect.Slice:
+			elem, header = o.t.Elem(), "[["+name+"]]"
+		case reflect.Map:
+			elem, name = o.t.Elem(), name+".<name>"
+			header = "[" + name + "]"
+		}
+		writeDoc(b, o.doc)
+		fmt.Fprintf(b, "# %s\n\n", header)
+		if err := writeTable(b, elem, name+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDoc writes the documentation of an option wrapped into comment lines
+func writeDoc(b *strings.Builder, doc string) {
+	line := "#"
+	for _, word := range strings.Fields(doc) {
+		if len(line)+1+len(word) > 80 && line != "#" {
+			b.WriteString(line + "\n")
+			line = "#"
+		}
+		line += " " + word
+	}
+	if line != "#" {
+		b.WriteString(line + "\n")
+	}
+}
+
+// exampleValue returns the zero value of the type in TOML
+func exampleValue(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return `""`
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return `""`
+	case reflect.Bool:
+		return "false"
+	case reflect.Float32, reflect.Float64:
+		return "0.0"
+	case reflect.Slice:
+		return "[]"
+	case reflect.Map:
+		return "{}"
+	}
+	return "0"
+}
+
+// JSONSchema returns the JSON Schema of the config, e.g., for completion
+// in editors with TOML language servers
+func JSONSchema() ([]byte, error) {
+	schema, err := typeSchema(reflect.TypeFor[Config]())
+	if err != nil {
+		return nil, err
+	}
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "myfeed config"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// typeSchema describes values of the type
+func typeSchema(t reflect.Type) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return map[string]any{"type": "string"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice:
+		items, err := typeSchema(t.Elem())
+		return map[string]any{"type": "array", "items": items}, err
+	case reflect.Map:
+		values, err := typeSchema(t.Elem())
+		return map[string]any{"type": "object", "additionalProperties": values}, err
+	case reflect.Struct:
+		opts, err := options(t)
+		if err != nil {
+			return nil, err
+		}
+		properties := make(map[string]any)
+		for _, o := range opts {
+			property, err := typeSchema(o.t)
+			if err != nil {
+				return nil, err
+			}
+			if o.doc != "" {
+				property["description"] = o.doc
+			}
+			properties[o.key] = property
+		}
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}, nil
+	}
+	return map[string]any{"type": "integer"}, nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestOptionsAreDocumented keeps `myfeed config schema` complete, every
+// option needs a comment on its field
+func TestOptionsAreDocumented(t *testing.T) {
+	var walk func(t reflect.Type, prefix string) []string
+	walk = func(typ reflect.Type, prefix string) []string {
+		opts, err := options(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var missing []string
+		for _, o := range opts {
+			if o.doc == "" {
+				missing = append(missing, prefix+o.key)
+			}
+			if isValue(o.t) {
+				continue
+			}
+			elem := o.t
+			if elem.Kind() == reflect.Slice || elem.Kind() == reflect.Map {
+				elem = elem.Elem()
+			}
+			missing = append(missing, walk(elem, prefix+o.key+".")...)
+		}
+		return missing
+	}
+	if missing := walk(reflect.TypeFor[Config](), ""); len(missing) > 0 {
+		t.Errorf("options without a comment on their field: %s", strings.Join(missing, ", "))
+	}
+}
+
+func TestWriteExample(t *testing.T) {
+	var b strings.Builder
+	if err := WriteExample(&b); err != nil {
+		t.Fatal(err)
+	}
+	example := b.String()
+	for _, want := range []string{
+		"# Listen address (defaults to 127.0.0.1:8080)\n# bind = \"\"\n",
+		"# [[resources]]\n",
+		"# [resources.parser_options]\n",
+		"# [filters.<name>]\n",
+		"# history_retention = \"\"\n",
+		"# blocked_domains = []\n",
+	} {
+		if !strings.Contains(example, want) {
+			t.Errorf("expected the example to contain %q", want)
+		}
+	}
+	// Values of a table come before its subtables
+	if strings.Index(example, "# interests = []") > strings.Index(example, "# [[resources]]") {
+		t.Error("expected top-level values before the first table")
+	}
+}
+
+func TestParseDocs_Invalid(t *testing.T) {
+	if _, err := parseDocs(map[string]string{"config": "package config\n\ntype Config struct {"}); err == nil {
+		t.Error("expected source that doesn't parse to fail")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties map[string]struct {
+			Type        string `json:"type"`
+			Description string `json:"description"`
+			Items       struct {
+				Properties map[string]struct {
+					Type        string `json:"type"`
+					Description string `json:"description"`
+				} `json:"properties"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.Properties["history_retention"]; got.Type != "string" || !strings.Contains(got.Description, "Prune runs") {
+		t.Errorf("expected durations to be documented strings, got %+v", got)
+	}
+	resources := schema.Properties["resources"]
+	if resources.Type != "array" || resources.Items.Properties["min_score"].Type != "integer" {
+		t.Errorf("expected resources to be an array of tables, got %+v", resources)
+	}
+	if got := resources.Items.Properties["parser_options"]; got.Type != "object" || got.Description == "" {
+		t.Errorf("expected parser options of the parser package, got %+v", got)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// runConfig handles `config schema [-json]`, printing an example config
+// documenting every option or its JSON Schema
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "schema" {
+		return errors.New("usage: myfeed config schema [-json]")
+	}
+	schemaFlags := flag.NewFlagSet("config schema", flag.ExitOnError)
+	asJSON := schemaFlags.Bool("json", false, "print the JSON Schema instead of an example config")
+	schemaFlags.Parse(args[1:])
+
+	if !*asJSON {
+		return config.WriteExample(os.Stdout)
+	}
+	schema, err := config.JSONSchema()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(append(schema, '\n'))
+	return err
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/cache"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+)
+
+// errCurationAborted is returned when the issue is discarded while curating
+var errCurationAborted = errors.New("curation aborted")
+
+// curatedItem is a page of the issue with the decisions made about it
+type curatedItem struct {
+	resource int // Index in Newsletter.Resources
+	page     Page
+	dropped  bool
+}
+
+// curation holds the processed issue while it is reviewed item by item
+type curation struct {
+	issue  Newsletter
+	items  []curatedItem
+	cursor int
+	status string // Outcome of the last action, shown under the list
+}
+
+func newCuration(n Newsletter) *curation {
+	c := &curation{issue: n}
+	for i, res := range n.Resources {
+		for _, page := range res.Pages {
+			c.items = append(c.items, curatedItem{resource: i, page: page})
+		}
+	}
+	return c
+}
+
+// move moves the cursor by delta items
+func (c *curation) move(delta int) {
+	c.cursor = min(max(c.cursor+delta, 0), max(len(c.items)-1, 0))
+}
+
+// shift moves the selected item by delta positions within its resource
+func (c *curation) shift(delta int) {
+	to := c.cursor + delta
+	if to < 0 || to >= len(c.items) || c.items[to].resource != c.items[c.cursor].resource {
+		return
+	}
+	c.items[c.cursor], c.items[to] = c.items[to], c.items[c.cursor]
+	c.cursor = to
+}
+
+func (c *curation) toggleDrop() {
+	if len(c.items) > 0 {
+		c.items[c.cursor].dropped = !c.items[c.cursor].dropped
+	}
+}
+
+func (c *curation) toggleStar() {
+	if len(c.items) > 0 {
+		c.items[c.cursor].page.Starred = !c.items[c.cursor].page.Starred
+	}
+}
+
+// newsletter returns the issue with dropped items removed and the rest in
+// their curated order, resources left without items are removed
+func (c *curation) newsletter() Newsletter {
+	n := c.issue
+	n.Resources = nil
+	pages := make([][]Page, len(c.issue.Resources))
+	for _, item := range c.items {
+		if !item.dropped {
+			pages[item.resource] = append(pages[item.resource], item.page)
+		}
+	}
+	for i, res := range c.issue.Resources {
+		if len(pages[i]) == 0 {
+			continue
+		}
+		res.Pages = pages[i]
+		n.Resources = append(n.Resources, res)
+	}
+	return n
+}
+
+// kept reports whether the link is still part of the curated issue
+func (c *curation) kept() map[string]bool {
+	links := make(map[string]bool)
+	for _, item := range c.items {
+		if !item.dropped {
+			links[item.page.Link] = true
+		}
+	}
+	return links
+}
+
+// view renders the item list and a preview of the selected item
+func (c *curation) view(width, height int) string {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	rows := 0
+	line := func(s string) {
+		b.WriteString(truncate(s, width))
+		b.WriteString("\r\n")
+		rows++
+	}
+
+	// Keep the cursor visible when the list is longer than half the screen
+	listHeight := max(height/2, 3)
+	first := min(max(c.cursor-listHeight/2, 0), max(len(c.items)-listHeight, 0))
+	last := min(first+listHeight, len(c.items))
+	resource := -1
+	for i := first; i < last; i++ {
+		item := c.items[i]
+		if item.resource != resource {
+			resource = item.resource
+			line(c.issue.Resources[resource].Name)
+		}
+		marker, star, drop := "  ", " ", ""
+		if i == c.cursor {
+			marker = "> "
+		}
+		if item.page.Starred {
+			star = "★"
+		}
+		if item.dropped {
+			drop = " (dropped)"
+		}
+		line(fmt.Sprintf("%s%s %s%s", marker, star, item.page.Title, drop))
+	}
+
+	line(strings.Repeat("─", max(width, 1)))
+	if len(c.items) > 0 {
+		item := c.items[c.cursor].page
+		line(item.Link)
+		for _, l := range wrap(plainText(string(item.Content)), width) {
+			if rows >= height-2 {
+				break
+			}
+			line(l)
+		}
+	}
+	for rows < height-2 {
+		line("")
+	}
+	line(c.status)
+	b.WriteString(truncate("j/k move · J/K reorder · d drop · s star · r re-run agents · y render · q quit", width))
+	return b.String()
+}
+
+// curate shows the processed issue in the terminal until it is confirmed
+// or discarded. rerun replaces the content of a page by running its agents
+// again, nil when there is nothing to run.
+func curate(in io.Reader, out io.Writer, c *curation, size func() (int, int), rerun func(Resource, Page) (template.HTML, error)) error {
+	keys := bufio.NewReader(in)
+	for {
+		width, height := size()
+		if _, err := io.WriteString(out, c.view(width, height)); err != nil {
+			return err
+		}
+
+		key, err := readKey(keys)
+		if err != nil {
+			return err
+		}
+		c.status = ""
+		switch key {
+		case "j", "down":
+			c.move(1)
+		case "k", "up":
+			c.move(-1)
+		case "J":
+			c.shift(1)
+		case "K":
+			c.shift(-1)
+		case "d", "x":
+			c.toggleDrop()
+		case "s":
+			c.toggleStar()
+		case "r":
+			if len(c.items) == 0 || rerun == nil {
+				continue
+			}
+			item := &c.items[c.cursor]
+			io.WriteString(out, "\r\n"+truncate("re-running agents...", width))
+			content, err := rerun(c.issue.Resources[item.resource], item.page)
+			if err != nil {
+				c.status = "re-run failed: " + err.Error()
+				continue
+			}
+			item.page.Content = content
+			c.status = "agents re-run"
+		case "y", "enter":
+			io.WriteString(out, "\x1b[H\x1b[2J")
+			return nil
+		case "q", "ctrl-c":
+			io.WriteString(out, "\x1b[H\x1b[2J")
+			return errCurationAborted
+		}
+	}
+}
+
+// readKey reads a key press, arrows are reported as "up" and "down"
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 3:
+		return "ctrl-c", nil
+	case '\r', '\n':
+		return "enter", nil
+	case 0x1b:
+		// Escape sequences of arrow keys: ESC [ A and ESC [ B
+		if next, err := r.Peek(2); err == nil && next[0] == '[' {
+			r.Discard(2)
+			switch next[1] {
+			case 'A':
+				return "up", nil
+			case 'B':
+				return "down", nil
+			}
+		}
+		return "esc", nil
+	}
+	return string(rune(b)), nil
+}
+
+// curateInTerminal runs the curation on the controlling terminal in raw mode
+func curateInTerminal(ctx context.Context, proc processor, n Newsletter) (*curation, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("curating needs an interactive terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch terminal into raw mode with %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	size := func() (int, int) {
+		width, height, err := term.GetSize(fd)
+		if err != nil {
+			return 80, 24
+		}
+		return width, height
+	}
+	rerun := func(res Resource, page Page) (template.HTML, error) {
+		content, err := proc.rerunAgents(ctx, res.config(proc.conf), page.Link)
+		return renderMarkdown(content), err
+	}
+	c := newCuration(n)
+	return c, curate(os.Stdin, os.Stdout, c, size, rerun)
+}
+
+// rerunAgents runs the agents of the resource on the cached parser output of
+// the link again, bypassing and replacing their cached outputs
+func (proc processor) rerunAgents(ctx context.Context, resource config.ResourceConfig, link string) (string, error) {
+	if resource.Stages() == 0 {
+		return "", errors.New("resource has no agents")
+	}
+	cached, hit, err := proc.cache.GetParserOutput(link, resource.ParserCacheKey())
+	if err != nil {
+		return "", fmt.Errorf("failed to load parser output with %w", err)
+	}
+	if !hit {
+		return "", errors.New("parsed content is not cached")
+	}
+	parsed, err := cache.DeserializeParserResponse(string(resource.ParserT), cached)
+	if err != nil {
+		return "", fmt.Errorf("failed to deserialize parser output with %w", err)
+	}
+
+	content := parsed.String()
+	n := 0
+	for _, name := range resource.Agents {
+		if name == agent.Discussion || name == agent.Relevance {
+			continue
+		}
+		n++
+		a, ok := proc.agents[name]
+		if !ok {
+			return "", fmt.Errorf("agent '%s' not found", name)
+		}
+		content, err = a.Process(ctx, content, agentOptions(resource, parsed))
+		if err != nil {
+			return "", fmt.Errorf("agent '%s' processing failed: %w", name, err)
+		}
+		if err := proc.cache.SetAgentOutput(link, resource.ParserCacheKey(), resource.StagePipeline(n), content); err != nil {
+			slog.Warn("failed to cache agent stage output", "error", err)
+		}
+	}
+	if err := proc.cache.SetAgentOutput(link, resource.ParserCacheKey(), resource.AgentPipeline(), content); err != nil {
+		slog.Warn("failed to cache agent output", "error", err)
+	}
+	return content, nil
+}
+
+// curated returns the run with the curated issue, dropped items are not
+// indexed for back-references
+func (run issueRun) curated(c *curation, started time.Time) issueRun {
+	kept := c.kept()
+	var processed []db.SaveProcessedItemParams
+	for _, item := range run.processedItems {
+		if kept[item.Url] {
+			processed = append(processed, item)
+		}
+	}
+	run.processedItems = processed
+	run.newsletter = c.newsletter()
+	run.stats = collectStats(run.newsletter, run.stats, started)
+	run.newsletter.Stats = &run.stats
+	return run
+}
+
+// plainText strips markup from rendered content for the terminal
+func plainText(content string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(htmlTagRe.ReplaceAllString(content, " "))), " ")
+}
+
+// wrap splits text into lines of at most width characters
+func wrap(text string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// truncate cuts s to width characters
+func truncate(s string, width int) string {
+	if r := []rune(s); width > 0 && len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
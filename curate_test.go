@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/db"
+)
+
+func curationIssue() Newsletter {
+	return Newsletter{Title: "Issue", Resources: []Resource{
+		{Name: "Blog A", Pages: []Page{{Title: "A1", Link: "https://a.example/1"}, {Title: "A2", Link: "https://a.example/2"}}},
+		{Name: "Blog B", Pages: []Page{{Title: "B1", Link: "https://b.example/1", Content: "<p>Old</p>"}}},
+	}}
+}
+
+// titles lists page titles of the issue in order, starred ones marked
+func titles(n Newsletter) string {
+	var got []string
+	for _, res := range n.Resources {
+		for _, page := range res.Pages {
+			title := page.Title
+			if page.Starred {
+				title += "*"
+			}
+			got = append(got, title)
+		}
+	}
+	return strings.Join(got, ",")
+}
+
+func TestCurate(t *testing.T) {
+	c := newCuration(curationIssue())
+	size := func() (int, int) { return 80, 24 }
+	var reruns []string
+	rerun := func(res Resource, page Page) (template.HTML, error) {
+		reruns = append(reruns, res.Name+" "+page.Link)
+		return "<p>New</p>", nil
+	}
+
+	// Move A2 above A1 and star it, K on the first item of a resource does
+	// nothing, B1 is re-run and A1 dropped before rendering
+	keys := "jKKs" + "\x1b[B\x1b[B" + "r" + "kd" + "y"
+	if err := curate(strings.NewReader(keys), io.Discard, c, size, rerun); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n := c.newsletter()
+	if got := titles(n); got != "A2*,B1" {
+		t.Errorf("expected curated pages A2*,B1, got %s", got)
+	}
+	if len(reruns) != 1 || reruns[0] != "Blog B https://b.example/1" {
+		t.Errorf("expected B1 to be re-run, got %v", reruns)
+	}
+	if n.Resources[1].Pages[0].Content != "<p>New</p>" {
+		t.Errorf("expected re-run content, got %q", n.Resources[1].Pages[0].Content)
+	}
+}
+
+func TestCurate_DropsEmptyResources(t *testing.T) {
+	c := newCuration(curationIssue())
+	c.cursor = 2
+	c.toggleDrop()
+	c.shift(-1) // B1 can't move into Blog A
+	if c.cursor != 2 {
+		t.Errorf("expected the item to stay in its resource, cursor at %d", c.cursor)
+	}
+	n := c.newsletter()
+	if len(n.Resources) != 1 || n.Resources[0].Name != "Blog A" {
+		t.Errorf("expected only Blog A left, got %+v", n.Resources)
+	}
+}
+
+func TestCurate_Abort(t *testing.T) {
+	c := newCuration(curationIssue())
+	err := curate(strings.NewReader("dq"), io.Discard, c, func() (int, int) { return 80, 24 }, nil)
+	if !errors.Is(err, errCurationAborted) {
+		t.Errorf("expected the curation to be aborted, got %v", err)
+	}
+}
+
+func TestIssueRun_Curated(t *testing.T) {
+	run := issueRun{
+		newsletter: curationIssue(),
+		processedItems: []db.SaveProcessedItemParams{
+			{Url: "https://a.example/1"}, {Url: "https://a.example/2"}, {Url: "https://b.example/1"},
+		},
+	}
+	c := newCuration(run.newsletter)
+	c.toggleDrop()
+
+	run = run.curated(c, time.Now())
+	if len(run.processedItems) != 2 || run.processedItems[0].Url != "https://a.example/2" {
+		t.Errorf("expected dropped items not to be indexed, got %+v", run.processedItems)
+	}
+	if run.stats.Items != 2 || run.newsletter.Stats == nil || run.newsletter.Stats.Items != 2 {
+		t.Errorf("expected stats of the curated issue, got %+v", run.stats)
+	}
+}
+
+func TestCurationView(t *testing.T) {
+	c := newCuration(curationIssue())
+	c.move(2)
+	c.toggleStar()
+	view := c.view(40, 12)
+	for _, want := range []string{"Blog A", "Blog B", "> ★ B1", "https://b.example/1", "Old", "j/k move"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected view to contain %q:\n%s", want, view)
+		}
+	}
+	if rows := strings.Count(view, "\r\n"); rows != 11 {
+		t.Errorf("expected the view to fill 12 rows, got %d", rows+1)
+	}
+}
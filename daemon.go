@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/daemon"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/fetcher/telegram"
+)
+
+// runDaemon serves the daemon HTTP endpoints and regenerates issues on schedule.
+// Every generation re-executes the binary so a failing run can't take the daemon down.
+// Non-empty pprofAddr serves runtime profiles of the daemon, generations serve
+// theirs at a free port of the same host.
+func runDaemon(cfgPath string, conf config.Config, pprofAddr string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable with %w", err)
+	}
+
+	// Scheduled generations run after a manual one instead of being skipped
+	args := []string{"-config", cfgPath, "-wait"}
+	if conf.Daemon.Approval {
+		if !conf.Email.Enabled() {
+			return fmt.Errorf("approval holds emails back, configure [email] delivery")
+		}
+		args = append(args, "-draft")
+	}
+	if pprofAddr != "" {
+		if err := servePprof(pprofAddr); err != nil {
+			return err
+		}
+		args = append(args, "-pprof", generationPprofAddr(pprofAddr))
+	}
+
+	run := func(ctx context.Context) error {
+		cmd := exec.CommandContext(ctx, executable, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("generation exited with %w", err)
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d := daemon.New(conf.Daemon, conf.OutputDirectory, run)
+	database, err := initDB(ctx, conf.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	queries := db.New(database)
+
+	// Notes and links can be pinned into the next issue from the dashboard
+	d.EnablePins(pinHooks(queries))
+
+	// Answers of agents are compared to the weeks before to notice model drift
+	d.EnableQuality(func(ctx context.Context) ([]daemon.AgentQuality, error) {
+		return agentQuality(ctx, queries, time.Now())
+	})
+
+	if conf.Daemon.PublicURL != "" {
+		d.EnableWebSub(newWebSub(conf, queries))
+	}
+	if conf.Daemon.Approval {
+		d.EnableApproval(approvalHooks(queries, conf.Email))
+	}
+	if conf.Daemon.TrackClicks {
+		d.EnableClickTracking(func(ctx context.Context, c daemon.Click) error {
+			return saveReadingEvent(ctx, queries, eventClick, c.URL, c.Item, c.Author)
+		})
+	}
+	if conf.Daemon.TelegramUpdates {
+		stream, err := newTelegramStream(conf, queries, filepath.Dir(cfgPath))
+		if err != nil {
+			return err
+		}
+		if stream != nil {
+			go func() {
+				if err := stream.Run(ctx); err != nil {
+					slog.Error("telegram stream stopped, channels are polled again", "error", err)
+				}
+			}()
+		}
+	}
+	return d.Run(ctx)
+}
+
+// newTelegramStream receives new posts of enabled Telegram channels while
+// the daemon runs, nil when there are none
+func newTelegramStream(conf config.Config, queries *db.Queries, configDir string) (*telegram.Stream, error) {
+	var channels []string
+	for _, r := range conf.Resources {
+		if r.IsEnabled() && r.T == config.TelegramChannel {
+			channels = append(channels, r.FeedURL)
+		}
+	}
+	if len(channels) == 0 {
+		return nil, nil
+	}
+
+	credPath, err := config.DefaultCredentialsPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate telegram credentials: %w", err)
+	}
+	creds, err := config.LoadOrPromptTelegramCredentials(credPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get telegram credentials: %w", err)
+	}
+
+	return telegram.NewStream(configDir, creds.AppID, creds.AppHash, creds.PhoneNumber, fetcher.TelegramLoginOptions(conf.TelegramLogin), channels, telegram.StreamHooks{
+		Push: func(ctx context.Context, channelURL string, feed fetcher.Feed) error {
+			if err := enqueueFeed(ctx, queries, channelURL, feed, time.Now().Unix()); err != nil {
+				return err
+			}
+			// Offsets only move forward, albums are queued after later posts
+			if offset, err := queries.GetFeedOffset(ctx, channelURL); err == nil && offset >= feed.Offset {
+				return nil
+			}
+			saveFetchState(ctx, queries, channelURL, feed)
+			return nil
+		},
+		Lease: func(ctx context.Context, channelURL string, expires time.Time) error {
+			return savePushLease(ctx, queries, channelURL, expires)
+		},
+	}), nil
+}
+
+// newWebSub subscribes enabled RSS resources advertising a hub, pushed
+// items are queued like fetched ones
+func newWebSub(conf config.Config, queries *db.Queries) *daemon.WebSub {
+	resources := make(map[string]config.ResourceConfig)
+	var feeds []string
+	for _, r := range conf.Resources {
+		if r.IsEnabled() && r.T == config.RSS {
+			resources[r.FeedURL] = r
+			feeds = append(feeds, r.FeedURL)
+		}
+	}
+
+	rss := fetcher.NewRSSFetcher(nil)
+	return daemon.NewWebSub(conf.Daemon.PublicURL, feeds, daemon.WebSubHooks{
+		Discover: func(ctx context.Context, feedURL string) (string, string, error) {
+			r := resources[feedURL]
+			hub, err := rss.DiscoverHub(ctx, feedURL, fetcher.FetchOptions{
+				Header:    r.Auth.Header(),
+				Proxy:     r.ProxyURL(conf.Proxy),
+				UserAgent: r.UserAgentFor(conf.UserAgent),
+			})
+			return hub.URL, hub.Topic, err
+		},
+		Push: func(ctx context.Context, feedURL string, body []byte) error {
+			feed, err := fetcher.ParseFeed(body)
+			if err != nil {
+				return err
+			}
+			return enqueueFeed(ctx, queries, feedURL, explodeFeed(resources[feedURL], feed), time.Now().Unix())
+		},
+		Lease: func(ctx context.Context, feedURL string, expires time.Time) error {
+			return savePushLease(ctx, queries, feedURL, expires)
+		},
+	})
+}
+
+// savePushLease records until when the daemon receives the feed, a zero
+// expiry makes generations poll it again
+func savePushLease(ctx context.Context, queries *db.Queries, feedURL string, expires time.Time) error {
+	if expires.IsZero() {
+		return queries.DeletePushLease(ctx, feedURL)
+	}
+	return queries.SavePushLease(ctx, db.SavePushLeaseParams{
+		FeedUrl:   feedURL,
+		ExpiresAt: expires.Unix(),
+	})
+}
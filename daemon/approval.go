@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrDraftDecided is returned when a draft was approved or rejected already
+var ErrDraftDecided = errors.New("draft is no longer pending")
+
+// approvalCheck is how often drafts are checked for auto-approval
+const approvalCheck = time.Minute
+
+// Draft is a generated issue held back until it is approved
+type Draft struct {
+	ID        int64     `json:"id"`
+	Title     string    `json:"title"`
+	Issue     string    `json:"issue"` // HTML file relative to the output directory
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ApprovalHooks connect the approval of drafts to their storage and delivery
+type ApprovalHooks struct {
+	// Pending lists drafts waiting for a decision, oldest first
+	Pending func(ctx context.Context) ([]Draft, error)
+	// Approve delivers the draft, ErrDraftDecided when it is not pending
+	Approve func(ctx context.Context, id int64) error
+	// Reject discards the draft, ErrDraftDecided when it is not pending
+	Reject func(ctx context.Context, id int64) error
+}
+
+// EnableApproval serves pending drafts on the dashboard and delivers the
+// ones left undecided for longer than auto_approve
+func (d *Daemon) EnableApproval(hooks ApprovalHooks) {
+	d.approval = &hooks
+}
+
+// autoApprove approves drafts pending for longer than auto_approve until
+// ctx is cancelled
+func (d *Daemon) autoApprove(ctx context.Context) {
+	ticker := time.NewTicker(approvalCheck)
+	defer ticker.Stop()
+	for {
+		d.approveExpired(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// approveExpired approves drafts created auto_approve or longer before now
+func (d *Daemon) approveExpired(ctx context.Context, now time.Time) {
+	drafts, err := d.approval.Pending(ctx)
+	if err != nil {
+		slog.Warn("daemon: failed to list drafts", "error", err)
+		return
+	}
+	for _, draft := range drafts {
+		if now.Sub(draft.CreatedAt) < d.cfg.AutoApprove.Duration {
+			continue
+		}
+		err := d.approval.Approve(ctx, draft.ID)
+		if err != nil && !errors.Is(err, ErrDraftDecided) {
+			slog.Error("daemon: failed to auto-approve draft", "id", draft.ID, "error", err)
+			continue
+		}
+		if err == nil {
+			slog.Info("daemon: draft auto-approved", "id", draft.ID, "title", draft.Title)
+		}
+	}
+}
+
+// pendingDraft is a draft as shown on the dashboard
+type pendingDraft struct {
+	Draft
+	AutoApproveAt time.Time // Zero when drafts wait for a decision
+}
+
+// pendingDrafts lists drafts waiting for a decision, nil when approval is disabled
+func (d *Daemon) pendingDrafts(ctx context.Context) ([]pendingDraft, error) {
+	if d.approval == nil {
+		return nil, nil
+	}
+	drafts, err := d.approval.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]pendingDraft, len(drafts))
+	for i, draft := range drafts {
+		pending[i].Draft = draft
+		if d.cfg.AutoApprove.Duration > 0 {
+			pending[i].AutoApproveAt = draft.CreatedAt.Add(d.cfg.AutoApprove.Duration)
+		}
+	}
+	return pending, nil
+}
+
+func (d *Daemon) handleDrafts(w http.ResponseWriter, r *http.Request) {
+	drafts, err := d.approval.Pending(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if drafts == nil {
+		drafts = []Draft{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(drafts)
+}
+
+// handleDecision approves or rejects the draft with decide, dashboard
+// forms are redirected back to the dashboard
+func (d *Daemon) handleDecision(decide func(ctx context.Context, id int64) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid draft id", http.StatusBadRequest)
+			return
+		}
+		// Detach from the request so a delivery isn't cut short
+		err = decide(context.WithoutCancel(r.Context()), id)
+		switch {
+		case errors.Is(err, ErrDraftDecided):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// fakeDrafts keeps drafts in memory, approved and rejected ones are removed
+type fakeDrafts struct {
+	drafts   []Draft
+	approved []int64
+	rejected []int64
+}
+
+func (f *fakeDrafts) decide(into *[]int64) func(context.Context, int64) error {
+	return func(ctx context.Context, id int64) error {
+		for i, d := range f.drafts {
+			if d.ID == id {
+				f.drafts = append(f.drafts[:i], f.drafts[i+1:]...)
+				*into = append(*into, id)
+				return nil
+			}
+		}
+		return ErrDraftDecided
+	}
+}
+
+func (f *fakeDrafts) hooks() ApprovalHooks {
+	return ApprovalHooks{
+		Pending: func(context.Context) ([]Draft, error) { return f.drafts, nil },
+		Approve: f.decide(&f.approved),
+		Reject:  f.decide(&f.rejected),
+	}
+}
+
+func TestApproveExpired(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	f := &fakeDrafts{drafts: []Draft{
+		{ID: 1, CreatedAt: now.Add(-5 * time.Hour)},
+		{ID: 2, CreatedAt: now.Add(-time.Hour)},
+	}}
+	d := New(config.Daemon{AutoApprove: config.Duration{Duration: 4 * time.Hour}}, t.TempDir(), nil)
+	d.EnableApproval(f.hooks())
+
+	d.approveExpired(context.Background(), now)
+	if len(f.approved) != 1 || f.approved[0] != 1 {
+		t.Errorf("expected only the draft pending for 5h to be approved, got %v", f.approved)
+	}
+	if len(f.drafts) != 1 || f.drafts[0].ID != 2 {
+		t.Errorf("expected the recent draft to stay pending, got %+v", f.drafts)
+	}
+}
+
+func TestDraftEndpoints(t *testing.T) {
+	f := &fakeDrafts{drafts: []Draft{
+		{ID: 1, Title: "Issue 1", Issue: "2025_03_01/myfeed_2025_03_01.html", CreatedAt: time.Now()},
+		{ID: 2, Title: "Issue 2", Issue: "2025_03_02/myfeed_2025_03_02.html", CreatedAt: time.Now()},
+	}}
+	d := New(config.Daemon{AutoApprove: config.Duration{Duration: time.Hour}}, t.TempDir(), nil)
+	d.EnableApproval(f.hooks())
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	resp, err := client.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Waiting for approval", `href="/issues/2025_03_01/myfeed_2025_03_01.html"`, "/api/drafts/2/reject", "unless rejected"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected dashboard to contain %q:\n%s", want, body)
+		}
+	}
+
+	// Dashboard forms are redirected back, API calls get no content
+	resp, err = client.PostForm(srv.URL+"/api/drafts/1/approve", url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("expected a redirect after approving on the dashboard, got %d", resp.StatusCode)
+	}
+	for path, status := range map[string]int{
+		"/api/drafts/2/reject":  http.StatusNoContent,
+		"/api/drafts/1/reject":  http.StatusConflict,
+		"/api/drafts/x/approve": http.StatusBadRequest,
+	} {
+		resp, err := client.Post(srv.URL+path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: got %d, want %d", path, resp.StatusCode, status)
+		}
+	}
+	if len(f.approved) != 1 || len(f.rejected) != 1 {
+		t.Errorf("expected one approved and one rejected draft, got %v and %v", f.approved, f.rejected)
+	}
+}
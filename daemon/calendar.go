@@ -0,0 +1,156 @@
+package daemon
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+const (
+	dateLayout    = "2006-01-02"
+	icsDateLayout = "20060102"
+
+	// maxEventDays bounds multi-day events of broken calendars
+	maxEventDays = 366
+)
+
+// Calendar decides on which days issues are generated
+type Calendar struct {
+	weekdays map[time.Weekday]bool
+	dates    map[string]bool // "2006-01-02"
+	yearly   map[string]bool // "01-02", recurring every year
+}
+
+// NewCalendar builds the calendar from the skip settings of the daemon config
+func NewCalendar(cfg config.Daemon) (*Calendar, error) {
+	c := &Calendar{
+		weekdays: make(map[time.Weekday]bool),
+		dates:    make(map[string]bool),
+		yearly:   make(map[string]bool),
+	}
+
+	for _, name := range cfg.SkipWeekdays {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		c.weekdays[day] = true
+	}
+
+	for _, date := range cfg.SkipDates {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf("invalid skip date '%s', expected YYYY-MM-DD", date)
+		}
+		c.dates[date] = true
+	}
+
+	if cfg.Holidays != "" {
+		f, err := os.Open(cfg.Holidays)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open holiday calendar with %w", err)
+		}
+		defer f.Close()
+		if err := c.addICS(f); err != nil {
+			return nil, fmt.Errorf("failed to parse holiday calendar at '%s' with %w", cfg.Holidays, err)
+		}
+	}
+	return c, nil
+}
+
+// Skips reports whether no issue should be generated on the day of t
+func (c *Calendar) Skips(t time.Time) bool {
+	return c.weekdays[t.Weekday()] || c.dates[t.Format(dateLayout)] || c.yearly[t.Format("01-02")]
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if n := strings.ToLower(name); n == full || n == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid skip weekday '%s'", name)
+}
+
+// addICS adds the all-day events of an iCalendar (RFC 5545) file.
+// Events recurring yearly (RRULE:FREQ=YEARLY) are skipped every year on the
+// same date, other recurrence rules are not supported.
+func (c *Calendar) addICS(r io.Reader) error {
+	var start, end time.Time
+	var yearly, inEvent bool
+
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(strings.ToUpper(name), ";") // Drop parameters, e.g., ;VALUE=DATE
+
+		switch name {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent = true
+				start, end, yearly = time.Time{}, time.Time{}, false
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent || len(value) < len(icsDateLayout) {
+				continue
+			}
+			date, err := time.Parse(icsDateLayout, value[:len(icsDateLayout)])
+			if err != nil {
+				return fmt.Errorf("invalid %s '%s'", name, value)
+			}
+			if name == "DTSTART" {
+				start = date
+			} else {
+				end = date
+			}
+		case "RRULE":
+			yearly = strings.Contains(strings.ToUpper(value), "FREQ=YEARLY")
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				continue
+			}
+			// DTEND of all-day events is exclusive
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day, n := start, 0; day.Before(end) && n < maxEventDays; day, n = day.AddDate(0, 0, 1), n+1 {
+				if yearly {
+					c.yearly[day.Format("01-02")] = true
+				} else {
+					c.dates[day.Format(dateLayout)] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// unfoldICS joins continuation lines, which start with a space or a tab
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
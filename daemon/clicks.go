@@ -0,0 +1,41 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// maxClickSize limits the body of a reported click
+const maxClickSize = 4 << 10
+
+// Click is a link opened in an issue served by the daemon
+type Click struct {
+	URL    string `json:"url"`    // Opened link
+	Item   string `json:"item"`   // Link of the item it was opened in
+	Author string `json:"author"` // Author of the item, empty if unknown
+}
+
+// EnableClickTracking records links opened in issues served by the daemon
+// with record
+func (d *Daemon) EnableClickTracking(record func(ctx context.Context, c Click) error) {
+	d.clicks = record
+}
+
+func (d *Daemon) handleClick(w http.ResponseWriter, r *http.Request) {
+	var c Click
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxClickSize)).Decode(&c); err != nil {
+		http.Error(w, "invalid click", http.StatusBadRequest)
+		return
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "invalid link", http.StatusBadRequest)
+		return
+	}
+	if err := d.clicks(r.Context(), c); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
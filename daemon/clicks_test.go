@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestHandleClick(t *testing.T) {
+	var clicks []Click
+	d := New(config.Daemon{}, t.TempDir(), nil)
+	d.EnableClickTracking(func(ctx context.Context, c Click) error {
+		clicks = append(clicks, c)
+		return nil
+	})
+	h := d.Handler()
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{body: `{"url": "https://example.com/post", "item": "https://news.example/1", "author": "Jane"}`, want: http.StatusNoContent},
+		{body: `{"url": "javascript:alert(1)"}`, want: http.StatusBadRequest},
+		{body: `not json`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/clicks", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.body, tt.want, rec.Code)
+		}
+	}
+	if len(clicks) != 1 || clicks[0].Author != "Jane" || clicks[0].Item != "https://news.example/1" {
+		t.Errorf("expected the valid click to be recorded, got %+v", clicks)
+	}
+}
+
+func TestHandleClick_Disabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(config.Daemon{}, t.TempDir(), nil).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/clicks", strings.NewReader(`{}`)))
+	if rec.Code == http.StatusNoContent {
+		t.Error("expected clicks not to be accepted without tracking")
+	}
+}
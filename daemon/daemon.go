@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// ErrRunInProgress is returned when a generation is triggered while another one is running
+var ErrRunInProgress = errors.New("generation already in progress")
+
+// RunFunc generates a single issue
+type RunFunc func(ctx context.Context) error
+
+// Status describes the generation history of the running daemon
+type Status struct {
+	Running       bool      `json:"running"`
+	Runs          int       `json:"runs"`
+	Failures      int       `json:"failures"`
+	LastRunAt     time.Time `json:"last_run_at"`
+	LastSuccessAt time.Time `json:"last_success_at"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextRunAt     time.Time `json:"next_run_at"`
+}
+
+// Daemon periodically generates issues and serves HTTP endpoints
+type Daemon struct {
+	cfg       config.Daemon
+	outputDir string
+	run       RunFunc
+	websub    *WebSub
+	approval  *ApprovalHooks
+	clicks    func(ctx context.Context, c Click) error
+	pins      *PinHooks
+	quality   func(ctx context.Context) ([]AgentQuality, error)
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a daemon running generations with run
+func New(cfg config.Daemon, outputDir string, run RunFunc) *Daemon {
+	return &Daemon{
+		cfg:       cfg,
+		outputDir: outputDir,
+		run:       run,
+	}
+}
+
+// EnableWebSub serves WebSub callbacks and subscribes feeds while the daemon runs
+func (d *Daemon) EnableWebSub(w *WebSub) {
+	d.websub = w
+}
+
+// Run starts the HTTP server and the generation schedule,
+// blocking until the context is cancelled or the server fails
+func (d *Daemon) Run(ctx context.Context) error {
+	sched, err := NewSchedule(d.cfg)
+	if err != nil {
+		return err
+	}
+	srv, err := d.newServer()
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- d.serve(srv)
+	}()
+	go d.schedule(ctx, sched)
+	if d.websub != nil {
+		go d.websub.Run(ctx)
+	}
+	if d.approval != nil && d.cfg.AutoApprove.Duration > 0 {
+		go d.autoApprove(ctx)
+	}
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+// Status returns a snapshot of the current status
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.status
+}
+
+// Trigger starts a generation in the background unless one is already running
+func (d *Daemon) Trigger(ctx context.Context) error {
+	if !d.begin() {
+		return ErrRunInProgress
+	}
+	go d.execute(ctx)
+	return nil
+}
+
+func (d *Daemon) schedule(ctx context.Context, sched *Schedule) {
+	next := sched.First(time.Now(), d.loadLastRun())
+	for {
+		d.mu.Lock()
+		d.status.NextRunAt = next
+		d.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if d.begin() {
+			d.execute(ctx)
+		} else {
+			slog.Info("skipping scheduled generation, previous one still running")
+		}
+		next = sched.Next(time.Now())
+	}
+}
+
+// begin marks a generation as running, returns false if one already is
+func (d *Daemon) begin() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.status.Running {
+		return false
+	}
+	d.status.Running = true
+	return true
+}
+
+func (d *Daemon) execute(ctx context.Context) {
+	slog.Info("daemon: generation started")
+	started := time.Now()
+	err := d.safeRun(ctx)
+
+	d.saveLastRun(started)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.status.Running = false
+	d.status.Runs++
+	d.status.LastRunAt = started
+	if err != nil {
+		d.status.Failures++
+		d.status.LastError = err.Error()
+		slog.Error("daemon: generation failed", "error", err, "took", time.Since(started))
+		return
+	}
+	d.status.LastSuccessAt = started
+	d.status.LastError = ""
+	slog.Info("daemon: generation finished", "took", time.Since(started))
+}
+
+func (d *Daemon) safeRun(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("generation panicked: %v", r)
+		}
+	}()
+	return d.run(ctx)
+}
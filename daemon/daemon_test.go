@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestHandlerRequiresToken(t *testing.T) {
+	d := New(config.Daemon{APIToken: "secret"}, t.TempDir(), func(context.Context) error { return nil })
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	tests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{"/healthz", "", http.StatusOK},
+		{"/api/status", "", http.StatusUnauthorized},
+		{"/api/status", "wrong", http.StatusUnauthorized},
+		{"/api/status", "secret", http.StatusOK},
+		{"/metrics", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s with token %q: got %d, want %d", tt.path, tt.token, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestHandlerBrowserSession(t *testing.T) {
+	d := New(config.Daemon{APIToken: "secret"}, t.TempDir(), func(context.Context) error { return nil })
+	d.EnableClickTracking(func(context.Context, Click) error { return nil })
+	d.EnablePins(PinHooks{Pending: func(context.Context) ([]Pin, error) { return nil, nil }})
+	h := d.Handler()
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/issues/a.html?token=wrong", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong token to be rejected, got %d", rec.Code)
+	}
+	rec = serve(httptest.NewRequest(http.MethodGet, "/issues/a.html?token=secret&page=2", nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/issues/a.html?page=2" {
+		t.Fatalf("expected a redirect without the token, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly {
+		t.Fatalf("expected an HttpOnly session cookie, got %+v", cookies)
+	}
+	session := cookies[0]
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(session)
+	rec = serve(req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the dashboard with the session, got %d", rec.Code)
+	}
+	csrf := d.csrfToken(req)
+	if !strings.Contains(rec.Body.String(), csrf) {
+		t.Error("expected the dashboard forms to carry the CSRF token")
+	}
+
+	post := func(form string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(session)
+		return serve(req).Code
+	}
+	if code := post(""); code != http.StatusForbidden {
+		t.Errorf("expected a post without the CSRF token to be rejected, got %d", code)
+	}
+	if code := post("csrf=" + csrf); code != http.StatusAccepted {
+		t.Errorf("expected a post with the CSRF token to be accepted, got %d", code)
+	}
+
+	forged := *session
+	forged.Value = "9999999999.00"
+	req = httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	req.AddCookie(&forged)
+	if code := serve(req).Code; code != http.StatusUnauthorized {
+		t.Errorf("expected a forged session to be rejected, got %d", code)
+	}
+	if d.validSession(d.newSession(time.Now().Add(-sessionTTL-time.Minute)), time.Now()) {
+		t.Error("expected an expired session to be rejected")
+	}
+
+	rec = serve(httptest.NewRequest(http.MethodPost, "/api/clicks", strings.NewReader(`{"url": "https://example.com"}`)))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected clicks without credentials to be recorded, got %d", rec.Code)
+	}
+}
+
+func TestTriggerRejectsConcurrentRuns(t *testing.T) {
+	release := make(chan struct{})
+	cfg := config.Daemon{StateFile: filepath.Join(t.TempDir(), "daemon.json")}
+	d := New(cfg, t.TempDir(), func(context.Context) error {
+		<-release
+		return nil
+	})
+
+	if err := d.Trigger(context.Background()); err != nil {
+		t.Fatalf("first trigger failed: %v", err)
+	}
+	if err := d.Trigger(context.Background()); err != ErrRunInProgress {
+		t.Fatalf("expected ErrRunInProgress, got %v", err)
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for d.Status().Running && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := d.Status(); s.Runs != 1 || s.Failures != 0 {
+		t.Errorf("unexpected status %+v", s)
+	}
+}
+
+func TestNewServerRefusesPublicBindWithoutAuth(t *testing.T) {
+	d := New(config.Daemon{Bind: "0.0.0.0:8080"}, t.TempDir(), nil)
+	if _, err := d.newServer(); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Fatalf("expected refusal, got %v", err)
+	}
+
+	d = New(config.Daemon{Bind: "0.0.0.0:8080", APIToken: "secret"}, t.TempDir(), nil)
+	if _, err := d.newServer(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	for bind, want := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		"0.0.0.0:8080":   false,
+		":8080":          false,
+	} {
+		if got := isLoopback(bind); got != want {
+			t.Errorf("isLoopback(%q) = %v, want %v", bind, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrPinIssued is returned when a pin went into an issue already
+var ErrPinIssued = errors.New("pin is no longer waiting for an issue")
+
+// Pin is a note or link pinned into the next issue
+type Pin struct {
+	ID        int64     `json:"id"`
+	Text      string    `json:"text"`
+	Section   string    `json:"section"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PinHooks connect pinning on the dashboard to the storage of pins
+type PinHooks struct {
+	// Pending lists pins waiting for the next issue, oldest first
+	Pending func(ctx context.Context) ([]Pin, error)
+	// Add pins the text into the section, the default one when empty
+	Add func(ctx context.Context, text, section string) (int64, error)
+	// Remove unpins the pin, ErrPinIssued when it is not waiting
+	Remove func(ctx context.Context, id int64) error
+}
+
+// EnablePins lists pins on the dashboard and lets new ones be added
+func (d *Daemon) EnablePins(hooks PinHooks) {
+	d.pins = &hooks
+}
+
+// pendingPins lists pins waiting for the next issue, nil when pinning is disabled
+func (d *Daemon) pendingPins(ctx context.Context) ([]Pin, error) {
+	if d.pins == nil {
+		return nil, nil
+	}
+	return d.pins.Pending(ctx)
+}
+
+func (d *Daemon) handlePins(w http.ResponseWriter, r *http.Request) {
+	pins, err := d.pins.Pending(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pins == nil {
+		pins = []Pin{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pins)
+}
+
+// handleAddPin pins a JSON {"text", "section"} or the dashboard form
+func (d *Daemon) handleAddPin(w http.ResponseWriter, r *http.Request) {
+	form := r.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
+	var pin Pin
+	if form {
+		pin.Text, pin.Section = r.PostFormValue("text"), r.PostFormValue("section")
+	} else if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+		http.Error(w, "invalid pin", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(pin.Text) == "" {
+		http.Error(w, "nothing to pin", http.StatusBadRequest)
+		return
+	}
+	id, err := d.pins.Add(r.Context(), pin.Text, pin.Section)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if form {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"id": id})
+}
+
+func (d *Daemon) handleRemovePin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid pin id", http.StatusBadRequest)
+		return
+	}
+	err = d.pins.Remove(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrPinIssued):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// fakePins keeps pins in memory
+type fakePins struct {
+	pins []Pin
+}
+
+func (f *fakePins) hooks() PinHooks {
+	return PinHooks{
+		Pending: func(context.Context) ([]Pin, error) { return f.pins, nil },
+		Add: func(_ context.Context, text, section string) (int64, error) {
+			id := int64(len(f.pins) + 1)
+			f.pins = append(f.pins, Pin{ID: id, Text: text, Section: section})
+			return id, nil
+		},
+		Remove: func(_ context.Context, id int64) error {
+			for i, p := range f.pins {
+				if p.ID == id {
+					f.pins = append(f.pins[:i], f.pins[i+1:]...)
+					return nil
+				}
+			}
+			return ErrPinIssued
+		},
+	}
+}
+
+func TestPinEndpoints(t *testing.T) {
+	f := &fakePins{}
+	d := New(config.Daemon{}, t.TempDir(), nil)
+	d.EnablePins(f.hooks())
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	// Dashboard forms are redirected back, API calls get the id
+	resp, err := client.PostForm(srv.URL+"/api/pins", url.Values{"text": {"Renew domain"}, "section": {"Personal"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("expected a redirect after pinning on the dashboard, got %d", resp.StatusCode)
+	}
+	resp, err = client.Post(srv.URL+"/api/pins", "application/json", strings.NewReader(`{"text": "https://example.com/talk"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || strings.TrimSpace(string(body)) != `{"id":2}` {
+		t.Errorf("expected the pin to be created, got %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = client.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"Pinned for the next issue", "Personal: Renew domain", "/api/pins/2/remove", `<form method="post" action="/api/pins">`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected dashboard to contain %q:\n%s", want, body)
+		}
+	}
+
+	for _, tt := range []struct {
+		path   string
+		body   string
+		status int
+	}{
+		{"/api/pins", `{"text": " "}`, http.StatusBadRequest},
+		{"/api/pins/1/remove", "", http.StatusNoContent},
+		{"/api/pins/1/remove", "", http.StatusConflict},
+		{"/api/pins/x/remove", "", http.StatusBadRequest},
+	} {
+		resp, err := client.Post(srv.URL+tt.path, "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: got %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+	}
+	if len(f.pins) != 1 || f.pins[0].ID != 2 {
+		t.Errorf("expected only the link to stay pinned, got %+v", f.pins)
+	}
+}
@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// QualityWindow describes the answers of an agent over a period
+type QualityWindow struct {
+	Calls                 int64   `json:"calls"`
+	LengthRatio           float64 `json:"length_ratio"`            // Characters of answers per character of content
+	LanguageMismatchRate  float64 `json:"language_mismatch_rate"`  // Share of answers in a wrong language
+	ContractViolationRate float64 `json:"contract_violation_rate"` // Share of answers breaking the output contract
+	FailureRate           float64 `json:"failure_rate"`            // Share of calls without an answer
+}
+
+// AgentQuality compares recent answers of an agent and model to its baseline
+type AgentQuality struct {
+	Agent    string        `json:"agent"`
+	Model    string        `json:"model"`
+	Recent   QualityWindow `json:"recent"`
+	Baseline QualityWindow `json:"baseline"`
+	Drift    []string      `json:"drift,omitempty"` // Metrics which moved away from the baseline
+}
+
+// EnableQuality shows quality drift of agents on the dashboard with trends
+func (d *Daemon) EnableQuality(trends func(ctx context.Context) ([]AgentQuality, error)) {
+	d.quality = trends
+}
+
+// qualityTrends lists quality drift of agents, nil when it is disabled
+func (d *Daemon) qualityTrends(ctx context.Context) ([]AgentQuality, error) {
+	if d.quality == nil {
+		return nil, nil
+	}
+	return d.quality(ctx)
+}
+
+func (d *Daemon) handleQuality(w http.ResponseWriter, r *http.Request) {
+	trends, err := d.quality(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if trends == nil {
+		trends = []AgentQuality{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trends)
+}
@@ -0,0 +1,49 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestQualityEndpoints(t *testing.T) {
+	d := New(config.Daemon{}, t.TempDir(), nil)
+	d.EnableQuality(func(context.Context) ([]AgentQuality, error) {
+		return []AgentQuality{{
+			Agent:    "summary",
+			Model:    "gemini-test",
+			Recent:   QualityWindow{Calls: 20, LengthRatio: 0.05, LanguageMismatchRate: 0.5},
+			Baseline: QualityWindow{Calls: 40, LengthRatio: 0.2},
+			Drift:    []string{"shorter answers", "wrong language"},
+		}}, nil
+	})
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	for _, want := range []string{"Agent quality", "gemini-test", "0.05 (0.20)", "50% (0%)", "<strong>shorter answers</strong>, <strong>wrong language</strong>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected dashboard to contain %q:\n%s", want, body)
+		}
+	}
+
+	resp, err = http.Get(srv.URL + "/api/quality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"drift":["shorter answers","wrong language"]`) {
+		t.Errorf("expected quality trends, got %d %s", resp.StatusCode, body)
+	}
+}
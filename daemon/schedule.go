@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// maxSkippedSlots bounds the search for the next slot, e.g., when every weekday is skipped
+const maxSkippedSlots = 10000
+
+// Schedule computes generation times: every interval or daily at a fixed
+// time, never on days skipped by the calendar
+type Schedule struct {
+	interval time.Duration
+	at       time.Duration // Offset from local midnight
+	daily    bool
+	calendar *Calendar
+}
+
+// NewSchedule creates the schedule of the daemon config
+func NewSchedule(cfg config.Daemon) (*Schedule, error) {
+	calendar, err := NewCalendar(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schedule{interval: cfg.Interval.Duration, calendar: calendar}
+	if s.interval <= 0 {
+		s.interval = 24 * time.Hour
+	}
+	if cfg.At != "" {
+		at, err := time.Parse("15:04", cfg.At)
+		if err != nil {
+			return nil, fmt.Errorf("invalid daemon time '%s', expected HH:MM", cfg.At)
+		}
+		s.daily = true
+		s.at = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+	return s, nil
+}
+
+// Next returns the first slot after t on a day which is not skipped
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := s.after(t)
+	for i := 0; s.calendar.Skips(next) && i < maxSkippedSlots; i++ {
+		next = s.after(next)
+	}
+	return next
+}
+
+// First returns when to generate after the daemon starts given the last run:
+// right away on the first start or when a slot was missed during downtime
+func (s *Schedule) First(now, lastRun time.Time) time.Time {
+	if !lastRun.IsZero() {
+		next := s.Next(lastRun)
+		if next.After(now) {
+			return next
+		}
+		slog.Info("catching up on generation missed during downtime", "last_run", lastRun, "missed", next)
+	}
+	if s.calendar.Skips(now) {
+		return s.Next(now)
+	}
+	return now
+}
+
+func (s *Schedule) after(t time.Time) time.Time {
+	if !s.daily {
+		return t.Add(s.interval)
+	}
+	y, m, d := t.Date()
+	next := time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Add(s.at)
+	if !next.After(t) {
+		next = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Add(s.at)
+	}
+	return next
+}
+
+// state is persisted between daemon restarts to catch up on missed runs
+type state struct {
+	LastRunAt time.Time `json:"last_run_at"`
+}
+
+func (d *Daemon) statePath() string {
+	if d.cfg.StateFile != "" {
+		return d.cfg.StateFile
+	}
+	return filepath.Join(os.Getenv("HOME"), ".cache", "myfeed", "daemon.json")
+}
+
+// loadLastRun returns the time of the last generation, zero if unknown
+func (d *Daemon) loadLastRun() time.Time {
+	data, err := os.ReadFile(d.statePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Warn("failed to read daemon state", "error", err)
+		}
+		return time.Time{}
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		slog.Warn("failed to decode daemon state", "error", err)
+		return time.Time{}
+	}
+	return st.LastRunAt
+}
+
+func (d *Daemon) saveLastRun(t time.Time) {
+	path := d.statePath()
+	data, err := json.Marshal(state{LastRunAt: t})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("failed to save daemon state", "error", err)
+	}
+}
@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+const testHolidays = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"DTSTART;VALUE=DATE:20251225\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Long\r\n" +
+	" weekend\r\n" +
+	"DTSTART;VALUE=DATE:20260505\r\n" +
+	"DTEND;VALUE=DATE:20260507\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalendarSkips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "holidays.ics")
+	if err := os.WriteFile(path, []byte(testHolidays), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cal, err := NewCalendar(config.Daemon{
+		SkipWeekdays: []string{"saturday", "Sun"},
+		SkipDates:    []string{"2026-03-02"},
+		Holidays:     path,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		date string
+		skip bool
+	}{
+		{"2026-02-28", true},  // Saturday
+		{"2026-03-01", true},  // Sunday
+		{"2026-03-02", true},  // Skip date
+		{"2026-03-03", false}, // Tuesday
+		{"2027-12-25", true},  // Yearly holiday
+		{"2026-05-05", true},  // Multi-day event
+		{"2026-05-06", true},
+		{"2026-05-07", false}, // DTEND is exclusive
+	}
+	for _, tt := range tests {
+		day, _ := time.Parse(dateLayout, tt.date)
+		if got := cal.Skips(day); got != tt.skip {
+			t.Errorf("Skips(%s) = %v, want %v", tt.date, got, tt.skip)
+		}
+	}
+}
+
+func TestNewCalendarRejectsInvalidSettings(t *testing.T) {
+	for _, cfg := range []config.Daemon{
+		{SkipWeekdays: []string{"someday"}},
+		{SkipDates: []string{"25.12.2025"}},
+		{Holidays: filepath.Join(t.TempDir(), "missing.ics")},
+	} {
+		if _, err := NewCalendar(cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	s, err := NewSchedule(config.Daemon{At: "07:00", SkipWeekdays: []string{"saturday", "sunday"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	friday := time.Date(2026, 2, 27, 8, 0, 0, 0, time.Local)
+	want := time.Date(2026, 3, 2, 7, 0, 0, 0, time.Local) // Monday
+	if got := s.Next(friday); !got.Equal(want) {
+		t.Errorf("Next(friday) = %v, want %v", got, want)
+	}
+
+	early := time.Date(2026, 3, 3, 6, 0, 0, 0, time.Local)
+	want = time.Date(2026, 3, 3, 7, 0, 0, 0, time.Local)
+	if got := s.Next(early); !got.Equal(want) {
+		t.Errorf("Next(early) = %v, want %v", got, want)
+	}
+
+	if _, err := NewSchedule(config.Daemon{At: "7am"}); err == nil || !strings.Contains(err.Error(), "HH:MM") {
+		t.Errorf("expected invalid time error, got %v", err)
+	}
+}
+
+func TestScheduleFirstCatchesUp(t *testing.T) {
+	s, err := NewSchedule(config.Daemon{At: "07:00", SkipWeekdays: []string{"saturday", "sunday"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	monday := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+
+	// Down since Friday morning, Monday's 07:00 slot was missed
+	friday := time.Date(2026, 2, 27, 7, 0, 0, 0, time.Local)
+	if got := s.First(monday, friday); !got.Equal(monday) {
+		t.Errorf("expected catch-up run right away, got %v", got)
+	}
+
+	// Already generated today
+	today := time.Date(2026, 3, 2, 7, 0, 0, 0, time.Local)
+	want := time.Date(2026, 3, 3, 7, 0, 0, 0, time.Local)
+	if got := s.First(monday, today); !got.Equal(want) {
+		t.Errorf("First() = %v, want %v", got, want)
+	}
+
+	// First start on a skipped day waits for Monday
+	saturday := time.Date(2026, 2, 28, 9, 0, 0, 0, time.Local)
+	want = time.Date(2026, 3, 2, 7, 0, 0, 0, time.Local)
+	if got := s.First(saturday, time.Time{}); !got.Equal(want) {
+		t.Errorf("First() = %v, want %v", got, want)
+	}
+}
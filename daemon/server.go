@@ -0,0 +1,314 @@
+package daemon
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+var dashboardTmpl = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"percent": func(rate float64) string { return fmt.Sprintf("%.0f%%", rate*100) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><title>myfeed</title></head>
+<body>
+<h1>myfeed</h1>
+<p>Runs: {{.Status.Runs}}, failures: {{.Status.Failures}}{{if .Status.Running}}, generating now{{end}}</p>
+{{if .Status.LastError}}<p>Last error: {{.Status.LastError}}</p>{{end}}
+{{if .Drafts}}<h2>Waiting for approval</h2>
+<ul>
+{{range .Drafts}}<li><a href="/issues/{{.Issue}}">{{.Title}}</a>, generated {{.CreatedAt.Format "2006-01-02 15:04"}}{{if not .AutoApproveAt.IsZero}}, delivered at {{.AutoApproveAt.Format "2006-01-02 15:04"}} unless rejected{{end}}
+<form method="post" action="/api/drafts/{{.ID}}/approve" style="display:inline"><input type="hidden" name="csrf" value="{{$.CSRF}}"><button>Approve</button></form>
+<form method="post" action="/api/drafts/{{.ID}}/reject" style="display:inline"><input type="hidden" name="csrf" value="{{$.CSRF}}"><button>Reject</button></form></li>
+{{end}}</ul>
+{{end}}{{if .PinsEnabled}}<h2>Pinned for the next issue</h2>
+<ul>
+{{range .Pins}}<li>{{.Section}}: {{.Text}}
+<form method="post" action="/api/pins/{{.ID}}/remove" style="display:inline"><input type="hidden" name="csrf" value="{{$.CSRF}}"><button>Unpin</button></form></li>
+{{end}}</ul>
+<form method="post" action="/api/pins">
+<input type="hidden" name="csrf" value="{{.CSRF}}">
+<input name="text" placeholder="Note or link" required size="40">
+<input name="section" placeholder="Section (Pinned)">
+<button>Pin</button>
+</form>
+{{end}}{{if .Quality}}<h2>Agent quality</h2>
+<p>The last 7 days against the 4 weeks before them.</p>
+<table>
+<tr><th>Agent</th><th>Model</th><th>Calls</th><th>Length ratio</th><th>Wrong language</th><th>Broken contract</th><th>Failures</th><th>Drift</th></tr>
+{{range .Quality}}<tr><td>{{.Agent}}</td><td>{{.Model}}</td><td>{{.Recent.Calls}}</td>
+<td>{{printf "%.2f" .Recent.LengthRatio}} ({{printf "%.2f" .Baseline.LengthRatio}})</td>
+<td>{{percent .Recent.LanguageMismatchRate}} ({{percent .Baseline.LanguageMismatchRate}})</td>
+<td>{{percent .Recent.ContractViolationRate}} ({{percent .Baseline.ContractViolationRate}})</td>
+<td>{{percent .Recent.FailureRate}} ({{percent .Baseline.FailureRate}})</td>
+<td>{{range $i, $d := .Drift}}{{if $i}}, {{end}}<strong>{{$d}}</strong>{{end}}</td></tr>
+{{end}}</table>
+{{end}}<ul>
+{{range .Issues}}<li><a href="/issues/{{.}}">{{.}}</a></li>
+{{end}}</ul>
+</body>
+</html>`))
+
+// Handler returns the HTTP handler with all daemon endpoints.
+// Everything except /healthz, WebSub callbacks, which are authenticated
+// by their signature, and reported clicks, which served issues send without
+// credentials, requires the API token when it is configured.
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	if d.websub != nil {
+		mux.Handle("/websub/", d.websub.Handler())
+	}
+	if d.clicks != nil {
+		mux.HandleFunc("POST /api/clicks", d.handleClick)
+	}
+
+	api := http.NewServeMux()
+	api.HandleFunc("GET /{$}", d.handleDashboard)
+	api.HandleFunc("GET /api/status", d.handleStatus)
+	api.HandleFunc("POST /api/run", d.handleRun)
+	api.HandleFunc("GET /metrics", d.handleMetrics)
+	if d.approval != nil {
+		api.HandleFunc("GET /api/drafts", d.handleDrafts)
+		api.HandleFunc("POST /api/drafts/{id}/approve", d.handleDecision(d.approval.Approve))
+		api.HandleFunc("POST /api/drafts/{id}/reject", d.handleDecision(d.approval.Reject))
+	}
+	if d.pins != nil {
+		api.HandleFunc("GET /api/pins", d.handlePins)
+		api.HandleFunc("POST /api/pins", d.handleAddPin)
+		api.HandleFunc("POST /api/pins/{id}/remove", d.handleRemovePin)
+	}
+	if d.quality != nil {
+		api.HandleFunc("GET /api/quality", d.handleQuality)
+	}
+	api.Handle("GET /issues/", http.StripPrefix("/issues/", http.FileServer(http.Dir(d.outputDir))))
+	mux.Handle("/", d.requireToken(api))
+
+	return mux
+}
+
+// requireToken checks the "Authorization: Bearer <token>" header or the
+// session cookie browsers get by opening any page with ?token=<token> once.
+// Posts authenticated by the cookie must carry the CSRF token in the csrf
+// form field or the X-CSRF-Token header.
+func (d *Daemon) requireToken(next http.Handler) http.Handler {
+	if d.cfg.APIToken == "" {
+		return next
+	}
+	expected := []byte("Bearer " + d.cfg.APIToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token := r.URL.Query().Get("token"); token != "" && r.Method == http.MethodGet &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(d.cfg.APIToken)) == 1 {
+			d.login(w, r)
+			return
+		}
+		if cookie, err := r.Cookie(sessionCookie); err == nil && d.validSession(cookie.Value, time.Now()) {
+			if r.Method == http.MethodPost {
+				csrf := cmp.Or(r.Header.Get("X-CSRF-Token"), r.PostFormValue("csrf"))
+				if subtle.ConstantTimeCompare([]byte(csrf), []byte(d.csrfToken(r))) != 1 {
+					http.Error(w, "invalid CSRF token", http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="myfeed"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+func (d *Daemon) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	issues, err := d.issues()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	drafts, err := d.pendingDrafts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pins, err := d.pendingPins(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	quality, err := d.qualityTrends(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = dashboardTmpl.Execute(w, map[string]any{
+		"Status":      d.Status(),
+		"Issues":      issues,
+		"Drafts":      drafts,
+		"Pins":        pins,
+		"PinsEnabled": d.pins != nil,
+		"Quality":     quality,
+		"CSRF":        d.csrfToken(r),
+	})
+	if err != nil {
+		slog.Warn("daemon: failed to render dashboard", "error", err)
+	}
+}
+
+func (d *Daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d.Status())
+}
+
+func (d *Daemon) handleRun(w http.ResponseWriter, r *http.Request) {
+	// Detach from the request so the run outlives it
+	if err := d.Trigger(context.WithoutCancel(r.Context())); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (d *Daemon) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s := d.Status()
+	running := 0
+	if s.Running {
+		running = 1
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# TYPE myfeed_runs_total counter\nmyfeed_runs_total %d\n", s.Runs)
+	fmt.Fprintf(w, "# TYPE myfeed_run_failures_total counter\nmyfeed_run_failures_total %d\n", s.Failures)
+	fmt.Fprintf(w, "# TYPE myfeed_running gauge\nmyfeed_running %d\n", running)
+	fmt.Fprintf(w, "# TYPE myfeed_last_run_timestamp_seconds gauge\nmyfeed_last_run_timestamp_seconds %d\n", unix(s.LastRunAt))
+	fmt.Fprintf(w, "# TYPE myfeed_last_success_timestamp_seconds gauge\nmyfeed_last_success_timestamp_seconds %d\n", unix(s.LastSuccessAt))
+}
+
+// issues lists generated HTML and PDF files relative to the output directory, newest first
+func (d *Daemon) issues() ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(d.outputDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(path); ext == ".html" || ext == ".pdf" {
+			rel, err := filepath.Rel(d.outputDir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, err
+}
+
+// newServer creates the HTTP server with TLS settings from the config
+func (d *Daemon) newServer() (*http.Server, error) {
+	bind := d.cfg.Address()
+	if !isLoopback(bind) && d.cfg.APIToken == "" && d.cfg.ClientCA == "" {
+		return nil, fmt.Errorf("refusing to listen on %s without api_token or client_ca", bind)
+	}
+
+	srv := &http.Server{
+		Addr:              bind,
+		Handler:           d.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	switch {
+	case len(d.cfg.ACMEDomains) > 0:
+		cacheDir := d.cfg.ACMECache
+		if cacheDir == "" {
+			cacheDir = filepath.Join(os.Getenv("HOME"), ".cache", "myfeed", "acme")
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(d.cfg.ACMEDomains...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+		srv.TLSConfig = m.TLSConfig()
+	case d.cfg.TLSCert != "" || d.cfg.TLSKey != "":
+		if d.cfg.TLSCert == "" || d.cfg.TLSKey == "" {
+			return nil, fmt.Errorf("both tls_cert and tls_key must be set")
+		}
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if d.cfg.ClientCA != "" {
+		if srv.TLSConfig == nil {
+			return nil, fmt.Errorf("client_ca requires TLS, set tls_cert and tls_key or acme_domains")
+		}
+		pem, err := os.ReadFile(d.cfg.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA at '%s' with %w", d.cfg.ClientCA, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA at '%s'", d.cfg.ClientCA)
+		}
+		srv.TLSConfig.ClientCAs = pool
+		srv.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return srv, nil
+}
+
+func (d *Daemon) serve(srv *http.Server) error {
+	var err error
+	if srv.TLSConfig != nil {
+		slog.Info("daemon: listening", "addr", srv.Addr, "tls", true)
+		err = srv.ListenAndServeTLS(d.cfg.TLSCert, d.cfg.TLSKey)
+	} else {
+		slog.Info("daemon: listening", "addr", srv.Addr, "tls", false)
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// isLoopback returns true if the bind address only accepts local connections
+func isLoopback(bind string) bool {
+	host, _, err := net.SplitHostPort(bind)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sessionCookie = "myfeed_session"
+	sessionTTL    = 30 * 24 * time.Hour
+)
+
+// sign returns the HMAC of msg keyed with the API token, so sessions end
+// when the token changes
+func (d *Daemon) sign(msg string) string {
+	mac := hmac.New(sha256.New, []byte(d.cfg.APIToken))
+	mac.Write([]byte(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSession returns a session cookie value valid until now+sessionTTL,
+// e.g., "1767225600.<signature>"
+func (d *Daemon) newSession(now time.Time) string {
+	expires := strconv.FormatInt(now.Add(sessionTTL).Unix(), 10)
+	return expires + "." + d.sign("session:"+expires)
+}
+
+// validSession reports whether the session cookie value was issued by the
+// daemon and has not expired
+func (d *Daemon) validSession(value string, now time.Time) bool {
+	expires, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() >= unix {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(d.sign("session:"+expires)))
+}
+
+// csrfToken returns the token forms posted with the session cookie must
+// carry, empty without a session
+func (d *Daemon) csrfToken(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || d.cfg.APIToken == "" {
+		return ""
+	}
+	return d.sign("csrf:" + cookie.Value)
+}
+
+// login sets the session cookie and redirects to the requested page
+// without the token in its URL
+func (d *Daemon) login(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    d.newSession(now),
+		Path:     "/",
+		Expires:  now.Add(sessionTTL),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax keeps the session on links followed from emails, posted
+		// forms are covered by the CSRF token
+		SameSite: http.SameSiteLaxMode,
+	})
+	u := *r.URL
+	query := u.Query()
+	query.Del("token")
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.RequestURI(), http.StatusSeeOther)
+}
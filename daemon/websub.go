@@ -0,0 +1,296 @@
+package daemon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxPushSize   = 10 << 20 // Pushed feed content larger than this is rejected
+	renewInterval = time.Hour
+	renewBefore   = 24 * time.Hour // Leases are renewed this long before they expire
+)
+
+// WebSubHooks connect WebSub subscriptions to feed discovery and the fetch queue
+type WebSubHooks struct {
+	// Discover returns the hub advertised by a feed and the topic it
+	// publishes, an empty hub if the feed has none
+	Discover func(ctx context.Context, feedURL string) (hub, topic string, err error)
+	// Push queues feed content pushed by the hub
+	Push func(ctx context.Context, feedURL string, body []byte) error
+	// Lease records until when the feed is pushed, zero once it no longer is
+	Lease func(ctx context.Context, feedURL string, expires time.Time) error
+}
+
+// WebSub subscribes feeds at their hubs and receives the content they push
+type WebSub struct {
+	publicURL string
+	feeds     []string
+	hooks     WebSubHooks
+	client    *http.Client
+
+	mu   sync.Mutex
+	subs map[string]*subscription // By callback ID
+}
+
+type subscription struct {
+	feedURL   string
+	hub       string
+	topic     string
+	secret    string
+	oldSecret string // Hubs sign with it until the renewed subscription is verified
+	requested time.Time
+	expires   time.Time // Zero until the hub verifies the subscription
+}
+
+// NewWebSub creates WebSub subscriptions for feeds, hubs reach the
+// callbacks at publicURL
+func NewWebSub(publicURL string, feeds []string, hooks WebSubHooks) *WebSub {
+	return &WebSub{
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		feeds:     feeds,
+		hooks:     hooks,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		subs:      make(map[string]*subscription),
+	}
+}
+
+// Run subscribes feeds advertising a hub and renews their leases until ctx
+// is cancelled, then the leases are released so the feeds are polled again
+func (w *WebSub) Run(ctx context.Context) {
+	for _, feedURL := range w.feeds {
+		hub, topic, err := w.hooks.Discover(ctx, feedURL)
+		if err != nil {
+			slog.Warn("websub: hub discovery failed", "feed", feedURL, "error", err)
+			continue
+		}
+		if hub == "" {
+			slog.Debug("websub: feed advertises no hub", "feed", feedURL)
+			continue
+		}
+		w.mu.Lock()
+		w.subs[callbackID(feedURL)] = &subscription{feedURL: feedURL, hub: hub, topic: topic}
+		w.mu.Unlock()
+	}
+	w.renew(ctx)
+
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			w.release()
+			return
+		case <-ticker.C:
+			w.renew(ctx)
+		}
+	}
+}
+
+// renew subscribes feeds whose lease is about to expire or whose
+// subscription was never verified
+func (w *WebSub) renew(ctx context.Context) {
+	now := time.Now()
+	w.mu.Lock()
+	var due []*subscription
+	for _, sub := range w.subs {
+		unverified := sub.expires.IsZero() && now.Sub(sub.requested) > renewInterval
+		if unverified || (!sub.expires.IsZero() && sub.expires.Sub(now) < renewBefore) {
+			sub.oldSecret, sub.secret = sub.secret, newSecret()
+			sub.requested = now
+			due = append(due, sub)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, sub := range due {
+		if err := w.subscribe(ctx, sub); err != nil {
+			slog.Warn("websub: subscription failed", "feed", sub.feedURL, "hub", sub.hub, "error", err)
+		}
+	}
+}
+
+// subscribe asks the hub to push the topic, the hub verifies the request
+// through the callback before it starts pushing
+func (w *WebSub) subscribe(ctx context.Context, sub *subscription) error {
+	w.mu.Lock()
+	form := url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.topic":    {sub.topic},
+		"hub.callback": {w.publicURL + "/websub/" + callbackID(sub.feedURL)},
+		"hub.secret":   {sub.secret},
+	}
+	w.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub responded with %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	slog.Info("websub: subscription requested", "feed", sub.feedURL, "hub", sub.hub)
+	return nil
+}
+
+// release clears the leases of all verified subscriptions
+func (w *WebSub) release() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, sub := range w.subs {
+		if sub.expires.IsZero() {
+			continue
+		}
+		sub.expires = time.Time{}
+		if err := w.hooks.Lease(context.Background(), sub.feedURL, time.Time{}); err != nil {
+			slog.Warn("websub: failed to release lease", "feed", sub.feedURL, "error", err)
+		}
+	}
+}
+
+// Handler serves the callbacks hubs verify subscriptions at and push content to
+func (w *WebSub) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /websub/{id}", w.handleVerify)
+	mux.HandleFunc("POST /websub/{id}", w.handlePush)
+	return mux
+}
+
+func (w *WebSub) handleVerify(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	w.mu.Lock()
+	sub, ok := w.subs[r.PathValue("id")]
+	if !ok || query.Get("hub.topic") != sub.topic {
+		w.mu.Unlock()
+		http.NotFound(rw, r)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		lease, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			w.mu.Unlock()
+			http.Error(rw, "invalid hub.lease_seconds", http.StatusBadRequest)
+			return
+		}
+		sub.expires = time.Now().Add(time.Duration(lease) * time.Second)
+		feedURL, expires := sub.feedURL, sub.expires
+		w.mu.Unlock()
+		if err := w.hooks.Lease(r.Context(), feedURL, expires); err != nil {
+			slog.Warn("websub: failed to save lease", "feed", feedURL, "error", err)
+		}
+		slog.Info("websub: subscription verified", "feed", feedURL, "expires", expires)
+	case "denied":
+		sub.expires = time.Time{}
+		feedURL := sub.feedURL
+		w.mu.Unlock()
+		if err := w.hooks.Lease(r.Context(), feedURL, time.Time{}); err != nil {
+			slog.Warn("websub: failed to release lease", "feed", feedURL, "error", err)
+		}
+		slog.Warn("websub: subscription denied", "feed", feedURL, "reason", query.Get("hub.reason"))
+	default:
+		// Subscriptions are never cancelled by the daemon
+		w.mu.Unlock()
+		http.NotFound(rw, r)
+		return
+	}
+	rw.Write([]byte(query.Get("hub.challenge")))
+}
+
+func (w *WebSub) handlePush(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	sub, ok := w.subs[r.PathValue("id")]
+	var feedURL string
+	var secrets []string
+	if ok {
+		feedURL, secrets = sub.feedURL, []string{sub.secret, sub.oldSecret}
+	}
+	w.mu.Unlock()
+	if !ok {
+		http.NotFound(rw, r)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxPushSize))
+	if err != nil {
+		http.Error(rw, "content too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	// Content with an invalid signature is acknowledged but ignored
+	signature := r.Header.Get("X-Hub-Signature")
+	if !validSignature(signature, secrets[0], body) && !validSignature(signature, secrets[1], body) {
+		slog.Warn("websub: ignoring push with invalid signature", "feed", feedURL)
+		rw.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err := w.hooks.Push(r.Context(), feedURL, body); err != nil {
+		slog.Error("websub: failed to queue pushed content", "feed", feedURL, "error", err)
+		http.Error(rw, "failed to queue content", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("websub: queued pushed content", "feed", feedURL, "size", len(body))
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks the "method=signature" HMAC of the body
+func validSignature(header, secret string, body []byte) bool {
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok || secret == "" {
+		return false
+	}
+	var h func() hash.Hash
+	switch method {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// callbackID identifies the feed in its callback URL
+func callbackID(feedURL string) string {
+	sum := sha256.Sum256([]byte(feedURL))
+	return hex.EncodeToString(sum[:8])
+}
+
+func newSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
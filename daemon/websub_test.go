@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebSubSubscribeAndPush(t *testing.T) {
+	const feedURL = "https://example.com/feed"
+	var mu sync.Mutex
+	var leases []time.Time
+	var pushed []string
+	subscribed := make(chan url.Values, 1)
+
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		subscribed <- r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	ws := NewWebSub("https://myfeed.example.com/", []string{feedURL}, WebSubHooks{
+		Discover: func(ctx context.Context, u string) (string, string, error) {
+			return hub.URL, "https://example.com/self", nil
+		},
+		Push: func(ctx context.Context, u string, body []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			pushed = append(pushed, string(body))
+			return nil
+		},
+		Lease: func(ctx context.Context, u string, expires time.Time) error {
+			mu.Lock()
+			defer mu.Unlock()
+			leases = append(leases, expires)
+			return nil
+		},
+	})
+	srv := httptest.NewServer(ws.Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ws.Run(ctx)
+		close(done)
+	}()
+
+	form := <-subscribed
+	callback := form.Get("hub.callback")
+	if !strings.HasPrefix(callback, "https://myfeed.example.com/websub/") || form.Get("hub.topic") != "https://example.com/self" {
+		t.Fatalf("unexpected subscription request %v", form)
+	}
+	path := strings.TrimPrefix(callback, "https://myfeed.example.com")
+
+	// Verification of intent echoes the challenge of known topics only
+	resp, err := http.Get(srv.URL + path + "?hub.mode=subscribe&hub.topic=https://example.com/other&hub.challenge=abc&hub.lease_seconds=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected unknown topic to be rejected, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get(srv.URL + path + "?hub.mode=subscribe&hub.topic=https://example.com/self&hub.challenge=abc&hub.lease_seconds=60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(challenge) != "abc" {
+		t.Errorf("expected challenge echoed, got %q", challenge)
+	}
+
+	// Only content signed with the subscription secret is queued
+	push := func(body, secret string) {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			t.Errorf("expected push to be accepted, got %d", resp.StatusCode)
+		}
+	}
+	push("<feed>forged</feed>", "wrong")
+	push("<feed>new</feed>", form.Get("hub.secret"))
+
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushed) != 1 || pushed[0] != "<feed>new</feed>" {
+		t.Errorf("expected only the signed push to be queued, got %v", pushed)
+	}
+	if len(leases) != 2 || time.Until(leases[0]) <= 0 || !leases[1].IsZero() {
+		t.Errorf("expected lease saved then released on shutdown, got %v", leases)
+	}
+}
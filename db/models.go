@@ -4,6 +4,18 @@
 
 package db
 
+type AgentMetric struct {
+	Agent              string
+	Model              string
+	Calls              int64
+	InputChars         int64
+	OutputChars        int64
+	LanguageMismatches int64
+	ContractViolations int64
+	Failures           int64
+	CreatedAt          int64
+}
+
 type AgentCache struct {
 	ID            int64
 	Url           string
@@ -14,12 +26,56 @@ type AgentCache struct {
 	AccessedAt    int64
 }
 
+type ArchiveItem struct {
+	FeedUrl     string
+	Guid        string
+	Title       string
+	Link        string
+	Description string
+	PublishedAt int64
+	ArchivedAt  int64
+}
+
+type DeferredItem struct {
+	FeedUrl    string
+	ItemKey    string
+	Reason     string
+	DeferredAt int64
+}
+
+type Draft struct {
+	ID        int64
+	Title     string
+	IssuePath string
+	Status    string
+	CreatedAt int64
+	DecidedAt int64
+}
+
+type DraftEmail struct {
+	DraftID    int64
+	Part       int64
+	Recipients string
+	Message    string
+}
+
 type Feed struct {
 	Url             string
 	Title           string
 	LastProcessedAt int64
 }
 
+type FeedOffset struct {
+	Url       string
+	MessageID int64
+}
+
+type FeedValidator struct {
+	Url          string
+	Etag         string
+	LastModified string
+}
+
 type GenerationHistory struct {
 	ID              int64
 	FeedUrl         string
@@ -27,6 +83,13 @@ type GenerationHistory struct {
 	CreatedAt       int64
 }
 
+type HighWaterMark struct {
+	FeedUrl     string
+	Guid        string
+	PublishedAt int64
+	CreatedAt   int64
+}
+
 type ParserCache struct {
 	ID         int64
 	Url        string
@@ -35,3 +98,60 @@ type ParserCache struct {
 	CreatedAt  int64
 	AccessedAt int64
 }
+
+type Pin struct {
+	ID        int64
+	Text      string
+	Section   string
+	CreatedAt int64
+	IssuedAt  int64
+}
+
+type ProcessedItem struct {
+	CanonicalUrl string
+	Url          string
+	Title        string
+	ProcessedAt  int64
+}
+
+type QueueItem struct {
+	FeedUrl   string
+	ItemKey   string
+	FeedTitle string
+	ItemData  string
+	Position  int64
+	QueuedAt  int64
+}
+
+type ReadingEvent struct {
+	ID        int64
+	Kind      string
+	Url       string
+	ItemUrl   string
+	Author    string
+	CreatedAt int64
+}
+
+type SentEmail struct {
+	MessageID  string
+	Subject    string
+	Recipients string
+	Size       int64
+	Attempts   int64
+	Error      string
+	SentAt     int64
+}
+
+type PushLease struct {
+	FeedUrl   string
+	ExpiresAt int64
+}
+
+type Issue struct {
+	ID         int64
+	Title      string
+	OutputPath string
+	FileName   string
+	Newsletter string
+	CreatedAt  int64
+}
@@ -9,6 +9,198 @@ import (
 	"context"
 )
 
+const countArchiveItems = `-- name: CountArchiveItems :one
+SELECT COUNT(*)
+FROM archive_item
+WHERE feed_url = ?
+`
+
+func (q *Queries) CountArchiveItems(ctx context.Context, feedUrl string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countArchiveItems, feedUrl)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countGenerationHistoryBefore = `-- name: CountGenerationHistoryBefore :one
+SELECT COUNT(*)
+FROM generation_history
+WHERE created_at < ?
+`
+
+func (q *Queries) CountGenerationHistoryBefore(ctx context.Context, createdAt int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countGenerationHistoryBefore, createdAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countProcessedItemsBefore = `-- name: CountProcessedItemsBefore :one
+SELECT COUNT(*)
+FROM processed_item
+WHERE processed_at < ?
+`
+
+func (q *Queries) CountProcessedItemsBefore(ctx context.Context, processedAt int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countProcessedItemsBefore, processedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createDraft = `-- name: CreateDraft :one
+INSERT INTO draft (title, issue_path, status, created_at, decided_at)
+VALUES (?, ?, 'pending', ?, 0)
+RETURNING id
+`
+
+type CreateDraftParams struct {
+	Title     string
+	IssuePath string
+	CreatedAt int64
+}
+
+func (q *Queries) CreateDraft(ctx context.Context, arg CreateDraftParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createDraft, arg.Title, arg.IssuePath, arg.CreatedAt)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createPin = `-- name: CreatePin :one
+INSERT INTO pin (text, section, created_at, issued_at)
+VALUES (?, ?, ?, 0)
+RETURNING id
+`
+
+type CreatePinParams struct {
+	Text      string
+	Section   string
+	CreatedAt int64
+}
+
+func (q *Queries) CreatePin(ctx context.Context, arg CreatePinParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createPin, arg.Text, arg.Section, arg.CreatedAt)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const decideDraft = `-- name: DecideDraft :execrows
+UPDATE draft
+SET status = ?,
+    decided_at = ?
+WHERE id = ?
+    AND status = 'pending'
+`
+
+type DecideDraftParams struct {
+	Status    string
+	DecidedAt int64
+	ID        int64
+}
+
+func (q *Queries) DecideDraft(ctx context.Context, arg DecideDraftParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, decideDraft, arg.Status, arg.DecidedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deferItem = `-- name: DeferItem :exec
+INSERT OR REPLACE INTO deferred_item (feed_url, item_key, reason, deferred_at)
+VALUES (?, ?, ?, ?)
+`
+
+type DeferItemParams struct {
+	FeedUrl    string
+	ItemKey    string
+	Reason     string
+	DeferredAt int64
+}
+
+func (q *Queries) DeferItem(ctx context.Context, arg DeferItemParams) error {
+	_, err := q.db.ExecContext(ctx, deferItem,
+		arg.FeedUrl,
+		arg.ItemKey,
+		arg.Reason,
+		arg.DeferredAt,
+	)
+	return err
+}
+
+const deleteAgentMetricsBefore = `-- name: DeleteAgentMetricsBefore :exec
+DELETE FROM agent_metric
+WHERE created_at < ?
+`
+
+func (q *Queries) DeleteAgentMetricsBefore(ctx context.Context, createdAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteAgentMetricsBefore, createdAt)
+	return err
+}
+
+const deleteDeferredItems = `-- name: DeleteDeferredItems :exec
+DELETE FROM deferred_item
+WHERE feed_url = ?
+    AND deferred_at <= ?
+`
+
+type DeleteDeferredItemsParams struct {
+	FeedUrl    string
+	DeferredAt int64
+}
+
+func (q *Queries) DeleteDeferredItems(ctx context.Context, arg DeleteDeferredItemsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteDeferredItems, arg.FeedUrl, arg.DeferredAt)
+	return err
+}
+
+const deleteDraftEmails = `-- name: DeleteDraftEmails :exec
+DELETE FROM draft_email
+WHERE draft_id = ?
+`
+
+func (q *Queries) DeleteDraftEmails(ctx context.Context, draftID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteDraftEmails, draftID)
+	return err
+}
+
+const deleteGenerationHistoryBefore = `-- name: DeleteGenerationHistoryBefore :exec
+DELETE FROM generation_history
+WHERE created_at < ?
+`
+
+func (q *Queries) DeleteGenerationHistoryBefore(ctx context.Context, createdAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteGenerationHistoryBefore, createdAt)
+	return err
+}
+
+const deleteHighWaterMarksBefore = `-- name: DeleteHighWaterMarksBefore :exec
+DELETE FROM high_water_mark
+WHERE created_at < ?
+    AND created_at < (
+        SELECT MAX(latest.created_at)
+        FROM high_water_mark AS latest
+        WHERE latest.feed_url = high_water_mark.feed_url
+    )
+`
+
+func (q *Queries) DeleteHighWaterMarksBefore(ctx context.Context, createdAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteHighWaterMarksBefore, createdAt)
+	return err
+}
+
+const deleteIssuesBefore = `-- name: DeleteIssuesBefore :exec
+DELETE FROM issue
+WHERE created_at < ?
+`
+
+func (q *Queries) DeleteIssuesBefore(ctx context.Context, createdAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteIssuesBefore, createdAt)
+	return err
+}
+
 const deleteLatestGeneration = `-- name: DeleteLatestGeneration :exec
 DELETE FROM generation_history
 WHERE created_at = (
@@ -24,6 +216,115 @@ func (q *Queries) DeleteLatestGeneration(ctx context.Context) error {
 	return err
 }
 
+const deleteLatestHighWaterMarks = `-- name: DeleteLatestHighWaterMarks :exec
+DELETE FROM high_water_mark
+WHERE created_at = (
+    SELECT MAX(created_at)
+    FROM generation_history
+)
+`
+
+func (q *Queries) DeleteLatestHighWaterMarks(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteLatestHighWaterMarks)
+	return err
+}
+
+const deleteLatestProcessedItems = `-- name: DeleteLatestProcessedItems :exec
+DELETE FROM processed_item
+WHERE processed_at = (
+    SELECT MAX(created_at)
+    FROM generation_history
+)
+`
+
+func (q *Queries) DeleteLatestProcessedItems(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteLatestProcessedItems)
+	return err
+}
+
+const deleteQueuedItems = `-- name: DeleteQueuedItems :exec
+DELETE FROM queue_item
+WHERE feed_url = ?
+    AND queued_at <= ?
+`
+
+type DeleteQueuedItemsParams struct {
+	FeedUrl  string
+	QueuedAt int64
+}
+
+func (q *Queries) DeleteQueuedItems(ctx context.Context, arg DeleteQueuedItemsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteQueuedItems, arg.FeedUrl, arg.QueuedAt)
+	return err
+}
+
+const deletePendingPin = `-- name: DeletePendingPin :execrows
+DELETE FROM pin
+WHERE id = ?
+    AND issued_at = 0
+`
+
+func (q *Queries) DeletePendingPin(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePendingPin, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteProcessedItemsBefore = `-- name: DeleteProcessedItemsBefore :exec
+DELETE FROM processed_item
+WHERE processed_at < ?
+`
+
+func (q *Queries) DeleteProcessedItemsBefore(ctx context.Context, processedAt int64) error {
+	_, err := q.db.ExecContext(ctx, deleteProcessedItemsBefore, processedAt)
+	return err
+}
+
+const deletePushLease = `-- name: DeletePushLease :exec
+DELETE FROM push_lease
+WHERE feed_url = ?
+`
+
+func (q *Queries) DeletePushLease(ctx context.Context, feedUrl string) error {
+	_, err := q.db.ExecContext(ctx, deletePushLease, feedUrl)
+	return err
+}
+
+const enqueueItem = `-- name: EnqueueItem :exec
+INSERT OR IGNORE INTO queue_item (
+        feed_url,
+        item_key,
+        feed_title,
+        item_data,
+        position,
+        queued_at
+    )
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type EnqueueItemParams struct {
+	FeedUrl   string
+	ItemKey   string
+	FeedTitle string
+	ItemData  string
+	Position  int64
+	QueuedAt  int64
+}
+
+func (q *Queries) EnqueueItem(ctx context.Context, arg EnqueueItemParams) error {
+	_, err := q.db.ExecContext(ctx, enqueueItem,
+		arg.FeedUrl,
+		arg.ItemKey,
+		arg.FeedTitle,
+		arg.ItemData,
+		arg.Position,
+		arg.QueuedAt,
+	)
+	return err
+}
+
 const getFeed = `-- name: GetFeed :one
 SELECT url, title, last_processed_at
 FROM feed
@@ -37,6 +338,52 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 	return i, err
 }
 
+const getFeedOffset = `-- name: GetFeedOffset :one
+SELECT message_id
+FROM feed_offset
+WHERE url = ?
+`
+
+func (q *Queries) GetFeedOffset(ctx context.Context, url string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getFeedOffset, url)
+	var message_id int64
+	err := row.Scan(&message_id)
+	return message_id, err
+}
+
+const getFeedValidator = `-- name: GetFeedValidator :one
+SELECT url, etag, last_modified
+FROM feed_validator
+WHERE url = ?
+`
+
+func (q *Queries) GetFeedValidator(ctx context.Context, url string) (FeedValidator, error) {
+	row := q.db.QueryRowContext(ctx, getFeedValidator, url)
+	var i FeedValidator
+	err := row.Scan(&i.Url, &i.Etag, &i.LastModified)
+	return i, err
+}
+
+const getIssue = `-- name: GetIssue :one
+SELECT id, title, output_path, file_name, newsletter, created_at
+FROM issue
+WHERE id = ?
+`
+
+func (q *Queries) GetIssue(ctx context.Context, id int64) (Issue, error) {
+	row := q.db.QueryRowContext(ctx, getIssue, id)
+	var i Issue
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.OutputPath,
+		&i.FileName,
+		&i.Newsletter,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getLatestGenerationTimestamp = `-- name: GetLatestGenerationTimestamp :one
 SELECT last_processed_at
 FROM generation_history
@@ -52,6 +399,537 @@ func (q *Queries) GetLatestGenerationTimestamp(ctx context.Context, feedUrl stri
 	return last_processed_at, err
 }
 
+const getLatestHighWaterMark = `-- name: GetLatestHighWaterMark :one
+SELECT feed_url, guid, published_at, created_at
+FROM high_water_mark
+WHERE feed_url = ?
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestHighWaterMark(ctx context.Context, feedUrl string) (HighWaterMark, error) {
+	row := q.db.QueryRowContext(ctx, getLatestHighWaterMark, feedUrl)
+	var i HighWaterMark
+	err := row.Scan(
+		&i.FeedUrl,
+		&i.Guid,
+		&i.PublishedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestIssue = `-- name: GetLatestIssue :one
+SELECT id, title, output_path, file_name, newsletter, created_at
+FROM issue
+ORDER BY id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLatestIssue(ctx context.Context) (Issue, error) {
+	row := q.db.QueryRowContext(ctx, getLatestIssue)
+	var i Issue
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.OutputPath,
+		&i.FileName,
+		&i.Newsletter,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getProcessedItem = `-- name: GetProcessedItem :one
+SELECT canonical_url, url, title, processed_at
+FROM processed_item
+WHERE canonical_url = ?
+`
+
+func (q *Queries) GetProcessedItem(ctx context.Context, canonicalUrl string) (ProcessedItem, error) {
+	row := q.db.QueryRowContext(ctx, getProcessedItem, canonicalUrl)
+	var i ProcessedItem
+	err := row.Scan(
+		&i.CanonicalUrl,
+		&i.Url,
+		&i.Title,
+		&i.ProcessedAt,
+	)
+	return i, err
+}
+
+const getPushLease = `-- name: GetPushLease :one
+SELECT expires_at
+FROM push_lease
+WHERE feed_url = ?
+`
+
+func (q *Queries) GetPushLease(ctx context.Context, feedUrl string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getPushLease, feedUrl)
+	var expires_at int64
+	err := row.Scan(&expires_at)
+	return expires_at, err
+}
+
+const listAgentMetrics = `-- name: ListAgentMetrics :many
+SELECT agent,
+    model,
+    calls,
+    input_chars,
+    output_chars,
+    language_mismatches,
+    contract_violations,
+    failures,
+    created_at
+FROM agent_metric
+WHERE created_at >= ?
+ORDER BY created_at
+`
+
+func (q *Queries) ListAgentMetrics(ctx context.Context, createdAt int64) ([]AgentMetric, error) {
+	rows, err := q.db.QueryContext(ctx, listAgentMetrics, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AgentMetric
+	for rows.Next() {
+		var i AgentMetric
+		if err := rows.Scan(
+			&i.Agent,
+			&i.Model,
+			&i.Calls,
+			&i.InputChars,
+			&i.OutputChars,
+			&i.LanguageMismatches,
+			&i.ContractViolations,
+			&i.Failures,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeferredItemKeys = `-- name: ListDeferredItemKeys :many
+SELECT item_key
+FROM deferred_item
+WHERE feed_url = ?
+`
+
+func (q *Queries) ListDeferredItemKeys(ctx context.Context, feedUrl string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listDeferredItemKeys, feedUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var item_key string
+		if err := rows.Scan(&item_key); err != nil {
+			return nil, err
+		}
+		items = append(items, item_key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDraftEmails = `-- name: ListDraftEmails :many
+SELECT draft_id, part, recipients, message
+FROM draft_email
+WHERE draft_id = ?
+ORDER BY part
+`
+
+func (q *Queries) ListDraftEmails(ctx context.Context, draftID int64) ([]DraftEmail, error) {
+	rows, err := q.db.QueryContext(ctx, listDraftEmails, draftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DraftEmail
+	for rows.Next() {
+		var i DraftEmail
+		if err := rows.Scan(
+			&i.DraftID,
+			&i.Part,
+			&i.Recipients,
+			&i.Message,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingDrafts = `-- name: ListPendingDrafts :many
+SELECT id, title, issue_path, status, created_at, decided_at
+FROM draft
+WHERE status = 'pending'
+ORDER BY created_at
+`
+
+func (q *Queries) ListPendingDrafts(ctx context.Context) ([]Draft, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingDrafts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Draft
+	for rows.Next() {
+		var i Draft
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.IssuePath,
+			&i.Status,
+			&i.CreatedAt,
+			&i.DecidedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingPins = `-- name: ListPendingPins :many
+SELECT id, text, section, created_at, issued_at
+FROM pin
+WHERE issued_at = 0
+ORDER BY created_at,
+    id
+`
+
+func (q *Queries) ListPendingPins(ctx context.Context) ([]Pin, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingPins)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Pin
+	for rows.Next() {
+		var i Pin
+		if err := rows.Scan(
+			&i.ID,
+			&i.Text,
+			&i.Section,
+			&i.CreatedAt,
+			&i.IssuedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQueuedItems = `-- name: ListQueuedItems :many
+SELECT
+    feed_url,
+    item_key,
+    feed_title,
+    item_data,
+    position,
+    queued_at
+FROM queue_item
+WHERE feed_url = ?
+ORDER BY queued_at DESC,
+    position
+`
+
+func (q *Queries) ListQueuedItems(ctx context.Context, feedUrl string) ([]QueueItem, error) {
+	rows, err := q.db.QueryContext(ctx, listQueuedItems, feedUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []QueueItem
+	for rows.Next() {
+		var i QueueItem
+		if err := rows.Scan(
+			&i.FeedUrl,
+			&i.ItemKey,
+			&i.FeedTitle,
+			&i.ItemData,
+			&i.Position,
+			&i.QueuedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReadingEvents = `-- name: ListReadingEvents :many
+SELECT id, kind, url, item_url, author, created_at
+FROM reading_event
+WHERE created_at >= ?
+ORDER BY created_at
+`
+
+func (q *Queries) ListReadingEvents(ctx context.Context, createdAt int64) ([]ReadingEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listReadingEvents, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReadingEvent
+	for rows.Next() {
+		var i ReadingEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.Url,
+			&i.ItemUrl,
+			&i.Author,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSentEmails = `-- name: ListSentEmails :many
+SELECT
+    message_id,
+    subject,
+    recipients,
+    size,
+    attempts,
+    error,
+    sent_at
+FROM sent_email
+ORDER BY sent_at DESC
+LIMIT ?
+`
+
+func (q *Queries) ListSentEmails(ctx context.Context, limit int64) ([]SentEmail, error) {
+	rows, err := q.db.QueryContext(ctx, listSentEmails, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SentEmail
+	for rows.Next() {
+		var i SentEmail
+		if err := rows.Scan(
+			&i.MessageID,
+			&i.Subject,
+			&i.Recipients,
+			&i.Size,
+			&i.Attempts,
+			&i.Error,
+			&i.SentAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPinIssued = `-- name: MarkPinIssued :exec
+UPDATE pin
+SET issued_at = ?
+WHERE id = ?
+`
+
+type MarkPinIssuedParams struct {
+	IssuedAt int64
+	ID       int64
+}
+
+func (q *Queries) MarkPinIssued(ctx context.Context, arg MarkPinIssuedParams) error {
+	_, err := q.db.ExecContext(ctx, markPinIssued, arg.IssuedAt, arg.ID)
+	return err
+}
+
+const saveAgentMetric = `-- name: SaveAgentMetric :exec
+INSERT OR REPLACE INTO agent_metric (
+        agent,
+        model,
+        calls,
+        input_chars,
+        output_chars,
+        language_mismatches,
+        contract_violations,
+        failures,
+        created_at
+    )
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type SaveAgentMetricParams struct {
+	Agent              string
+	Model              string
+	Calls              int64
+	InputChars         int64
+	OutputChars        int64
+	LanguageMismatches int64
+	ContractViolations int64
+	Failures           int64
+	CreatedAt          int64
+}
+
+func (q *Queries) SaveAgentMetric(ctx context.Context, arg SaveAgentMetricParams) error {
+	_, err := q.db.ExecContext(ctx, saveAgentMetric,
+		arg.Agent,
+		arg.Model,
+		arg.Calls,
+		arg.InputChars,
+		arg.OutputChars,
+		arg.LanguageMismatches,
+		arg.ContractViolations,
+		arg.Failures,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const saveArchiveItem = `-- name: SaveArchiveItem :exec
+INSERT OR IGNORE INTO archive_item (
+        feed_url,
+        guid,
+        title,
+        link,
+        description,
+        published_at,
+        archived_at
+    )
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type SaveArchiveItemParams struct {
+	FeedUrl     string
+	Guid        string
+	Title       string
+	Link        string
+	Description string
+	PublishedAt int64
+	ArchivedAt  int64
+}
+
+func (q *Queries) SaveArchiveItem(ctx context.Context, arg SaveArchiveItemParams) error {
+	_, err := q.db.ExecContext(ctx, saveArchiveItem,
+		arg.FeedUrl,
+		arg.Guid,
+		arg.Title,
+		arg.Link,
+		arg.Description,
+		arg.PublishedAt,
+		arg.ArchivedAt,
+	)
+	return err
+}
+
+const saveDraftEmail = `-- name: SaveDraftEmail :exec
+INSERT INTO draft_email (draft_id, part, recipients, message)
+VALUES (?, ?, ?, ?)
+`
+
+type SaveDraftEmailParams struct {
+	DraftID    int64
+	Part       int64
+	Recipients string
+	Message    string
+}
+
+func (q *Queries) SaveDraftEmail(ctx context.Context, arg SaveDraftEmailParams) error {
+	_, err := q.db.ExecContext(ctx, saveDraftEmail,
+		arg.DraftID,
+		arg.Part,
+		arg.Recipients,
+		arg.Message,
+	)
+	return err
+}
+
+const saveFeedOffset = `-- name: SaveFeedOffset :exec
+INSERT OR REPLACE INTO feed_offset (url, message_id)
+VALUES (?, ?)
+`
+
+type SaveFeedOffsetParams struct {
+	Url       string
+	MessageID int64
+}
+
+func (q *Queries) SaveFeedOffset(ctx context.Context, arg SaveFeedOffsetParams) error {
+	_, err := q.db.ExecContext(ctx, saveFeedOffset, arg.Url, arg.MessageID)
+	return err
+}
+
+const saveFeedValidator = `-- name: SaveFeedValidator :exec
+INSERT OR REPLACE INTO feed_validator (url, etag, last_modified)
+VALUES (?, ?, ?)
+`
+
+type SaveFeedValidatorParams struct {
+	Url          string
+	Etag         string
+	LastModified string
+}
+
+func (q *Queries) SaveFeedValidator(ctx context.Context, arg SaveFeedValidatorParams) error {
+	_, err := q.db.ExecContext(ctx, saveFeedValidator, arg.Url, arg.Etag, arg.LastModified)
+	return err
+}
+
 const saveGenerationHistory = `-- name: SaveGenerationHistory :exec
 INSERT INTO generation_history (feed_url, last_processed_at, created_at)
 VALUES (?, ?, ?)
@@ -68,6 +946,152 @@ func (q *Queries) SaveGenerationHistory(ctx context.Context, arg SaveGenerationH
 	return err
 }
 
+const saveHighWaterMark = `-- name: SaveHighWaterMark :exec
+INSERT OR REPLACE INTO high_water_mark (feed_url, guid, published_at, created_at)
+VALUES (?, ?, ?, ?)
+`
+
+type SaveHighWaterMarkParams struct {
+	FeedUrl     string
+	Guid        string
+	PublishedAt int64
+	CreatedAt   int64
+}
+
+func (q *Queries) SaveHighWaterMark(ctx context.Context, arg SaveHighWaterMarkParams) error {
+	_, err := q.db.ExecContext(ctx, saveHighWaterMark,
+		arg.FeedUrl,
+		arg.Guid,
+		arg.PublishedAt,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const saveIssue = `-- name: SaveIssue :one
+INSERT INTO issue (title, output_path, file_name, newsletter, created_at)
+VALUES (?, ?, ?, ?, ?)
+RETURNING id
+`
+
+type SaveIssueParams struct {
+	Title      string
+	OutputPath string
+	FileName   string
+	Newsletter string
+	CreatedAt  int64
+}
+
+func (q *Queries) SaveIssue(ctx context.Context, arg SaveIssueParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, saveIssue,
+		arg.Title,
+		arg.OutputPath,
+		arg.FileName,
+		arg.Newsletter,
+		arg.CreatedAt,
+	)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const saveProcessedItem = `-- name: SaveProcessedItem :exec
+INSERT OR IGNORE INTO processed_item (canonical_url, url, title, processed_at)
+VALUES (?, ?, ?, ?)
+`
+
+type SaveProcessedItemParams struct {
+	CanonicalUrl string
+	Url          string
+	Title        string
+	ProcessedAt  int64
+}
+
+func (q *Queries) SaveProcessedItem(ctx context.Context, arg SaveProcessedItemParams) error {
+	_, err := q.db.ExecContext(ctx, saveProcessedItem,
+		arg.CanonicalUrl,
+		arg.Url,
+		arg.Title,
+		arg.ProcessedAt,
+	)
+	return err
+}
+
+const saveReadingEvent = `-- name: SaveReadingEvent :exec
+INSERT INTO reading_event (kind, url, item_url, author, created_at)
+VALUES (?, ?, ?, ?, ?)
+`
+
+type SaveReadingEventParams struct {
+	Kind      string
+	Url       string
+	ItemUrl   string
+	Author    string
+	CreatedAt int64
+}
+
+func (q *Queries) SaveReadingEvent(ctx context.Context, arg SaveReadingEventParams) error {
+	_, err := q.db.ExecContext(ctx, saveReadingEvent,
+		arg.Kind,
+		arg.Url,
+		arg.ItemUrl,
+		arg.Author,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const saveSentEmail = `-- name: SaveSentEmail :exec
+INSERT OR REPLACE INTO sent_email (
+        message_id,
+        subject,
+        recipients,
+        size,
+        attempts,
+        error,
+        sent_at
+    )
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+type SaveSentEmailParams struct {
+	MessageID  string
+	Subject    string
+	Recipients string
+	Size       int64
+	Attempts   int64
+	Error      string
+	SentAt     int64
+}
+
+func (q *Queries) SaveSentEmail(ctx context.Context, arg SaveSentEmailParams) error {
+	_, err := q.db.ExecContext(ctx, saveSentEmail,
+		arg.MessageID,
+		arg.Subject,
+		arg.Recipients,
+		arg.Size,
+		arg.Attempts,
+		arg.Error,
+		arg.SentAt,
+	)
+	return err
+}
+
+const savePushLease = `-- name: SavePushLease :exec
+INSERT OR REPLACE INTO push_lease (feed_url, expires_at)
+VALUES (?, ?)
+`
+
+type SavePushLeaseParams struct {
+	FeedUrl   string
+	ExpiresAt int64
+}
+
+func (q *Queries) SavePushLease(ctx context.Context, arg SavePushLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, savePushLease, arg.FeedUrl, arg.ExpiresAt)
+	return err
+}
+
 const updateLastProcessedAt = `-- name: UpdateLastProcessedAt :exec
 INSERT OR REPLACE INTO feed (url, title, last_processed_at)
 VALUES (?, ?, ?)
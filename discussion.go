@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/cache"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/parser"
+)
+
+// summarizeDiscussion summarizes the comment thread of a parsed item.
+// Returns an empty string when the parser response carries no comments.
+func summarizeDiscussion(ctx context.Context, cacheDB *cache.Cache, discussionAgent agent.Agent, item fetcher.FeedItem, resource config.ResourceConfig, parsed parser.Response) (string, error) {
+	pipeline := resource.DiscussionPipeline()
+	if cached, hit, err := cacheDB.GetAgentOutput(item.Link, resource.ParserCacheKey(), pipeline); err == nil && hit {
+		slog.Debug("discussion cache hit", "url", item.Link)
+		return cached, nil
+	}
+
+	thread, ok := parsed.(parser.Discussion)
+	if !ok || thread.Comments() == "" {
+		return "", nil
+	}
+	if discussionAgent == nil {
+		return "", fmt.Errorf("agent '%s' not found", agent.Discussion)
+	}
+
+	summary, err := discussionAgent.Process(ctx, thread.Comments(), agentOptions(resource, parsed))
+	if err != nil {
+		return "", err
+	}
+	slog.Info("discussion summarized", "url", item.Link, "comments_length", len(thread.Comments()), "summary_length", len(summary))
+
+	if err := cacheDB.SetAgentOutput(item.Link, resource.ParserCacheKey(), pipeline, summary); err != nil {
+		slog.Warn("failed to cache discussion summary", "error", err)
+	}
+	return summary, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/daemon"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/filter"
+	"github.com/scipunch/myfeed/mailer"
+)
+
+const (
+	draftPending  = "pending"
+	draftApproved = "approved"
+	draftRejected = "rejected"
+)
+
+// delivery is the rendered issue for one set of recipients
+type delivery struct {
+	to   []string
+	msgs []mailer.Message
+}
+
+// renderDeliveries renders the issue for the main recipients and the
+// edition of every recipient group
+func renderDeliveries(t *template.Template, issue Newsletter, conf config.Config, outputPath string, filters *filter.FilterPipeline) []delivery {
+	var deliveries []delivery
+	if len(conf.Email.To) > 0 {
+		msgs, err := renderEmails(t, groupPages(issue, conf.GroupBy, time.Local), outputPath, conf.Email.MaxSizeBytes())
+		if err != nil {
+			slog.Error("failed to render email", "error", err)
+		}
+		deliveries = append(deliveries, delivery{to: conf.Email.To, msgs: msgs})
+	}
+	for _, recipient := range conf.Email.Recipients {
+		edition := editionFor(issue, recipient, filters)
+		if edition.pageCount() == 0 {
+			slog.Info("edition is empty, nothing to send", "recipients", recipient.To)
+			continue
+		}
+		msgs, err := renderEmails(t, groupPages(edition, conf.GroupBy, time.Local), outputPath, conf.Email.MaxSizeBytes())
+		if err != nil {
+			slog.Error("failed to render email", "error", err, "recipients", recipient.To)
+			continue
+		}
+		deliveries = append(deliveries, delivery{to: recipient.To, msgs: msgs})
+	}
+	return deliveries
+}
+
+// deliver sends every delivery by email
+func deliver(ctx context.Context, queries *db.Queries, cfg config.Email, deliveries []delivery) {
+	for _, d := range deliveries {
+		cfg.To = d.to
+		deliverEmails(ctx, queries, cfg, d.msgs)
+	}
+}
+
+// holdDraft saves the rendered emails of the issue as a draft instead of
+// sending them and notifies the main recipients where to preview it
+func holdDraft(ctx context.Context, queries *db.Queries, conf config.Config, title, htmlPath string, deliveries []delivery) error {
+	issue, err := filepath.Rel(conf.OutputDirectory, htmlPath)
+	if err != nil {
+		return fmt.Errorf("failed to locate issue in output directory with %w", err)
+	}
+	issue = filepath.ToSlash(issue)
+	created := time.Now()
+	id, err := queries.CreateDraft(ctx, db.CreateDraftParams{
+		Title:     title,
+		IssuePath: issue,
+		CreatedAt: created.Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create draft with %w", err)
+	}
+
+	part := 0
+	for _, d := range deliveries {
+		for _, msg := range d.msgs {
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return fmt.Errorf("failed to encode draft email with %w", err)
+			}
+			err = queries.SaveDraftEmail(ctx, db.SaveDraftEmailParams{
+				DraftID:    id,
+				Part:       int64(part),
+				Recipients: strings.Join(d.to, ", "),
+				Message:    string(data),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save draft email with %w", err)
+			}
+			part++
+		}
+	}
+	slog.Info("issue held for approval", "draft", id, "emails", part)
+
+	if len(conf.Email.To) > 0 {
+		notice := draftNotice(conf.Daemon, id, title, issue, created)
+		deliverEmails(ctx, queries, conf.Email, []mailer.Message{notice})
+	}
+	return nil
+}
+
+// draftNotice is the email telling where to preview and approve a draft
+func draftNotice(cfg config.Daemon, id int64, title, issue string, created time.Time) mailer.Message {
+	preview := cfg.BaseURL() + "/issues/" + issue
+	var b strings.Builder
+	fmt.Fprintf(&b, "<p>The issue <a href=\"%s\">%s</a> is ready for review.</p>\n", html.EscapeString(preview), html.EscapeString(title))
+	fmt.Fprintf(&b, "<p>Approve or reject it on the <a href=\"%s/\">dashboard</a>, or run <code>myfeed approve %d</code> or <code>myfeed reject %d</code>.</p>\n",
+		html.EscapeString(cfg.BaseURL()), id, id)
+	if cfg.AutoApprove.Duration > 0 {
+		fmt.Fprintf(&b, "<p>It is delivered automatically at %s unless it is rejected.</p>\n",
+			created.Add(cfg.AutoApprove.Duration).Format("2006-01-02 15:04"))
+	}
+	return mailer.Message{
+		Subject: "Draft: " + title,
+		HTML:    b.String(),
+	}
+}
+
+// approveDraft sends the emails of a pending draft
+func approveDraft(ctx context.Context, queries *db.Queries, cfg config.Email, id int64) error {
+	if err := decideDraft(ctx, queries, id, draftApproved); err != nil {
+		return err
+	}
+	emails, err := queries.ListDraftEmails(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to load draft emails with %w", err)
+	}
+
+	// Parts for the same recipients are consecutive
+	var deliveries []delivery
+	for _, e := range emails {
+		var msg mailer.Message
+		if err := json.Unmarshal([]byte(e.Message), &msg); err != nil {
+			return fmt.Errorf("failed to decode draft email with %w", err)
+		}
+		to := strings.Split(e.Recipients, ", ")
+		if n := len(deliveries); n > 0 && strings.Join(deliveries[n-1].to, ", ") == e.Recipients {
+			deliveries[n-1].msgs = append(deliveries[n-1].msgs, msg)
+			continue
+		}
+		deliveries = append(deliveries, delivery{to: to, msgs: []mailer.Message{msg}})
+	}
+	deliver(ctx, queries, cfg, deliveries)
+	slog.Info("draft approved", "draft", id, "emails", len(emails))
+
+	if err := queries.DeleteDraftEmails(ctx, id); err != nil {
+		slog.Warn("failed to delete sent draft emails", "draft", id, "error", err)
+	}
+	return nil
+}
+
+// rejectDraft discards a pending draft without sending it
+func rejectDraft(ctx context.Context, queries *db.Queries, id int64) error {
+	if err := decideDraft(ctx, queries, id, draftRejected); err != nil {
+		return err
+	}
+	slog.Info("draft rejected", "draft", id)
+	if err := queries.DeleteDraftEmails(ctx, id); err != nil {
+		slog.Warn("failed to delete rejected draft emails", "draft", id, "error", err)
+	}
+	return nil
+}
+
+// decideDraft moves a pending draft into status, a draft is decided once
+// even when the dashboard, the CLI and auto-approval race
+func decideDraft(ctx context.Context, queries *db.Queries, id int64, status string) error {
+	n, err := queries.DecideDraft(ctx, db.DecideDraftParams{
+		Status:    status,
+		DecidedAt: time.Now().Unix(),
+		ID:        id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update draft with %w", err)
+	}
+	if n == 0 {
+		return daemon.ErrDraftDecided
+	}
+	return nil
+}
+
+// approvalHooks connect the dashboard of the daemon to the drafts
+func approvalHooks(queries *db.Queries, cfg config.Email) daemon.ApprovalHooks {
+	return daemon.ApprovalHooks{
+		Pending: func(ctx context.Context) ([]daemon.Draft, error) {
+			pending, err := queries.ListPendingDrafts(ctx)
+			if err != nil {
+				return nil, err
+			}
+			drafts := make([]daemon.Draft, len(pending))
+			for i, p := range pending {
+				drafts[i] = daemon.Draft{
+					ID:        p.ID,
+					Title:     p.Title,
+					Issue:     p.IssuePath,
+					CreatedAt: time.Unix(p.CreatedAt, 0),
+				}
+			}
+			return drafts, nil
+		},
+		Approve: func(ctx context.Context, id int64) error {
+			return approveDraft(ctx, queries, cfg, id)
+		},
+		Reject: func(ctx context.Context, id int64) error {
+			return rejectDraft(ctx, queries, id)
+		},
+	}
+}
+
+// printDrafts prints the drafts waiting for approval
+func printDrafts(ctx context.Context, queries *db.Queries) error {
+	drafts, err := queries.ListPendingDrafts(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCREATED AT\tTITLE\tISSUE")
+	for _, d := range drafts {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", d.ID, time.Unix(d.CreatedAt, 0).Format(time.DateTime), d.Title, d.IssuePath)
+	}
+	return w.Flush()
+}
+
+// latestDraft returns the ID of the newest pending draft
+func latestDraft(ctx context.Context, queries *db.Queries) (int64, error) {
+	drafts, err := queries.ListPendingDrafts(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(drafts) == 0 {
+		return 0, fmt.Errorf("no drafts are waiting for approval")
+	}
+	return drafts[len(drafts)-1].ID, nil
+}
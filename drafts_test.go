@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/daemon"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/mailer"
+)
+
+func TestHoldDraft(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	database, err := initDB(ctx, filepath.Join(dir, "myfeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	queries := db.New(database)
+
+	conf := config.Config{OutputDirectory: dir}
+	deliveries := []delivery{
+		{to: []string{"a@example.com"}, msgs: []mailer.Message{{Subject: "Issue (1/2)"}, {Subject: "Issue (2/2)"}}},
+		{to: []string{"b@example.com", "c@example.com"}, msgs: []mailer.Message{{
+			Subject: "Issue",
+			Inline:  []mailer.Attachment{{Filename: "a.png", ContentID: "a", Data: []byte{1, 2}}},
+		}}},
+	}
+	htmlPath := filepath.Join(dir, "2025_03_01", "myfeed_2025_03_01.html")
+	if err := holdDraft(ctx, queries, conf, "Issue", htmlPath, deliveries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	drafts, err := approvalHooks(queries, conf.Email).Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drafts) != 1 || drafts[0].Issue != "2025_03_01/myfeed_2025_03_01.html" {
+		t.Fatalf("expected one pending draft of the issue, got %+v", drafts)
+	}
+	emails, err := queries.ListDraftEmails(ctx, drafts[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 3 || emails[2].Recipients != "b@example.com, c@example.com" || !strings.Contains(emails[2].Message, `"Data":"AQI="`) {
+		t.Errorf("expected the rendered emails to be saved, got %+v", emails)
+	}
+
+	if err := rejectDraft(ctx, queries, drafts[0].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := approveDraft(ctx, queries, conf.Email, drafts[0].ID); !errors.Is(err, daemon.ErrDraftDecided) {
+		t.Errorf("expected a rejected draft not to be approved, got %v", err)
+	}
+	if emails, _ := queries.ListDraftEmails(ctx, drafts[0].ID); len(emails) != 0 {
+		t.Errorf("expected emails of the rejected draft to be removed, got %d", len(emails))
+	}
+}
+
+func TestDraftNotice(t *testing.T) {
+	cfg := config.Daemon{PublicURL: "https://feed.example/", AutoApprove: config.Duration{Duration: 2 * time.Hour}}
+	created := time.Date(2025, 3, 1, 7, 0, 0, 0, time.Local)
+	msg := draftNotice(cfg, 4, "Issue", "2025_03_01/myfeed_2025_03_01.html", created)
+	for _, want := range []string{
+		`href="https://feed.example/issues/2025_03_01/myfeed_2025_03_01.html"`,
+		"myfeed approve 4",
+		"2025-03-01 09:00",
+	} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("expected notice to contain %q:\n%s", want, msg.HTML)
+		}
+	}
+	if msg.Subject != "Draft: Issue" {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/filter"
+)
+
+// editionFor returns the part of the issue a recipient asked for: resources
+// of the selected categories, with items passing the recipient filters.
+// Content is taken from the issue as is, nothing is parsed or processed again.
+func editionFor(n Newsletter, r config.Recipient, filters *filter.FilterPipeline) Newsletter {
+	edition := n
+	edition.Resources = nil
+	filtered := 0
+	for _, res := range n.Resources {
+		if len(r.Categories) > 0 && !slices.ContainsFunc(r.Categories, func(c string) bool {
+			return inCategory(res.Category, c)
+		}) {
+			continue
+		}
+
+		var pages []Page
+		for _, page := range res.Pages {
+			// Issues stored before descriptions were kept match their content
+			item := types.FeedItem{
+				Title:       page.Title,
+				Link:        page.Link,
+				Description: cmp.Or(page.Description, plainText(string(page.Content))),
+				Language:    page.Language,
+			}
+			if ok, _ := filters.ShouldInclude(item, r.Filters); ok {
+				pages = append(pages, page)
+			} else {
+				filtered++
+			}
+		}
+		if len(pages) > 0 {
+			res.Pages = pages
+			edition.Resources = append(edition.Resources, res)
+		}
+	}
+
+	// The footer describes the edition, not the whole issue
+	if n.Stats != nil {
+		stats := countContent(edition, *n.Stats)
+		stats.Filtered += filtered
+		edition.Stats = &stats
+	}
+	return edition
+}
+
+// inCategory reports whether category is the wanted one or nested into it,
+// e.g., "News/Local" is in "News"
+func inCategory(category, wanted string) bool {
+	return strings.EqualFold(category, wanted) ||
+		len(category) > len(wanted) && strings.EqualFold(category[:len(wanted)+1], wanted+"/")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/agent/usage"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/filter"
+)
+
+func TestEditionFor(t *testing.T) {
+	filters, err := filter.NewFilterPipeline(map[string]config.Filter{
+		"no_ads": {ExcludePatterns: []string{"(?i)sponsored"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := Newsletter{
+		Title: "Issue",
+		Resources: []Resource{
+			{Name: "Local", Category: "News/Local", Pages: []Page{
+				{Title: "Council meets", Link: "https://a.example/1", Description: "Agenda", Content: "<p>Agenda</p>"},
+				// The summary hides what the feed item said
+				{Title: "Deals", Link: "https://a.example/2", Description: "Sponsored by a shop", Content: "<p>Offers</p>"},
+			}},
+			{Name: "Go", Category: "Tech", Pages: []Page{
+				{Title: "Go 1.25", Link: "https://b.example/1", Content: "<p>Release <em>notes</em></p>"},
+				{Title: "Old issue", Link: "https://b.example/2", Content: "<p>Sponsored <b>post</b></p>"},
+			}},
+		},
+		Stats: &IssueStats{Sources: 2, Items: 4, Words: 7, Filtered: 3, Tokens: usage.Tokens{Input: 100}},
+	}
+
+	tests := []struct {
+		name      string
+		recipient config.Recipient
+		want      []string
+		filtered  int
+	}{
+		{
+			name: "everything",
+			want: []string{"https://a.example/1", "https://a.example/2", "https://b.example/1", "https://b.example/2"},
+		},
+		{
+			name:      "nested category",
+			recipient: config.Recipient{Categories: []string{"news"}},
+			want:      []string{"https://a.example/1", "https://a.example/2"},
+		},
+		{
+			name:      "filters match the feed description or stored content",
+			recipient: config.Recipient{Filters: []string{"no_ads"}},
+			want:      []string{"https://a.example/1", "https://b.example/1"},
+			filtered:  2,
+		},
+		{
+			name:      "nothing left",
+			recipient: config.Recipient{Categories: []string{"Sports"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edition := editionFor(issue, tt.recipient, filters)
+			var links []string
+			for _, res := range edition.Resources {
+				for _, page := range res.Pages {
+					links = append(links, page.Link)
+				}
+			}
+			if strings.Join(links, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", links, tt.want)
+			}
+			if edition.Stats.Items != len(tt.want) || edition.Stats.Sources != len(edition.Resources) ||
+				edition.Stats.Filtered != 3+tt.filtered || edition.Stats.Tokens.Sum() != 100 {
+				t.Errorf("expected stats of the edition, got %+v", *edition.Stats)
+			}
+		})
+	}
+	if issue.Stats.Items != 4 {
+		t.Error("expected the stats of the issue to be left as they are")
+	}
+}
+
+func TestInCategory(t *testing.T) {
+	tests := []struct {
+		category, wanted string
+		want             bool
+	}{
+		{"News", "News", true},
+		{"news", "News", true},
+		{"News/Local", "News", true},
+		{"News/Local/City", "news/local", true},
+		{"Newsletter", "News", false},
+		{"News", "News/Local", false},
+		{"", "News", false},
+		{"Tech", "News", false},
+	}
+	for _, tt := range tests {
+		if got := inCategory(tt.category, tt.wanted); got != tt.want {
+			t.Errorf("inCategory(%q, %q) = %v, want %v", tt.category, tt.wanted, got, tt.want)
+		}
+	}
+}
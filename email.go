@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/mailer"
+	"github.com/scipunch/myfeed/widget"
+)
+
+// deliverEmails sends the messages and records every delivery attempt in
+// the sent-mail log
+func deliverEmails(ctx context.Context, queries *db.Queries, cfg config.Email, msgs []mailer.Message) {
+	for i, msg := range msgs {
+		receipt, err := mailer.Send(ctx, cfg, msg)
+		entry := db.SaveSentEmailParams{
+			MessageID:  receipt.MessageID,
+			Subject:    msg.Subject,
+			Recipients: strings.Join(cfg.To, ", "),
+			Size:       int64(receipt.Size),
+			Attempts:   int64(receipt.Attempts),
+			SentAt:     time.Now().Unix(),
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			slog.Error("failed to send email", "error", err, "part", i+1, "parts", len(msgs))
+		} else {
+			slog.Info("issue sent by email",
+				"message_id", receipt.MessageID,
+				"recipients", len(cfg.To),
+				"attempts", receipt.Attempts,
+				"part", i+1,
+				"parts", len(msgs))
+		}
+		if err := queries.SaveSentEmail(ctx, entry); err != nil {
+			slog.Warn("failed to save sent email", "error", err, "message_id", receipt.MessageID)
+		}
+	}
+}
+
+// printSentEmails prints the latest entries of the sent-mail log
+func printSentEmails(ctx context.Context, queries *db.Queries, limit int) error {
+	emails, err := queries.ListSentEmails(ctx, int64(limit))
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SENT AT\tSTATUS\tATTEMPTS\tSIZE\tSUBJECT\tRECIPIENTS\tMESSAGE-ID")
+	for _, e := range emails {
+		status := "sent"
+		if e.Error != "" {
+			status = "failed: " + e.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%dKB\t%s\t%s\t%s\n",
+			time.Unix(e.SentAt, 0).Format(time.DateTime),
+			status,
+			e.Attempts,
+			e.Size/1024,
+			e.Subject,
+			e.Recipients,
+			e.MessageID)
+	}
+	return w.Flush()
+}
+
+// renderEmails renders the issue into as few messages as possible, each
+// under maxSize bytes (0 = no limit). Pages are split across numbered
+// messages, and a page exceeding the limit on its own is sent without its
+// embedded images.
+func renderEmails(t *template.Template, n Newsletter, issueDir string, maxSize int) ([]mailer.Message, error) {
+	msg, size, err := renderEmail(t, n, issueDir, false)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize <= 0 || size <= maxSize {
+		return []mailer.Message{msg}, nil
+	}
+
+	empty := n
+	empty.Resources = nil
+	_, overhead, err := renderEmail(t, empty, issueDir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pack pages greedily by their size when sent alone. Images shared
+	// between pages are counted for each of them, so parts never exceed the limit.
+	var parts []Newsletter
+	var omitImages []bool
+	part, partSize := empty, overhead
+	for _, res := range n.Resources {
+		for _, page := range res.Pages {
+			single := withPages(empty, res.Name, page)
+			_, pageSize, err := renderEmail(t, single, issueDir, false)
+			if err != nil {
+				return nil, err
+			}
+			pageSize -= overhead
+
+			oversized := pageSize+overhead > maxSize
+			if (oversized || partSize+pageSize > maxSize) && part.pageCount() > 0 {
+				parts = append(parts, part)
+				omitImages = append(omitImages, false)
+				part, partSize = empty, overhead
+			}
+			if oversized {
+				parts = append(parts, single)
+				omitImages = append(omitImages, true)
+				continue
+			}
+			part = withPages(part, res.Name, page)
+			partSize += pageSize
+		}
+	}
+	if part.pageCount() > 0 {
+		parts = append(parts, part)
+		omitImages = append(omitImages, false)
+	}
+
+	msgs := make([]mailer.Message, 0, len(parts))
+	for i, part := range parts {
+		part.Title = fmt.Sprintf("%s (%d/%d)", n.Title, i+1, len(parts))
+		if i < len(parts)-1 {
+			part.Stats = nil // Footer goes into the last part only
+		}
+		if i > 0 {
+			part.Widgets = nil // Widgets go into the first part only
+		}
+		msg, _, err := renderEmail(t, part, issueDir, omitImages[i])
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// withPages returns a copy of n with the page appended to the named resource,
+// which is added when it is not the last one
+func withPages(n Newsletter, name string, page Page) Newsletter {
+	resources := append([]Resource{}, n.Resources...)
+	if len(resources) == 0 || resources[len(resources)-1].Name != name {
+		resources = append(resources, Resource{Name: name})
+	}
+	last := &resources[len(resources)-1]
+	last.Pages = append(append([]Page{}, last.Pages...), page)
+	n.Resources = resources
+	return n
+}
+
+// renderEmail renders the issue with the email-client-safe template and
+// returns the message with its encoded size. Content is copied with styles
+// inlined and local images embedded (or omitted), images are resolved
+// against the issue directory.
+func renderEmail(t *template.Template, n Newsletter, issueDir string, omitImages bool) (mailer.Message, int, error) {
+	inliner := mailer.NewInliner(issueDir)
+	inliner.OmitImages = omitImages
+	email := n
+	email.Resources = make([]Resource, len(n.Resources))
+	for i, res := range n.Resources {
+		pages := make([]Page, len(res.Pages))
+		for j, page := range res.Pages {
+			content, err := inliner.Inline(string(page.Content))
+			if err != nil {
+				return mailer.Message{}, 0, fmt.Errorf("failed to inline '%s' with %w", page.Link, err)
+			}
+			discussion, err := inliner.Inline(string(page.Discussion))
+			if err != nil {
+				return mailer.Message{}, 0, fmt.Errorf("failed to inline discussion of '%s' with %w", page.Link, err)
+			}
+			page.Content, page.Discussion = template.HTML(content), template.HTML(discussion)
+			pages[j] = page
+		}
+		res.Pages = pages
+		email.Resources[i] = res
+	}
+	email.Widgets = make([]widget.Block, len(n.Widgets))
+	for i, block := range n.Widgets {
+		inlined, err := inliner.Inline(string(block.HTML))
+		if err != nil {
+			return mailer.Message{}, 0, fmt.Errorf("failed to inline widget '%s' with %w", block.Name, err)
+		}
+		block.HTML = template.HTML(inlined)
+		email.Widgets[i] = block
+	}
+
+	var body bytes.Buffer
+	if err := t.ExecuteTemplate(&body, "email", email); err != nil {
+		return mailer.Message{}, 0, fmt.Errorf("could not execute email template: %w", err)
+	}
+	msg := mailer.Message{
+		Subject: n.Title,
+		HTML:    body.String(),
+		Inline:  inliner.Attachments(),
+	}
+	size, err := msg.Size()
+	if err != nil {
+		return mailer.Message{}, 0, err
+	}
+	return msg, size, nil
+}
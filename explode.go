@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/urlnorm"
+)
+
+// explodeFeed replaces the items of a resource with explode_links by the
+// outbound links of their descriptions, other feeds are returned as they are
+func explodeFeed(resource config.ResourceConfig, feed fetcher.Feed) fetcher.Feed {
+	if !resource.ExplodeLinks {
+		return feed
+	}
+	seen := make(map[string]bool)
+	exploded := feed
+	exploded.Items = nil
+	for _, item := range feed.Items {
+		links := explodeLinks(item)
+		if len(links) == 0 {
+			// Issues without links, e.g., an editorial, stay as they are
+			exploded.Items = append(exploded.Items, item)
+			continue
+		}
+		for _, link := range links {
+			if key := urlnorm.Canonical(link.Link); !seen[key] {
+				seen[key] = true
+				exploded.Items = append(exploded.Items, link)
+			}
+		}
+	}
+	return exploded
+}
+
+// explodeLinks turns every link of the item description to another site into
+// an item titled with the link text and described by its paragraph
+func explodeLinks(item fetcher.FeedItem) []fetcher.FeedItem {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(item.Description))
+	if err != nil {
+		return nil
+	}
+	base, err := url.Parse(item.Link)
+	if err != nil {
+		return nil
+	}
+
+	var items []fetcher.FeedItem
+	doc.Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		ref, err := url.Parse(strings.TrimSpace(a.AttrOr("href", "")))
+		if err != nil {
+			return
+		}
+		link := base.ResolveReference(ref)
+		link.Fragment = ""
+		// Links back to the digest are navigation, e.g., "read online"
+		if link.Scheme != "http" && link.Scheme != "https" || sameSite(link.Host, base.Host) {
+			return
+		}
+		// Links without text are mostly logos and share buttons
+		title := strings.Join(strings.Fields(a.Text()), " ")
+		if title == "" {
+			return
+		}
+		context := a.Closest("li, p, td, blockquote, dd")
+		if context.Length() == 0 {
+			context = a
+		}
+		description, _ := context.Html()
+		items = append(items, fetcher.FeedItem{
+			Title:       title,
+			Link:        link.String(),
+			Description: description,
+			Published:   item.Published,
+			GUID:        link.String(),
+			Language:    item.Language,
+		})
+	})
+	return items
+}
+
+// sameSite reports whether two hosts belong to one site, ignoring "www."
+func sameSite(a, b string) bool {
+	return strings.TrimPrefix(strings.ToLower(a), "www.") == strings.TrimPrefix(strings.ToLower(b), "www.")
+}
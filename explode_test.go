@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/fetcher"
+)
+
+func TestExplodeFeed(t *testing.T) {
+	published := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+	feed := fetcher.Feed{Title: "Weekly", Items: []fetcher.FeedItem{
+		{
+			Title:     "Issue 42",
+			Link:      "https://weekly.example/42",
+			Published: published,
+			Description: `<p><a href="/42/web">Read online</a></p>
+				<ul>
+					<li><a href="https://blog.example/post#intro">A <b>great</b> post</a> about things</li>
+					<li><a href="https://other.example/a">Another</a> and <a href="mailto:me@example.com">mail</a></li>
+					<li><a href="https://other.example/a?utm_source=weekly"><img src="logo.png"></a></li>
+				</ul>`,
+		},
+		{Title: "Issue 43", Link: "https://weekly.example/43", Description: `<p>Only an <a href="https://www.weekly.example/about">editorial</a></p>`},
+		{Title: "Issue 44", Link: "https://weekly.example/44", Description: `<p><a href="https://other.example/a/">Another</a> again</p>`},
+	}}
+
+	if got := explodeFeed(config.ResourceConfig{}, feed); len(got.Items) != 3 {
+		t.Fatalf("expected feeds without explode_links to stay as they are, got %d items", len(got.Items))
+	}
+
+	got := explodeFeed(config.ResourceConfig{ExplodeLinks: true}, feed)
+	var links []string
+	for _, item := range got.Items {
+		links = append(links, item.Link)
+	}
+	want := []string{"https://blog.example/post", "https://other.example/a", "https://weekly.example/43"}
+	if len(links) != len(want) {
+		t.Fatalf("expected links %v, got %v", want, links)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("expected links %v, got %v", want, links)
+			break
+		}
+	}
+
+	first := got.Items[0]
+	if first.Title != "A great post" || first.GUID != first.Link || !first.Published.Equal(published) {
+		t.Errorf("expected the link text, the link as GUID and the date of the digest, got %+v", first)
+	}
+	if want := `<a href="https://blog.example/post#intro">A <b>great</b> post</a> about things`; first.Description != want {
+		t.Errorf("expected the list item as description, got %q", first.Description)
+	}
+	if got.Items[2].Title != "Issue 43" {
+		t.Errorf("expected a digest without outbound links to stay, got %+v", got.Items[2])
+	}
+}
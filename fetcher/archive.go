@@ -0,0 +1,93 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"log/slog"
+	neturl "net/url"
+	"strings"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// maxBackfillPages bounds how many archive pages a single backfill follows
+const maxBackfillPages = 500
+
+// backfill follows RFC 5005 links starting from the already fetched page and
+// collects items of every older page. Failures stop the walk keeping what was collected.
+func (f *RSSFetcher) backfill(ctx context.Context, feedURL string, body []byte, opts types.FetchOptions) []types.FeedItem {
+	pageURL := feedURL
+	var items []types.FeedItem
+	visited := map[string]bool{pageURL: true}
+
+	pages := 0
+	for ; pages < maxBackfillPages; pages++ {
+		next := nextPageLink(body, pageURL)
+		if next == "" || visited[next] {
+			break
+		}
+		visited[next] = true
+
+		var err error
+		body, _, err = f.get(ctx, next, opts, types.Validators{})
+		if err != nil {
+			slog.Warn("backfill stopped", "url", next, "error", err)
+			break
+		}
+		page, err := f.parser.Parse(bytes.NewReader(body))
+		if err != nil {
+			slog.Warn("backfill stopped, failed to parse archive page", "url", next, "error", err)
+			break
+		}
+		items = append(items, convertItems(page.Items)...)
+		pageURL = next
+	}
+
+	slog.Info("backfill finished", "feed", feedURL, "pages", pages, "items", len(items))
+	return items
+}
+
+// nextPageLink returns the absolute URL of the next older page advertised with
+// <link rel="next"> (paged feeds) or <link rel="prev-archive"> (archived feeds)
+func nextPageLink(body []byte, pageURL string) string {
+	base, err := neturl.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	links := make(map[string]string)
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.Strict = false
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok || el.Name.Local != "link" {
+			continue
+		}
+		var rel, href string
+		for _, attr := range el.Attr {
+			switch attr.Name.Local {
+			case "rel":
+				rel = strings.ToLower(strings.TrimSpace(attr.Value))
+			case "href":
+				href = strings.TrimSpace(attr.Value)
+			}
+		}
+		if href != "" && links[rel] == "" {
+			links[rel] = href
+		}
+	}
+
+	for _, rel := range []string{"next", "prev-archive"} {
+		if href := links[rel]; href != "" {
+			if u, err := base.Parse(href); err == nil {
+				return u.String()
+			}
+		}
+	}
+	return ""
+}
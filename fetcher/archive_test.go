@@ -0,0 +1,100 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const pagedFeed = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Paged</title>
+    %s
+    <item><title>%s</title><link>https://example.com/%s</link></item>
+  </channel>
+</rss>`
+
+func TestRSSFetcher_Backfill(t *testing.T) {
+	pages := map[string]string{
+		"/feed":   fmt.Sprintf(pagedFeed, `<atom:link rel="next" href="/feed?page=2"/>`, "Newest", "3"),
+		"/feed?2": fmt.Sprintf(pagedFeed, `<atom:link rel="next" href="/feed?page=3"/>`, "Older", "2"),
+		"/feed?3": fmt.Sprintf(pagedFeed, `<atom:link rel="next" href="/feed"/>`, "Oldest", "1"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path
+		if page := r.URL.Query().Get("page"); page != "" {
+			key += "?" + page
+		}
+		w.Write([]byte(pages[key]))
+	}))
+	defer srv.Close()
+
+	f := NewRSSFetcher(nil)
+	feed, err := f.Fetch(context.Background(), srv.URL+"/feed", FetchOptions{Backfill: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Items) != 1 || feed.Items[0].Title != "Newest" {
+		t.Errorf("unexpected items %+v", feed.Items)
+	}
+	if len(feed.Archive) != 2 || feed.Archive[0].Title != "Older" || feed.Archive[1].Title != "Oldest" {
+		t.Errorf("unexpected archive %+v", feed.Archive)
+	}
+
+	feed, err = f.Fetch(context.Background(), srv.URL+"/feed", FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Archive) != 0 {
+		t.Errorf("expected no archive without backfill, got %d items", len(feed.Archive))
+	}
+}
+
+func TestNextPageLink(t *testing.T) {
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom">
+  <link rel="self" href="https://example.com/feed"/>
+  <link rel="prev-archive" href="archive/2023"/>
+</feed>`
+	if got := nextPageLink([]byte(atom), "https://example.com/feed"); got != "https://example.com/archive/2023" {
+		t.Errorf("got %q", got)
+	}
+	if got := nextPageLink([]byte(testFeed), "https://example.com/feed"); got != "" {
+		t.Errorf("expected no next link, got %q", got)
+	}
+}
+
+func TestRSSFetcher_BackfillRequestTimeout(t *testing.T) {
+	pages := map[string]string{
+		"":  fmt.Sprintf(pagedFeed, `<atom:link rel="next" href="/feed?page=2"/>`, "Newest", "4"),
+		"2": fmt.Sprintf(pagedFeed, `<atom:link rel="next" href="/feed?page=3"/>`, "Older", "3"),
+		"3": fmt.Sprintf(pagedFeed, `<atom:link rel="next" href="/feed?page=4"/>`, "Oldest", "2"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Query().Get("page")]
+		if !ok {
+			// A stuck archive page ends the walk
+			<-r.Context().Done()
+			return
+		}
+		time.Sleep(30 * time.Millisecond)
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	// The walk takes longer than the timeout, every request fits into it
+	f := WithRetry(NewRSSFetcher(nil))
+	feed, err := f.Fetch(context.Background(), srv.URL+"/feed", FetchOptions{
+		Backfill: true,
+		Retry:    RetryPolicy{Timeout: 80 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Items) != 1 || len(feed.Archive) != 2 {
+		t.Errorf("expected 1 item and 2 archived ones, got %d and %d", len(feed.Items), len(feed.Archive))
+	}
+}
@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// ErrNoFeedFound is returned when an HTML page does not advertise any feed
+var ErrNoFeedFound = errors.New("no feed advertised on the page")
+
+// feedTypes ranks advertised feed MIME types, lower is better
+var feedTypes = map[string]int{
+	"application/rss+xml":   0,
+	"application/atom+xml":  0,
+	"application/feed+json": 1,
+	"application/json":      2,
+	"application/xml":       2,
+	"text/xml":              2,
+}
+
+// feedLink is a feed advertised with <link rel="alternate">
+type feedLink struct {
+	URL   string
+	Type  string
+	Title string
+}
+
+// isHTML reports whether a response body is an HTML page rather than a feed
+func isHTML(body []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// discoverFeeds returns feeds advertised in the page head, best candidate first.
+// Main feeds are preferred over comment feeds and RSS/Atom over JSON feeds.
+func discoverFeeds(body []byte, pageURL string) ([]feedLink, error) {
+	base, err := neturl.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []feedLink
+	seen := make(map[string]bool)
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		name, hasAttr := z.TagName()
+		if !hasAttr {
+			continue
+		}
+		switch string(name) {
+		case "base":
+			if href := attrs(z)["href"]; href != "" {
+				if u, err := base.Parse(href); err == nil {
+					base = u
+				}
+			}
+		case "link":
+			a := attrs(z)
+			mime := strings.ToLower(strings.TrimSpace(a["type"]))
+			if _, ok := feedTypes[mime]; !ok || !hasRel(a["rel"], "alternate") || a["href"] == "" {
+				continue
+			}
+			u, err := base.Parse(strings.TrimSpace(a["href"]))
+			if err != nil || seen[u.String()] {
+				continue
+			}
+			seen[u.String()] = true
+			links = append(links, feedLink{URL: u.String(), Type: mime, Title: a["title"]})
+		}
+	}
+
+	if len(links) == 0 {
+		return nil, ErrNoFeedFound
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		return rank(links[i]) < rank(links[j])
+	})
+	return links, nil
+}
+
+func rank(l feedLink) int {
+	r := feedTypes[l.Type]
+	if strings.Contains(strings.ToLower(l.Title+" "+l.URL), "comment") {
+		r += 10
+	}
+	return r
+}
+
+func attrs(z *html.Tokenizer) map[string]string {
+	a := make(map[string]string)
+	for {
+		key, val, more := z.TagAttr()
+		a[strings.ToLower(string(key))] = string(val)
+		if !more {
+			return a
+		}
+	}
+}
+
+func hasRel(rel, want string) bool {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		if r == want {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverFeed returns the best feed of a site, url is either the feed
+// itself or a page advertising it
+func (f *RSSFetcher) DiscoverFeed(ctx context.Context, url string, opts types.FetchOptions) (string, error) {
+	body, _, err := f.get(ctx, url, opts, types.Validators{})
+	if err != nil {
+		return "", err
+	}
+	if !isHTML(body) {
+		return url, nil
+	}
+	candidates, err := discoverFeeds(body, url)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0].URL, nil
+}
@@ -0,0 +1,91 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Blog</title>
+  <link rel="alternate" type="application/rss+xml" title="Comments" href="/comments/feed">
+  <link rel="stylesheet" href="/style.css">
+  <link rel="alternate" type="application/feed+json" href="/feed.json">
+  <link rel="Alternate" type="application/rss+xml" title="Posts" href="/feed.xml">
+</head>
+<body></body>
+</html>`
+
+func TestDiscoverFeeds(t *testing.T) {
+	links, err := discoverFeeds([]byte(testPage), "https://example.com/blog/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"https://example.com/feed.xml",
+		"https://example.com/feed.json",
+		"https://example.com/comments/feed",
+	}
+	if len(links) != len(want) {
+		t.Fatalf("expected %d links, got %+v", len(want), links)
+	}
+	for i, w := range want {
+		if links[i].URL != w {
+			t.Errorf("link %d: got %s, want %s", i, links[i].URL, w)
+		}
+	}
+}
+
+func TestDiscoverFeeds_None(t *testing.T) {
+	_, err := discoverFeeds([]byte("<html><head></head></html>"), "https://example.com")
+	if !errors.Is(err, ErrNoFeedFound) {
+		t.Fatalf("expected ErrNoFeedFound, got %v", err)
+	}
+}
+
+func TestRSSFetcher_Autodiscovery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/blog/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testFeed))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	feed, err := NewRSSFetcher(nil).Fetch(context.Background(), srv.URL+"/blog/", FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Title != "Test feed" {
+		t.Errorf("expected discovered feed, got %q", feed.Title)
+	}
+}
+
+func TestRSSFetcher_DiscoverFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testFeed))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := NewRSSFetcher(nil)
+	for _, url := range []string{srv.URL + "/", srv.URL + "/feed.xml"} {
+		got, err := f.DiscoverFeed(context.Background(), url, FetchOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != srv.URL+"/feed.xml" {
+			t.Errorf("expected the feed of %s, got %s", url, got)
+		}
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/scipunch/myfeed/fetcher/types"
 )
 
-// GetFetchers creates a map of resource types to their corresponding fetchers
-func GetFetchers(resourceTypes []config.ResourceType, configDir string) (map[config.ResourceType]types.FeedFetcher, error) {
+// GetFetchers creates a map of resource types to their corresponding fetchers.
+// validators enables conditional GET for fetchers supporting it, offsets
+// enables fetching Telegram messages newer than the last run, nil disables them.
+func GetFetchers(resourceTypes []config.ResourceType, configDir string, login config.TelegramLogin, validators types.ValidatorStore, offsets types.OffsetStore) (map[config.ResourceType]types.FeedFetcher, error) {
 	fetchers := make(map[config.ResourceType]types.FeedFetcher)
 
 	// Check if telegram is needed
@@ -24,8 +26,10 @@ func GetFetchers(resourceTypes []config.ResourceType, configDir string) (map[con
 	// Load or prompt for telegram credentials if needed
 	var telegramCreds config.TelegramCredentials
 	if needsTelegram {
-		credPath := config.DefaultCredentialsPath()
-		var err error
+		credPath, err := config.DefaultCredentialsPath()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate telegram credentials: %w", err)
+		}
 		telegramCreds, err = config.LoadOrPromptTelegramCredentials(credPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get telegram credentials: %w", err)
@@ -40,9 +44,9 @@ func GetFetchers(resourceTypes []config.ResourceType, configDir string) (map[con
 
 		switch rt {
 		case config.RSS:
-			fetchers[rt] = NewRSSFetcher()
+			fetchers[rt] = WithRetry(NewRSSFetcher(validators))
 		case config.TelegramChannel:
-			fetchers[rt] = telegram.NewTelegramFetcher(configDir, telegramCreds.AppID, telegramCreds.AppHash, telegramCreds.PhoneNumber)
+			fetchers[rt] = telegram.NewTelegramFetcher(configDir, telegramCreds.AppID, telegramCreds.AppHash, telegramCreds.PhoneNumber, TelegramLoginOptions(login), offsets)
 		default:
 			return nil, fmt.Errorf("unknown resource type: %s", rt)
 		}
@@ -50,3 +54,12 @@ func GetFetchers(resourceTypes []config.ResourceType, configDir string) (map[con
 
 	return fetchers, nil
 }
+
+// TelegramLoginOptions converts the login settings for the Telegram client
+func TelegramLoginOptions(login config.TelegramLogin) telegram.LoginOptions {
+	return telegram.LoginOptions{
+		NonInteractive:  login.NonInteractive,
+		CodeCommand:     login.CodeCommand,
+		PasswordCommand: login.PasswordCommand,
+	}
+}
@@ -8,3 +8,10 @@ import (
 type Feed = types.Feed
 type FeedItem = types.FeedItem
 type FeedFetcher = types.FeedFetcher
+type FetchOptions = types.FetchOptions
+type RetryPolicy = types.RetryPolicy
+type Validators = types.Validators
+type ValidatorStore = types.ValidatorStore
+type OffsetStore = types.OffsetStore
+
+var ErrNotModified = types.ErrNotModified
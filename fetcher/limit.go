@@ -0,0 +1,61 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxSize bounds feed responses when FetchOptions.MaxSize is not set
+const DefaultMaxSize = 10 << 20
+
+// ErrTooLarge is returned for responses over the size limit which can't be truncated
+var ErrTooLarge = errors.New("response is larger than the size limit")
+
+// feedClosers end the root elements left open by cutting a feed after an item
+var feedClosers = []struct {
+	root, end, closer string
+}{
+	{"<rss", "</item>", "</channel></rss>"},
+	{"<rdf:RDF", "</item>", "</rdf:RDF>"},
+	{"<feed", "</entry>", "</feed>"},
+}
+
+// readLimited reads the body up to limit bytes and cuts longer feeds after
+// their last complete item, so misbehaving feeds can't exhaust memory
+func readLimited(r io.Reader, limit int64) ([]byte, bool, error) {
+	if limit <= 0 {
+		limit = DefaultMaxSize
+	}
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(body)) <= limit {
+		return body, false, nil
+	}
+
+	body, err = truncateFeed(body[:limit])
+	if err != nil {
+		return nil, false, fmt.Errorf("%w of %d bytes: %w", ErrTooLarge, limit, err)
+	}
+	return body, true, nil
+}
+
+// truncateFeed keeps the items of a cut RSS or Atom document up to the
+// last complete one and closes its root elements
+func truncateFeed(body []byte) ([]byte, error) {
+	for _, c := range feedClosers {
+		if !bytes.Contains(body, []byte(c.root)) {
+			continue
+		}
+		end := bytes.LastIndex(body, []byte(c.end))
+		if end < 0 {
+			return nil, errors.New("no complete item before the limit")
+		}
+		truncated := append(body[:end+len(c.end):end+len(c.end)], c.closer...)
+		return truncated, nil
+	}
+	return nil, errors.New("not an RSS or Atom feed")
+}
@@ -0,0 +1,66 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRSSFetcher_MaxSize(t *testing.T) {
+	var items strings.Builder
+	for i := range 100 {
+		fmt.Fprintf(&items, "<item><title>Item %d</title><link>https://example.com/%d</link></item>\n", i, i)
+	}
+	huge := `<?xml version="1.0"?><rss version="2.0"><channel><title>Huge</title>` + items.String() + `</channel></rss>`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(huge))
+	}))
+	defer srv.Close()
+
+	// Items before the limit are kept
+	feed, err := NewRSSFetcher(nil).Fetch(context.Background(), srv.URL, FetchOptions{MaxSize: int64(len(huge) / 2)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Title != "Huge" || len(feed.Items) == 0 || len(feed.Items) >= 100 {
+		t.Errorf("expected a truncated feed, got %d items", len(feed.Items))
+	}
+	if last := feed.Items[len(feed.Items)-1]; !strings.HasPrefix(last.Link, "https://example.com/") {
+		t.Errorf("unexpected last item: %+v", last)
+	}
+
+	// A limit before the first item can't be recovered from
+	_, err = NewRSSFetcher(nil).Fetch(context.Background(), srv.URL, FetchOptions{MaxSize: 80})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
+
+func TestTruncateFeed(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"rss", `<rss><channel><item>1</item><item>2`, `<rss><channel><item>1</item></channel></rss>`},
+		{"rdf", `<rdf:RDF><channel/><item>1</item><it`, `<rdf:RDF><channel/><item>1</item></rdf:RDF>`},
+		{"atom", `<feed><entry>1</entry><entry>2</entry><entry>`, `<feed><entry>1</entry><entry>2</entry></feed>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := truncateFeed([]byte(tt.body))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := truncateFeed([]byte(`<html><body>`)); err == nil {
+		t.Error("expected error for a document which is not a feed")
+	}
+}
@@ -0,0 +1,108 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// WithRetry wraps a fetcher applying the timeout and retry policy from
+// FetchOptions.Retry with exponential backoff between attempts
+func WithRetry(f types.FeedFetcher) types.FeedFetcher {
+	return &retryFetcher{underlying: f}
+}
+
+type retryFetcher struct {
+	underlying types.FeedFetcher
+}
+
+func (r *retryFetcher) Fetch(ctx context.Context, url string, opts types.FetchOptions) (types.Feed, error) {
+	policy := opts.Retry
+	backoff := policy.InitialBackoff
+
+	var lastErr error
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		feed, err := r.attempt(ctx, url, opts)
+		if err == nil {
+			if attempt > 0 {
+				slog.Info("feed fetched after retries", "url", url, "attempts", attempt+1)
+			}
+			return feed, nil
+		}
+		lastErr = err
+
+		// Parent context is done or the error won't go away on its own
+		if ctx.Err() != nil || !isTransient(err) {
+			return feed, err
+		}
+		if attempt == policy.MaxRetries {
+			break
+		}
+
+		slog.Warn("feed fetch failed, retrying",
+			"url", url,
+			"attempt", attempt+1,
+			"max_attempts", policy.MaxRetries+1,
+			"retry_in", backoff,
+			"error", err)
+
+		select {
+		case <-ctx.Done():
+			return types.Feed{}, fmt.Errorf("fetch cancelled during backoff: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+
+	return types.Feed{}, fmt.Errorf("max retries (%d) exceeded: %w", policy.MaxRetries, lastErr)
+}
+
+// attempt runs a single fetch limited by the per-attempt timeout. Walking
+// the whole history can't fit into it, a backfill limits every request.
+func (r *retryFetcher) attempt(ctx context.Context, url string, opts types.FetchOptions) (types.Feed, error) {
+	if opts.Retry.Timeout > 0 && !opts.Backfill {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Retry.Timeout)
+		defer cancel()
+	}
+	return r.underlying.Fetch(ctx, url, opts)
+}
+
+// isTransient reports whether a failed fetch may succeed if repeated:
+// timeouts, connection errors, 408, 425, 429 and 5xx responses
+func isTransient(err error) bool {
+	if errors.Is(err, types.ErrNotModified) {
+		return false
+	}
+
+	var httpErr gofeed.HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusRequestTimeout,
+			httpErr.StatusCode == http.StatusTooEarly,
+			httpErr.StatusCode == http.StatusTooManyRequests,
+			httpErr.StatusCode >= 500:
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,82 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithRetry_RetriesTransientStatus(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(testFeed))
+	}))
+	defer srv.Close()
+
+	f := WithRetry(NewRSSFetcher(nil))
+	feed, err := f.Fetch(context.Background(), srv.URL, FetchOptions{
+		Retry: RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Items) != 1 {
+		t.Errorf("expected 1 item, got %d", len(feed.Items))
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 requests, got %d", calls.Load())
+	}
+}
+
+func TestWithRetry_StopsOnPermanentStatus(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	f := WithRetry(NewRSSFetcher(nil))
+	_, err := f.Fetch(context.Background(), srv.URL, FetchOptions{
+		Retry: RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a single request, got %d", calls.Load())
+	}
+}
+
+func TestWithRetry_AttemptTimeout(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(testFeed))
+	}))
+	defer srv.Close()
+
+	f := WithRetry(NewRSSFetcher(nil))
+	_, err := f.Fetch(context.Background(), srv.URL, FetchOptions{
+		Retry: RetryPolicy{Timeout: 50 * time.Millisecond, MaxRetries: 1, InitialBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 requests, got %d", calls.Load())
+	}
+}
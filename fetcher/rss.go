@@ -1,32 +1,84 @@
 package fetcher
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
 
 	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/httpclient"
+	"github.com/scipunch/myfeed/ratelimit"
 )
 
+// acceptFeeds prefers feed formats while still accepting HTML pages for autodiscovery
+const acceptFeeds = "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, text/xml;q=0.9, text/html;q=0.5, */*;q=0.1"
+
 // RSSFetcher fetches RSS feeds using gofeed
 type RSSFetcher struct {
-	parser *gofeed.Parser
+	parser     *gofeed.Parser
+	client     *http.Client
+	validators types.ValidatorStore
 }
 
-// NewRSSFetcher creates a new RSS fetcher
-func NewRSSFetcher() *RSSFetcher {
+// NewRSSFetcher creates a new RSS fetcher.
+// When validators is not nil, feeds are requested conditionally and
+// types.ErrNotModified is returned for unchanged feeds.
+func NewRSSFetcher(validators types.ValidatorStore) *RSSFetcher {
+	// Can't fail without a proxy
+	client, _ := httpclient.New("")
 	return &RSSFetcher{
-		parser: gofeed.NewParser(),
+		parser:     newFeedParser(),
+		client:     client,
+		validators: validators,
 	}
 }
 
-// Fetch retrieves and parses an RSS feed from the given URL
-func (f *RSSFetcher) Fetch(ctx context.Context, url string) (types.Feed, error) {
+// Fetch retrieves and parses an RSS feed from the given URL.
+// If the URL points to an HTML page, the feed advertised by it is used instead.
+func (f *RSSFetcher) Fetch(ctx context.Context, url string, opts types.FetchOptions) (types.Feed, error) {
 	var feed types.Feed
 
-	gofeedFeed, err := f.parser.ParseURLWithContext(url, ctx)
+	// Send validators from the previous run for a conditional GET
+	var prev types.Validators
+	if f.validators != nil {
+		var err error
+		prev, err = f.validators.GetValidators(ctx, url)
+		if err != nil {
+			slog.Warn("failed to load feed validators", "error", err, "url", url)
+		}
+	}
+
+	feedURL := url
+	body, header, err := f.get(ctx, feedURL, opts, prev)
+	if err != nil {
+		return feed, err
+	}
+
+	if isHTML(body) {
+		candidates, err := discoverFeeds(body, url)
+		if err != nil {
+			return feed, fmt.Errorf("failed to discover feed at '%s': %w", url, err)
+		}
+		best := candidates[0]
+		slog.Info("discovered feed",
+			"page", url,
+			"feed", best.URL,
+			"title", best.Title,
+			"candidates", len(candidates))
+		feedURL = best.URL
+		body, header, err = f.get(ctx, feedURL, opts, prev)
+		if err != nil {
+			return feed, err
+		}
+	}
+
+	gofeedFeed, err := f.parser.Parse(bytes.NewReader(body))
 	if err != nil {
 		return feed, fmt.Errorf("failed to parse RSS feed: %w", err)
 	}
@@ -34,14 +86,63 @@ func (f *RSSFetcher) Fetch(ctx context.Context, url string) (types.Feed, error)
 	// Convert gofeed.Feed to our custom Feed type
 	feed.Title = gofeedFeed.Title
 	feed.Description = gofeedFeed.Description
-	feed.Items = make([]types.FeedItem, 0, len(gofeedFeed.Items))
+	feed.Items = convertItems(gofeedFeed.Items)
+	feed.Validators = types.Validators{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+
+	if opts.Backfill {
+		feed.Archive = f.backfill(ctx, feedURL, body, opts)
+	}
+
+	return feed, nil
+}
+
+// newFeedParser creates a parser keeping the comments link of RSS items
+func newFeedParser() *gofeed.Parser {
+	p := gofeed.NewParser()
+	p.RSSTranslator = &rssTranslator{}
+	return p
+}
+
+// rssTranslator stores the <comments> link of RSS items, e.g., the HN
+// thread of an article, in Custom["comments"]
+type rssTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *rssTranslator) Translate(feed any) (*gofeed.Feed, error) {
+	translated, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	rssFeed, ok := feed.(*rss.Feed)
+	if !ok || len(rssFeed.Items) != len(translated.Items) {
+		return translated, nil
+	}
+	for i, item := range rssFeed.Items {
+		if item.Comments == "" {
+			continue
+		}
+		if translated.Items[i].Custom == nil {
+			translated.Items[i].Custom = make(map[string]string)
+		}
+		translated.Items[i].Custom["comments"] = item.Comments
+	}
+	return translated, nil
+}
 
-	for _, item := range gofeedFeed.Items {
+// convertItems converts gofeed items to our custom FeedItem type
+func convertItems(gofeedItems []*gofeed.Item) []types.FeedItem {
+	items := make([]types.FeedItem, 0, len(gofeedItems))
+	for _, item := range gofeedItems {
 		feedItem := types.FeedItem{
 			Title:       item.Title,
 			Link:        item.Link,
 			Description: item.Description,
 			GUID:        item.GUID,
+			CommentsURL: item.Custom["comments"],
 		}
 
 		// Parse published date if available
@@ -53,8 +154,78 @@ func (f *RSSFetcher) Fetch(ctx context.Context, url string) (types.Feed, error)
 			feedItem.Published = time.Time{}
 		}
 
-		feed.Items = append(feed.Items, feedItem)
+		items = append(items, feedItem)
 	}
+	return items
+}
 
-	return feed, nil
+// get requests the URL and returns the response body and headers. While
+// backfilling, the attempt timeout applies to every request.
+func (f *RSSFetcher) get(ctx context.Context, url string, opts types.FetchOptions, prev types.Validators) ([]byte, http.Header, error) {
+	if opts.Backfill && opts.Retry.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Retry.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	userAgent := opts.UserAgent
+	if userAgent == "" {
+		userAgent = httpclient.DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", acceptFeeds)
+	for key, values := range opts.Header {
+		req.Header[key] = values
+	}
+	if prev.ETag != "" {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if prev.LastModified != "" {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+
+	client, err := f.httpClient(opts.Proxy)
+	if err != nil {
+		return nil, nil, err
+	}
+	release, err := ratelimit.Acquire(ctx, ratelimit.HostKey(url))
+	if err != nil {
+		return nil, nil, fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+	defer release()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to request RSS feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil, types.ErrNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	body, truncated, err := readLimited(resp.Body, opts.MaxSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read RSS feed: %w", err)
+	}
+	if truncated {
+		slog.Warn("feed exceeds the size limit, keeping items before it", "url", url, "size", len(body))
+	}
+	return body, resp.Header, nil
+}
+
+// httpClient returns a client sending requests through the proxy if set
+func (f *RSSFetcher) httpClient(proxy string) (*http.Client, error) {
+	if proxy == "" {
+		return f.client, nil
+	}
+	return httpclient.New(proxy)
 }
@@ -0,0 +1,152 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Test feed</title>
+    <item>
+      <title>First</title>
+      <link>https://example.com/first</link>
+    </item>
+  </channel>
+</rss>`
+
+type staticValidators Validators
+
+func (s staticValidators) GetValidators(ctx context.Context, url string) (Validators, error) {
+	return Validators(s), nil
+}
+
+func TestRSSFetcher_ConditionalGet(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(testFeed))
+	}))
+	defer srv.Close()
+
+	// First fetch without stored validators returns the feed and its validators
+	feed, err := NewRSSFetcher(staticValidators{}).Fetch(context.Background(), srv.URL, FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feed.Title != "Test feed" || len(feed.Items) != 1 {
+		t.Errorf("unexpected feed: %+v", feed)
+	}
+	if feed.Validators.ETag != etag || feed.Validators.LastModified != lastModified {
+		t.Errorf("unexpected validators: %+v", feed.Validators)
+	}
+
+	// Second fetch with stored validators is skipped
+	_, err = NewRSSFetcher(staticValidators(feed.Validators)).Fetch(context.Background(), srv.URL, FetchOptions{})
+	if !errors.Is(err, ErrNotModified) {
+		t.Errorf("expected ErrNotModified, got %v", err)
+	}
+
+	// Without a store the feed is always fetched
+	if _, err := NewRSSFetcher(nil).Fetch(context.Background(), srv.URL, FetchOptions{}); err != nil {
+		t.Errorf("unexpected error without validator store: %v", err)
+	}
+}
+
+func TestRSSFetcher_Headers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Cookie") != "session=abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testFeed))
+	}))
+	defer srv.Close()
+
+	header := make(http.Header)
+	header.Set("Authorization", "Bearer secret")
+	header.Set("Cookie", "session=abc")
+
+	if _, err := NewRSSFetcher(nil).Fetch(context.Background(), srv.URL, FetchOptions{Header: header}); err != nil {
+		t.Errorf("unexpected error with auth headers: %v", err)
+	}
+	if _, err := NewRSSFetcher(nil).Fetch(context.Background(), srv.URL, FetchOptions{}); err == nil {
+		t.Error("expected error without auth headers")
+	}
+}
+
+func TestRSSFetcher_UserAgent(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.Write([]byte(testFeed))
+	}))
+	defer srv.Close()
+
+	f := NewRSSFetcher(nil)
+	if _, err := f.Fetch(context.Background(), srv.URL, FetchOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got, "myfeed/") {
+		t.Errorf("expected default user agent identifying myfeed, got %q", got)
+	}
+
+	if _, err := f.Fetch(context.Background(), srv.URL, FetchOptions{UserAgent: "Custom/2.0"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "Custom/2.0" {
+		t.Errorf("expected configured user agent, got %q", got)
+	}
+}
+
+func TestRSSFetcher_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Write([]byte(testFeed))
+	}))
+	defer proxy.Close()
+
+	const feedURL = "http://feeds.example.com/rss"
+	feed, err := NewRSSFetcher(nil).Fetch(context.Background(), feedURL, FetchOptions{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if proxied != feedURL {
+		t.Errorf("expected request for %s to go through proxy, got %q", feedURL, proxied)
+	}
+	if feed.Title != "Test feed" {
+		t.Errorf("unexpected feed title: %s", feed.Title)
+	}
+}
+
+func TestRSSFetcher_CommentsURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Hacker News</title>
+<item><title>Show HN</title><link>https://example.com/</link><comments>https://news.ycombinator.com/item?id=42</comments></item>
+<item><title>No thread</title><link>https://example.com/2</link></item>
+</channel></rss>`))
+	}))
+	defer srv.Close()
+
+	feed, err := NewRSSFetcher(nil).Fetch(context.Background(), srv.URL, FetchOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(feed.Items) != 2 || feed.Items[0].CommentsURL != "https://news.ycombinator.com/item?id=42" || feed.Items[1].CommentsURL != "" {
+		t.Errorf("unexpected comments links: %+v", feed.Items)
+	}
+}
@@ -2,10 +2,12 @@ package telegram
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
 	"syscall"
 
@@ -60,3 +62,73 @@ func (TerminalUserAuthenticator) Password(_ context.Context) (string, error) {
 	fmt.Println() // Add newline after password input
 	return strings.TrimSpace(string(bytePwd)), nil
 }
+
+// ErrLoginRequired is returned when the session is missing or expired and
+// the login code can't be obtained without a terminal
+var ErrLoginRequired = errors.New("telegram session is missing or expired, log in by running myfeed in a terminal or set telegram_login.code_command")
+
+// LoginOptions configures logging in when the session is missing or expired
+type LoginOptions struct {
+	NonInteractive  bool   // Never prompt on stdin, implied when stdin is not a terminal
+	CodeCommand     string // Shell command printing the login code
+	PasswordCommand string // Shell command printing the 2FA password
+}
+
+func (o LoginOptions) interactive() bool {
+	return !o.NonInteractive && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// canLogin reports whether a login code can be obtained
+func (o LoginOptions) canLogin() bool {
+	return o.CodeCommand != "" || o.interactive()
+}
+
+// loginAuthenticator reads the login code and password from commands,
+// prompting the terminal only when running interactively
+type loginAuthenticator struct {
+	TerminalUserAuthenticator
+	opts LoginOptions
+}
+
+func (a loginAuthenticator) Code(ctx context.Context, sentCode *tg.AuthSentCode) (string, error) {
+	if a.opts.CodeCommand != "" {
+		return commandOutput(ctx, a.opts.CodeCommand)
+	}
+	if !a.opts.interactive() {
+		return "", ErrLoginRequired
+	}
+	return a.TerminalUserAuthenticator.Code(ctx, sentCode)
+}
+
+func (a loginAuthenticator) Phone(ctx context.Context) (string, error) {
+	if a.PhoneNumber == "" && !a.opts.interactive() {
+		return "", errors.New("telegram phone number is missing from credentials")
+	}
+	return a.TerminalUserAuthenticator.Phone(ctx)
+}
+
+func (a loginAuthenticator) Password(ctx context.Context) (string, error) {
+	if a.opts.PasswordCommand != "" {
+		return commandOutput(ctx, a.opts.PasswordCommand)
+	}
+	if !a.opts.interactive() {
+		return "", errors.New("telegram account requires a 2FA password, set telegram_login.password_command")
+	}
+	return a.TerminalUserAuthenticator.Password(ctx)
+}
+
+// commandOutput runs the command with sh and returns its trimmed stdout
+func commandOutput(ctx context.Context, command string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("'%s' failed with %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	value := strings.TrimSpace(string(out))
+	if value == "" {
+		return "", fmt.Errorf("'%s' printed nothing", command)
+	}
+	return value, nil
+}
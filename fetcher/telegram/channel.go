@@ -0,0 +1,211 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gotd/td/telegram/query"
+	"github.com/gotd/td/telegram/query/dialogs"
+	"github.com/gotd/td/tg"
+)
+
+// channelRef identifies a channel by exactly one of its username,
+// invite link hash or numeric ID. Topic selects a topic of a forum
+// supergroup, zero for channels.
+type channelRef struct {
+	Username   string
+	InviteHash string
+	ID         int64
+	Topic      int
+}
+
+func (r channelRef) String() string {
+	var s string
+	switch {
+	case r.Username != "":
+		s = "@" + r.Username
+	case r.InviteHash != "":
+		s = "invite +" + r.InviteHash
+	default:
+		s = fmt.Sprintf("channel %d", r.ID)
+	}
+	if r.Topic != 0 {
+		s += fmt.Sprintf(" topic %d", r.Topic)
+	}
+	return s
+}
+
+// errFound stops the dialogs iteration once the channel is found
+var errFound = errors.New("found")
+
+// parseChannelRef extracts the channel reference from various URL formats
+// Supports:
+//   - https://t.me/channelname, t.me/channelname, @channelname, channelname
+//   - https://t.me/+hash, https://t.me/joinchat/hash (private invite links)
+//   - https://t.me/c/1234567890, -1001234567890, 1234567890 (channel IDs)
+//   - any of the above with ?topic=123 (a topic of a forum supergroup)
+func parseChannelRef(url string) (channelRef, error) {
+	url = strings.TrimSpace(url)
+
+	// Forum topic
+	url, rawQuery, _ := strings.Cut(url, "?")
+	topic := 0
+	if rawQuery != "" {
+		values, err := neturl.ParseQuery(rawQuery)
+		if err != nil {
+			return channelRef{}, fmt.Errorf("invalid channel URL query: %w", err)
+		}
+		if t := values.Get("topic"); t != "" {
+			topic, err = strconv.Atoi(t)
+			if err != nil || topic <= 0 {
+				return channelRef{}, fmt.Errorf("invalid topic ID: %s", t)
+			}
+		}
+	}
+	ref, err := parseChannelPath(url)
+	if err != nil {
+		return channelRef{}, err
+	}
+	ref.Topic = topic
+	return ref, nil
+}
+
+func parseChannelPath(url string) (channelRef, error) {
+
+	// Remove protocol if present
+	url = strings.TrimPrefix(url, "https://")
+	url = strings.TrimPrefix(url, "http://")
+
+	// Remove t.me/ if present
+	url = strings.TrimPrefix(url, "t.me/")
+
+	// Remove @ if present
+	url = strings.TrimPrefix(url, "@")
+
+	// Remove trailing slash
+	url = strings.TrimSuffix(url, "/")
+
+	// Validate username (basic validation)
+	if url == "" {
+		return channelRef{}, fmt.Errorf("empty channel username")
+	}
+
+	// Private invite links
+	if hash, ok := strings.CutPrefix(url, "+"); ok {
+		return channelRef{InviteHash: hash}, nil
+	}
+	if hash, ok := strings.CutPrefix(url, "joinchat/"); ok {
+		return channelRef{InviteHash: hash}, nil
+	}
+
+	// Channel IDs, usernames can't start with a digit
+	id := strings.TrimPrefix(url, "c/")
+	id = strings.TrimPrefix(id, "-100")
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil && n > 0 {
+		return channelRef{ID: n}, nil
+	}
+
+	// Username should not contain slashes (no deep links)
+	if strings.Contains(url, "/") {
+		return channelRef{}, fmt.Errorf("invalid channel URL format: %s", url)
+	}
+
+	return channelRef{Username: url}, nil
+}
+
+// resolveChannel looks up the channel with its access hash. Private
+// channels must be joined by the account, their access hash is taken from
+// the invite link or from the account's dialogs.
+func resolveChannel(ctx context.Context, api *tg.Client, ref channelRef) (*tg.Channel, error) {
+	var chats []tg.ChatClass
+	switch {
+	case ref.Username != "":
+		resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
+			Username: ref.Username,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve channel %s: %w", ref, err)
+		}
+		chats = resolved.Chats
+
+	case ref.InviteHash != "":
+		invite, err := api.MessagesCheckChatInvite(ctx, ref.InviteHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", ref, err)
+		}
+		switch inv := invite.(type) {
+		case *tg.ChatInviteAlready:
+			chats = []tg.ChatClass{inv.Chat}
+		case *tg.ChatInvitePeek:
+			chats = []tg.ChatClass{inv.Chat}
+		default:
+			return nil, fmt.Errorf("not a member of the channel behind %s, join it with this account first", ref)
+		}
+
+	default:
+		var input *tg.InputPeerChannel
+		err := query.GetDialogs(api).BatchSize(100).ForEach(ctx, func(ctx context.Context, elem dialogs.Elem) error {
+			if peer, ok := elem.Peer.(*tg.InputPeerChannel); ok && peer.ChannelID == ref.ID {
+				input = peer
+				return errFound
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errFound) {
+			return nil, fmt.Errorf("failed to list dialogs: %w", err)
+		}
+		if input == nil {
+			return nil, fmt.Errorf("%s not found in dialogs, join it with this account first", ref)
+		}
+
+		resolved, err := api.ChannelsGetChannels(ctx, []tg.InputChannelClass{
+			&tg.InputChannel{ChannelID: input.ChannelID, AccessHash: input.AccessHash},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %w", ref, err)
+		}
+		chats = resolved.GetChats()
+	}
+
+	// Extract channel from resolved peer
+	for _, chat := range chats {
+		if ch, ok := chat.(*tg.Channel); ok {
+			return ch, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found in resolved peers", ref)
+}
+
+// messageLink returns the public link of a message, private channels use
+// t.me/c/<id>. Messages of a forum topic link to it in the topic.
+func messageLink(channel *tg.Channel, topic, messageID int) string {
+	path := strconv.Itoa(messageID)
+	if topic != 0 {
+		path = fmt.Sprintf("%d/%d", topic, messageID)
+	}
+	if channel.Username != "" {
+		return fmt.Sprintf("https://t.me/%s/%s", channel.Username, path)
+	}
+	return fmt.Sprintf("https://t.me/c/%d/%s", channel.ID, path)
+}
+
+// topicTitle returns the title of a forum topic
+func topicTitle(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, topic int) (string, error) {
+	resp, err := api.MessagesGetForumTopicsByID(ctx, &tg.MessagesGetForumTopicsByIDRequest{
+		Peer:   peer,
+		Topics: []int{topic},
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, t := range resp.Topics {
+		if t, ok := t.(*tg.ForumTopic); ok && t.ID == topic {
+			return t.Title, nil
+		}
+	}
+	return "", fmt.Errorf("topic %d not found", topic)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -19,7 +20,14 @@ import (
 type ClientRunner func(ctx context.Context, client *telegram.Client) error
 
 // RunWithAuth creates a Telegram client, authenticates it, and runs the provided function
-func RunWithAuth(ctx context.Context, configDir string, appID int, appHash string, phoneNumber string, runner ClientRunner) error {
+// When the session has to be logged in, login tells where the code comes from.
+func RunWithAuth(ctx context.Context, configDir string, appID int, appHash string, phoneNumber string, login LoginOptions, runner ClientRunner) error {
+	return runClient(ctx, configDir, appID, appHash, phoneNumber, login, nil, runner)
+}
+
+// runClient is RunWithAuth passing updates received by the client to
+// handler, nil ignores them
+func runClient(ctx context.Context, configDir string, appID int, appHash string, phoneNumber string, login LoginOptions, handler telegram.UpdateHandler, runner ClientRunner) error {
 
 	// Set up session storage
 	sessionPath := filepath.Join(configDir, "telegram-session.json")
@@ -43,11 +51,12 @@ func RunWithAuth(ctx context.Context, configDir string, appID int, appHash strin
 	client := telegram.NewClient(appID, appHash, telegram.Options{
 		SessionStorage: sessionStorage,
 		Logger:         logger,
+		UpdateHandler:  handler,
 	})
 
 	// Create auth flow
 	flow := tdauth.NewFlow(
-		TerminalUserAuthenticator{PhoneNumber: phoneNumber},
+		loginAuthenticator{TerminalUserAuthenticator{PhoneNumber: phoneNumber}, login},
 		tdauth.SendCodeOptions{},
 	)
 
@@ -60,6 +69,15 @@ func RunWithAuth(ctx context.Context, configDir string, appID int, appHash strin
 		slog.Info("waiter.Run callback started")
 		err := client.Run(ctx, func(ctx context.Context) error {
 			slog.Info("client.Run callback started, calling Auth().IfNecessary")
+			// Fail early instead of sending a code nobody can enter
+			status, err := client.Auth().Status(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to check authorization status: %w", err)
+			}
+			if !status.Authorized && !login.canLogin() {
+				return ErrLoginRequired
+			}
+
 			// Authenticate if necessary
 			if err := client.Auth().IfNecessary(ctx, flow); err != nil {
 				return fmt.Errorf("authentication failed: %w", err)
@@ -78,8 +96,9 @@ func RunWithAuth(ctx context.Context, configDir string, appID int, appHash strin
 			}
 			slog.Info("telegram authenticated", "as", name)
 
-			// Run the user's function
-			return runner(ctx, client)
+			// Run the user's function, the callback runs in a separate
+			// goroutine so panics have to be recovered here
+			return runSafely(ctx, client, runner)
 		})
 		if err != nil {
 			slog.Error("client.Run failed", "error", err)
@@ -87,3 +106,76 @@ func RunWithAuth(ctx context.Context, configDir string, appID int, appHash strin
 		return err
 	})
 }
+
+// runSafely runs the runner and converts a panic into a returned error
+func runSafely(ctx context.Context, client *telegram.Client, runner ClientRunner) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("telegram runner panicked: %v", r)
+		}
+	}()
+	return runner(ctx, client)
+}
+
+// connection keeps one authenticated client connected between fetches, so a
+// run authenticates once and fetches every channel over the same connection
+type connection struct {
+	configDir   string
+	appID       int
+	appHash     string
+	phoneNumber string
+	login       LoginOptions
+
+	once   sync.Once
+	cancel context.CancelFunc
+	client *telegram.Client
+	ready  chan struct{} // Closed once the client is authenticated
+	done   chan struct{} // Closed once the client is disconnected
+	err    error         // Why the client disconnected, set before done is closed
+}
+
+// connect returns the authenticated client, connecting on the first call.
+// The connection outlives ctx and is kept until close.
+func (c *connection) connect(ctx context.Context) (*telegram.Client, error) {
+	c.once.Do(func() {
+		c.ready = make(chan struct{})
+		c.done = make(chan struct{})
+		runCtx, cancel := context.WithCancel(context.Background())
+		c.cancel = cancel
+		go func() {
+			defer close(c.done)
+			c.err = RunWithAuth(runCtx, c.configDir, c.appID, c.appHash, c.phoneNumber, c.login, func(ctx context.Context, client *telegram.Client) error {
+				c.client = client
+				close(c.ready)
+				<-ctx.Done()
+				return nil
+			})
+			if c.err == nil {
+				c.err = fmt.Errorf("telegram client disconnected")
+			}
+		}()
+	})
+
+	select {
+	case <-c.done:
+		return nil, c.err
+	default:
+	}
+	select {
+	case <-c.ready:
+		return c.client, nil
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// close disconnects the client, if it was ever connected
+func (c *connection) close() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+}
@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// maxRepliesScanned bounds the replies ranked per post, the most recent ones are scanned
+const maxRepliesScanned = 100
+
+// fetchComments returns up to limit replies to the channel post from its
+// linked discussion group, the most reacted ones first, then ordered by date.
+// Posts without a discussion thread have no comments.
+func fetchComments(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, msg *tg.Message, limit int) ([]types.Comment, error) {
+	replies, ok := msg.GetReplies()
+	if !ok || !replies.Comments || replies.Replies == 0 {
+		return nil, nil
+	}
+
+	data, err := api.MessagesGetReplies(ctx, &tg.MessagesGetRepliesRequest{
+		Peer:  peer,
+		MsgID: msg.ID,
+		Limit: maxRepliesScanned,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch replies to message %d: %w", msg.ID, err)
+	}
+	page, ok := data.AsModified()
+	if !ok {
+		return nil, nil
+	}
+	hist := history{
+		chats: make(map[int64]*tg.Channel),
+		users: make(map[int64]*tg.User),
+	}
+	hist.add(page)
+
+	var comments []types.Comment
+	for _, m := range hist.messages {
+		reply, ok := m.(*tg.Message)
+		if !ok || reply.Message == "" {
+			continue
+		}
+		comments = append(comments, types.Comment{
+			Author:    authorOf(reply, hist),
+			Text:      reply.Message,
+			Published: time.Unix(int64(reply.Date), 0),
+			Reactions: reactionCount(reply),
+		})
+	}
+	return topComments(comments, limit), nil
+}
+
+// topComments keeps the limit most reacted comments in chronological order
+func topComments(comments []types.Comment, limit int) []types.Comment {
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].Reactions > comments[j].Reactions
+	})
+	if len(comments) > limit {
+		comments = comments[:limit]
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].Published.Before(comments[j].Published)
+	})
+	return comments
+}
+
+// authorOf names the sender of a message in a discussion group
+func authorOf(msg *tg.Message, hist history) string {
+	from, _ := msg.GetFromID()
+	switch peer := from.(type) {
+	case *tg.PeerUser:
+		if user, ok := hist.users[peer.UserID]; ok {
+			return userName(user)
+		}
+	case *tg.PeerChannel:
+		if channel, ok := hist.chats[peer.ChannelID]; ok {
+			return channel.Title
+		}
+	}
+	if author, ok := msg.GetPostAuthor(); ok {
+		return author
+	}
+	return "Anonymous"
+}
@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"github.com/gotd/td/tg"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// textEntities converts formatting entities of a message, entities without
+// a rendering (hashtags, custom emoji, ...) are dropped. The result is never
+// nil, so parsers know the text needs no pseudo-markdown conversion.
+func textEntities(entities []tg.MessageEntityClass) []types.TextEntity {
+	result := make([]types.TextEntity, 0, len(entities))
+	for _, entity := range entities {
+		e := types.TextEntity{Offset: entity.GetOffset(), Length: entity.GetLength()}
+		switch v := entity.(type) {
+		case *tg.MessageEntityBold:
+			e.Type = "bold"
+		case *tg.MessageEntityItalic:
+			e.Type = "italic"
+		case *tg.MessageEntityUnderline:
+			e.Type = "underline"
+		case *tg.MessageEntityStrike:
+			e.Type = "strike"
+		case *tg.MessageEntityCode:
+			e.Type = "code"
+		case *tg.MessageEntityPre:
+			e.Type = "pre"
+			e.Language = v.Language
+		case *tg.MessageEntityTextURL:
+			e.Type = "text_link"
+			e.URL = v.URL
+		case *tg.MessageEntityURL:
+			e.Type = "url"
+		case *tg.MessageEntityMention:
+			e.Type = "mention"
+		case *tg.MessageEntityEmail:
+			e.Type = "email"
+		case *tg.MessageEntitySpoiler:
+			e.Type = "spoiler"
+		case *tg.MessageEntityBlockquote:
+			e.Type = "blockquote"
+		default:
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
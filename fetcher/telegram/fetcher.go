@@ -13,219 +13,396 @@ import (
 	"github.com/gotd/td/tg"
 
 	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/ratelimit"
 )
 
 const (
-	defaultMessageLimit = 50
+	defaultMessageLimit = 50 // Messages fetched on the first run of a channel
+	historyPageSize     = 100
+	maxHistoryMessages  = 2000 // Bounds catching up on channels not fetched for long
 )
 
-// TelegramFetcher fetches feeds from Telegram channels
+// TelegramFetcher fetches feeds from Telegram channels over a single
+// connection, which is kept open until Close
 type TelegramFetcher struct {
-	configDir   string
-	appID       int
-	appHash     string
-	phoneNumber string
+	conn    *connection
+	offsets types.OffsetStore
 }
 
-// NewTelegramFetcher creates a new Telegram fetcher with provided credentials
-func NewTelegramFetcher(configDir string, appID int, appHash string, phoneNumber string) *TelegramFetcher {
+// NewTelegramFetcher creates a new Telegram fetcher with provided credentials.
+// offsets enables fetching only messages newer than the last run, nil
+// fetches the latest messages every time.
+func NewTelegramFetcher(configDir string, appID int, appHash string, phoneNumber string, login LoginOptions, offsets types.OffsetStore) *TelegramFetcher {
 	return &TelegramFetcher{
-		configDir:   configDir,
-		appID:       appID,
-		appHash:     appHash,
-		phoneNumber: phoneNumber,
+		conn: &connection{
+			configDir:   configDir,
+			appID:       appID,
+			appHash:     appHash,
+			phoneNumber: phoneNumber,
+			login:       login,
+		},
+		offsets: offsets,
 	}
 }
 
+// Close disconnects the Telegram client shared by fetches
+func (f *TelegramFetcher) Close() error {
+	f.conn.close()
+	return nil
+}
+
 // Fetch retrieves a feed from a Telegram channel
-func (f *TelegramFetcher) Fetch(ctx context.Context, url string) (types.Feed, error) {
+// HTTP options are not applicable to MTProto and are ignored.
+// opts.Comments adds the top replies of the linked discussion group.
+func (f *TelegramFetcher) Fetch(ctx context.Context, url string, opts types.FetchOptions) (types.Feed, error) {
 	var feed types.Feed
 
-	// Parse URL to extract channel username
-	username, err := parseChannelURL(url)
+	// Parse URL to extract channel reference
+	ref, err := parseChannelRef(url)
 	if err != nil {
 		return feed, fmt.Errorf("invalid channel URL: %w", err)
 	}
 
+	var minID int
+	if f.offsets != nil {
+		offset, err := f.offsets.GetOffset(ctx, url)
+		if err != nil {
+			slog.Warn("failed to load channel offset, fetching latest messages", "error", err, "channel", ref.String())
+		}
+		minID = int(offset)
+	}
+
 	// Create temporary directory for media downloads
 	tmpDir := filepath.Join(os.TempDir(), "myfeed-telegram-media")
 	if err := os.MkdirAll(tmpDir, 0755); err != nil {
 		return feed, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
-	// Run with authenticated client
-	err = RunWithAuth(ctx, f.configDir, f.appID, f.appHash, f.phoneNumber, func(ctx context.Context, client *telegram.Client) error {
-		api := client.API()
+	client, err := f.conn.connect(ctx)
+	if err != nil {
+		return feed, fmt.Errorf("failed to connect to telegram: %w", err)
+	}
 
-		// Resolve channel username
-		resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
-			Username: username,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to resolve channel @%s: %w", username, err)
-		}
+	release, err := ratelimit.Acquire(ctx, ratelimit.Telegram)
+	if err != nil {
+		return feed, fmt.Errorf("rate limit wait cancelled: %w", err)
+	}
+	defer release()
 
-		// Extract channel from resolved peer
-		var channel *tg.Channel
-		for _, chat := range resolved.Chats {
-			if ch, ok := chat.(*tg.Channel); ok {
-				channel = ch
-				break
-			}
-		}
+	return fetchChannel(ctx, client, ref, minID, opts, tmpDir)
+}
 
-		if channel == nil {
-			return fmt.Errorf("channel @%s not found in resolved peers", username)
-		}
+// fetchChannel fetches messages newer than minID from the channel
+func fetchChannel(ctx context.Context, client *telegram.Client, ref channelRef, minID int, opts types.FetchOptions, tmpDir string) (types.Feed, error) {
+	var feed types.Feed
+	api := client.API()
 
-		// Check if it's actually a channel
-		if !channel.Broadcast {
-			panic(fmt.Sprintf("@%s is not a channel (it's a group or supergroup)", username))
-		}
+	channel, err := resolveChannel(ctx, api, ref)
+	if err != nil {
+		return feed, err
+	}
 
-		// Set feed metadata
-		feed.Title = channel.Title
-		feed.Description = fmt.Sprintf("Telegram channel @%s", username)
+	// Groups are only supported through their forum topics
+	if ref.Topic != 0 && !channel.Forum {
+		return feed, fmt.Errorf("%s is not a forum, only supergroups with topics have them", ref)
+	}
+	if !channel.Broadcast && ref.Topic == 0 {
+		return feed, fmt.Errorf("%s is a group, only broadcast channels and forum topics (?topic=<id>) are supported", ref)
+	}
 
-		// Try to get full channel info for description
-		fullChan, err := api.ChannelsGetFullChannel(ctx, &tg.InputChannel{
-			ChannelID:  channel.ID,
-			AccessHash: channel.AccessHash,
-		})
-		if err == nil {
-			if chatFull, ok := fullChan.FullChat.(*tg.ChannelFull); ok {
-				if chatFull.About != "" {
-					feed.Description = chatFull.About
-				}
+	// Set feed metadata
+	feed.Title = channel.Title
+	feed.Description = fmt.Sprintf("Telegram channel %s", ref)
+	if channel.Username != "" {
+		feed.Description = fmt.Sprintf("Telegram channel @%s", channel.Username)
+	}
+
+	// Try to get full channel info for description
+	fullChan, err := api.ChannelsGetFullChannel(ctx, &tg.InputChannel{
+		ChannelID:  channel.ID,
+		AccessHash: channel.AccessHash,
+	})
+	if err == nil {
+		if chatFull, ok := fullChan.FullChat.(*tg.ChannelFull); ok {
+			if chatFull.About != "" {
+				feed.Description = chatFull.About
 			}
 		}
+	}
 
-		// Fetch channel messages
-		inputPeer := &tg.InputPeerChannel{
-			ChannelID:  channel.ID,
-			AccessHash: channel.AccessHash,
-		}
+	// Fetch channel messages
+	inputPeer := &tg.InputPeerChannel{
+		ChannelID:  channel.ID,
+		AccessHash: channel.AccessHash,
+	}
 
-		messagesData, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
-			Peer:  inputPeer,
-			Limit: defaultMessageLimit,
-		})
+	if ref.Topic != 0 {
+		title, err := topicTitle(ctx, api, inputPeer, ref.Topic)
 		if err != nil {
-			return fmt.Errorf("failed to fetch messages from @%s: %w", username, err)
-		}
-
-		// Extract messages
-		var messages []tg.MessageClass
-		switch m := messagesData.(type) {
-		case *tg.MessagesMessages:
-			messages = m.Messages
-		case *tg.MessagesMessagesSlice:
-			messages = m.Messages
-		case *tg.MessagesChannelMessages:
-			messages = m.Messages
-		case *tg.MessagesMessagesNotModified:
-			slog.Warn("messages not modified", "channel", username)
-			return nil
-		default:
-			return fmt.Errorf("unexpected messages type: %T", messagesData)
-		}
-
-		// Convert messages to feed items
-		feed.Items = make([]types.FeedItem, 0, len(messages))
-		for _, msgClass := range messages {
-			msg, ok := msgClass.(*tg.Message)
-			if !ok {
-				continue // Skip service messages
-			}
+			return feed, fmt.Errorf("failed to get %s: %w", ref, err)
+		}
+		feed.Title = fmt.Sprintf("%s: %s", channel.Title, title)
+	}
 
-			// Create GUID for this message
-			messageGUID := fmt.Sprintf("%d", msg.ID)
+	hist, err := fetchHistory(ctx, api, inputPeer, ref.Topic, minID)
+	if err != nil {
+		return feed, fmt.Errorf("failed to fetch messages from %s: %w", ref, err)
+	}
+	feed.Offset = int64(minID)
+	for _, msg := range hist.messages {
+		feed.Offset = max(feed.Offset, int64(msg.GetID()))
+	}
+	if minID > 0 && feed.Offset == int64(minID) {
+		return feed, types.ErrNotModified
+	}
 
-			// Extract media attachments (photos)
-			media, err := extractMediaFromMessage(ctx, client, msg, messageGUID, tmpDir)
-			if err != nil {
-				slog.Warn("failed to extract media from message",
-					"error", err,
-					"message_id", msg.ID,
-					"channel", username)
-				// Continue processing the message even if media extraction fails
-			}
+	// Convert messages to feed items
+	feed.Items = make([]types.FeedItem, 0, len(hist.messages))
+	albums := make(map[int64]int)
+	for _, msgClass := range hist.messages {
+		msg, ok := msgClass.(*tg.Message)
+		if !ok {
+			continue // Skip service messages
+		}
 
-			// Determine title: use message text if available, otherwise indicate it's a photo
-			title := msg.Message
-			if title == "" && len(media) > 0 {
-				if len(media) == 1 {
-					title = "Photo"
-				} else {
-					title = fmt.Sprintf("Album (%d photos)", len(media))
-				}
-			} else if title != "" {
-				title = truncateText(title, 100) // Use first 100 chars as title
+		item, ok := messageItem(ctx, client, channel, ref.Topic, msg, hist, tmpDir)
+		if !ok {
+			continue
+		}
+		if opts.Comments > 0 {
+			var err error
+			item.Comments, err = fetchComments(ctx, api, inputPeer, msg, opts.Comments)
+			if err != nil {
+				slog.Warn("failed to fetch comments", "error", err, "message_id", msg.ID, "channel", ref.String())
 			}
+		}
 
-			// Skip completely empty messages (no text and no media)
-			if title == "" && len(media) == 0 {
+		// Messages of an album share a grouped ID and are merged into one item
+		if groupedID, ok := msg.GetGroupedID(); ok {
+			if idx, seen := albums[groupedID]; seen {
+				feed.Items[idx] = mergeAlbumItem(feed.Items[idx], item)
 				continue
 			}
+			albums[groupedID] = len(feed.Items)
+		}
 
-			item := types.FeedItem{
-				Title:       title,
-				Link:        fmt.Sprintf("https://t.me/%s/%d", username, msg.ID),
-				Description: msg.Message,
-				Published:   time.Unix(int64(msg.Date), 0),
-				GUID:        messageGUID,
-				Media:       media,
-			}
+		feed.Items = append(feed.Items, item)
+	}
 
-			feed.Items = append(feed.Items, item)
-		}
+	for i := range feed.Items {
+		feed.Items[i].Title = itemTitle(feed.Items[i].Description, feed.Items[i].Media)
+	}
 
-		// Reverse the items to get oldest first (Telegram API returns newest first)
-		for i, j := 0, len(feed.Items)-1; i < j; i, j = i+1, j-1 {
-			feed.Items[i], feed.Items[j] = feed.Items[j], feed.Items[i]
-		}
+	// Reverse the items to get oldest first (Telegram API returns newest first)
+	for i, j := 0, len(feed.Items)-1; i < j; i, j = i+1, j-1 {
+		feed.Items[i], feed.Items[j] = feed.Items[j], feed.Items[i]
+	}
 
-		slog.Info("fetched Telegram channel", "channel", username, "messages", len(feed.Items))
-		return nil
-	})
+	slog.Info("fetched Telegram channel", "channel", ref.String(), "messages", len(feed.Items))
+	return feed, nil
+}
 
-	return feed, err
+// messageItem converts a channel post into a feed item, downloading its
+// media. Empty messages are reported as not ok.
+func messageItem(ctx context.Context, client *telegram.Client, channel *tg.Channel, topic int, msg *tg.Message, hist history, tmpDir string) (types.FeedItem, bool) {
+	// Create GUID for this message
+	messageGUID := fmt.Sprintf("%d", msg.ID)
+
+	// Extract media attachments (photos)
+	media, err := extractMediaFromMessage(ctx, client, msg, messageGUID, tmpDir)
+	if err != nil {
+		slog.Warn("failed to extract media from message",
+			"error", err,
+			"message_id", msg.ID,
+			"channel", channel.Title)
+		// Continue processing the message even if media extraction fails
+	}
+
+	// Skip completely empty messages (no text and no media)
+	if msg.Message == "" && len(media) == 0 {
+		return types.FeedItem{}, false
+	}
+
+	link := messageLink(channel, topic, msg.ID)
+	for i := range media {
+		media[i].Link = link
+	}
+
+	item := types.FeedItem{
+		Link:        link,
+		Description: msg.Message,
+		Published:   time.Unix(int64(msg.Date), 0),
+		GUID:        messageGUID,
+		Media:       media,
+		Entities:    textEntities(msg.Entities),
+		Engagement:  engagement(msg),
+	}
+	if fwd, ok := msg.GetFwdFrom(); ok {
+		item.Forward = forwardOf(fwd, hist)
+	}
+	return item, true
 }
 
-// parseChannelURL extracts the channel username from various URL formats
-// Supports:
-//   - https://t.me/channelname
-//   - http://t.me/channelname
-//   - t.me/channelname
-//   - @channelname
-//   - channelname
-func parseChannelURL(url string) (string, error) {
-	url = strings.TrimSpace(url)
+// history holds fetched messages with the chats and users they reference,
+// e.g., the sources of forwarded messages
+type history struct {
+	messages []tg.MessageClass
+	chats    map[int64]*tg.Channel
+	users    map[int64]*tg.User
+}
 
-	// Remove protocol if present
-	url = strings.TrimPrefix(url, "https://")
-	url = strings.TrimPrefix(url, "http://")
+func (h *history) add(page tg.ModifiedMessagesMessages) {
+	h.messages = append(h.messages, page.GetMessages()...)
+	for _, chat := range page.GetChats() {
+		if channel, ok := chat.(*tg.Channel); ok {
+			h.chats[channel.ID] = channel
+		}
+	}
+	for _, u := range page.GetUsers() {
+		if user, ok := u.(*tg.User); ok {
+			h.users[user.ID] = user
+		}
+	}
+}
 
-	// Remove t.me/ if present
-	url = strings.TrimPrefix(url, "t.me/")
+// fetchHistory returns messages newer than minID, newest first, paging
+// through the history so no message is missed. Without minID only the
+// latest messages are returned. A non-zero topic reads the forum topic
+// thread instead. Of more than maxHistoryMessages new messages the oldest
+// are returned, so the offset of the channel stays before the newer ones
+// and the next run continues with them.
+func fetchHistory(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, topic, minID int) (history, error) {
+	h := history{
+		chats: make(map[int64]*tg.Channel),
+		users: make(map[int64]*tg.User),
+	}
+	if minID <= 0 {
+		page, err := historyPage(ctx, api, peer, topic, 0, 0, defaultMessageLimit)
+		if err != nil {
+			return h, err
+		}
+		h.add(page)
+		return h, nil
+	}
 
-	// Remove @ if present
-	url = strings.TrimPrefix(url, "@")
+	offsetID := 0 // Start from the newest message
+	skipped := 0
+	for {
+		page, err := historyPage(ctx, api, peer, topic, offsetID, minID, historyPageSize)
+		if err != nil {
+			return h, err
+		}
+		h.add(page)
+		if extra := len(h.messages) - maxHistoryMessages; extra > 0 {
+			h.messages = h.messages[extra:]
+			skipped += extra
+		}
+		messages := page.GetMessages()
+		if len(messages) < historyPageSize {
+			break
+		}
+		offsetID = messages[len(messages)-1].GetID()
+		if offsetID <= minID+1 {
+			break
+		}
+	}
+	if skipped > 0 {
+		slog.Warn("too many new messages in channel, newer ones are left for the next run", "limit", maxHistoryMessages, "left", skipped)
+	}
+	return h, nil
+}
+
+// historyPage requests a page of the channel history, or of the forum topic
+// thread when topic is set
+func historyPage(ctx context.Context, api *tg.Client, peer tg.InputPeerClass, topic, offsetID, minID, limit int) (tg.ModifiedMessagesMessages, error) {
+	var data tg.MessagesMessagesClass
+	var err error
+	if topic != 0 {
+		data, err = api.MessagesGetReplies(ctx, &tg.MessagesGetRepliesRequest{
+			Peer:     peer,
+			MsgID:    topic,
+			OffsetID: offsetID,
+			MinID:    minID,
+			Limit:    limit,
+		})
+	} else {
+		data, err = api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:     peer,
+			OffsetID: offsetID,
+			MinID:    minID,
+			Limit:    limit,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	page, ok := data.AsModified()
+	if !ok {
+		return &tg.MessagesMessages{}, nil // Not modified
+	}
+	return page, nil
+}
 
-	// Remove trailing slash
-	url = strings.TrimSuffix(url, "/")
+// mergeAlbumItem folds an older message of the same album into the album item.
+// Telegram returns messages newest first, so the older message defines the
+// album's GUID, link and date, and its media go first. Engagement is the
+// highest of the album's messages.
+func mergeAlbumItem(album, older types.FeedItem) types.FeedItem {
+	album.Media = append(older.Media, album.Media...)
+	album.Comments = append(older.Comments, album.Comments...)
+	if older.Description != "" {
+		album.Description = older.Description
+		album.Entities = older.Entities
+	}
+	if album.Engagement != nil && older.Engagement != nil {
+		album.Engagement = &types.Engagement{
+			Views:     max(album.Engagement.Views, older.Engagement.Views),
+			Forwards:  max(album.Engagement.Forwards, older.Engagement.Forwards),
+			Reactions: max(album.Engagement.Reactions, older.Engagement.Reactions),
+		}
+	}
+	album.GUID = older.GUID
+	album.Link = older.Link
+	album.Published = older.Published
+	return album
+}
 
-	// Validate username (basic validation)
-	if url == "" {
-		return "", fmt.Errorf("empty channel username")
+// engagement returns the views, forwards and reactions of a channel post
+func engagement(msg *tg.Message) *types.Engagement {
+	views, ok := msg.GetViews()
+	if !ok {
+		return nil
 	}
+	e := &types.Engagement{Views: views, Reactions: reactionCount(msg)}
+	e.Forwards, _ = msg.GetForwards()
+	return e
+}
 
-	// Username should not contain slashes (no deep links)
-	if strings.Contains(url, "/") {
-		return "", fmt.Errorf("invalid channel URL format: %s", url)
+// reactionCount returns the total of all reactions to the message
+func reactionCount(msg *tg.Message) int {
+	total := 0
+	if reactions, ok := msg.GetReactions(); ok {
+		for _, r := range reactions.Results {
+			total += r.Count
+		}
 	}
+	return total
+}
 
-	return url, nil
+// itemTitle uses the message text if available, otherwise indicates the kind of media
+func itemTitle(text string, media []types.MediaAttachment) string {
+	switch {
+	case text != "":
+		return truncateText(text, 100) // Use first 100 chars as title
+	case len(media) == 1 && media[0].Type == "video":
+		return "Video"
+	case len(media) == 1:
+		return "Photo"
+	case len(media) > 1:
+		return fmt.Sprintf("Album (%d photos)", len(media))
+	default:
+		return ""
+	}
 }
 
 // truncateText truncates text to maxLen characters, adding "..." if truncated
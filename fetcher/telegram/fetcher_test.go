@@ -0,0 +1,330 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/tg"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+func TestMergeAlbumItem(t *testing.T) {
+	newer := types.FeedItem{
+		Link:      "https://t.me/channel/12",
+		GUID:      "12",
+		Published: time.Unix(200, 0),
+		Media:     []types.MediaAttachment{{LocalPath: "b.jpg"}},
+	}
+	older := types.FeedItem{
+		Link:        "https://t.me/channel/11",
+		GUID:        "11",
+		Description: "Album caption",
+		Published:   time.Unix(100, 0),
+		Media:       []types.MediaAttachment{{LocalPath: "a.jpg"}},
+	}
+
+	album := mergeAlbumItem(newer, older)
+	if album.GUID != "11" || album.Link != "https://t.me/channel/11" {
+		t.Errorf("expected the earliest message to identify the album, got %q %q", album.GUID, album.Link)
+	}
+	if !album.Published.Equal(time.Unix(100, 0)) {
+		t.Errorf("expected the earliest date, got %v", album.Published)
+	}
+	if album.Description != "Album caption" {
+		t.Errorf("expected the caption to be kept, got %q", album.Description)
+	}
+	if len(album.Media) != 2 || album.Media[0].LocalPath != "a.jpg" {
+		t.Errorf("expected media in chronological order, got %+v", album.Media)
+	}
+}
+
+func TestItemTitle(t *testing.T) {
+	photo := types.MediaAttachment{Type: "photo"}
+	video := types.MediaAttachment{Type: "video"}
+	tests := []struct {
+		text  string
+		media []types.MediaAttachment
+		want  string
+	}{
+		{"Hello", []types.MediaAttachment{photo, photo, photo}, "Hello"},
+		{"", []types.MediaAttachment{photo}, "Photo"},
+		{"", []types.MediaAttachment{video}, "Video"},
+		{"", []types.MediaAttachment{photo, photo, photo}, "Album (3 photos)"},
+		{"", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := itemTitle(tt.text, tt.media); got != tt.want {
+			t.Errorf("itemTitle(%q, %d media) = %q, want %q", tt.text, len(tt.media), got, tt.want)
+		}
+	}
+}
+
+func TestParseChannelRef(t *testing.T) {
+	tests := []struct {
+		url  string
+		want channelRef
+	}{
+		{"https://t.me/durov", channelRef{Username: "durov"}},
+		{"@durov", channelRef{Username: "durov"}},
+		{"https://t.me/+AbCdEf123", channelRef{InviteHash: "AbCdEf123"}},
+		{"t.me/joinchat/AbCdEf123", channelRef{InviteHash: "AbCdEf123"}},
+		{"https://t.me/c/1234567890", channelRef{ID: 1234567890}},
+		{"-1001234567890", channelRef{ID: 1234567890}},
+		{"1234567890", channelRef{ID: 1234567890}},
+		{"https://t.me/c/1234567890?topic=123", channelRef{ID: 1234567890, Topic: 123}},
+		{"t.me/golang_ru?topic=7", channelRef{Username: "golang_ru", Topic: 7}},
+	}
+	for _, tt := range tests {
+		got, err := parseChannelRef(tt.url)
+		if err != nil {
+			t.Errorf("parseChannelRef(%q) failed: %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseChannelRef(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+
+	for _, url := range []string{"", "https://t.me/durov/123", "t.me/c/123?topic=abc", "t.me/c/123?topic=0"} {
+		if _, err := parseChannelRef(url); err == nil {
+			t.Errorf("parseChannelRef(%q) expected error", url)
+		}
+	}
+}
+
+// historyInvoker serves MessagesGetHistory from a channel with messages
+// 1..last and MessagesGetReplies from a forum topic with the same messages
+type historyInvoker struct {
+	last  int
+	calls int
+	topic int // Topic of the last MessagesGetReplies request
+}
+
+func (h *historyInvoker) Invoke(_ context.Context, input bin.Encoder, output bin.Decoder) error {
+	var req tg.MessagesGetHistoryRequest
+	switch r := input.(type) {
+	case *tg.MessagesGetHistoryRequest:
+		req = *r
+	case *tg.MessagesGetRepliesRequest:
+		h.topic = r.MsgID
+		req = tg.MessagesGetHistoryRequest{OffsetID: r.OffsetID, MinID: r.MinID, Limit: r.Limit}
+	default:
+		return fmt.Errorf("unexpected request %T", input)
+	}
+	h.calls++
+	top := h.last
+	if req.OffsetID > 0 {
+		top = req.OffsetID - 1
+	}
+	resp := &tg.MessagesChannelMessages{}
+	for id := top; id > req.MinID && len(resp.Messages) < req.Limit; id-- {
+		resp.Messages = append(resp.Messages, &tg.Message{ID: id, PeerID: &tg.PeerChannel{ChannelID: 1}})
+	}
+	var b bin.Buffer
+	if err := resp.Encode(&b); err != nil {
+		return err
+	}
+	return output.Decode(&b)
+}
+
+func TestFetchHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		minID int
+		want  int
+	}{
+		{"first run", 0, defaultMessageLimit},
+		{"several pages", 30, 220},
+		{"exact page", 150, historyPageSize},
+		{"nothing new", 250, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoker := &historyInvoker{last: 250}
+			hist, err := fetchHistory(context.Background(), tg.NewClient(invoker), &tg.InputPeerEmpty{}, 0, tt.minID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			messages := hist.messages
+			if len(messages) != tt.want {
+				t.Fatalf("expected %d messages, got %d", tt.want, len(messages))
+			}
+			// Newest first without gaps
+			for i, msg := range messages {
+				if msg.GetID() != 250-i {
+					t.Fatalf("expected message %d at %d, got %d", 250-i, i, msg.GetID())
+				}
+			}
+		})
+	}
+}
+
+func TestForwardOf(t *testing.T) {
+	hist := history{
+		chats: map[int64]*tg.Channel{
+			10: {ID: 10, Title: "Public", Username: "public"},
+			20: {ID: 20, Title: "Private"},
+		},
+		users: map[int64]*tg.User{
+			30: {ID: 30, FirstName: "Jane", LastName: "Doe", Username: "jane"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		fwd  tg.MessageFwdHeader
+		want types.Forward
+	}{
+		{
+			name: "public channel post",
+			fwd:  tg.MessageFwdHeader{FromID: &tg.PeerChannel{ChannelID: 10}, ChannelPost: 7},
+			want: types.Forward{From: "Public", Link: "https://t.me/public/7"},
+		},
+		{
+			name: "private channel with author",
+			fwd:  tg.MessageFwdHeader{FromID: &tg.PeerChannel{ChannelID: 20}, ChannelPost: 7, PostAuthor: "Editor"},
+			want: types.Forward{From: "Private (Editor)", Link: "https://t.me/c/20/7"},
+		},
+		{
+			name: "user",
+			fwd:  tg.MessageFwdHeader{FromID: &tg.PeerUser{UserID: 30}},
+			want: types.Forward{From: "Jane Doe", Link: "https://t.me/jane"},
+		},
+		{
+			name: "hidden user",
+			fwd:  tg.MessageFwdHeader{FromName: "Anonymous"},
+			want: types.Forward{From: "Anonymous"},
+		},
+		{
+			name: "inaccessible channel",
+			fwd:  tg.MessageFwdHeader{FromID: &tg.PeerChannel{ChannelID: 99}},
+			want: types.Forward{From: "unknown source"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fwd.SetFlags()
+			if got := forwardOf(tt.fwd, hist); *got != tt.want {
+				t.Errorf("forwardOf() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextEntities(t *testing.T) {
+	got := textEntities([]tg.MessageEntityClass{
+		&tg.MessageEntityBold{Offset: 0, Length: 4},
+		&tg.MessageEntityHashtag{Offset: 5, Length: 3},
+		&tg.MessageEntityTextURL{Offset: 9, Length: 2, URL: "https://example.com"},
+		&tg.MessageEntityPre{Offset: 12, Length: 5, Language: "go"},
+	})
+	want := []types.TextEntity{
+		{Type: "bold", Offset: 0, Length: 4},
+		{Type: "text_link", Offset: 9, Length: 2, URL: "https://example.com"},
+		{Type: "pre", Offset: 12, Length: 5, Language: "go"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entities, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entity %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if entities := textEntities(nil); entities == nil {
+		t.Error("expected non-nil entities for plain messages")
+	}
+}
+
+func TestTopComments(t *testing.T) {
+	comments := []types.Comment{
+		{Text: "a", Published: time.Unix(1, 0), Reactions: 1},
+		{Text: "b", Published: time.Unix(2, 0), Reactions: 9},
+		{Text: "c", Published: time.Unix(3, 0)},
+		{Text: "d", Published: time.Unix(4, 0), Reactions: 5},
+	}
+	got := topComments(comments, 2)
+	if len(got) != 2 || got[0].Text != "b" || got[1].Text != "d" {
+		t.Errorf("expected most reacted comments in date order, got %+v", got)
+	}
+}
+
+func TestLoginAuthenticator(t *testing.T) {
+	ctx := context.Background()
+
+	a := loginAuthenticator{opts: LoginOptions{NonInteractive: true}}
+	if _, err := a.Code(ctx, nil); !errors.Is(err, ErrLoginRequired) {
+		t.Errorf("expected ErrLoginRequired without a code command, got %v", err)
+	}
+	if a.opts.canLogin() {
+		t.Error("expected non-interactive login without a code command to be impossible")
+	}
+	if _, err := a.Password(ctx); err == nil {
+		t.Error("expected error without a password command")
+	}
+
+	a.opts.CodeCommand = "printf ' 12345\\n'"
+	a.opts.PasswordCommand = "exit 3"
+	if code, err := a.Code(ctx, nil); err != nil || code != "12345" {
+		t.Errorf("expected code from command, got %q, %v", code, err)
+	}
+	if _, err := a.Password(ctx); err == nil {
+		t.Error("expected failing password command to return an error")
+	}
+}
+
+func TestFetchHistory_Limit(t *testing.T) {
+	invoker := &historyInvoker{last: maxHistoryMessages + 550}
+	hist, err := fetchHistory(context.Background(), tg.NewClient(invoker), &tg.InputPeerEmpty{}, 0, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The oldest new messages come first, the rest is fetched on the next run
+	messages := hist.messages
+	if len(messages) != maxHistoryMessages {
+		t.Fatalf("expected %d messages, got %d", maxHistoryMessages, len(messages))
+	}
+	if newest, oldest := messages[0].GetID(), messages[len(messages)-1].GetID(); newest != maxHistoryMessages+10 || oldest != 11 {
+		t.Errorf("expected messages 11..%d, got %d..%d", maxHistoryMessages+10, oldest, newest)
+	}
+}
+
+func TestFetchHistory_Topic(t *testing.T) {
+	invoker := &historyInvoker{last: 250}
+	hist, err := fetchHistory(context.Background(), tg.NewClient(invoker), &tg.InputPeerEmpty{}, 42, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if invoker.topic != 42 {
+		t.Errorf("expected replies to topic 42, got %d", invoker.topic)
+	}
+	if len(hist.messages) != 220 {
+		t.Errorf("expected 220 messages, got %d", len(hist.messages))
+	}
+}
+
+func TestMessageLink(t *testing.T) {
+	public := &tg.Channel{ID: 1234, Username: "durov"}
+	private := &tg.Channel{ID: 1234}
+	tests := []struct {
+		channel *tg.Channel
+		topic   int
+		want    string
+	}{
+		{public, 0, "https://t.me/durov/5"},
+		{private, 0, "https://t.me/c/1234/5"},
+		{public, 3, "https://t.me/durov/3/5"},
+		{private, 3, "https://t.me/c/1234/3/5"},
+	}
+	for _, tt := range tests {
+		if got := messageLink(tt.channel, tt.topic, 5); got != tt.want {
+			t.Errorf("messageLink(topic %d) = %q, want %q", tt.topic, got, tt.want)
+		}
+	}
+}
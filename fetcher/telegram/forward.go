@@ -0,0 +1,60 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gotd/td/tg"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// forwardOf attributes a forwarded message to its original channel or
+// author, looked up in the chats and users returned with the history
+func forwardOf(fwd tg.MessageFwdHeader, hist history) *types.Forward {
+	forward := &types.Forward{}
+
+	from, _ := fwd.GetFromID()
+	switch peer := from.(type) {
+	case *tg.PeerChannel:
+		channel, ok := hist.chats[peer.ChannelID]
+		if !ok {
+			break
+		}
+		forward.From = channel.Title
+		if post, ok := fwd.GetChannelPost(); ok {
+			forward.Link = messageLink(channel, 0, post)
+		} else if channel.Username != "" {
+			forward.Link = "https://t.me/" + channel.Username
+		}
+	case *tg.PeerUser:
+		user, ok := hist.users[peer.UserID]
+		if !ok {
+			break
+		}
+		forward.From = userName(user)
+		if user.Username != "" {
+			forward.Link = "https://t.me/" + user.Username
+		}
+	}
+
+	// Authors hiding their account are known by name only
+	if forward.From == "" {
+		forward.From, _ = fwd.GetFromName()
+	}
+	if forward.From == "" {
+		forward.From = "unknown source"
+	}
+	if author, ok := fwd.GetPostAuthor(); ok && author != "" {
+		forward.From = fmt.Sprintf("%s (%s)", forward.From, author)
+	}
+	return forward
+}
+
+func userName(user *tg.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" && user.Username != "" {
+		name = "@" + user.Username
+	}
+	return name
+}
@@ -15,20 +15,97 @@ import (
 )
 
 const (
-	maxPhotoSize = 500 * 1024 * 1024 // 500MB max file size
+	maxPhotoSize         = 500 * 1024 * 1024 // 500MB max file size
+	maxImageDocumentSize = 20 * 1024 * 1024  // Images sent as files are often originals, keep the issue small
 )
 
-// downloadPhoto downloads a photo from Telegram and returns a MediaAttachment
-// Photos are saved with a filename based on the message GUID to ensure uniqueness
-func downloadPhoto(ctx context.Context, client *telegram.Client, photo *tg.Photo, messageGUID string, tmpDir string) (types.MediaAttachment, error) {
-	var attachment types.MediaAttachment
-	attachment.Type = "photo"
+// imageDocumentExtensions lists document MIME types rendered as images
+var imageDocumentExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp", // Static stickers
+}
 
-	// Find the largest photo size
+// downloadFile downloads the file at location into localPath.
+// The file is removed when the download fails or exceeds maxSize.
+func downloadFile(ctx context.Context, client *telegram.Client, location tg.InputFileLocationClass, localPath string, maxSize int64) (int64, error) {
+	// Ensure temp directory exists
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	// Create file for download
+	file, err := os.Create(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = downloader.NewDownloader().Download(client.API(), location).Stream(ctx, file)
+	if err != nil {
+		os.Remove(localPath) // Clean up on error
+		return 0, err
+	}
+
+	// Check file size after download
+	fileInfo, err := file.Stat()
+	if err != nil {
+		os.Remove(localPath)
+		return 0, fmt.Errorf("failed to stat downloaded file: %w", err)
+	}
+
+	if fileInfo.Size() > maxSize {
+		os.Remove(localPath)
+		return 0, fmt.Errorf("file size (%d bytes) exceeds maximum allowed size (%d bytes)", fileInfo.Size(), maxSize)
+	}
+	return fileInfo.Size(), nil
+}
+
+// downloadImageDocument downloads an image sent as a file (uploaded
+// uncompressed, GIF or sticker) and returns a MediaAttachment
+func downloadImageDocument(ctx context.Context, client *telegram.Client, document *tg.Document, messageGUID string, tmpDir string) (types.MediaAttachment, error) {
+	attachment := types.MediaAttachment{Type: "photo"}
+	for _, attr := range document.Attributes {
+		if size, ok := attr.(*tg.DocumentAttributeImageSize); ok {
+			attachment.Width = size.W
+			attachment.Height = size.H
+		}
+	}
+
+	if document.Size > maxImageDocumentSize {
+		return attachment, fmt.Errorf("image size (%d bytes) exceeds maximum allowed size (%d bytes)", document.Size, maxImageDocumentSize)
+	}
+
+	filename := fmt.Sprintf("document_%s_%d%s", messageGUID, document.ID, imageDocumentExtensions[document.MimeType])
+	localPath := filepath.Join(tmpDir, filename)
+
+	location := &tg.InputDocumentFileLocation{
+		ID:            document.ID,
+		AccessHash:    document.AccessHash,
+		FileReference: document.FileReference,
+	}
+
+	size, err := downloadFile(ctx, client, location, localPath, maxImageDocumentSize)
+	if err != nil {
+		return attachment, fmt.Errorf("failed to download image document: %w", err)
+	}
+
+	slog.Debug("image document downloaded",
+		"filename", filename,
+		"mime_type", document.MimeType,
+		"size", size)
+
+	attachment.LocalPath = localPath
+	return attachment, nil
+}
+
+// largestPhotoSize returns the downloadable size with the most pixels, nil if there is none
+func largestPhotoSize(sizes []tg.PhotoSizeClass) *tg.PhotoSize {
 	var largestSize *tg.PhotoSize
 	var maxPixels int
 
-	for _, sizeClass := range photo.Sizes {
+	for _, sizeClass := range sizes {
 		switch size := sizeClass.(type) {
 		case *tg.PhotoSize:
 			pixels := size.W * size.H
@@ -50,6 +127,64 @@ func downloadPhoto(ctx context.Context, client *telegram.Client, photo *tg.Photo
 			}
 		}
 	}
+	return largestSize
+}
+
+// downloadVideoThumbnail downloads the largest thumbnail of a video and
+// returns it as a "video" MediaAttachment
+func downloadVideoThumbnail(ctx context.Context, client *telegram.Client, document *tg.Document, messageGUID string, tmpDir string) (types.MediaAttachment, error) {
+	attachment := types.MediaAttachment{Type: "video"}
+
+	thumbs, _ := document.GetThumbs()
+	thumb := largestPhotoSize(thumbs)
+	if thumb == nil {
+		return attachment, fmt.Errorf("video has no thumbnail")
+	}
+	attachment.Width = thumb.W
+	attachment.Height = thumb.H
+
+	filename := fmt.Sprintf("video_%s_%d.jpg", messageGUID, document.ID)
+	localPath := filepath.Join(tmpDir, filename)
+
+	location := &tg.InputDocumentFileLocation{
+		ID:            document.ID,
+		AccessHash:    document.AccessHash,
+		FileReference: document.FileReference,
+		ThumbSize:     thumb.Type,
+	}
+
+	size, err := downloadFile(ctx, client, location, localPath, maxPhotoSize)
+	if err != nil {
+		return attachment, fmt.Errorf("failed to download video thumbnail: %w", err)
+	}
+
+	slog.Debug("video thumbnail downloaded",
+		"filename", filename,
+		"size", size,
+		"dimensions", fmt.Sprintf("%dx%d", attachment.Width, attachment.Height))
+
+	attachment.LocalPath = localPath
+	return attachment, nil
+}
+
+// isVideo reports whether the document is a video (including round videos and GIFs sent as MP4)
+func isVideo(document *tg.Document) bool {
+	for _, attr := range document.Attributes {
+		if _, ok := attr.(*tg.DocumentAttributeVideo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadPhoto downloads a photo from Telegram and returns a MediaAttachment
+// Photos are saved with a filename based on the message GUID to ensure uniqueness
+func downloadPhoto(ctx context.Context, client *telegram.Client, photo *tg.Photo, messageGUID string, tmpDir string) (types.MediaAttachment, error) {
+	var attachment types.MediaAttachment
+	attachment.Type = "photo"
+
+	// Find the largest photo size
+	largestSize := largestPhotoSize(photo.Sizes)
 
 	if largestSize == nil {
 		return attachment, fmt.Errorf("no suitable photo size found")
@@ -64,21 +199,6 @@ func downloadPhoto(ctx context.Context, client *telegram.Client, photo *tg.Photo
 	filename := fmt.Sprintf("photo_%s_%d.jpg", messageGUID, photo.ID)
 	localPath := filepath.Join(tmpDir, filename)
 
-	// Ensure temp directory exists
-	if err := os.MkdirAll(tmpDir, 0755); err != nil {
-		return attachment, fmt.Errorf("failed to create temp directory: %w", err)
-	}
-
-	// Create file for download
-	file, err := os.Create(localPath)
-	if err != nil {
-		return attachment, fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	// Create downloader
-	d := downloader.NewDownloader()
-
 	// Create input location for the photo
 	location := &tg.InputPhotoFileLocation{
 		ID:            photo.ID,
@@ -87,28 +207,14 @@ func downloadPhoto(ctx context.Context, client *telegram.Client, photo *tg.Photo
 		ThumbSize:     largestSize.Type,
 	}
 
-	// Download the photo
-	_, err = d.Download(client.API(), location).Stream(ctx, file)
+	size, err := downloadFile(ctx, client, location, localPath, maxPhotoSize)
 	if err != nil {
-		os.Remove(localPath) // Clean up on error
 		return attachment, fmt.Errorf("failed to download photo: %w", err)
 	}
 
-	// Check file size after download
-	fileInfo, err := file.Stat()
-	if err != nil {
-		os.Remove(localPath)
-		return attachment, fmt.Errorf("failed to stat downloaded file: %w", err)
-	}
-
-	if fileInfo.Size() > maxPhotoSize {
-		os.Remove(localPath)
-		return attachment, fmt.Errorf("photo size (%d bytes) exceeds maximum allowed size (%d bytes)", fileInfo.Size(), maxPhotoSize)
-	}
-
 	slog.Debug("photo downloaded",
 		"filename", filename,
-		"size", fileInfo.Size(),
+		"size", size,
 		"dimensions", fmt.Sprintf("%dx%d", attachment.Width, attachment.Height))
 
 	attachment.LocalPath = localPath
@@ -149,8 +255,8 @@ func extractMediaFromMessage(ctx context.Context, client *telegram.Client, msg *
 		attachments = append(attachments, attachment)
 
 	case *tg.MessageMediaDocument:
-		// Could be photo, video, or other document
-		// For now, we only handle photos (ignore videos per requirements)
+		// Could be an image, video, or other document
+		// Images are rendered as is, videos by their thumbnail
 		doc, ok := media.GetDocument()
 		if !ok {
 			return attachments, nil
@@ -161,25 +267,28 @@ func extractMediaFromMessage(ctx context.Context, client *telegram.Client, msg *
 			return attachments, nil
 		}
 
-		// Check if it's an image by MIME type
-		isImage := false
-		for _, attr := range document.Attributes {
-			if imgAttr, ok := attr.(*tg.DocumentAttributeImageSize); ok {
-				// This is an image
-				isImage = true
-				_ = imgAttr // Will use dimensions later
-				break
+		if isVideo(document) {
+			attachment, err := downloadVideoThumbnail(ctx, client, document, messageGUID, tmpDir)
+			if err != nil {
+				slog.Warn("failed to download video thumbnail", "error", err, "message_id", msg.ID)
+				attachment.Caption = fmt.Sprintf("Error downloading video thumbnail: %s", err.Error())
 			}
+			attachments = append(attachments, attachment)
+			return attachments, nil
 		}
 
-		if !isImage {
-			// Not an image, skip (could be video, document, etc.)
+		if _, ok := imageDocumentExtensions[document.MimeType]; !ok {
+			slog.Debug("skipping non-image document", "message_id", msg.ID, "mime_type", document.MimeType)
 			return attachments, nil
 		}
 
-		// Skip for now - MessageMediaDocument requires different handling
-		// We'll focus on MessageMediaPhoto which covers most cases
-		slog.Debug("skipping document media (not implemented yet)", "message_id", msg.ID)
+		attachment, err := downloadImageDocument(ctx, client, document, messageGUID, tmpDir)
+		if err != nil {
+			slog.Warn("failed to download image document", "error", err, "message_id", msg.ID)
+			// Return attachment with error in caption to display as alt text
+			attachment.Caption = fmt.Sprintf("Error downloading image: %s", err.Error())
+		}
+		attachments = append(attachments, attachment)
 	}
 
 	return attachments, nil
@@ -0,0 +1,219 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/telegram"
+	"github.com/gotd/td/telegram/updates"
+	"github.com/gotd/td/tg"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+const (
+	albumDelay   = 3 * time.Second // Album parts arriving within it are merged into one item
+	streamLease  = 5 * time.Minute // Streamed channels are not polled while their lease is active
+	leaseRefresh = time.Minute
+)
+
+// StreamHooks connect the update stream to the fetch queue
+type StreamHooks struct {
+	// Push queues a new post, feed.Offset is the ID of its last message
+	Push func(ctx context.Context, channelURL string, feed types.Feed) error
+	// Lease records until when the channel is streamed, zero once it no longer is
+	Lease func(ctx context.Context, channelURL string, expires time.Time) error
+}
+
+// Stream receives new posts of channels over the updates API. Telegram only
+// sends updates of joined channels, others have to be polled.
+type Stream struct {
+	configDir   string
+	appID       int
+	appHash     string
+	phoneNumber string
+	login       LoginOptions
+	channels    []string
+	hooks       StreamHooks
+
+	mu     sync.Mutex
+	albums map[int64]*pendingAlbum
+}
+
+// pendingAlbum collects messages of an album until no more parts arrive
+type pendingAlbum struct {
+	channelURL string
+	title      string
+	item       types.FeedItem
+	lastID     int
+	updated    time.Time
+	timer      *time.Timer
+}
+
+// NewStream creates a stream of new posts of the channels
+func NewStream(configDir string, appID int, appHash string, phoneNumber string, login LoginOptions, channels []string, hooks StreamHooks) *Stream {
+	return &Stream{
+		configDir:   configDir,
+		appID:       appID,
+		appHash:     appHash,
+		phoneNumber: phoneNumber,
+		login:       login,
+		channels:    channels,
+		hooks:       hooks,
+		albums:      make(map[int64]*pendingAlbum),
+	}
+}
+
+// Run streams new posts until ctx is cancelled, then the leases are released
+// so the channels are polled again
+func (s *Stream) Run(ctx context.Context) error {
+	tmpDir := filepath.Join(os.TempDir(), "myfeed-telegram-media")
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	dispatcher := tg.NewUpdateDispatcher()
+	gaps := updates.New(updates.Config{Handler: dispatcher})
+
+	return runClient(ctx, s.configDir, s.appID, s.appHash, s.phoneNumber, s.login, gaps, func(ctx context.Context, client *telegram.Client) error {
+		api := client.API()
+
+		// Only joined broadcast channels receive updates
+		streamed := make(map[int64]string)
+		for _, url := range s.channels {
+			ref, err := parseChannelRef(url)
+			if err != nil {
+				slog.Warn("telegram stream: invalid channel URL", "url", url, "error", err)
+				continue
+			}
+			if ref.Topic != 0 {
+				slog.Info("telegram stream: forum topics are polled", "channel", ref.String())
+				continue
+			}
+			channel, err := resolveChannel(ctx, api, ref)
+			if err != nil {
+				slog.Warn("telegram stream: failed to resolve channel", "channel", ref.String(), "error", err)
+				continue
+			}
+			if !channel.Broadcast || channel.Left {
+				slog.Info("telegram stream: channel not joined, polling it instead", "channel", ref.String())
+				continue
+			}
+			streamed[channel.ID] = url
+		}
+		if len(streamed) == 0 {
+			slog.Info("telegram stream: no joined channels to stream")
+			<-ctx.Done()
+			return nil
+		}
+
+		dispatcher.OnNewChannelMessage(func(ctx context.Context, e tg.Entities, u *tg.UpdateNewChannelMessage) error {
+			msg, ok := u.Message.(*tg.Message)
+			if !ok {
+				return nil
+			}
+			peer, ok := msg.PeerID.(*tg.PeerChannel)
+			if !ok {
+				return nil
+			}
+			url, ok := streamed[peer.ChannelID]
+			channel := e.Channels[peer.ChannelID]
+			if !ok || channel == nil {
+				return nil
+			}
+			hist := history{chats: e.Channels, users: e.Users}
+			item, ok := messageItem(ctx, client, channel, 0, msg, hist, tmpDir)
+			if !ok {
+				return nil
+			}
+			s.receive(ctx, url, channel.Title, msg, item)
+			return nil
+		})
+
+		self, err := client.Self(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get self info: %w", err)
+		}
+
+		go s.refreshLeases(ctx, streamed)
+		slog.Info("telegram stream: receiving new posts", "channels", len(streamed))
+		return gaps.Run(ctx, api, self.ID, updates.AuthOptions{})
+	})
+}
+
+// receive queues a post, album parts are held back until the album is complete
+func (s *Stream) receive(ctx context.Context, url, title string, msg *tg.Message, item types.FeedItem) {
+	groupedID, ok := msg.GetGroupedID()
+	if !ok {
+		item.Title = itemTitle(item.Description, item.Media)
+		s.push(ctx, url, title, item, msg.ID)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if album, ok := s.albums[groupedID]; ok {
+		album.item = mergeAlbumItem(item, album.item)
+		album.lastID = max(album.lastID, msg.ID)
+		album.updated = time.Now()
+		album.timer.Reset(albumDelay)
+		return
+	}
+	album := &pendingAlbum{channelURL: url, title: title, item: item, lastID: msg.ID, updated: time.Now()}
+	album.timer = time.AfterFunc(albumDelay, func() {
+		s.mu.Lock()
+		// A part arrived while the timer fired, the reset timer pushes the album
+		if time.Since(album.updated) < albumDelay {
+			s.mu.Unlock()
+			return
+		}
+		delete(s.albums, groupedID)
+		item := album.item
+		s.mu.Unlock()
+		item.Title = itemTitle(item.Description, item.Media)
+		s.push(context.WithoutCancel(ctx), album.channelURL, album.title, item, album.lastID)
+	})
+	s.albums[groupedID] = album
+}
+
+func (s *Stream) push(ctx context.Context, url, title string, item types.FeedItem, lastID int) {
+	feed := types.Feed{
+		Title:  title,
+		Items:  []types.FeedItem{item},
+		Offset: int64(lastID),
+	}
+	if err := s.hooks.Push(ctx, url, feed); err != nil {
+		slog.Error("telegram stream: failed to queue post", "channel", url, "link", item.Link, "error", err)
+		return
+	}
+	slog.Info("telegram stream: queued new post", "channel", url, "link", item.Link)
+}
+
+// refreshLeases keeps the leases of streamed channels active while the
+// stream runs and releases them once it stops
+func (s *Stream) refreshLeases(ctx context.Context, streamed map[int64]string) {
+	ticker := time.NewTicker(leaseRefresh)
+	defer ticker.Stop()
+	for {
+		for _, url := range streamed {
+			if err := s.hooks.Lease(ctx, url, time.Now().Add(streamLease)); err != nil {
+				slog.Warn("telegram stream: failed to save lease", "channel", url, "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			for _, url := range streamed {
+				if err := s.hooks.Lease(context.Background(), url, time.Time{}); err != nil {
+					slog.Warn("telegram stream: failed to release lease", "channel", url, "error", err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
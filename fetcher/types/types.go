@@ -2,14 +2,43 @@ package types
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"time"
 )
 
+// ErrNotModified is returned by fetchers when the feed did not change since the last fetch
+var ErrNotModified = errors.New("feed not modified")
+
 // Feed represents a collection of items from a feed source
 type Feed struct {
 	Title       string
 	Description string
 	Items       []FeedItem
+	Validators  Validators // HTTP cache validators of the fetched feed (empty if not supported)
+	Archive     []FeedItem // Items from older archive pages, set only when backfilling
+	Offset      int64      // Highest message ID fetched, for fetchers supporting offsets (0 otherwise)
+}
+
+// Validators are HTTP cache validators used for conditional GET requests
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// IsZero returns true if no validators are set
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// ValidatorStore provides validators saved by previous runs
+type ValidatorStore interface {
+	GetValidators(ctx context.Context, url string) (Validators, error)
+}
+
+// OffsetStore provides feed offsets saved by previous runs, 0 if unknown
+type OffsetStore interface {
+	GetOffset(ctx context.Context, url string) (int64, error)
 }
 
 // FeedItem represents a single item in a feed
@@ -20,6 +49,43 @@ type FeedItem struct {
 	Published   time.Time
 	GUID        string            // Unique identifier (GUID for RSS, message ID for Telegram)
 	Media       []MediaAttachment // Media attachments (photos, videos, etc.)
+	Forward     *Forward          // Original source of a forwarded message, nil if not forwarded
+	Entities    []TextEntity      // Formatting of Description, nil if the source provides none
+	Engagement  *Engagement       // Audience reaction at fetch time, nil if the source does not report it
+	Comments    []Comment         // Top replies to the item, oldest first, if requested and supported
+	CommentsURL string            // Discussion thread of the item, e.g., on HN, empty if the feed links none
+	Language    string            // ISO 639-1 code of the content, empty to detect it from the text
+}
+
+// Comment is a reply to an item, e.g., from a channel's discussion group
+type Comment struct {
+	Author    string
+	Text      string
+	Published time.Time
+	Reactions int
+}
+
+// Engagement counts how the audience reacted to an item
+type Engagement struct {
+	Views     int
+	Forwards  int
+	Reactions int // Total of all reaction counts
+}
+
+// TextEntity marks formatted text in a message, e.g., bold or a link.
+// Offset and Length are in UTF-16 code units, as sent by Telegram.
+type TextEntity struct {
+	Type     string // "bold", "italic", "underline", "strike", "code", "pre", "text_link", "url", "mention", "email", "spoiler" or "blockquote"
+	Offset   int
+	Length   int
+	URL      string // Target of "text_link"
+	Language string // Language of "pre" blocks
+}
+
+// Forward attributes a forwarded message to where it was originally posted
+type Forward struct {
+	From string // Channel title or author name
+	Link string // URL of the original message or author, empty if not public
 }
 
 // MediaAttachment represents a media file attached to a feed item
@@ -29,9 +95,29 @@ type MediaAttachment struct {
 	Width     int    // Width in pixels (for photos/videos)
 	Height    int    // Height in pixels (for photos/videos)
 	Caption   string // Optional caption for the media
+	Link      string // URL of the original post, e.g., to watch a video
+}
+
+// FetchOptions holds per-resource settings for a single fetch
+type FetchOptions struct {
+	Header    http.Header // Extra request headers, e.g., Authorization or Cookie
+	Proxy     string      // HTTP or SOCKS5 proxy URL, empty for direct connection
+	UserAgent string      // User-Agent header, empty for the fetcher default
+	Retry     RetryPolicy // Timeouts and retries, applied by fetchers wrapped with WithRetry
+	Backfill  bool        // Follow RFC 5005 archive links and return older items in Feed.Archive
+	Comments  int         // Top replies fetched per item from the discussion group (Telegram), 0 disables
+	MaxSize   int64       // Largest response body in bytes, longer feeds are truncated (0 = fetcher default)
+}
+
+// RetryPolicy defines how transient fetch failures are retried
+type RetryPolicy struct {
+	Timeout        time.Duration // Timeout of a single attempt (0 = no timeout)
+	MaxRetries     int           // Retries after the first attempt (0 = single attempt)
+	InitialBackoff time.Duration // Delay before the first retry, doubled on every next one
+	MaxBackoff     time.Duration // Upper bound for the delay between retries
 }
 
 // FeedFetcher is an interface for fetching feeds from different sources
 type FeedFetcher interface {
-	Fetch(ctx context.Context, url string) (Feed, error)
+	Fetch(ctx context.Context, url string, opts FetchOptions) (Feed, error)
 }
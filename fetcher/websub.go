@@ -0,0 +1,146 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// Hub is a WebSub hub advertised by a feed
+type Hub struct {
+	URL   string // Hub to subscribe at
+	Topic string // Canonical feed URL the hub publishes, the "self" link
+}
+
+// DiscoverHub returns the WebSub hub advertised by the feed in its Link
+// headers or <link rel="hub"> elements, a zero Hub if there is none
+func (f *RSSFetcher) DiscoverHub(ctx context.Context, url string, opts types.FetchOptions) (Hub, error) {
+	feedURL := url
+	body, header, err := f.get(ctx, feedURL, opts, types.Validators{})
+	if err != nil {
+		return Hub{}, err
+	}
+	if isHTML(body) {
+		candidates, err := discoverFeeds(body, url)
+		if err != nil {
+			return Hub{}, fmt.Errorf("failed to discover feed at '%s': %w", url, err)
+		}
+		feedURL = candidates[0].URL
+		body, header, err = f.get(ctx, feedURL, opts, types.Validators{})
+		if err != nil {
+			return Hub{}, err
+		}
+	}
+
+	hub := hubFromHeader(header)
+	if hub.URL == "" {
+		hub = hubFromFeed(body)
+	}
+	if hub.URL == "" {
+		return Hub{}, nil
+	}
+	if hub.Topic == "" {
+		hub.Topic = feedURL
+	}
+	base, err := neturl.Parse(feedURL)
+	if err != nil {
+		return Hub{}, err
+	}
+	for _, ref := range []*string{&hub.URL, &hub.Topic} {
+		u, err := base.Parse(*ref)
+		if err != nil {
+			return Hub{}, fmt.Errorf("invalid WebSub link '%s': %w", *ref, err)
+		}
+		*ref = u.String()
+	}
+	return hub, nil
+}
+
+// ParseFeed parses feed content, e.g., pushed by a WebSub hub
+func ParseFeed(body []byte) (types.Feed, error) {
+	parsed, err := newFeedParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		return types.Feed{}, fmt.Errorf("failed to parse RSS feed: %w", err)
+	}
+	return types.Feed{
+		Title:       parsed.Title,
+		Description: parsed.Description,
+		Items:       convertItems(parsed.Items),
+	}, nil
+}
+
+// hubFromHeader reads `Link: <url>; rel="hub"` response headers
+func hubFromHeader(header http.Header) Hub {
+	var hub Hub
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = strings.Trim(target, "<>")
+			for _, param := range strings.Split(params, ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(key, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					switch strings.ToLower(rel) {
+					case "hub":
+						hub.URL = target
+					case "self":
+						hub.Topic = target
+					}
+				}
+			}
+		}
+	}
+	return hub
+}
+
+// hubFromFeed reads <link rel="hub" href="..."> of Atom feeds and
+// <atom:link> of RSS feeds
+func hubFromFeed(body []byte) Hub {
+	var hub Hub
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return hub
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		// Items are not part of the feed head
+		if el.Name.Local == "item" || el.Name.Local == "entry" {
+			return hub
+		}
+		if el.Name.Local != "link" {
+			continue
+		}
+		var rel, href string
+		for _, attr := range el.Attr {
+			switch attr.Name.Local {
+			case "rel":
+				rel = attr.Value
+			case "href":
+				href = attr.Value
+			}
+		}
+		switch {
+		case href == "":
+		case rel == "hub" && hub.URL == "":
+			hub.URL = href
+		case rel == "self" && hub.Topic == "":
+			hub.Topic = href
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscoverHub(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/atom", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <link rel="hub" href="https://hub.example.com/"/>
+  <link rel="self" href="/atom.xml"/>
+  <entry><link rel="hub" href="https://wrong.example.com/"/></entry>
+</feed>`))
+	})
+	mux.HandleFunc("/header", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `<https://hub.example.com/h>; rel="hub", <https://example.com/topic>; rel="self"`)
+		w.Write([]byte(`<rss version="2.0"><channel><title>t</title></channel></rss>`))
+	})
+	mux.HandleFunc("/none", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<rss version="2.0"><channel><title>t</title></channel></rss>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want Hub
+	}{
+		{"/atom", Hub{URL: "https://hub.example.com/", Topic: srv.URL + "/atom.xml"}},
+		{"/header", Hub{URL: "https://hub.example.com/h", Topic: "https://example.com/topic"}},
+		{"/none", Hub{}},
+	}
+	for _, tt := range tests {
+		got, err := NewRSSFetcher(nil).DiscoverHub(context.Background(), srv.URL+tt.path, FetchOptions{})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
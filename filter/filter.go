@@ -3,11 +3,14 @@ package filter
 import (
 	"log/slog"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 
 	"github.com/scipunch/myfeed/config"
 	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/lang"
+	"github.com/scipunch/myfeed/parser"
 )
 
 // FilterPipeline applies a series of named filters to feed items
@@ -69,6 +72,31 @@ func (fp *FilterPipeline) ShouldInclude(item types.FeedItem, filterNames []strin
 	return true, ""
 }
 
+// ShouldIncludeResponse returns true if the parsed item passes the checks of
+// all filters which need its content, unknown metadata passes
+func (fp *FilterPipeline) ShouldIncludeResponse(resp parser.Response, filterNames []string) (bool, string) {
+	for _, filterName := range filterNames {
+		filter, exists := fp.filters[filterName]
+		if !exists {
+			continue
+		}
+
+		words := resp.WordCount()
+		if filter.config.MinArticleWords > 0 && words > 0 && words < filter.config.MinArticleWords {
+			return false, filterName + ":min_article_words"
+		}
+
+		author := resp.Author()
+		if author != "" && slices.ContainsFunc(filter.config.ExcludeAuthors, func(a string) bool {
+			return strings.EqualFold(strings.TrimSpace(a), author)
+		}) {
+			return false, filterName + ":exclude_authors"
+		}
+	}
+
+	return true, ""
+}
+
 // applyFilter applies a single filter to an item
 func (fp *FilterPipeline) applyFilter(item types.FeedItem, filter *CompiledFilter, filterName string) (bool, string) {
 	// Get the text to analyze (title + description)
@@ -101,6 +129,35 @@ func (fp *FilterPipeline) applyFilter(item types.FeedItem, filter *CompiledFilte
 		}
 	}
 
+	// 5. Check engagement, items of sources not reporting it pass
+	if e := item.Engagement; e != nil {
+		if e.Views < filter.config.MinViews {
+			return false, filterName + ":min_views"
+		}
+		if e.Forwards < filter.config.MinForwards {
+			return false, filterName + ":min_forwards"
+		}
+		if e.Reactions < filter.config.MinReactions {
+			return false, filterName + ":min_reactions"
+		}
+	}
+
+	// 6. Check language, items whose language can't be detected pass, as do
+	// all items when a language Detect doesn't know is accepted
+	if len(filter.config.Languages) > 0 && !slices.ContainsFunc(filter.config.Languages, func(l string) bool {
+		return !lang.Supported(lang.Normalize(l))
+	}) {
+		language := item.Language
+		if language == lang.Unknown {
+			language = lang.Detect(text)
+		}
+		if language != lang.Unknown && !slices.ContainsFunc(filter.config.Languages, func(l string) bool {
+			return lang.Normalize(l) == language
+		}) {
+			return false, filterName + ":languages"
+		}
+	}
+
 	return true, ""
 }
 
@@ -1,11 +1,14 @@
 package filter
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/scipunch/myfeed/config"
 	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/parser"
 )
 
 func TestFilterPipeline_MinLength(t *testing.T) {
@@ -235,6 +238,89 @@ func TestFilterPipeline_RequireParagraphs(t *testing.T) {
 	}
 }
 
+func TestFilterPipeline_Engagement(t *testing.T) {
+	filters := map[string]config.Filter{
+		"popular": {
+			MinViews:     1000,
+			MinReactions: 10,
+		},
+	}
+
+	pipeline, err := NewFilterPipeline(filters)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		engagement    *types.Engagement
+		shouldInclude bool
+		reason        string
+	}{
+		{"popular post", &types.Engagement{Views: 5000, Reactions: 40}, true, ""},
+		{"few views", &types.Engagement{Views: 300, Reactions: 40}, false, "popular:min_views"},
+		{"few reactions", &types.Engagement{Views: 5000, Reactions: 2}, false, "popular:min_reactions"},
+		{"source without engagement", nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := types.FeedItem{Title: "Post", Engagement: tt.engagement}
+			include, reason := pipeline.ShouldInclude(item, []string{"popular"})
+			if include != tt.shouldInclude || reason != tt.reason {
+				t.Errorf("Expected (%v, %q), got (%v, %q)", tt.shouldInclude, tt.reason, include, reason)
+			}
+		})
+	}
+}
+
+func TestFilterPipeline_Languages(t *testing.T) {
+	filters := map[string]config.Filter{
+		"languages": {
+			Languages: []string{"English", "uk"},
+		},
+	}
+
+	pipeline, err := NewFilterPipeline(filters)
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		item          types.FeedItem
+		shouldInclude bool
+	}{
+		{"detected english", types.FeedItem{Description: "This is the story of a city and the people who live in it."}, true},
+		{"detected russian", types.FeedItem{Description: "Это история о городе и людях, которые в нем живут, и не только."}, false},
+		{"stated language", types.FeedItem{Description: "This is the story of a city and the people who live in it.", Language: "ru"}, false},
+		{"undetectable", types.FeedItem{Description: "Ok"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			include, reason := pipeline.ShouldInclude(tt.item, []string{"languages"})
+			if include != tt.shouldInclude {
+				t.Errorf("Expected %v, got %v (%s)", tt.shouldInclude, include, reason)
+			}
+		})
+	}
+}
+
+func TestFilterPipeline_UnsupportedLanguage(t *testing.T) {
+	pipeline, err := NewFilterPipeline(map[string]config.Filter{
+		"languages": {Languages: []string{"pl"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	item := types.FeedItem{Description: "To jest historia miasta i ludzi, którzy w nim mieszkają od wielu lat."}
+	if include, reason := pipeline.ShouldInclude(item, []string{"languages"}); !include {
+		t.Errorf("Expected polish item to pass, got %s", reason)
+	}
+}
+
 func TestFilterPipeline_Pipeline(t *testing.T) {
 	filters := map[string]config.Filter{
 		"length": {
@@ -287,3 +373,69 @@ func TestFilterPipeline_NoFilters(t *testing.T) {
 		t.Errorf("Expected item to be included when no filters applied")
 	}
 }
+
+// article is a parsed item with the given author and word count
+type article struct {
+	parser.Metadata
+}
+
+func (a article) String() string { return "" }
+
+func TestFilterPipeline_Response(t *testing.T) {
+	pipeline, err := NewFilterPipeline(map[string]config.Filter{
+		"articles": {MinArticleWords: 100, ExcludeAuthors: []string{"Press Release "}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create pipeline: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		meta          parser.Meta
+		shouldInclude bool
+		reason        string
+	}{
+		{name: "long article", meta: parser.Meta{WordCount: 500, Author: "Jane"}, shouldInclude: true},
+		{name: "short article", meta: parser.Meta{WordCount: 50}, reason: "articles:min_article_words"},
+		{name: "unknown length", meta: parser.Meta{}, shouldInclude: true},
+		{name: "excluded author", meta: parser.Meta{WordCount: 500, Author: "press release"}, reason: "articles:exclude_authors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := article{parser.Metadata{Meta: tt.meta}}
+			got, reason := pipeline.ShouldIncludeResponse(resp, []string{"articles"})
+			if got != tt.shouldInclude || reason != tt.reason {
+				t.Errorf("expected %v %q, got %v %q", tt.shouldInclude, tt.reason, got, reason)
+			}
+		})
+	}
+}
+
+func BenchmarkFilterPipeline(b *testing.B) {
+	pipeline, err := NewFilterPipeline(map[string]config.Filter{
+		"length":     {MinLength: 100},
+		"words":      {MinWords: 20},
+		"paragraphs": {RequireParagraphs: true},
+		"patterns":   {ExcludePatterns: []string{"(?i)sponsored", "^[Дд]ержите.*", `\bgiveaway\b`}},
+	})
+	if err != nil {
+		b.Fatalf("Failed to create pipeline: %v", err)
+	}
+	filterNames := []string{"length", "words", "paragraphs", "patterns"}
+
+	items := make([]types.FeedItem, 10000)
+	paragraph := strings.Repeat("Некоторый текст статьи with mixed words. ", 20)
+	for i := range items {
+		items[i] = types.FeedItem{
+			Title:       fmt.Sprintf("Article %d", i),
+			Description: paragraph + "\n\n" + paragraph,
+			Published:   time.Now(),
+		}
+	}
+
+	for b.Loop() {
+		for _, item := range items {
+			pipeline.ShouldInclude(item, filterNames)
+		}
+	}
+}
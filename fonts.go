@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/lang"
+)
+
+// defaultFontStack follows the configured fonts, so scripts they lack are
+// still rendered with the template font
+const defaultFontStack = "Georgia, serif"
+
+// printFonts returns the CSS loading the configured font files for the PDF.
+// Fonts are not subset, so the files are referenced where they are instead
+// of being copied next to the issue, and their stacks apply to print only:
+// browsers viewing the issue never download them, while Chromium embeds only
+// the glyphs in use when printing, so large CJK fonts barely grow the PDF.
+func printFonts(fonts []config.Font) (string, error) {
+	urls := make([]string, len(fonts))
+	for i, font := range fonts {
+		if fontFormat(font.Path) == "" {
+			return "", fmt.Errorf("unsupported font file '%s', expected TTF, OTF, WOFF or WOFF2", font.Path)
+		}
+		path, err := filepath.Abs(font.Path)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve font '%s' with %w", font.Path, err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("failed to read font '%s' with %w", font.Path, err)
+		}
+		urls[i] = fileURL(path)
+	}
+	return fontCSS(fonts, urls), nil
+}
+
+// fileURL returns the file URL of an absolute path, e.g., "file:///C:/Fonts/a.ttf"
+func fileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// fontCSS declares a font face per URL and sets the print font stacks:
+// fonts without languages apply to the whole issue, the others come first
+// in articles of their languages and are fallbacks everywhere else, so text
+// of an undetected language still finds its glyphs
+func fontCSS(fonts []config.Font, urls []string) string {
+	var b strings.Builder
+	var general, fallbacks []string
+	byLang := make(map[string][]string)
+	var langs []string
+	for i, font := range fonts {
+		family := fontFamily(font)
+		fmt.Fprintf(&b, "@font-face { font-family: %q; src: url(%q) format(%q); font-weight: %s; font-style: %s; }\n",
+			family, urls[i], fontFormat(font.Path), orDefault(font.Weight, "normal"), orDefault(font.Style, "normal"))
+		if len(font.Languages) == 0 {
+			general = appendFamily(general, family)
+			continue
+		}
+		for _, l := range font.Languages {
+			l = lang.Normalize(l)
+			if _, ok := byLang[l]; !ok {
+				langs = append(langs, l)
+			}
+			byLang[l] = appendFamily(byLang[l], family)
+		}
+		fallbacks = appendFamily(fallbacks, family)
+	}
+
+	stack := slices.Clone(general)
+	for _, family := range fallbacks {
+		stack = appendFamily(stack, family)
+	}
+	b.WriteString("@media print {\n")
+	fmt.Fprintf(&b, "  body { font-family: %s, %s; }\n", quoteFamilies(stack), defaultFontStack)
+	for _, l := range langs {
+		stack := slices.Clone(byLang[l])
+		for _, family := range general {
+			stack = appendFamily(stack, family)
+		}
+		fmt.Fprintf(&b, "  [lang=%q] { font-family: %s, %s; }\n", l, quoteFamilies(stack), defaultFontStack)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// fontFamily returns the configured family or the file name without extension
+func fontFamily(font config.Font) string {
+	if font.Family != "" {
+		return font.Family
+	}
+	base := filepath.Base(font.Path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// fontFormat returns the CSS format of the font file, empty if unsupported
+func fontFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ttf":
+		return "truetype"
+	case ".otf":
+		return "opentype"
+	case ".woff":
+		return "woff"
+	case ".woff2":
+		return "woff2"
+	}
+	return ""
+}
+
+// appendFamily appends the family once, faces of several weights share a
+// family
+func appendFamily(stack []string, family string) []string {
+	if slices.Contains(stack, family) {
+		return stack
+	}
+	return append(stack, family)
+}
+
+// quoteFamilies joins the families of a stack into a CSS list
+func quoteFamilies(stack []string) string {
+	quoted := make([]string, len(stack))
+	for i, family := range stack {
+		quoted[i] = fmt.Sprintf("%q", family)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestFontCSS(t *testing.T) {
+	fonts := []config.Font{
+		{Path: "/fonts/Literata.ttf"},
+		{Path: "/fonts/Literata-Bold.ttf", Family: "Literata", Weight: "bold"},
+		{Path: "/fonts/NotoSerifCJK.otf", Languages: []string{"ja", "zh-Hans"}},
+		{Path: "/fonts/NotoSansJP.woff2", Languages: []string{"JA"}, Style: "italic"},
+	}
+	urls := []string{"file:///fonts/Literata.ttf", "file:///fonts/Literata-Bold.ttf", "file:///fonts/NotoSerifCJK.otf", "file:///fonts/NotoSansJP.woff2"}
+	want := `@font-face { font-family: "Literata"; src: url("file:///fonts/Literata.ttf") format("truetype"); font-weight: normal; font-style: normal; }
+@font-face { font-family: "Literata"; src: url("file:///fonts/Literata-Bold.ttf") format("truetype"); font-weight: bold; font-style: normal; }
+@font-face { font-family: "NotoSerifCJK"; src: url("file:///fonts/NotoSerifCJK.otf") format("opentype"); font-weight: normal; font-style: normal; }
+@font-face { font-family: "NotoSansJP"; src: url("file:///fonts/NotoSansJP.woff2") format("woff2"); font-weight: normal; font-style: italic; }
+@media print {
+  body { font-family: "Literata", "NotoSerifCJK", "NotoSansJP", Georgia, serif; }
+  [lang="ja"] { font-family: "NotoSerifCJK", "NotoSansJP", "Literata", Georgia, serif; }
+  [lang="zh"] { font-family: "NotoSerifCJK", "Literata", Georgia, serif; }
+}
+`
+	if got := fontCSS(fonts, urls); got != want {
+		t.Errorf("fontCSS() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPrintFonts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "My Font.ttf")
+	if err := os.WriteFile(path, []byte("font"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	css, err := printFonts([]config.Font{{Path: path}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(css, `url("`+fileURL(path)+`")`) || !strings.Contains(fileURL(path), "My%20Font.ttf") {
+		t.Errorf("expected the font to be loaded from where it is, got\n%s", css)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fonts")); !os.IsNotExist(err) {
+		t.Error("expected the font not to be copied")
+	}
+
+	for _, font := range []config.Font{{Path: filepath.Join(dir, "font.pcf")}, {Path: filepath.Join(dir, "missing.ttf")}} {
+		if _, err := printFonts([]config.Font{font}); err == nil || !strings.Contains(err.Error(), font.Path) {
+			t.Errorf("expected an error naming '%s', got %v", font.Path, err)
+		}
+	}
+}
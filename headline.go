@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/scipunch/myfeed/fetcher"
+)
+
+// headlineLength is the longest description shown for headline resources
+const headlineLength = 200
+
+// headline renders the description of an item of a headline resource as a
+// single short line, empty when the item has no description
+func headline(item fetcher.FeedItem) string {
+	text := plainText(item.Description)
+	if text == "" {
+		return ""
+	}
+	if r := []rune(text); len(r) > headlineLength {
+		text = strings.TrimSpace(string(r[:headlineLength-1])) + "…"
+	}
+	return fmt.Sprintf(`<p class="headline">%s</p>`, html.EscapeString(text))
+}
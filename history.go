@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/scipunch/myfeed/db"
+)
+
+// backReference renders a short note pointing to an earlier issue
+// where the same link was already included
+func backReference(prev db.ProcessedItem) string {
+	date := time.Unix(prev.ProcessedAt, 0).Format("2006-01-02")
+	return fmt.Sprintf(
+		`<p class="back-reference">Previously summarized on %s: <a href="%s">%s</a></p>`,
+		date,
+		html.EscapeString(prev.Url),
+		html.EscapeString(prev.Title),
+	)
+}
@@ -0,0 +1,82 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrBlockedDomain is returned for requests the domain policy refuses
+var ErrBlockedDomain = errors.New("domain is blocked")
+
+// Domains configures which hosts may be requested, a domain covers its subdomains
+type Domains struct {
+	Blocked []string // Never requested, wins over Allowed
+	Allowed []string // Only these are requested when set
+}
+
+var defaultDomains Domains
+
+// ConfigureDomains replaces the process-wide domain policy
+func ConfigureDomains(d Domains) {
+	defaultDomains = Domains{
+		Blocked: normalizeDomains(d.Blocked),
+		Allowed: normalizeDomains(d.Allowed),
+	}
+}
+
+// CheckDomain returns ErrBlockedDomain when the domain policy refuses the URL.
+// URLs without a host, e.g., Telegram channel names, are always allowed.
+func CheckDomain(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+	if !defaultDomains.allows(u.Hostname()) {
+		return fmt.Errorf("'%s': %w", u.Hostname(), ErrBlockedDomain)
+	}
+	return nil
+}
+
+func (d Domains) allows(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if matchDomain(host, d.Blocked) {
+		return false
+	}
+	return len(d.Allowed) == 0 || matchDomain(host, d.Allowed)
+}
+
+// matchDomain reports whether the host is one of the domains or their subdomain
+func matchDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDomains lowercases domains and strips what users tend to paste
+// along, e.g., "https://", "*." or a path
+func normalizeDomains(domains []string) []string {
+	var normalized []string
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if i := strings.Index(domain, "://"); i >= 0 {
+			domain = domain[i+3:]
+		}
+		if i := strings.IndexAny(domain, "/?#"); i >= 0 {
+			domain = domain[:i]
+		}
+		if host, _, err := net.SplitHostPort(domain); err == nil {
+			domain = host
+		}
+		domain = strings.Trim(strings.TrimPrefix(domain, "*."), ".")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
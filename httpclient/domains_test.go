@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckDomain(t *testing.T) {
+	defer ConfigureDomains(Domains{})
+	ConfigureDomains(Domains{
+		Blocked: []string{"ads.example.com", "https://Tracker.example/path"},
+		Allowed: []string{"*.example.com", "news.example"},
+	})
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{url: "https://example.com/post"},
+		{url: "https://blog.example.com:8443/post"},
+		{url: "https://news.example./feed"},
+		{url: "https://ads.example.com/banner", blocked: true},
+		{url: "https://cdn.ads.example.com/banner", blocked: true},
+		{url: "https://tracker.example/pixel", blocked: true},
+		{url: "https://notexample.com/post", blocked: true},
+		{url: "https://other.example/post", blocked: true},
+		{url: "@channel"},
+	}
+	for _, tt := range tests {
+		err := CheckDomain(tt.url)
+		if blocked := errors.Is(err, ErrBlockedDomain); blocked != tt.blocked {
+			t.Errorf("%s: expected blocked %v, got %v", tt.url, tt.blocked, err)
+		}
+	}
+}
+
+func TestNew_RefusesBlockedRedirects(t *testing.T) {
+	defer ConfigureDomains(Domains{})
+	ConfigureDomains(Domains{Blocked: []string{"blocked.example"}})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://blocked.example/", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	client, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, ErrBlockedDomain) {
+		t.Errorf("expected the redirect to a blocked domain to fail, got %v", err)
+	}
+}
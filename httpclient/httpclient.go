@@ -0,0 +1,119 @@
+// Package httpclient provides the HTTP client shared by fetchers and parsers.
+// It spaces requests to the same host, optionally honors robots.txt crawl-delay
+// and refuses hosts blocked by the domain policy.
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultUserAgent identifies myfeed when no user agent is configured
+const DefaultUserAgent = "myfeed/1.0 (+https://github.com/scipunch/myfeed)"
+
+// robotsAgent is the token matched against robots.txt user-agent groups
+const robotsAgent = "myfeed"
+
+// Politeness configures request spacing per host
+type Politeness struct {
+	MinDelay      time.Duration // Minimum delay between requests to the same host
+	RespectRobots bool          // Use robots.txt Crawl-delay when it is longer than MinDelay
+}
+
+// hosts tracks when the next request to a host may be sent
+type hosts struct {
+	cfg    Politeness
+	robots *robotsCache
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func newHosts(cfg Politeness) *hosts {
+	return &hosts{
+		cfg:    cfg,
+		robots: newRobotsCache(),
+		next:   make(map[string]time.Time),
+	}
+}
+
+var defaultHosts = newHosts(Politeness{})
+
+// Configure replaces the process-wide politeness settings
+func Configure(cfg Politeness) {
+	defaultHosts = newHosts(cfg)
+}
+
+// Wait blocks until a request to the URL's host is allowed, it fails right
+// away with ErrBlockedDomain when the domain policy refuses the host
+func Wait(ctx context.Context, rawURL string) error {
+	if err := CheckDomain(rawURL); err != nil {
+		return err
+	}
+	return defaultHosts.wait(ctx, rawURL)
+}
+
+func (h *hosts) wait(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+	host := strings.ToLower(u.Host)
+
+	spacing := h.cfg.MinDelay
+	if h.cfg.RespectRobots {
+		spacing = max(spacing, h.robots.crawlDelay(ctx, u.Scheme, host))
+	}
+	if spacing <= 0 {
+		return nil
+	}
+
+	// Reserve the next slot so concurrent callers queue up behind each other
+	h.mu.Lock()
+	now := time.Now()
+	at := now
+	if next := h.next[host]; next.After(now) {
+		at = next
+	}
+	h.next[host] = at.Add(spacing)
+	h.mu.Unlock()
+
+	if delay := at.Sub(now); delay > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return nil
+}
+
+// New creates a client sending requests through the proxy if set
+// and spacing them per host
+func New(proxy string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: &politeTransport{base: transport}}, nil
+}
+
+type politeTransport struct {
+	base http.RoundTripper
+}
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := Wait(req.Context(), req.URL.String()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
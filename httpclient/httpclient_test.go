@@ -0,0 +1,89 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCrawlDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots string
+		want   time.Duration
+	}{
+		{
+			name:   "wildcard",
+			robots: "User-agent: *\nDisallow: /private\nCrawl-delay: 5\n",
+			want:   5 * time.Second,
+		},
+		{
+			name:   "specific group wins",
+			robots: "User-agent: *\nCrawl-delay: 10\n\nUser-agent: MyFeed\nCrawl-delay: 0.5\n",
+			want:   500 * time.Millisecond,
+		},
+		{
+			name:   "other agent only",
+			robots: "User-agent: Googlebot\nCrawl-delay: 3\n",
+			want:   0,
+		},
+		{
+			name:   "shared group",
+			robots: "User-agent: bingbot\nUser-agent: *\nCrawl-delay: 2 # seconds\n",
+			want:   2 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCrawlDelay(strings.NewReader(tt.robots), robotsAgent); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWait_SpacesRequestsPerHost(t *testing.T) {
+	h := newHosts(Politeness{MinDelay: 30 * time.Millisecond})
+
+	start := time.Now()
+	for range 3 {
+		if err := h.wait(context.Background(), "https://example.com/feed"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("expected requests to be spaced, took %v", elapsed)
+	}
+
+	// Another host is not delayed by the first one
+	start = time.Now()
+	if err := h.wait(context.Background(), "https://other.com/feed"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("expected no delay for another host, took %v", elapsed)
+	}
+}
+
+func TestWait_RespectsRobots(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nCrawl-delay: 0.05\n"))
+		}
+	}))
+	defer srv.Close()
+
+	h := newHosts(Politeness{RespectRobots: true})
+	start := time.Now()
+	for range 2 {
+		if err := h.wait(context.Background(), srv.URL+"/feed"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected crawl-delay to apply, took %v", elapsed)
+	}
+}
@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxCrawlDelay caps delays requested by robots.txt so a single host can't stall a run
+const maxCrawlDelay = time.Minute
+
+// robotsCache remembers crawl delays by host for the lifetime of the process
+type robotsCache struct {
+	client *http.Client
+
+	mu     sync.Mutex
+	delays map[string]time.Duration
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{
+		client: &http.Client{Timeout: 10 * time.Second},
+		delays: make(map[string]time.Duration),
+	}
+}
+
+// crawlDelay returns the Crawl-delay for the host, 0 if robots.txt is missing or has none
+func (c *robotsCache) crawlDelay(ctx context.Context, scheme, host string) time.Duration {
+	c.mu.Lock()
+	delay, ok := c.delays[host]
+	c.mu.Unlock()
+	if ok {
+		return delay
+	}
+
+	delay = c.fetch(ctx, scheme, host)
+	c.mu.Lock()
+	c.delays[host] = delay
+	c.mu.Unlock()
+	return delay
+}
+
+func (c *robotsCache) fetch(ctx context.Context, scheme, host string) time.Duration {
+	if scheme == "" {
+		scheme = "https"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/robots.txt", nil)
+	if err != nil {
+		return 0
+	}
+	req.Header.Set("User-Agent", DefaultUserAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		slog.Debug("failed to fetch robots.txt", "host", host, "error", err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0
+	}
+
+	delay := parseCrawlDelay(io.LimitReader(resp.Body, 512*1024), robotsAgent)
+	if delay > 0 {
+		slog.Info("honoring robots.txt crawl-delay", "host", host, "delay", delay)
+	}
+	return min(delay, maxCrawlDelay)
+}
+
+// parseCrawlDelay returns the Crawl-delay of the group matching the agent,
+// falling back to the "*" group
+func parseCrawlDelay(r io.Reader, agent string) time.Duration {
+	var (
+		groupAgents []string
+		inRules     bool
+		specific    = time.Duration(-1)
+		wildcard    = time.Duration(-1)
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "crawl-delay":
+			inRules = true
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			delay := time.Duration(seconds * float64(time.Second))
+			for _, a := range groupAgents {
+				switch {
+				case a == "*":
+					wildcard = delay
+				case strings.Contains(agent, a) || strings.Contains(a, agent):
+					specific = delay
+				}
+			}
+		default:
+			inRules = true
+		}
+	}
+
+	if specific >= 0 {
+		return specific
+	}
+	return max(wildcard, 0)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/opml"
+	"github.com/scipunch/myfeed/parser"
+)
+
+// importOPML appends RSS resources from an OPML file to the config file,
+// leaving its content as it is. Feeds already present in the config are
+// skipped.
+func importOPML(cfgPath string, conf config.Config, opmlPath string) error {
+	f, err := os.Open(opmlPath)
+	if err != nil {
+		return fmt.Errorf("failed to open OPML file at '%s' with %w", opmlPath, err)
+	}
+	defer f.Close()
+
+	feeds, err := opml.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool, len(conf.Resources))
+	for _, r := range conf.Resources {
+		existing[r.FeedURL] = true
+	}
+
+	var added []config.ResourceConfig
+	for _, feed := range feeds {
+		if existing[feed.URL] {
+			slog.Debug("feed already configured, skipping", "url", feed.URL)
+			continue
+		}
+		existing[feed.URL] = true
+		added = append(added, config.ResourceConfig{
+			FeedURL:  feed.URL,
+			ParserT:  parser.Web,
+			T:        config.RSS,
+			Category: feed.Category,
+		})
+	}
+
+	if len(added) == 0 {
+		slog.Info("no new feeds to import", "found", len(feeds))
+		return nil
+	}
+	if err := appendResources(cfgPath, added); err != nil {
+		return err
+	}
+	slog.Info("imported feeds from OPML", "added", len(added), "skipped", len(feeds)-len(added))
+	return nil
+}
+
+// importedResource is the block of an imported feed in the config file
+type importedResource struct {
+	FeedURL  string `toml:"feed_url"`
+	T        string `toml:"type"`
+	ParserT  string `toml:"parser"`
+	Category string `toml:"category,omitempty"`
+}
+
+// appendResources appends a [[resources]] block per resource to the end of
+// the config file, so comments and formatting of the rest are kept. The
+// file is left untouched if it would no longer parse.
+func appendResources(cfgPath string, resources []config.ResourceConfig) error {
+	current, err := os.ReadFile(cfgPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file at '%s' with %w", cfgPath, err)
+	}
+
+	blocks := struct {
+		Resources []importedResource `toml:"resources"`
+	}{}
+	for _, r := range resources {
+		blocks.Resources = append(blocks.Resources, importedResource{
+			FeedURL:  r.FeedURL,
+			T:        string(r.T),
+			ParserT:  string(r.ParserT),
+			Category: r.Category,
+		})
+	}
+	var appended bytes.Buffer
+	if len(current) > 0 {
+		appended.WriteString("\n")
+		if !bytes.HasSuffix(current, []byte("\n")) {
+			appended.WriteString("\n")
+		}
+	}
+	enc := toml.NewEncoder(&appended)
+	enc.Indent = ""
+	if err := enc.Encode(blocks); err != nil {
+		return fmt.Errorf("failed to encode resources with %w", err)
+	}
+
+	updated := append(current, appended.Bytes()...)
+	if _, err := config.Parse(updated); err != nil {
+		return fmt.Errorf("config file at '%s' can't take new resources, e.g., they are an inline array, with %w", cfgPath, err)
+	}
+	if err := os.WriteFile(cfgPath, updated, 0644); err != nil {
+		return fmt.Errorf("failed to write into config file at '%s' with %w", cfgPath, err)
+	}
+	slog.Info("config written", "at", cfgPath)
+	return nil
+}
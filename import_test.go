@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+const importFeeds = `<?xml version="1.0"?>
+<opml version="2.0"><body>
+  <outline text="Tech">
+    <outline type="rss" text="Go" xmlUrl="https://go.dev/blog/feed.atom"/>
+    <outline type="rss" text="Known" xmlUrl="https://known.example/feed"/>
+  </outline>
+  <outline type="rss" text="News" xmlUrl="https://news.example/rss"/>
+</body></opml>`
+
+func TestImportOPML_KeepsConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.toml")
+	original := `# My feeds
+output_directory = "/tmp/out"  # where issues go
+
+[[resources]]
+feed_url = "https://known.example/feed"  # kept as is
+type = "rss"
+parser = "web"`
+	opmlPath := filepath.Join(dir, "feeds.opml")
+	if err := os.WriteFile(cfgPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(opmlPath, []byte(importFeeds), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.Read(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := importOPML(cfgPath, conf, opmlPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), original+"\n\n[[resources]]\n") {
+		t.Errorf("expected the config to be kept and resources appended, got\n%s", data)
+	}
+	updated, err := config.Read(cfgPath)
+	if err != nil {
+		t.Fatalf("failed to read the updated config: %v", err)
+	}
+	var got []string
+	for _, r := range updated.Resources {
+		got = append(got, r.FeedURL+" "+string(r.T)+" "+string(r.ParserT)+" "+r.Category)
+	}
+	want := []string{
+		"https://known.example/feed rss web ",
+		"https://go.dev/blog/feed.atom rss web Tech",
+		"https://news.example/rss rss web ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got resources\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Importing again adds nothing
+	if err := importOPML(cfgPath, updated, opmlPath); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(cfgPath); string(again) != string(data) {
+		t.Error("expected configured feeds to be skipped")
+	}
+}
+
+func TestImportOPML_InlineResources(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.toml")
+	original := `resources = [{feed_url = "https://known.example/feed", type = "rss", parser = "web"}]` + "\n"
+	opmlPath := filepath.Join(dir, "feeds.opml")
+	if err := os.WriteFile(cfgPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(opmlPath, []byte(importFeeds), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.Read(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := importOPML(cfgPath, conf, opmlPath); err == nil {
+		t.Error("expected an error for resources that can't be appended to")
+	}
+	if data, _ := os.ReadFile(cfgPath); string(data) != original {
+		t.Errorf("expected the config to be left untouched, got\n%s", data)
+	}
+}
@@ -0,0 +1,166 @@
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Unknown is returned when the language cannot be determined
+const Unknown = ""
+
+// minLetters is the minimum amount of letters required for detection
+const minLetters = 20
+
+// stopwords are frequent short words used to tell apart languages sharing a script
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "was", "on", "are", "this", "be"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "zu", "den", "mit", "sich", "auf", "für", "auch", "eine"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "un", "du", "que", "dans", "pour", "pas", "qui", "sur"},
+	"es": {"el", "la", "de", "que", "y", "los", "en", "las", "por", "un", "una", "del", "con", "es", "para"},
+	"it": {"il", "di", "che", "e", "la", "per", "un", "non", "sono", "una", "del", "della", "gli", "con", "è"},
+	"pt": {"de", "que", "não", "o", "e", "do", "da", "em", "um", "para", "com", "uma", "os", "no", "se"},
+	"ru": {"и", "в", "не", "на", "что", "с", "по", "это", "как", "к", "но", "из", "у", "за", "от"},
+	"uk": {"і", "в", "не", "на", "що", "з", "та", "це", "як", "до", "але", "із", "у", "за", "від"},
+}
+
+// Detect returns the ISO 639-1 code of the dominant language in text, or
+// Unknown when there is too little text to decide or its script is none of
+// the known ones, e.g., Greek or Hebrew
+func Detect(text string) string {
+	var latin, cyrillic, han, kana, hangul, arabic, other, letters int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		default:
+			other++
+		}
+	}
+	if letters < minLetters {
+		return Unknown
+	}
+
+	switch max(latin, cyrillic, han+kana, hangul, arabic, other) {
+	case other:
+		return Unknown
+	case kana + han:
+		if kana > 0 {
+			return "ja"
+		}
+		return "zh"
+	case hangul:
+		return "ko"
+	case arabic:
+		return "ar"
+	case cyrillic:
+		return byStopwords(text, "ru", "uk")
+	default:
+		return byStopwords(text, "en", "de", "fr", "es", "it", "pt")
+	}
+}
+
+// Matches reports whether text is written in the expected language.
+// Texts whose language cannot be detected and languages Detect doesn't
+// know, e.g., Polish, are considered matching.
+func Matches(text, expected string) bool {
+	expected = Normalize(expected)
+	if !Supported(expected) {
+		return true
+	}
+	detected := Detect(text)
+	if detected == Unknown {
+		return true
+	}
+	return detected == expected
+}
+
+// Supported reports whether Detect can recognize the language of the
+// ISO 639-1 code, other languages of a known script are detected as one
+// of these, e.g., Dutch as "en"
+func Supported(code string) bool {
+	switch code {
+	case "ja", "zh", "ko", "ar":
+		return true
+	}
+	_, ok := stopwords[code]
+	return ok
+}
+
+// Normalize maps common language names and tags with a region, e.g.,
+// "en-US", to ISO 639-1 codes
+func Normalize(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	language, _, _ = strings.Cut(strings.ReplaceAll(language, "_", "-"), "-")
+	switch language {
+	case "english":
+		return "en"
+	case "russian":
+		return "ru"
+	case "ukrainian":
+		return "uk"
+	case "german":
+		return "de"
+	case "french":
+		return "fr"
+	case "spanish":
+		return "es"
+	case "italian":
+		return "it"
+	case "portuguese":
+		return "pt"
+	case "japanese":
+		return "ja"
+	case "chinese":
+		return "zh"
+	case "korean":
+		return "ko"
+	case "arabic":
+		return "ar"
+	}
+	return language
+}
+
+// byStopwords picks the candidate with the highest stopword frequency,
+// defaulting to the first candidate on ties
+func byStopwords(text string, candidates ...string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for _, code := range candidates {
+			for _, sw := range stopwords[code] {
+				if word == sw {
+					counts[code]++
+					break
+				}
+			}
+		}
+	}
+
+	// Ukrainian specific letters are a stronger signal than shared stopwords
+	if counts["uk"] > 0 && strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+		counts["uk"] += 2
+	}
+
+	best := candidates[0]
+	for _, code := range candidates[1:] {
+		if counts[code] > counts[best] {
+			best = code
+		}
+	}
+	return best
+}
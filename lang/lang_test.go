@@ -0,0 +1,106 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "english",
+			text:     "The quick brown fox jumps over the lazy dog and this is a test of the detector.",
+			expected: "en",
+		},
+		{
+			name:     "russian",
+			text:     "Это краткое содержание статьи, и в нем нет ничего на английском языке.",
+			expected: "ru",
+		},
+		{
+			name:     "ukrainian",
+			text:     "Це короткий зміст статті, і в ньому немає нічого англійською мовою.",
+			expected: "uk",
+		},
+		{
+			name:     "german",
+			text:     "Das ist ein kurzer Text, der nicht auf Englisch geschrieben ist und auch sich selbst erklärt.",
+			expected: "de",
+		},
+		{
+			name:     "japanese",
+			text:     "これは日本語で書かれた短いテキストです。検出器のテストに使います。",
+			expected: "ja",
+		},
+		{
+			name:     "chinese",
+			text:     "这是一段用中文写的简短文字，用来测试语言检测器是否正常工作。",
+			expected: "zh",
+		},
+		{
+			name:     "greek is not detected",
+			text:     "Αυτό είναι ένα σύντομο κείμενο γραμμένο στα ελληνικά για τη δοκιμή.",
+			expected: Unknown,
+		},
+		{
+			name:     "hebrew is not detected",
+			text:     "זהו טקסט קצר שנכתב בעברית כדי לבדוק את מזהה השפה של המערכת.",
+			expected: Unknown,
+		},
+		{
+			name:     "too short",
+			text:     "Hi there",
+			expected: Unknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	ru := "Это краткое содержание статьи, и в нем нет ничего на английском языке."
+	if !Matches(ru, "ru") {
+		t.Error("expected russian text to match 'ru'")
+	}
+	if !Matches(ru, "Russian") {
+		t.Error("expected russian text to match 'Russian'")
+	}
+	if Matches(ru, "en") {
+		t.Error("expected russian text not to match 'en'")
+	}
+	if !Matches("ok", "en") {
+		t.Error("expected undetectable text to match any language")
+	}
+}
+
+func TestMatches_UnknownScript(t *testing.T) {
+	el := "Αυτό είναι ένα σύντομο κείμενο γραμμένο στα ελληνικά για τη δοκιμή."
+	if !Matches(el, "el") || !Matches(el, "zh") {
+		t.Error("expected text of an undetectable script to skip the check")
+	}
+}
+
+func TestMatches_UnsupportedLanguage(t *testing.T) {
+	pl := "To jest krótki tekst napisany po polsku, który sprawdza wykrywanie języka w systemie."
+	if !Matches(pl, "pl") || !Matches(pl, "Polish-PL") {
+		t.Error("expected a language the detector doesn't know to skip the check")
+	}
+	if Matches(pl, "de") {
+		t.Error("expected polish text not to match 'de'")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{"en": "en", "English": "en", "en-US": "en", "pt_BR": "pt", " RU ": "ru"} {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
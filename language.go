@@ -0,0 +1,28 @@
+package main
+
+import (
+	"html"
+	"slices"
+
+	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/lang"
+	"github.com/scipunch/myfeed/parser"
+)
+
+// pageLanguage returns the language of the final content of an item: the
+// output language when agents rewrote it, the detected one otherwise and
+// the language stated by the source when the text is too short to tell
+func pageLanguage(content string, resource config.ResourceConfig, parsed parser.Response) string {
+	rewritten := slices.ContainsFunc(resource.Agents, func(name string) bool { return name != agent.Discussion && name != agent.Relevance })
+	if resource.OutputLang != "" && rewritten {
+		return lang.Normalize(resource.OutputLang)
+	}
+	if detected := lang.Detect(html.UnescapeString(htmlTagRe.ReplaceAllString(content, " "))); detected != lang.Unknown {
+		return detected
+	}
+	if l, ok := parsed.(parser.Language); ok {
+		return lang.Normalize(l.Language())
+	}
+	return lang.Unknown
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+var imgTagRe = regexp.MustCompile(`(?i)<img\b`)
+
+// Printable area of a B5 page with 15mm margins at 96 DPI
+const (
+	printableWidthPx  = 552 // 146mm
+	printableHeightPx = 831 // 220mm
+)
+
+// trimToPageBudgetJS removes articles (and their TOC entries) which end
+// past the page budget. The TOC is printed on its own pages. The first
+// article is always kept, so a single long article still gets printed.
+const trimToPageBudgetJS = `({maxPages, pageHeight}) => {
+	const bottom = (el) => el.getBoundingClientRect().bottom + window.scrollY;
+	const toc = document.querySelector(".toc");
+	const contentStart = toc ? bottom(toc) : 0;
+	const tocPages = Math.ceil(contentStart / pageHeight);
+	const budget = (maxPages - tocPages) * pageHeight;
+	const overflow = [];
+	let kept = 0;
+	for (const article of document.querySelectorAll("article.article")) {
+		if (overflow.length > 0 || (kept > 0 && bottom(article) - contentStart > budget)) {
+			overflow.push(article.id);
+			continue;
+		}
+		kept++;
+	}
+	for (const id of overflow) {
+		document.getElementById(id).remove();
+		const link = document.querySelector(".toc a[href='#" + id + "']");
+		if (link) link.closest("li").remove();
+	}
+	for (const res of document.querySelectorAll(".toc-resource")) {
+		if (!res.querySelector("li")) res.remove();
+	}
+	return overflow;
+}`
+
+// splitByLimits keeps pages in the issue until the item or image limit is
+// reached and moves every following page into the appendix issue. The issue
+// keeps its first page even when it is over the limits alone. Resource order
+// is preserved in both issues.
+func splitByLimits(n Newsletter, limits config.Limits) (Newsletter, Newsletter) {
+	issue := n
+	issue.Resources = nil
+	appendix := Newsletter{Title: n.Title + " — Appendix", Fonts: n.Fonts}
+
+	items, images := 0, 0
+	overflow := false
+	for _, res := range n.Resources {
+		kept := res
+		kept.Pages = nil
+		moved := kept
+		for _, page := range res.Pages {
+			pageImages := countImages(string(page.Content))
+			if !overflow && items > 0 {
+				if limits.MaxItems > 0 && items+1 > limits.MaxItems {
+					overflow = true
+				}
+				if limits.MaxImages > 0 && images+pageImages > limits.MaxImages {
+					overflow = true
+				}
+			}
+			if overflow {
+				moved.Pages = append(moved.Pages, page)
+				continue
+			}
+			items++
+			images += pageImages
+			kept.Pages = append(kept.Pages, page)
+		}
+		if len(kept.Pages) > 0 {
+			issue.Resources = append(issue.Resources, kept)
+		}
+		if len(moved.Pages) > 0 {
+			appendix.Resources = append(appendix.Resources, moved)
+		}
+	}
+
+	return issue, appendix
+}
+
+// moveToAppendix moves pages with the given IDs from the issue to the appendix
+func moveToAppendix(issue, appendix Newsletter, ids []string) (Newsletter, Newsletter) {
+	if len(ids) == 0 {
+		return issue, appendix
+	}
+	overflow := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		overflow[id] = true
+	}
+
+	kept := issue
+	kept.Resources = nil
+	moved := appendix
+	moved.Resources = nil
+	for _, res := range issue.Resources {
+		k := res
+		k.Pages = nil
+		m := k
+		for _, page := range res.Pages {
+			if overflow[page.ID] {
+				m.Pages = append(m.Pages, page)
+			} else {
+				k.Pages = append(k.Pages, page)
+			}
+		}
+		if len(k.Pages) > 0 {
+			kept.Resources = append(kept.Resources, k)
+		}
+		if len(m.Pages) > 0 {
+			moved.Resources = append(moved.Resources, m)
+		}
+	}
+	// Pages already in the appendix come after the ones overflowing by size
+	moved.Resources = append(moved.Resources, appendix.Resources...)
+
+	return kept, moved
+}
+
+// countImages counts <img> tags in rendered content
+func countImages(content string) int {
+	return len(imgTagRe.FindAllStringIndex(content, -1))
+}
+
+// pageCount returns the total number of pages in the issue
+func (n Newsletter) pageCount() int {
+	total := 0
+	for _, res := range n.Resources {
+		total += len(res.Pages)
+	}
+	return total
+}
+
+// trimToPageBudget estimates where each article lands in the printed layout
+// and removes the ones that do not fit into maxPages, returning their IDs
+func trimToPageBudget(page playwright.Page, maxPages int) ([]string, error) {
+	if err := page.EmulateMedia(playwright.PageEmulateMediaOptions{Media: playwright.MediaPrint}); err != nil {
+		return nil, fmt.Errorf("could not emulate print media: %w", err)
+	}
+	if err := page.SetViewportSize(printableWidthPx, printableHeightPx); err != nil {
+		return nil, fmt.Errorf("could not set viewport: %w", err)
+	}
+
+	result, err := page.Evaluate(trimToPageBudgetJS, map[string]any{
+		"maxPages":   maxPages,
+		"pageHeight": printableHeightPx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not measure articles: %w", err)
+	}
+
+	raw, ok := result.([]any)
+	if !ok {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if id, ok := v.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// limitsIssue has five pages in two resources, a2 with two images and b1 with one
+func limitsIssue() Newsletter {
+	page := func(id string, images int) Page {
+		return Page{ID: id, Content: template.HTML(strings.Repeat(`<img src="a.png">`, images))}
+	}
+	return Newsletter{
+		Title: "Issue",
+		Stats: &IssueStats{Items: 5},
+		Resources: []Resource{
+			{Name: "Blog", Category: "Tech", feed: 1, route: 2, Pages: []Page{page("a1", 0), page("a2", 2), page("a3", 0)}},
+			{Name: "Notes", Pinned: true, feed: 3, Pages: []Page{page("b1", 1), page("b2", 0)}},
+		},
+	}
+}
+
+// pageIDs lists the page IDs of every resource, e.g., "Blog:a1,a2 Notes:b1"
+func pageIDs(n Newsletter) string {
+	var parts []string
+	for _, res := range n.Resources {
+		var ids []string
+		for _, page := range res.Pages {
+			ids = append(ids, page.ID)
+		}
+		parts = append(parts, res.Name+":"+strings.Join(ids, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestSplitByLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   config.Limits
+		issue    string
+		appendix string
+	}{
+		{
+			name:  "no limits",
+			issue: "Blog:a1,a2,a3 Notes:b1,b2",
+		},
+		{
+			name:     "items",
+			limits:   config.Limits{MaxItems: 4},
+			issue:    "Blog:a1,a2,a3 Notes:b1",
+			appendix: "Notes:b2",
+		},
+		{
+			name:     "images, later pages follow the first one over the limit",
+			limits:   config.Limits{MaxImages: 1},
+			issue:    "Blog:a1",
+			appendix: "Blog:a2,a3 Notes:b1,b2",
+		},
+		{
+			name:     "first limit reached",
+			limits:   config.Limits{MaxItems: 10, MaxImages: 2},
+			issue:    "Blog:a1,a2,a3",
+			appendix: "Notes:b1,b2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue, appendix := splitByLimits(limitsIssue(), tt.limits)
+			if got := pageIDs(issue); got != tt.issue {
+				t.Errorf("issue = %q, want %q", got, tt.issue)
+			}
+			if got := pageIDs(appendix); got != tt.appendix {
+				t.Errorf("appendix = %q, want %q", got, tt.appendix)
+			}
+			if issue.Stats == nil || appendix.Stats != nil || appendix.Title != "Issue — Appendix" {
+				t.Error("expected the footer to stay with the issue")
+			}
+		})
+	}
+}
+
+func TestSplitByLimits_KeepsResourceFields(t *testing.T) {
+	issue, appendix := splitByLimits(limitsIssue(), config.Limits{MaxImages: 1})
+	want := limitsIssue().Resources[0]
+	want.Pages = nil
+	for _, res := range []Resource{issue.Resources[0], appendix.Resources[0]} {
+		res.Pages = nil
+		if !reflect.DeepEqual(res, want) {
+			t.Errorf("got %+v, want %+v", res, want)
+		}
+	}
+}
+
+func TestSplitByLimits_KeepsFirstPage(t *testing.T) {
+	n := limitsIssue()
+	n.Resources = n.Resources[:1]
+	n.Resources[0].Pages = n.Resources[0].Pages[1:]
+
+	// a2 alone is over the image limit, splitting again still moves on
+	var parts []string
+	for rest := n; rest.pageCount() > 0; {
+		var part Newsletter
+		part, rest = splitByLimits(rest, config.Limits{MaxItems: 1, MaxImages: 1})
+		if part.pageCount() == 0 {
+			t.Fatal("expected every part to keep a page")
+		}
+		parts = append(parts, pageIDs(part))
+	}
+	if got, want := strings.Join(parts, " "), "Blog:a2 Blog:a3"; got != want {
+		t.Errorf("parts = %q, want %q", got, want)
+	}
+}
+
+func TestMoveToAppendix(t *testing.T) {
+	issue, appendix := splitByLimits(limitsIssue(), config.Limits{MaxItems: 4})
+	issue, appendix = moveToAppendix(issue, appendix, []string{"a3", "b1"})
+	if got, want := pageIDs(issue), "Blog:a1,a2"; got != want {
+		t.Errorf("issue = %q, want %q", got, want)
+	}
+	// Pages overflowing by size come before those already in the appendix
+	if got, want := pageIDs(appendix), "Blog:a3 Notes:b1 Notes:b2"; got != want {
+		t.Errorf("appendix = %q, want %q", got, want)
+	}
+	if appendix.Title != "Issue — Appendix" || issue.Stats == nil {
+		t.Error("expected titles and the footer to be kept")
+	}
+	if res := appendix.Resources[1]; res.feed != 3 || !res.Pinned {
+		t.Errorf("expected resource fields to be kept, got %+v", res)
+	}
+}
+
+func TestMoveToAppendix_NothingToMove(t *testing.T) {
+	issue, appendix := splitByLimits(limitsIssue(), config.Limits{})
+	kept, moved := moveToAppendix(issue, appendix, nil)
+	if pageIDs(kept) != pageIDs(issue) || moved.pageCount() != 0 {
+		t.Error("expected the issue to be left as it is")
+	}
+}
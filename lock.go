@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// lockPoll is how often a waiting run retries the lock
+const lockPoll = 500 * time.Millisecond
+
+// errRunActive is returned while another run of the same config holds the lock
+var errRunActive = errors.New("another run is active")
+
+// runLock is an advisory lock held for the whole run, so overlapping runs of
+// one config, e.g., a slow cron job, don't corrupt the output or spend tokens
+// on the same items twice. The lock is released when the process exits.
+type runLock struct {
+	f *os.File
+}
+
+// lockPath returns the lock file of the config, next to its database
+func lockPath(dbPath string) string {
+	return dbPath + ".lock"
+}
+
+// acquireRunLock takes the lock at path. When another run holds it, wait
+// blocks until that run finishes, otherwise errRunActive is returned.
+func acquireRunLock(ctx context.Context, path string, wait bool) (*runLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file with %w", err)
+	}
+	logged := false
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock '%s' with %w", path, err)
+		}
+		if locked {
+			break
+		}
+		holder := lockHolder(f)
+		if !wait {
+			f.Close()
+			return nil, fmt.Errorf("%w (%s), pass -wait to run after it", errRunActive, holder)
+		}
+		if !logged {
+			slog.Info("another run is active, waiting for it to finish", "holder", holder, "lock", path)
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockPoll):
+		}
+	}
+
+	// Tell runs started meanwhile who holds the lock
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "pid %d since %s\n", os.Getpid(), time.Now().Format(time.DateTime))
+	}
+	return &runLock{f: f}, nil
+}
+
+// lockHolder describes the run holding the lock
+func lockHolder(f *os.File) string {
+	buf := make([]byte, 128)
+	n, _ := f.ReadAt(buf, 0)
+	if holder := strings.TrimSpace(string(buf[:n])); holder != "" {
+		return holder
+	}
+	return "unknown process"
+}
+
+// release frees the lock for the next run
+func (l *runLock) release() error {
+	l.f.Truncate(0)
+	return l.f.Close()
+}
+
+// concurrentCommand reports whether the command may run next to a
+// generation: it only reads the database or adds pins, which a generation
+// loads after processing and marks by id
+func concurrentCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "drafts", "suggest", "pin", "unpin":
+		return true
+	case "db":
+		return len(args) > 1 && args[1] == "sent"
+	}
+	return false
+}
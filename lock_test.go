@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunLock(t *testing.T) {
+	path := lockPath(filepath.Join(t.TempDir(), "data.db"))
+	lock, err := acquireRunLock(context.Background(), path, false)
+	if err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	_, err = acquireRunLock(context.Background(), path, false)
+	if !errors.Is(err, errRunActive) {
+		t.Fatalf("expected errRunActive, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "pid ") {
+		t.Errorf("expected the error to name the holder, got %v", err)
+	}
+
+	// Waiting runs start once the holder is done
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		lock.release()
+	}()
+	next, err := acquireRunLock(context.Background(), path, true)
+	if err != nil {
+		t.Fatalf("failed to wait for lock: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := acquireRunLock(ctx, path, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected waiting to stop with the context, got %v", err)
+	}
+
+	if err := next.release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 0 {
+		t.Errorf("expected released lock to be empty, got %q", data)
+	}
+}
+
+func TestConcurrentCommand(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"fetch"}, false},
+		{[]string{"db", "prune"}, false},
+		{[]string{"db", "sent"}, true},
+		{[]string{"drafts"}, true},
+		{[]string{"suggest", "-n", "5"}, true},
+		{[]string{"pin", "Renew domain"}, true},
+	} {
+		if got := concurrentCommand(tt.args); got != tt.want {
+			t.Errorf("concurrentCommand(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dkimHeaders lists the headers covered by the signature, when present
+var dkimHeaders = []string{"From", "Reply-To", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+
+var wspRe = regexp.MustCompile(`[ \t]+`)
+
+// DKIMSigner signs messages so receivers can verify them against the public
+// key published in DNS at <selector>._domainkey.<domain>
+type DKIMSigner struct {
+	domain   string
+	selector string
+	key      crypto.Signer
+}
+
+// LoadDKIM reads a PEM encoded RSA or Ed25519 private key (PKCS#1 or PKCS#8)
+func LoadDKIM(domain, selector, keyPath string) (*DKIMSigner, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DKIM key at '%s' with %w", keyPath, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in DKIM key at '%s'", keyPath)
+	}
+
+	var key crypto.Signer
+	if rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		key = rsaKey
+	} else {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DKIM key at '%s' with %w", keyPath, err)
+		}
+		switch k := parsed.(type) {
+		case *rsa.PrivateKey:
+			key = k
+		case ed25519.PrivateKey:
+			key = k
+		default:
+			return nil, fmt.Errorf("unsupported DKIM key type %T, use RSA or Ed25519", parsed)
+		}
+	}
+	return NewDKIMSigner(domain, selector, key)
+}
+
+// NewDKIMSigner creates a signer from an RSA or Ed25519 private key
+func NewDKIMSigner(domain, selector string, key crypto.Signer) (*DKIMSigner, error) {
+	if domain == "" || selector == "" {
+		return nil, errors.New("DKIM domain and selector are required")
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return nil, fmt.Errorf("unsupported DKIM key type %T, use RSA or Ed25519", key)
+	}
+	return &DKIMSigner{domain: domain, selector: selector, key: key}, nil
+}
+
+// Sign prepends a DKIM-Signature header (RFC 6376, relaxed/relaxed
+// canonicalization) to the raw message
+func (s *DKIMSigner) Sign(msg []byte) ([]byte, error) {
+	rawHeader, body, ok := bytes.Cut(msg, []byte("\r\n\r\n"))
+	if !ok {
+		return nil, errors.New("message has no body")
+	}
+	bodyHash := sha256.Sum256(canonicalBody(body))
+
+	headers := parseHeaders(string(rawHeader) + "\r\n")
+	var signed []string
+	var canonical strings.Builder
+	for _, name := range dkimHeaders {
+		if value, ok := headers[strings.ToLower(name)]; ok {
+			signed = append(signed, strings.ToLower(name))
+			canonical.WriteString(canonicalHeader(name, value))
+		}
+	}
+
+	algorithm := "rsa-sha256"
+	if _, ok := s.key.(ed25519.PrivateKey); ok {
+		algorithm = "ed25519-sha256"
+	}
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		algorithm,
+		s.domain,
+		s.selector,
+		time.Now().Unix(),
+		strings.Join(signed, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+	// The signature header itself is signed with an empty b= and no trailing CRLF
+	canonical.WriteString(strings.TrimSuffix(canonicalHeader("DKIM-Signature", value), "\r\n"))
+
+	digest := sha256.Sum256([]byte(canonical.String()))
+	var opts crypto.SignerOpts = crypto.SHA256
+	if algorithm == "ed25519-sha256" {
+		opts = crypto.Hash(0) // Ed25519 signs the SHA-256 digest itself (RFC 8463)
+	}
+	signature, err := s.key.Sign(rand.Reader, digest[:], opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign message with %w", err)
+	}
+
+	header := "DKIM-Signature: " + value + base64.StdEncoding.EncodeToString(signature) + "\r\n"
+	return append([]byte(header), msg...), nil
+}
+
+// parseHeaders returns unfolded header values by lowercased name.
+// Only the last instance of a repeated header is kept.
+func parseHeaders(raw string) map[string]string {
+	headers := make(map[string]string)
+	raw = strings.ReplaceAll(raw, "\r\n ", " ")
+	raw = strings.ReplaceAll(raw, "\r\n\t", "\t")
+	for _, line := range strings.Split(raw, "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		headers[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return headers
+}
+
+// canonicalHeader applies the relaxed header canonicalization
+func canonicalHeader(name, value string) string {
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = strings.TrimSpace(wspRe.ReplaceAllString(value, " "))
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + value + "\r\n"
+}
+
+// canonicalBody applies the relaxed body canonicalization
+func canonicalBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(wspRe.ReplaceAllString(line, " "), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
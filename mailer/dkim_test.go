@@ -0,0 +1,98 @@
+package mailer
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalBody(t *testing.T) {
+	// Example from RFC 6376, section 3.4.5
+	got := string(canonicalBody([]byte(" C \r\nD \t E\r\n\r\n\r\n")))
+	if got != " C\r\nD E\r\n" {
+		t.Errorf("unexpected canonical body %q", got)
+	}
+	if canonicalBody([]byte("\r\n\r\n")) != nil {
+		t.Error("expected empty body to canonicalize to nothing")
+	}
+}
+
+func TestCanonicalHeader(t *testing.T) {
+	// Example from RFC 6376, section 3.4.5
+	headers := parseHeaders("A: X\r\nB : Y\t\r\n\tZ  \r\n")
+	got := canonicalHeader("A", headers["a"]) + canonicalHeader("B ", headers["b"])
+	if got != "a:X\r\nb:Y Z\r\n" {
+		t.Errorf("unexpected canonical headers %q", got)
+	}
+}
+
+func TestDKIMSigner_Sign(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("From: feed@example.com\r\nTo: me@example.com\r\nSubject:  Issue \r\n\r\n<p>Hello</p>  \r\n\r\n")
+	tests := []struct {
+		name   string
+		key    crypto.Signer
+		verify func(digest, sig []byte) error
+	}{
+		{"rsa", rsaKey, func(digest, sig []byte) error {
+			return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest, sig)
+		}},
+		{"ed25519", edKey, func(digest, sig []byte) error {
+			if !ed25519.Verify(edPub, digest, sig) {
+				return rsa.ErrVerification
+			}
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewDKIMSigner("example.com", "myfeed", tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := signer.Sign(msg)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			header, rest, _ := strings.Cut(string(signed), "\r\n")
+			if rest != string(msg) {
+				t.Fatal("expected the message to follow the signature header")
+			}
+			value := strings.TrimPrefix(header, "DKIM-Signature: ")
+			if !strings.Contains(value, "d=example.com; s=myfeed;") || !strings.Contains(value, "h=from:to:subject;") {
+				t.Errorf("unexpected signature tags %q", value)
+			}
+
+			bodyHash := sha256.Sum256([]byte("<p>Hello</p>\r\n"))
+			if !strings.Contains(value, "bh="+base64.StdEncoding.EncodeToString(bodyHash[:])) {
+				t.Errorf("unexpected body hash in %q", value)
+			}
+
+			idx := strings.LastIndex(value, "; b=") + len("; b=")
+			unsigned, sig := value[:idx], value[idx:]
+			signature, err := base64.StdEncoding.DecodeString(sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data := "from:feed@example.com\r\nto:me@example.com\r\nsubject:Issue\r\n" + "dkim-signature:" + unsigned
+			digest := sha256.Sum256([]byte(data))
+			if err := tt.verify(digest[:], signature); err != nil {
+				t.Errorf("signature does not verify: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// elementStyles mirrors the article content CSS of the browser template,
+// email clients drop <style> blocks so it is applied to every element inline
+var elementStyles = map[atom.Atom]string{
+	atom.H1:         "font-size:1.6em;margin:1em 0 0.5em 0;",
+	atom.H2:         "font-size:1.4em;margin:1em 0 0.5em 0;",
+	atom.H3:         "font-size:1.2em;margin:1em 0 0.5em 0;",
+	atom.P:          "margin:0 0 1em 0;",
+	atom.Ul:         "margin:0 0 1em 0;padding-left:2em;",
+	atom.Ol:         "margin:0 0 1em 0;padding-left:2em;",
+	atom.Pre:        "background:#f5f5f5;padding:1em;border-radius:4px;white-space:pre-wrap;word-break:break-word;",
+	atom.Code:       "background:#f5f5f5;padding:0.2em 0.4em;border-radius:3px;font-size:0.9em;",
+	atom.Blockquote: "border-left:4px solid #ddd;padding-left:1em;margin-left:0;font-style:italic;",
+	atom.Img:        "max-width:100%;height:auto;display:block;margin:1em 0;border:0;",
+	atom.A:          "color:#1f2937;",
+}
+
+// classStyles holds styles of the classes produced by parsers and source rules
+var classStyles = map[string]string{
+	"back-reference": "font-style:italic;color:#6b7280;",
+	"byline":         "font-size:0.85em;color:#6b7280;",
+	"forwarded":      "font-size:0.85em;color:#6b7280;border-left:3px solid #d1d5db;padding-left:0.5em;",
+	"attachment":     "font-size:0.9em;background:#f3f4f6;padding:0.5em 0.75em;border-radius:4px;",
+	"comments":       "border-top:1px solid #e5e7eb;margin-top:1em;padding-top:0.5em;",
+	"comment":        "margin-bottom:0.75em;",
+	"hl-keyword":     "color:#8839ef;font-weight:bold;",
+	"hl-string":      "color:#40802b;",
+	"hl-comment":     "color:#6b7280;font-style:italic;",
+	"hl-number":      "color:#c2410c;",
+	"widget-detail":  "font-size:0.85em;color:#6b7280;",
+}
+
+// Inliner rewrites HTML fragments for email clients: CSS is moved into style
+// attributes and local images are embedded into the message
+type Inliner struct {
+	// OmitImages replaces local images with a note instead of embedding them,
+	// used when the message would exceed the provider's size limit
+	OmitImages bool
+
+	baseDir string
+	images  map[string]Attachment // By the original src
+	order   []string
+}
+
+// NewInliner creates an inliner resolving relative image paths against baseDir
+func NewInliner(baseDir string) *Inliner {
+	return &Inliner{
+		baseDir: baseDir,
+		images:  make(map[string]Attachment),
+	}
+}
+
+// Inline returns the fragment with styles inlined and local images
+// referenced by their Content-ID
+func (in *Inliner) Inline(fragment string) (string, error) {
+	if strings.TrimSpace(fragment) == "" {
+		return fragment, nil
+	}
+	parent := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), parent)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML fragment with %w", err)
+	}
+
+	for _, node := range nodes {
+		parent.AppendChild(node)
+	}
+	if err := in.rewrite(parent); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for node := parent.FirstChild; node != nil; node = node.NextSibling {
+		if err := html.Render(&b, node); err != nil {
+			return "", fmt.Errorf("failed to render HTML fragment with %w", err)
+		}
+	}
+	return b.String(), nil
+}
+
+// Attachments returns the embedded images in the order they were first referenced
+func (in *Inliner) Attachments() []Attachment {
+	attachments := make([]Attachment, 0, len(in.order))
+	for _, src := range in.order {
+		attachments = append(attachments, in.images[src])
+	}
+	return attachments
+}
+
+func (in *Inliner) rewrite(node *html.Node) error {
+	if node.Type == html.ElementNode {
+		applyStyles(node)
+		if node.DataAtom == atom.Img {
+			if in.OmitImages {
+				omitImage(node)
+				return nil
+			}
+			if err := in.embedImage(node); err != nil {
+				return err
+			}
+		}
+	}
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling // The child may be replaced
+		if err := in.rewrite(child); err != nil {
+			return err
+		}
+		child = next
+	}
+	return nil
+}
+
+// omitImage replaces a local image with a note keeping its alt text
+func omitImage(node *html.Node) {
+	var src, alt string
+	for _, attr := range node.Attr {
+		switch attr.Key {
+		case "src":
+			src = attr.Val
+		case "alt":
+			alt = attr.Val
+		}
+	}
+	if !isLocal(src) || node.Parent == nil {
+		return
+	}
+
+	note := "[Image omitted to fit the email size limit]"
+	if alt != "" {
+		note = fmt.Sprintf("[Image omitted to fit the email size limit: %s]", alt)
+	}
+	replacement := &html.Node{
+		Type:     html.ElementNode,
+		Data:     "em",
+		DataAtom: atom.Em,
+		Attr:     []html.Attribute{{Key: "style", Val: "color:#6b7280;"}},
+	}
+	replacement.AppendChild(&html.Node{Type: html.TextNode, Data: note})
+	node.Parent.InsertBefore(replacement, node)
+	node.Parent.RemoveChild(node)
+}
+
+// applyStyles prepends element and class styles so the node's own style wins
+func applyStyles(node *html.Node) {
+	style := elementStyles[node.DataAtom]
+	idx := -1
+	for i, attr := range node.Attr {
+		switch attr.Key {
+		case "class":
+			for _, class := range strings.Fields(attr.Val) {
+				style += classStyles[class]
+			}
+		case "style":
+			idx = i
+		}
+	}
+	if style == "" {
+		return
+	}
+	if idx < 0 {
+		node.Attr = append(node.Attr, html.Attribute{Key: "style", Val: style})
+		return
+	}
+	node.Attr[idx].Val = style + node.Attr[idx].Val
+}
+
+// embedImage replaces the src of a local image with a cid: reference.
+// Remote images are left as is.
+func (in *Inliner) embedImage(node *html.Node) error {
+	for i, attr := range node.Attr {
+		if attr.Key != "src" || !isLocal(attr.Val) {
+			continue
+		}
+		image, ok := in.images[attr.Val]
+		if !ok {
+			data, err := os.ReadFile(filepath.Join(in.baseDir, filepath.FromSlash(attr.Val)))
+			if err != nil {
+				return fmt.Errorf("failed to read image '%s' with %w", attr.Val, err)
+			}
+			image = Attachment{
+				Filename:  filepath.Base(attr.Val),
+				ContentID: fmt.Sprintf("image%d@myfeed", len(in.order)+1),
+				Data:      data,
+			}
+			in.images[attr.Val] = image
+			in.order = append(in.order, attr.Val)
+		}
+		node.Attr[i].Val = "cid:" + image.ContentID
+	}
+	return nil
+}
+
+// isLocal reports whether src points to a file next to the issue
+func isLocal(src string) bool {
+	if src == "" || strings.HasPrefix(src, "/") {
+		return false
+	}
+	return !strings.Contains(src, ":")
+}
@@ -0,0 +1,242 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// DefaultPort is the SMTP submission port used when none is configured
+const DefaultPort = 587
+
+// Attachment is an image embedded into the message and referenced via cid:
+type Attachment struct {
+	Filename  string
+	ContentID string
+	Data      []byte
+}
+
+// Message is an HTML email with its inline images
+type Message struct {
+	ID      string // Message-ID, generated when empty
+	Subject string
+	HTML    string
+	Inline  []Attachment
+}
+
+// Receipt describes a delivered (or failed) message for the sent-mail log
+type Receipt struct {
+	MessageID string
+	Size      int
+	Attempts  int
+}
+
+// Bytes encodes the message as multipart/related MIME ready for SMTP
+func (m Message) Bytes(cfg config.Email) ([]byte, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	id := m.ID
+	if id == "" {
+		id = messageID(cfg.From)
+	}
+	header := []string{
+		"From: " + cfg.From,
+		"To: " + strings.Join(cfg.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + id,
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/related; boundary=%q", w.Boundary()),
+	}
+	if cfg.ReplyTo != "" {
+		header = append(header, "Reply-To: "+cfg.ReplyTo)
+	}
+	var msg bytes.Buffer
+	msg.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTML part with %w", err)
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(m.HTML)); err != nil {
+		return nil, fmt.Errorf("failed to encode HTML part with %w", err)
+	}
+	if err := qp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode HTML part with %w", err)
+	}
+
+	for _, a := range m.Inline {
+		contentType := mime.TypeByExtension(filepath.Ext(a.Filename))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-ID":                {"<" + a.ContentID + ">"},
+			"Content-Disposition":       {mime.FormatMediaType("inline", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create image part with %w", err)
+		}
+		if err := writeBase64(part, a.Data); err != nil {
+			return nil, fmt.Errorf("failed to encode image '%s' with %w", a.Filename, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish message with %w", err)
+	}
+	msg.Write(buf.Bytes())
+	return msg.Bytes(), nil
+}
+
+// Size returns the encoded size of the message in bytes, the sender and
+// recipient headers aside
+func (m Message) Size() (int, error) {
+	raw, err := m.Bytes(config.Email{})
+	if err != nil {
+		return 0, err
+	}
+	return len(raw), nil
+}
+
+// Send delivers the message to all configured recipients, signing it with
+// DKIM when configured. The connection is upgraded with STARTTLS when the
+// server supports it. Temporary failures are retried with exponential backoff.
+func Send(ctx context.Context, cfg config.Email, m Message) (Receipt, error) {
+	if m.ID == "" {
+		m.ID = messageID(cfg.From)
+	}
+	receipt := Receipt{MessageID: m.ID}
+
+	body, err := m.Bytes(cfg)
+	if err != nil {
+		return receipt, err
+	}
+	if cfg.DKIM.Enabled() {
+		signer, err := LoadDKIM(cfg.DKIM.Domain, cfg.DKIM.Selector, cfg.DKIM.PrivateKey)
+		if err != nil {
+			return receipt, err
+		}
+		if body, err = signer.Sign(body); err != nil {
+			return receipt, err
+		}
+	}
+	receipt.Size = len(body)
+
+	port := cfg.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	recipients := make([]string, len(cfg.To))
+	for i, to := range cfg.To {
+		recipients[i] = bareAddress(to)
+	}
+
+	backoff := cfg.RetryBackoff()
+	maxRetries := cfg.MaxRetries()
+	for {
+		receipt.Attempts++
+		err := sendMail(addr, auth, bareAddress(cfg.From), recipients, body)
+		if err == nil {
+			return receipt, nil
+		}
+		if !isTemporary(err) || receipt.Attempts > maxRetries {
+			return receipt, fmt.Errorf("failed to send email via %s after %d attempts with %w", addr, receipt.Attempts, err)
+		}
+
+		slog.Warn("email delivery failed, retrying",
+			"attempt", receipt.Attempts,
+			"max_attempts", maxRetries+1,
+			"retry_in", backoff,
+			"error", err)
+
+		select {
+		case <-ctx.Done():
+			return receipt, fmt.Errorf("email delivery cancelled during backoff: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// sendMail is swapped in tests
+var sendMail = smtp.SendMail
+
+// isTemporary reports whether a delivery error may succeed on retry:
+// 4xx SMTP replies and network failures. 5xx replies are permanent.
+func isTemporary(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code >= 400 && protoErr.Code < 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF)
+}
+
+// bareAddress extracts the envelope address from a header value,
+// e.g., "myfeed <feed@example.com>"
+func bareAddress(value string) string {
+	if addr, err := mail.ParseAddress(value); err == nil {
+		return addr.Address
+	}
+	return value
+}
+
+// writeBase64 writes data base64-encoded in lines of 76 characters
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := w.Write([]byte(encoded[:n] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// messageID generates a unique Message-ID in the sender's domain
+func messageID(from string) string {
+	domain := "myfeed"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain)
+}
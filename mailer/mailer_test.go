@@ -0,0 +1,190 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestInline_AppliesStyles(t *testing.T) {
+	in := NewInliner(t.TempDir())
+	got, err := in.Inline(`<p class="byline">By me</p><p style="color:red">Text</p>`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, `<p class="byline" style="margin:0 0 1em 0;font-size:0.85em;color:#6b7280;">`) {
+		t.Errorf("expected element and class styles, got %s", got)
+	}
+	if !strings.Contains(got, `style="margin:0 0 1em 0;color:red"`) {
+		t.Errorf("expected own style to come last, got %s", got)
+	}
+}
+
+func TestInline_EmbedsLocalImages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "media"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "media", "a.jpg"), []byte("jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	in := NewInliner(dir)
+	got, err := in.Inline(`<img src="media/a.jpg"><img src="media/a.jpg"><img src="https://example.com/b.png">`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Count(got, `src="cid:image1@myfeed"`) != 2 {
+		t.Errorf("expected local image to be referenced by cid twice, got %s", got)
+	}
+	if !strings.Contains(got, `src="https://example.com/b.png"`) {
+		t.Errorf("expected remote image to be kept, got %s", got)
+	}
+	attachments := in.Attachments()
+	if len(attachments) != 1 || string(attachments[0].Data) != "jpeg" || attachments[0].Filename != "a.jpg" {
+		t.Errorf("expected a single embedded image, got %+v", attachments)
+	}
+}
+
+func TestMessage_Bytes(t *testing.T) {
+	m := Message{
+		Subject: "Issue №1",
+		HTML:    `<p>Hello</p>`,
+		Inline:  []Attachment{{Filename: "a.png", ContentID: "image1@myfeed", Data: []byte("png")}},
+	}
+	raw, err := m.Bytes(config.Email{
+		From:    "myfeed <feed@example.com>",
+		To:      []string{"me@example.com"},
+		ReplyTo: "me@example.com",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Issue №1" {
+		t.Errorf("unexpected subject %q (%v)", subject, err)
+	}
+	if msg.Header.Get("Reply-To") != "me@example.com" {
+		t.Errorf("unexpected Reply-To %q", msg.Header.Get("Reply-To"))
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("expected Message-ID in the sender domain, got %q", msg.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" {
+		t.Fatalf("unexpected content type %q (%v)", mediaType, err)
+	}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+
+	html, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("failed to read HTML part: %v", err)
+	}
+	body, _ := io.ReadAll(html) // Quoted-printable is decoded by the reader
+	if string(body) != "<p>Hello</p>" {
+		t.Errorf("unexpected HTML part %q", body)
+	}
+
+	image, err := r.NextPart()
+	if err != nil {
+		t.Fatalf("failed to read image part: %v", err)
+	}
+	if image.Header.Get("Content-ID") != "<image1@myfeed>" || image.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("unexpected image headers %v", image.Header)
+	}
+}
+
+func TestInline_OmitsImages(t *testing.T) {
+	in := NewInliner(t.TempDir())
+	in.OmitImages = true
+	got, err := in.Inline(`<img src="media/a.jpg" alt="Cat"><p><img src="https://example.com/b.png"></p>`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "Image omitted to fit the email size limit: Cat") || strings.Contains(got, "media/a.jpg") {
+		t.Errorf("expected local image to be replaced, got %s", got)
+	}
+	if !strings.Contains(got, `src="https://example.com/b.png"`) {
+		t.Errorf("expected remote image to be kept, got %s", got)
+	}
+	if len(in.Attachments()) != 0 {
+		t.Errorf("expected no embedded images, got %d", len(in.Attachments()))
+	}
+}
+
+func TestSend_RetriesTemporaryFailures(t *testing.T) {
+	var calls int
+	var gotFrom string
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		calls++
+		gotFrom = from
+		if calls < 3 {
+			return &textproto.Error{Code: 451, Msg: "try again later"}
+		}
+		return nil
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	retries := 3
+	cfg := config.Email{
+		Host:    "smtp.example.com",
+		From:    "myfeed <feed@example.com>",
+		To:      []string{"me@example.com"},
+		Retries: &retries,
+		Backoff: config.Duration{Duration: time.Millisecond},
+	}
+	receipt, err := Send(context.Background(), cfg, Message{Subject: "Issue", HTML: "<p>Hi</p>"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receipt.Attempts != 3 || calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", receipt.Attempts)
+	}
+	if gotFrom != "feed@example.com" {
+		t.Errorf("expected bare envelope sender, got %q", gotFrom)
+	}
+	if receipt.MessageID == "" || receipt.Size == 0 {
+		t.Errorf("expected receipt to describe the message, got %+v", receipt)
+	}
+}
+
+func TestSend_StopsOnPermanentFailure(t *testing.T) {
+	var calls int
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		calls++
+		return &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+	}
+	defer func() { sendMail = smtp.SendMail }()
+
+	cfg := config.Email{
+		Host:    "smtp.example.com",
+		From:    "feed@example.com",
+		To:      []string{"me@example.com"},
+		Backoff: config.Duration{Duration: time.Millisecond},
+	}
+	receipt, err := Send(context.Background(), cfg, Message{Subject: "Issue"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 || receipt.Attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
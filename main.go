@@ -1,14 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	_ "embed"
-	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
+	"html/template"
 	"io"
 	"log"
 	"log/slog"
@@ -16,21 +16,27 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
-	"text/template"
 	"time"
 
 	_ "modernc.org/sqlite"
 
 	"github.com/playwright-community/playwright-go"
 	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/agent/provider"
 	"github.com/scipunch/myfeed/cache"
 	"github.com/scipunch/myfeed/config"
 	"github.com/scipunch/myfeed/db"
-	"github.com/scipunch/myfeed/fetcher"
 	"github.com/scipunch/myfeed/filter"
+	"github.com/scipunch/myfeed/httpclient"
 	"github.com/scipunch/myfeed/parser"
 	"github.com/scipunch/myfeed/parser/factory"
+	"github.com/scipunch/myfeed/parser/rules"
+	"github.com/scipunch/myfeed/ratelimit"
+	"github.com/scipunch/myfeed/route"
+	"github.com/scipunch/myfeed/widget"
 )
 
 //go:embed schema.sql
@@ -39,24 +45,40 @@ var ddl string
 type Newsletter struct {
 	Title     string
 	Resources []Resource
+	Widgets   []widget.Block // Blocks above the items, e.g., today's weather
+	Stats     *IssueStats    // Rendered as the issue footer, nil to omit it
+	Fonts     template.CSS   // CSS of the configured print fonts, empty to use the host fonts
 }
 
 type Resource struct {
-	Name  string
-	Pages []Page
+	Name     string
+	Category string // Category of the resource config, used to build per-recipient editions
+	Pages    []Page
+	Pinned   bool // Section of notes and links pinned with `myfeed pin`, kept first
+	feed     int  // Index of the resource config, used to re-run its agents while curating
+	route    int  // Route of the items in the resource config starting at 1, 0 for none
 }
 
 type Page struct {
-	Title     string
-	Link      string
-	Content   string
-	ID        string // Unique ID for anchor links
-	Published time.Time
+	Title       string
+	Link        string
+	Description string         // Plain text of the feed item, recipient filters match it like the issue filters
+	Content     template.HTML  // Sanitized HTML of the parsed content or agent answers
+	Discussion  template.HTML  // Summary of the comment thread, empty if there is none
+	Fields      map[string]any // Fields of the answer of an agent with a schema, e.g., tags
+	ID          string         // Unique ID for anchor links
+	Published   time.Time
+	Source      string        // Feed title, set when sections are not per feed
+	Author      string        // Author reported by the parser, empty if unknown
+	Image       string        // Preview image of the source, empty if unknown or already in Content
+	ReadingTime time.Duration // Estimated time to read the source, 0 if unknown
+	Language    string        // ISO 639-1 code of Content, empty if unknown
+	Starred     bool          // Highlighted while curating the issue
+	Coverage    []Coverage    // Other sources of the same story merged into this page
 }
 
 func main() {
-	// TODO: Use embedded templates
-	t := template.Must(template.ParseGlob("templates/*.html"))
+	started := time.Now()
 
 	if os.Getenv("DEBUG") != "" {
 		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
@@ -66,15 +88,41 @@ func main() {
 	var cleanCache bool
 	var includeAll bool
 	var regenerate bool
-	flag.StringVar(&cfgPath, "config", config.DefaultPath(), "path to a TOML config")
+	var pprofAddr string
+	var draft bool
+	var wait bool
+	defaultCfgPath, defaultCfgErr := config.DefaultPath()
+	flag.StringVar(&cfgPath, "config", defaultCfgPath, "path to a TOML config")
 	flag.BoolVar(&cleanCache, "clean", false, "remove all cache entries")
 	flag.BoolVar(&includeAll, "include-all", false, "include all feed items, ignoring last processed timestamp")
 	flag.BoolVar(&regenerate, "regenerate", false, "delete last generation history and regenerate with same or new feed items")
+	flag.StringVar(&pprofAddr, "pprof", "", "serve pprof profiles at the address while running, e.g. ':6060'")
+	flag.BoolVar(&draft, "draft", false, "hold the issue for approval instead of emailing it, set by the daemon")
+	flag.BoolVar(&wait, "wait", false, "wait for another run of this config to finish instead of failing")
 	flag.Parse()
+	if cfgPath == "" {
+		log.Fatalf("failed to locate config: %s", defaultCfgErr)
+	}
+
+	// Handle `backup create|restore <file>`, a restore may bring the config
+	if flag.Arg(0) == "backup" {
+		if err := runBackup(context.Background(), cfgPath, flag.Args()[1:]); err != nil {
+			log.Fatalf("backup failed with %s", err)
+		}
+		return
+	}
+
+	// Handle `config schema [-json]`, generated from the config structs
+	if flag.Arg(0) == "config" {
+		if err := runConfig(flag.Args()[1:]); err != nil {
+			log.Fatalf("failed to generate config schema with %s", err)
+		}
+		return
+	}
 
 	// Read config and create if default is missing
 	conf, err := config.Read(cfgPath)
-	if errors.Is(err, os.ErrNotExist) && cfgPath == config.DefaultPath() {
+	if errors.Is(err, os.ErrNotExist) && cfgPath == defaultCfgPath {
 		if err := config.Write(cfgPath, conf); err != nil {
 			log.Fatalf("failed to write default config with %s", err)
 		}
@@ -82,8 +130,95 @@ func main() {
 		log.Fatalf("failed to read config with %s", err)
 	}
 
+	// TODO: Use embedded templates
+	t, err := loadTemplates(templatesDir(cfgPath, conf))
+	if err != nil {
+		log.Fatalf("failed to load templates with %s", err)
+	}
+
+	// `fetch` only queues new items, `process` only renders queued ones,
+	// `curate` renders them after a review in the terminal
+	command := flag.Arg(0)
+
+	// Handle `import <file.opml>` command
+	if flag.Arg(0) == "import" {
+		if flag.NArg() != 2 {
+			log.Fatal("usage: myfeed import <file.opml>")
+		}
+		if err := importOPML(cfgPath, conf, flag.Arg(1)); err != nil {
+			log.Fatalf("failed to import OPML with %s", err)
+		}
+		return
+	}
+
+	// Reject resources their parsers can't handle before anything runs
+	var resourceErrs []error
+	for _, r := range conf.Resources {
+		if !r.IsEnabled() {
+			continue
+		}
+		if err := factory.Check(r); err != nil {
+			resourceErrs = append(resourceErrs, fmt.Errorf("resource '%s': %w", r.FeedURL, err))
+		}
+		if r.MinScore < 0 || r.MinScore > agent.MaxScore {
+			resourceErrs = append(resourceErrs, fmt.Errorf("resource '%s': min_score must be between 0 and %d", r.FeedURL, agent.MaxScore))
+		}
+		if _, err := route.New(r.Routes); err != nil {
+			resourceErrs = append(resourceErrs, fmt.Errorf("resource '%s': %w", r.FeedURL, err))
+		}
+		for i, rt := range r.Routes {
+			if rt.Drop {
+				continue
+			}
+			if err := factory.Check(r.Routed(i)); err != nil {
+				resourceErrs = append(resourceErrs, fmt.Errorf("resource '%s': route %d: %w", r.FeedURL, i+1, err))
+			}
+		}
+	}
+	if len(resourceErrs) > 0 {
+		log.Fatalf("invalid resources in config:\n%s", errors.Join(resourceErrs...))
+	}
+
+	// Handle `daemon` command
+	if flag.Arg(0) == "daemon" {
+		if err := runDaemon(cfgPath, conf, pprofAddr); err != nil {
+			log.Fatalf("daemon failed with %s", err)
+		}
+		return
+	}
+
+	// Profile this run, generations of the daemon pass a free port
+	if pprofAddr != "" {
+		if err := servePprof(pprofAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Share rate limits across all fetchers, parsers and agents
+	limits := make(map[string]ratelimit.Limit, len(conf.RateLimits))
+	for key, l := range conf.RateLimits {
+		limits[key] = ratelimit.Limit{
+			Rate:        l.RequestsPerMinute / 60,
+			Burst:       l.Burst,
+			Concurrency: l.Concurrency,
+			TokenRate:   l.TokensPerMinute / 60,
+		}
+	}
+	ratelimit.Configure(limits)
+	httpclient.Configure(httpclient.Politeness{
+		MinDelay:      conf.Politeness.MinDelay.Duration,
+		RespectRobots: conf.Politeness.RespectRobots,
+	})
+	httpclient.ConfigureDomains(httpclient.Domains{
+		Blocked: conf.BlockedDomains,
+		Allowed: conf.AllowedDomains,
+	})
+
 	// Load credentials
-	credPath := config.DefaultCredentialsPath()
+	credPath, err := config.DefaultCredentialsPath()
+	if err != nil {
+		log.Fatalf("failed to locate credentials: %s", err)
+	}
 	creds, err := config.ReadCredentials(credPath)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		log.Fatalf("failed to read credentials: %s", err)
@@ -98,17 +233,48 @@ func main() {
 		slog.Info("initialized filters", "count", len(conf.Filters))
 	}
 
+	// Widgets are checked before anything runs and rendered with the issue
+	widgetClient, err := httpclient.New(conf.Proxy)
+	if err != nil {
+		log.Fatalf("failed to create widget HTTP client: %s", err)
+	}
+	widgets, err := widget.New(conf.Widgets, widgetClient, cmp.Or(conf.UserAgent, httpclient.DefaultUserAgent), filepath.Dir(cfgPath))
+	if err != nil {
+		log.Fatalf("failed to initialize widgets: %s", err)
+	}
+
+	// Fetch-only runs neither parse nor process items
 	var parserTypes []parser.Type
 	for _, r := range conf.Resources {
-		if r.IsEnabled() {
+		if r.IsEnabled() && !r.IsHeadline() && command != "fetch" {
 			parserTypes = append(parserTypes, r.ParserT)
+			for _, rt := range r.Routes {
+				if rt.Parser != "" && !rt.Drop {
+					parserTypes = append(parserTypes, rt.Parser)
+				}
+			}
 		}
 	}
-	parsers, err := factory.Init(parserTypes)
+	parsers, err := factory.Init(parserTypes, conf.Whisper)
 	if err != nil {
 		log.Fatalf("failed to initialize some parsers with %s", err)
 	}
 
+	// Load per-domain cleanup rules for article extraction
+	rulesPath := sourceRulesPath(cfgPath, conf)
+	sourceRules, err := rules.Load(rulesPath)
+	if err != nil {
+		log.Fatalf("failed to load source rules with %s", err)
+	}
+	if len(sourceRules.Rules) > 0 {
+		for t, p := range parsers {
+			if ra, ok := p.(parser.RulesAware); ok {
+				parsers[t] = ra.WithRules(sourceRules)
+			}
+		}
+		slog.Info("loaded source rules", "path", rulesPath, "count", len(sourceRules.Rules))
+	}
+
 	// Connect to database & initialize schema
 	dbBasePath := path.Dir(conf.DatabasePath)
 	err = os.MkdirAll(dbBasePath, os.ModePerm)
@@ -128,6 +294,15 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Only one run of a config may change its database and output at a time
+	if !concurrentCommand(flag.Args()) {
+		lock, err := acquireRunLock(ctx, lockPath(conf.DatabasePath), wait)
+		if err != nil {
+			log.Fatalf("failed to start run: %s", err)
+		}
+		defer lock.release()
+	}
+
 	// Initialize database (includes both main and cache schemas)
 	database, err := initDB(ctx, conf.DatabasePath)
 	if err != nil {
@@ -153,8 +328,155 @@ func main() {
 		return
 	}
 
+	// Handle `resource migrate -from <url> -to <url> [-dry-run]` command
+	if command == "resource" {
+		if err := runResource(ctx, database, conf, flag.Args()[1:]); err != nil {
+			log.Fatalf("failed to migrate resource: %v", err)
+		}
+		return
+	}
+
+	// Handle `db sent [-n 20]` command
+	if flag.Arg(0) == "db" && flag.Arg(1) == "sent" {
+		sentFlags := flag.NewFlagSet("db sent", flag.ExitOnError)
+		limit := sentFlags.Int("n", 20, "number of messages to show")
+		sentFlags.Parse(flag.Args()[2:])
+
+		if err := printSentEmails(ctx, queries, *limit); err != nil {
+			log.Fatalf("failed to list sent emails: %v", err)
+		}
+		return
+	}
+
+	// Handle `drafts`, `approve [id]` and `reject [id]` commands
+	switch command {
+	case "drafts":
+		if err := printDrafts(ctx, queries); err != nil {
+			log.Fatalf("failed to list drafts: %v", err)
+		}
+		return
+	case "approve", "reject":
+		id, err := latestDraft(ctx, queries)
+		if flag.NArg() > 1 {
+			id, err = strconv.ParseInt(flag.Arg(1), 10, 64)
+		}
+		if err != nil {
+			log.Fatalf("usage: myfeed %s [draft id]: %v", command, err)
+		}
+		if command == "approve" {
+			err = approveDraft(ctx, queries, conf.Email, id)
+		} else {
+			err = rejectDraft(ctx, queries, id)
+		}
+		if err != nil {
+			log.Fatalf("failed to %s draft %d: %v", command, id, err)
+		}
+		return
+	}
+
+	// Handle `rerender [-run latest] [-format pdf]` command
+	if command == "rerender" {
+		rerenderFlags := flag.NewFlagSet("rerender", flag.ExitOnError)
+		run := rerenderFlags.String("run", "latest", "issue to render again, 'latest' or its ID")
+		format := rerenderFlags.String("format", "pdf", "format rendered next to the HTML, only 'pdf' is supported, there is no EPUB output")
+		rerenderFlags.Parse(flag.Args()[1:])
+
+		if err := rerender(ctx, t, conf, queries, *run, *format); err != nil {
+			log.Fatalf("failed to render issue again: %v", err)
+		}
+		return
+	}
+
+	// Handle `pin [note or link] [-section Pinned]` and `unpin <id>` commands,
+	// pin without a note lists the pins waiting for the next issue
+	switch command {
+	case "pin":
+		pinFlags := flag.NewFlagSet("pin", flag.ExitOnError)
+		section := pinFlags.String("section", defaultPinSection, "section of the issue the pin goes into")
+		// Flags may follow the note, e.g., `myfeed pin "Renew domain" -section Personal`
+		var words []string
+		for args := flag.Args()[1:]; ; args = pinFlags.Args()[1:] {
+			pinFlags.Parse(args)
+			if pinFlags.NArg() == 0 {
+				break
+			}
+			words = append(words, pinFlags.Arg(0))
+		}
+		if len(words) == 0 {
+			if err := printPins(ctx, queries); err != nil {
+				log.Fatalf("failed to list pins: %v", err)
+			}
+			return
+		}
+		id, err := addPin(ctx, queries, strings.Join(words, " "), *section)
+		if err != nil {
+			log.Fatalf("failed to pin: %v", err)
+		}
+		slog.Info("pinned into the next issue", "id", id, "section", *section)
+		return
+	case "unpin":
+		id, err := strconv.ParseInt(flag.Arg(1), 10, 64)
+		if err != nil {
+			log.Fatalf("usage: myfeed unpin <pin id>: %v", err)
+		}
+		if err := removePin(ctx, queries, id); err != nil {
+			log.Fatalf("failed to unpin: %v", err)
+		}
+		return
+	}
+
+	// Handle `suggest [-since 90d] [-n 10]` command
+	if command == "suggest" {
+		suggestFlags := flag.NewFlagSet("suggest", flag.ExitOnError)
+		since := config.Duration{Duration: 90 * 24 * time.Hour}
+		suggestFlags.TextVar(&since, "since", since, "consider clicks and stars of this period, e.g., '30d'")
+		limit := suggestFlags.Int("n", 10, "number of sites to suggest")
+		suggestFlags.Parse(flag.Args()[1:])
+
+		if err := suggestSources(ctx, conf, queries, time.Now().Add(-since.Duration), *limit); err != nil {
+			log.Fatalf("failed to suggest sources: %v", err)
+		}
+		return
+	}
+
+	// Handle `db prune [--dry-run]` command
+	if flag.Arg(0) == "db" {
+		if flag.Arg(1) != "prune" {
+			log.Fatal("usage: myfeed db prune [--dry-run] | myfeed db sent [-n 20]")
+		}
+		pruneFlags := flag.NewFlagSet("db prune", flag.ExitOnError)
+		dryRun := pruneFlags.Bool("dry-run", false, "only show what would be removed")
+		pruneFlags.Parse(flag.Args()[2:])
+
+		if conf.HistoryRetention.Duration <= 0 {
+			log.Fatal("history_retention is not configured")
+		}
+		stats, err := pruneHistory(ctx, queries, cacheDB, conf.OutputDirectory, conf.HistoryRetention.Duration, *dryRun)
+		if err != nil {
+			log.Fatalf("failed to prune history: %v", err)
+		}
+		stats.log()
+		return
+	}
+
+	// Apply retention policy as part of regular maintenance
+	if conf.HistoryRetention.Duration > 0 {
+		stats, err := pruneHistory(ctx, queries, cacheDB, conf.OutputDirectory, conf.HistoryRetention.Duration, false)
+		if err != nil {
+			slog.Warn("failed to prune history", "error", err)
+		} else {
+			stats.log()
+		}
+	}
+
 	// Handle -regenerate flag
 	if regenerate {
+		if err := queries.DeleteLatestProcessedItems(ctx); err != nil {
+			log.Fatalf("failed to delete latest processed items: %v", err)
+		}
+		if err := queries.DeleteLatestHighWaterMarks(ctx); err != nil {
+			log.Fatalf("failed to delete latest high-water marks: %v", err)
+		}
 		if err := queries.DeleteLatestGeneration(ctx); err != nil {
 			log.Fatalf("failed to delete latest generation history: %v", err)
 		}
@@ -174,250 +496,77 @@ func main() {
 	// Initialize agents if any resource requires them
 	agentTypes := agent.CollectUniqueAgentTypes(conf.Resources)
 	var agents map[string]agent.Agent
-	if len(agentTypes) > 0 {
-		// Validate Gemini credentials
-		if !creds.Gemini.IsValid() {
-			log.Fatal("Gemini API key and model required for agents but not found in creds.toml")
-		}
-
-		// Initialize agents with fail-fast validation
-		agents, err = agent.InitAgents(ctx, agentTypes, creds.Gemini)
+	if len(agentTypes) > 0 && command != "fetch" {
+		// Initialize agents with fail-fast validation, including credentials of their providers
+		providers := provider.Settings{Default: conf.Provider, Gemini: creds.Gemini, Claude: creds.Claude, Ollama: conf.Ollama, Models: conf.Models, Fallback: conf.Fallback, Chunk: conf.ChunkSize}
+		agents, err = agent.InitAgents(ctx, agentTypes, providers, conf.Contracts, conf.Interests, conf.Agents)
 		if err != nil {
 			log.Fatalf("failed to initialize agents: %s", err)
 		}
 		slog.Info("initialized agents", "types", agentTypes)
 	}
 
-	// Initialize fetchers
-	var resourceTypes []config.ResourceType
-	for _, r := range conf.Resources {
-		if r.IsEnabled() {
-			resourceTypes = append(resourceTypes, r.T)
+	// Fetch feeds into the queue, `process` works on queued items only
+	if command != "process" && command != "curate" {
+		if err := fetchToQueue(ctx, conf, queries, path.Dir(cfgPath), includeAll, regenerate); err != nil {
+			if ctx.Err() != nil {
+				slog.Info("interrupted by user during fetch, exiting gracefully")
+				return
+			}
+			log.Fatalf("failed to fetch feeds with %s", err)
 		}
 	}
-	configDir := path.Dir(cfgPath)
-	fetchers, err := fetcher.GetFetchers(resourceTypes, configDir)
+	if command == "fetch" {
+		slog.Info("fetched items queued for processing")
+		return
+	}
+
+	// Process everything waiting in the queue, including items of earlier fetch-only runs
+	queueCutoff := time.Now().Unix()
+	feeds, err := queuedFeeds(ctx, queries, conf.Resources)
 	if err != nil {
-		log.Fatalf("failed to initialize fetchers with %s", err)
+		log.Fatal(err)
 	}
 
-	// Fetch configured feeds
-	var errs []error
-	feeds := make([]*fetcher.Feed, len(conf.Resources))
-	for i, resource := range conf.Resources {
-		// Skip disabled resources
-		if !resource.IsEnabled() {
-			slog.Debug("skipping disabled resource", "url", resource.FeedURL)
-			continue
-		}
+	// Process new items
+	proc := processor{
+		conf:       conf,
+		queries:    queries,
+		cache:      cacheDB,
+		parsers:    parsers,
+		agents:     agents,
+		filters:    filterPipeline,
+		includeAll: includeAll,
+	}
+	run, err := proc.process(ctx, feeds, started)
+	if err != nil {
+		slog.Info("interrupted by user, exiting gracefully")
+		return
+	}
 
-		// Check for cancellation before fetching
-		select {
-		case <-ctx.Done():
-			slog.Info("interrupted by user during fetch, exiting gracefully")
+	// Review the issue item by item before it is rendered
+	if command == "curate" {
+		c, err := curateInTerminal(ctx, proc, run.newsletter)
+		if errors.Is(err, errCurationAborted) {
+			slog.Info("issue discarded, items stay queued for the next run")
 			return
-		default:
 		}
-
-		f := fetchers[resource.T]
-		feed, err := f.Fetch(ctx, resource.FeedURL)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("'%s' fetch failed with %w", resource.FeedURL, err))
-			continue
+			log.Fatalf("failed to curate issue with %s", err)
 		}
-		feeds[i] = &feed
+		run = run.curated(c, started)
+		recordStars(ctx, queries, run.newsletter)
 	}
-	slog.Info("fetched feeds", "amount", len(feeds))
-	if len(errs) > 0 {
-		slog.Error("several feeds were not parsed", "feeds", errors.Join(errs...))
+	// Pinned notes and links lead the issue
+	pins, err := queries.ListPendingPins(ctx)
+	if err != nil {
+		slog.Warn("failed to load pins", "error", err)
 	}
+	run.newsletter = withPins(run.newsletter, pins)
+	run.newsletter.Widgets = widget.Render(ctx, widgets, cacheDB, time.Now())
 
-	// Process new items
-	errs = nil
-	newsletter := Newsletter{Title: "Test newsletter"}
-	resourceMap := make(map[int]*Resource)   // Map index to resource
-	feedLastProcessed := make(map[int]int64) // Track latest timestamp per feed
-	mediaFiles := make(map[string]string)    // Map temp path -> output filename for media files
-
-	for i, feed := range feeds {
-		// Check if context was cancelled
-		select {
-		case <-ctx.Done():
-			slog.Info("interrupted by user, exiting gracefully")
-			return
-		default:
-		}
-
-		if feed == nil {
-			slog.Debug("skipping failed to parse feed")
-			continue
-		}
-		resource := conf.Resources[i]
-
-		// Get last processed timestamp for this feed
-		var lastProcessedAt int64
-		if !includeAll {
-			// Try to get from generation history first
-			timestamp, err := queries.GetLatestGenerationTimestamp(ctx, resource.FeedURL)
-			if err == nil {
-				lastProcessedAt = timestamp
-				slog.Debug("loaded last processed timestamp from generation history",
-					"feed", resource.FeedURL,
-					"timestamp", lastProcessedAt,
-					"time", time.Unix(lastProcessedAt, 0))
-			} else if !errors.Is(err, sql.ErrNoRows) {
-				slog.Warn("failed to get generation history", "error", err, "feed", resource.FeedURL)
-			}
-		}
-
-		p := parsers[resource.ParserT]
-		for _, item := range feed.Items {
-			// Check for cancellation before processing each item
-			select {
-			case <-ctx.Done():
-				slog.Info("interrupted by user, exiting gracefully")
-				return
-			default:
-			}
-
-			// Skip items that were already processed (based on published date)
-			itemTimestamp := item.Published.Unix()
-			if !includeAll && itemTimestamp > 0 && itemTimestamp <= lastProcessedAt {
-				slog.Debug("item already processed, skipping",
-					"title", item.Title,
-					"published", item.Published,
-					"last_processed", time.Unix(lastProcessedAt, 0))
-				continue
-			}
-
-			// Apply filters
-			if len(resource.FilterNames) > 0 {
-				shouldInclude, reason := filterPipeline.ShouldInclude(item, resource.FilterNames)
-				if !shouldInclude {
-					slog.Debug("item filtered out", "title", item.Title, "reason", reason, "url", item.Link)
-					continue
-				}
-			}
-
-			// Track the latest timestamp for this feed
-			if itemTimestamp > feedLastProcessed[i] {
-				feedLastProcessed[i] = itemTimestamp
-			}
-
-			var content string
-			var parsedData parser.Response
-			cacheHit := false
-
-			// Step 1: Check agent cache first (if agents configured)
-			if len(resource.Agents) > 0 {
-				if cached, hit, err := cacheDB.GetAgentOutput(item.Link, string(resource.ParserT), resource.Agents); err == nil && hit {
-					content = cached
-					cacheHit = true
-					slog.Debug("agent cache hit", "url", item.Link, "agents", resource.Agents)
-				}
-			}
-
-			// Step 2: If no agent cache, try parser cache
-			if !cacheHit {
-				if cached, hit, err := cacheDB.GetParserOutput(item.Link, string(resource.ParserT)); err == nil && hit {
-					// Deserialize cached parser output
-					if data, err := cache.DeserializeParserResponse(string(resource.ParserT), cached); err == nil {
-						parsedData = data
-						slog.Debug("parser cache hit", "url", item.Link, "parser", resource.ParserT)
-					} else {
-						slog.Warn("failed to deserialize cached parser output", "error", err)
-						// Fall through to re-parse
-					}
-				}
-
-				// Step 3: If no parser cache, parse now
-				if parsedData == nil {
-					data, err := p.Parse(item)
-					if err != nil {
-						errs = append(errs, err)
-						continue
-					}
-					parsedData = data
-					slog.Info("feed item parsed", "url", item.Link, "length", len(data.String()))
-
-					// Cache parser output
-					if serialized, err := cache.SerializeParserResponse(string(resource.ParserT), parsedData); err == nil {
-						if err := cacheDB.SetParserOutput(item.Link, string(resource.ParserT), serialized); err != nil {
-							slog.Warn("failed to cache parser output", "error", err)
-						}
-					} else {
-						slog.Warn("failed to serialize parser output", "error", err)
-					}
-				}
-
-				content = parsedData.String()
-
-				// Step 4: Apply agents if configured
-				if len(resource.Agents) > 0 {
-					for _, agentName := range resource.Agents {
-						agentInstance, ok := agents[agentName]
-						if !ok {
-							errs = append(errs, fmt.Errorf("agent '%s' not found", agentName))
-							continue
-						}
-
-						processed, err := agentInstance.Process(ctx, content)
-						if err != nil {
-							errs = append(errs, fmt.Errorf("agent '%s' processing failed: %w", agentName, err))
-							slog.Error("agent processing failed, using original content", "agent", agentName, "error", err)
-							// Continue with original content on error
-							break
-						}
-
-						content = processed
-						slog.Info("content processed by agent", "agent", agentName, "original_length", len(parsedData.String()), "processed_length", len(content))
-					}
-
-					// Cache final agent output
-					if err := cacheDB.SetAgentOutput(item.Link, string(resource.ParserT), resource.Agents, content); err != nil {
-						slog.Warn("failed to cache agent output", "error", err)
-					}
-				}
-			}
-
-			// Generate unique ID for anchor link
-			hash := sha256.Sum256([]byte(item.Link))
-			pageID := hex.EncodeToString(hash[:8])
-
-			// Track media files for later copying to output directory
-			for _, media := range item.Media {
-				if media.LocalPath != "" && media.Type == "photo" {
-					// Use the filename from the local path
-					filename := filepath.Base(media.LocalPath)
-					mediaFiles[media.LocalPath] = filename
-				}
-			}
-
-			// Get or create resource for this feed
-			res, exists := resourceMap[i]
-			if !exists {
-				res = &Resource{
-					Name:  feed.Title,
-					Pages: []Page{},
-				}
-				resourceMap[i] = res
-			}
-
-			res.Pages = append(res.Pages, Page{
-				Title:     item.Title,
-				Link:      item.Link,
-				Content:   content,
-				ID:        pageID,
-				Published: item.Published,
-			})
-		}
-	}
-	// Convert resource map to slice in order
-	for i := 0; i < len(feeds); i++ {
-		if res, exists := resourceMap[i]; exists && len(res.Pages) > 0 {
-			newsletter.Resources = append(newsletter.Resources, *res)
-		}
-	}
+	newsletter, issueStats, errs, mediaFiles := run.newsletter, run.stats, run.errs, run.mediaFiles
+	slog.Info("issue stats", "stats", issueStats.String())
 
 	totalPages := 0
 	for _, res := range newsletter.Resources {
@@ -456,77 +605,65 @@ func main() {
 		slog.Info("copied media files", "count", len(mediaFiles))
 	}
 
+	// Embed configured fonts into the PDF, it keeps only the glyphs in use
+	if len(conf.Fonts) > 0 {
+		fonts, err := printFonts(conf.Fonts)
+		if err != nil {
+			slog.Error("failed to load fonts, using the host fonts", "error", err)
+		} else {
+			newsletter.Fonts = template.CSS(fonts)
+			slog.Info("loaded fonts", "count", len(conf.Fonts))
+		}
+	}
+
 	// Generate file names with date
 	fileName := fmt.Sprintf("myfeed_%s", now.Format("2006_01_02"))
 	htmlPath := path.Join(outputPath, fileName+".html")
-	pdfPath := path.Join(outputPath, fileName+".pdf")
 
 	// Generate HTML report
-	out, err := os.Create(htmlPath)
+	issue, appendix, err := renderIssueHTML(t, conf, newsletter, htmlPath)
 	if err != nil {
-		log.Fatal("could not create newsletter HTML file", err)
-	}
-	defer out.Close()
-	err = t.Execute(out, newsletter)
-	if err != nil {
-		log.Fatal("could not convert newsletter into HTML", err)
+		log.Fatal("could not generate newsletter HTML file", err)
 	}
 	slog.Info("HTML file generated", "path", htmlPath)
 
-	// Update last processed timestamps for feeds and save generation history
-	if !includeAll {
-		generationTime := time.Now().Unix()
-		for i, feed := range feeds {
-			if feed == nil {
-				continue
-			}
+	saveRun(ctx, conf, queries, feeds, run, includeAll, queueCutoff)
+	markPinsIssued(ctx, queries, pins)
+	saveIssue(ctx, queries, newsletter, outputPath, fileName)
 
-			// Get the latest timestamp for this feed
-			lastTimestamp := feedLastProcessed[i]
-			if lastTimestamp > 0 {
-				resource := conf.Resources[i]
-
-				// Update the main feed table
-				err := queries.UpdateLastProcessedAt(ctx, db.UpdateLastProcessedAtParams{
-					Url:             resource.FeedURL,
-					Title:           feed.Title,
-					LastProcessedAt: lastTimestamp,
-				})
-				if err != nil {
-					slog.Warn("failed to update last processed timestamp",
-						"error", err,
-						"feed", resource.FeedURL)
-				} else {
-					slog.Debug("updated last processed timestamp",
-						"feed", resource.FeedURL,
-						"timestamp", time.Unix(lastTimestamp, 0))
-				}
+	issue = renderIssuePDF(ctx, t, conf, issue, appendix, outputPath, fileName)
 
-				// Save to generation history
-				err = queries.SaveGenerationHistory(ctx, db.SaveGenerationHistoryParams{
-					FeedUrl:         resource.FeedURL,
-					LastProcessedAt: lastTimestamp,
-					CreatedAt:       generationTime,
-				})
-				if err != nil {
-					slog.Warn("failed to save generation history",
-						"error", err,
-						"feed", resource.FeedURL)
-				}
+	// Deliver the issue by email, every recipient gets their own edition.
+	// Drafts of the daemon are sent once they are approved.
+	if conf.Email.Enabled() {
+		deliveries := renderDeliveries(t, issue, conf, outputPath, filterPipeline)
+		if draft {
+			if err := holdDraft(ctx, queries, conf, issue.Title, htmlPath, deliveries); err != nil {
+				slog.Error("failed to hold issue for approval, it is not sent", "error", err)
 			}
+		} else {
+			deliver(ctx, queries, conf.Email, deliveries)
 		}
 	}
+}
 
-	// Generate PDF report
-	if err := generatePDF(ctx, htmlPath, pdfPath); err != nil {
-		slog.Error("failed to generate PDF", "error", err)
-	} else {
-		slog.Info("PDF file generated", "path", pdfPath)
+// renderHTML executes the newsletter template into the file at htmlPath
+func renderHTML(t *template.Template, htmlPath string, n Newsletter) error {
+	out, err := os.Create(htmlPath)
+	if err != nil {
+		return fmt.Errorf("could not create file at '%s': %w", htmlPath, err)
+	}
+	defer out.Close()
+
+	if err := t.ExecuteTemplate(out, "index", n); err != nil {
+		return fmt.Errorf("could not execute template: %w", err)
 	}
+	return nil
 }
 
 func initDB(ctx context.Context, source string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", source)
+	// Wait for locks held by the daemon queueing pushed items
+	db, err := sql.Open("sqlite", source+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database at '%s' with %w", source, err)
 	}
@@ -539,41 +676,52 @@ func initDB(ctx context.Context, source string) (*sql.DB, error) {
 	return db, nil
 }
 
-func generatePDF(ctx context.Context, htmlPath, pdfPath string) error {
+// generatePDF prints the HTML file into a PDF.
+// When maxPages is positive, articles that would land past the page budget
+// are removed before printing and their IDs are returned.
+func generatePDF(ctx context.Context, htmlPath, pdfPath string, maxPages int) ([]string, error) {
 	// Install playwright if needed
 	err := playwright.Install()
 	if err != nil {
-		return fmt.Errorf("could not install playwright: %w", err)
+		return nil, fmt.Errorf("could not install playwright: %w", err)
 	}
 
 	pw, err := playwright.Run()
 	if err != nil {
-		return fmt.Errorf("could not start playwright: %w", err)
+		return nil, fmt.Errorf("could not start playwright: %w", err)
 	}
 	defer pw.Stop()
 
 	browser, err := pw.Chromium.Launch()
 	if err != nil {
-		return fmt.Errorf("could not launch browser: %w", err)
+		return nil, fmt.Errorf("could not launch browser: %w", err)
 	}
 	defer browser.Close()
 
 	page, err := browser.NewPage()
 	if err != nil {
-		return fmt.Errorf("could not create page: %w", err)
+		return nil, fmt.Errorf("could not create page: %w", err)
 	}
 	defer page.Close()
 
 	// Get absolute path to HTML file
 	absPath, err := filepath.Abs(htmlPath)
 	if err != nil {
-		return fmt.Errorf("could not get absolute path: %w", err)
+		return nil, fmt.Errorf("could not get absolute path: %w", err)
 	}
 
 	// Navigate to local HTML file
 	fileURL := fmt.Sprintf("file://%s", absPath)
 	if _, err = page.Goto(fileURL); err != nil {
-		return fmt.Errorf("could not navigate to HTML file: %w", err)
+		return nil, fmt.Errorf("could not navigate to HTML file: %w", err)
+	}
+
+	var overflowIDs []string
+	if maxPages > 0 {
+		overflowIDs, err = trimToPageBudget(page, maxPages)
+		if err != nil {
+			return nil, fmt.Errorf("could not enforce page limit: %w", err)
+		}
 	}
 
 	// Generate PDF with proper settings
@@ -591,10 +739,10 @@ func generatePDF(ctx context.Context, htmlPath, pdfPath string) error {
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("could not generate PDF: %w", err)
+		return nil, fmt.Errorf("could not generate PDF: %w", err)
 	}
 
-	return nil
+	return overflowIDs, nil
 }
 
 // copyMediaFile copies a media file from src to dst
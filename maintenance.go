@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/scipunch/myfeed/cache"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/parser/web"
+)
+
+// pruneStats reports what was (or would be, in dry run) removed
+type pruneStats struct {
+	Runs      int64
+	Items     int64
+	Cache     cache.CacheStats
+	MediaDirs []string
+	Images    []string // Downloaded with localize_images and not shown since
+	OlderThan time.Time
+	DryRun    bool
+}
+
+// pruneHistory removes generation history, processed items, agent metrics,
+// cache entries, media of issues and downloaded images older than the
+// retention period
+func pruneHistory(ctx context.Context, queries *db.Queries, cacheDB *cache.Cache, outputDir string, retention time.Duration, dryRun bool) (pruneStats, error) {
+	cutoff := time.Now().Add(-retention)
+	stats := pruneStats{OlderThan: cutoff, DryRun: dryRun}
+
+	var err error
+	stats.Runs, err = queries.CountGenerationHistoryBefore(ctx, cutoff.Unix())
+	if err != nil {
+		return stats, fmt.Errorf("failed to count generation history with %w", err)
+	}
+	stats.Items, err = queries.CountProcessedItemsBefore(ctx, cutoff.Unix())
+	if err != nil {
+		return stats, fmt.Errorf("failed to count processed items with %w", err)
+	}
+	stats.Cache, err = cacheDB.Prune(cutoff, dryRun)
+	if err != nil {
+		return stats, err
+	}
+	stats.MediaDirs, err = oldMediaDirs(outputDir, cutoff)
+	if err != nil {
+		return stats, err
+	}
+	stats.Images, err = oldImages(web.ImageDir(), cutoff)
+	if err != nil {
+		return stats, err
+	}
+
+	if dryRun {
+		return stats, nil
+	}
+
+	if err := queries.DeleteGenerationHistoryBefore(ctx, cutoff.Unix()); err != nil {
+		return stats, fmt.Errorf("failed to prune generation history with %w", err)
+	}
+	if err := queries.DeleteProcessedItemsBefore(ctx, cutoff.Unix()); err != nil {
+		return stats, fmt.Errorf("failed to prune processed items with %w", err)
+	}
+	// The latest mark of every resource is kept so quiet feeds are not reprocessed
+	if err := queries.DeleteHighWaterMarksBefore(ctx, cutoff.Unix()); err != nil {
+		return stats, fmt.Errorf("failed to prune high-water marks with %w", err)
+	}
+	if err := queries.DeleteAgentMetricsBefore(ctx, cutoff.Unix()); err != nil {
+		return stats, fmt.Errorf("failed to prune agent metrics with %w", err)
+	}
+	if err := queries.DeleteIssuesBefore(ctx, cutoff.Unix()); err != nil {
+		return stats, fmt.Errorf("failed to prune stored issues with %w", err)
+	}
+	for _, dir := range stats.MediaDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Warn("failed to remove media directory", "path", dir, "error", err)
+		}
+	}
+	for _, image := range stats.Images {
+		if err := os.Remove(image); err != nil {
+			slog.Warn("failed to remove downloaded image", "path", image, "error", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// oldMediaDirs lists media directories of dated issues generated before cutoff
+func oldMediaDirs(outputDir string, cutoff time.Time) ([]string, error) {
+	entries, err := os.ReadDir(outputDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory at '%s' with %w", outputDir, err)
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		date, err := time.ParseInLocation("2006_01_02", entry.Name(), time.Local)
+		if err != nil || !date.Before(cutoff) {
+			continue
+		}
+		mediaDir := filepath.Join(outputDir, entry.Name(), "media")
+		if _, err := os.Stat(mediaDir); err == nil {
+			dirs = append(dirs, mediaDir)
+		}
+	}
+	return dirs, nil
+}
+
+// oldImages lists downloaded images last used before cutoff, reusing an
+// image refreshes its modification time
+func oldImages(dir string, cutoff time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image directory at '%s' with %w", dir, err)
+	}
+
+	var images []string
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		images = append(images, filepath.Join(dir, entry.Name()))
+	}
+	return images, nil
+}
+
+func (s pruneStats) log() {
+	msg := "pruned history"
+	if s.DryRun {
+		msg = "history prune preview (dry run)"
+	}
+	slog.Info(msg,
+		"older_than", s.OlderThan.Format(time.DateOnly),
+		"runs", s.Runs,
+		"items", s.Items,
+		"parser_cache", s.Cache.ParserEntries,
+		"agent_cache", s.Cache.AgentEntries,
+		"media_dirs", len(s.MediaDirs),
+		"images", len(s.Images))
+	for _, dir := range s.MediaDirs {
+		slog.Debug("media directory", "path", dir)
+	}
+}
@@ -0,0 +1,424 @@
+// Package markdown is the common representation of item content: parsers
+// emit Markdown, agents read and write it, and issues render it to HTML in
+// one place so every source is styled the same way.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// FromHTML converts an HTML fragment to Markdown. Scripts, styles and
+// elements without a Markdown equivalent are reduced to their text.
+func FromHTML(content string) string {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return Escape(content)
+	}
+	return strings.Join(blocks(doc), "\n\n")
+}
+
+// skipped elements contribute nothing to the Markdown
+var skipped = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Iframe: true, atom.Svg: true, atom.Form: true,
+	atom.Button: true, atom.Input: true, atom.Select: true, atom.Textarea: true,
+}
+
+// blockElements start a new block of Markdown
+var blockElements = map[atom.Atom]bool{
+	atom.Html: true, atom.Body: true, atom.P: true, atom.Div: true, atom.Section: true,
+	atom.Article: true, atom.Main: true, atom.Header: true, atom.Footer: true,
+	atom.Aside: true, atom.Nav: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Details: true, atom.Summary: true, atom.Address: true, atom.Center: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Pre: true, atom.Blockquote: true, atom.Hr: true, atom.Table: true,
+}
+
+// blocks converts the children of n to Markdown blocks
+func blocks(n *html.Node) []string {
+	var out []string
+	var run strings.Builder
+	flush := func() {
+		if p := paragraph(run.String()); p != "" {
+			out = append(out, p)
+		}
+		run.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && skipped[c.DataAtom] {
+			continue
+		}
+		if c.Type != html.ElementNode || !blockElements[c.DataAtom] {
+			run.WriteString(inline(c))
+			continue
+		}
+		flush()
+		out = append(out, block(c)...)
+	}
+	flush()
+	return out
+}
+
+// block converts a block element to Markdown blocks
+func block(n *html.Node) []string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := collapse(inlineChildren(n))
+		if text == "" {
+			return nil
+		}
+		level, _ := strconv.Atoi(n.Data[1:])
+		return []string{strings.Repeat("#", level) + " " + text}
+	case atom.Hr:
+		return []string{"---"}
+	case atom.Pre:
+		return []string{fence(textContent(n), codeLanguage(n))}
+	case atom.Blockquote:
+		inner := strings.Join(blocks(n), "\n\n")
+		if inner == "" {
+			return nil
+		}
+		return []string{prefixLines(inner, "> ", ">")}
+	case atom.Ul, atom.Ol:
+		if l := list(n); l != "" {
+			return []string{l}
+		}
+		return nil
+	case atom.Table:
+		if t := table(n); t != "" {
+			return []string{t}
+		}
+		return nil
+	case atom.Figcaption, atom.Summary, atom.Dt:
+		if text := collapse(inlineChildren(n)); text != "" {
+			return []string{"*" + text + "*"}
+		}
+		return nil
+	}
+	return blocks(n)
+}
+
+// list converts ul and ol elements, items with several blocks are indented
+func list(n *html.Node) string {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); ordered && err == nil {
+		number = start
+	}
+	var items []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		parts := blocks(c)
+		if c.DataAtom != atom.Li {
+			parts = block(c)
+		}
+		content := joinItem(parts)
+		if content == "" {
+			continue
+		}
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+prefixLines(content, indent, "")[len(indent):])
+	}
+	return strings.Join(items, "\n")
+}
+
+// joinItem joins the blocks of a list item, nested lists follow the text
+// directly so the list stays tight
+func joinItem(parts []string) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteString("\n")
+			if _, nested := parseMarker(part); !nested || strings.Contains(parts[i-1], "\n\n") {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// table converts a table to a GitHub table, the first row is the header
+func table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if c.DataAtom != atom.Tr {
+				walk(c)
+				continue
+			}
+			var row []string
+			for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+					text := collapse(inlineChildren(cell))
+					row = append(row, strings.ReplaceAll(text, "|", `\|`))
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	var b strings.Builder
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// inline converts a node inside a paragraph
+func inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return Escape(whitespaceRe.ReplaceAllString(n.Data, " "))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if skipped[n.DataAtom] {
+		return ""
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		return "\\\n"
+	case atom.Strong, atom.B:
+		return wrap(inlineChildren(n), "**")
+	case atom.Em, atom.I, atom.Cite:
+		return wrap(inlineChildren(n), "*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrap(inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		return code(textContent(n))
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return "![" + Escape(attr(n, "alt")) + "](" + destination(src) + ")"
+	case atom.A:
+		text := collapse(inlineChildren(n))
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return text
+		}
+		if text == "" {
+			text = Escape(href)
+		}
+		return "[" + text + "](" + destination(href) + ")"
+	}
+	return inlineChildren(n)
+}
+
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockElements[c.DataAtom] {
+			// Blocks nested in inline elements, e.g., a div inside a link
+			b.WriteString(" " + inlineChildren(c) + " ")
+			continue
+		}
+		b.WriteString(inline(c))
+	}
+	return b.String()
+}
+
+var (
+	whitespaceRe = regexp.MustCompile(`[ \t\r\n\f]+`)
+	// lineStartRe matches text at the start of a line which Markdown would
+	// read as a block, e.g., a heading or a list item
+	lineStartRe = regexp.MustCompile(`^([#>+\-=|]|\d+[.)](\s|$))`)
+)
+
+// paragraph trims an inline run and keeps its lines from starting blocks
+func paragraph(run string) string {
+	lines := strings.Split(run, "\n")
+	var kept []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || line == `\` {
+			continue
+		}
+		if m := lineStartRe.FindStringIndex(line); m != nil {
+			if line[0] >= '0' && line[0] <= '9' {
+				i := strings.IndexAny(line, ".)")
+				line = line[:i] + `\` + line[i:]
+			} else {
+				line = `\` + line
+			}
+		}
+		kept = append(kept, line)
+	}
+	p := strings.Join(kept, "\n")
+	return strings.TrimSuffix(p, `\`)
+}
+
+// collapse trims inline content to a single line
+func collapse(s string) string {
+	s = strings.ReplaceAll(s, "\\\n", " ")
+	return strings.TrimSpace(whitespaceRe.ReplaceAllString(s, " "))
+}
+
+// wrap surrounds inline content with a delimiter, spaces stay outside of it
+func wrap(s, delimiter string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	start := strings.Index(s, trimmed)
+	return s[:start] + delimiter + trimmed + delimiter + s[start+len(trimmed):]
+}
+
+// code formats inline code, using more backticks than the code contains
+func code(s string) string {
+	s = whitespaceRe.ReplaceAllString(s, " ")
+	if s == "" {
+		return ""
+	}
+	ticks := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return ticks + s + ticks
+}
+
+// fence formats a code block
+func fence(s, language string) string {
+	s = strings.Trim(s, "\n")
+	ticks := strings.Repeat("`", max(3, longestRun(s, '`')+1))
+	return ticks + language + "\n" + s + "\n" + ticks
+}
+
+// languagePrefixes mark the language in classes of code blocks, e.g.,
+// "language-go" or "highlight-source-go" on GitHub
+var languagePrefixes = []string{"language-", "lang-", "highlight-source-"}
+
+// codeLanguage reads the language of a code block from the classes of the
+// block, its code or its wrapper
+func codeLanguage(pre *html.Node) string {
+	for _, n := range []*html.Node{pre, pre.FirstChild, pre.Parent} {
+		if n == nil || n.Type != html.ElementNode {
+			continue
+		}
+		for _, class := range strings.Fields(attr(n, "class")) {
+			for _, prefix := range languagePrefixes {
+				if lang, ok := strings.CutPrefix(class, prefix); ok && lang != "" {
+					return lang
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// destination formats a link or image URL
+func destination(url string) string {
+	url = strings.TrimSpace(url)
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+// prefixLines prefixes every line of s, empty lines get empty
+func prefixLines(s, prefix, empty string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = empty
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.DataAtom == atom.Br {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+// Escape escapes text so Markdown shows it literally
+func Escape(text string) string {
+	var b strings.Builder
+	for i, r := range text {
+		switch r {
+		case '\\', '*', '`', '[', ']', '<', '~':
+			b.WriteByte('\\')
+		case '_':
+			// Underscores inside words, e.g., in URLs, never emphasize
+			if i > 0 && i < len(text)-1 && isWordByte(text[i-1]) && isWordByte(text[i+1]) {
+				break
+			}
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
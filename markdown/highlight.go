@@ -0,0 +1,374 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Classes of highlighted tokens, styled by the issue and email templates
+const (
+	classKeyword = "hl-keyword"
+	classString  = "hl-string"
+	classComment = "hl-comment"
+	classNumber  = "hl-number"
+)
+
+// syntax describes the tokens of a language well enough to color them
+type syntax struct {
+	keywords       map[string]bool
+	foldCase       bool     // Keywords match in any case, e.g., SQL
+	lineComments   []string // e.g., "//"
+	blockComment   [2]string
+	nestedComments bool   // Block comments nest, e.g., in Rust
+	lineStartBlock bool   // Block comments are delimited at the start of lines, e.g., =begin in Ruby
+	quotes         string // Characters starting strings
+	multiline      string // Quotes whose strings may span lines, e.g., "`" in Go
+	doubledQuotes  bool   // A doubled quote doesn't end the string, e.g., 'it''s' in SQL
+	rawStrings     bool   // Rust raw strings, e.g., r#"say "hi""#
+	heredocs       bool   // Shell here-documents, e.g., <<EOF
+	tripleQuotes   bool   // Python docstrings
+	commentAtWord  bool   // Line comments start only after a space, e.g., "#" in shell
+}
+
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		set[w] = true
+	}
+	return set
+}
+
+var (
+	cLike = [2]string{"/*", "*/"}
+
+	goSyntax = &syntax{
+		keywords:     words("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var true false nil iota"),
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       "\"'`",
+		multiline:    "`",
+	}
+	cSyntax = &syntax{
+		keywords:     words("auto break case char class const constexpr continue default delete do double else enum extern false float for goto if inline int long namespace new nullptr private protected public register return short signed sizeof static struct switch template this true typedef typename union unsigned using virtual void volatile while #include #define #ifdef #ifndef #endif #if #else #pragma"),
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       "\"'",
+	}
+	javaSyntax = &syntax{
+		keywords:     words("abstract boolean break byte case catch char class const continue data default do double else enum extends final finally float for fun if implements import instanceof int interface is long new null object override package private protected public return short static super switch this throw throws true false try val var void when while"),
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       "\"'",
+	}
+	jsSyntax = &syntax{
+		keywords:     words("as async await break case catch class const continue debugger default delete do else enum export extends false finally for from function if implements import in instanceof interface let new null of private protected public return static super switch this throw true try type typeof undefined var void while with yield"),
+		lineComments: []string{"//"},
+		blockComment: cLike,
+		quotes:       "\"'`",
+		multiline:    "`",
+	}
+	pythonSyntax = &syntax{
+		keywords:      words("False None True and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return self try while with yield"),
+		lineComments:  []string{"#"},
+		quotes:        "\"'",
+		tripleQuotes:  true,
+		commentAtWord: true,
+	}
+	rustSyntax = &syntax{
+		keywords:       words("as async await break const continue crate dyn else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while Some None Ok Err"),
+		lineComments:   []string{"//"},
+		blockComment:   cLike,
+		nestedComments: true,
+		quotes:         "\"",
+		multiline:      "\"",
+		rawStrings:     true,
+	}
+	rubySyntax = &syntax{
+		keywords:       words("BEGIN END alias and begin break case class def do else elsif end ensure false for if in module next nil not or redo rescue retry return self super then true undef unless until when while yield require attr_accessor"),
+		lineComments:   []string{"#"},
+		blockComment:   [2]string{"=begin", "=end"},
+		lineStartBlock: true,
+		quotes:         "\"'",
+		multiline:      "\"'",
+		commentAtWord:  true,
+	}
+	shellSyntax = &syntax{
+		keywords:      words("if then else elif fi for while until do done case esac in function return export local readonly echo exit set unset source sudo cd"),
+		lineComments:  []string{"#"},
+		quotes:        "\"'",
+		multiline:     "\"'",
+		heredocs:      true,
+		commentAtWord: true,
+	}
+	sqlSyntax = &syntax{
+		keywords:      words("add all alter and as asc begin between by case check column commit constraint create database default delete desc distinct drop else end exists foreign from full group having if in index inner insert into is join key left like limit not null offset on or order outer primary references returning right rollback select set table then transaction union unique update using values view when where with"),
+		foldCase:      true,
+		lineComments:  []string{"--"},
+		blockComment:  cLike,
+		quotes:        "'\"",
+		multiline:     "'\"",
+		doubledQuotes: true,
+	}
+	dataSyntax = &syntax{
+		keywords:      words("true false null yes no on off"),
+		lineComments:  []string{"#"},
+		quotes:        "\"'",
+		commentAtWord: true,
+	}
+	cssSyntax = &syntax{
+		keywords:     words("important inherit initial none auto @media @import @font-face @keyframes"),
+		blockComment: cLike,
+		quotes:       "\"'",
+	}
+)
+
+// languages maps names of code block languages to their syntax
+var languages = map[string]*syntax{
+	"go": goSyntax, "golang": goSyntax,
+	"c": cSyntax, "h": cSyntax, "cpp": cSyntax, "c++": cSyntax, "cc": cSyntax, "hpp": cSyntax, "cs": javaSyntax, "csharp": javaSyntax,
+	"java": javaSyntax, "kotlin": javaSyntax, "kt": javaSyntax, "scala": javaSyntax, "swift": javaSyntax,
+	"js": jsSyntax, "javascript": jsSyntax, "jsx": jsSyntax, "ts": jsSyntax, "typescript": jsSyntax, "tsx": jsSyntax,
+	"python": pythonSyntax, "py": pythonSyntax, "python3": pythonSyntax,
+	"rust": rustSyntax, "rs": rustSyntax,
+	"ruby": rubySyntax, "rb": rubySyntax,
+	"sh": shellSyntax, "bash": shellSyntax, "shell": shellSyntax, "zsh": shellSyntax, "console": shellSyntax, "dockerfile": shellSyntax,
+	"sql": sqlSyntax, "postgresql": sqlSyntax, "sqlite": sqlSyntax, "mysql": sqlSyntax,
+	"json": dataSyntax, "yaml": dataSyntax, "yml": dataSyntax, "toml": dataSyntax, "ini": dataSyntax,
+	"css": cssSyntax, "scss": cssSyntax,
+}
+
+// Hints of the language of unlabeled code, checked in order
+var guesses = []struct {
+	re     *regexp.Regexp
+	syntax *syntax
+}{
+	{regexp.MustCompile(`(?m)^package \w+$|\bfunc (\(\w+ \*?\w+\) )?\w+\(|:= `), goSyntax},
+	{regexp.MustCompile(`(?m)^\s*fn \w+|\blet mut\b|\bimpl\b.*\{`), rustSyntax},
+	{regexp.MustCompile(`(?m)^\s*(def \w+\(.*\):|from \w+ import|import \w+$|class \w+(\(.*\))?:$)`), pythonSyntax},
+	{regexp.MustCompile(`(?m)^#include\b|\bint main\(`), cSyntax},
+	{regexp.MustCompile(`\bfunction\b|\bconst \w+ = |=> |\bconsole\.log\(`), jsSyntax},
+	{regexp.MustCompile(`(?m)^(public |private )?(class|interface) \w+.*\{|\bSystem\.out\.`), javaSyntax},
+	{regexp.MustCompile(`(?im)^\s*(select .* from|insert into|create table|update \w+ set)\b`), sqlSyntax},
+	{regexp.MustCompile(`(?m)^(#!/bin/|\$ \w+)`), shellSyntax},
+}
+
+// Highlight colors code of the language with spans of token classes, code of
+// unknown languages is guessed from its content and escaped when that fails
+func Highlight(code, language string) string {
+	s, ok := languages[strings.ToLower(language)]
+	if !ok && language == "" {
+		for _, guess := range guesses {
+			if guess.re.MatchString(code) {
+				s, ok = guess.syntax, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return html.EscapeString(code)
+	}
+	return s.highlight(code)
+}
+
+func (s *syntax) highlight(code string) string {
+	var b strings.Builder
+	span := func(class, text string) {
+		b.WriteString(`<span class="` + class + `">` + html.EscapeString(text) + "</span>")
+	}
+	for i := 0; i < len(code); {
+		c := code[i]
+		rest := code[i:]
+		switch {
+		case s.lineComment(code, i):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			span(classComment, rest[:end])
+			i += end
+		case s.blockCommentAt(code, i):
+			end := s.blockCommentEnd(rest)
+			span(classComment, rest[:end])
+			i += end
+		case s.rawStrings && c == 'r' && (i == 0 || !isIdentByte(code[i-1])) && rawStringEnd(rest) > 0:
+			end := rawStringEnd(rest)
+			span(classString, rest[:end])
+			i += end
+		case s.heredocs && strings.HasPrefix(rest, "<<") && heredocEnd(rest) > 0:
+			body, end := strings.IndexByte(rest, '\n')+1, heredocEnd(rest)
+			b.WriteString(html.EscapeString(rest[:body]))
+			span(classString, rest[body:end])
+			i += end
+		case strings.IndexByte(s.quotes, c) >= 0:
+			end := s.stringEnd(rest)
+			span(classString, rest[:end])
+			i += end
+		case isDigit(c) && (i == 0 || !isIdentByte(code[i-1])):
+			end := 1
+			for end < len(rest) && (isIdentByte(rest[end]) || rest[end] == '.' && end+1 < len(rest) && isDigit(rest[end+1])) {
+				end++
+			}
+			span(classNumber, rest[:end])
+			i += end
+		case isIdentStart(c) || c == '#' || c == '@':
+			end := 1
+			for end < len(rest) && isIdentByte(rest[end]) {
+				end++
+			}
+			word := rest[:end]
+			if s.foldCase {
+				word = strings.ToLower(word)
+			}
+			if s.keywords[word] {
+				span(classKeyword, rest[:end])
+			} else {
+				b.WriteString(html.EscapeString(rest[:end]))
+			}
+			i += end
+		default:
+			b.WriteString(html.EscapeString(rest[:1]))
+			i++
+		}
+	}
+	return b.String()
+}
+
+// lineComment reports whether a line comment starts at code[i]
+func (s *syntax) lineComment(code string, i int) bool {
+	for _, start := range s.lineComments {
+		if !strings.HasPrefix(code[i:], start) {
+			continue
+		}
+		if !s.commentAtWord || i == 0 || code[i-1] == ' ' || code[i-1] == '\t' || code[i-1] == '\n' {
+			return true
+		}
+	}
+	return false
+}
+
+// blockCommentAt reports whether a block comment starts at code[i]
+func (s *syntax) blockCommentAt(code string, i int) bool {
+	if s.blockComment[0] == "" || !strings.HasPrefix(code[i:], s.blockComment[0]) {
+		return false
+	}
+	return !s.lineStartBlock || i == 0 || code[i-1] == '\n'
+}
+
+// blockCommentEnd returns the length of the block comment starting rest,
+// the whole line of the closing delimiter when it is at the start of lines
+func (s *syntax) blockCommentEnd(rest string) int {
+	open, closing := s.blockComment[0], s.blockComment[1]
+	if s.lineStartBlock {
+		end := strings.Index(rest, "\n"+closing)
+		if end < 0 {
+			return len(rest)
+		}
+		end += 1 + len(closing)
+		if eol := strings.IndexByte(rest[end:], '\n'); eol >= 0 {
+			return end + eol
+		}
+		return len(rest)
+	}
+	depth := 0
+	for j := 0; j < len(rest); {
+		switch {
+		case strings.HasPrefix(rest[j:], open) && (depth == 0 || s.nestedComments):
+			depth++
+			j += len(open)
+		case strings.HasPrefix(rest[j:], closing):
+			depth--
+			j += len(closing)
+			if depth == 0 {
+				return j
+			}
+		default:
+			j++
+		}
+	}
+	return len(rest)
+}
+
+// stringEnd returns the length of the string literal starting s
+func (s *syntax) stringEnd(rest string) int {
+	quote := rest[0]
+	if s.tripleQuotes && len(rest) >= 3 && rest[1] == quote && rest[2] == quote {
+		if end := strings.Index(rest[3:], rest[:3]); end >= 0 {
+			return end + 6
+		}
+		return len(rest)
+	}
+	multiline := strings.IndexByte(s.multiline, quote) >= 0
+	for j := 1; j < len(rest); j++ {
+		switch rest[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case '\n':
+			if !multiline {
+				return j
+			}
+		case quote:
+			if s.doubledQuotes && j+1 < len(rest) && rest[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(rest)
+}
+
+// rawStringEnd returns the length of the Rust raw string starting rest,
+// e.g., r#"a "quoted" word"#, or 0 if there is none
+func rawStringEnd(rest string) int {
+	hashes := len(rest[1:]) - len(strings.TrimLeft(rest[1:], "#"))
+	start := 1 + hashes
+	if start >= len(rest) || rest[start] != '"' {
+		return 0
+	}
+	end := strings.Index(rest[start+1:], `"`+strings.Repeat("#", hashes))
+	if end < 0 {
+		return len(rest)
+	}
+	return start + 1 + end + 1 + hashes
+}
+
+// heredocEnd returns the length of the shell here-document starting rest,
+// e.g., <<EOF, up to its terminating line, or 0 if there is none
+func heredocEnd(rest string) int {
+	eol := strings.IndexByte(rest, '\n')
+	if eol < 0 {
+		return 0
+	}
+	header := strings.TrimLeft(strings.TrimLeft(rest[2:eol], "-~ "), `'"`)
+	n := 0
+	for n < len(header) && isIdentByte(header[n]) {
+		n++
+	}
+	if n == 0 || !isIdentStart(header[0]) {
+		return 0
+	}
+	word := header[:n]
+	for j := eol + 1; j < len(rest); {
+		line, _, _ := strings.Cut(rest[j:], "\n")
+		if strings.TrimLeft(line, "\t") == word {
+			return j + len(line)
+		}
+		j += len(line) + 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isIdentByte(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
@@ -0,0 +1,97 @@
+package markdown
+
+import "testing"
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		want     string
+	}{
+		{
+			name:     "keywords, strings and numbers",
+			code:     `return "a<b", 42`,
+			language: "go",
+			want:     `<span class="hl-keyword">return</span> <span class="hl-string">&#34;a&lt;b&#34;</span>, <span class="hl-number">42</span>`,
+		},
+		{
+			name:     "comments",
+			code:     "x = 1 # one\n/* no */",
+			language: "python",
+			want:     "x = <span class=\"hl-number\">1</span> <span class=\"hl-comment\"># one</span>\n/* no */",
+		},
+		{
+			name:     "escaped quotes",
+			code:     `'it\'s' or`,
+			language: "js",
+			want:     `<span class="hl-string">&#39;it\&#39;s&#39;</span> or`,
+		},
+		{
+			name:     "case-insensitive keywords",
+			code:     "SELECT id FROM t1",
+			language: "sql",
+			want:     `<span class="hl-keyword">SELECT</span> id <span class="hl-keyword">FROM</span> t1`,
+		},
+		{
+			name: "guessed language",
+			code: "def f(x):\n    pass",
+			want: "<span class=\"hl-keyword\">def</span> f(x):\n    <span class=\"hl-keyword\">pass</span>",
+		},
+		{
+			name:     "multi-line comments",
+			code:     "/* if\nreturn */ return",
+			language: "go",
+			want:     "<span class=\"hl-comment\">/* if\nreturn */</span> <span class=\"hl-keyword\">return</span>",
+		},
+		{
+			name:     "nested comments",
+			code:     "/* a /* b */ if */ let",
+			language: "rust",
+			want:     `<span class="hl-comment">/* a /* b */ if */</span> <span class="hl-keyword">let</span>`,
+		},
+		{
+			name:     "comments at the start of lines",
+			code:     "=begin\nif end\n=end\nputs",
+			language: "ruby",
+			want:     "<span class=\"hl-comment\">=begin\nif end\n=end</span>\nputs",
+		},
+		{
+			name:     "keywords in raw strings",
+			code:     `r#"say "if" now"# if`,
+			language: "rust",
+			want:     `<span class="hl-string">r#&#34;say &#34;if&#34; now&#34;#</span> <span class="hl-keyword">if</span>`,
+		},
+		{
+			name:     "keywords in multi-line strings",
+			code:     "echo \"one\nif two\" done",
+			language: "sh",
+			want:     "<span class=\"hl-keyword\">echo</span> <span class=\"hl-string\">&#34;one\nif two&#34;</span> <span class=\"hl-keyword\">done</span>",
+		},
+		{
+			name:     "keywords in doubled quote strings",
+			code:     "SELECT 'it''s from' FROM t",
+			language: "sql",
+			want:     `<span class="hl-keyword">SELECT</span> <span class="hl-string">&#39;it&#39;&#39;s from&#39;</span> <span class="hl-keyword">FROM</span> t`,
+		},
+		{
+			name:     "here-documents",
+			code:     "cat <<'EOF'\nif then\nEOF\necho $((a << b))",
+			language: "bash",
+			want:     "cat &lt;&lt;&#39;EOF&#39;\n<span class=\"hl-string\">if then\nEOF</span>\n<span class=\"hl-keyword\">echo</span> $((a &lt;&lt; b))",
+		},
+		{
+			name:     "unknown language",
+			code:     "if a < b",
+			language: "brainfuck",
+			want:     "if a &lt; b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.code, tt.language); got != tt.want {
+				t.Errorf("Highlight() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestFromHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs and inline formatting",
+			html: "<p>Some <strong>bold</strong>, <em>italic</em> and <code>a * b</code></p><p>Second<br>line</p>",
+			want: "Some **bold**, *italic* and `a * b`\n\nSecond\\\nline",
+		},
+		{
+			name: "headings and links",
+			html: `<h2>Title</h2><p>See <a href="https://example.com/a_b">the docs</a> and <a href="#top">top</a></p>`,
+			want: "## Title\n\nSee [the docs](https://example.com/a_b) and top",
+		},
+		{
+			name: "images",
+			html: `<img src="media/a.jpg" alt="A photo"><img src="b (1).png">`,
+			want: "![A photo](media/a.jpg)![](<b (1).png>)",
+		},
+		{
+			name: "nested lists",
+			html: "<ul><li>One<ul><li>Inner</li></ul></li><li>Two</li></ul><ol start=\"3\"><li>Three</li></ol>",
+			want: "- One\n  - Inner\n- Two\n\n3. Three",
+		},
+		{
+			name: "code blocks and quotes",
+			html: `<pre><code class="language-go">x := 1
+fmt.Println(x)</code></pre><blockquote><p>Quoted</p><p>Twice</p></blockquote>`,
+			want: "```go\nx := 1\nfmt.Println(x)\n```\n\n> Quoted\n>\n> Twice",
+		},
+		{
+			name: "code block language of the wrapper",
+			html: `<div class="highlight highlight-source-python"><pre>pass</pre></div>`,
+			want: "```python\npass\n```",
+		},
+		{
+			name: "tables",
+			html: "<table><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td>1</td></tr></table>",
+			want: "| Name | Value |\n| --- | --- |\n| a\\|b | 1 |",
+		},
+		{
+			name: "text is escaped",
+			html: "<p># Not a heading, *not* emphasis &lt;b&gt; snake_case _x_</p><p>1. Not a list</p>",
+			want: "\\# Not a heading, \\*not\\* emphasis \\<b> snake_case \\_x\\_\n\n1\\. Not a list",
+		},
+		{
+			name: "scripts and styles are dropped",
+			html: "<style>p{}</style><p>Text</p><script>alert(1)</script>",
+			want: "Text",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromHTML(tt.html); got != tt.want {
+				t.Errorf("FromHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{
+			name: "paragraphs and inline formatting",
+			md:   "Some **bold**, *italic*, ~~gone~~ and `a < b`\nsame paragraph\\\nnew line",
+			want: "<p>Some <strong>bold</strong>, <em>italic</em>, <del>gone</del> and <code>a &lt; b</code>\nsame paragraph<br>\nnew line</p>",
+		},
+		{
+			name: "headings and rules",
+			md:   "# One\n\n### Three ###\n\n---",
+			want: "<h1>One</h1>\n<h3>Three</h3>\n<hr>",
+		},
+		{
+			name: "links",
+			md:   "[docs](https://example.com/a_(b) \"Title\"), <https://example.com>, https://example.com/x_y. [bad](javascript:alert(1))",
+			want: `<p><a href="https://example.com/a_(b)">docs</a>, <a href="https://example.com">https://example.com</a>, <a href="https://example.com/x_y">https://example.com/x_y</a>. bad</p>`,
+		},
+		{
+			name: "images",
+			md:   "![A *photo*](media/a.jpg)",
+			want: `<p><img src="media/a.jpg" alt="A *photo*"></p>`,
+		},
+		{
+			name: "tight lists",
+			md:   "- One\n  - Inner\n- Two\n\n3. Three\n4. Four",
+			want: "<ul>\n<li>One\n<ul>\n<li>Inner</li>\n</ul></li>\n<li>Two</li>\n</ul>\n<ol start=\"3\">\n<li>Three</li>\n<li>Four</li>\n</ol>",
+		},
+		{
+			name: "loose lists",
+			md:   "1. One\n\n   More\n\n2. Two",
+			want: "<ol>\n<li><p>One</p>\n<p>More</p></li>\n<li><p>Two</p></li>\n</ol>",
+		},
+		{
+			name: "code blocks",
+			md:   "```go\nif a < b {\n\n}\n```",
+			want: "<pre><code class=\"language-go\"><span class=\"hl-keyword\">if</span> a &lt; b {\n\n}\n</code></pre>",
+		},
+		{
+			name: "quotes",
+			md:   "> Quoted\n>\n> - item",
+			want: "<blockquote>\n<p>Quoted</p>\n<ul>\n<li>item</li>\n</ul>\n</blockquote>",
+		},
+		{
+			name: "tables",
+			md:   "| Name | Value |\n| :--- | ---: |\n| a\\|b | **1** |",
+			want: "<table>\n<thead>\n<tr>\n<th align=\"left\">Name</th>\n<th align=\"right\">Value</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">a|b</td>\n<td align=\"right\"><strong>1</strong></td>\n</tr>\n</tbody>\n</table>",
+		},
+		{
+			name: "HTML blocks pass through",
+			md:   "<p class=\"back-reference\">Seen <a href=\"x\">before</a></p>\n\nAfter",
+			want: "<p class=\"back-reference\">Seen <a href=\"x\">before</a></p>\n<p>After</p>",
+		},
+		{
+			name: "escapes",
+			md:   "\\*not\\* snake_case 2 * 3 & 4 &amp; <3",
+			want: "<p>*not* snake_case 2 * 3 &amp; 4 &amp; &lt;3</p>",
+		},
+		{
+			name: "years don't start lists",
+			md:   "It happened in\n2020. Then more",
+			want: "<p>It happened in\n2020. Then more</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.md); got != tt.want {
+				t.Errorf("ToHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToHTML_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		md   string
+		want string
+	}{
+		{
+			name: "unclosed emphasis stays literal",
+			md:   "**unclosed bold and *unclosed italic, a ** b ** c",
+			want: "<p>**unclosed bold and *unclosed italic, a ** b ** c</p>",
+		},
+		{
+			name: "unclosed code span stays literal",
+			md:   "`unclosed 2 < 3",
+			want: "<p>`unclosed 2 &lt; 3</p>",
+		},
+		{
+			name: "unclosed fence runs to the end",
+			md:   "```\nunclosed <fence>",
+			want: "<pre><code>unclosed &lt;fence&gt;\n</code></pre>",
+		},
+		{
+			name: "unclosed link",
+			md:   "[unclosed link(https://example.com",
+			want: `<p>[unclosed link(<a href="https://example.com">https://example.com</a></p>`,
+		},
+		{
+			name: "mixed list types start new lists",
+			md:   "- one\n- two\n1. three\n2. four",
+			want: "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n<ol>\n<li>three</li>\n<li>four</li>\n</ol>",
+		},
+		{
+			name: "ordered list nested in a bullet list",
+			md:   "- one\n  1. inner",
+			want: "<ul>\n<li>one\n<ol>\n<li>inner</li>\n</ol></li>\n</ul>",
+		},
+		{
+			name: "empty list items",
+			md:   "- \n-",
+			want: "<ul>\n<li></li>\n<li></li>\n</ul>",
+		},
+		{
+			name: "ragged table rows are padded or cut to the header",
+			md:   "| a | b |\n| --- | --- |\n| 1 |\n| 1 | 2 | 3 |",
+			want: "<table>\n<thead>\n<tr>\n<th>a</th>\n<th>b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td>1</td>\n<td></td>\n</tr>\n<tr>\n<td>1</td>\n<td>2</td>\n</tr>\n</tbody>\n</table>",
+		},
+		{
+			name: "delimiter row not matching the header is no table",
+			md:   "| a |\n| --- | --- |",
+			want: "<p>| a |\n| --- | --- |</p>",
+		},
+		{
+			name: "empty heading",
+			md:   "#",
+			want: "<h1></h1>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.md); got != tt.want {
+				t.Errorf("ToHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromHTML_Malformed(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "unclosed inline elements",
+			html: "<p><b>unclosed <i>nested</p>",
+			want: "**unclosed *nested***",
+		},
+		{
+			name: "mixed list types",
+			html: "<ul><li>a<ol><li>b</li></ol></li></ul>",
+			want: "- a\n  1. b",
+		},
+		{
+			name: "orphan list item",
+			html: "<li>orphan</li>",
+			want: "orphan",
+		},
+		{
+			name: "ragged table rows are padded",
+			html: "<table><tr><th>a</th><th>b</th></tr><tr><td>1</td></tr><tr><td>1</td><td>2</td><td>3</td></tr></table>",
+			want: "| a | b |  |\n| --- | --- | --- |\n| 1 |  |  |\n| 1 | 2 | 3 |",
+		},
+		{
+			name: "blocks in table cells are joined",
+			html: "<table><tr><td><p>a</p><p>b</p></td></tr></table>",
+			want: "| a b |\n| --- |",
+		},
+		{
+			name: "empty elements",
+			html: "<table></table><em></em><ul></ul>",
+			want: "",
+		},
+		{
+			name: "spaces inside emphasis move out",
+			html: "<strong> space </strong>x",
+			want: "**space** x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromHTML(tt.html); got != tt.want {
+				t.Errorf("FromHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// unsafeLinks returns the links and image sources of rendered HTML which
+// would run code, e.g., javascript: URLs, and the scripts in it
+func unsafeLinks(t *testing.T, rendered string) []string {
+	doc, err := html.Parse(strings.NewReader(rendered))
+	if err != nil {
+		t.Fatalf("failed to parse rendered HTML: %v", err)
+	}
+	var found []string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if n.DataAtom == atom.Script {
+				found = append(found, "<script>")
+			}
+			for _, a := range n.Attr {
+				if (a.Key == "href" || a.Key == "src") && !safeURL(a.Val, n.DataAtom == atom.Img) {
+					found = append(found, a.Val)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return found
+}
+
+func FuzzToHTML(f *testing.F) {
+	for _, seed := range []string{
+		"Some **bold**, *italic*, ~~gone~~ and `code`\\\nnew line",
+		"# One\n\n---\n\n> Quoted\n>\n> - item",
+		"[docs](https://example.com/a_(b) \"Title\"), <https://example.com> [bad](javascript:alert(1))",
+		"- One\n  - Inner\n- Two\n\n3. Three\n\n   More",
+		"| Name | Value |\n| :--- | ---: |\n| a\\|b | **1** |\n| ragged |",
+		"```go\nif a < b {\n\n}\n",
+		"**unclosed *emphasis _and `code",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, md string) {
+		got := ToHTML(md)
+		if utf8.ValidString(md) && !utf8.ValidString(got) {
+			t.Errorf("invalid UTF-8 in %q", got)
+		}
+		// Raw HTML passes through, everything else must not run code
+		if !strings.Contains(md, "<") {
+			if unsafe := unsafeLinks(t, got); len(unsafe) > 0 {
+				t.Errorf("unsafe links %q in %q", unsafe, got)
+			}
+		}
+	})
+}
+
+func FuzzFromHTML(f *testing.F) {
+	for _, seed := range []string{
+		"<p>Some <strong>bold</strong>, <em>italic</em> and <code>a * b</code></p><p>Second<br>line</p>",
+		`<h2>Title</h2><p>See <a href="https://example.com/a_b">the docs</a> and <a href="javascript:alert(1)">bad</a></p>`,
+		"<ul><li>One<ol><li>Inner</li></ol></li><li>Two</li></ul>",
+		"<pre><code class=\"language-go\">x := 1</code></pre><blockquote><p>Quoted</p></blockquote>",
+		"<table><tr><th>a</th></tr><tr><td>1</td><td>2</td></tr></table>",
+		"<p><b>unclosed <i>nested</p><script>alert(1)</script>",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, content string) {
+		md := FromHTML(content)
+		if utf8.ValidString(content) && !utf8.ValidString(md) {
+			t.Errorf("invalid UTF-8 in %q", md)
+		}
+		// Markdown of any page renders without scripts or code running links
+		if unsafe := unsafeLinks(t, ToHTML(md)); len(unsafe) > 0 {
+			t.Errorf("unsafe links %q rendered from %q", unsafe, md)
+		}
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	// Content converted from HTML renders to the same structure
+	source := `<h2>News</h2><p>A <a href="https://example.com">link</a>, <strong>bold</strong> and 2 * 3 &lt; 7_</p>` +
+		`<ul><li>One</li><li>Two</li></ul><blockquote><p>Quote</p></blockquote><img src="a.png" alt="A">`
+	want := `<h2>News</h2>
+<p>A <a href="https://example.com">link</a>, <strong>bold</strong> and 2 * 3 &lt; 7_</p>
+<ul>
+<li>One</li>
+<li>Two</li>
+</ul>
+<blockquote>
+<p>Quote</p>
+</blockquote>
+<p><img src="a.png" alt="A"></p>`
+	if got := ToHTML(FromHTML(source)); got != want {
+		t.Errorf("round trip =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for text, want := range map[string]string{
+		"plain text":           "plain text",
+		"snake_case and _x_":   "snake_case and \\_x\\_",
+		"[link](x) *a* `b` <c": "\\[link\\](x) \\*a\\* \\`b\\` \\<c",
+	} {
+		escaped := Escape(text)
+		if escaped != want {
+			t.Errorf("Escape(%q) = %q, want %q", text, escaped, want)
+		}
+		if got := ToHTML(escaped); !strings.Contains(got, strings.ReplaceAll(text, "<", "&lt;")) {
+			t.Errorf("expected %q to render literally, got %q", text, got)
+		}
+	}
+}
@@ -0,0 +1,636 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ToHTML renders Markdown to HTML. Blocks starting with an HTML tag are kept
+// as they are, so snippets like back-references pass through.
+func ToHTML(md string) string {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	md = strings.ReplaceAll(md, "\t", "    ")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(md, "\n"), false)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+var (
+	fenceRe     = regexp.MustCompile("^(`{3,}|~{3,})\\s*([^`\\s]*)")
+	headingRe   = regexp.MustCompile(`^(#{1,6})(?:\s+(.*))?$`)
+	closingRe   = regexp.MustCompile(`(?:^|\s+)#+\s*$`)
+	hrRe        = regexp.MustCompile(`^(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	listRe      = regexp.MustCompile(`^([-*+]|(\d{1,9})[.)])(?:( +)(.*))?$`)
+	delimiterRe = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+	htmlBlockRe = regexp.MustCompile(`(?i)^<(?:!--|/?(?:address|article|aside|audio|blockquote|details|dialog|div|dl|dd|dt|fieldset|figcaption|figure|footer|form|h[1-6]|header|hr|iframe|img|li|main|nav|ol|p|picture|pre|section|summary|table|tbody|td|tfoot|th|thead|tr|ul|video)(?:[\s/>]|$))`)
+)
+
+// renderBlocks renders lines as blocks, tight list items render their
+// paragraphs without <p>
+func renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		switch {
+		case strings.TrimSpace(trimmed) == "":
+			i++
+		case fenceRe.MatchString(trimmed):
+			i = renderFence(b, lines, i)
+		case headingRe.MatchString(trimmed):
+			m := headingRe.FindStringSubmatch(trimmed)
+			text := strings.TrimSpace(closingRe.ReplaceAllString(m[2], ""))
+			level := strconv.Itoa(len(m[1]))
+			b.WriteString("<h" + level + ">" + renderInline(text) + "</h" + level + ">\n")
+			i++
+		case hrRe.MatchString(trimmed):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			i = renderQuote(b, lines, i)
+		case listRe.MatchString(trimmed):
+			i = renderList(b, lines, i)
+		case htmlBlockRe.MatchString(trimmed):
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				b.WriteString(lines[i] + "\n")
+			}
+		case i+1 < len(lines) && isTable(trimmed, lines[i+1]):
+			i = renderTable(b, lines, i)
+		default:
+			i = renderParagraph(b, lines, i, tight)
+		}
+	}
+}
+
+func renderFence(b *strings.Builder, lines []string, i int) int {
+	m := fenceRe.FindStringSubmatch(strings.TrimLeft(lines[i], " "))
+	end := i + 1
+	for end < len(lines) {
+		closing := strings.TrimSpace(lines[end])
+		if len(closing) >= len(m[1]) && strings.Trim(closing, m[1][:1]) == "" {
+			break
+		}
+		end++
+	}
+	class := ""
+	if m[2] != "" {
+		class = ` class="language-` + html.EscapeString(m[2]) + `"`
+	}
+	code := strings.Join(lines[i+1:min(end, len(lines))], "\n")
+	if code != "" {
+		code = Highlight(code, m[2]) + "\n"
+	}
+	b.WriteString("<pre><code" + class + ">" + code + "</code></pre>\n")
+	return end + 1
+}
+
+func renderQuote(b *strings.Builder, lines []string, i int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		rest, ok := strings.CutPrefix(strings.TrimLeft(lines[i], " "), ">")
+		if !ok {
+			break
+		}
+		inner = append(inner, strings.TrimPrefix(rest, " "))
+	}
+	b.WriteString("<blockquote>\n")
+	renderBlocks(b, inner, false)
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+// listMarker describes the marker of a list item
+type listMarker struct {
+	indent  int    // Spaces before the marker
+	width   int    // Columns until the content of the item
+	ordered bool   // Whether it's numbered
+	symbol  byte   // "-", "*", "+" for bullets, "." or ")" when numbered
+	start   int    // Number of an ordered item
+	text    string // Content on the marker line
+}
+
+func parseMarker(line string) (listMarker, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	m := listRe.FindStringSubmatch(trimmed)
+	if m == nil || hrRe.MatchString(trimmed) {
+		return listMarker{}, false
+	}
+	marker := listMarker{
+		indent: len(line) - len(trimmed),
+		symbol: m[1][len(m[1])-1],
+		text:   m[4],
+	}
+	if m[2] != "" {
+		marker.ordered = true
+		marker.start, _ = strconv.Atoi(m[2])
+	}
+	spaces := len(m[3])
+	if spaces == 0 || spaces > 4 {
+		// Content indented further is part of the item
+		spaces = 1
+		if m[3] != "" {
+			marker.text = m[3][1:] + m[4]
+		}
+	}
+	marker.width = marker.indent + len(m[1]) + spaces
+	return marker, true
+}
+
+func renderList(b *strings.Builder, lines []string, i int) int {
+	first, _ := parseMarker(lines[i])
+	sameList := func(line string) (listMarker, bool) {
+		m, ok := parseMarker(line)
+		return m, ok && m.ordered == first.ordered && m.symbol == first.symbol && m.indent < first.width
+	}
+
+	var items [][]string
+	var item []string
+	width := first.width
+	loose := false
+	for i < len(lines) {
+		line := lines[i]
+		trimmed := strings.TrimLeft(line, " ")
+		if m, ok := sameList(line); ok && len(line)-len(trimmed) < width {
+			if item != nil {
+				items = append(items, item)
+			}
+			item, width = []string{m.text}, m.width
+			i++
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			// Blank lines continue the list when it goes on after them
+			next := i
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next == len(lines) {
+				break
+			}
+			_, isItem := sameList(lines[next])
+			if !isItem && indentation(lines[next]) < width {
+				break
+			}
+			loose = true
+			for ; i < next; i++ {
+				item = append(item, "")
+			}
+			continue
+		}
+		if indentation(line) >= width {
+			item = append(item, line[width:])
+			i++
+			continue
+		}
+		// A paragraph continues on unindented lines
+		if last := item[len(item)-1]; strings.TrimSpace(last) != "" && !startsBlock(trimmed) {
+			item = append(item, trimmed)
+			i++
+			continue
+		}
+		break
+	}
+	items = append(items, item)
+
+	if first.ordered {
+		if first.start != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(first.start) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+	for _, item := range items {
+		var inner strings.Builder
+		renderBlocks(&inner, item, !loose)
+		b.WriteString("<li>" + strings.TrimSuffix(inner.String(), "\n") + "</li>\n")
+	}
+	if first.ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// isTable reports whether a header row and a delimiter row start a table
+func isTable(header, delimiter string) bool {
+	delimiter = strings.TrimSpace(delimiter)
+	if !strings.Contains(header, "|") || !delimiterRe.MatchString(delimiter) {
+		return false
+	}
+	return len(splitRow(header)) == len(splitRow(delimiter))
+}
+
+func renderTable(b *strings.Builder, lines []string, i int) int {
+	header := splitRow(lines[i])
+	var align []string
+	for _, cell := range splitRow(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			align = append(align, ` align="center"`)
+		case strings.HasSuffix(cell, ":"):
+			align = append(align, ` align="right"`)
+		case strings.HasPrefix(cell, ":"):
+			align = append(align, ` align="left"`)
+		default:
+			align = append(align, "")
+		}
+	}
+	row := func(cells []string, tag string) {
+		b.WriteString("<tr>\n")
+		for j := range header {
+			cell := ""
+			if j < len(cells) {
+				cell = cells[j]
+			}
+			b.WriteString("<" + tag + align[j] + ">" + renderInline(cell) + "</" + tag + ">\n")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	row(header, "th")
+	b.WriteString("</thead>\n")
+	i += 2
+	if i < len(lines) && strings.Contains(lines[i], "|") {
+		b.WriteString("<tbody>\n")
+		for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+			row(splitRow(lines[i]), "td")
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+	return i
+}
+
+// splitRow splits a table row into cells, escaped pipes stay in the cell
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	start := 0
+	for j := 0; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+		case '|':
+			cells = append(cells, strings.TrimSpace(line[start:j]))
+			start = j + 1
+		}
+	}
+	return append(cells, strings.TrimSpace(line[start:]))
+}
+
+func renderParagraph(b *strings.Builder, lines []string, i int, tight bool) int {
+	var text []string
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if strings.TrimSpace(trimmed) == "" || len(text) > 0 && startsBlock(trimmed) {
+			break
+		}
+		// Two trailing spaces break the line
+		if strings.HasSuffix(trimmed, "  ") {
+			trimmed = strings.TrimRight(trimmed, " ") + `\`
+		}
+		text = append(text, trimmed)
+	}
+	content := renderInline(strings.TrimSuffix(strings.Join(text, "\n"), `\`))
+	if tight {
+		b.WriteString(content + "\n")
+	} else {
+		b.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+// startsBlock reports whether a line interrupts a paragraph
+func startsBlock(trimmed string) bool {
+	if fenceRe.MatchString(trimmed) || headingRe.MatchString(trimmed) || hrRe.MatchString(trimmed) ||
+		strings.HasPrefix(trimmed, ">") || htmlBlockRe.MatchString(trimmed) {
+		return true
+	}
+	// Only lists starting with one interrupt a paragraph, e.g., not a year
+	m, ok := parseMarker(trimmed)
+	return ok && strings.TrimSpace(m.text) != "" && (!m.ordered || m.start == 1)
+}
+
+var (
+	autolinkRe = regexp.MustCompile(`^<(https?://[^\s<>]+|mailto:[^\s<>]+)>`)
+	inlineTag  = regexp.MustCompile(`^(?:<[a-zA-Z][a-zA-Z0-9-]*(?:\s+[a-zA-Z_:][\w.:-]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*\s*/?>|</[a-zA-Z][a-zA-Z0-9-]*\s*>)`)
+	entityRe   = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+	bareURLRe  = regexp.MustCompile(`^https?://[^\s<>"]+`)
+)
+
+// renderInline renders the content of a paragraph, heading or table cell
+func renderInline(s string) string {
+	var b strings.Builder
+	// Delimiters whose closing search failed from an offset fail from any
+	// later one too, remembering them keeps unmatched runs linear
+	unclosed := make(map[string]int)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			if code, n := codeSpan(s[i:]); n > 0 {
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n
+				continue
+			}
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if text, dest, n := linkAt(s[i+1:]); n > 0 {
+				if safeURL(dest, true) {
+					b.WriteString(`<img src="` + html.EscapeString(dest) + `" alt="` + html.EscapeString(plain(text)) + `">`)
+				} else {
+					b.WriteString(html.EscapeString(plain(text)))
+				}
+				i += 1 + n
+				continue
+			}
+		case c == '[':
+			if text, dest, n := linkAt(s[i:]); n > 0 {
+				if safeURL(dest, false) {
+					b.WriteString(`<a href="` + html.EscapeString(dest) + `">` + renderInline(text) + "</a>")
+				} else {
+					b.WriteString(renderInline(text))
+				}
+				i += n
+				continue
+			}
+		case c == '<':
+			if m := autolinkRe.FindStringSubmatch(s[i:]); m != nil {
+				b.WriteString(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(strings.TrimPrefix(m[1], "mailto:")) + "</a>")
+				i += len(m[0])
+				continue
+			}
+			if tag := inlineTag.FindString(s[i:]); tag != "" {
+				b.WriteString(tag)
+				i += len(tag)
+				continue
+			}
+		case c == '&':
+			if entity := entityRe.FindString(s[i:]); entity != "" {
+				b.WriteString(entity)
+				i += len(entity)
+				continue
+			}
+		case c == 'h' && (i == 0 || !isWordByte(s[i-1])):
+			if url := bareURL(s[i:]); url != "" {
+				href := unescape(url)
+				b.WriteString(`<a href="` + html.EscapeString(href) + `">` + html.EscapeString(href) + "</a>")
+				i += len(url)
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			if rendered, n := emphasis(s, i, unclosed); n > 0 {
+				b.WriteString(rendered)
+				i += n
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// codeSpan returns the code of a span starting at s[0] and its length
+func codeSpan(s string) (string, int) {
+	ticks := len(s) - len(strings.TrimLeft(s, "`"))
+	for j := ticks; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		run := len(s[j:]) - len(strings.TrimLeft(s[j:], "`"))
+		if run == ticks {
+			code := strings.ReplaceAll(s[ticks:j], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			return code, j + run
+		}
+		j += run
+	}
+	return "", 0
+}
+
+// linkAt parses "[text](destination "title")" at s[0], returning its text,
+// destination and length
+func linkAt(s string) (string, string, int) {
+	depth := 0
+	end := -1
+	for j := 0; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			if _, n := codeSpan(s[j:]); n > 0 {
+				j += n - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", "", 0
+	}
+	text := s[1:end]
+	j := end + 2
+	for j < len(s) && s[j] == ' ' {
+		j++
+	}
+
+	var dest string
+	if j < len(s) && s[j] == '<' {
+		closing := strings.IndexAny(s[j:], ">\n")
+		if closing < 0 || s[j+closing] != '>' {
+			return "", "", 0
+		}
+		dest = s[j+1 : j+closing]
+		j += closing + 1
+	} else {
+		start, parens := j, 0
+	scan:
+		for ; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+			case '(':
+				parens++
+			case ')':
+				if parens == 0 {
+					break scan
+				}
+				parens--
+			case ' ', '\n':
+				break scan
+			}
+		}
+		dest = s[start:min(j, len(s))]
+	}
+
+	for j < len(s) && (s[j] == ' ' || s[j] == '\n') {
+		j++
+	}
+	if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+		closing := strings.IndexByte(s[j+1:], s[j])
+		if closing < 0 {
+			return "", "", 0
+		}
+		j += closing + 2
+		for j < len(s) && s[j] == ' ' {
+			j++
+		}
+	}
+	if j >= len(s) || s[j] != ')' {
+		return "", "", 0
+	}
+	return text, unescape(dest), j + 1
+}
+
+// emphasis renders emphasis, strong emphasis or a strikethrough opened at
+// s[i], returning the HTML and the length of the source. Delimiters not
+// closed after their offset in unclosed are skipped, failed ones are added.
+func emphasis(s string, i int, unclosed map[string]int) (string, int) {
+	for _, d := range []string{"**", "__", "~~", "*", "_"} {
+		if !strings.HasPrefix(s[i:], d) || d == "~" {
+			continue
+		}
+		start := i + len(d)
+		if start >= len(s) || isSpace(s[start]) {
+			continue
+		}
+		// Underscores inside words don't emphasize
+		if d[0] == '_' && i > 0 && isWordByte(s[i-1]) {
+			continue
+		}
+		if from, ok := unclosed[d]; ok && start >= from {
+			continue
+		}
+		end := closingDelimiter(s, start, d)
+		if end < 0 {
+			unclosed[d] = start
+			continue
+		}
+		tag := map[string]string{"**": "strong", "__": "strong", "~~": "del", "*": "em", "_": "em"}[d]
+		return "<" + tag + ">" + renderInline(s[start:end]) + "</" + tag + ">", end + len(d) - i
+	}
+	return "", 0
+}
+
+// closingDelimiter finds the delimiter closing emphasis opened before from
+func closingDelimiter(s string, from int, d string) int {
+	for j := from; j < len(s); j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+			continue
+		case s[j] == '`':
+			if _, n := codeSpan(s[j:]); n > 0 {
+				j += n - 1
+			}
+			continue
+		case !strings.HasPrefix(s[j:], d):
+			continue
+		}
+		end := j + len(d)
+		if len(d) == 1 && end < len(s) && s[end] == d[0] {
+			// A double delimiter belongs to strong emphasis inside
+			j++
+			continue
+		}
+		if j == from || isSpace(s[j-1]) {
+			continue
+		}
+		if d[0] == '_' && end < len(s) && isWordByte(s[end]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// bareURL returns the URL at the start of s without trailing punctuation
+func bareURL(s string) string {
+	url := bareURLRe.FindString(s)
+	for url != "" {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,:;!?'\"*_~", last) >= 0:
+		case last == ')' && strings.Count(url, "(") < strings.Count(url, ")"):
+		default:
+			return url
+		}
+		url = url[:len(url)-1]
+	}
+	return ""
+}
+
+// safeURL reports whether a link or image may point to dest
+func safeURL(dest string, image bool) bool {
+	lower := strings.ToLower(strings.TrimSpace(dest))
+	if image && strings.HasPrefix(lower, "data:image/") {
+		return true
+	}
+	for _, scheme := range []string{"javascript:", "vbscript:", "data:"} {
+		if strings.HasPrefix(lower, scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+// plain returns the text of inline Markdown, e.g., for alt attributes
+func plain(s string) string {
+	s = strings.NewReplacer("**", "", "__", "", "~~", "", "`", "").Replace(s)
+	return unescape(s)
+}
+
+// unescape removes backslash escapes
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/urlnorm"
+)
+
+// feedColumns hold the identity of a feed, rows move to the new feed URL.
+// Rows of both URLs keep the ones of the new URL.
+var feedColumns = []struct{ table, column string }{
+	{"feed", "url"},
+	{"generation_history", "feed_url"},
+	{"feed_offset", "url"},
+	{"queue_item", "feed_url"},
+	{"deferred_item", "feed_url"},
+	{"archive_item", "feed_url"},
+	{"high_water_mark", "feed_url"},
+}
+
+// staleFeedTables answered for the old feed URL only, e.g., validators of
+// its server, and are forgotten
+var staleFeedTables = []struct{ table, column string }{
+	{"feed_validator", "url"},
+	{"push_lease", "feed_url"},
+}
+
+// itemColumns hold links of items, rewritten when the source moved to
+// another domain so seen items, caches and clicks carry over
+var itemColumns = []struct{ table, column string }{
+	{"processed_item", "url"},
+	{"parser_cache", "url"},
+	{"agent_cache", "url"},
+	{"archive_item", "guid"},
+	{"archive_item", "link"},
+	{"high_water_mark", "guid"},
+	{"reading_event", "url"},
+	{"reading_event", "item_url"},
+}
+
+// runResource handles `resource migrate -from <url> -to <url> [-dry-run]`
+func runResource(ctx context.Context, database *sql.DB, conf config.Config, args []string) error {
+	if len(args) == 0 || args[0] != "migrate" {
+		return errors.New("usage: myfeed resource migrate -from <url> -to <url> [-dry-run]")
+	}
+	migrateFlags := flag.NewFlagSet("resource migrate", flag.ExitOnError)
+	from := migrateFlags.String("from", "", "feed URL the history was recorded for")
+	to := migrateFlags.String("to", "", "new feed URL of the source")
+	dryRun := migrateFlags.Bool("dry-run", false, "report what would change without changing it")
+	migrateFlags.Parse(args[1:])
+	if *from == "" || *to == "" || *from == *to {
+		return errors.New("usage: myfeed resource migrate -from <url> -to <url> [-dry-run]")
+	}
+
+	changes, err := migrateResource(ctx, database, *from, *to, *dryRun)
+	if err != nil {
+		return err
+	}
+	total := int64(0)
+	for _, change := range changes {
+		slog.Info("migrated rows", "table", change.table, "column", change.column, "rows", change.rows)
+		total += change.rows
+	}
+	slog.Info("migrated resource", "from", *from, "to", *to, "rows", total, "dry_run", *dryRun)
+
+	for _, r := range conf.Resources {
+		if r.FeedURL == *from {
+			slog.Warn("the config still uses the old feed URL, update feed_url of the resource", "url", *from)
+			break
+		}
+	}
+	return nil
+}
+
+// migration is the number of rows changed in a column
+type migration struct {
+	table, column string
+	rows          int64
+}
+
+// migrateResource moves the history of a feed to its new URL within one
+// transaction, rolled back on a dry run. Links of items are rewritten too
+// when the new URL is on another domain.
+func migrateResource(ctx context.Context, database *sql.DB, from, to string, dryRun bool) ([]migration, error) {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start migration with %w", err)
+	}
+	defer tx.Rollback()
+
+	var changes []migration
+	record := func(table, column string, res sql.Result) {
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			return
+		}
+		if last := len(changes) - 1; last >= 0 && changes[last].table == table && changes[last].column == column {
+			changes[last].rows += n
+			return
+		}
+		changes = append(changes, migration{table: table, column: column, rows: n})
+	}
+
+	for _, c := range feedColumns {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE OR IGNORE %s SET %s = ?2 WHERE %[2]s = ?1", c.table, c.column), from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate %s with %w", c.table, err)
+		}
+		record(c.table, c.column, res)
+		// Conflicting rows are left behind with the old URL
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", c.table, c.column), from); err != nil {
+			return nil, fmt.Errorf("failed to clean up %s with %w", c.table, err)
+		}
+	}
+	for _, c := range staleFeedTables {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", c.table, c.column), from)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clean up %s with %w", c.table, err)
+		}
+		record(c.table, c.column, res)
+	}
+
+	prefixes, canonical := originRewrites(from, to)
+	for _, c := range itemColumns {
+		for old, replacement := range prefixes {
+			res, err := rewritePrefix(ctx, tx, c.table, c.column, old, replacement)
+			if err != nil {
+				return nil, err
+			}
+			record(c.table, c.column, res)
+		}
+	}
+	if canonical[0] != "" {
+		res, err := rewritePrefix(ctx, tx, "processed_item", "canonical_url", canonical[0], canonical[1])
+		if err != nil {
+			return nil, err
+		}
+		record("processed_item", "canonical_url", res)
+	}
+
+	if dryRun {
+		return changes, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit migration with %w", err)
+	}
+	return changes, nil
+}
+
+// rewritePrefix replaces the origin at the start of links in a column, e.g.,
+// "https://old.example/post" to "https://new.example/post"
+func rewritePrefix(ctx context.Context, tx *sql.Tx, table, column, old, replacement string) (sql.Result, error) {
+	query := fmt.Sprintf(
+		"UPDATE OR IGNORE %s SET %s = ?2 || substr(%[2]s, length(?1) + 1) "+
+			"WHERE %[2]s = ?1 OR substr(%[2]s, 1, length(?1) + 1) IN (?1 || '/', ?1 || '?', ?1 || '#')",
+		table, column)
+	res, err := tx.ExecContext(ctx, query, old, replacement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite links of %s with %w", table, err)
+	}
+	return res, nil
+}
+
+// originRewrites maps the origins of the old feed's site to the new one,
+// with and without "www." and over both schemes. The canonical pair rewrites
+// urlnorm.Canonical keys, both are empty when the site stays on its domain.
+func originRewrites(from, to string) (map[string]string, [2]string) {
+	oldURL, err := url.Parse(from)
+	if err != nil || oldURL.Host == "" {
+		return nil, [2]string{}
+	}
+	newURL, err := url.Parse(to)
+	if err != nil || newURL.Host == "" {
+		return nil, [2]string{}
+	}
+	oldHost := urlnorm.Host(from)
+	newHost := urlnorm.Host(to)
+	if oldHost == newHost {
+		return nil, [2]string{}
+	}
+
+	newOrigin := newURL.Scheme + "://" + strings.ToLower(newURL.Host)
+	prefixes := make(map[string]string)
+	for _, scheme := range []string{"http", "https"} {
+		for _, host := range []string{oldHost, "www." + oldHost} {
+			prefixes[scheme+"://"+host] = newOrigin
+		}
+	}
+	return prefixes, [2]string{"https://" + oldHost, "https://" + newHost}
+}
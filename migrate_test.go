@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/scipunch/myfeed/cache"
+	"github.com/scipunch/myfeed/db"
+)
+
+func TestMigrateResource(t *testing.T) {
+	ctx := context.Background()
+	database, err := initDB(ctx, filepath.Join(t.TempDir(), "myfeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	queries := db.New(database)
+	cacheDB, err := cache.NewCacheFromDB(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const from, to = "https://www.old.example/feed.xml", "https://new.example/rss"
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(queries.SaveGenerationHistory(ctx, db.SaveGenerationHistoryParams{FeedUrl: from, LastProcessedAt: 100, CreatedAt: 1}))
+	must(queries.SaveHighWaterMark(ctx, db.SaveHighWaterMarkParams{FeedUrl: from, Guid: "https://old.example/post", PublishedAt: 100, CreatedAt: 1}))
+	must(queries.SaveFeedValidator(ctx, db.SaveFeedValidatorParams{Url: from, Etag: `"abc"`}))
+	must(queries.SaveProcessedItem(ctx, db.SaveProcessedItemParams{CanonicalUrl: "https://old.example/post", Url: "http://old.example/post?utm_source=x", Title: "Post", ProcessedAt: 1}))
+	must(queries.SaveProcessedItem(ctx, db.SaveProcessedItemParams{CanonicalUrl: "https://old.example.org/other", Url: "https://old.example.org/other", Title: "Other", ProcessedAt: 1}))
+	must(cacheDB.SetParserOutput("https://www.old.example/post", "web", []byte("parsed")))
+
+	changes, err := migrateResource(ctx, database, from, to, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if len(changes) == 0 {
+		t.Fatal("expected the dry run to report changes")
+	}
+	if _, err := queries.GetLatestGenerationTimestamp(ctx, from); err != nil {
+		t.Fatalf("expected the dry run to change nothing: %v", err)
+	}
+
+	if _, err := migrateResource(ctx, database, from, to, false); err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if ts, err := queries.GetLatestGenerationTimestamp(ctx, to); err != nil || ts != 100 {
+		t.Errorf("expected the history under the new URL, got %d, %v", ts, err)
+	}
+	if mark, err := queries.GetLatestHighWaterMark(ctx, to); err != nil || mark.Guid != "https://new.example/post" {
+		t.Errorf("expected the high-water mark with a rewritten GUID, got %+v, %v", mark, err)
+	}
+	if _, err := queries.GetFeedValidator(ctx, to); err == nil {
+		t.Error("expected validators of the old server to be forgotten")
+	}
+	if item, err := queries.GetProcessedItem(ctx, "https://new.example/post"); err != nil || item.Url != "https://new.example/post?utm_source=x" {
+		t.Errorf("expected the processed item on the new domain, got %+v, %v", item, err)
+	}
+	if _, err := queries.GetProcessedItem(ctx, "https://old.example.org/other"); err != nil {
+		t.Errorf("expected links of other domains to stay: %v", err)
+	}
+	if data, ok, _ := cacheDB.GetParserOutput("https://new.example/post", "web"); !ok || string(data) != "parsed" {
+		t.Errorf("expected the cache under the new link, got %q", data)
+	}
+}
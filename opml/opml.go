@@ -0,0 +1,74 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Document is the root element of an OPML file
+type Document struct {
+	XMLName xml.Name  `xml:"opml"`
+	Title   string    `xml:"head>title"`
+	Body    []Outline `xml:"body>outline"`
+}
+
+// Outline is either a folder (has children) or a feed (has xmlUrl)
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr"`
+	Type     string    `xml:"type,attr"`
+	XMLURL   string    `xml:"xmlUrl,attr"`
+	HTMLURL  string    `xml:"htmlUrl,attr"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Feed is a single subscription extracted from an OPML document
+type Feed struct {
+	Title    string
+	URL      string
+	Category string // Slash-separated folder path, empty for top level feeds
+}
+
+// Parse reads an OPML document and returns all feeds in document order.
+// Nested folder names are joined with "/" into the feed category.
+func Parse(r io.Reader) ([]Feed, error) {
+	var doc Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode OPML: %w", err)
+	}
+
+	var feeds []Feed
+	collect(doc.Body, "", &feeds)
+	return feeds, nil
+}
+
+func collect(outlines []Outline, category string, feeds *[]Feed) {
+	for _, o := range outlines {
+		if url := strings.TrimSpace(o.XMLURL); url != "" {
+			*feeds = append(*feeds, Feed{
+				Title:    o.title(),
+				URL:      url,
+				Category: category,
+			})
+			continue
+		}
+
+		// Outline without a feed URL is a folder
+		folder := o.title()
+		if category != "" && folder != "" {
+			folder = category + "/" + folder
+		} else if folder == "" {
+			folder = category
+		}
+		collect(o.Outlines, folder, feeds)
+	}
+}
+
+func (o Outline) title() string {
+	if o.Title != "" {
+		return strings.TrimSpace(o.Title)
+	}
+	return strings.TrimSpace(o.Text)
+}
@@ -0,0 +1,49 @@
+package opml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Top level" type="rss" xmlUrl="https://example.com/feed"/>
+    <outline text="Tech" title="Tech">
+      <outline text="LWN" type="rss" xmlUrl="https://lwn.net/headlines/rss" htmlUrl="https://lwn.net"/>
+      <outline text="Linux">
+        <outline title="Kernel" text="ignored" xmlUrl=" https://kernel.org/feeds/kdist.xml "/>
+      </outline>
+    </outline>
+    <outline text="Empty folder"/>
+  </body>
+</opml>`
+
+	feeds, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Feed{
+		{Title: "Top level", URL: "https://example.com/feed", Category: ""},
+		{Title: "LWN", URL: "https://lwn.net/headlines/rss", Category: "Tech"},
+		{Title: "Kernel", URL: "https://kernel.org/feeds/kdist.xml", Category: "Tech/Linux"},
+	}
+
+	if len(feeds) != len(expected) {
+		t.Fatalf("expected %d feeds, got %d: %+v", len(expected), len(feeds), feeds)
+	}
+	for i, want := range expected {
+		if feeds[i] != want {
+			t.Errorf("feed %d: expected %+v, got %+v", i, want, feeds[i])
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	if _, err := Parse(strings.NewReader("not xml")); err == nil {
+		t.Error("expected error for invalid OPML")
+	}
+}
@@ -0,0 +1,167 @@
+// Package command parses items with an external executable, so niche
+// sources can be supported without changing myfeed.
+//
+// The item is written to the command's stdin as JSON and the command prints
+// the parsed content as JSON to its stdout:
+//
+//	{"html": "<p>...</p>", "title": "...", "author": "...", "published_at": "2024-01-02T15:04:05Z", "images": ["..."]}
+//
+// Only html is required. A non-zero exit fails the item with the stderr of
+// the command.
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/markdown"
+	"github.com/scipunch/myfeed/parser"
+)
+
+// Version of the parser output, bump it when rendering changes to
+// invalidate cached outputs of earlier versions
+const Version = 1
+
+// Timeout is how long a command may take to parse one item
+const Timeout = 2 * time.Minute
+
+// Capabilities of the parser: items of any resource, the command decides
+// what it can load
+var Capabilities = parser.Capabilities{
+	ResourceTypes: []string{"rss", "telegram_channel"},
+	Command:       true,
+}
+
+// Parser runs a shell command for every item
+type Parser struct {
+	command string
+}
+
+// New creates a parser without a command, see WithCommand
+func New() (Parser, error) {
+	return Parser{}, nil
+}
+
+// WithCommand returns a parser running the command with sh
+func (p Parser) WithCommand(command string) parser.Parser {
+	p.command = command
+	return p
+}
+
+// Item is the feed item as the command reads it
+type Item struct {
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Description string    `json:"description"`
+	Published   time.Time `json:"published,omitzero"`
+	GUID        string    `json:"guid,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	CommentsURL string    `json:"comments_url,omitempty"`
+	Media       []Media   `json:"media,omitempty"`
+}
+
+// Media is an attachment of the item, e.g., a Telegram photo
+type Media struct {
+	Type      string `json:"type"`
+	LocalPath string `json:"local_path,omitempty"`
+	Caption   string `json:"caption,omitempty"`
+}
+
+// Output is the parsed content printed by the command
+type Output struct {
+	HTML        string    `json:"html"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	PublishedAt time.Time `json:"published_at"`
+	Images      []string  `json:"images"`
+}
+
+// Response is the content parsed by the command
+type Response struct {
+	parser.Metadata
+	HTML string
+}
+
+// String returns the output of the command as Markdown
+func (r Response) String() string {
+	return markdown.FromHTML(r.HTML)
+}
+
+// Parse runs the command on the item
+func (p Parser) Parse(ctx context.Context, item types.FeedItem) (parser.Response, error) {
+	if p.command == "" {
+		return nil, errors.New("parser command is not set, see parser_command")
+	}
+	input, err := json.Marshal(newItem(item))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode item with %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.command)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("parser command timed out after %s", Timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parser command failed with %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return decode(out, item)
+}
+
+// decode reads the output of the command, metadata it leaves out is taken
+// from the item and the content
+func decode(out []byte, item types.FeedItem) (Response, error) {
+	var o Output
+	if err := json.Unmarshal(out, &o); err != nil {
+		return Response{}, fmt.Errorf("failed to decode parser command output with %w", err)
+	}
+	if strings.TrimSpace(o.HTML) == "" {
+		return Response{}, errors.New("parser command printed no html")
+	}
+	resp := Response{HTML: o.HTML}
+	resp.Meta = parser.Meta{
+		Title:       o.Title,
+		Author:      o.Author,
+		PublishedAt: o.PublishedAt,
+		WordCount:   parser.CountWords(o.HTML),
+		Images:      o.Images,
+	}
+	if resp.Meta.Title == "" {
+		resp.Meta.Title = item.Title
+	}
+	if resp.Meta.PublishedAt.IsZero() {
+		resp.Meta.PublishedAt = item.Published
+	}
+	if resp.Meta.Images == nil {
+		resp.Meta.Images = parser.ImageSources(o.HTML)
+	}
+	return resp, nil
+}
+
+func newItem(item types.FeedItem) Item {
+	in := Item{
+		Title:       item.Title,
+		Link:        item.Link,
+		Description: item.Description,
+		Published:   item.Published,
+		GUID:        item.GUID,
+		Language:    item.Language,
+		CommentsURL: item.CommentsURL,
+	}
+	for _, m := range item.Media {
+		in.Media = append(in.Media, Media{Type: m.Type, LocalPath: m.LocalPath, Caption: m.Caption})
+	}
+	return in
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+func TestParse(t *testing.T) {
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := types.FeedItem{Title: "Item", Link: "https://example.com/1", Published: published}
+
+	p, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+	// The item arrives on stdin
+	parsed, err := p.WithCommand(`grep -q '"link":"https://example.com/1"' && printf '{"html": "<p>Hello there</p><img src=\"a.png\">", "author": "Bot"}'`).Parse(context.Background(), item)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.String() != "Hello there\n\n![](a.png)" {
+		t.Errorf("expected the html of the command as Markdown, got %q", parsed.String())
+	}
+	if parsed.Author() != "Bot" || parsed.Title() != "Item" || !parsed.PublishedAt().Equal(published) {
+		t.Errorf("expected metadata of the command completed by the item, got %q %q %v", parsed.Author(), parsed.Title(), parsed.PublishedAt())
+	}
+	if parsed.WordCount() != 2 || len(parsed.Images()) != 1 {
+		t.Errorf("expected 2 words and 1 image, got %d and %v", parsed.WordCount(), parsed.Images())
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		wantErr string
+	}{
+		{name: "no command", wantErr: "parser_command"},
+		{name: "failing command", command: "echo 'site is down' >&2; exit 3", wantErr: "site is down"},
+		{name: "invalid output", command: "echo not json", wantErr: "failed to decode"},
+		{name: "empty html", command: `echo '{"title": "Empty"}'`, wantErr: "no html"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New()
+			_, err := p.WithCommand(tt.command).Parse(context.Background(), types.FeedItem{Link: "https://example.com/1"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
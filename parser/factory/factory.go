@@ -1,16 +1,97 @@
 package factory
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"slices"
+	"strings"
 
+	"github.com/scipunch/myfeed/config"
 	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/parser/command"
 	tgparser "github.com/scipunch/myfeed/parser/telegram"
 	"github.com/scipunch/myfeed/parser/web"
 	"github.com/scipunch/myfeed/parser/youtube"
 )
 
-func Init(types []parser.Type) (map[parser.Type]parser.Parser, error) {
+// registry declares what every implemented parser supports
+var registry = map[parser.Type]parser.Capabilities{
+	parser.Web:      web.Capabilities,
+	parser.Telegram: tgparser.Capabilities,
+	parser.YouTube:  youtube.Capabilities,
+	parser.Command:  command.Capabilities,
+}
+
+// Capabilities returns the declared capabilities of the parser, false if it is not implemented
+func Capabilities(t parser.Type) (parser.Capabilities, bool) {
+	c, ok := registry[t]
+	return c, ok
+}
+
+// Check rejects settings of the resource its parser can't handle,
+// e.g., the youtube parser on a Telegram channel
+func Check(r config.ResourceConfig) error {
+	if r.ExplodeLinks && r.T != config.RSS {
+		return fmt.Errorf("explode_links works only for resources of type '%s'", config.RSS)
+	}
+	switch r.Mode {
+	case "":
+	case config.ModeHeadline:
+		// Nothing is parsed, the parser may be left out
+		if len(r.Agents) > 0 {
+			return fmt.Errorf("agents have no effect in headline mode")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown mode '%s', expected '%s' or none", r.Mode, config.ModeHeadline)
+	}
+
+	c, ok := registry[r.ParserT]
+	if !ok {
+		var known []string
+		for t := range registry {
+			known = append(known, t)
+		}
+		slices.Sort(known)
+		return fmt.Errorf("unknown parser '%s', expected one of %s", r.ParserT, strings.Join(known, ", "))
+	}
+
+	var errs []error
+	if !slices.Contains(c.ResourceTypes, r.T) {
+		errs = append(errs, fmt.Errorf("parser '%s' can't handle resources of type '%s', only %s", r.ParserT, r.T, strings.Join(c.ResourceTypes, ", ")))
+	}
+	if !r.ParserOptions.IsZero() && !c.Options {
+		errs = append(errs, fmt.Errorf("parser '%s' has no parser_options", r.ParserT))
+	}
+	if r.Comments > 0 && !c.Comments {
+		errs = append(errs, fmt.Errorf("parser '%s' can't append comments", r.ParserT))
+	}
+	if r.MaxVideoMinutes > 0 && !c.MaxDuration {
+		errs = append(errs, fmt.Errorf("parser '%s' does not transcribe videos, max_video_minutes has no effect", r.ParserT))
+	}
+	if c.Command && r.ParserCommand == "" {
+		errs = append(errs, fmt.Errorf("parser '%s' needs parser_command", r.ParserT))
+	}
+	if r.ParserCommand != "" && !c.Command {
+		errs = append(errs, fmt.Errorf("parser '%s' runs no command, parser_command has no effect", r.ParserT))
+	}
+	if r.ExplodeLinks && r.ParserT != parser.Web {
+		errs = append(errs, fmt.Errorf("explode_links needs the '%s' parser for the linked pages", parser.Web))
+	}
+	return errors.Join(errs...)
+}
+
+// WhisperOptions converts the transcription settings for the youtube parser
+func WhisperOptions(w config.Whisper) youtube.Whisper {
+	return youtube.Whisper{
+		Model:       w.Model,
+		Device:      w.Device,
+		Language:    w.Language,
+		MaxDuration: w.MaxDuration.Duration,
+	}
+}
+
+func Init(types []parser.Type, whisper config.Whisper) (map[parser.Type]parser.Parser, error) {
 	res := make(map[parser.Type]parser.Parser)
 	for _, parserT := range types {
 		if res[parserT] != nil {
@@ -26,9 +107,11 @@ func Init(types []parser.Type) (map[parser.Type]parser.Parser, error) {
 		case parser.Telegram:
 			p, err = tgparser.New()
 		case parser.YouTube:
-			p, err = youtube.New()
+			p, err = youtube.New(WhisperOptions(whisper))
+		case parser.Command:
+			p, err = command.New()
 		default:
-			log.Fatalf("parser with type %s not implemented", parserT)
+			return res, fmt.Errorf("parser with type %s not implemented", parserT)
 		}
 		if err != nil {
 			return res, fmt.Errorf("failed to initialize parser for %s with %w", parserT, err)
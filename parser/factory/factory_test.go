@@ -0,0 +1,110 @@
+package factory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource config.ResourceConfig
+		wantErr  string
+	}{
+		{
+			name:     "web on rss",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.Web, Comments: 5, ParserOptions: parser.Options{StripImages: true}},
+		},
+		{
+			name:     "telegram with comments",
+			resource: config.ResourceConfig{T: config.TelegramChannel, ParserT: parser.Telegram, Comments: 3},
+		},
+		{
+			name:     "youtube on telegram channel",
+			resource: config.ResourceConfig{T: config.TelegramChannel, ParserT: parser.YouTube},
+			wantErr:  "can't handle resources of type 'telegram_channel'",
+		},
+		{
+			name:     "options of youtube",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.YouTube, ParserOptions: parser.Options{StripTables: true}},
+			wantErr:  "has no parser_options",
+		},
+		{
+			name:     "youtube with max video minutes",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.YouTube, MaxVideoMinutes: 60},
+		},
+		{
+			name:     "max video minutes of web",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.Web, MaxVideoMinutes: 60},
+			wantErr:  "max_video_minutes has no effect",
+		},
+		{
+			name:     "command without parser_command",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.Command},
+			wantErr:  "needs parser_command",
+		},
+		{
+			name:     "parser_command of web",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.Web, ParserCommand: "./parse.sh"},
+			wantErr:  "parser_command has no effect",
+		},
+		{
+			name:     "headline without parser",
+			resource: config.ResourceConfig{T: config.RSS, Mode: config.ModeHeadline},
+		},
+		{
+			name:     "headline with agents",
+			resource: config.ResourceConfig{T: config.RSS, Mode: config.ModeHeadline, Agents: []string{"summary"}},
+			wantErr:  "agents have no effect in headline mode",
+		},
+		{
+			name:     "exploded links of headlines",
+			resource: config.ResourceConfig{T: config.RSS, Mode: config.ModeHeadline, ExplodeLinks: true},
+		},
+		{
+			name:     "exploded links of youtube",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.YouTube, ExplodeLinks: true},
+			wantErr:  "explode_links needs the 'web' parser",
+		},
+		{
+			name:     "exploded links of telegram channel",
+			resource: config.ResourceConfig{T: config.TelegramChannel, ParserT: parser.Web, ExplodeLinks: true},
+			wantErr:  "explode_links works only for resources of type 'rss'",
+		},
+		{
+			name:     "unknown mode",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.Web, Mode: "preview"},
+			wantErr:  "unknown mode 'preview'",
+		},
+		{
+			name:     "unknown parser",
+			resource: config.ResourceConfig{T: config.RSS, ParserT: parser.Torrent},
+			wantErr:  "unknown parser 'torrent', expected one of command, telegram, web, youtube",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.resource)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesAcceptsLink(t *testing.T) {
+	web, _ := Capabilities(parser.Web)
+	if !web.AcceptsLink("https://example.com/post") || web.AcceptsLink("magnet:?xt=urn:btih:abc") {
+		t.Error("expected the web parser to load HTTP links only")
+	}
+	telegram, _ := Capabilities(parser.Telegram)
+	if !telegram.AcceptsLink("tg://resolve?domain=channel") {
+		t.Error("expected the telegram parser to accept any link")
+	}
+}
@@ -0,0 +1,30 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCountWords(t *testing.T) {
+	content := `<h1>Title</h1><p>Two&nbsp;words and <a href="https://example.com">a link</a></p>`
+	if got := CountWords(content); got != 6 {
+		t.Errorf("expected 6 words, got %d", got)
+	}
+}
+
+func TestImageSources(t *testing.T) {
+	content := `<img src="a.png"><p><IMG alt="b" SRC='b.jpg?x=1&amp;y=2'></p><img src="a.png">`
+	want := []string{"a.png", "b.jpg?x=1&y=2"}
+	if got := ImageSources(content); !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	for words, want := range map[int]time.Duration{0: 0, 1: time.Minute, 230: time.Minute, 231: 2 * time.Minute, 1000: 5 * time.Minute} {
+		if got := ReadingTime(words); got != want {
+			t.Errorf("ReadingTime(%d) = %v, want %v", words, got, want)
+		}
+	}
+}
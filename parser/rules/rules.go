@@ -0,0 +1,156 @@
+// Package rules applies shareable per-domain cleanup rules to extracted articles
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+
+	"github.com/scipunch/myfeed/urlnorm"
+)
+
+// Rule describes cleanup of articles from a set of domains
+type Rule struct {
+	Domains        []string `toml:"domains"`         // Domains the rule applies to, subdomains included
+	Remove         []string `toml:"remove"`          // CSS selectors removed from the extracted article
+	StripPhrases   []string `toml:"strip_phrases"`   // Boilerplate phrases removed from the text
+	AuthorSelector string   `toml:"author_selector"` // CSS selector of the author on the original page
+	DateSelector   string   `toml:"date_selector"`   // CSS selector of the publication date on the original page
+}
+
+// Set is a collection of rules loaded from a rules file
+type Set struct {
+	Rules []Rule `toml:"rule"`
+}
+
+// Load reads rules from a TOML file. A missing file yields an empty set.
+func Load(path string) (*Set, error) {
+	var set Set
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &set, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source rules at '%s' with %w", path, err)
+	}
+	if _, err := toml.Decode(string(dat), &set); err != nil {
+		return nil, fmt.Errorf("failed to decode source rules at '%s' with %w", path, err)
+	}
+	for i, rule := range set.Rules {
+		for _, sel := range append(append([]string{}, rule.Remove...), rule.AuthorSelector, rule.DateSelector) {
+			if sel == "" {
+				continue
+			}
+			if _, err := cascadia.Compile(sel); err != nil {
+				return nil, fmt.Errorf("rule %d has invalid selector %q: %w", i+1, sel, err)
+			}
+		}
+	}
+	return &set, nil
+}
+
+// Match returns rules applying to the link's domain
+func (s *Set) Match(link string) []Rule {
+	if s == nil {
+		return nil
+	}
+	host := urlnorm.Host(link)
+	if host == "" {
+		return nil
+	}
+
+	var matched []Rule
+	for _, rule := range s.Rules {
+		for _, domain := range rule.Domains {
+			domain = strings.TrimPrefix(strings.ToLower(domain), "www.")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				matched = append(matched, rule)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Apply cleans the extracted article HTML using rules matching the link.
+// The author and date are looked up in the original page and prepended as a byline.
+func (s *Set) Apply(link, page, article string) (string, error) {
+	matched := s.Match(link)
+	if len(matched) == 0 {
+		return article, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(article))
+	if err != nil {
+		return article, fmt.Errorf("failed to parse article with %w", err)
+	}
+	original, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return article, fmt.Errorf("failed to parse page with %w", err)
+	}
+
+	var author, date string
+	for _, rule := range matched {
+		for _, sel := range rule.Remove {
+			doc.Find(sel).Remove()
+		}
+		for _, phrase := range rule.StripPhrases {
+			stripPhrase(doc.Selection, phrase)
+		}
+		if author == "" && rule.AuthorSelector != "" {
+			author = strings.TrimSpace(original.Find(rule.AuthorSelector).First().Text())
+		}
+		if date == "" && rule.DateSelector != "" {
+			el := original.Find(rule.DateSelector).First()
+			date = strings.TrimSpace(el.AttrOr("datetime", el.Text()))
+		}
+	}
+
+	// Elements left empty after stripping boilerplate
+	doc.Find("p, li, span, div").Each(func(_ int, el *goquery.Selection) {
+		if strings.TrimSpace(el.Text()) == "" && el.Find("img, video, iframe").Length() == 0 {
+			el.Remove()
+		}
+	})
+
+	body, err := doc.Find("body").Html()
+	if err != nil {
+		return article, fmt.Errorf("failed to render article with %w", err)
+	}
+	return byline(author, date) + body, nil
+}
+
+// stripPhrase removes the phrase from every text node under the selection
+func stripPhrase(sel *goquery.Selection, phrase string) {
+	if phrase == "" {
+		return
+	}
+	sel.Contents().Each(func(_ int, node *goquery.Selection) {
+		if goquery.NodeName(node) == "#text" {
+			n := node.Get(0)
+			n.Data = strings.ReplaceAll(n.Data, phrase, "")
+			return
+		}
+		stripPhrase(node, phrase)
+	})
+}
+
+func byline(author, date string) string {
+	var parts []string
+	if author != "" {
+		parts = append(parts, "By "+html.EscapeString(author))
+	}
+	if date != "" {
+		parts = append(parts, html.EscapeString(date))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return `<p class="byline">` + strings.Join(parts, " · ") + "</p>"
+}
@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRules = `
+[[rule]]
+domains = ["example.com"]
+remove = [".promo", "aside"]
+strip_phrases = ["Subscribe to our newsletter!"]
+author_selector = ".byline .author"
+date_selector = "time"
+`
+
+func loadTestRules(t *testing.T) *Set {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	if err := os.WriteFile(path, []byte(testRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return set
+}
+
+func TestLoad_Missing(t *testing.T) {
+	set, err := Load(filepath.Join(t.TempDir(), "missing.toml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(set.Rules) != 0 {
+		t.Errorf("expected empty set, got %d rules", len(set.Rules))
+	}
+}
+
+func TestLoad_InvalidSelector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.toml")
+	os.WriteFile(path, []byte("[[rule]]\ndomains = [\"a.com\"]\nremove = [\"div[\"]\n"), 0644)
+	if _, err := Load(path); err == nil {
+		t.Fatal("expected invalid selector error")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	set := loadTestRules(t)
+	for link, want := range map[string]int{
+		"https://example.com/post":      1,
+		"https://www.example.com/post":  1,
+		"https://blog.example.com/post": 1,
+		"https://notexample.com/post":   0,
+		"https://other.org/post":        0,
+	} {
+		if got := len(set.Match(link)); got != want {
+			t.Errorf("Match(%q) = %d rules, want %d", link, got, want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	set := loadTestRules(t)
+	page := `<html><body>
+		<div class="byline"><span class="author">Jane Doe</span><time datetime="2024-05-02">May 2</time></div>
+		<article>...</article>
+	</body></html>`
+	article := `<div>
+		<p>Actual content.</p>
+		<div class="promo">Buy now</div>
+		<aside>Related posts</aside>
+		<p>Subscribe to our newsletter!</p>
+	</div>`
+
+	got, err := set.Apply("https://example.com/post", page, article)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, unwanted := range []string{"Buy now", "Related posts", "Subscribe"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("expected %q to be removed, got %s", unwanted, got)
+		}
+	}
+	if !strings.Contains(got, "Actual content.") {
+		t.Errorf("expected content to be kept, got %s", got)
+	}
+	if !strings.HasPrefix(got, `<p class="byline">By Jane Doe · 2024-05-02</p>`) {
+		t.Errorf("expected byline, got %s", got)
+	}
+
+	untouched, err := set.Apply("https://other.org/post", page, article)
+	if err != nil || untouched != article {
+		t.Errorf("expected article of unmatched domain to be unchanged")
+	}
+}
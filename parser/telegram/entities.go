@@ -0,0 +1,172 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// convertEntitiesToHTML renders the message text with the formatting
+// entities sent by Telegram. Offsets are in UTF-16 code units. Entities
+// overlapping without nesting are closed and reopened around each other.
+func convertEntitiesToHTML(text string, entities []types.TextEntity) string {
+	if text == "" {
+		return ""
+	}
+	units := utf16.Encode([]rune(text))
+
+	valid := make([]types.TextEntity, 0, len(entities))
+	for _, e := range entities {
+		if e.Length > 0 && e.Offset >= 0 && e.Offset+e.Length <= len(units) && openTag(e, "") != "" {
+			valid = append(valid, e)
+		}
+	}
+	// Outer entities first when several start at the same offset
+	sort.SliceStable(valid, func(i, j int) bool {
+		if valid[i].Offset != valid[j].Offset {
+			return valid[i].Offset < valid[j].Offset
+		}
+		return valid[i].Length > valid[j].Length
+	})
+
+	var b strings.Builder
+	var open []types.TextEntity
+	next := 0 // Next entity to open
+	pre := 0  // Open pre and code entities, which keep newlines as is
+	for pos := 0; pos <= len(units); pos++ {
+		// Close entities ending here, reopening inner ones which end later
+		var reopen []types.TextEntity
+		for ending(open, pos) {
+			top := open[len(open)-1]
+			open = open[:len(open)-1]
+			b.WriteString(closeTag(top))
+			if isVerbatim(top) {
+				pre--
+			}
+			if top.Offset+top.Length != pos {
+				reopen = append(reopen, top)
+			}
+		}
+		for i := len(reopen) - 1; i >= 0; i-- {
+			b.WriteString(openTag(reopen[i], entityText(units, reopen[i])))
+			open = append(open, reopen[i])
+			if isVerbatim(reopen[i]) {
+				pre++
+			}
+		}
+
+		for ; next < len(valid) && valid[next].Offset == pos; next++ {
+			b.WriteString(openTag(valid[next], entityText(units, valid[next])))
+			open = append(open, valid[next])
+			if isVerbatim(valid[next]) {
+				pre++
+			}
+		}
+
+		if pos == len(units) {
+			break
+		}
+		// Write text up to the next entity boundary
+		end := len(units)
+		if next < len(valid) {
+			end = valid[next].Offset
+		}
+		for _, e := range open {
+			end = min(end, e.Offset+e.Length)
+		}
+		segment := html.EscapeString(string(utf16.Decode(units[pos:end])))
+		if pre == 0 {
+			segment = strings.ReplaceAll(segment, "\n", "<br>\n")
+		}
+		b.WriteString(segment)
+		pos = end - 1
+	}
+
+	return fmt.Sprintf("<p>%s</p>", b.String())
+}
+
+// ending reports whether any open entity ends at pos
+func ending(open []types.TextEntity, pos int) bool {
+	for _, e := range open {
+		if e.Offset+e.Length == pos {
+			return true
+		}
+	}
+	return false
+}
+
+func isVerbatim(e types.TextEntity) bool {
+	return e.Type == "pre" || e.Type == "code"
+}
+
+func entityText(units []uint16, e types.TextEntity) string {
+	return string(utf16.Decode(units[e.Offset : e.Offset+e.Length]))
+}
+
+// openTag returns the opening HTML of the entity, empty for unknown types
+func openTag(e types.TextEntity, text string) string {
+	switch e.Type {
+	case "bold":
+		return "<strong>"
+	case "italic":
+		return "<em>"
+	case "underline":
+		return "<u>"
+	case "strike":
+		return "<del>"
+	case "code":
+		return "<code>"
+	case "pre":
+		if e.Language != "" {
+			return fmt.Sprintf(`<pre><code class="language-%s">`, html.EscapeString(e.Language))
+		}
+		return "<pre><code>"
+	case "text_link":
+		return fmt.Sprintf(`<a href="%s">`, html.EscapeString(e.URL))
+	case "url":
+		href := text
+		if !strings.Contains(href, "://") {
+			href = "https://" + href
+		}
+		return fmt.Sprintf(`<a href="%s">`, html.EscapeString(href))
+	case "mention":
+		return fmt.Sprintf(`<a href="https://t.me/%s">`, html.EscapeString(strings.TrimPrefix(text, "@")))
+	case "email":
+		return fmt.Sprintf(`<a href="mailto:%s">`, html.EscapeString(text))
+	case "spoiler":
+		return `<span class="spoiler">`
+	case "blockquote":
+		return "<blockquote>"
+	default:
+		return ""
+	}
+}
+
+func closeTag(e types.TextEntity) string {
+	switch e.Type {
+	case "bold":
+		return "</strong>"
+	case "italic":
+		return "</em>"
+	case "underline":
+		return "</u>"
+	case "strike":
+		return "</del>"
+	case "code":
+		return "</code>"
+	case "pre":
+		return "</code></pre>"
+	case "text_link", "url", "mention", "email":
+		return "</a>"
+	case "spoiler":
+		return "</span>"
+	case "blockquote":
+		return "</blockquote>"
+	default:
+		return ""
+	}
+}
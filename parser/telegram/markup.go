@@ -0,0 +1,213 @@
+package telegram
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+// markers of the pseudo-markdown and the entities they stand for
+var markers = map[string]string{
+	"**": "bold",
+	"__": "italic",
+	"~~": "strike",
+}
+
+type tokenKind int
+
+const (
+	tokenText tokenKind = iota
+	tokenMarker
+	tokenVerbatim // Code span or block, its text is never tokenized
+	tokenLinkOpen
+	tokenLinkClose
+)
+
+type token struct {
+	kind     tokenKind
+	text     string // Text, marker or code
+	entity   string // Entity of markers and verbatim tokens
+	language string // Language of code blocks
+	url      string // Target of link closes
+	paired   bool   // Whether the marker has a counterpart
+}
+
+// parseMarkup converts Telegram pseudo-markdown into plain text and its
+// formatting entities, so both kinds of messages share the renderer.
+// Markers without a counterpart stay in the text as is.
+func parseMarkup(text string) (string, []types.TextEntity) {
+	tokens := tokenize(strings.ToValidUTF8(text, "\uFFFD"))
+	pairMarkers(tokens)
+
+	var (
+		b        strings.Builder
+		entities []types.TextEntity
+		offset   int                // Length of the plain text in UTF-16 units
+		starts   = map[string]int{} // Offsets of open markers by entity
+		link     int                // Offset of the open link
+	)
+	write := func(s string) {
+		b.WriteString(s)
+		offset += utf16Len(s)
+	}
+	for _, t := range tokens {
+		switch t.kind {
+		case tokenText:
+			write(t.text)
+		case tokenMarker:
+			if !t.paired {
+				write(t.text)
+				continue
+			}
+			if start, ok := starts[t.entity]; ok {
+				entities = append(entities, types.TextEntity{Type: t.entity, Offset: start, Length: offset - start})
+				delete(starts, t.entity)
+			} else {
+				starts[t.entity] = offset
+			}
+		case tokenVerbatim:
+			start := offset
+			write(t.text)
+			entities = append(entities, types.TextEntity{Type: t.entity, Offset: start, Length: offset - start, Language: t.language})
+		case tokenLinkOpen:
+			link = offset
+		case tokenLinkClose:
+			entities = append(entities, types.TextEntity{Type: "text_link", Offset: link, Length: offset - link, URL: t.url})
+		}
+	}
+	return b.String(), entities
+}
+
+// tokenize splits the text into markers, code and links. A link is only
+// opened when its closing "](url)" follows, code can't cross it.
+func tokenize(text string) []token {
+	var (
+		tokens    []token
+		plain     strings.Builder
+		linkClose = -1 // Index of the "]" closing the open link
+		linkEnd   = -1 // Index after the ")" of the open link
+		linkURL   string
+	)
+	flush := func() {
+		if plain.Len() > 0 {
+			tokens = append(tokens, token{kind: tokenText, text: plain.String()})
+			plain.Reset()
+		}
+	}
+	// Code and markers of the label must end before the link does
+	limit := func() string {
+		if linkClose >= 0 {
+			return text[:linkClose]
+		}
+		return text
+	}
+
+	for i := 0; i < len(text); {
+		if i == linkClose {
+			flush()
+			tokens = append(tokens, token{kind: tokenLinkClose, url: linkURL})
+			i, linkClose, linkEnd = linkEnd, -1, -1
+			continue
+		}
+		rest := limit()[i:]
+		switch {
+		case strings.HasPrefix(rest, "```"):
+			if end := strings.Index(rest[3:], "```"); end > 0 {
+				flush()
+				code, language := codeBlock(rest[3 : 3+end])
+				tokens = append(tokens, token{kind: tokenVerbatim, text: code, entity: "pre", language: language})
+				i += 3 + end + 3
+				continue
+			}
+		case strings.HasPrefix(rest, "`"):
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				flush()
+				tokens = append(tokens, token{kind: tokenVerbatim, text: rest[1 : 1+end], entity: "code"})
+				i += 1 + end + 1
+				continue
+			}
+		case rest != "" && rest[0] == '[' && linkClose < 0:
+			if label, url, ok := linkAt(rest); ok {
+				flush()
+				tokens = append(tokens, token{kind: tokenLinkOpen})
+				linkClose = i + 1 + len(label)
+				linkEnd = linkClose + 2 + len(url) + 1
+				linkURL = url
+				i++
+				continue
+			}
+		}
+		if len(rest) >= 2 {
+			if entity, ok := markers[rest[:2]]; ok {
+				flush()
+				tokens = append(tokens, token{kind: tokenMarker, text: rest[:2], entity: entity})
+				i += 2
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		plain.WriteString(text[i : i+size])
+		i += size
+	}
+	flush()
+	return tokens
+}
+
+// linkAt parses "[label](url)" at the start of s
+func linkAt(s string) (label, url string, ok bool) {
+	end := strings.IndexByte(s, ']')
+	if end <= 1 || !strings.HasPrefix(s[end+1:], "(") {
+		return "", "", false
+	}
+	label = s[1:end]
+	rest := s[end+2:]
+	close := strings.IndexByte(rest, ')')
+	if close <= 0 || strings.ContainsAny(rest[:close], " \n\t") {
+		return "", "", false
+	}
+	return label, rest[:close], true
+}
+
+// codeBlock splits the language off the first line of a code block,
+// e.g., "go\nx := 1"
+func codeBlock(s string) (code, language string) {
+	first, rest, found := strings.Cut(s, "\n")
+	if !found || rest == "" || first == "" || strings.ContainsAny(first, " \t`") {
+		return s, ""
+	}
+	return rest, first
+}
+
+// pairMarkers pairs markers of the same entity in order. Markers without
+// a counterpart and pairs around nothing stay literal.
+func pairMarkers(tokens []token) {
+	open := map[string]int{}
+	for i, t := range tokens {
+		if t.kind != tokenMarker {
+			continue
+		}
+		j, ok := open[t.entity]
+		if !ok {
+			open[t.entity] = i
+			continue
+		}
+		delete(open, t.entity)
+		if j+1 == i {
+			// Nothing between, e.g., "****"
+			continue
+		}
+		tokens[j].paired = true
+		tokens[i].paired = true
+	}
+}
+
+// utf16Len returns the length of s in UTF-16 units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
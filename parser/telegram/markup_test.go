@@ -0,0 +1,65 @@
+package telegram
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// FuzzConvertTelegramToHTML checks that malformed posts still produce
+// well-formed HTML: every tag is closed in order and no text turns into markup
+func FuzzConvertTelegramToHTML(f *testing.F) {
+	for _, seed := range []string{
+		"**bold** __italic__ ~~strike~~ `code` [link](https://example.com)",
+		"```go\nx := `raw`\n```",
+		"**a __b** c__",
+		"[**a](https://example.com)**",
+		"[a `b](https://example.com) c`",
+		"<b>**</b>**",
+		"***___~~~```",
+		"[[]](())",
+		"😀 **😀** \xff**",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		out := convertTelegramToHTML(input)
+		if input == "" {
+			return
+		}
+		if !utf8.ValidString(out) {
+			t.Fatalf("invalid UTF-8 in %q", out)
+		}
+
+		var open []string
+		z := html.NewTokenizer(strings.NewReader(out))
+		for {
+			switch z.Next() {
+			case html.ErrorToken:
+				if z.Err() != io.EOF {
+					t.Fatalf("tokenizing %q: %s", out, z.Err())
+				}
+				if len(open) > 0 {
+					t.Fatalf("unclosed %v in %q", open, out)
+				}
+				return
+			case html.StartTagToken:
+				name, _ := z.TagName()
+				if string(name) != "br" {
+					open = append(open, string(name))
+				}
+			case html.EndTagToken:
+				name, _ := z.TagName()
+				if len(open) == 0 || open[len(open)-1] != string(name) {
+					t.Fatalf("unexpected </%s> with %v open in %q", name, open, out)
+				}
+				open = open[:len(open)-1]
+			case html.SelfClosingTagToken, html.CommentToken, html.DoctypeToken:
+				t.Fatalf("unexpected markup %q in %q", z.Raw(), out)
+			}
+		}
+	})
+}
@@ -1,16 +1,27 @@
 package telegram
 
 import (
+	"context"
 	"fmt"
 	"html"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/markdown"
 	"github.com/scipunch/myfeed/parser"
 )
 
+// Version of the parser output, bump it when rendering changes to
+// invalidate cached outputs of earlier versions
+const Version = 3
+
+// Capabilities of the parser: messages fetched from Telegram, their links are never loaded
+var Capabilities = parser.Capabilities{
+	ResourceTypes: []string{"telegram_channel"},
+	Comments:      true,
+}
+
 // Parser parses Telegram messages and converts them to HTML
 type Parser struct{}
 
@@ -21,54 +32,137 @@ func New() (Parser, error) {
 
 // Response represents a parsed Telegram message
 type Response struct {
-	HTML string
+	parser.Metadata
+	HTML   string
+	Thread string `json:",omitempty"` // Replies from the discussion group as plain text
 }
 
+// String returns the message as Markdown
 func (r Response) String() string {
-	return r.HTML
+	return markdown.FromHTML(r.HTML)
+}
+
+// Comments returns the replies for the discussion agent
+func (r Response) Comments() string {
+	return r.Thread
 }
 
 // Parse takes a FeedItem and converts the Description (Telegram message content) to HTML
 // Uses item.Link as the cache key, but processes item.Description as the content
-// Also includes any media attachments (photos) in the HTML
-func (p Parser) Parse(item types.FeedItem) (parser.Response, error) {
+// Also includes any media attachments (photos, video thumbnails) in the HTML
+// and the original source of forwarded messages
+func (p Parser) Parse(_ context.Context, item types.FeedItem) (parser.Response, error) {
 	var htmlBuilder strings.Builder
 
-	// Add media (photos) before the text content
+	// Attribute forwarded content to its original source
+	if item.Forward != nil {
+		htmlBuilder.WriteString(forwardHTML(*item.Forward))
+		htmlBuilder.WriteString("\n")
+	}
+
+	// Add media (photos and video thumbnails) before the text content
 	for _, media := range item.Media {
-		if media.Type == "photo" {
-			if media.LocalPath != "" {
-				// Image with successful download
-				// Use relative path: media/filename
-				filename := filepath.Base(media.LocalPath)
-				relativePath := filepath.Join("media", filename)
-
-				htmlBuilder.WriteString(fmt.Sprintf(
-					`<img src="%s" alt="%s" style="max-width: 100%%; height: auto; margin-bottom: 1em;" width="%d" height="%d">`,
-					relativePath,
-					escapeHTML(media.Caption),
-					media.Width,
-					media.Height,
-				))
-				htmlBuilder.WriteString("\n")
-			} else if media.Caption != "" {
-				// Failed download - show error message
-				htmlBuilder.WriteString(fmt.Sprintf(
-					`<div style="padding: 1em; background: #fee; border: 1em solid #fcc; margin-bottom: 1em;">%s</div>`,
-					escapeHTML(media.Caption),
-				))
-				htmlBuilder.WriteString("\n")
+		if media.Type != "photo" && media.Type != "video" {
+			continue
+		}
+		if media.LocalPath != "" {
+			// Image with successful download
+			// Use relative path: media/filename
+			filename := filepath.Base(media.LocalPath)
+			relativePath := filepath.Join("media", filename)
+
+			img := fmt.Sprintf(
+				`<img src="%s" alt="%s" style="max-width: 100%%; height: auto; margin-bottom: 1em;" width="%d" height="%d">`,
+				relativePath,
+				escapeHTML(media.Caption),
+				media.Width,
+				media.Height,
+			)
+			// Videos can't be played on paper, the thumbnail links to the post
+			if media.Type == "video" && media.Link != "" {
+				img = fmt.Sprintf(`<a href="%s">%s</a>`, escapeHTML(media.Link), img)
 			}
+			htmlBuilder.WriteString(img)
+			htmlBuilder.WriteString("\n")
+		} else if media.Caption != "" {
+			// Failed download - show error message
+			htmlBuilder.WriteString(fmt.Sprintf(
+				`<div style="padding: 1em; background: #fee; border: 1em solid #fcc; margin-bottom: 1em;">%s</div>`,
+				escapeHTML(media.Caption),
+			))
+			htmlBuilder.WriteString("\n")
+		}
+		if media.Type == "video" && media.Link != "" {
+			htmlBuilder.WriteString(fmt.Sprintf(
+				`<p class="video-link"><a href="%s">▶ Watch the video on Telegram</a></p>`,
+				escapeHTML(media.Link),
+			))
+			htmlBuilder.WriteString("\n")
 		}
 	}
 
 	// Add text content
-	if item.Description != "" {
+	// Formatting comes from Telegram entities, items without them (e.g., from
+	// older caches) fall back to pseudo-markdown
+	if item.Entities != nil {
+		htmlBuilder.WriteString(convertEntitiesToHTML(item.Description, item.Entities))
+	} else if item.Description != "" {
 		textHTML := convertTelegramToHTML(item.Description)
 		htmlBuilder.WriteString(textHTML)
 	}
 
-	return Response{HTML: htmlBuilder.String()}, nil
+	// Top replies from the discussion group follow the post
+	if len(item.Comments) > 0 {
+		htmlBuilder.WriteString("\n")
+		htmlBuilder.WriteString(commentsHTML(item.Comments))
+	}
+
+	resp := Response{HTML: htmlBuilder.String(), Thread: thread(item.Comments)}
+	resp.Meta = parser.Meta{
+		Title:       item.Title,
+		PublishedAt: item.Published,
+		WordCount:   parser.CountWords(item.Description),
+		Images:      parser.ImageSources(resp.HTML),
+	}
+	if item.Forward != nil {
+		resp.Meta.Author = item.Forward.From
+	}
+	return resp, nil
+}
+
+// commentsHTML renders replies as a comments section
+func commentsHTML(comments []types.Comment) string {
+	var b strings.Builder
+	b.WriteString(`<section class="comments"><h3>Comments</h3>`)
+	for _, c := range comments {
+		fmt.Fprintf(&b, `<div class="comment"><p class="byline">%s`, escapeHTML(c.Author))
+		if c.Reactions > 0 {
+			fmt.Fprintf(&b, " · %d reactions", c.Reactions)
+		}
+		b.WriteString("</p>")
+		b.WriteString(strings.ReplaceAll(escapeHTML(c.Text), "\n", "<br>\n"))
+		b.WriteString("</div>")
+	}
+	b.WriteString("</section>")
+	return b.String()
+}
+
+// thread formats replies as plain text, one per paragraph
+func thread(comments []types.Comment) string {
+	parts := make([]string, len(comments))
+	for i, c := range comments {
+		parts[i] = c.Author + ": " + c.Text
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// forwardHTML renders the "Forwarded from" line, linking the source if public
+func forwardHTML(fwd types.Forward) string {
+	from := escapeHTML(fwd.From)
+	if fwd.Link != "" {
+		from = fmt.Sprintf(`<a href="%s">%s</a>`, escapeHTML(fwd.Link), from)
+	}
+	return fmt.Sprintf(`<p class="forwarded">↪ Forwarded from %s</p>`, from)
 }
 
 // escapeHTML escapes HTML special characters
@@ -83,48 +177,14 @@ func (p Parser) ParseMessage(message string) Response {
 	return Response{HTML: html}
 }
 
-// convertTelegramToHTML converts Telegram formatting to HTML
+// convertTelegramToHTML converts Telegram pseudo-markdown to HTML
 // Telegram supports:
 // - **bold**
 // - __italic__
+// - ~~strikethrough~~
 // - `code`
-// - ```pre```
+// - ```pre``` with an optional language on the first line
 // - [text](url) - links
 func convertTelegramToHTML(text string) string {
-	if text == "" {
-		return ""
-	}
-
-	// Escape HTML first
-	text = html.EscapeString(text)
-
-	// Convert code blocks (```code```)
-	codeBlockRe := regexp.MustCompile("```([^`]+)```")
-	text = codeBlockRe.ReplaceAllString(text, "<pre><code>$1</code></pre>")
-
-	// Convert inline code (`code`)
-	inlineCodeRe := regexp.MustCompile("`([^`]+)`")
-	text = inlineCodeRe.ReplaceAllString(text, "<code>$1</code>")
-
-	// Convert bold (**text**)
-	boldRe := regexp.MustCompile(`\*\*([^\*]+)\*\*`)
-	text = boldRe.ReplaceAllString(text, "<strong>$1</strong>")
-
-	// Convert italic (__text__)
-	italicRe := regexp.MustCompile(`__([^_]+)__`)
-	text = italicRe.ReplaceAllString(text, "<em>$1</em>")
-
-	// Convert strikethrough (~~text~~)
-	strikeRe := regexp.MustCompile(`~~([^~]+)~~`)
-	text = strikeRe.ReplaceAllString(text, "<del>$1</del>")
-
-	// Convert links [text](url)
-	linkRe := regexp.MustCompile(`\[([^\]]+)\]\(([^\)]+)\)`)
-	text = linkRe.ReplaceAllString(text, `<a href="$2">$1</a>`)
-
-	// Convert newlines to <br>
-	text = strings.ReplaceAll(text, "\n", "<br>\n")
-
-	// Wrap in paragraph
-	return fmt.Sprintf("<p>%s</p>", text)
+	return convertEntitiesToHTML(parseMarkup(text))
 }
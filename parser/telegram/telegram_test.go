@@ -1,6 +1,7 @@
 package telegram
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -63,6 +64,41 @@ func TestConvertTelegramToHTML(t *testing.T) {
 			input:    "This has <script>alert('xss')</script> tags",
 			expected: "<p>This has &lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt; tags</p>",
 		},
+		{
+			name:     "unbalanced markers stay literal",
+			input:    "**bold and __italic",
+			expected: "<p>**bold and __italic</p>",
+		},
+		{
+			name:     "nested",
+			input:    "**bold __both__**",
+			expected: "<p><strong>bold <em>both</em></strong></p>",
+		},
+		{
+			name:     "crossed markers",
+			input:    "**a __b** c__",
+			expected: "<p><strong>a <em>b</em></strong><em> c</em></p>",
+		},
+		{
+			name:     "backticks in code block",
+			input:    "```go\nfmt.Println(`**raw**`)```",
+			expected: "<p><pre><code class=\"language-go\">fmt.Println(`**raw**`)</code></pre></p>",
+		},
+		{
+			name:     "formatted link label",
+			input:    "[**bold** link](https://example.com/?a=1&b=2)",
+			expected: `<p><a href="https://example.com/?a=1&amp;b=2"><strong>bold</strong> link</a></p>`,
+		},
+		{
+			name:     "code can't cross a link",
+			input:    "[a `b](https://example.com) c`",
+			expected: "<p><a href=\"https://example.com\">a `b</a> c`</p>",
+		},
+		{
+			name:     "empty markers",
+			input:    "**** and ``",
+			expected: "<p>**** and ``</p>",
+		},
 	}
 
 	for _, tt := range tests {
@@ -81,7 +117,7 @@ func TestParseMessage(t *testing.T) {
 	message := "**Important:** This is a test message with __formatting__"
 	response := parser.ParseMessage(message)
 
-	result := response.String()
+	result := response.HTML
 	if !strings.Contains(result, "<strong>Important:</strong>") {
 		t.Errorf("Expected bold formatting, got: %s", result)
 	}
@@ -102,13 +138,186 @@ func TestParse(t *testing.T) {
 		Link:        "https://t.me/test/123",
 		Description: message,
 	}
-	response, err := parser.Parse(item)
+	response, err := parser.Parse(context.Background(), item)
 	if err != nil {
 		t.Fatalf("Parse failed: %v", err)
 	}
 
-	result := response.String()
+	result := response.(Response).HTML
 	if !strings.Contains(result, "<strong>bold</strong>") {
 		t.Errorf("Expected formatted output, got: %s", result)
 	}
+	if md := response.String(); md != "Test message with **bold**" {
+		t.Errorf("Expected Markdown for the agents, got: %s", md)
+	}
+}
+
+func TestParse_VideoThumbnail(t *testing.T) {
+	parser, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	item := types.FeedItem{
+		Title: "Video",
+		Link:  "https://t.me/test/124",
+		Media: []types.MediaAttachment{{
+			Type:      "video",
+			LocalPath: "/tmp/myfeed-telegram-media/video_124_1.jpg",
+			Width:     320,
+			Height:    180,
+			Link:      "https://t.me/test/124",
+		}},
+	}
+	response, err := parser.Parse(context.Background(), item)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	result := response.(Response).HTML
+	if !strings.Contains(result, `<a href="https://t.me/test/124"><img src="media/video_124_1.jpg"`) {
+		t.Errorf("Expected thumbnail linking to the post, got: %s", result)
+	}
+	if !strings.Contains(result, "Watch the video on Telegram") {
+		t.Errorf("Expected link to the video, got: %s", result)
+	}
+	if images := response.Images(); len(images) != 1 || images[0] != "media/video_124_1.jpg" {
+		t.Errorf("Expected the thumbnail in images, got: %v", images)
+	}
+}
+
+func TestParse_Forwarded(t *testing.T) {
+	parser, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	item := types.FeedItem{
+		Link:        "https://t.me/test/125",
+		Description: "Original text",
+		Forward:     &types.Forward{From: "News & Co", Link: "https://t.me/news/42"},
+	}
+	response, err := parser.Parse(context.Background(), item)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := `<p class="forwarded">↪ Forwarded from <a href="https://t.me/news/42">News &amp; Co</a></p>`
+	if result := response.(Response).HTML; !strings.HasPrefix(result, want) {
+		t.Errorf("Expected attribution before the text, got: %s", result)
+	}
+	if response.Author() != "News & Co" || response.WordCount() != 2 {
+		t.Errorf("Expected the forwarded author and 2 words, got %q and %d", response.Author(), response.WordCount())
+	}
+
+	item.Forward = &types.Forward{From: "Hidden author"}
+	response, err = parser.Parse(context.Background(), item)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if result := response.(Response).HTML; !strings.Contains(result, "Forwarded from Hidden author</p>") {
+		t.Errorf("Expected unlinked attribution, got: %s", result)
+	}
+}
+
+func TestConvertEntitiesToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []types.TextEntity
+		want     string
+	}{
+		{
+			name: "no formatting keeps pseudo-markdown as is",
+			text: "call __init__ with **kwargs",
+			want: "<p>call __init__ with **kwargs</p>",
+		},
+		{
+			name:     "bold and link",
+			text:     "Read this <now>",
+			entities: []types.TextEntity{{Type: "bold", Offset: 0, Length: 4}, {Type: "text_link", Offset: 5, Length: 4, URL: "https://example.com/?a=1&b=2"}},
+			want:     `<p><strong>Read</strong> <a href="https://example.com/?a=1&amp;b=2">this</a> &lt;now&gt;</p>`,
+		},
+		{
+			name:     "offsets in UTF-16 units",
+			text:     "😀 bold",
+			entities: []types.TextEntity{{Type: "bold", Offset: 3, Length: 4}},
+			want:     "<p>😀 <strong>bold</strong></p>",
+		},
+		{
+			name:     "nested",
+			text:     "bold italic",
+			entities: []types.TextEntity{{Type: "italic", Offset: 5, Length: 6}, {Type: "bold", Offset: 0, Length: 11}},
+			want:     "<p><strong>bold <em>italic</em></strong></p>",
+		},
+		{
+			name:     "overlapping",
+			text:     "abcdef",
+			entities: []types.TextEntity{{Type: "bold", Offset: 0, Length: 4}, {Type: "italic", Offset: 2, Length: 4}},
+			want:     "<p><strong>ab<em>cd</em></strong><em>ef</em></p>",
+		},
+		{
+			name:     "pre keeps newlines",
+			text:     "code:\nx := 1\ny := 2",
+			entities: []types.TextEntity{{Type: "pre", Offset: 6, Length: 13, Language: "go"}},
+			want:     "<p>code:<br>\n<pre><code class=\"language-go\">x := 1\ny := 2</code></pre></p>",
+		},
+		{
+			name:     "url and mention",
+			text:     "see example.com by @someone",
+			entities: []types.TextEntity{{Type: "url", Offset: 4, Length: 11}, {Type: "mention", Offset: 19, Length: 8}},
+			want:     `<p>see <a href="https://example.com">example.com</a> by <a href="https://t.me/someone">@someone</a></p>`,
+		},
+		{
+			name:     "out of range entities are ignored",
+			text:     "short",
+			entities: []types.TextEntity{{Type: "bold", Offset: 3, Length: 10}, {Type: "hashtag", Offset: 0, Length: 2}},
+			want:     "<p>short</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities := tt.entities
+			if entities == nil {
+				entities = []types.TextEntity{}
+			}
+			if got := convertEntitiesToHTML(tt.text, entities); got != tt.want {
+				t.Errorf("convertEntitiesToHTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Comments(t *testing.T) {
+	parser, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create parser: %v", err)
+	}
+
+	item := types.FeedItem{
+		Link:        "https://t.me/test/126",
+		Description: "Post",
+		Comments: []types.Comment{
+			{Author: "Jane", Text: "Great <post>", Reactions: 3},
+			{Author: "John", Text: "Agreed"},
+		},
+	}
+	response, err := parser.Parse(context.Background(), item)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	result := response.(Response).HTML
+	if !strings.Contains(result, `<p class="byline">Jane · 3 reactions</p>Great &lt;post&gt;`) {
+		t.Errorf("Expected rendered comment, got: %s", result)
+	}
+	if !strings.Contains(result, `<p class="byline">John</p>Agreed`) {
+		t.Errorf("Expected comment without reactions, got: %s", result)
+	}
+
+	thread, ok := response.(interface{ Comments() string })
+	if !ok || thread.Comments() != "Jane: Great <post>\n\nJohn: Agreed" {
+		t.Errorf("Expected plain text thread for the discussion agent, got: %v", response)
+	}
 }
@@ -0,0 +1,216 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/httpclient"
+	"github.com/scipunch/myfeed/ratelimit"
+)
+
+// hnAPI is the official Hacker News API, its comment lists are ranked like the site
+var hnAPI = "https://hacker-news.firebaseio.com/v0"
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// thread is a discussion on an aggregator
+type thread struct {
+	hnID      string // Item ID of an HN story
+	redditURL string // Comments JSON of a Reddit post
+}
+
+// threadOf finds the HN or Reddit discussion of the item: the comments link
+// of the feed or the item link itself
+func threadOf(item types.FeedItem) (thread, bool) {
+	for _, link := range []string{item.CommentsURL, item.Link} {
+		u, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+		switch {
+		case host == "news.ycombinator.com" && u.Path == "/item" && u.Query().Get("id") != "":
+			return thread{hnID: u.Query().Get("id")}, true
+		case (host == "reddit.com" || host == "old.reddit.com") && strings.Contains(u.Path, "/comments/"):
+			return thread{redditURL: "https://www.reddit.com" + strings.TrimSuffix(u.Path, "/") + ".json"}, true
+		}
+	}
+	return thread{}, false
+}
+
+// appendComments renders the top comments of the item's thread below the
+// article. Failures keep the article without them.
+func (p Parser) appendComments(ctx context.Context, resp *Response, item types.FeedItem) {
+	t, ok := threadOf(item)
+	if !ok {
+		return
+	}
+
+	var comments []types.Comment
+	var err error
+	if t.hnID != "" {
+		comments, err = p.hnComments(ctx, hnAPI, t.hnID, p.comments)
+	} else {
+		comments, err = p.redditComments(ctx, t.redditURL, p.comments)
+	}
+	if err != nil {
+		slog.Warn("failed to fetch comments", "url", item.Link, "error", err)
+		return
+	}
+	if len(comments) == 0 {
+		return
+	}
+	resp.HTML += commentsHTML(comments)
+	resp.Thread = threadText(comments)
+}
+
+// hnComments returns up to limit top-level comments of the story in the
+// order HN ranks them
+func (p Parser) hnComments(ctx context.Context, api, id string, limit int) ([]types.Comment, error) {
+	var story struct {
+		Kids []int `json:"kids"`
+	}
+	if err := p.getJSON(ctx, fmt.Sprintf("%s/item/%s.json", api, id), &story); err != nil {
+		return nil, err
+	}
+
+	var comments []types.Comment
+	for _, kid := range story.Kids {
+		if len(comments) == limit {
+			break
+		}
+		var c struct {
+			By      string `json:"by"`
+			Text    string `json:"text"`
+			Time    int64  `json:"time"`
+			Deleted bool   `json:"deleted"`
+			Dead    bool   `json:"dead"`
+		}
+		if err := p.getJSON(ctx, fmt.Sprintf("%s/item/%d.json", api, kid), &c); err != nil {
+			return comments, err
+		}
+		if c.Deleted || c.Dead || c.Text == "" {
+			continue
+		}
+		comments = append(comments, types.Comment{
+			Author:    c.By,
+			Text:      htmlToText(c.Text),
+			Published: time.Unix(c.Time, 0),
+		})
+	}
+	return comments, nil
+}
+
+// redditComments returns up to limit top-level comments of the post sorted by score
+func (p Parser) redditComments(ctx context.Context, jsonURL string, limit int) ([]types.Comment, error) {
+	type listing struct {
+		Data struct {
+			Children []struct {
+				Kind string `json:"kind"`
+				Data struct {
+					Author     string  `json:"author"`
+					Body       string  `json:"body"`
+					Score      int     `json:"score"`
+					CreatedUTC float64 `json:"created_utc"`
+				} `json:"data"`
+			} `json:"children"`
+		} `json:"data"`
+	}
+	// The post comes first, then its comments
+	var listings []listing
+	query := url.Values{"sort": {"top"}, "depth": {"1"}, "limit": {fmt.Sprint(limit)}, "raw_json": {"1"}}
+	if err := p.getJSON(ctx, jsonURL+"?"+query.Encode(), &listings); err != nil {
+		return nil, err
+	}
+	if len(listings) < 2 {
+		return nil, nil
+	}
+
+	var comments []types.Comment
+	for _, child := range listings[1].Data.Children {
+		if len(comments) == limit {
+			break
+		}
+		// "more" entries link to comments which were not loaded
+		if child.Kind != "t1" || child.Data.Body == "[deleted]" || child.Data.Body == "[removed]" {
+			continue
+		}
+		comments = append(comments, types.Comment{
+			Author:    child.Data.Author,
+			Text:      child.Data.Body,
+			Published: time.Unix(int64(child.Data.CreatedUTC), 0),
+			Reactions: child.Data.Score,
+		})
+	}
+	return comments, nil
+}
+
+// getJSON requests the API endpoint and decodes its JSON reply into v
+func (p Parser) getJSON(ctx context.Context, endpoint string, v any) error {
+	client, err := httpclient.New(p.proxy)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", p.requestUserAgent())
+	req.Header.Set("Accept", "application/json")
+	release, err := ratelimit.Acquire(ctx, ratelimit.HostKey(endpoint))
+	if err != nil {
+		return err
+	}
+	defer release()
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// htmlToText converts the markup of HN comments to plain text, paragraphs
+// are separated by blank lines
+func htmlToText(s string) string {
+	s = strings.ReplaceAll(s, "<p>", "\n\n")
+	return strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(s, "")))
+}
+
+// commentsHTML renders comments under a "Discussion" heading
+func commentsHTML(comments []types.Comment) string {
+	var b strings.Builder
+	b.WriteString(`<section class="comments"><h3>Discussion</h3>`)
+	for _, c := range comments {
+		fmt.Fprintf(&b, `<div class="comment"><p class="byline">%s`, html.EscapeString(c.Author))
+		if c.Reactions > 0 {
+			fmt.Fprintf(&b, " · %d points", c.Reactions)
+		}
+		b.WriteString("</p>")
+		b.WriteString(textToHTML(c.Text))
+		b.WriteString("</div>")
+	}
+	b.WriteString("</section>")
+	return b.String()
+}
+
+// threadText formats comments as plain text, one per paragraph
+func threadText(comments []types.Comment) string {
+	parts := make([]string, len(comments))
+	for i, c := range comments {
+		parts[i] = c.Author + ": " + c.Text
+	}
+	return strings.Join(parts, "\n\n")
+}
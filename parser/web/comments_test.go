@@ -0,0 +1,103 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+func TestThreadOf(t *testing.T) {
+	tests := []struct {
+		name string
+		item types.FeedItem
+		want thread
+		ok   bool
+	}{
+		{
+			name: "hn comments link",
+			item: types.FeedItem{Link: "https://example.com/post", CommentsURL: "https://news.ycombinator.com/item?id=42"},
+			want: thread{hnID: "42"},
+			ok:   true,
+		},
+		{
+			name: "reddit post",
+			item: types.FeedItem{Link: "https://old.reddit.com/r/golang/comments/abc/title/"},
+			want: thread{redditURL: "https://www.reddit.com/r/golang/comments/abc/title.json"},
+			ok:   true,
+		},
+		{
+			name: "no thread",
+			item: types.FeedItem{Link: "https://example.com/post", CommentsURL: "https://example.com/post#comments"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := threadOf(tt.item)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHNComments(t *testing.T) {
+	items := map[string]string{
+		"/item/1.json": `{"kids":[2,3,4,5]}`,
+		"/item/2.json": `{"by":"alice","text":"First &amp; best<p>Second <i>paragraph</i>","time":1700000000}`,
+		"/item/3.json": `{"deleted":true}`,
+		"/item/4.json": `{"by":"bob","text":"Agreed","time":1700000100}`,
+		"/item/5.json": `{"by":"carol","text":"Not fetched","time":1700000200}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(items[r.URL.Path]))
+	}))
+	defer srv.Close()
+
+	comments, err := Parser{}.hnComments(context.Background(), srv.URL, "1", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(comments) != 2 || comments[0].Author != "alice" || comments[1].Author != "bob" {
+		t.Fatalf("expected the first two live comments, got %+v", comments)
+	}
+	if comments[0].Text != "First & best\n\nSecond paragraph" {
+		t.Errorf("unexpected text: %q", comments[0].Text)
+	}
+}
+
+func TestRedditComments(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sort") != "top" || r.URL.Query().Get("limit") != "2" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[
+			{"data":{"children":[{"kind":"t3","data":{"author":"op","body":""}}]}},
+			{"data":{"children":[
+				{"kind":"t1","data":{"author":"alice","body":"Great <post>","score":12,"created_utc":1700000000}},
+				{"kind":"t1","data":{"author":"[deleted]","body":"[deleted]","score":5}},
+				{"kind":"more","data":{}}
+			]}}
+		]`))
+	}))
+	defer srv.Close()
+
+	comments, err := Parser{}.redditComments(context.Background(), srv.URL+"/r/golang/comments/abc/title.json", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(comments) != 1 || comments[0].Author != "alice" || comments[0].Reactions != 12 {
+		t.Fatalf("unexpected comments: %+v", comments)
+	}
+
+	html := commentsHTML(comments)
+	if !strings.Contains(html, `<h3>Discussion</h3>`) || !strings.Contains(html, `<p class="byline">alice · 12 points</p><p>Great &lt;post&gt;</p>`) {
+		t.Errorf("unexpected HTML: %s", html)
+	}
+	if got := threadText(comments); got != "alice: Great <post>" {
+		t.Errorf("unexpected thread: %q", got)
+	}
+}
@@ -0,0 +1,247 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/scipunch/myfeed/httpclient"
+	"github.com/scipunch/myfeed/ratelimit"
+)
+
+const (
+	sniffSize   = 512      // Bytes read to detect a missing or generic content type
+	maxTextSize = 1 << 20  // Plain text documents larger than this are shown as attachments
+	maxPDFSize  = 50 << 20 // PDF documents larger than this are not downloaded
+	maxPageSize = 10 << 20 // HTML pages larger than this are not loaded, rendered ones are cut
+)
+
+// content describes what a link points to
+type content struct {
+	Type string // Media type without parameters, e.g., "application/pdf"
+	Size int64  // Total size in bytes, -1 if unknown
+}
+
+func (c content) isHTML() bool {
+	return c.Type == "text/html" || c.Type == "application/xhtml+xml"
+}
+
+// sniff requests the first bytes of the link and detects its content type,
+// trusting the Content-Type header unless it is missing or generic
+func (p Parser) sniff(ctx context.Context, link string) (content, error) {
+	c := content{Size: -1}
+	client, err := httpclient.New(p.proxy)
+	if err != nil {
+		return c, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return c, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", sniffSize-1))
+	req.Header.Set("User-Agent", p.requestUserAgent())
+
+	release, err := ratelimit.Acquire(ctx, ratelimit.HostKey(link))
+	if err != nil {
+		return c, err
+	}
+	defer release()
+	resp, err := client.Do(req)
+	if err != nil {
+		return c, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return c, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	head, err := io.ReadAll(io.LimitReader(resp.Body, sniffSize))
+	if err != nil {
+		return c, err
+	}
+	c.Type, _, _ = mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if c.Type == "" || c.Type == "application/octet-stream" || c.Type == "binary/octet-stream" {
+		c.Type, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	}
+	c.Size = contentSize(resp)
+	return c, nil
+}
+
+// contentSize reads the total size from Content-Range of partial responses
+// or Content-Length of full ones
+func contentSize(resp *http.Response) int64 {
+	if resp.StatusCode == http.StatusPartialContent {
+		_, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/")
+		if size, err := strconv.ParseInt(total, 10, 64); ok && err == nil {
+			return size
+		}
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// renderContent renders a link which is not an HTML page: images are
+// embedded, PDF and plain text documents are converted to paragraphs and
+// anything else becomes a card linking to the file
+func (p Parser) renderContent(ctx context.Context, link, title string, c content) string {
+	switch {
+	case strings.HasPrefix(c.Type, "image/") && c.Type != "image/svg+xml":
+		return fmt.Sprintf(`<figure><img src="%s" alt="%s"></figure>`, html.EscapeString(link), html.EscapeString(title))
+	case c.Type == "application/pdf":
+		text, err := p.pdfText(ctx, link, c)
+		if err == nil && strings.TrimSpace(text) != "" {
+			return textToHTML(text) + attachmentCard(link, c)
+		}
+		slog.Info("showing PDF as attachment", "url", link, "reason", err)
+	case c.Type == "text/plain" && c.Size <= maxTextSize:
+		body, err := p.download(ctx, link, maxTextSize)
+		if err == nil {
+			return textToHTML(string(body))
+		}
+		slog.Warn("failed to download text document", "url", link, "error", err)
+	}
+	return attachmentCard(link, c)
+}
+
+// pdfText extracts the text of a PDF with pdftotext from poppler-utils
+func (p Parser) pdfText(ctx context.Context, link string, c content) (string, error) {
+	if c.Size > maxPDFSize {
+		return "", fmt.Errorf("document is larger than %d bytes", maxPDFSize)
+	}
+	pdftotext, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", fmt.Errorf("pdftotext is not installed")
+	}
+	body, err := p.download(ctx, link, maxPDFSize)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "myfeed-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	out, err := exec.CommandContext(ctx, pdftotext, "-enc", "UTF-8", f.Name(), "-").Output()
+	if err != nil {
+		return "", fmt.Errorf("pdftotext failed with %w", err)
+	}
+	return string(out), nil
+}
+
+// download reads the whole document, failing when it is larger than limit
+func (p Parser) download(ctx context.Context, link string, limit int64) ([]byte, error) {
+	client, err := httpclient.New(p.proxy)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", p.requestUserAgent())
+	release, err := ratelimit.Acquire(ctx, ratelimit.HostKey(link))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("document is larger than %d bytes", limit)
+	}
+	return body, nil
+}
+
+// requestUserAgent returns the configured user agent or one identifying myfeed
+func (p Parser) requestUserAgent() string {
+	if p.userAgent == "" {
+		return httpclient.DefaultUserAgent
+	}
+	return p.userAgent
+}
+
+// truncateHTML cuts the document to at most limit bytes without splitting
+// a tag, the HTML parser closes elements left open
+func truncateHTML(doc string, limit int) string {
+	if len(doc) <= limit {
+		return doc
+	}
+	doc = doc[:limit]
+	if i := strings.LastIndexByte(doc, '<'); i > strings.LastIndexByte(doc, '>') {
+		doc = doc[:i]
+	}
+	return strings.ToValidUTF8(doc, "")
+}
+
+// textToHTML escapes plain text, blank lines separate paragraphs
+func textToHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	// pdftotext separates pages with form feeds
+	text = strings.ReplaceAll(text, "\f", "\n\n")
+	var b strings.Builder
+	for _, para := range strings.Split(text, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		lines := strings.Split(html.EscapeString(para), "\n")
+		fmt.Fprintf(&b, "<p>%s</p>\n", strings.Join(lines, "<br>\n"))
+	}
+	return b.String()
+}
+
+// attachmentCard links to a file which can't be shown inline
+func attachmentCard(link string, c content) string {
+	name := link
+	if u, err := url.Parse(link); err == nil {
+		if base := path.Base(u.Path); base != "/" && base != "." {
+			name = base
+		}
+	}
+	details := c.Type
+	if details == "" {
+		details = "unknown type"
+	}
+	if c.Size >= 0 {
+		details += ", " + formatSize(c.Size)
+	}
+	return fmt.Sprintf(`<p class="attachment">Attachment: <a href="%s">%s</a> (%s)</p>`,
+		html.EscapeString(link), html.EscapeString(name), html.EscapeString(details))
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSniff(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 1000)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body>hi</body></html>"))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		// Generic type, detected from the first bytes
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(png))
+	})
+	mux.HandleFunc("/archive.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(strings.Repeat("x", 2048)))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		want content
+	}{
+		{"/page", content{Type: "text/html", Size: 28}},
+		{"/image", content{Type: "image/png", Size: int64(len(png))}},
+		{"/archive.zip", content{Type: "application/zip", Size: 2048}},
+	}
+	for _, tt := range tests {
+		got, err := Parser{}.sniff(context.Background(), srv.URL+tt.path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRenderContent(t *testing.T) {
+	image := Parser{}.renderContent(context.Background(), "https://example.com/cat.png", `A "cat"`, content{Type: "image/png", Size: 10})
+	if image != `<figure><img src="https://example.com/cat.png" alt="A &#34;cat&#34;"></figure>` {
+		t.Errorf("unexpected image embed: %s", image)
+	}
+
+	card := Parser{}.renderContent(context.Background(), "https://example.com/files/data.zip?v=1", "", content{Type: "application/zip", Size: 3 << 20})
+	want := `<p class="attachment">Attachment: <a href="https://example.com/files/data.zip?v=1">data.zip</a> (application/zip, 3.0 MB)</p>`
+	if card != want {
+		t.Errorf("got %s, want %s", card, want)
+	}
+}
+
+func TestTextToHTML(t *testing.T) {
+	got := textToHTML("Title\r\n\r\nline <1>\nline 2\f\n\nnext page")
+	want := "<p>Title</p>\n<p>line &lt;1&gt;<br>\nline 2</p>\n<p>next page</p>\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTruncateHTML(t *testing.T) {
+	doc := "<html><body><p>first</p><p>sec<b>ond</b></p></body></html>"
+	if got := truncateHTML(doc, 30); got != "<html><body><p>first</p><p>sec" {
+		t.Errorf("got %q", got)
+	}
+	if got := truncateHTML(doc, len(doc)); got != doc {
+		t.Errorf("expected the document unchanged, got %q", got)
+	}
+	// Multi-byte characters are not split
+	if got := truncateHTML("<p>привет", 6); got != "<p>п" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/scipunch/myfeed/parser"
+)
+
+const (
+	defaultMaxImageSize = 5 << 20  // Images larger than this stay remote unless max_image_mb is set
+	maxArticleImages    = 25 << 20 // Images of one article beyond this total stay remote
+)
+
+// imageExtensions maps types of downloaded images to file extensions
+var imageExtensions = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/avif":    ".avif",
+	"image/svg+xml": ".svg",
+}
+
+// ImageDir returns the directory of images downloaded with localize_images,
+// shared by runs so an image is downloaded once
+func ImageDir() string {
+	return filepath.Join(os.Getenv("HOME"), ".cache", "myfeed", "images")
+}
+
+// localizeImages downloads the images of the response into dir and points
+// them at media/<file name>. Images failing to download or exceeding the size
+// caps keep their remote source.
+func (p Parser) localizeImages(ctx context.Context, resp *Response, pageURL, dir string) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return
+	}
+	limit := int64(defaultMaxImageSize)
+	if p.options.MaxImageMB > 0 {
+		limit = int64(p.options.MaxImageMB * (1 << 20))
+	}
+	budget := int64(maxArticleImages)
+	local := make(map[string]string) // Media sources by the remote one
+	localize := func(src string) (string, bool) {
+		ref, err := url.Parse(strings.TrimSpace(src))
+		if err != nil {
+			return "", false
+		}
+		link := base.ResolveReference(ref).String()
+		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+			return "", false
+		}
+		if media, ok := local[link]; ok {
+			return media, true
+		}
+		if budget <= 0 {
+			return "", false
+		}
+		path, size, err := p.downloadImage(ctx, link, dir, min(limit, budget))
+		if err != nil {
+			slog.Debug("image stays remote", "url", link, "error", err)
+			return "", false
+		}
+		budget -= size
+		local[link] = "media/" + filepath.Base(path)
+		resp.Media = append(resp.Media, path)
+		return local[link], true
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(resp.HTML))
+	if err != nil {
+		return
+	}
+	doc.Find("img[src]").Each(func(_ int, img *goquery.Selection) {
+		media, ok := localize(img.AttrOr("src", ""))
+		if !ok {
+			return
+		}
+		img.SetAttr("src", media)
+		// Alternative sources would load the remote image again
+		img.RemoveAttr("srcset")
+		img.RemoveAttr("sizes")
+		img.Closest("picture").Find("source").Remove()
+	})
+	if len(local) > 0 {
+		if content, err := doc.Find("body").Html(); err == nil {
+			resp.HTML = content
+		}
+	}
+	if resp.Meta.Image != "" {
+		if media, ok := localize(resp.Meta.Image); ok {
+			resp.Meta.Image = media
+		}
+	}
+	resp.Meta.Images = parser.ImageSources(resp.HTML)
+	if len(resp.Media) > 0 {
+		slog.Debug("localized images", "url", pageURL, "count", len(resp.Media))
+	}
+}
+
+// downloadImage saves the image at link into dir, named by the hash of the
+// link, and returns its path and size. Images downloaded before are reused.
+func (p Parser) downloadImage(ctx context.Context, link, dir string, limit int64) (string, int64, error) {
+	name := fmt.Sprintf("%x", sha256.Sum256([]byte(link)))[:32]
+	if matches, _ := filepath.Glob(filepath.Join(dir, name+".*")); len(matches) > 0 {
+		if info, err := os.Stat(matches[0]); err == nil && info.Size() <= limit {
+			// Images in use are kept by history_retention
+			now := time.Now()
+			_ = os.Chtimes(matches[0], now, now)
+			return matches[0], info.Size(), nil
+		}
+	}
+
+	body, err := p.download(ctx, link, limit)
+	if err != nil {
+		return "", 0, err
+	}
+	ext, ok := imageExtensions[imageType(body)]
+	if !ok {
+		return "", 0, fmt.Errorf("not an image")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create image directory with %w", err)
+	}
+	path := filepath.Join(dir, name+ext)
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return "", 0, fmt.Errorf("failed to save image with %w", err)
+	}
+	return path, int64(len(body)), nil
+}
+
+// imageType detects the type of image data, including the SVG and AVIF
+// formats http.DetectContentType doesn't know
+func imageType(data []byte) string {
+	if len(data) >= 12 && bytes.Equal(data[4:12], []byte("ftypavif")) {
+		return "image/avif"
+	}
+	detected := http.DetectContentType(data)
+	if strings.HasPrefix(detected, "text/") && bytes.Contains(data[:min(len(data), 1024)], []byte("<svg")) {
+		return "image/svg+xml"
+	}
+	return detected
+}
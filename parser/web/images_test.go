@@ -0,0 +1,86 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/parser"
+)
+
+func TestLocalizeImages(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 100)
+	downloads := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cat.png", func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write([]byte(png))
+	})
+	mux.HandleFunc("/huge.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(png + strings.Repeat("\x00", 2000)))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>not an image</body></html>"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	p := Parser{options: parser.Options{LocalizeImages: true, MaxImageMB: 0.001}}
+	resp := Response{
+		HTML: `<picture><source srcset="/cat.webp"><img src="/cat.png" srcset="/cat@2x.png 2x"></picture>` +
+			`<img src="` + srv.URL + `/cat.png"><img src="/huge.png"><img src="/page"><img src="data:image/gif;base64,R0lGOD">`,
+		Metadata: parser.Metadata{Meta: parser.Meta{Image: "/cat.png"}},
+	}
+	p.localizeImages(context.Background(), &resp, srv.URL+"/post", dir)
+
+	if len(resp.Media) != 1 || filepath.Dir(resp.Media[0]) != dir {
+		t.Fatalf("expected one image downloaded into %s, got %v", dir, resp.Media)
+	}
+	if downloads != 1 {
+		t.Errorf("expected the shared image to be downloaded once, got %d", downloads)
+	}
+	media := "media/" + filepath.Base(resp.Media[0])
+	if got := strings.Count(resp.HTML, `src="`+media+`"`); got != 2 {
+		t.Errorf("expected both references to point at %s, got %s", media, resp.HTML)
+	}
+	if strings.Contains(resp.HTML, "srcset") || strings.Contains(resp.HTML, "<source") {
+		t.Errorf("expected alternative sources of the image to be removed, got %s", resp.HTML)
+	}
+	// Too large, not an image and inline
+	for _, remote := range []string{`src="/huge.png"`, `src="/page"`, `src="data:image/gif`} {
+		if !strings.Contains(resp.HTML, remote) {
+			t.Errorf("expected %s to stay, got %s", remote, resp.HTML)
+		}
+	}
+	if resp.Image() != media {
+		t.Errorf("expected the preview image to be localized, got %q", resp.Image())
+	}
+	if data, err := os.ReadFile(resp.Media[0]); err != nil || string(data) != png {
+		t.Errorf("unexpected image file: %v", err)
+	}
+
+	// Images downloaded before are reused
+	again := Response{HTML: `<img src="/cat.png">`}
+	p.localizeImages(context.Background(), &again, srv.URL+"/post", dir)
+	if downloads != 1 || len(again.Media) != 1 || again.Media[0] != resp.Media[0] {
+		t.Errorf("expected the image to be reused, got %v after %d downloads", again.Media, downloads)
+	}
+}
+
+func TestImageType(t *testing.T) {
+	for data, want := range map[string]string{
+		"\x89PNG\r\n\x1a\n\x00\x00":                    "image/png",
+		`<?xml version="1.0"?><svg xmlns="http://x"/>`: "image/svg+xml",
+		"\x00\x00\x00\x1cftypavif\x00\x00":             "image/avif",
+		"<html><body>not an image</body></html>":       "text/html; charset=utf-8",
+	} {
+		if got := imageType([]byte(data)); got != want {
+			t.Errorf("imageType(%q) = %q, want %q", data, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"cmp"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/parser"
+)
+
+// Layouts of publication dates in page metadata
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05", "2006-01-02"}
+
+// pageMeta reads the title, author, publication date and preview image the
+// page at pageURL declares in OpenGraph, article and standard meta tags
+func pageMeta(rawHtml, pageURL string) parser.Meta {
+	var meta parser.Meta
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHtml))
+	if err != nil {
+		return meta
+	}
+	first := func(selectors ...string) string {
+		for _, selector := range selectors {
+			if v := strings.TrimSpace(doc.Find(selector).First().AttrOr("content", "")); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	meta.Title = cmp.Or(first(`meta[property="og:title"]`, `meta[name="twitter:title"]`), strings.TrimSpace(doc.Find("title").First().Text()))
+	// article:author is often a profile URL rather than a name
+	meta.Author = first(`meta[name="author"]`, `meta[property="article:author"]:not([content^="http"])`)
+	published := first(`meta[property="article:published_time"]`, `meta[itemprop="datePublished"]`, `meta[name="date"]`)
+	if published == "" {
+		published = strings.TrimSpace(doc.Find(`time[datetime]`).First().AttrOr("datetime", ""))
+	}
+	// Relative images are resolved against the page
+	image := first(`meta[property="og:image"]`, `meta[property="og:image:url"]`, `meta[name="twitter:image"]`, `meta[name="twitter:image:src"]`)
+	if image == "" {
+		image = strings.TrimSpace(doc.Find(`link[rel="image_src"]`).First().AttrOr("href", ""))
+	}
+	if base, err := url.Parse(pageURL); err == nil && image != "" {
+		if ref, err := url.Parse(image); err == nil {
+			meta.Image = base.ResolveReference(ref).String()
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, published); err == nil {
+			meta.PublishedAt = t
+			break
+		}
+	}
+	return meta
+}
+
+// describe completes the metadata of a response from the feed item and the
+// extracted article
+func describe(meta parser.Meta, item types.FeedItem, content string) parser.Meta {
+	meta.Title = cmp.Or(meta.Title, item.Title)
+	if meta.PublishedAt.IsZero() {
+		meta.PublishedAt = item.Published
+	}
+	meta.WordCount = parser.CountWords(content)
+	meta.Images = parser.ImageSources(content)
+	return meta
+}
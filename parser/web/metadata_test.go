@@ -0,0 +1,47 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/parser"
+)
+
+func TestPageMeta(t *testing.T) {
+	page := `<html><head>
+		<title>Fallback | Blog</title>
+		<meta property="og:title" content="Real title">
+		<meta property="article:author" content="https://example.com/jane">
+		<meta name="author" content="Jane Doe">
+		<meta property="article:published_time" content="2024-03-05T10:00:00+01:00">
+		<meta property="og:image" content="/images/cover.png">
+	</head><body></body></html>`
+	meta := pageMeta(page, "https://example.com/posts/1")
+	if meta.Title != "Real title" || meta.Author != "Jane Doe" {
+		t.Errorf("expected title and author of the meta tags, got %+v", meta)
+	}
+	if want := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC); !meta.PublishedAt.Equal(want) {
+		t.Errorf("expected published at %v, got %v", want, meta.PublishedAt)
+	}
+	if meta.Image != "https://example.com/images/cover.png" {
+		t.Errorf("expected the image resolved against the page, got %q", meta.Image)
+	}
+
+	meta = pageMeta(`<html><head><title>Only title</title></head><body><time datetime="2024-03-05">March</time></body></html>`, "https://example.com/")
+	if meta.Title != "Only title" || meta.Author != "" || meta.PublishedAt.IsZero() || meta.Image != "" {
+		t.Errorf("expected fallbacks of the page, got %+v", meta)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	published := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	item := types.FeedItem{Title: "Feed title", Published: published}
+	meta := describe(parser.Meta{Author: "Jane"}, item, `<p>Three short words</p><img src="a.png">`)
+	if meta.Title != "Feed title" || !meta.PublishedAt.Equal(published) || meta.Author != "Jane" {
+		t.Errorf("expected the item to fill missing metadata, got %+v", meta)
+	}
+	if meta.WordCount != 3 || len(meta.Images) != 1 {
+		t.Errorf("expected 3 words and 1 image, got %+v", meta)
+	}
+}
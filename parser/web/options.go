@@ -0,0 +1,98 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/mackee/go-readability"
+
+	"github.com/scipunch/myfeed/parser"
+)
+
+// Elements removed by the strip options
+const (
+	imageSelector = "img, picture, figure, svg"
+	tableSelector = "table"
+)
+
+// extractArticle returns the article of the page: the elements matching the
+// content selector if set, the readability result otherwise, trimmed with
+// the exclusion and strip options
+func extractArticle(rawHtml string, opts parser.Options) (string, error) {
+	var content string
+	if opts.ContentSelector != "" {
+		var err error
+		content, err = selectContent(rawHtml, opts.ContentSelector)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		options := readability.DefaultOptions()
+		if opts.CharThreshold > 0 {
+			options.CharThreshold = opts.CharThreshold
+		}
+		article, err := readability.Extract(rawHtml, options)
+		if err != nil {
+			return "", fmt.Errorf("readability failed with %w", err)
+		}
+		if article.Root == nil {
+			return "", fmt.Errorf("readability returned empty article")
+		}
+		content = readability.ToHTML(article.Root)
+	}
+	return trimArticle(content, opts)
+}
+
+// selectContent joins the elements matching the selector
+func selectContent(rawHtml, selector string) (string, error) {
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return "", fmt.Errorf("invalid content selector %q: %w", selector, err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHtml))
+	if err != nil {
+		return "", err
+	}
+	matches := doc.FindMatcher(sel)
+	if matches.Length() == 0 {
+		return "", fmt.Errorf("content selector %q matched nothing", selector)
+	}
+	var b strings.Builder
+	for _, node := range matches.Nodes {
+		html, err := goquery.OuterHtml(goquery.NewDocumentFromNode(node).Selection)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(html)
+	}
+	return b.String(), nil
+}
+
+// trimArticle removes excluded elements, images and tables from the article
+func trimArticle(content string, opts parser.Options) (string, error) {
+	remove := append([]string{}, opts.Exclude...)
+	if opts.StripImages {
+		remove = append(remove, imageSelector)
+	}
+	if opts.StripTables {
+		remove = append(remove, tableSelector)
+	}
+	if len(remove) == 0 {
+		return content, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	for _, selector := range remove {
+		sel, err := cascadia.Compile(selector)
+		if err != nil {
+			return "", fmt.Errorf("invalid exclude selector %q: %w", selector, err)
+		}
+		doc.FindMatcher(sel).Remove()
+	}
+	return doc.Find("body").Html()
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/parser"
+)
+
+const optionsPage = `<html><body>
+<nav>Home · About</nav>
+<div class="post">
+  <p>First paragraph of the post.</p>
+  <div class="signup">Subscribe to the newsletter!</div>
+  <figure><img src="chart.png"><figcaption>Chart</figcaption></figure>
+  <table><tr><td>1</td></tr></table>
+</div>
+<div class="post"><p>Second post.</p></div>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestExtractArticle_ContentSelector(t *testing.T) {
+	got, err := extractArticle(optionsPage, parser.Options{
+		ContentSelector: ".post",
+		Exclude:         []string{".signup"},
+		StripImages:     true,
+		StripTables:     true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"First paragraph", "Second post"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Home", "Copyright", "Subscribe", "<img", "<figure", "<table"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("expected %q removed from %q", unwanted, got)
+		}
+	}
+}
+
+func TestExtractArticle_Errors(t *testing.T) {
+	for name, opts := range map[string]parser.Options{
+		"invalid content selector": {ContentSelector: "div[["},
+		"nothing matched":          {ContentSelector: "article"},
+		"invalid exclude selector": {ContentSelector: ".post", Exclude: []string{"::"}},
+	} {
+		if _, err := extractArticle(optionsPage, opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestTrimArticle_NoOptions(t *testing.T) {
+	content := "<p>Kept <img src=\"a.png\"> as is</p>"
+	got, err := trimArticle(content, parser.Options{})
+	if err != nil || got != content {
+		t.Errorf("expected content unchanged, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxArticlePages bounds how many pages of a single article are stitched together
+const maxArticlePages = 10
+
+// nextPageURL returns the next page of a paginated article advertised with
+// rel="next". To avoid walking into neighbouring posts, the next page must be
+// on the same host and either extend the first page path or differ only in query.
+func nextPageURL(rawHtml, current, first string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHtml))
+	if err != nil {
+		return ""
+	}
+	href, ok := doc.Find(`link[rel~="next"], a[rel~="next"]`).First().Attr("href")
+	if !ok || strings.TrimSpace(href) == "" {
+		return ""
+	}
+
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	next, err := base.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	next.Fragment = ""
+
+	origin, err := url.Parse(first)
+	if err != nil || !strings.EqualFold(next.Host, origin.Host) {
+		return ""
+	}
+	originPath := strings.TrimSuffix(origin.Path, "/")
+	samePath := strings.TrimSuffix(next.Path, "/") == originPath
+	if !samePath && !strings.HasPrefix(next.Path, originPath+"/") {
+		return ""
+	}
+	return next.String()
+}
@@ -0,0 +1,45 @@
+package web
+
+import "testing"
+
+func TestNextPageURL(t *testing.T) {
+	const first = "https://example.com/articles/long-read"
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "link in head",
+			html: `<html><head><link rel="next" href="/articles/long-read/2"></head></html>`,
+			want: "https://example.com/articles/long-read/2",
+		},
+		{
+			name: "anchor with query page",
+			html: `<a class="pager" rel="next nofollow" href="?page=2#top">Next</a>`,
+			want: "https://example.com/articles/long-read?page=2",
+		},
+		{
+			name: "neighbouring post",
+			html: `<link rel="next" href="/articles/another-post">`,
+			want: "",
+		},
+		{
+			name: "other host",
+			html: `<link rel="next" href="https://other.com/articles/long-read/2">`,
+			want: "",
+		},
+		{
+			name: "no pagination",
+			html: `<p>Single page</p>`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextPageURL(tt.html, first, first); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,42 @@
 package web
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"net/url"
 
-	"github.com/mackee/go-readability"
 	"github.com/playwright-community/playwright-go"
 
 	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/httpclient"
+	"github.com/scipunch/myfeed/markdown"
 	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/parser/rules"
+	"github.com/scipunch/myfeed/ratelimit"
 )
 
+// Version of the parser output, bump it when extraction changes to
+// invalidate cached outputs of earlier versions
+const Version = 2
+
+// Capabilities of the parser: pages of feed items loaded in the browser
+var Capabilities = parser.Capabilities{
+	ResourceTypes: []string{"rss"},
+	Schemes:       []string{"http", "https"},
+	Options:       true,
+	Comments:      true,
+}
+
 type Parser struct {
-	pw      *playwright.Playwright
-	browser playwright.Browser
+	pw        *playwright.Playwright
+	browser   playwright.Browser
+	proxy     string     // Proxy URL for page requests, empty for direct connection
+	rules     *rules.Set // Per-domain cleanup applied after readability
+	userAgent string     // User-Agent of page requests, empty for the browser default
+	options   parser.Options
+	comments  int    // Top comments of the HN or Reddit thread appended to the article, 0 for none
+	imageDir  string // Images downloaded with localize_images, see ImageDir
 }
 
 func New() (Parser, error) {
@@ -31,9 +55,45 @@ func New() (Parser, error) {
 	}
 	p.pw = pw
 	p.browser = browser
+	p.imageDir = ImageDir()
 	return p, nil
 }
 
+// WithProxy returns a parser loading pages through the proxy.
+// The browser instance is shared with the original parser.
+func (p Parser) WithProxy(proxy string) parser.Parser {
+	p.proxy = proxy
+	return p
+}
+
+// WithUserAgent returns a parser loading pages with the user agent.
+// The browser instance is shared with the original parser.
+func (p Parser) WithUserAgent(userAgent string) parser.Parser {
+	p.userAgent = userAgent
+	return p
+}
+
+// WithRules returns a parser cleaning extracted articles with the source rules.
+// The browser instance is shared with the original parser.
+func (p Parser) WithRules(set *rules.Set) parser.Parser {
+	p.rules = set
+	return p
+}
+
+// WithOptions returns a parser extracting articles with the options.
+// The browser instance is shared with the original parser.
+func (p Parser) WithOptions(opts parser.Options) parser.Parser {
+	p.options = opts
+	return p
+}
+
+// WithComments returns a parser appending up to limit top comments of the
+// item's HN or Reddit thread. The browser instance is shared with the original parser.
+func (p Parser) WithComments(limit int) parser.Parser {
+	p.comments = limit
+	return p
+}
+
 func (p Parser) Close() error {
 	if err := p.browser.Close(); err != nil {
 		return err
@@ -42,39 +102,158 @@ func (p Parser) Close() error {
 }
 
 type Response struct {
-	HTML string
+	parser.Metadata
+	HTML   string
+	Thread string   `json:",omitempty"` // Top comments of the discussion thread as plain text
+	Media  []string `json:",omitempty"` // Local paths of images downloaded with localize_images
 }
 
+// String returns the page as Markdown
 func (r Response) String() string {
-	return r.HTML
+	return markdown.FromHTML(r.HTML)
+}
+
+// Comments returns the top comments for the discussion agent
+func (r Response) Comments() string {
+	return r.Thread
 }
 
-func (p Parser) Parse(item types.FeedItem) (parser.Response, error) {
+// MediaFiles returns the downloaded images shown in the page
+func (r Response) MediaFiles() []string {
+	return r.Media
+}
+
+func (p Parser) Parse(ctx context.Context, item types.FeedItem) (parser.Response, error) {
 	var resp Response
-	page, err := p.browser.NewPage()
+
+	// Images, documents and archives can't go through readability
+	c, err := p.sniff(ctx, item.Link)
 	if err != nil {
-		return resp, fmt.Errorf("could not create page: %w", err)
+		slog.Debug("failed to detect content type, loading as a page", "url", item.Link, "error", err)
+	} else if !c.isHTML() {
+		slog.Info("link is not an HTML page", "url", item.Link, "type", c.Type)
+		resp.HTML = p.renderContent(ctx, item.Link, item.Title, c)
+		resp.Meta = describe(parser.Meta{}, item, resp.HTML)
+		if p.options.LocalizeImages {
+			p.localizeImages(ctx, &resp, item.Link, p.imageDir)
+		}
+		if p.comments > 0 {
+			p.appendComments(ctx, &resp, item)
+		}
+		return resp, nil
+	} else if c.Size > maxPageSize {
+		return resp, fmt.Errorf("page at '%s' is larger than %d bytes", item.Link, maxPageSize)
+	}
+
+	rawHtml, content, err := p.extract(ctx, item.Link)
+	if err != nil {
+		return resp, err
+	}
+	resp.HTML = content
+	meta := pageMeta(rawHtml, item.Link)
+
+	// Stitch articles split into several pages
+	visited := map[string]bool{item.Link: true}
+	link := item.Link
+	for pages := 1; pages < maxArticlePages; pages++ {
+		next := nextPageURL(rawHtml, link, item.Link)
+		if next == "" || visited[next] {
+			break
+		}
+		visited[next] = true
+
+		rawHtml, content, err = p.extract(ctx, next)
+		if err != nil {
+			slog.Warn("failed to fetch next article page", "url", next, "error", err)
+			break
+		}
+		slog.Info("stitched article page", "url", item.Link, "page", pages+1)
+		resp.HTML += content
+		link = next
+	}
+	resp.Meta = describe(meta, item, resp.HTML)
+	if p.options.LocalizeImages {
+		p.localizeImages(ctx, &resp, item.Link, p.imageDir)
+	}
+
+	if p.comments > 0 {
+		p.appendComments(ctx, &resp, item)
+	}
+	return resp, nil
+}
+
+// extract loads the page and returns its raw HTML and the readable article
+func (p Parser) extract(ctx context.Context, link string) (string, string, error) {
+	release, err := ratelimit.Acquire(ctx, ratelimit.HostKey(link))
+	if err != nil {
+		return "", "", err
+	}
+	defer release()
+	if err := httpclient.Wait(ctx, link); err != nil {
+		return "", "", err
+	}
+	page, err := p.newPage()
+	if err != nil {
+		return "", "", fmt.Errorf("could not create page: %w", err)
 	}
 	defer page.Close()
-	if _, err = page.Goto(item.Link); err != nil {
-		return resp, fmt.Errorf("could not go to '%s': %w", item.Link, err)
+	if _, err = page.Goto(link); err != nil {
+		return "", "", fmt.Errorf("could not go to '%s': %w", link, err)
 	}
 	rawHtml, err := page.Content()
 	if err != nil {
-		return resp, fmt.Errorf("could not read page content at '%s': %w", item.Link, err)
+		return "", "", fmt.Errorf("could not read page content at '%s': %w", link, err)
+	}
+	if len(rawHtml) > maxPageSize {
+		slog.Warn("page exceeds the size limit, extracting its beginning", "url", link, "size", len(rawHtml))
+		rawHtml = truncateHTML(rawHtml, maxPageSize)
 	}
-	resp.HTML = rawHtml
 
-	options := readability.DefaultOptions()
-	article, err := readability.Extract(string(rawHtml), options)
+	content, err := extractArticle(rawHtml, p.options)
 	if err != nil {
-		return resp, fmt.Errorf("could not use readability for '%s': %w", item.Link, err)
+		return "", "", fmt.Errorf("could not extract article from '%s': %w", link, err)
+	}
+	if p.rules != nil {
+		cleaned, err := p.rules.Apply(link, rawHtml, content)
+		if err != nil {
+			slog.Warn("failed to apply source rules", "url", link, "error", err)
+		} else {
+			content = cleaned
+		}
 	}
+	return rawHtml, content, nil
+}
 
-	if article.Root == nil {
-		return resp, fmt.Errorf("readability returned empty article for '%s'", item.Link)
+// newPage opens a page in a fresh browser context using the configured proxy and user agent
+func (p Parser) newPage() (playwright.Page, error) {
+	var opts playwright.BrowserNewPageOptions
+	if p.userAgent != "" {
+		opts.UserAgent = playwright.String(p.userAgent)
+	}
+	if p.proxy != "" {
+		proxy, err := playwrightProxy(p.proxy)
+		if err != nil {
+			return nil, err
+		}
+		opts.Proxy = proxy
 	}
+	return p.browser.NewPage(opts)
+}
 
-	resp.HTML = readability.ToHTML(article.Root)
-	return resp, nil
+// playwrightProxy converts a proxy URL into playwright settings,
+// moving credentials out of the URL
+func playwrightProxy(raw string) (*playwright.Proxy, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	proxy := &playwright.Proxy{Server: u.Scheme + "://" + u.Host}
+	if u.User != nil {
+		proxy.Username = playwright.String(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			proxy.Password = playwright.String(password)
+		}
+	}
+	return proxy, nil
 }
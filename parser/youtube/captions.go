@@ -0,0 +1,196 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/httpclient"
+)
+
+// innertubeURL is the player endpoint of the YouTube web API listing caption tracks
+var innertubeURL = "https://www.youtube.com/youtubei/v1/player?prettyPrint=false"
+
+// innertubeClient identifies the request as the Android app, whose caption
+// tracks can be downloaded without a proof-of-origin token
+var innertubeClient = map[string]any{
+	"clientName":        "ANDROID",
+	"clientVersion":     "19.09.37",
+	"androidSdkVersion": 30,
+	"hl":                "en",
+}
+
+// innertubeUserAgent matches the client the requests claim to come from
+const innertubeUserAgent = "com.google.android.youtube/19.09.37 (Linux; U; Android 11) gzip"
+
+// errNoCaptions is returned for videos without any caption track
+var errNoCaptions = errors.New("video has no captions")
+
+type captionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"` // "asr" for automatic captions
+}
+
+// videoID extracts the video ID from watch, short, live, embed and youtu.be links
+func videoID(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	if host == "youtu.be" {
+		if id := strings.Trim(u.Path, "/"); id != "" {
+			return id, nil
+		}
+	}
+	if id := u.Query().Get("v"); id != "" {
+		return id, nil
+	}
+	for _, prefix := range []string{"/shorts/", "/live/", "/embed/"} {
+		if id, ok := strings.CutPrefix(u.Path, prefix); ok && id != "" {
+			return strings.Trim(id, "/"), nil
+		}
+	}
+	return "", fmt.Errorf("no video ID in '%s'", link)
+}
+
+// captions downloads the existing captions of the video without running
+// Python, errNoCaptions means only transcribing the audio can help. The
+// length of the video is returned whenever the player was loaded.
+func captions(ctx context.Context, link string) (Transcription, time.Duration, error) {
+	var t Transcription
+	id, err := videoID(link)
+	if err != nil {
+		return t, 0, err
+	}
+
+	var player struct {
+		VideoDetails struct {
+			Title            string `json:"title"`
+			Author           string `json:"author"`
+			ShortDescription string `json:"shortDescription"`
+			LengthSeconds    int64  `json:"lengthSeconds,string"`
+		} `json:"videoDetails"`
+		Captions struct {
+			Renderer struct {
+				Tracks []captionTrack `json:"captionTracks"`
+			} `json:"playerCaptionsTracklistRenderer"`
+		} `json:"captions"`
+	}
+	body, _ := json.Marshal(map[string]any{
+		"context": map[string]any{"client": innertubeClient},
+		"videoId": id,
+	})
+	if err := request(ctx, http.MethodPost, innertubeURL, body, &player); err != nil {
+		return t, 0, fmt.Errorf("failed to request player of video %s: %w", id, err)
+	}
+	length := time.Duration(player.VideoDetails.LengthSeconds) * time.Second
+	t.Title = player.VideoDetails.Title
+	t.Channel = player.VideoDetails.Author
+	t.Description = player.VideoDetails.ShortDescription
+	t.Duration = length.Seconds()
+
+	track, ok := pickTrack(player.Captions.Renderer.Tracks)
+	if !ok {
+		return t, length, errNoCaptions
+	}
+	t.Language = track.LanguageCode
+	t.Segments, err = timedText(ctx, track.BaseURL)
+	if err != nil {
+		return t, length, err
+	}
+	if len(t.Segments) == 0 {
+		return t, length, errNoCaptions
+	}
+	return t, length, nil
+}
+
+// pickTrack prefers captions written by the author over automatic ones,
+// English over other languages
+func pickTrack(tracks []captionTrack) (captionTrack, bool) {
+	best, bestScore := captionTrack{}, -1
+	for _, track := range tracks {
+		score := 0
+		if track.Kind != "asr" {
+			score += 2
+		}
+		if strings.HasPrefix(track.LanguageCode, "en") {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = track, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// timedText downloads the caption track in the json3 format
+func timedText(ctx context.Context, baseURL string) ([]Segment, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("fmt", "json3")
+	u.RawQuery = query.Encode()
+
+	var track struct {
+		Events []struct {
+			StartMs    int64 `json:"tStartMs"`
+			DurationMs int64 `json:"dDurationMs"`
+			Segs       []struct {
+				Text string `json:"utf8"`
+			} `json:"segs"`
+		} `json:"events"`
+	}
+	if err := request(ctx, http.MethodGet, u.String(), nil, &track); err != nil {
+		return nil, fmt.Errorf("failed to download captions: %w", err)
+	}
+
+	var segments []Segment
+	for _, event := range track.Events {
+		var text strings.Builder
+		for _, seg := range event.Segs {
+			text.WriteString(seg.Text)
+		}
+		line := strings.Join(strings.Fields(text.String()), " ")
+		if line == "" {
+			continue
+		}
+		segments = append(segments, Segment{
+			Start: float64(event.StartMs) / 1000,
+			End:   float64(event.StartMs+event.DurationMs) / 1000,
+			Text:  line,
+		})
+	}
+	return segments, nil
+}
+
+// request sends the request and decodes the JSON reply into v
+func request(ctx context.Context, method, endpoint string, body []byte, v any) error {
+	client, _ := httpclient.New("")
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", innertubeUserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,109 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+func TestVideoID(t *testing.T) {
+	links := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ":     "dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?t=42":               "dQw4w9WgXcQ",
+		"https://www.youtube.com/shorts/dQw4w9WgXcQ":      "dQw4w9WgXcQ",
+		"https://www.youtube.com/embed/dQw4w9WgXcQ?rel=0": "dQw4w9WgXcQ",
+	}
+	for link, want := range links {
+		if got, err := videoID(link); err != nil || got != want {
+			t.Errorf("%s: got %q, %v, want %q", link, got, err, want)
+		}
+	}
+	if _, err := videoID("https://www.youtube.com/channel/UC123"); err == nil {
+		t.Error("expected error for a link without a video")
+	}
+}
+
+func TestPickTrack(t *testing.T) {
+	tracks := []captionTrack{
+		{LanguageCode: "en", Kind: "asr"},
+		{LanguageCode: "de"},
+		{LanguageCode: "en-GB"},
+	}
+	if got, _ := pickTrack(tracks); got.LanguageCode != "en-GB" {
+		t.Errorf("expected manual English captions, got %+v", got)
+	}
+	if got, _ := pickTrack(tracks[:2]); got.LanguageCode != "de" {
+		t.Errorf("expected manual captions over automatic ones, got %+v", got)
+	}
+	if _, ok := pickTrack(nil); ok {
+		t.Error("expected no track")
+	}
+}
+
+func TestCaptions(t *testing.T) {
+	tracks := []captionTrack{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/player":
+			var body struct {
+				VideoID string `json:"videoId"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.VideoID != "abc" {
+				t.Errorf("unexpected video ID: %q", body.VideoID)
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"videoDetails": map[string]any{"title": "Talk", "author": "Conf", "shortDescription": "Slides: example.com", "lengthSeconds": "10800"},
+				"captions": map[string]any{
+					"playerCaptionsTracklistRenderer": map[string]any{"captionTracks": tracks},
+				},
+			})
+		case "/timedtext":
+			if r.URL.Query().Get("fmt") != "json3" || r.URL.Query().Get("lang") != "en" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"events":[
+				{"tStartMs":0,"dDurationMs":1500,"segs":[{"utf8":"Hello"},{"utf8":" world"}]},
+				{"tStartMs":1500,"dDurationMs":100,"segs":[{"utf8":"\n"}]},
+				{"tStartMs":61000,"dDurationMs":2000,"segs":[{"utf8":"Bye"}]}
+			]}`))
+		}
+	}))
+	defer srv.Close()
+	innertubeURL = srv.URL + "/player"
+
+	// Videos without tracks need the audio
+	if _, length, err := captions(t.Context(), "https://www.youtube.com/watch?v=abc"); !errors.Is(err, errNoCaptions) || length != 3*time.Hour {
+		t.Fatalf("expected errNoCaptions of a 3 hours long video, got %v and %v", err, length)
+	}
+
+	// Long videos without captions become a stub instead of being transcribed
+	resp, err := Parser{}.WithMaxDuration(time.Hour).Parse(context.Background(), types.FeedItem{Link: "https://www.youtube.com/watch?v=abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resp.String(); got != "# Talk\n\n**Channel:** Conf\n\n**Duration:** 3:00:00\n\n*Not transcribed: the video is 180 minutes long, longer than the limit of 60 minutes.*\n" {
+		t.Errorf("unexpected stub: %q", got)
+	}
+
+	tracks = append(tracks, captionTrack{BaseURL: srv.URL + "/timedtext?lang=en", LanguageCode: "en"})
+	got, _, err := captions(t.Context(), "https://www.youtube.com/watch?v=abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Segment{{Start: 0, End: 1.5, Text: "Hello world"}, {Start: 61, End: 63, Text: "Bye"}}
+	if got.Title != "Talk" || got.Language != "en" || got.Channel != "Conf" || got.Description != "Slides: example.com" || got.Duration != 10800 || len(got.Segments) != len(want) {
+		t.Fatalf("unexpected transcription: %+v", got)
+	}
+	for i := range want {
+		if got.Segments[i] != want[i] {
+			t.Errorf("segment %d: got %+v, want %+v", i, got.Segments[i], want[i])
+		}
+	}
+}
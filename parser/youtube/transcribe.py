@@ -4,6 +4,7 @@ YouTube video transcription script using yt-dlp subtitle extraction.
 This script extracts subtitles from YouTube videos using yt-dlp's built-in subtitle functionality.
 """
 
+import argparse
 import sys
 import os
 import json
@@ -28,6 +29,19 @@ def ensure_dependencies():
             subprocess.check_call([sys.executable, "-m", "pip", "install", package_name])
 
 
+def video_metadata(info):
+    """Channel, description, duration and chapters of the video info."""
+    return {
+        "channel": info.get('channel') or info.get('uploader') or "",
+        "description": info.get('description') or "",
+        "duration": info.get('duration') or 0,
+        "chapters": [
+            {"start": chapter.get('start_time', 0), "title": chapter.get('title', "")}
+            for chapter in (info.get('chapters') or [])
+        ],
+    }
+
+
 def parse_webvtt_time(time_str):
     """Parse WebVTT time format (HH:MM:SS.mmm) to seconds."""
     # Handle both HH:MM:SS.mmm and MM:SS.mmm formats
@@ -147,11 +161,12 @@ def extract_subtitles(video_url, temp_dir):
     return {
         "title": title,
         "language": language,
-        "segments": segments
+        "segments": segments,
+        **video_metadata(info),
     }
 
 
-def download_audio(video_url, output_path):
+def download_audio(video_url, output_path, max_duration=0):
     """Download audio from YouTube video using yt-dlp."""
     import yt_dlp  # type: ignore
     import os
@@ -160,6 +175,13 @@ def download_audio(video_url, output_path):
     # Redirect stdout and stderr to capture any unwanted output from yt-dlp
     with open(os.devnull, 'w') as devnull:
         with contextlib.redirect_stdout(devnull), contextlib.redirect_stderr(devnull):
+            if max_duration:
+                # Check the length before downloading hours of audio
+                with yt_dlp.YoutubeDL({'quiet': True, 'no_warnings': True}) as probe:  # type: ignore
+                    duration = probe.extract_info(video_url, download=False).get('duration') or 0
+                if duration > max_duration:
+                    raise Exception(f"video is {duration // 60} minutes long, longer than max_duration of {max_duration // 60} minutes")
+
             ydl = yt_dlp.YoutubeDL({  # type: ignore
                 'format': 'bestaudio/best',
                 'outtmpl': str(output_path),
@@ -173,18 +195,19 @@ def download_audio(video_url, output_path):
             })
             
             info = ydl.extract_info(video_url, download=True)
-            return info.get('title', 'Unknown Title')
+            return info
 
 
-def transcribe_audio(audio_path, model_size="tiny"):
+def transcribe_audio(audio_path, model_size="tiny", device="cpu", language=None):
     """Transcribe audio file using faster-whisper with timing information."""
     from faster_whisper import WhisperModel  # type: ignore
     
-    print("Loading Whisper model...", file=sys.stderr)
-    model = WhisperModel(model_size, device="cpu", compute_type="int8")
+    print(f"Loading Whisper model {model_size} on {device}...", file=sys.stderr)
+    compute_type = {"cpu": "int8", "cuda": "float16"}.get(device, "default")
+    model = WhisperModel(model_size, device=device, compute_type=compute_type)
     
     print("Starting transcription...", file=sys.stderr)
-    segments, info = model.transcribe(audio_path, beam_size=5)
+    segments, info = model.transcribe(str(audio_path), beam_size=5, language=language)
     
     transcription = {
         "title": "",
@@ -205,12 +228,14 @@ def transcribe_audio(audio_path, model_size="tiny"):
     return transcription
 
 
-def extract_with_fallback(video_url, temp_dir):
+def extract_with_fallback(video_url, temp_dir, args):
     """Extract subtitles first, fallback to audio transcription if needed."""
-    print("Attempting subtitle extraction...", file=sys.stderr)
-    
     try:
+        if args.audio_only:
+            raise Exception("skipped, the video has no captions")
+
         # Try subtitle extraction first
+        print("Attempting subtitle extraction...", file=sys.stderr)
         result = extract_subtitles(video_url, temp_dir)
         print("✓ Subtitle extraction successful", file=sys.stderr)
         return result
@@ -221,7 +246,7 @@ def extract_with_fallback(video_url, temp_dir):
         try:
             # Fallback to audio transcription
             audio_path = Path(temp_dir) / "audio.%(ext)s"
-            title = download_audio(video_url, audio_path)
+            info = download_audio(video_url, audio_path, args.max_duration)
             
             # Find the actual audio file (yt-dlp adds extension)
             audio_files = list(Path(temp_dir).glob("audio.*"))
@@ -232,8 +257,9 @@ def extract_with_fallback(video_url, temp_dir):
             print(f"Audio downloaded: {actual_audio_path}", file=sys.stderr)
             
             # Transcribe
-            transcription = transcribe_audio(actual_audio_path)
-            transcription["title"] = title
+            transcription = transcribe_audio(actual_audio_path, args.model, args.device, args.language)
+            transcription["title"] = info.get('title', 'Unknown Title')
+            transcription.update(video_metadata(info))
             
             print("✓ Audio transcription successful", file=sys.stderr)
             return transcription
@@ -243,11 +269,15 @@ def extract_with_fallback(video_url, temp_dir):
 
 
 def main():
-    if len(sys.argv) != 2:
-        print("Usage: transcribe.py <youtube_url>", file=sys.stderr)
-        sys.exit(1)
-    
-    video_url = sys.argv[1]
+    parser = argparse.ArgumentParser(description="Transcribe a YouTube video")
+    parser.add_argument("video_url")
+    parser.add_argument("--audio-only", action="store_true", help="skip subtitles, the video has none")
+    parser.add_argument("--model", default="tiny", help="Whisper model size")
+    parser.add_argument("--device", default="cpu", choices=["cpu", "cuda", "auto"])
+    parser.add_argument("--language", default=None, help="spoken language hint, e.g. en")
+    parser.add_argument("--max-duration", type=int, default=0, help="refuse longer videos, in seconds")
+    args = parser.parse_args()
+    video_url = args.video_url
     
     try:
         print("Installing dependencies...", file=sys.stderr)
@@ -255,7 +285,7 @@ def main():
         
         with tempfile.TemporaryDirectory() as temp_dir:
             print("Processing video with hybrid approach...", file=sys.stderr)
-            transcription = extract_with_fallback(video_url, temp_dir)
+            transcription = extract_with_fallback(video_url, temp_dir, args)
             
             # Output clean JSON (dependencies should already be installed)
             print(json.dumps(transcription, indent=2))
@@ -1,25 +1,81 @@
 package youtube
 
 import (
+	"cmp"
+	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/lang"
+	"github.com/scipunch/myfeed/markdown"
 	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/ratelimit"
 )
 
+// Version of the parser output, bump it when transcription changes to
+// invalidate cached outputs of earlier versions
+const Version = 2
+
+// Capabilities of the parser: videos linked by feed items
+var Capabilities = parser.Capabilities{
+	ResourceTypes: []string{"rss"},
+	Schemes:       []string{"http", "https"},
+	MaxDuration:   true,
+}
+
 //go:embed transcribe.py
 var transcribeScript string
 
+// Whisper configures transcription of videos without captions
+type Whisper struct {
+	Model       string        // Model size, "tiny" when empty
+	Device      string        // "cpu" when empty, "cuda" or "auto"
+	Language    string        // Spoken language hint, detected when empty
+	MaxDuration time.Duration // Longer videos are not transcribed, 0 for no limit
+}
+
+// args returns the transcribe.py arguments of the settings
+func (w Whisper) args() []string {
+	var args []string
+	if w.Model != "" {
+		args = append(args, "--model", w.Model)
+	}
+	if w.Device != "" {
+		args = append(args, "--device", w.Device)
+	}
+	if w.Language != "" {
+		args = append(args, "--language", w.Language)
+	}
+	if w.MaxDuration > 0 {
+		args = append(args, "--max-duration", strconv.Itoa(int(w.MaxDuration.Seconds())))
+	}
+	return args
+}
+
+// venvMu guards setting up the virtual environment shared by all parsers,
+// venvReady is set once a transcription ran in it
+var (
+	venvMu    sync.Mutex
+	venvReady bool
+)
+
 type Parser struct {
-	venvPath   string
-	pythonPath string
+	venvPath    string
+	pythonPath  string
+	whisper     Whisper
+	maxDuration time.Duration
 }
 
 type Segment struct {
@@ -28,36 +84,111 @@ type Segment struct {
 	Text  string  `json:"text"`
 }
 
+// Chapter is a titled part of the video starting at Start seconds
+type Chapter struct {
+	Start float64 `json:"start"`
+	Title string  `json:"title"`
+}
+
 type Transcription struct {
-	Title    string    `json:"title"`
-	Language string    `json:"language"`
-	Segments []Segment `json:"segments"`
+	Title       string    `json:"title"`
+	Language    string    `json:"language"`
+	Segments    []Segment `json:"segments"`
+	Channel     string    `json:"channel"`
+	Description string    `json:"description"`
+	Duration    float64   `json:"duration"` // Length of the video in seconds, 0 if unknown
+	Chapters    []Chapter `json:"chapters"`
 }
 
 type Response struct {
+	parser.Metadata
 	Transcription Transcription
+	Skipped       string // Why the video was not transcribed, e.g., it is too long
+}
+
+// Language returns the language of the transcription
+func (r Response) Language() string {
+	return lang.Normalize(r.Transcription.Language)
 }
 
 func (r Response) String() string {
 	var result strings.Builder
+	t := r.Transcription
 
-	result.WriteString(fmt.Sprintf("# %s\n\n", r.Transcription.Title))
-	result.WriteString(fmt.Sprintf("**Language:** %s\n\n", r.Transcription.Language))
+	result.WriteString(fmt.Sprintf("# %s\n\n", markdown.Escape(t.Title)))
+	if t.Channel != "" {
+		result.WriteString(fmt.Sprintf("**Channel:** %s\n\n", markdown.Escape(t.Channel)))
+	}
+	if t.Duration > 0 {
+		result.WriteString(fmt.Sprintf("**Duration:** %s\n\n", timestamp(t.Duration)))
+	}
+	if r.Skipped != "" {
+		result.WriteString(fmt.Sprintf("*%s*\n", r.Skipped))
+		return result.String()
+	}
+	result.WriteString(fmt.Sprintf("**Language:** %s\n\n", t.Language))
+	if t.Description != "" {
+		result.WriteString("## Description\n\n")
+		result.WriteString(markdown.Escape(t.Description))
+		result.WriteString("\n\n")
+	}
 	result.WriteString("## Transcription\n\n")
 
-	for _, segment := range r.Transcription.Segments {
+	// Chapter headings go before the first segment they cover
+	chapter := 0
+	for _, segment := range t.Segments {
+		for chapter < len(t.Chapters) && t.Chapters[chapter].Start <= segment.Start {
+			result.WriteString(fmt.Sprintf("### %s %s\n\n", timestamp(t.Chapters[chapter].Start), markdown.Escape(t.Chapters[chapter].Title)))
+			chapter++
+		}
 		minutes := int(segment.Start) / 60
 		seconds := int(segment.Start) % 60
-		result.WriteString(fmt.Sprintf("[%02d:%02d] %s\n\n", minutes, seconds, segment.Text))
+		result.WriteString(fmt.Sprintf("[%02d:%02d] %s\n\n", minutes, seconds, markdown.Escape(segment.Text)))
 	}
 
 	return result.String()
 }
 
-func New() (Parser, error) {
-	var p Parser
+// timestamp formats seconds like YouTube does, e.g., "4:05" or "1:02:03"
+func timestamp(seconds float64) string {
+	s := int(seconds)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// chapterRe matches a description line starting with a timestamp, e.g., "1:02:03 - Q&A"
+var chapterRe = regexp.MustCompile(`^\s*(?:(\d+):)?(\d{1,2}):(\d{2})\s*(?:[-–—:|]\s*)?(.+?)\s*$`)
+
+// parseChapters reads chapters from timestamps in the description. Like
+// YouTube, it needs at least three ascending ones starting at 0:00.
+func parseChapters(description string) []Chapter {
+	var chapters []Chapter
+	for _, line := range strings.Split(description, "\n") {
+		m := chapterRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		seconds, _ := strconv.Atoi(m[3])
+		start := float64(hours*3600 + minutes*60 + seconds)
+		if len(chapters) > 0 && start <= chapters[len(chapters)-1].Start {
+			return nil
+		}
+		chapters = append(chapters, Chapter{Start: start, Title: m[4]})
+	}
+	if len(chapters) < 3 || chapters[0].Start != 0 {
+		return nil
+	}
+	return chapters
+}
 
-	slog.Info("youtube parser: initializing")
+// New creates the parser. The Python environment for videos without
+// captions is set up on the first one.
+func New(whisper Whisper) (Parser, error) {
+	p := Parser{whisper: whisper}
 
 	// Set up virtual environment path in temp directory
 	tempDir := os.TempDir()
@@ -69,18 +200,16 @@ func New() (Parser, error) {
 	} else {
 		p.pythonPath = filepath.Join(p.venvPath, "bin", "python")
 	}
-
-	slog.Info("youtube parser: setting up virtual environment", "path", p.venvPath)
-
-	// Create virtual environment if it doesn't exist
-	if err := p.ensureVirtualEnv(); err != nil {
-		return p, fmt.Errorf("failed to set up virtual environment: %w", err)
-	}
-
-	slog.Info("youtube parser: initialization complete")
 	return p, nil
 }
 
+// WithMaxDuration returns a parser which transcribes only videos up to d long,
+// longer ones are parsed from their captions or into a stub
+func (p Parser) WithMaxDuration(d time.Duration) parser.Parser {
+	p.maxDuration = d
+	return p
+}
+
 func (p Parser) ensureVirtualEnv() error {
 	// Check if virtual environment exists
 	if _, err := os.Stat(p.pythonPath); err == nil {
@@ -105,41 +234,133 @@ func (p Parser) ensureVirtualEnv() error {
 	return nil
 }
 
-func (p Parser) Parse(item types.FeedItem) (parser.Response, error) {
+func (p Parser) Parse(ctx context.Context, item types.FeedItem) (parser.Response, error) {
 	var resp Response
 
-	slog.Info("youtube parser: starting transcription", "url", item.Link)
+	release, err := ratelimit.Acquire(ctx, ratelimit.YouTube)
+	if err != nil {
+		return resp, err
+	}
+	defer release()
+
+	// Existing captions need neither Python nor the audio
+	transcription, length, err := captions(ctx, item.Link)
+	if err == nil {
+		resp.Transcription = withChapters(transcription)
+		resp.Meta = meta(item, resp.Transcription)
+		slog.Info("youtube parser: captions downloaded", "url", item.Link, "language", transcription.Language, "segments", len(transcription.Segments))
+		return resp, nil
+	}
+	audioOnly := errors.Is(err, errNoCaptions)
+	if audioOnly && p.maxDuration > 0 && length > p.maxDuration {
+		slog.Info("youtube parser: video without captions is too long, skipping transcription", "url", item.Link, "length", length, "max", p.maxDuration)
+		resp.Transcription = transcription
+		resp.Transcription.Title = cmp.Or(transcription.Title, item.Title)
+		resp.Skipped = fmt.Sprintf("Not transcribed: the video is %d minutes long, longer than the limit of %d minutes.", int(length.Minutes()), int(p.maxDuration.Minutes()))
+		resp.Meta = meta(item, resp.Transcription)
+		return resp, nil
+	}
+	if audioOnly {
+		slog.Info("youtube parser: video has no captions, transcribing audio", "url", item.Link)
+	} else {
+		slog.Warn("youtube parser: failed to download captions, falling back to yt-dlp", "url", item.Link, "error", err)
+	}
+
+	resp.Transcription, err = p.transcribe(ctx, item.Link, audioOnly)
+	if err != nil {
+		return resp, err
+	}
+	resp.Transcription = withChapters(resp.Transcription)
+	resp.Meta = meta(item, resp.Transcription)
+	slog.Info("youtube parser: transcription completed", "title", resp.Transcription.Title, "segments", len(resp.Transcription.Segments))
+	return resp, nil
+}
+
+// meta describes the video, the feed item tells when it was published
+func meta(item types.FeedItem, t Transcription) parser.Meta {
+	words := 0
+	for _, segment := range t.Segments {
+		words += len(strings.Fields(segment.Text))
+	}
+	return parser.Meta{
+		Title:       cmp.Or(t.Title, item.Title),
+		Author:      t.Channel,
+		PublishedAt: item.Published,
+		WordCount:   words,
+	}
+}
+
+// withChapters fills chapters from the description when the source had none
+func withChapters(t Transcription) Transcription {
+	if len(t.Chapters) == 0 {
+		t.Chapters = parseChapters(t.Description)
+	}
+	return t
+}
 
-	// Create temporary script file
-	scriptPath := filepath.Join(p.venvPath, "transcribe.py")
-	if err := os.WriteFile(scriptPath, []byte(transcribeScript), 0755); err != nil {
-		return resp, fmt.Errorf("failed to write transcribe script: %w", err)
+// transcribe runs the Python pipeline: subtitles via yt-dlp, then Whisper on
+// the audio. audioOnly skips straight to Whisper.
+func (p Parser) transcribe(ctx context.Context, link string, audioOnly bool) (Transcription, error) {
+	var transcription Transcription
+
+	// Parallel workers share the environment, the first one sets it up and
+	// installs the packages alone
+	venvMu.Lock()
+	setup := !venvReady
+	if setup {
+		defer venvMu.Unlock()
+		slog.Info("youtube parser: setting up virtual environment", "path", p.venvPath)
+		if err := p.ensureVirtualEnv(); err != nil {
+			return transcription, fmt.Errorf("failed to set up virtual environment: %w", err)
+		}
+	} else {
+		venvMu.Unlock()
+	}
+
+	// Every call runs its own copy of the script
+	script, err := os.CreateTemp(p.venvPath, "transcribe-*.py")
+	if err != nil {
+		return transcription, fmt.Errorf("failed to create transcribe script: %w", err)
+	}
+	defer os.Remove(script.Name())
+	_, err = script.WriteString(transcribeScript)
+	if closeErr := script.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return transcription, fmt.Errorf("failed to write transcribe script: %w", err)
 	}
-	defer os.Remove(scriptPath)
 
 	slog.Info("youtube parser: executing transcription script")
 
 	// Execute transcription script
-	cmd := exec.Command(p.pythonPath, scriptPath, item.Link)
+	// The shorter limit applies when the length is only known to yt-dlp
+	whisper := p.whisper
+	if p.maxDuration > 0 && (whisper.MaxDuration == 0 || p.maxDuration < whisper.MaxDuration) {
+		whisper.MaxDuration = p.maxDuration
+	}
+	args := append([]string{script.Name(), link}, whisper.args()...)
+	if audioOnly {
+		args = append(args, "--audio-only")
+	}
+	cmd := exec.CommandContext(ctx, p.pythonPath, args...)
 	output, err := cmd.Output()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			slog.Error("youtube parser: transcription failed", "error", string(exitErr.Stderr))
-			return resp, fmt.Errorf("transcription failed: %s", string(exitErr.Stderr))
+			return transcription, fmt.Errorf("transcription failed: %s", string(exitErr.Stderr))
 		}
-		return resp, fmt.Errorf("failed to execute transcription: %w", err)
+		return transcription, fmt.Errorf("failed to execute transcription: %w", err)
 	}
 
-	slog.Info("youtube parser: parsing transcription output")
-
 	// Parse JSON output
-	if err := json.Unmarshal(output, &resp.Transcription); err != nil {
-		return resp, fmt.Errorf("failed to parse transcription output: %w", err)
+	if err := json.Unmarshal(output, &transcription); err != nil {
+		return transcription, fmt.Errorf("failed to parse transcription output: %w", err)
 	}
-
-	slog.Info("youtube parser: transcription completed", "title", resp.Transcription.Title, "segments", len(resp.Transcription.Segments))
-
-	return resp, nil
+	if setup {
+		venvReady = true
+	}
+	return transcription, nil
 }
 
 func isWindows() bool {
@@ -1,6 +1,7 @@
 package youtube
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/fs"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/scipunch/myfeed/fetcher/types"
 )
@@ -51,7 +53,7 @@ func TestYouTubeParser(t *testing.T) {
 		t.Skip("No test data files found in _test_data directory")
 	}
 
-	parser, err := New()
+	parser, err := New(Whisper{})
 	if err != nil {
 		t.Fatalf("Failed to create YouTube parser: %v", err)
 	}
@@ -72,7 +74,7 @@ func TestYouTubeParser(t *testing.T) {
 				Link:        tc.VideoURL,
 				Description: tc.Description,
 			}
-			response, err := parser.Parse(item)
+			response, err := parser.Parse(context.Background(), item)
 			if err != nil {
 				// Check if we should skip this test
 				if strings.Contains(err.Error(), "ERROR: [youtube]") ||
@@ -266,3 +268,62 @@ func validateExactMatch(t *testing.T, response any, testData TestData) {
 		t.Logf("Text similarity check passed: %.3f >= %.3f", similarity, similarityThreshold)
 	}
 }
+
+func TestWhisperArgs(t *testing.T) {
+	if args := (Whisper{}).args(); len(args) != 0 {
+		t.Errorf("expected script defaults without settings, got %v", args)
+	}
+
+	w := Whisper{Model: "small", Device: "cuda", Language: "de", MaxDuration: 90 * time.Minute}
+	want := "--model small --device cuda --language de --max-duration 5400"
+	if got := strings.Join(w.args(), " "); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParseChapters(t *testing.T) {
+	description := `Talk about parsers.
+
+0:00 Intro
+2:30 - Tokens
+1:02:03 Q&A
+Links: https://example.com`
+	want := []Chapter{{0, "Intro"}, {150, "Tokens"}, {3723, "Q&A"}}
+	got := parseChapters(description)
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chapter %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	for _, description := range []string{
+		"0:00 Intro\n5:00 End",              // Too few
+		"0:10 Intro\n1:00 Middle\n2:00 End", // Not starting at 0:00
+		"0:00 Intro\n2:00 Middle\n1:00 End", // Not ascending
+	} {
+		if got := parseChapters(description); got != nil {
+			t.Errorf("expected no chapters in %q, got %v", description, got)
+		}
+	}
+}
+
+func TestResponseString_Chapters(t *testing.T) {
+	resp := Response{Transcription: Transcription{
+		Title:    "Talk",
+		Language: "en",
+		Channel:  "Conf",
+		Duration: 200,
+		Segments: []Segment{{Start: 0, Text: "Hello"}, {Start: 70, Text: "First"}, {Start: 130, Text: "Second"}},
+		Chapters: []Chapter{{0, "Intro"}, {60, "Part one"}, {120, "Part two"}},
+	}}
+	want := "# Talk\n\n**Channel:** Conf\n\n**Duration:** 3:20\n\n**Language:** en\n\n## Transcription\n\n" +
+		"### 0:00 Intro\n\n[00:00] Hello\n\n" +
+		"### 1:00 Part one\n\n[01:10] First\n\n" +
+		"### 2:00 Part two\n\n[02:10] Second\n\n"
+	if got := resp.String(); got != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, want)
+	}
+}
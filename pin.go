@@ -0,0 +1,143 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scipunch/myfeed/daemon"
+	"github.com/scipunch/myfeed/db"
+)
+
+// defaultPinSection names the section of pins added without one
+const defaultPinSection = "Pinned"
+
+// addPin pins a note or link into the next issue
+func addPin(ctx context.Context, queries *db.Queries, text, section string) (int64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, errors.New("nothing to pin")
+	}
+	return queries.CreatePin(ctx, db.CreatePinParams{
+		Text:      text,
+		Section:   cmp.Or(strings.TrimSpace(section), defaultPinSection),
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// removePin unpins a note or link which is not in an issue yet
+func removePin(ctx context.Context, queries *db.Queries, id int64) error {
+	n, err := queries.DeletePendingPin(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("pin %d is not waiting for an issue", id)
+	}
+	return nil
+}
+
+// printPins prints the pins waiting for the next issue
+func printPins(ctx context.Context, queries *db.Queries) error {
+	pins, err := queries.ListPendingPins(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPINNED AT\tSECTION\tTEXT")
+	for _, p := range pins {
+		text, _, _ := strings.Cut(p.Text, "\n")
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", p.ID, time.Unix(p.CreatedAt, 0).Format(time.DateTime), p.Section, text)
+	}
+	return w.Flush()
+}
+
+// pinPage shows a pin as an item: a link with its address, a note with its
+// first line as the title and the rest as Markdown content
+func pinPage(pin db.Pin) Page {
+	page := Page{
+		ID:        fmt.Sprintf("pin-%d", pin.ID),
+		Published: time.Unix(pin.CreatedAt, 0),
+	}
+	if u, err := url.Parse(pin.Text); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		!strings.ContainsAny(pin.Text, " \n") {
+		page.Title = pin.Text
+		page.Link = pin.Text
+		return page
+	}
+	title, rest, _ := strings.Cut(pin.Text, "\n")
+	page.Title = strings.TrimSpace(title)
+	page.Content = renderMarkdown(strings.TrimSpace(rest))
+	return page
+}
+
+// withPins puts the pins before the items of the issue, in a section for
+// every pin section in the order they were first used
+func withPins(n Newsletter, pins []db.Pin) Newsletter {
+	if len(pins) == 0 {
+		return n
+	}
+	var sections []Resource
+	index := make(map[string]int)
+	for _, pin := range pins {
+		i, ok := index[pin.Section]
+		if !ok {
+			i = len(sections)
+			index[pin.Section] = i
+			sections = append(sections, Resource{Name: pin.Section, Pinned: true})
+		}
+		sections[i].Pages = append(sections[i].Pages, pinPage(pin))
+	}
+	n.Resources = append(sections, n.Resources...)
+	return n
+}
+
+// markPinsIssued records that the pins went into an issue, so the next one
+// doesn't show them again
+func markPinsIssued(ctx context.Context, queries *db.Queries, pins []db.Pin) {
+	now := time.Now().Unix()
+	for _, pin := range pins {
+		if err := queries.MarkPinIssued(ctx, db.MarkPinIssuedParams{IssuedAt: now, ID: pin.ID}); err != nil {
+			slog.Warn("failed to mark pin as issued", "id", pin.ID, "error", err)
+		}
+	}
+}
+
+// pinHooks connect the dashboard of the daemon to the pins
+func pinHooks(queries *db.Queries) daemon.PinHooks {
+	return daemon.PinHooks{
+		Pending: func(ctx context.Context) ([]daemon.Pin, error) {
+			pending, err := queries.ListPendingPins(ctx)
+			if err != nil {
+				return nil, err
+			}
+			pins := make([]daemon.Pin, len(pending))
+			for i, p := range pending {
+				pins[i] = daemon.Pin{
+					ID:        p.ID,
+					Text:      p.Text,
+					Section:   p.Section,
+					CreatedAt: time.Unix(p.CreatedAt, 0),
+				}
+			}
+			return pins, nil
+		},
+		Add: func(ctx context.Context, text, section string) (int64, error) {
+			return addPin(ctx, queries, text, section)
+		},
+		Remove: func(ctx context.Context, id int64) error {
+			n, err := queries.DeletePendingPin(ctx, id)
+			if err == nil && n == 0 {
+				return daemon.ErrPinIssued
+			}
+			return err
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+)
+
+func TestPins(t *testing.T) {
+	ctx := context.Background()
+	database, err := initDB(ctx, filepath.Join(t.TempDir(), "myfeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	queries := db.New(database)
+
+	for _, pin := range [][2]string{
+		{"Remember to renew domain", "Personal"},
+		{"https://example.com/talk", ""},
+		{"Call the bank\nAbout the **card**", "Personal"},
+	} {
+		if _, err := addPin(ctx, queries, pin[0], pin[1]); err != nil {
+			t.Fatalf("failed to pin %q: %v", pin[0], err)
+		}
+	}
+	if _, err := addPin(ctx, queries, "  ", ""); err == nil {
+		t.Error("expected an empty pin to be rejected")
+	}
+
+	pins, err := queries.ListPendingPins(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := withPins(Newsletter{Resources: []Resource{{Name: "Blog", Pages: []Page{{Title: "Post"}}}}}, pins)
+	var names []string
+	for _, res := range issue.Resources {
+		names = append(names, res.Name)
+	}
+	if got := strings.Join(names, ","); got != "Personal,Pinned,Blog" {
+		t.Fatalf("expected pin sections before the items, got %s", got)
+	}
+	personal := issue.Resources[0].Pages
+	if len(personal) != 2 || personal[0].Title != "Remember to renew domain" || personal[0].Link != "" {
+		t.Errorf("expected notes without a link, got %+v", personal)
+	}
+	if personal[1].Title != "Call the bank" || personal[1].Content != "<p>About the <strong>card</strong></p>" {
+		t.Errorf("expected the first line as title and the rest as content, got %+v", personal[1])
+	}
+	if link := issue.Resources[1].Pages[0]; link.Link != "https://example.com/talk" {
+		t.Errorf("expected a pinned link to link to the page, got %+v", link)
+	}
+
+	// Issued pins are not shown again and can't be unpinned
+	markPinsIssued(ctx, queries, pins[:1])
+	if err := removePin(ctx, queries, pins[0].ID); err == nil {
+		t.Error("expected an issued pin not to be unpinned")
+	}
+	if err := removePin(ctx, queries, pins[1].ID); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if pending, _ := pinHooks(queries).Pending(ctx); len(pending) != 1 || pending[0].ID != pins[2].ID {
+		t.Errorf("expected only the last pin to wait for an issue, got %+v", pending)
+	}
+}
+
+func TestGroupPages_KeepsPinsFirst(t *testing.T) {
+	morning := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	n := Newsletter{Resources: []Resource{
+		{Name: "Blog", Pages: []Page{{Title: "Post", Published: morning}}},
+		{Name: "Pinned", Pinned: true, Pages: []Page{{Title: "Note", Published: morning.Add(time.Hour)}}},
+	}}
+	n = withPins(n, nil)
+	grouped := groupPages(n, config.GroupByTimeOfDay, time.UTC)
+	if len(grouped.Resources) != 2 || grouped.Resources[0].Name != "Pinned" || grouped.Resources[1].Name != "Morning" {
+		t.Errorf("expected the pinned section first and untouched, got %+v", grouped.Resources)
+	}
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/cache"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/filter"
+	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/parser/web"
+)
+
+// fakeFetcher serves feeds by URL, failing for URLs in errs
+type fakeFetcher struct {
+	feeds map[string]fetcher.Feed
+	errs  map[string]error
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, url string, _ fetcher.FetchOptions) (fetcher.Feed, error) {
+	if err := f.errs[url]; err != nil {
+		return fetcher.Feed{}, err
+	}
+	return f.feeds[url], nil
+}
+
+// fakeParser renders the item description as a web page, failing or
+// panicking for links in errs and panics
+type fakeParser struct {
+	mu     sync.Mutex
+	calls  []string
+	errs   map[string]error
+	panics map[string]bool
+	meta   map[string]parser.Meta
+}
+
+func (p *fakeParser) Parse(_ context.Context, item fetcher.FeedItem) (parser.Response, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, item.Link)
+	p.mu.Unlock()
+	if p.panics[item.Link] {
+		panic("broken page")
+	}
+	if err := p.errs[item.Link]; err != nil {
+		return nil, err
+	}
+	resp := web.Response{HTML: "<p>" + item.Description + "</p>"}
+	resp.Meta = p.meta[item.Link]
+	return resp, nil
+}
+
+// fakeAgent prefixes content with its name, failing for content containing fail
+type fakeAgent struct {
+	mu    sync.Mutex
+	calls int
+	fail  string
+}
+
+func (a *fakeAgent) Name() string { return "summary" }
+
+func (a *fakeAgent) Process(_ context.Context, content string, _ agent.Options) (string, error) {
+	a.mu.Lock()
+	a.calls++
+	a.mu.Unlock()
+	if a.fail != "" && strings.Contains(content, a.fail) {
+		return "", errors.New("model unavailable")
+	}
+	return "<p>Summary:</p>" + content, nil
+}
+
+// simulation runs the whole pipeline, from fetching into the queue to the
+// rendered HTML, on a temporary database with fake providers
+type simulation struct {
+	t       *testing.T
+	ctx     context.Context
+	conf    config.Config
+	queries *db.Queries
+	cache   *cache.Cache
+	fetcher *fakeFetcher
+	parser  *fakeParser
+	agent   *fakeAgent
+	agents  map[string]agent.Agent
+	filters *filter.FilterPipeline
+}
+
+func newSimulation(t *testing.T, conf config.Config) *simulation {
+	t.Helper()
+	ctx := context.Background()
+	database, err := initDB(ctx, filepath.Join(t.TempDir(), "myfeed.db"))
+	if err != nil {
+		t.Fatalf("failed to init database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	cacheDB, err := cache.NewCacheFromDB(database)
+	if err != nil {
+		t.Fatalf("failed to init cache: %v", err)
+	}
+	filters, err := filter.NewFilterPipeline(conf.Filters)
+	if err != nil {
+		t.Fatalf("failed to init filters: %v", err)
+	}
+	conf.OutputDirectory = t.TempDir()
+	summary := &fakeAgent{}
+	return &simulation{
+		t:       t,
+		ctx:     ctx,
+		conf:    conf,
+		queries: db.New(database),
+		cache:   cacheDB,
+		fetcher: &fakeFetcher{feeds: make(map[string]fetcher.Feed), errs: make(map[string]error)},
+		parser:  &fakeParser{errs: make(map[string]error), panics: make(map[string]bool)},
+		agent:   summary,
+		agents:  map[string]agent.Agent{"summary": summary},
+		filters: filters,
+	}
+}
+
+// run fetches, processes, renders and saves an issue like `myfeed` does
+func (s *simulation) run(includeAll bool) (issueRun, string) {
+	s.t.Helper()
+	fetchers := map[config.ResourceType]fetcher.FeedFetcher{config.RSS: s.fetcher}
+	if err := fetchFeeds(s.ctx, s.conf, s.queries, fetchers, includeAll, false); err != nil {
+		s.t.Fatalf("fetch failed: %v", err)
+	}
+
+	queueCutoff := time.Now().Unix()
+	feeds, err := queuedFeeds(s.ctx, s.queries, s.conf.Resources)
+	if err != nil {
+		s.t.Fatalf("failed to load queue: %v", err)
+	}
+	proc := processor{
+		conf:       s.conf,
+		queries:    s.queries,
+		cache:      s.cache,
+		parsers:    map[parser.Type]parser.Parser{parser.Web: s.parser},
+		agents:     s.agents,
+		filters:    s.filters,
+		includeAll: includeAll,
+	}
+	run, err := proc.process(s.ctx, feeds, time.Now())
+	if err != nil {
+		s.t.Fatalf("process failed: %v", err)
+	}
+
+	t := template.Must(loadTemplates(""))
+	htmlPath := filepath.Join(s.conf.OutputDirectory, "issue.html")
+	if err := renderHTML(t, htmlPath, groupPages(run.newsletter, s.conf.GroupBy, time.UTC)); err != nil {
+		s.t.Fatalf("render failed: %v", err)
+	}
+	html, err := os.ReadFile(htmlPath)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	saveRun(s.ctx, s.conf, s.queries, feeds, run, includeAll, queueCutoff)
+	return run, string(html)
+}
+
+func resource(url string, agents ...string) config.ResourceConfig {
+	return config.ResourceConfig{FeedURL: url, T: config.RSS, ParserT: parser.Web, Agents: agents}
+}
+
+func feedOf(title string, links ...string) fetcher.Feed {
+	feed := fetcher.Feed{Title: title}
+	published := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	for i, link := range links {
+		feed.Items = append(feed.Items, fetcher.FeedItem{
+			Title:       "Title of " + link,
+			Link:        link,
+			Description: fmt.Sprintf("Text of %s, long enough to pass filters about length.", link),
+			Published:   published.Add(time.Duration(i) * time.Hour),
+			GUID:        link,
+		})
+	}
+	return feed
+}
+
+// pageLinks lists page links by resource name
+func pageLinks(n Newsletter) map[string][]string {
+	links := make(map[string][]string)
+	for _, res := range n.Resources {
+		for _, page := range res.Pages {
+			links[res.Name] = append(links[res.Name], page.Link)
+		}
+	}
+	return links
+}
+
+func TestPipeline_Ordering(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{
+		resource("https://b.example/feed"),
+		resource("https://a.example/feed"),
+	}})
+	s.fetcher.feeds["https://b.example/feed"] = feedOf("Blog B", "https://b.example/1", "https://b.example/2")
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1")
+
+	run, html := s.run(false)
+	if len(run.errs) > 0 {
+		t.Fatalf("unexpected errors: %v", run.errs)
+	}
+
+	// Resources follow the config, pages follow the feed
+	var names []string
+	for _, res := range run.newsletter.Resources {
+		names = append(names, res.Name)
+	}
+	if strings.Join(names, ",") != "Blog B,Blog A" {
+		t.Errorf("expected resources in config order, got %v", names)
+	}
+	if got := pageLinks(run.newsletter)["Blog B"]; strings.Join(got, ",") != "https://b.example/1,https://b.example/2" {
+		t.Errorf("expected pages in feed order, got %v", got)
+	}
+
+	// The rendered issue shows them in the same order, with links of the
+	// text rendered from Markdown
+	first := strings.Index(html, `Text of <a href="https://b.example/2">`)
+	second := strings.Index(html, `Text of <a href="https://a.example/1">`)
+	if first < 0 || second < 0 || first > second {
+		t.Errorf("expected rendered pages in order, found at %d and %d", first, second)
+	}
+	if run.stats.Items != 3 || run.stats.Sources != 2 {
+		t.Errorf("expected 3 items of 2 sources, got %d of %d", run.stats.Items, run.stats.Sources)
+	}
+}
+
+func TestPipeline_NewItemsOnly(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{resource("https://a.example/feed")}})
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1", "https://a.example/2")
+	if run, _ := s.run(false); run.stats.Items != 2 {
+		t.Fatalf("expected 2 items in the first issue, got %d", run.stats.Items)
+	}
+
+	// The processed items left the queue and are not repeated
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1", "https://a.example/2", "https://a.example/3")
+	run, _ := s.run(false)
+	if got := pageLinks(run.newsletter)["Blog A"]; strings.Join(got, ",") != "https://a.example/3" {
+		t.Errorf("expected only the new item, got %v", got)
+	}
+	queued, err := s.queries.ListQueuedItems(s.ctx, "https://a.example/feed")
+	if err != nil || len(queued) != 0 {
+		t.Errorf("expected an empty queue, got %d items (%v)", len(queued), err)
+	}
+}
+
+func TestPipeline_Caching(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{
+		resource("https://a.example/feed", "summary"),
+		resource("https://b.example/feed"),
+	}})
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1")
+	s.fetcher.feeds["https://b.example/feed"] = feedOf("Blog B", "https://b.example/1")
+
+	first, _ := s.run(true)
+	if len(s.parser.calls) != 2 || s.agent.calls != 1 {
+		t.Fatalf("expected 2 parses and 1 agent call, got %d and %d", len(s.parser.calls), s.agent.calls)
+	}
+	if first.stats.CacheHits != 0 {
+		t.Errorf("expected no cache hits in the first run, got %d", first.stats.CacheHits)
+	}
+
+	// Agent output is served from the cache without parsing, parser output
+	// of resources without agents is served from the parser cache
+	second, _ := s.run(true)
+	if len(s.parser.calls) != 2 || s.agent.calls != 1 {
+		t.Errorf("expected no new parses or agent calls, got %d and %d", len(s.parser.calls), s.agent.calls)
+	}
+	if second.stats.CacheHits != 2 || second.stats.CacheLookups != 2 {
+		t.Errorf("expected 2 of 2 cache hits, got %d of %d", second.stats.CacheHits, second.stats.CacheLookups)
+	}
+	for _, res := range second.newsletter.Resources {
+		for _, page := range res.Pages {
+			if res.Name == "Blog A" && !strings.HasPrefix(string(page.Content), "<p>Summary:</p>") {
+				t.Errorf("expected cached summary, got %q", page.Content)
+			}
+		}
+	}
+}
+
+func TestPipeline_StageCaching(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{resource("https://a.example/feed", "summary")}})
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1")
+	s.run(true)
+
+	// Appending an agent runs only the new stage on the cached summary
+	translate := &fakeAgent{}
+	s.agents["translate"] = translate
+	s.conf.Resources[0].Agents = []string{"summary", "translate"}
+	run, _ := s.run(true)
+	if len(s.parser.calls) != 1 || s.agent.calls != 1 || translate.calls != 1 {
+		t.Fatalf("expected 1 parse, 1 summary and 1 translation, got %d, %d and %d", len(s.parser.calls), s.agent.calls, translate.calls)
+	}
+	if run.stats.CacheHits != 1 {
+		t.Errorf("expected the summary stage from the cache, got %d cache hits", run.stats.CacheHits)
+	}
+	page := run.newsletter.Resources[0].Pages[0]
+	if !strings.HasPrefix(string(page.Content), "<p>Summary:</p><p>Summary:</p>") {
+		t.Errorf("expected both stages applied, got %q", page.Content)
+	}
+
+	// Changing the last agent back reuses the first stage as well
+	s.conf.Resources[0].Agents = []string{"summary"}
+	s.run(true)
+	if s.agent.calls != 1 || translate.calls != 1 {
+		t.Errorf("expected no new agent calls, got %d and %d", s.agent.calls, translate.calls)
+	}
+}
+
+func TestPipeline_Filters(t *testing.T) {
+	s := newSimulation(t, config.Config{
+		Filters: map[string]config.Filter{
+			"no_ads": {ExcludePatterns: []string{"sponsored"}},
+		},
+		Resources: []config.ResourceConfig{resource("https://a.example/feed")},
+	})
+	s.conf.Resources[0].FilterNames = []string{"no_ads"}
+	feed := feedOf("Blog A", "https://a.example/1", "https://a.example/sponsored")
+	s.fetcher.feeds["https://a.example/feed"] = feed
+
+	run, _ := s.run(false)
+	if got := pageLinks(run.newsletter)["Blog A"]; strings.Join(got, ",") != "https://a.example/1" {
+		t.Errorf("expected the sponsored item filtered out, got %v", got)
+	}
+	if run.stats.Filtered != 1 {
+		t.Errorf("expected 1 filtered item, got %d", run.stats.Filtered)
+	}
+	// Filtered items are never parsed
+	for _, link := range s.parser.calls {
+		if link == "https://a.example/sponsored" {
+			t.Error("expected the filtered item not to be parsed")
+		}
+	}
+}
+
+func TestPipeline_Routes(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{resource("https://a.example/feed", "summary")}})
+	s.conf.Resources[0].Routes = []config.Route{
+		{When: `link contains "/ads/"`, Drop: true},
+		{When: `link contains "/notes/"`, Agents: []string{}, Category: "Notes"},
+	}
+	feed := feedOf("Blog A", "https://a.example/1", "https://a.example/ads/1", "https://a.example/notes/1")
+	s.fetcher.feeds["https://a.example/feed"] = feed
+
+	run, _ := s.run(false)
+	if run.stats.Filtered != 1 {
+		t.Errorf("expected 1 dropped item, got %d", run.stats.Filtered)
+	}
+	if len(run.newsletter.Resources) != 2 {
+		t.Fatalf("expected routed items in a section of their own, got %+v", run.newsletter.Resources)
+	}
+	notes := run.newsletter.Resources[1]
+	if notes.Category != "Notes" || notes.Pages[0].Link != "https://a.example/notes/1" {
+		t.Errorf("expected the note in its category, got %+v", notes)
+	}
+	if strings.Contains(string(notes.Pages[0].Content), "Summary:") {
+		t.Error("expected the note without agents")
+	}
+	if s.agent.calls != 1 {
+		t.Errorf("expected only the other item summarized, got %d calls", s.agent.calls)
+	}
+}
+
+// fakeTagger answers with a JSON object of the content and a tag
+type fakeTagger struct{}
+
+func (fakeTagger) Name() string { return "tagger" }
+
+func (fakeTagger) Process(_ context.Context, content string, _ agent.Options) (string, error) {
+	return `{"summary":"Tagged ` + strings.ReplaceAll(content, `"`, `'`) + `","tags":["go"]}`, nil
+}
+
+func TestPipeline_StructuredAgent(t *testing.T) {
+	s := newSimulation(t, config.Config{
+		Resources: []config.ResourceConfig{resource("https://a.example/feed", "summary", "tagger")},
+		Agents: map[string]config.CustomAgent{
+			"tagger": {Schema: map[string]string{"summary": "string", "tags": "list"}, Text: "summary"},
+		},
+	})
+	s.agents["tagger"] = fakeTagger{}
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1")
+
+	// Fields of the answer are decoded from the cache as well
+	for range 2 {
+		run, _ := s.run(true)
+		page := run.newsletter.Resources[0].Pages[0]
+		if !strings.HasPrefix(string(page.Content), "<p>Tagged") || strings.Contains(string(page.Content), "tags") {
+			t.Errorf("expected the text field as the content, got %q", page.Content)
+		}
+		if tags, _ := page.Fields["tags"].([]any); len(tags) != 1 || tags[0] != "go" {
+			t.Errorf("expected the tags among the fields, got %v", page.Fields)
+		}
+	}
+	if s.agent.calls != 1 {
+		t.Errorf("expected the second run from the cache, got %d summaries", s.agent.calls)
+	}
+}
+
+// fakeDescriber appends the number of photos it sees to the content
+type fakeDescriber struct{}
+
+func (fakeDescriber) Name() string { return agent.Describe }
+
+func (fakeDescriber) Process(_ context.Context, content string, opts agent.Options) (string, error) {
+	return fmt.Sprintf("%s\n\nPhotos: %d", content, len(opts.Images)), nil
+}
+
+func TestPipeline_DescribePhotos(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{resource("https://a.example/feed", agent.Describe)}})
+	s.agents[agent.Describe] = fakeDescriber{}
+	feed := feedOf("Channel", "https://a.example/1")
+	feed.Items[0].Media = []types.MediaAttachment{
+		{Type: "photo", LocalPath: filepath.Join(t.TempDir(), "1.jpg")},
+		{Type: "video", LocalPath: filepath.Join(t.TempDir(), "2.jpg")},
+		{Type: "photo", Caption: "Failed to download"},
+	}
+	s.fetcher.feeds["https://a.example/feed"] = feed
+
+	run, _ := s.run(true)
+	if page := run.newsletter.Resources[0].Pages[0]; !strings.Contains(string(page.Content), "Photos: 1") {
+		t.Errorf("expected the downloaded photo passed to the agent, got %q", page.Content)
+	}
+}
+
+// fakeScorer scores content by the first of its scores contained in it
+type fakeScorer struct {
+	calls  int
+	scores map[string]string
+}
+
+func (a *fakeScorer) Name() string { return agent.Relevance }
+
+func (a *fakeScorer) Process(_ context.Context, content string, _ agent.Options) (string, error) {
+	a.calls++
+	for key, score := range a.scores {
+		if strings.Contains(content, key) {
+			return score, nil
+		}
+	}
+	return "nonsense", nil
+}
+
+func TestPipeline_MinScore(t *testing.T) {
+	s := newSimulation(t, config.Config{
+		Interests: []string{"Go"},
+		Resources: []config.ResourceConfig{resource("https://a.example/feed", "summary")},
+	})
+	s.conf.Resources[0].MinScore = 6
+	scorer := &fakeScorer{scores: map[string]string{"/go": "9", "/cats": "2"}}
+	s.agents[agent.Relevance] = scorer
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/go", "https://a.example/cats", "https://a.example/unsure")
+
+	run, _ := s.run(false)
+	// Unparsable scores keep the item
+	if got := pageLinks(run.newsletter)["Blog A"]; strings.Join(got, ",") != "https://a.example/go,https://a.example/unsure" {
+		t.Errorf("expected the low scoring item dropped, got %v", got)
+	}
+	if run.stats.Filtered != 1 || s.agent.calls != 2 {
+		t.Errorf("expected 1 filtered item and no summary of it, got %d filtered and %d summaries", run.stats.Filtered, s.agent.calls)
+	}
+
+	// Scores are cached until the interests change, kept items come back as
+	// back-references which are not scored
+	s.run(true)
+	if scorer.calls != 3 {
+		t.Errorf("expected cached scores to be reused, got %d calls", scorer.calls)
+	}
+	s.conf.Interests = []string{"Cats"}
+	s.run(true)
+	if scorer.calls != 4 {
+		t.Errorf("expected new interests to score items again, got %d calls", scorer.calls)
+	}
+}
+
+func TestPipeline_Errors(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{
+		resource("https://down.example/feed"),
+		resource("https://a.example/feed", "summary"),
+	}})
+	s.fetcher.errs["https://down.example/feed"] = errors.New("connection refused")
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A",
+		"https://a.example/ok", "https://a.example/broken", "https://a.example/panics", "https://a.example/agent-fails")
+	s.parser.errs["https://a.example/broken"] = errors.New("timeout")
+	s.parser.panics["https://a.example/panics"] = true
+	s.agent.fail = "agent-fails"
+
+	run, _ := s.run(false)
+
+	// A failing feed or item does not stop the others
+	if got := pageLinks(run.newsletter)["Blog A"]; strings.Join(got, ",") != "https://a.example/ok,https://a.example/agent-fails" {
+		t.Errorf("expected the working items, got %v", got)
+	}
+	if len(run.errs) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(run.errs), run.errs)
+	}
+	for i, want := range []string{"https://a.example/broken", "https://a.example/panics", "agent 'summary' processing failed"} {
+		if !strings.Contains(run.errs[i].Error(), want) {
+			t.Errorf("expected error %d to mention %q, got %v", i, want, run.errs[i])
+		}
+	}
+
+	// The agent failure falls back to the parsed content
+	for _, page := range run.newsletter.Resources[0].Pages {
+		if page.Link == "https://a.example/agent-fails" && strings.Contains(string(page.Content), "Summary:") {
+			t.Errorf("expected parsed content after agent failure, got %q", page.Content)
+		}
+	}
+}
+
+func TestPipeline_CircuitBreaker(t *testing.T) {
+	// One worker fails items in feed order, so the last ones are deferred
+	s := newSimulation(t, config.Config{Workers: 1, Resources: []config.ResourceConfig{resource("https://a.example/feed", "summary")}})
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A",
+		"https://a.example/1", "https://a.example/2", "https://a.example/3", "https://a.example/4", "https://a.example/5")
+	s.agent.fail = "Text of"
+
+	// Three failures in a row open the circuit, the remaining items are deferred
+	run, _ := s.run(false)
+	if s.agent.calls != 3 {
+		t.Errorf("expected Gemini to be skipped after 3 failures, got %d calls", s.agent.calls)
+	}
+	if got := pageLinks(run.newsletter)["Blog A"]; len(got) != 3 {
+		t.Errorf("expected 3 pages with parsed content, got %v", got)
+	}
+	if run.stats.Deferred != 2 {
+		t.Errorf("expected 2 deferred items, got %d", run.stats.Deferred)
+	}
+	if len(run.errs) != 4 || !strings.Contains(run.errs[3].Error(), "'gemini' kept failing") {
+		t.Errorf("expected 3 agent errors and the open circuit, got %v", run.errs)
+	}
+
+	// The next run picks the deferred items up although the feed moved on
+	s.agent.fail = ""
+	run, _ = s.run(false)
+	if got := pageLinks(run.newsletter)["Blog A"]; strings.Join(got, ",") != "https://a.example/4,https://a.example/5" {
+		t.Errorf("expected the deferred items, got %v", got)
+	}
+	if run.stats.Deferred != 0 || len(run.errs) != 0 {
+		t.Errorf("expected no deferred items or errors, got %d and %v", run.stats.Deferred, run.errs)
+	}
+
+	run, _ = s.run(false)
+	if run.stats.Items != 0 {
+		t.Errorf("expected nothing left after the retry, got %v", pageLinks(run.newsletter))
+	}
+}
+
+func TestPipeline_BackReferences(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{
+		resource("https://a.example/feed"),
+		resource("https://b.example/feed"),
+	}})
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1")
+	s.run(false)
+
+	// Another feed linking to an item of an earlier issue gets a back-reference
+	s.fetcher.feeds["https://b.example/feed"] = feedOf("Blog B", "https://a.example/1?utm_source=b")
+	run, _ := s.run(false)
+	pages := run.newsletter.Resources[0].Pages
+	if len(pages) != 1 || !strings.Contains(string(pages[0].Content), "back-reference") {
+		t.Fatalf("expected a back-reference, got %+v", pages)
+	}
+	if len(s.parser.calls) != 1 {
+		t.Errorf("expected the repeated item not to be parsed, got %d parses", len(s.parser.calls))
+	}
+}
+
+func TestPipeline_Headlines(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{
+		{FeedURL: "https://a.example/feed", T: config.RSS, Mode: config.ModeHeadline},
+	}})
+	feed := feedOf("Blog A", "https://a.example/1")
+	feed.Items[0].Description = "<p>First line</p>\n<p>" + strings.Repeat("word ", 100) + "</p>"
+	s.fetcher.feeds["https://a.example/feed"] = feed
+
+	run, html := s.run(false)
+	if len(s.parser.calls) != 0 || s.agent.calls != 0 || run.stats.CacheLookups != 0 {
+		t.Errorf("expected headlines not to be parsed, processed or cached")
+	}
+	page := run.newsletter.Resources[0].Pages[0]
+	if !strings.HasPrefix(string(page.Content), `<p class="headline">First line word`) || !strings.HasSuffix(string(page.Content), "…</p>") {
+		t.Errorf("expected a shortened line of the description, got %q", page.Content)
+	}
+	if !strings.Contains(html, "Title of https://a.example/1") {
+		t.Error("expected the title in the issue")
+	}
+	if len(run.processedItems) != 0 {
+		t.Errorf("expected headlines not to be linked back to, got %+v", run.processedItems)
+	}
+}
+
+func TestPipeline_ItemHeader(t *testing.T) {
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{resource("https://a.example/feed")}})
+	s.fetcher.feeds["https://a.example/feed"] = feedOf("Blog A", "https://a.example/1", "https://a.example/2")
+	s.parser.meta = map[string]parser.Meta{
+		"https://a.example/1": {Image: "https://a.example/cover.png", WordCount: 1000},
+		// The image is in the text already
+		"https://a.example/2": {Image: "https://a.example/2"},
+	}
+
+	run, html := s.run(false)
+	pages := run.newsletter.Resources[0].Pages
+	if pages[0].Image != "https://a.example/cover.png" || pages[0].ReadingTime != 5*time.Minute {
+		t.Errorf("expected the preview image and reading time of the parser, got %q and %v", pages[0].Image, pages[0].ReadingTime)
+	}
+	if pages[1].Image != "" {
+		t.Errorf("expected no preview image shown in the content, got %q", pages[1].Image)
+	}
+	if !strings.Contains(html, `<img class="article-cover" src="https://a.example/cover.png"`) || !strings.Contains(html, "Reading time: 5 min") {
+		t.Error("expected the preview image and reading time in the issue")
+	}
+}
+
+func TestPipeline_ExplodeLinks(t *testing.T) {
+	digest := resource("https://weekly.example/feed")
+	digest.ExplodeLinks = true
+	s := newSimulation(t, config.Config{Resources: []config.ResourceConfig{digest}})
+	feed := feedOf("Weekly", "https://weekly.example/1")
+	feed.Items[0].Description = `<ul><li><a href="https://a.example/1">First</a></li><li><a href="https://b.example/2">Second</a></li></ul>`
+	s.fetcher.feeds["https://weekly.example/feed"] = feed
+
+	run, _ := s.run(false)
+	if got := strings.Join(slices.Sorted(slices.Values(s.parser.calls)), ","); got != "https://a.example/1,https://b.example/2" {
+		t.Errorf("expected the linked pages to be parsed, got %v", got)
+	}
+	if got := pageLinks(run.newsletter)["Weekly"]; len(got) != 2 || run.newsletter.Resources[0].Pages[0].Title != "First" {
+		t.Errorf("expected an item per link titled with its text, got %v", got)
+	}
+}
+
+func BenchmarkRenderHTML(b *testing.B) {
+	t := template.Must(loadTemplates(""))
+
+	// A big newsletter: 50 resources with 40 long items each
+	content := strings.Repeat("<p>Lorem ipsum dolor sit amet, <a href=\"https://example.com\">consectetur</a> adipiscing elit.</p>", 50)
+	var n Newsletter
+	for r := range 50 {
+		res := Resource{Name: fmt.Sprintf("Resource %d", r)}
+		for p := range 40 {
+			res.Pages = append(res.Pages, Page{
+				Title:     fmt.Sprintf("Item %d of resource %d", p, r),
+				Link:      fmt.Sprintf("https://example.com/%d/%d", r, p),
+				Content:   template.HTML(content),
+				ID:        fmt.Sprintf("item-%d-%d", r, p),
+				Published: time.Now(),
+			})
+		}
+		n.Resources = append(n.Resources, res)
+	}
+	htmlPath := filepath.Join(b.TempDir(), "issue.html")
+
+	for b.Loop() {
+		if err := renderHTML(t, htmlPath, n); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
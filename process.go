@@ -0,0 +1,771 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/breaker"
+	"github.com/scipunch/myfeed/cache"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/filter"
+	"github.com/scipunch/myfeed/markdown"
+	"github.com/scipunch/myfeed/parser"
+	"github.com/scipunch/myfeed/parser/factory"
+	"github.com/scipunch/myfeed/route"
+	"github.com/scipunch/myfeed/sanitize"
+	"github.com/scipunch/myfeed/urlnorm"
+)
+
+// processor turns queued feeds into an issue: items are skipped when seen
+// before, filtered, parsed and passed through agents, using the cache
+type processor struct {
+	conf       config.Config
+	queries    *db.Queries
+	cache      *cache.Cache
+	parsers    map[parser.Type]parser.Parser
+	agents     map[string]agent.Agent
+	filters    *filter.FilterPipeline
+	includeAll bool
+}
+
+// issueRun is the outcome of processing, saved once the issue is rendered
+type issueRun struct {
+	newsletter     Newsletter
+	stats          IssueStats
+	errs           []error                      // Items which failed to process, their pages are missing or degraded
+	lastProcessed  map[int]int64                // Latest processed timestamp by feed index
+	mediaFiles     map[string]string            // Temp path -> output filename of media files
+	processedItems []db.SaveProcessedItemParams // Items to link back to from future issues
+	deferred       map[int][]deferredItem       // Items skipped by open circuits by feed index, queued for the next run
+}
+
+// section identifies the items of a feed which matched the same route, 0
+// for items matching none
+type section struct {
+	feed, route int
+}
+
+// itemOutcome is what processing an item added to the issue, collected in
+// feed order once every worker is done
+type itemOutcome struct {
+	err       error
+	errs      []error           // Failures keeping the item, e.g., of an agent
+	stats     IssueStats        // Filtered items and cache lookups only
+	media     map[string]string // Temp path -> output filename of media files
+	timestamp int64             // Published time of an item that passed the filters
+	page      *Page             // Nil when the item was skipped
+	routed    int               // Route the item matched starting at 1, 0 for none
+	category  string
+	processed *db.SaveProcessedItemParams
+}
+
+// config returns the resource config the items of res were processed with
+func (res Resource) config(conf config.Config) config.ResourceConfig {
+	resource := conf.Resources[res.feed]
+	if res.route > 0 {
+		return resource.Routed(res.route - 1)
+	}
+	return resource
+}
+
+// deferredItem is an item skipped because a provider it needs kept failing
+type deferredItem struct {
+	item   fetcher.FeedItem
+	reason string
+}
+
+// process processes feeds, indexed like conf.Resources with nil for feeds
+// without queued items. Items failing to process are reported in errs, an
+// error is returned only when ctx is cancelled.
+func (proc processor) process(ctx context.Context, feeds []*fetcher.Feed, started time.Time) (issueRun, error) {
+	conf, queries, cacheDB, includeAll := proc.conf, proc.queries, proc.cache, proc.includeAll
+	filterPipeline := proc.filters
+	// Providers failing finally are skipped for the rest of the run
+	circuits := breaker.New(conf.BreakerThreshold())
+	parsers, agents := proc.parsers, guardAgents(proc.agents, circuits)
+	var stats IssueStats
+	var errs []error
+	newsletter := Newsletter{Title: "Test newsletter"}
+	resourceMap := make(map[section]*Resource) // Map feed index and route to resource
+	feedLastProcessed := make(map[int]int64)   // Track latest timestamp per feed
+	mediaFiles := make(map[string]string)      // Map temp path -> output filename for media files
+	var processedItems []db.SaveProcessedItemParams
+	deferred := make(map[int][]deferredItem)
+
+	// Items are processed by workers and collected once all are done
+	outcomes := make([][]*itemOutcome, len(feeds))
+	workers := make(chan struct{}, conf.ItemWorkers())
+	var wg sync.WaitGroup
+
+	for i, feed := range feeds {
+		// Check if context was cancelled
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return issueRun{}, ctx.Err()
+		default:
+		}
+
+		if feed == nil {
+			slog.Debug("skipping failed to parse feed")
+			continue
+		}
+		resource := conf.Resources[i]
+
+		// Get last processed timestamp for this feed
+		var lastProcessedAt int64
+		if !includeAll {
+			// Try to get from generation history first
+			timestamp, err := queries.GetLatestGenerationTimestamp(ctx, resource.FeedURL)
+			if err == nil {
+				lastProcessedAt = timestamp
+				slog.Debug("loaded last processed timestamp from generation history",
+					"feed", resource.FeedURL,
+					"timestamp", lastProcessedAt,
+					"time", time.Unix(lastProcessedAt, 0))
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				slog.Warn("failed to get generation history", "error", err, "feed", resource.FeedURL)
+			}
+		}
+
+		// Undated items at or after the previous high-water mark in feed order were seen already
+		markIndex := -1
+		if !includeAll {
+			mark, err := queries.GetLatestHighWaterMark(ctx, resource.FeedURL)
+			if err == nil {
+				markIndex = markPosition(feed.Items, mark.Guid)
+				lastProcessedAt = max(lastProcessedAt, mark.PublishedAt)
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				slog.Warn("failed to get high-water mark", "error", err, "feed", resource.FeedURL)
+			}
+		}
+
+		// Items deferred by an earlier run are processed even when seen before
+		retry := make(map[string]bool)
+		if keys, err := queries.ListDeferredItemKeys(ctx, resource.FeedURL); err == nil {
+			for _, key := range keys {
+				retry[key] = true
+			}
+		} else {
+			slog.Warn("failed to load deferred items", "error", err, "feed", resource.FeedURL)
+		}
+
+		p := resourceParser(conf, parsers, resource, circuits)
+		router, err := route.New(resource.Routes)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("'%s' routes are invalid: %w", resource.FeedURL, err))
+			continue
+		}
+		routeParsers := make([]parser.Parser, len(resource.Routes))
+		for k := range resource.Routes {
+			routeParsers[k] = resourceParser(conf, parsers, resource.Routed(k), circuits)
+		}
+		outcomes[i] = make([]*itemOutcome, len(feed.Items))
+		for j, item := range feed.Items {
+			// Wait for a free worker, checking for cancellation
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return issueRun{}, ctx.Err()
+			case workers <- struct{}{}:
+			}
+
+			out := &itemOutcome{media: make(map[string]string)}
+			outcomes[i][j] = out
+			wg.Add(1)
+			go func() {
+				defer func() { <-workers; wg.Done() }()
+				// Recover from panics so one broken item does not abort the run
+				out.err = recoverPanic(func() error {
+					// Skip items that were already processed (based on published date)
+					itemTimestamp := item.Published.Unix()
+					seen := !includeAll && !retry[itemKey(item)]
+					if seen && itemTimestamp > 0 && itemTimestamp <= lastProcessedAt {
+						slog.Debug("item already processed, skipping",
+							"title", item.Title,
+							"published", item.Published,
+							"last_processed", time.Unix(lastProcessedAt, 0))
+						return nil
+					}
+					if seen && itemTimestamp <= 0 && markIndex >= 0 && j >= markIndex {
+						slog.Debug("undated item seen in previous run, skipping", "title", item.Title, "url", item.Link)
+						return nil
+					}
+
+					// Items of mixed-content feeds may go to another parser, agents or category
+					resource, p, routed := resource, p, 0
+					if k, ok := router.Match(item); ok {
+						if resource.Routes[k].Drop {
+							slog.Debug("item dropped by route", "title", item.Title, "route", k+1, "url", item.Link)
+							out.stats.Filtered++
+							return nil
+						}
+						resource, p, routed = resource.Routed(k), routeParsers[k], k+1
+					}
+
+					// Apply filters
+					if len(resource.FilterNames) > 0 {
+						shouldInclude, reason := filterPipeline.ShouldInclude(item, resource.FilterNames)
+						if !shouldInclude {
+							slog.Debug("item filtered out", "title", item.Title, "reason", reason, "url", item.Link)
+							out.stats.Filtered++
+							return nil
+						}
+					}
+
+					// Track the latest timestamp for this feed
+					out.timestamp = itemTimestamp
+
+					var content string
+					var parsedData parser.Response
+					cacheHit := false
+					answeredBy := "" // Agent whose answer is the content, empty for the parsed one
+
+					// Final content is neither parsed nor cached, e.g., a back-reference
+					canonical := urlnorm.Canonical(item.Link)
+					final := false
+					if resource.IsHeadline() {
+						content = headline(item)
+						final = true
+					} else if prev, err := queries.GetProcessedItem(ctx, canonical); err == nil {
+						content = backReference(prev)
+						final = true
+						slog.Debug("item was processed before, adding back-reference", "url", item.Link, "previous", prev.Url)
+					} else if !errors.Is(err, sql.ErrNoRows) {
+						slog.Warn("failed to look up processed item", "error", err, "url", item.Link)
+					}
+
+					if !final {
+						out.stats.CacheLookups++
+					}
+
+					// Step 1: Check agent cache first (if agents configured)
+					if !final && len(resource.Agents) > 0 {
+						if cached, hit, err := cacheDB.GetAgentOutput(item.Link, resource.ParserCacheKey(), resource.AgentPipeline()); err == nil && hit {
+							content = cached
+							answeredBy = resource.Stage(resource.Stages())
+							cacheHit = true
+							out.stats.CacheHits++
+							slog.Debug("agent cache hit", "url", item.Link, "agents", resource.Agents)
+						}
+					}
+
+					// Resume from the longest cached stage, e.g. after appending an agent
+					stage := 0
+					if !final && !cacheHit {
+						for n := resource.Stages(); n > 0; n-- {
+							if cached, hit, err := cacheDB.GetAgentOutput(item.Link, resource.ParserCacheKey(), resource.StagePipeline(n)); err == nil && hit {
+								content = cached
+								answeredBy = resource.Stage(n)
+								stage = n
+								out.stats.CacheHits++
+								slog.Debug("agent stage cache hit", "url", item.Link, "agents", resource.StagePipeline(n))
+								break
+							}
+						}
+					}
+
+					// Step 2: If no agent cache, try parser cache
+					if !final && !cacheHit && stage == 0 {
+						if cached, hit, err := cacheDB.GetParserOutput(item.Link, resource.ParserCacheKey()); err == nil && hit {
+							// Deserialize cached parser output
+							if data, err := cache.DeserializeParserResponse(string(resource.ParserT), cached); err == nil {
+								parsedData = data
+								slog.Debug("parser cache hit", "url", item.Link, "parser", resource.ParserT)
+								out.stats.CacheHits++
+							} else if errors.Is(err, cache.ErrStaleVersion) {
+								slog.Debug("cached parser output is stale, parsing again", "url", item.Link, "error", err)
+							} else {
+								slog.Warn("failed to deserialize cached parser output", "error", err)
+								// Fall through to re-parse
+							}
+						}
+
+						// Step 3: If no parser cache, parse now
+						if parsedData == nil {
+							if caps, ok := factory.Capabilities(resource.ParserT); ok && !caps.AcceptsLink(item.Link) {
+								return fmt.Errorf("parser '%s' can't load link '%s'", resource.ParserT, item.Link)
+							}
+							data, err := p.Parse(ctx, item)
+							if err != nil {
+								return err
+							}
+							parsedData = data
+							slog.Info("feed item parsed", "url", item.Link, "length", len(data.String()))
+
+							// Cache parser output
+							if serialized, err := cache.SerializeParserResponse(string(resource.ParserT), parsedData); err == nil {
+								if err := cacheDB.SetParserOutput(item.Link, resource.ParserCacheKey(), serialized); err != nil {
+									slog.Warn("failed to cache parser output", "error", err)
+								}
+							} else {
+								slog.Warn("failed to serialize parser output", "error", err)
+							}
+						}
+
+						content = parsedData.String()
+					}
+
+					// Items resumed from the agent cache still need their metadata
+					if !final && parsedData == nil {
+						parsedData = cachedResponse(cacheDB, item.Link, resource)
+					}
+
+					// Filter on the parsed content, e.g., its length and author
+					if parsedData != nil && len(resource.FilterNames) > 0 {
+						shouldInclude, reason := filterPipeline.ShouldIncludeResponse(parsedData, resource.FilterNames)
+						if !shouldInclude {
+							slog.Debug("parsed item filtered out", "title", item.Title, "reason", reason, "url", item.Link)
+							out.stats.Filtered++
+							return nil
+						}
+					}
+
+					// Drop items the relevance agent scores below min_score, before
+					// the other agents spend tokens on them
+					if !final && resource.MinScore > 0 {
+						score, err := scoreRelevance(ctx, cacheDB, agents[agent.Relevance], item, resource, conf.Interests, content, parsedData)
+						if errors.Is(err, breaker.ErrOpen) {
+							return err
+						}
+						if err != nil {
+							out.errs = append(out.errs, fmt.Errorf("agent '%s' processing failed: %w", agent.Relevance, err))
+							slog.Error("relevance scoring failed, keeping the item", "url", item.Link, "error", err)
+						} else if score < resource.MinScore {
+							slog.Debug("item scored below min_score, filtered out", "title", item.Title, "score", score, "url", item.Link)
+							out.stats.Filtered++
+							return nil
+						}
+					}
+
+					// Step 4: Apply agents if configured
+					if !final && !cacheHit && len(resource.Agents) > 0 {
+						original := len(content)
+						n := 0
+						for _, agentName := range resource.Agents {
+							// Runs on the comments, see summarizeDiscussion, or
+							// scores the content, see scoreRelevance
+							if agentName == agent.Discussion || agentName == agent.Relevance {
+								continue
+							}
+							n++
+							// Output of this stage is cached
+							if n <= stage {
+								continue
+							}
+							agentInstance, ok := agents[agentName]
+							if !ok {
+								out.errs = append(out.errs, fmt.Errorf("agent '%s' not found", agentName))
+								continue
+							}
+
+							opts := agentOptions(resource, parsedData)
+							opts.Images = itemPhotos(item)
+							processed, err := agentInstance.Process(ctx, content, opts)
+							if errors.Is(err, breaker.ErrOpen) {
+								return err
+							}
+							if err != nil {
+								out.errs = append(out.errs, fmt.Errorf("agent '%s' processing failed: %w", agentName, err))
+								slog.Error("agent processing failed, using original content", "agent", agentName, "error", err)
+								// Continue with original content on error
+								break
+							}
+
+							content, answeredBy = processed, agentName
+							slog.Info("content processed by agent", "agent", agentName, "original_length", original, "processed_length", len(content))
+
+							// Cache every stage so changing a later agent reuses it
+							if err := cacheDB.SetAgentOutput(item.Link, resource.ParserCacheKey(), resource.StagePipeline(n), content); err != nil {
+								slog.Warn("failed to cache agent stage output", "error", err)
+							}
+						}
+
+						// Cache final agent output
+						if err := cacheDB.SetAgentOutput(item.Link, resource.ParserCacheKey(), resource.AgentPipeline(), content); err != nil {
+							slog.Warn("failed to cache agent output", "error", err)
+						}
+					}
+
+					// Summarize the comment thread as a separate subsection
+					var discussion string
+					if !final && slices.Contains(resource.Agents, agent.Discussion) {
+						var err error
+						discussion, err = summarizeDiscussion(ctx, cacheDB, agents[agent.Discussion], item, resource, parsedData)
+						if errors.Is(err, breaker.ErrOpen) {
+							return err
+						}
+						if err != nil {
+							out.errs = append(out.errs, fmt.Errorf("agent '%s' processing failed: %w", agent.Discussion, err))
+							slog.Error("discussion summary failed", "url", item.Link, "error", err)
+						}
+					}
+
+					// Generate unique ID for anchor link, stable across tracking params
+					hash := sha256.Sum256([]byte(canonical))
+					pageID := hex.EncodeToString(hash[:8])
+
+					// Track media files for later copying to output directory, headlines show none
+					for _, media := range item.Media {
+						if media.LocalPath != "" && (media.Type == "photo" || media.Type == "video") && !resource.IsHeadline() {
+							// Use the filename from the local path
+							filename := filepath.Base(media.LocalPath)
+							out.media[media.LocalPath] = filename
+						}
+					}
+					if files, ok := parsedData.(parser.MediaFiles); ok && !resource.IsHeadline() {
+						for _, path := range files.MediaFiles() {
+							out.media[path] = filepath.Base(path)
+						}
+					}
+
+					if !final {
+						out.processed = &db.SaveProcessedItemParams{
+							CanonicalUrl: canonical,
+							Url:          item.Link,
+							Title:        item.Title,
+						}
+					}
+
+					// Agents with a schema answer with JSON, their text field is shown
+					var fields map[string]any
+					if def := conf.Agents[answeredBy]; !final && len(def.Schema) > 0 {
+						if text, decoded, err := agent.DecodeFields(content, def.Text); err == nil {
+							content, fields = text, decoded
+						} else {
+							slog.Warn("failed to decode structured agent answer, showing it as is", "agent", answeredBy, "error", err)
+						}
+					}
+
+					var language string
+					if !final {
+						language = pageLanguage(content, resource, parsedData)
+					}
+
+					page := Page{
+						Title:       item.Title,
+						Link:        sanitize.Link(item.Link),
+						Description: plainText(item.Description),
+						Content:     renderMarkdown(content),
+						Discussion:  renderMarkdown(discussion),
+						Fields:      fields,
+						ID:          pageID,
+						Published:   item.Published,
+						Language:    language,
+					}
+					if parsedData != nil {
+						page.Title = cmp.Or(page.Title, parsedData.Title())
+						page.Author = parsedData.Author()
+						page.ReadingTime = parsedData.ReadingTime()
+						if image := parsedData.Image(); !strings.Contains(string(page.Content), image) {
+							page.Image = image
+						}
+						if page.Published.IsZero() {
+							page.Published = parsedData.PublishedAt()
+						}
+					}
+					out.page, out.routed, out.category = &page, routed, resource.Category
+
+					return nil
+				})
+			}()
+		}
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return issueRun{}, ctx.Err()
+	}
+
+	// Collect outcomes in feed order, so the issue doesn't depend on timing
+	for i, feed := range feeds {
+		for j, out := range outcomes[i] {
+			item := feed.Items[j]
+			stats.Filtered += out.stats.Filtered
+			stats.CacheHits += out.stats.CacheHits
+			stats.CacheLookups += out.stats.CacheLookups
+			errs = append(errs, out.errs...)
+			maps.Copy(mediaFiles, out.media)
+			feedLastProcessed[i] = max(feedLastProcessed[i], out.timestamp)
+			if errors.Is(out.err, breaker.ErrOpen) {
+				slog.Info("provider is skipped, deferring item to the next run", "url", item.Link, "error", out.err)
+				deferred[i] = append(deferred[i], deferredItem{item: item, reason: out.err.Error()})
+				stats.Deferred++
+				continue
+			}
+			if out.err != nil {
+				errs = append(errs, fmt.Errorf("'%s' processing failed with %w", item.Link, out.err))
+			}
+			if out.page == nil {
+				continue
+			}
+
+			// Get or create resource for this feed
+			res, exists := resourceMap[section{i, out.routed}]
+			if !exists {
+				res = &Resource{
+					Name:     feed.Title,
+					Category: out.category,
+					Pages:    []Page{},
+					feed:     i,
+					route:    out.routed,
+				}
+				resourceMap[section{i, out.routed}] = res
+			}
+			res.Pages = append(res.Pages, *out.page)
+			if out.processed != nil {
+				processedItems = append(processedItems, *out.processed)
+			}
+		}
+	}
+
+	// Report every open circuit once instead of the items it deferred
+	for key, err := range circuits.Open() {
+		errs = append(errs, fmt.Errorf("'%s' kept failing and was skipped for the rest of the run, last error: %w", key, err))
+	}
+
+	// Convert resource map to slice in order
+	for i := 0; i < len(feeds); i++ {
+		for k := 0; k <= len(conf.Resources[i].Routes); k++ {
+			if res, exists := resourceMap[section{i, k}]; exists && len(res.Pages) > 0 {
+				newsletter.Resources = append(newsletter.Resources, *res)
+			}
+		}
+	}
+
+	// Stories covered by several sources are shown once
+	newsletter.Resources, stats.Merged = clusterPages(newsletter.Resources, conf.Clustering)
+
+	stats = collectStats(newsletter, stats, started)
+	newsletter.Stats = &stats
+	return issueRun{
+		newsletter:     newsletter,
+		stats:          stats,
+		errs:           errs,
+		lastProcessed:  feedLastProcessed,
+		mediaFiles:     mediaFiles,
+		processedItems: processedItems,
+		deferred:       deferred,
+	}, nil
+}
+
+// resourceParser returns the parser of the resource configured with its
+// settings and guarded by the circuit breakers
+func resourceParser(conf config.Config, parsers map[parser.Type]parser.Parser, resource config.ResourceConfig, circuits *breaker.Breaker) parser.Parser {
+	p := parsers[resource.ParserT]
+	if proxy := resource.ProxyURL(conf.Proxy); proxy != "" {
+		if pa, ok := p.(parser.ProxyAware); ok {
+			p = pa.WithProxy(proxy)
+		}
+	}
+	if userAgent := resource.UserAgentFor(conf.UserAgent); userAgent != "" {
+		if ua, ok := p.(parser.UserAgentAware); ok {
+			p = ua.WithUserAgent(userAgent)
+		}
+	}
+	if !resource.ParserOptions.IsZero() {
+		if oa, ok := p.(parser.OptionsAware); ok {
+			p = oa.WithOptions(resource.ParserOptions)
+		} else {
+			slog.Warn("parser has no options, ignoring parser_options", "parser", resource.ParserT, "feed", resource.FeedURL)
+		}
+	}
+	if resource.Comments > 0 {
+		if ca, ok := p.(parser.CommentsAware); ok {
+			p = ca.WithComments(resource.Comments)
+		}
+	}
+	if resource.ParserCommand != "" {
+		if ca, ok := p.(parser.CommandAware); ok {
+			p = ca.WithCommand(resource.ParserCommand)
+		}
+	}
+	if resource.MaxVideoMinutes > 0 {
+		if da, ok := p.(parser.DurationAware); ok {
+			p = da.WithMaxDuration(time.Duration(resource.MaxVideoMinutes) * time.Minute)
+		}
+	}
+	return guardedParser{Parser: p, t: resource.ParserT, breaker: circuits}
+}
+
+// renderMarkdown renders content of parsers and agents for the templates,
+// stripping anything that could run while the PDF is generated
+func renderMarkdown(md string) template.HTML {
+	return template.HTML(sanitize.HTML(markdown.ToHTML(md)))
+}
+
+// cachedResponse returns the cached parser output of the link, nil when it
+// isn't cached or can't be decoded
+func cachedResponse(cacheDB *cache.Cache, link string, resource config.ResourceConfig) parser.Response {
+	cached, hit, err := cacheDB.GetParserOutput(link, resource.ParserCacheKey())
+	if err != nil || !hit {
+		return nil
+	}
+	data, err := cache.DeserializeParserResponse(string(resource.ParserT), cached)
+	if err != nil {
+		slog.Debug("cached parser output can't be decoded, metadata is unknown", "url", link, "error", err)
+		return nil
+	}
+	return data
+}
+
+// agentOptions are the options of the agents of resource for a parsed item,
+// parsed is nil when unknown
+func agentOptions(resource config.ResourceConfig, parsed parser.Response) agent.Options {
+	opts := agent.Options{Language: resource.OutputLang}
+	if parsed != nil {
+		opts.Title = parsed.Title()
+		opts.Author = parsed.Author()
+	}
+	return opts
+}
+
+// itemPhotos returns the local paths of the downloaded photos of the item
+func itemPhotos(item fetcher.FeedItem) []string {
+	var photos []string
+	for _, media := range item.Media {
+		if media.Type == "photo" && media.LocalPath != "" {
+			photos = append(photos, media.LocalPath)
+		}
+	}
+	return photos
+}
+
+// saveRun records what the run processed, so the next run skips it, and
+// removes processed items from the queue. Items queued after queueCutoff,
+// by a concurrent fetch, stay queued.
+func saveRun(ctx context.Context, conf config.Config, queries *db.Queries, feeds []*fetcher.Feed, run issueRun, includeAll bool, queueCutoff int64) {
+	feedLastProcessed, processedItems := run.lastProcessed, run.processedItems
+
+	// Update last processed timestamps for feeds and save generation history
+	if !includeAll {
+		generationTime := time.Now().Unix()
+		for i, feed := range feeds {
+			if feed == nil {
+				continue
+			}
+
+			// Remember the newest item so the next run skips everything seen
+			if len(feed.Items) > 0 {
+				newest := newestItem(feed.Items)
+				err := queries.SaveHighWaterMark(ctx, db.SaveHighWaterMarkParams{
+					FeedUrl:     conf.Resources[i].FeedURL,
+					Guid:        itemKey(newest),
+					PublishedAt: max(0, newest.Published.Unix()),
+					CreatedAt:   generationTime,
+				})
+				if err != nil {
+					slog.Warn("failed to save high-water mark",
+						"error", err,
+						"feed", conf.Resources[i].FeedURL)
+				}
+			}
+
+			// Get the latest timestamp for this feed
+			lastTimestamp := feedLastProcessed[i]
+			if lastTimestamp > 0 {
+				resource := conf.Resources[i]
+
+				// Update the main feed table
+				err := queries.UpdateLastProcessedAt(ctx, db.UpdateLastProcessedAtParams{
+					Url:             resource.FeedURL,
+					Title:           feed.Title,
+					LastProcessedAt: lastTimestamp,
+				})
+				if err != nil {
+					slog.Warn("failed to update last processed timestamp",
+						"error", err,
+						"feed", resource.FeedURL)
+				} else {
+					slog.Debug("updated last processed timestamp",
+						"feed", resource.FeedURL,
+						"timestamp", time.Unix(lastTimestamp, 0))
+				}
+
+				// Save to generation history
+				err = queries.SaveGenerationHistory(ctx, db.SaveGenerationHistoryParams{
+					FeedUrl:         resource.FeedURL,
+					LastProcessedAt: lastTimestamp,
+					CreatedAt:       generationTime,
+				})
+				if err != nil {
+					slog.Warn("failed to save generation history",
+						"error", err,
+						"feed", resource.FeedURL)
+				}
+			}
+		}
+
+		// Index processed items for back-references in future issues
+		for _, item := range processedItems {
+			item.ProcessedAt = generationTime
+			if err := queries.SaveProcessedItem(ctx, item); err != nil {
+				slog.Warn("failed to save processed item", "error", err, "url", item.Url)
+			}
+		}
+	}
+
+	// Processed items leave the queue, items queued by a concurrent fetch stay
+	for i, feed := range feeds {
+		if feed == nil {
+			continue
+		}
+		err := queries.DeleteQueuedItems(ctx, db.DeleteQueuedItemsParams{
+			FeedUrl:  conf.Resources[i].FeedURL,
+			QueuedAt: queueCutoff,
+		})
+		if err != nil {
+			slog.Warn("failed to remove processed items from the queue", "error", err, "feed", conf.Resources[i].FeedURL)
+		}
+		requeueDeferred(ctx, queries, conf.Resources[i].FeedURL, feed.Title, run.deferred[i], queueCutoff)
+	}
+
+	saveAgentMetrics(ctx, queries, time.Now())
+}
+
+// requeueDeferred puts items skipped by open circuits back into the queue and
+// forgets items deferred by earlier runs, which were processed by this one
+func requeueDeferred(ctx context.Context, queries *db.Queries, url, title string, deferred []deferredItem, queueCutoff int64) {
+	err := queries.DeleteDeferredItems(ctx, db.DeleteDeferredItemsParams{FeedUrl: url, DeferredAt: queueCutoff})
+	if err != nil {
+		slog.Warn("failed to remove retried deferred items", "error", err, "feed", url)
+	}
+	if len(deferred) == 0 {
+		return
+	}
+
+	now := time.Now().Unix()
+	feed := fetcher.Feed{Title: title}
+	for _, d := range deferred {
+		feed.Items = append(feed.Items, d.item)
+		err := queries.DeferItem(ctx, db.DeferItemParams{
+			FeedUrl:    url,
+			ItemKey:    itemKey(d.item),
+			Reason:     d.reason,
+			DeferredAt: now,
+		})
+		if err != nil {
+			slog.Warn("failed to save deferred item", "error", err, "url", d.item.Link)
+		}
+	}
+	if err := enqueueFeed(ctx, queries, url, feed, now); err != nil {
+		slog.Warn("failed to queue deferred items", "error", err, "feed", url)
+		return
+	}
+	slog.Info("deferred items queued for the next run", "feed", url, "items", len(deferred))
+}
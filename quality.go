@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/scipunch/myfeed/agent/quality"
+	"github.com/scipunch/myfeed/daemon"
+	"github.com/scipunch/myfeed/db"
+)
+
+const (
+	// qualityRecent is the period compared to the baseline before it
+	qualityRecent = 7 * 24 * time.Hour
+	// qualityBaseline is the period answers are expected to look like
+	qualityBaseline = 28 * 24 * time.Hour
+	// qualityMinCalls keeps a few unlucky answers from looking like drift
+	qualityMinCalls = 10
+	// lengthDrift is the relative change of the length ratio reported as drift
+	lengthDrift = 0.3
+	// rateDrift is the increase of a rate reported as drift, e.g., 10 more
+	// answers in a wrong language out of 100
+	rateDrift = 0.1
+)
+
+// saveAgentMetrics stores answers of agents during the run for quality drift trends
+func saveAgentMetrics(ctx context.Context, queries *db.Queries, now time.Time) {
+	for name, c := range quality.Take() {
+		err := queries.SaveAgentMetric(ctx, db.SaveAgentMetricParams{
+			Agent:              name,
+			Model:              c.Model,
+			Calls:              int64(c.Calls),
+			InputChars:         int64(c.InputChars),
+			OutputChars:        int64(c.OutputChars),
+			LanguageMismatches: int64(c.LanguageMismatches),
+			ContractViolations: int64(c.ContractViolations),
+			Failures:           int64(c.Failures),
+			CreatedAt:          now.Unix(),
+		})
+		if err != nil {
+			slog.Warn("failed to save agent metrics", "error", err, "agent", name)
+		}
+	}
+}
+
+// agentQuality compares answers of the recent period to the baseline before
+// it per agent and model, agents drifting away first
+func agentQuality(ctx context.Context, queries *db.Queries, now time.Time) ([]daemon.AgentQuality, error) {
+	recentSince := now.Add(-qualityRecent).Unix()
+	metrics, err := queries.ListAgentMetrics(ctx, now.Add(-qualityRecent-qualityBaseline).Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agent metrics with %w", err)
+	}
+
+	type key struct{ agent, model string }
+	type totals struct{ recent, baseline db.AgentMetric }
+	grouped := make(map[key]*totals)
+	for _, m := range metrics {
+		k := key{m.Agent, m.Model}
+		if grouped[k] == nil {
+			grouped[k] = &totals{}
+		}
+		sum := &grouped[k].baseline
+		if m.CreatedAt >= recentSince {
+			sum = &grouped[k].recent
+		}
+		sum.Calls += m.Calls
+		sum.InputChars += m.InputChars
+		sum.OutputChars += m.OutputChars
+		sum.LanguageMismatches += m.LanguageMismatches
+		sum.ContractViolations += m.ContractViolations
+		sum.Failures += m.Failures
+	}
+
+	var trends []daemon.AgentQuality
+	for k, t := range grouped {
+		q := daemon.AgentQuality{
+			Agent:    k.agent,
+			Model:    k.model,
+			Recent:   qualityWindow(t.recent),
+			Baseline: qualityWindow(t.baseline),
+		}
+		q.Drift = qualityDrift(q.Recent, q.Baseline)
+		trends = append(trends, q)
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if (len(trends[i].Drift) > 0) != (len(trends[j].Drift) > 0) {
+			return len(trends[i].Drift) > 0
+		}
+		if trends[i].Agent != trends[j].Agent {
+			return trends[i].Agent < trends[j].Agent
+		}
+		return trends[i].Model < trends[j].Model
+	})
+	return trends, nil
+}
+
+// qualityWindow derives ratios from summed metrics
+func qualityWindow(m db.AgentMetric) daemon.QualityWindow {
+	w := daemon.QualityWindow{Calls: m.Calls}
+	if m.InputChars > 0 {
+		w.LengthRatio = float64(m.OutputChars) / float64(m.InputChars)
+	}
+	if answers := m.Calls - m.Failures; answers > 0 {
+		w.LanguageMismatchRate = float64(m.LanguageMismatches) / float64(answers)
+		w.ContractViolationRate = float64(m.ContractViolations) / float64(answers)
+	}
+	if m.Calls > 0 {
+		w.FailureRate = float64(m.Failures) / float64(m.Calls)
+	}
+	return w
+}
+
+// qualityDrift lists metrics of the recent period which moved away from the
+// baseline, none until both periods saw enough calls
+func qualityDrift(recent, baseline daemon.QualityWindow) []string {
+	if recent.Calls < qualityMinCalls || baseline.Calls < qualityMinCalls {
+		return nil
+	}
+	var drift []string
+	if baseline.LengthRatio > 0 && math.Abs(recent.LengthRatio-baseline.LengthRatio) > lengthDrift*baseline.LengthRatio {
+		if recent.LengthRatio < baseline.LengthRatio {
+			drift = append(drift, "shorter answers")
+		} else {
+			drift = append(drift, "longer answers")
+		}
+	}
+	if recent.LanguageMismatchRate-baseline.LanguageMismatchRate > rateDrift {
+		drift = append(drift, "wrong language")
+	}
+	if recent.ContractViolationRate-baseline.ContractViolationRate > rateDrift {
+		drift = append(drift, "broken contract")
+	}
+	if recent.FailureRate-baseline.FailureRate > rateDrift {
+		drift = append(drift, "failures")
+	}
+	return drift
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/agent/quality"
+	"github.com/scipunch/myfeed/db"
+)
+
+func TestAgentQuality(t *testing.T) {
+	ctx := context.Background()
+	database, err := initDB(ctx, filepath.Join(t.TempDir(), "myfeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	queries := db.New(database)
+
+	now := time.Now()
+	quality.Take()
+	quality.SetModel("summary", "gemini-test")
+	for range 20 {
+		quality.Answer("summary", 1000, 200)
+		quality.Answer("keypoints", 1000, 100)
+	}
+	saveAgentMetrics(ctx, queries, now.Add(-14*24*time.Hour))
+
+	// Summaries got shorter and were written in a wrong language
+	for range 20 {
+		quality.Answer("summary", 1000, 50)
+		quality.LanguageMismatch("summary")
+		quality.Answer("keypoints", 1000, 110)
+	}
+	saveAgentMetrics(ctx, queries, now.Add(-time.Hour))
+
+	trends, err := agentQuality(ctx, queries, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trends) != 2 {
+		t.Fatalf("expected trends of 2 agents, got %+v", trends)
+	}
+	summary := trends[0]
+	if summary.Agent != "summary" || summary.Model != "gemini-test" {
+		t.Fatalf("expected the drifting summary agent first, got %+v", summary)
+	}
+	if summary.Recent.Calls != 20 || summary.Recent.LengthRatio != 0.05 || summary.Baseline.LengthRatio != 0.2 {
+		t.Errorf("unexpected windows %+v", summary)
+	}
+	if len(summary.Drift) != 2 || summary.Drift[0] != "shorter answers" || summary.Drift[1] != "wrong language" {
+		t.Errorf("expected shorter answers in a wrong language, got %q", summary.Drift)
+	}
+	if len(trends[1].Drift) != 0 {
+		t.Errorf("expected key points to be steady, got %q", trends[1].Drift)
+	}
+}
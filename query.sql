@@ -46,3 +46,462 @@ WHERE
         LIMIT
             1
     );
+
+-- name: GetProcessedItem :one
+SELECT
+    canonical_url,
+    url,
+    title,
+    processed_at
+FROM
+    processed_item
+WHERE
+    canonical_url = ?;
+
+-- name: SaveProcessedItem :exec
+INSERT
+    OR IGNORE INTO processed_item (canonical_url, url, title, processed_at)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: DeleteLatestProcessedItems :exec
+DELETE FROM
+    processed_item
+WHERE
+    processed_at = (
+        SELECT
+            MAX(created_at)
+        FROM
+            generation_history
+    );
+
+-- name: GetFeedValidator :one
+SELECT
+    url,
+    etag,
+    last_modified
+FROM
+    feed_validator
+WHERE
+    url = ?;
+
+-- name: SaveFeedValidator :exec
+INSERT
+    OR REPLACE INTO feed_validator (url, etag, last_modified)
+VALUES
+    (?, ?, ?);
+
+-- name: GetFeedOffset :one
+SELECT
+    message_id
+FROM
+    feed_offset
+WHERE
+    url = ?;
+
+-- name: SaveFeedOffset :exec
+INSERT
+    OR REPLACE INTO feed_offset (url, message_id)
+VALUES
+    (?, ?);
+
+-- name: CountGenerationHistoryBefore :one
+SELECT
+    COUNT(*)
+FROM
+    generation_history
+WHERE
+    created_at < ?;
+
+-- name: DeleteGenerationHistoryBefore :exec
+DELETE FROM
+    generation_history
+WHERE
+    created_at < ?;
+
+-- name: CountProcessedItemsBefore :one
+SELECT
+    COUNT(*)
+FROM
+    processed_item
+WHERE
+    processed_at < ?;
+
+-- name: DeleteProcessedItemsBefore :exec
+DELETE FROM
+    processed_item
+WHERE
+    processed_at < ?;
+
+-- name: CountArchiveItems :one
+SELECT
+    COUNT(*)
+FROM
+    archive_item
+WHERE
+    feed_url = ?;
+
+-- name: SaveArchiveItem :exec
+INSERT
+    OR IGNORE INTO archive_item (
+        feed_url,
+        guid,
+        title,
+        link,
+        description,
+        published_at,
+        archived_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetLatestHighWaterMark :one
+SELECT
+    feed_url,
+    guid,
+    published_at,
+    created_at
+FROM
+    high_water_mark
+WHERE
+    feed_url = ?
+ORDER BY
+    created_at DESC
+LIMIT
+    1;
+
+-- name: SaveHighWaterMark :exec
+INSERT
+    OR REPLACE INTO high_water_mark (feed_url, guid, published_at, created_at)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: DeleteLatestHighWaterMarks :exec
+DELETE FROM
+    high_water_mark
+WHERE
+    created_at = (
+        SELECT
+            MAX(created_at)
+        FROM
+            generation_history
+    );
+
+-- name: DeleteHighWaterMarksBefore :exec
+DELETE FROM
+    high_water_mark
+WHERE
+    created_at < ?
+    AND created_at < (
+        SELECT
+            MAX(latest.created_at)
+        FROM
+            high_water_mark AS latest
+        WHERE
+            latest.feed_url = high_water_mark.feed_url
+    );
+
+-- name: SaveSentEmail :exec
+INSERT
+    OR REPLACE INTO sent_email (
+        message_id,
+        subject,
+        recipients,
+        size,
+        attempts,
+        error,
+        sent_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListSentEmails :many
+SELECT
+    message_id,
+    subject,
+    recipients,
+    size,
+    attempts,
+    error,
+    sent_at
+FROM
+    sent_email
+ORDER BY
+    sent_at DESC
+LIMIT
+    ?;
+
+-- name: EnqueueItem :exec
+INSERT
+    OR IGNORE INTO queue_item (
+        feed_url,
+        item_key,
+        feed_title,
+        item_data,
+        position,
+        queued_at
+    )
+VALUES
+    (?, ?, ?, ?, ?, ?);
+
+-- name: ListQueuedItems :many
+SELECT
+    feed_url,
+    item_key,
+    feed_title,
+    item_data,
+    position,
+    queued_at
+FROM
+    queue_item
+WHERE
+    feed_url = ?
+ORDER BY
+    queued_at DESC,
+    position;
+
+-- name: DeleteQueuedItems :exec
+DELETE FROM
+    queue_item
+WHERE
+    feed_url = ?
+    AND queued_at <= ?;
+
+-- name: DeferItem :exec
+INSERT OR REPLACE INTO
+    deferred_item (feed_url, item_key, reason, deferred_at)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: ListDeferredItemKeys :many
+SELECT
+    item_key
+FROM
+    deferred_item
+WHERE
+    feed_url = ?;
+
+-- name: DeleteDeferredItems :exec
+DELETE FROM
+    deferred_item
+WHERE
+    feed_url = ?
+    AND deferred_at <= ?;
+
+-- name: GetPushLease :one
+SELECT
+    expires_at
+FROM
+    push_lease
+WHERE
+    feed_url = ?;
+
+-- name: SavePushLease :exec
+INSERT
+    OR REPLACE INTO push_lease (feed_url, expires_at)
+VALUES
+    (?, ?);
+
+-- name: DeletePushLease :exec
+DELETE FROM
+    push_lease
+WHERE
+    feed_url = ?;
+
+-- name: CreateDraft :one
+INSERT INTO
+    draft (title, issue_path, status, created_at, decided_at)
+VALUES
+    (?, ?, 'pending', ?, 0)
+RETURNING
+    id;
+
+-- name: SaveDraftEmail :exec
+INSERT INTO
+    draft_email (draft_id, part, recipients, message)
+VALUES
+    (?, ?, ?, ?);
+
+-- name: ListPendingDrafts :many
+SELECT
+    id,
+    title,
+    issue_path,
+    status,
+    created_at,
+    decided_at
+FROM
+    draft
+WHERE
+    status = 'pending'
+ORDER BY
+    created_at;
+
+-- name: ListDraftEmails :many
+SELECT
+    draft_id,
+    part,
+    recipients,
+    message
+FROM
+    draft_email
+WHERE
+    draft_id = ?
+ORDER BY
+    part;
+
+-- name: DecideDraft :execrows
+UPDATE
+    draft
+SET
+    status = ?,
+    decided_at = ?
+WHERE
+    id = ?
+    AND status = 'pending';
+
+-- name: DeleteDraftEmails :exec
+DELETE FROM
+    draft_email
+WHERE
+    draft_id = ?;
+
+-- name: SaveReadingEvent :exec
+INSERT INTO
+    reading_event (kind, url, item_url, author, created_at)
+VALUES
+    (?, ?, ?, ?, ?);
+
+-- name: ListReadingEvents :many
+SELECT
+    id,
+    kind,
+    url,
+    item_url,
+    author,
+    created_at
+FROM
+    reading_event
+WHERE
+    created_at >= ?
+ORDER BY
+    created_at;
+
+-- name: CreatePin :one
+INSERT INTO
+    pin (text, section, created_at, issued_at)
+VALUES
+    (?, ?, ?, 0)
+RETURNING
+    id;
+
+-- name: ListPendingPins :many
+SELECT
+    id,
+    text,
+    section,
+    created_at,
+    issued_at
+FROM
+    pin
+WHERE
+    issued_at = 0
+ORDER BY
+    created_at,
+    id;
+
+-- name: MarkPinIssued :exec
+UPDATE
+    pin
+SET
+    issued_at = ?
+WHERE
+    id = ?;
+
+-- name: DeletePendingPin :execrows
+DELETE FROM
+    pin
+WHERE
+    id = ?
+    AND issued_at = 0;
+
+-- name: SaveAgentMetric :exec
+INSERT OR REPLACE INTO agent_metric (
+    agent,
+    model,
+    calls,
+    input_chars,
+    output_chars,
+    language_mismatches,
+    contract_violations,
+    failures,
+    created_at
+)
+VALUES
+    (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListAgentMetrics :many
+SELECT
+    agent,
+    model,
+    calls,
+    input_chars,
+    output_chars,
+    language_mismatches,
+    contract_violations,
+    failures,
+    created_at
+FROM
+    agent_metric
+WHERE
+    created_at >= ?
+ORDER BY
+    created_at;
+
+-- name: DeleteAgentMetricsBefore :exec
+DELETE FROM
+    agent_metric
+WHERE
+    created_at < ?;
+
+-- name: SaveIssue :one
+INSERT INTO
+    issue (title, output_path, file_name, newsletter, created_at)
+VALUES
+    (?, ?, ?, ?, ?)
+RETURNING
+    id;
+
+-- name: GetIssue :one
+SELECT
+    id,
+    title,
+    output_path,
+    file_name,
+    newsletter,
+    created_at
+FROM
+    issue
+WHERE
+    id = ?;
+
+-- name: GetLatestIssue :one
+SELECT
+    id,
+    title,
+    output_path,
+    file_name,
+    newsletter,
+    created_at
+FROM
+    issue
+ORDER BY
+    id DESC
+LIMIT
+    1;
+
+-- name: DeleteIssuesBefore :exec
+DELETE FROM
+    issue
+WHERE
+    created_at < ?;
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/httpclient"
+)
+
+// fetchToQueue initializes the fetchers of the configured resource types and
+// queues new items of every enabled resource for processing
+func fetchToQueue(ctx context.Context, conf config.Config, queries *db.Queries, configDir string, includeAll, regenerate bool) error {
+	// Initialize fetchers
+	var resourceTypes []config.ResourceType
+	for _, r := range conf.Resources {
+		if r.IsEnabled() {
+			resourceTypes = append(resourceTypes, r.T)
+		}
+	}
+
+	// Conditional GET is skipped when all items have to be fetched again
+	var validators fetcher.ValidatorStore
+	var offsets fetcher.OffsetStore
+	if !includeAll && !regenerate {
+		validators = validatorStore{queries: queries}
+		offsets = offsetStore{queries: queries}
+	}
+	fetchers, err := fetcher.GetFetchers(resourceTypes, configDir, conf.TelegramLogin, validators, offsets)
+	if err != nil {
+		return fmt.Errorf("failed to initialize fetchers with %w", err)
+	}
+	// Fetchers keeping connections between fetches, e.g., Telegram, are closed with the run
+	defer func() {
+		for t, f := range fetchers {
+			if c, ok := f.(io.Closer); ok {
+				if err := c.Close(); err != nil {
+					slog.Warn("failed to close fetcher", "type", t, "error", err)
+				}
+			}
+		}
+	}()
+	return fetchFeeds(ctx, conf, queries, fetchers, includeAll, regenerate)
+}
+
+// fetchFeeds fetches every enabled resource with the fetcher of its type
+// and queues its items. Failed feeds are logged and skipped.
+func fetchFeeds(ctx context.Context, conf config.Config, queries *db.Queries, fetchers map[config.ResourceType]fetcher.FeedFetcher, includeAll, regenerate bool) error {
+	// Fetch configured feeds
+	authCommands := config.NewAuthCommands()
+	var errs []error
+	fetched := 0
+	fetchedAt := time.Now().Unix()
+	for _, resource := range conf.Resources {
+		// Skip disabled resources
+		if !resource.IsEnabled() {
+			slog.Debug("skipping disabled resource", "url", resource.FeedURL)
+			continue
+		}
+
+		// Feeds pushed to the daemon are queued as they are published
+		if !includeAll && !regenerate && pushedToDaemon(ctx, queries, resource.FeedURL) {
+			slog.Info("feed is pushed to the daemon, skipping", "url", resource.FeedURL)
+			continue
+		}
+
+		// Feeds on refused domains are not requested at all
+		if err := httpclient.CheckDomain(resource.FeedURL); err != nil {
+			slog.Info("feed domain is blocked, skipping", "url", resource.FeedURL)
+			continue
+		}
+
+		// Check for cancellation before fetching
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		f := fetchers[resource.T]
+		policy := config.DefaultFetchPolicy().Override(conf.Fetch).Override(resource.Fetch)
+		opts := fetcher.FetchOptions{
+			Header:    resource.Auth.Header(),
+			Proxy:     resource.ProxyURL(conf.Proxy),
+			UserAgent: resource.UserAgentFor(conf.UserAgent),
+			Comments:  resource.Comments,
+			MaxSize:   policy.MaxSizeBytes(),
+			Retry: fetcher.RetryPolicy{
+				Timeout:        policy.Timeout.Duration,
+				MaxRetries:     policy.MaxRetries(),
+				InitialBackoff: policy.Backoff.Duration,
+				MaxBackoff:     policy.MaxBackoff.Duration,
+			},
+		}
+		if resource.AuthCommand != "" {
+			header, err := authCommands.Header(ctx, resource.AuthCommand)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("'%s' auth failed with %w", resource.FeedURL, err))
+				continue
+			}
+			for key, values := range header {
+				opts.Header[key] = values
+			}
+		}
+		if resource.Backfill {
+			opts.Backfill = needsBackfill(ctx, queries, resource.FeedURL)
+		}
+		if opts.Backfill {
+			slog.Info("backfilling feed history", "url", resource.FeedURL)
+		}
+		var feed fetcher.Feed
+		err := recoverPanic(func() error {
+			var err error
+			feed, err = f.Fetch(ctx, resource.FeedURL, opts)
+			return err
+		})
+		if errors.Is(err, fetcher.ErrNotModified) {
+			slog.Info("feed not modified, skipping", "url", resource.FeedURL)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("'%s' fetch failed with %w", resource.FeedURL, err))
+			continue
+		}
+		if resource.Backfill {
+			if err := archiveItems(ctx, queries, resource.FeedURL, slices.Concat(feed.Items, feed.Archive)); err != nil {
+				slog.Warn("failed to archive feed items", "url", resource.FeedURL, "error", err)
+			}
+		}
+		if err := enqueueFeed(ctx, queries, resource.FeedURL, explodeFeed(resource, feed), fetchedAt); err != nil {
+			errs = append(errs, fmt.Errorf("'%s' enqueue failed with %w", resource.FeedURL, err))
+			continue
+		}
+		if !includeAll {
+			saveFetchState(ctx, queries, resource.FeedURL, feed)
+		}
+		fetched++
+	}
+	slog.Info("fetched feeds", "amount", fetched)
+	if len(errs) > 0 {
+		slog.Error("several feeds were not parsed", "feeds", errors.Join(errs...))
+	}
+	return nil
+}
+
+// enqueueFeed persists fetched items until they are processed into an issue.
+// Items already waiting in the queue are kept as they are, items linking to
+// blocked domains are dropped.
+func enqueueFeed(ctx context.Context, queries *db.Queries, url string, feed fetcher.Feed, queuedAt int64) error {
+	for i, item := range feed.Items {
+		if err := httpclient.CheckDomain(item.Link); err != nil {
+			slog.Debug("dropping item of a blocked domain", "feed", url, "item", item.Link)
+			continue
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to encode item '%s' with %w", item.Link, err)
+		}
+		err = queries.EnqueueItem(ctx, db.EnqueueItemParams{
+			FeedUrl:   url,
+			ItemKey:   itemKey(item),
+			FeedTitle: feed.Title,
+			ItemData:  string(data),
+			Position:  int64(i),
+			QueuedAt:  queuedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enqueue item '%s' with %w", item.Link, err)
+		}
+	}
+	return nil
+}
+
+// queuedFeeds returns the queued feed of every resource, indexed like
+// resources, nil for disabled resources and feeds without queued items
+func queuedFeeds(ctx context.Context, queries *db.Queries, resources []config.ResourceConfig) ([]*fetcher.Feed, error) {
+	feeds := make([]*fetcher.Feed, len(resources))
+	for i, resource := range resources {
+		if !resource.IsEnabled() {
+			continue
+		}
+		feed, err := queuedFeed(ctx, queries, resource.FeedURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load queued items of '%s' with %w", resource.FeedURL, err)
+		}
+		feeds[i] = feed
+	}
+	return feeds, nil
+}
+
+// queuedFeed returns the items waiting in the queue as a feed, the latest
+// fetch first, nil if nothing is queued
+func queuedFeed(ctx context.Context, queries *db.Queries, url string) (*fetcher.Feed, error) {
+	queued, err := queries.ListQueuedItems(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if len(queued) == 0 {
+		return nil, nil
+	}
+
+	feed := &fetcher.Feed{Title: queued[0].FeedTitle}
+	for _, q := range queued {
+		var item fetcher.FeedItem
+		if err := json.Unmarshal([]byte(q.ItemData), &item); err != nil {
+			slog.Warn("dropping undecodable queued item", "error", err, "feed", url, "item", q.ItemKey)
+			continue
+		}
+		// Items queued before their domain was blocked
+		if err := httpclient.CheckDomain(item.Link); err != nil {
+			slog.Debug("dropping queued item of a blocked domain", "feed", url, "item", item.Link)
+			continue
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, nil
+}
+
+// pushedToDaemon reports whether the daemon holds an active push lease for the
+// feed, received over WebSub or Telegram updates
+func pushedToDaemon(ctx context.Context, queries *db.Queries, url string) bool {
+	expiresAt, err := queries.GetPushLease(ctx, url)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Warn("failed to load push lease", "error", err, "feed", url)
+		}
+		return false
+	}
+	return time.Now().Unix() < expiresAt
+}
+
+// saveFetchState remembers validators and offsets of a fetched feed, so the
+// next fetch only returns what is not in the queue yet
+func saveFetchState(ctx context.Context, queries *db.Queries, url string, feed fetcher.Feed) {
+	if !feed.Validators.IsZero() {
+		err := queries.SaveFeedValidator(ctx, db.SaveFeedValidatorParams{
+			Url:          url,
+			Etag:         feed.Validators.ETag,
+			LastModified: feed.Validators.LastModified,
+		})
+		if err != nil {
+			slog.Warn("failed to save feed validators", "error", err, "feed", url)
+		}
+	}
+	if feed.Offset > 0 {
+		err := queries.SaveFeedOffset(ctx, db.SaveFeedOffsetParams{
+			Url:       url,
+			MessageID: feed.Offset,
+		})
+		if err != nil {
+			slog.Warn("failed to save feed offset", "error", err, "feed", url)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/httpclient"
+)
+
+func TestQueue_DropsBlockedDomains(t *testing.T) {
+	ctx := context.Background()
+	database, err := initDB(ctx, filepath.Join(t.TempDir(), "myfeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	queries := db.New(database)
+
+	const url = "https://weekly.example/feed"
+	feed := fetcher.Feed{Title: "Weekly", Items: []fetcher.FeedItem{
+		{Title: "Fine", Link: "https://blog.example/post"},
+		{Title: "Refused", Link: "https://www.tabloid.example/gossip"},
+		{Title: "Later refused", Link: "https://paywall.example/story"},
+	}}
+	defer httpclient.ConfigureDomains(httpclient.Domains{})
+	httpclient.ConfigureDomains(httpclient.Domains{Blocked: []string{"tabloid.example"}})
+	if err := enqueueFeed(ctx, queries, url, feed, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items queued before their domain is blocked never reach the issue either
+	httpclient.ConfigureDomains(httpclient.Domains{Blocked: []string{"tabloid.example", "paywall.example"}})
+	queued, err := queuedFeed(ctx, queries, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued.Items) != 1 || queued.Items[0].Title != "Fine" {
+		t.Errorf("expected only the item of an allowed domain, got %+v", queued.Items)
+	}
+}
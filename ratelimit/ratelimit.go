@@ -0,0 +1,244 @@
+// Package ratelimit provides token buckets and concurrency caps for external
+// services shared by every worker of the process.
+package ratelimit
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Well known service keys
+const (
+	Telegram = "telegram"
+	Gemini   = "gemini"
+	YouTube  = "youtube"
+	// Host is the fallback limit for HTTP hosts without their own "host:<name>" entry
+	Host = "host"
+)
+
+// Limit configures a single service.
+// Zero fields disable the respective restriction.
+type Limit struct {
+	Rate        float64 // Requests per second
+	Burst       int     // Requests allowed at once before the rate applies (defaults to 1)
+	Concurrency int     // Maximum requests in flight
+	TokenRate   float64 // Model tokens per second, a minute of them may be spent at once
+}
+
+// Registry holds limiters by service key
+type Registry struct {
+	mu       sync.Mutex
+	limits   map[string]Limit
+	limiters map[string]*limiter
+}
+
+// NewRegistry creates a registry with the given limits by service key
+func NewRegistry(limits map[string]Limit) *Registry {
+	normalized := make(map[string]Limit, len(limits))
+	for key, limit := range limits {
+		normalized[strings.ToLower(key)] = limit
+	}
+	return &Registry{
+		limits:   normalized,
+		limiters: make(map[string]*limiter),
+	}
+}
+
+var defaultRegistry = NewRegistry(nil)
+
+// Configure replaces limits of the process-wide registry
+func Configure(limits map[string]Limit) {
+	defaultRegistry = NewRegistry(limits)
+}
+
+// Acquire waits for the service in the process-wide registry
+func Acquire(ctx context.Context, key string) (release func(), err error) {
+	return defaultRegistry.Acquire(ctx, key)
+}
+
+// Spend accounts model tokens of an answer of the service in the
+// process-wide registry
+func Spend(key string, tokens int) {
+	defaultRegistry.Spend(key, tokens)
+}
+
+// HostKey returns the service key of the URL's host, e.g., "host:example.com"
+func HostKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return Host
+	}
+	return Host + ":" + strings.ToLower(u.Hostname())
+}
+
+// Acquire waits until a request to the service is allowed.
+// The returned release must be called once the request finishes.
+func (r *Registry) Acquire(ctx context.Context, key string) (func(), error) {
+	l := r.limiter(strings.ToLower(key))
+	if l == nil {
+		return func() {}, nil
+	}
+	return l.acquire(ctx)
+}
+
+// Spend accounts model tokens of an answer of the service, requests wait
+// once more tokens were spent than the rate allows
+func (r *Registry) Spend(key string, tokens int) {
+	l := r.limiter(strings.ToLower(key))
+	if l == nil || l.tokenRate <= 0 {
+		return
+	}
+	l.spend(float64(tokens))
+}
+
+// limiter returns the limiter for the key creating it on first use.
+// Hosts without their own limit share the configuration, not the bucket, of "host".
+func (r *Registry) limiter(key string) *limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l, ok := r.limiters[key]; ok {
+		return l
+	}
+	limit, ok := r.limits[key]
+	if !ok && strings.HasPrefix(key, Host+":") {
+		limit, ok = r.limits[Host]
+	}
+	if !ok || (limit.Rate <= 0 && limit.Concurrency <= 0 && limit.TokenRate <= 0) {
+		r.limiters[key] = nil
+		return nil
+	}
+	l := newLimiter(limit)
+	r.limiters[key] = l
+	return l
+}
+
+type limiter struct {
+	rate  float64
+	burst float64
+	slots chan struct{}
+
+	tokenRate  float64 // Model tokens per second
+	tokenBurst float64
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	budget  float64 // Model tokens left, negative when overspent
+	updated time.Time
+}
+
+func newLimiter(limit Limit) *limiter {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	l := &limiter{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+
+		tokenRate:  limit.TokenRate,
+		tokenBurst: limit.TokenRate * 60,
+		budget:     limit.TokenRate * 60,
+		updated:    time.Now(),
+	}
+	if limit.Concurrency > 0 {
+		l.slots = make(chan struct{}, limit.Concurrency)
+	}
+	return l
+}
+
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if err := l.waitBudget(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// wait takes a token from the bucket, sleeping until one is available
+func (l *limiter) wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// waitBudget sleeps until overspent model tokens are refilled
+func (l *limiter) waitBudget(ctx context.Context) error {
+	if l.tokenRate <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		l.refill()
+		if l.budget > 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.budget) / l.tokenRate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// spend takes model tokens from the budget, it may become negative since
+// tokens of an answer are known only once it is done
+func (l *limiter) spend(tokens float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.budget -= tokens
+}
+
+// refill adds the model tokens of the time since the last update, l.mu must be held
+func (l *limiter) refill() {
+	now := time.Now()
+	l.budget = min(l.tokenBurst, l.budget+now.Sub(l.updated).Seconds()*l.tokenRate)
+	l.updated = now
+}
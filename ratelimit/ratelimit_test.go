@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Rate(t *testing.T) {
+	r := NewRegistry(map[string]Limit{Gemini: {Rate: 20, Burst: 2}})
+
+	start := time.Now()
+	for range 4 {
+		release, err := r.Acquire(context.Background(), Gemini)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	// Two requests fit the burst, two more wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected requests to be throttled, took %v", elapsed)
+	}
+}
+
+func TestRegistry_Concurrency(t *testing.T) {
+	r := NewRegistry(map[string]Limit{Telegram: {Concurrency: 2}})
+
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := r.Acquire(context.Background(), Telegram)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 requests in flight, got %d", peak.Load())
+	}
+}
+
+func TestRegistry_HostFallback(t *testing.T) {
+	r := NewRegistry(map[string]Limit{
+		Host:                    {Concurrency: 1},
+		"host:fast.example.com": {},
+	})
+	if r.limiter(HostKey("https://slow.example.com/feed")) == nil {
+		t.Error("expected default host limit to apply")
+	}
+	if r.limiter(HostKey("https://fast.example.com/feed")) != nil {
+		t.Error("expected explicit empty limit to disable limiting")
+	}
+	if r.limiter(YouTube) != nil {
+		t.Error("expected unconfigured service to be unlimited")
+	}
+}
+
+func TestRegistry_CancelledWait(t *testing.T) {
+	r := NewRegistry(map[string]Limit{Gemini: {Rate: 0.001}})
+	release, _ := r.Acquire(context.Background(), Gemini)
+	release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.Acquire(ctx, Gemini); err == nil {
+		t.Error("expected context error")
+	}
+}
+
+func TestRegistry_TokenBudget(t *testing.T) {
+	r := NewRegistry(map[string]Limit{Gemini: {TokenRate: 1000}})
+
+	release, err := r.Acquire(context.Background(), Gemini)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	// A minute of tokens is spent at once, the overspent 50 refill in 50ms
+	r.Spend(Gemini, 60_050)
+
+	start := time.Now()
+	release, err = r.Acquire(context.Background(), Gemini)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected the request to wait for the budget, took %v", elapsed)
+	}
+
+	// Services without a token limit ignore spending
+	r.Spend(Telegram, 1_000_000)
+}
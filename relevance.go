@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/scipunch/myfeed/agent"
+	"github.com/scipunch/myfeed/cache"
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/parser"
+)
+
+// scoreRelevance scores the content of an item against the interests, out of
+// agent.MaxScore. Scores are cached until the interests change.
+func scoreRelevance(ctx context.Context, cacheDB *cache.Cache, relevanceAgent agent.Agent, item fetcher.FeedItem, resource config.ResourceConfig, interests []string, content string, parsed parser.Response) (int, error) {
+	pipeline := resource.RelevancePipeline(interests)
+	if cached, hit, err := cacheDB.GetAgentOutput(item.Link, resource.ParserCacheKey(), pipeline); err == nil && hit {
+		slog.Debug("relevance cache hit", "url", item.Link)
+		return agent.ParseScore(cached)
+	}
+	if relevanceAgent == nil {
+		return 0, fmt.Errorf("agent '%s' not found", agent.Relevance)
+	}
+
+	// The score is a number, it has no language to check
+	opts := agentOptions(resource, parsed)
+	opts.Language = ""
+	answer, err := relevanceAgent.Process(ctx, content, opts)
+	if err != nil {
+		return 0, err
+	}
+	score, err := agent.ParseScore(answer)
+	if err != nil {
+		return 0, err
+	}
+	slog.Info("item scored", "url", item.Link, "score", score, "min_score", resource.MinScore)
+
+	if err := cacheDB.SetAgentOutput(item.Link, resource.ParserCacheKey(), pipeline, strconv.Itoa(score)); err != nil {
+		slog.Warn("failed to cache relevance score", "error", err)
+	}
+	return score, nil
+}
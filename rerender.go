@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+)
+
+// renderIssueHTML renders the HTML of the issue at htmlPath, items over the
+// size limits are moved into the returned appendix
+func renderIssueHTML(t *template.Template, conf config.Config, newsletter Newsletter, htmlPath string) (Newsletter, Newsletter, error) {
+	// Enforce issue size limits, overflow goes into an appendix issue
+	issue, appendix := splitByLimits(newsletter, conf.Limits)
+	if appendix.pageCount() > 0 {
+		slog.Warn("issue size limits exceeded, moving overflow into appendix",
+			"kept", issue.pageCount(),
+			"moved", appendix.pageCount())
+	}
+	err := renderHTML(t, htmlPath, groupPages(issue, conf.GroupBy, time.Local))
+	return issue, appendix, err
+}
+
+// renderIssuePDF renders the PDF of the issue whose HTML is rendered and
+// the appendix, if any. Items overflowing max_pages are moved into the
+// appendix, the issue is returned as it was rendered.
+func renderIssuePDF(ctx context.Context, t *template.Template, conf config.Config, issue, appendix Newsletter, outputPath, fileName string) Newsletter {
+	htmlPath := path.Join(outputPath, fileName+".html")
+	pdfPath := path.Join(outputPath, fileName+".pdf")
+
+	// Generate PDF report
+	overflowIDs, err := generatePDF(ctx, htmlPath, pdfPath, conf.Limits.MaxPages)
+	if err != nil {
+		slog.Error("failed to generate PDF", "error", err)
+	} else {
+		slog.Info("PDF file generated", "path", pdfPath)
+	}
+
+	// Keep the HTML in sync with the PDF when pages overflowed
+	if len(overflowIDs) > 0 {
+		slog.Warn("issue exceeds max pages, moving overflow into appendix",
+			"max_pages", conf.Limits.MaxPages,
+			"moved", len(overflowIDs))
+		issue, appendix = moveToAppendix(issue, appendix, overflowIDs)
+		if err := renderHTML(t, htmlPath, groupPages(issue, conf.GroupBy, time.Local)); err != nil {
+			slog.Error("failed to regenerate HTML", "error", err)
+		}
+	}
+
+	// Generate appendix for everything that did not fit
+	if appendix.pageCount() > 0 {
+		renderAppendix(ctx, t, conf, appendix, outputPath, fileName)
+	}
+	return issue
+}
+
+// renderAppendix renders the HTML and PDF of the appendix within the limits
+// of the issue. Items overflowing the appendix go into further parts, e.g.,
+// _appendix_2.html, so no item is left out.
+func renderAppendix(ctx context.Context, t *template.Template, conf config.Config, appendix Newsletter, outputPath, fileName string) {
+	title := appendix.Title
+	rest := appendix
+	for part := 1; rest.pageCount() > 0; part++ {
+		suffix := "_appendix"
+		if part > 1 {
+			suffix = fmt.Sprintf("_appendix_%d", part)
+		}
+		htmlPath := path.Join(outputPath, fileName+suffix+".html")
+		pdfPath := path.Join(outputPath, fileName+suffix+".pdf")
+
+		// Every part keeps at least one item, so the loop ends
+		var current Newsletter
+		current, rest = splitByLimits(rest, conf.Limits)
+		current.Title = title
+		if part > 1 {
+			current.Title = fmt.Sprintf("%s %d", title, part)
+		}
+		if err := renderHTML(t, htmlPath, groupPages(current, conf.GroupBy, time.Local)); err != nil {
+			slog.Error("could not generate appendix HTML file", "error", err, "left_out", current.pageCount()+rest.pageCount())
+			return
+		}
+		slog.Info("appendix HTML file generated", "path", htmlPath)
+
+		overflowIDs, err := generatePDF(ctx, htmlPath, pdfPath, conf.Limits.MaxPages)
+		if err != nil {
+			slog.Error("failed to generate appendix PDF", "error", err)
+		} else {
+			slog.Info("appendix PDF file generated", "path", pdfPath)
+		}
+		if len(overflowIDs) > 0 {
+			current, rest = moveToAppendix(current, rest, overflowIDs)
+			if err := renderHTML(t, htmlPath, groupPages(current, conf.GroupBy, time.Local)); err != nil {
+				slog.Error("failed to regenerate appendix HTML", "error", err)
+			}
+		}
+		if rest.pageCount() > 0 {
+			slog.Warn("appendix exceeds the size limits, moving overflow into the next part", "moved", rest.pageCount())
+		}
+	}
+}
+
+// saveIssue stores the newsletter of the run before it is split and
+// rendered, so `myfeed rerender` can render it again
+func saveIssue(ctx context.Context, queries *db.Queries, newsletter Newsletter, outputPath, fileName string) {
+	data, err := json.Marshal(newsletter)
+	if err != nil {
+		slog.Warn("failed to encode issue, it can't be rendered again", "error", err)
+		return
+	}
+	_, err = queries.SaveIssue(ctx, db.SaveIssueParams{
+		Title:      newsletter.Title,
+		OutputPath: outputPath,
+		FileName:   fileName,
+		Newsletter: string(data),
+		CreatedAt:  time.Now().Unix(),
+	})
+	if err != nil {
+		slog.Warn("failed to save issue, it can't be rendered again", "error", err)
+	}
+}
+
+// loadIssue loads the stored issue of a run by its ID or "latest"
+func loadIssue(ctx context.Context, queries *db.Queries, run string) (db.Issue, Newsletter, error) {
+	var stored db.Issue
+	var err error
+	if run == "latest" {
+		stored, err = queries.GetLatestIssue(ctx)
+	} else {
+		id, parseErr := strconv.ParseInt(run, 10, 64)
+		if parseErr != nil {
+			return db.Issue{}, Newsletter{}, fmt.Errorf("run must be 'latest' or an issue ID, got '%s'", run)
+		}
+		stored, err = queries.GetIssue(ctx, id)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Issue{}, Newsletter{}, fmt.Errorf("no stored issue for run '%s'", run)
+	}
+	if err != nil {
+		return db.Issue{}, Newsletter{}, fmt.Errorf("failed to load issue with %w", err)
+	}
+
+	var newsletter Newsletter
+	if err := json.Unmarshal([]byte(stored.Newsletter), &newsletter); err != nil {
+		return db.Issue{}, Newsletter{}, fmt.Errorf("failed to decode issue %d with %w", stored.ID, err)
+	}
+	return stored, newsletter, nil
+}
+
+// rerender renders the HTML and PDF files of a stored run again with the
+// current templates and limits, without fetching, parsing or calling agents.
+// PDF is the only format, myfeed doesn't write EPUB.
+func rerender(ctx context.Context, t *template.Template, conf config.Config, queries *db.Queries, run, format string) error {
+	if format != "pdf" {
+		return fmt.Errorf("rerender writes HTML and PDF only, format '%s' is not supported", format)
+	}
+	stored, newsletter, err := loadIssue(ctx, queries, run)
+	if err != nil {
+		return err
+	}
+	slog.Info("rendering stored issue again", "id", stored.ID, "created", time.Unix(stored.CreatedAt, 0), "path", stored.OutputPath)
+
+	if err := os.MkdirAll(stored.OutputPath, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create output directory at '%s' with %w", stored.OutputPath, err)
+	}
+	htmlPath := path.Join(stored.OutputPath, stored.FileName+".html")
+	issue, appendix, err := renderIssueHTML(t, conf, newsletter, htmlPath)
+	if err != nil {
+		return fmt.Errorf("could not generate newsletter HTML file: %w", err)
+	}
+	slog.Info("HTML file generated", "path", htmlPath)
+
+	renderIssuePDF(ctx, t, conf, issue, appendix, stored.OutputPath, stored.FileName)
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+)
+
+func TestRerender_StoredIssue(t *testing.T) {
+	ctx := context.Background()
+	database, err := initDB(ctx, filepath.Join(t.TempDir(), "myfeed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	queries := db.New(database)
+
+	if _, _, err := loadIssue(ctx, queries, "latest"); err == nil || !strings.Contains(err.Error(), "no stored issue") {
+		t.Errorf("expected no issue before the first run, got %v", err)
+	}
+
+	outputPath := t.TempDir()
+	newsletter := Newsletter{Title: "Issue", Resources: []Resource{{Name: "Blog A", Pages: []Page{{Title: "Post", Link: "https://a.example/1", Content: "<p>Text</p>", ID: "a1", Starred: true}}}}}
+	saveIssue(ctx, queries, newsletter, outputPath, "myfeed_2026_01_01")
+	saveIssue(ctx, queries, Newsletter{Title: "Later"}, outputPath, "myfeed_2026_01_02")
+
+	stored, loaded, err := loadIssue(ctx, queries, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.FileName != "myfeed_2026_01_01" || loaded.Resources[0].Pages[0].Link != "https://a.example/1" || !loaded.Resources[0].Pages[0].Starred {
+		t.Errorf("expected the stored issue back, got %+v", loaded)
+	}
+	if _, latest, _ := loadIssue(ctx, queries, "latest"); latest.Title != "Later" {
+		t.Errorf("expected the latest issue, got %q", latest.Title)
+	}
+	if _, _, err := loadIssue(ctx, queries, "yesterday"); err == nil {
+		t.Error("expected an invalid run to fail")
+	}
+	if err := rerender(ctx, nil, config.Config{}, queries, "1", "epub"); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected EPUB to be refused, got %v", err)
+	}
+
+	// The HTML is rendered with the current templates
+	tmpl := template.Must(loadTemplates(""))
+	template.Must(tmpl.New("index").Parse(`{{range .Resources}}{{range .Pages}}<h2>{{.Title}}</h2>{{end}}{{end}}`))
+	htmlPath := filepath.Join(outputPath, stored.FileName+".html")
+	if _, _, err := renderIssueHTML(tmpl, config.Config{}, loaded, htmlPath); err != nil {
+		t.Fatal(err)
+	}
+	html, err := os.ReadFile(htmlPath)
+	if err != nil || string(html) != "<h2>Post</h2>" {
+		t.Errorf("expected the changed template rendered, got %q, %v", html, err)
+	}
+}
@@ -0,0 +1,373 @@
+package route
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// kind is the type of a value of an expression
+type kind int
+
+const (
+	kindString kind = iota
+	kindNumber
+	kindBool
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindString:
+		return "string"
+	case kindNumber:
+		return "number"
+	}
+	return "bool"
+}
+
+// Expr is a compiled expression on the fields of an item. Strings are
+// compared with ==, !=, contains (case-insensitive) and matches (regular
+// expression), numbers with ==, !=, <, <=, > and >=, conditions are
+// combined with &&, || and ! and grouped with parentheses.
+type Expr struct {
+	root node
+}
+
+// Compile parses the expression, failing on unknown fields and operands of
+// the wrong type
+func Compile(src string) (*Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	if root.kind() != kindBool {
+		return nil, fmt.Errorf("expression is a %s, expected a condition", root.kind())
+	}
+	return &Expr{root: root}, nil
+}
+
+// Eval reports whether the fields match the expression
+func (e *Expr) Eval(fields map[string]any) bool {
+	v, _ := e.root.eval(fields).(bool)
+	return v
+}
+
+type node interface {
+	eval(fields map[string]any) any
+	kind() kind
+}
+
+type fieldNode struct {
+	name string
+	k    kind
+}
+
+func (n fieldNode) eval(fields map[string]any) any { return fields[n.name] }
+func (n fieldNode) kind() kind                     { return n.k }
+
+type literalNode struct {
+	v any
+	k kind
+}
+
+func (n literalNode) eval(map[string]any) any { return n.v }
+func (n literalNode) kind() kind              { return n.k }
+
+type notNode struct{ x node }
+
+func (n notNode) eval(fields map[string]any) any {
+	v, _ := n.x.eval(fields).(bool)
+	return !v
+}
+func (n notNode) kind() kind { return kindBool }
+
+type logicalNode struct {
+	and  bool
+	l, r node
+}
+
+func (n logicalNode) eval(fields map[string]any) any {
+	l, _ := n.l.eval(fields).(bool)
+	if l != n.and {
+		return l
+	}
+	r, _ := n.r.eval(fields).(bool)
+	return r
+}
+func (n logicalNode) kind() kind { return kindBool }
+
+type compareNode struct {
+	op   string
+	l, r node
+}
+
+func (n compareNode) eval(fields map[string]any) any {
+	l, r := n.l.eval(fields), n.r.eval(fields)
+	switch n.op {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	case "contains":
+		ls, _ := l.(string)
+		rs, _ := r.(string)
+		return strings.Contains(strings.ToLower(ls), strings.ToLower(rs))
+	}
+	ln, _ := l.(float64)
+	rn, _ := r.(float64)
+	switch n.op {
+	case "<":
+		return ln < rn
+	case "<=":
+		return ln <= rn
+	case ">":
+		return ln > rn
+	}
+	return ln >= rn
+}
+func (n compareNode) kind() kind { return kindBool }
+
+type matchNode struct {
+	x  node
+	re *regexp.Regexp
+}
+
+func (n matchNode) eval(fields map[string]any) any {
+	s, _ := n.x.eval(fields).(string)
+	return n.re.MatchString(s)
+}
+func (n matchNode) kind() kind { return kindBool }
+
+// exprParser is a recursive descent parser of the tokens of an expression
+type exprParser struct {
+	tokens []token
+	i      int
+}
+
+func (p *exprParser) peek() token { return p.tokens[p.i] }
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *exprParser) or() (node, error) {
+	return p.logical("||", false, p.and)
+}
+
+func (p *exprParser) and() (node, error) {
+	return p.logical("&&", true, p.unary)
+}
+
+func (p *exprParser) logical(op string, and bool, operand func() (node, error)) (node, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is(op) {
+		t := p.next()
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		if l.kind() != kindBool || r.kind() != kindBool {
+			return nil, fmt.Errorf("%s at %d combines conditions, got a %s and a %s", op, t.pos, l.kind(), r.kind())
+		}
+		l = logicalNode{and: and, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *exprParser) unary() (node, error) {
+	if p.peek().is("!") {
+		t := p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if x.kind() != kindBool {
+			return nil, fmt.Errorf("! at %d negates conditions, got a %s", t.pos, x.kind())
+		}
+		return notNode{x: x}, nil
+	}
+	return p.comparison()
+}
+
+func (p *exprParser) comparison() (node, error) {
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.is("matches"):
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokString {
+			return nil, fmt.Errorf("matches at %d needs a quoted regular expression", t.pos)
+		}
+		if l.kind() != kindString {
+			return nil, fmt.Errorf("matches at %d needs a string, got a %s", t.pos, l.kind())
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at %d: %w", pattern.pos, err)
+		}
+		return matchNode{x: l, re: re}, nil
+	case t.is("contains"), t.is("=="), t.is("!="), t.is("<"), t.is("<="), t.is(">"), t.is(">="):
+		p.next()
+		r, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if l.kind() != r.kind() {
+			return nil, fmt.Errorf("%s at %d compares a %s to a %s", t.text, t.pos, l.kind(), r.kind())
+		}
+		if t.is("contains") && l.kind() != kindString {
+			return nil, fmt.Errorf("contains at %d needs strings, got a %s", t.pos, l.kind())
+		}
+		if t.text[0] == '<' || t.text[0] == '>' {
+			if l.kind() != kindNumber {
+				return nil, fmt.Errorf("%s at %d needs numbers, got a %s", t.text, t.pos, l.kind())
+			}
+		}
+		return compareNode{op: t.text, l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+func (p *exprParser) operand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return literalNode{v: t.text, k: kindString}, nil
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return literalNode{v: n, k: kindNumber}, nil
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return literalNode{v: t.text == "true", k: kindBool}, nil
+		}
+		k, ok := kinds[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q at %d", t.text, t.pos)
+		}
+		return fieldNode{name: t.text, k: k}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	if t.is("(") {
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next().is(")") {
+			return nil, fmt.Errorf("( at %d is never closed", t.pos)
+		}
+		return x, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOperator
+)
+
+// token is a lexeme of an expression at the byte offset pos
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// is reports whether the token is the operator or keyword
+func (t token) is(op string) bool {
+	return (t.kind == tokOperator || t.kind == tokIdent) && t.text == op
+}
+
+// operators are sorted so longer ones are tried first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != src[i] {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("string at %d is never closed", i)
+			}
+			text := src[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(src[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid string at %d: %w", i, err)
+				}
+				text = unquoted
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: i})
+			i = end + 1
+		case unicode.IsDigit(c):
+			end := i
+			for end < len(src) && (unicode.IsDigit(rune(src[end])) || src[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[i:end], pos: i})
+			i = end
+		case unicode.IsLetter(c) || c == '_':
+			end := i
+			for end < len(src) && (unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end])) || src[end] == '_') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
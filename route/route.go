@@ -0,0 +1,94 @@
+// Package route evaluates the routes of a resource, sending its items to
+// another parser, agents or category, or dropping them, by expressions on
+// item fields, e.g., `link contains "youtube.com" && words < 50`
+package route
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/fetcher/types"
+	"github.com/scipunch/myfeed/urlnorm"
+)
+
+// Router picks the route of an item among the routes of a resource
+type Router struct {
+	exprs []*Expr
+}
+
+// New compiles the expressions of the routes
+func New(routes []config.Route) (*Router, error) {
+	r := &Router{}
+	for i, route := range routes {
+		expr, err := Compile(route.When)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i+1, err)
+		}
+		r.exprs = append(r.exprs, expr)
+	}
+	return r, nil
+}
+
+// Match returns the index of the first route the item matches, false when
+// it matches none
+func (r *Router) Match(item types.FeedItem) (int, bool) {
+	if r == nil || len(r.exprs) == 0 {
+		return 0, false
+	}
+	fields := Fields(item)
+	for i, expr := range r.exprs {
+		if expr.Eval(fields) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Fields returns the values of the item expressions refer to by name
+func Fields(item types.FeedItem) map[string]any {
+	fields := map[string]any{
+		"title":     item.Title,
+		"link":      item.Link,
+		"domain":    urlnorm.Host(item.Link),
+		"text":      item.Description,
+		"words":     float64(countWords(item.Description)),
+		"language":  item.Language,
+		"media":     float64(len(item.Media)),
+		"comments":  float64(len(item.Comments)),
+		"forwarded": item.Forward != nil,
+		"views":     0.0,
+		"forwards":  0.0,
+		"reactions": 0.0,
+	}
+	if e := item.Engagement; e != nil {
+		fields["views"] = float64(e.Views)
+		fields["forwards"] = float64(e.Forwards)
+		fields["reactions"] = float64(e.Reactions)
+	}
+	return fields
+}
+
+// kinds are the types of the fields, checked when an expression is compiled
+var kinds = map[string]kind{
+	"title":     kindString,
+	"link":      kindString,
+	"domain":    kindString,
+	"text":      kindString,
+	"words":     kindNumber,
+	"language":  kindString,
+	"media":     kindNumber,
+	"comments":  kindNumber,
+	"forwarded": kindBool,
+	"views":     kindNumber,
+	"forwards":  kindNumber,
+	"reactions": kindNumber,
+}
+
+// countWords counts runs of letters and digits
+func countWords(text string) int {
+	return len(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
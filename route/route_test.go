@@ -0,0 +1,82 @@
+package route
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/fetcher/types"
+)
+
+func TestExpr(t *testing.T) {
+	item := types.FeedItem{
+		Title:       "Weekly Roundup",
+		Link:        "https://www.youtube.com/watch?v=1",
+		Description: "A short video",
+		Engagement:  &types.Engagement{Views: 1200},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`link contains "youtube.com" && words < 50`, true},
+		{`link contains "YouTube.com" && words >= 50`, false},
+		{`domain == "youtube.com"`, true},
+		{`title matches '^weekly' || title matches '(?i)^weekly'`, true},
+		{`!(views > 1000)`, false},
+		{`forwarded || reactions != 0`, false},
+		{`language == "" && media == 0`, true},
+		{`true`, true},
+	}
+	fields := Fields(item)
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Eval(fields); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := map[string]string{
+		`url contains "x"`:   "unknown field",
+		`words contains "5"`: "compares a number to a string",
+		`title < "b"`:        "needs numbers",
+		`title`:              "expected a condition",
+		`title matches "("`:  "invalid regular expression",
+		`(words > 1`:         "never closed",
+		`title == "x`:        "never closed",
+		`words > 1 &&`:       "unexpected end",
+		`words > 1 title`:    "unexpected",
+		`words # 1`:          "unexpected",
+	}
+	for src, want := range tests {
+		_, err := Compile(src)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%q) = %v, want an error containing %q", src, err, want)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	r, err := New([]config.Route{
+		{When: `domain == "youtube.com"`, Parser: "youtube"},
+		{When: `link contains "youtube"`, Drop: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, ok := r.Match(types.FeedItem{Link: "https://youtube.com/watch?v=1"}); !ok || i != 0 {
+		t.Errorf("expected the first matching route, got %d, %v", i, ok)
+	}
+	if _, ok := r.Match(types.FeedItem{Link: "https://example.com"}); ok {
+		t.Error("expected no route to match")
+	}
+	if _, err := New([]config.Route{{When: `words <`}}); err == nil || !strings.HasPrefix(err.Error(), "route 1:") {
+		t.Errorf("expected the invalid route named, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Package sanitize strips active content from HTML of feeds, pages and agents
+// before it is templated into the issue, so nothing in it runs while Chromium
+// renders the PDF or a reader opens the HTML issue.
+package sanitize
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// dropped elements are removed together with their content, SVG animations
+// and references are among them as they can set links to scripts
+var dropped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"iframe": true, "frame": true, "frameset": true, "object": true, "embed": true, "applet": true, "portal": true,
+	"base": true, "link": true, "meta": true,
+	"form": true, "input": true, "button": true, "select": true, "textarea": true,
+	"foreignobject": true, "use": true, "animate": true, "animatemotion": true, "animatetransform": true, "set": true,
+}
+
+// removedAttrs run or submit something on their own
+var removedAttrs = map[string]bool{
+	"srcdoc":     true,
+	"formaction": true,
+	"action":     true,
+	"ping":       true,
+	"http-equiv": true,
+}
+
+// urlAttrs hold a single link, e.g., href
+var urlAttrs = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"poster":     true,
+	"cite":       true,
+	"background": true,
+	"longdesc":   true,
+	"lowsrc":     true,
+	"data":       true,
+}
+
+// schemes allowed in links, relative links have none
+var schemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"tel":    true,
+}
+
+// HTML removes scripts, frames, embedded objects, forms, event handlers and
+// links with scripting schemes from the fragment. Fragments without any of
+// them are returned as they are.
+func HTML(fragment string) string {
+	if !strings.Contains(fragment, "<") {
+		return fragment
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), body)
+	if err != nil {
+		return html.EscapeString(fragment)
+	}
+
+	changed := false
+	var kept []*html.Node
+	for _, n := range nodes {
+		if clean(n) {
+			changed = true
+		}
+		if n.Type == html.ElementNode && dropped[strings.ToLower(n.Data)] {
+			changed = true
+			continue
+		}
+		kept = append(kept, n)
+	}
+	if !changed {
+		return fragment
+	}
+
+	var b strings.Builder
+	for _, n := range kept {
+		if err := html.Render(&b, n); err != nil {
+			return html.EscapeString(fragment)
+		}
+	}
+	return b.String()
+}
+
+// clean removes unsafe descendants and attributes of n, reporting whether
+// anything was removed
+func clean(n *html.Node) bool {
+	changed := false
+	if n.Type == html.ElementNode {
+		attrs := n.Attr[:0]
+		for _, a := range n.Attr {
+			if safeAttr(a) {
+				attrs = append(attrs, a)
+			} else {
+				changed = true
+			}
+		}
+		n.Attr = attrs
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.ElementNode && dropped[strings.ToLower(c.Data)] {
+			n.RemoveChild(c)
+			changed = true
+		} else if clean(c) {
+			changed = true
+		}
+		c = next
+	}
+	return changed
+}
+
+func safeAttr(a html.Attribute) bool {
+	key := strings.ToLower(a.Key)
+	switch {
+	case strings.HasPrefix(key, "on"), removedAttrs[key]:
+		return false
+	case urlAttrs[key]:
+		return safeURL(a.Val, key == "src" || key == "poster")
+	case key == "srcset":
+		for _, candidate := range strings.Split(a.Val, ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 && !safeURL(fields[0], true) {
+				return false
+			}
+		}
+		return true
+	case key == "style":
+		style := strings.ToLower(normalize(a.Val))
+		for _, active := range []string{"javascript:", "vbscript:", "expression(", "-moz-binding", "behavior:"} {
+			if strings.Contains(style, active) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// Link returns the link of an item if it is an http or https address, ""
+// otherwise, e.g., for "javascript:alert(1)" taken from a feed
+func Link(link string) string {
+	scheme, _, ok := strings.Cut(strings.ToLower(normalize(link)), ":")
+	if !ok || (scheme != "http" && scheme != "https") {
+		return ""
+	}
+	return link
+}
+
+// safeURL reports whether the link has no scheme or an allowed one, images
+// may be inlined as data URLs
+func safeURL(link string, image bool) bool {
+	link = strings.ToLower(normalize(link))
+	i := strings.IndexAny(link, ":/?#")
+	if i < 0 || link[i] != ':' {
+		return true
+	}
+	scheme := link[:i]
+	return schemes[scheme] || image && scheme == "data" && strings.HasPrefix(link, "data:image/")
+}
+
+// normalize removes whitespace and control characters, which browsers
+// ignore in schemes, e.g., "java\tscript:"
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
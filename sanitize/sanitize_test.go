@@ -0,0 +1,70 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "clean fragments are kept as they are",
+			html: `<p>Some <a href="https://example.com/a?b=1&c=2">link</a><br></p><img src="media/a.png" alt="">`,
+			want: `<p>Some <a href="https://example.com/a?b=1&c=2">link</a><br></p><img src="media/a.png" alt="">`,
+		},
+		{
+			name: "scripts, styles and frames",
+			html: `<p>Text</p><script>alert(1)</script><style>p{}</style><div><iframe src="https://x"></iframe>kept</div>`,
+			want: `<p>Text</p><div>kept</div>`,
+		},
+		{
+			name: "event handlers",
+			html: `<img src="a.png" onerror="alert(1)" ONLOAD="x()"><details open="">x</details>`,
+			want: `<img src="a.png"/><details open="">x</details>`,
+		},
+		{
+			name: "scripting links",
+			html: `<a href=" java&#x09;script:alert(1)">a</a><a href="mailto:me@example.com">b</a><img src="data:image/png;base64,AA"><a href="data:text/html,x">c</a>`,
+			want: `<a>a</a><a href="mailto:me@example.com">b</a><img src="data:image/png;base64,AA"/><a>c</a>`,
+		},
+		{
+			name: "styles and forms",
+			html: `<p style="color:red">a</p><p style="background:url(javascript:x)">b</p><form action="/x"><input name="q"></form>`,
+			want: `<p style="color:red">a</p><p>b</p>`,
+		},
+		{
+			name: "svg",
+			html: `<svg><path d="M0 0"></path><script>x()</script><a href="javascript:x()"><text>t</text></a></svg>`,
+			want: `<svg><path d="M0 0"></path><a><text>t</text></a></svg>`,
+		},
+		{
+			name: "plain text",
+			html: "no markup & more",
+			want: "no markup & more",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.html); got != tt.want {
+				t.Errorf("HTML() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLink(t *testing.T) {
+	for link, want := range map[string]string{
+		"https://example.com/a?b=1": "https://example.com/a?b=1",
+		"HTTP://example.com":        "HTTP://example.com",
+		"javascript:alert(1)":       "",
+		" java\tscript:alert(1)":    "",
+		"data:text/html,x":          "",
+		"/relative":                 "",
+		"":                          "",
+	} {
+		if got := Link(link); got != want {
+			t.Errorf("Link(%q) = %q, want %q", link, got, want)
+		}
+	}
+}
@@ -46,3 +46,145 @@ CREATE TABLE IF NOT EXISTS agent_cache (
 CREATE INDEX IF NOT EXISTS idx_agent_cache_lookup ON agent_cache(url, parser_type, agent_pipeline);
 
 CREATE INDEX IF NOT EXISTS idx_agent_cache_accessed ON agent_cache(accessed_at);
+
+-- Processed items: canonical URL index of items included in past generations
+CREATE TABLE IF NOT EXISTS processed_item (
+    canonical_url TEXT PRIMARY KEY,
+    url TEXT NOT NULL,
+    title TEXT NOT NULL,
+    processed_at INTEGER NOT NULL
+);
+
+-- Feed validators: HTTP cache validators for conditional GET of feeds
+CREATE TABLE IF NOT EXISTS feed_validator (
+    url TEXT PRIMARY KEY,
+    etag TEXT NOT NULL,
+    last_modified TEXT NOT NULL
+);
+
+-- Queue: fetched items waiting to be processed into an issue
+CREATE TABLE IF NOT EXISTS queue_item (
+    feed_url TEXT NOT NULL,
+    item_key TEXT NOT NULL,
+    feed_title TEXT NOT NULL,
+    item_data TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    queued_at INTEGER NOT NULL,
+    PRIMARY KEY (feed_url, item_key)
+);
+
+-- Deferred items: queued items skipped because a provider kept failing,
+-- the next run processes them even when they are older than its high-water mark
+CREATE TABLE IF NOT EXISTS deferred_item (
+    feed_url TEXT NOT NULL,
+    item_key TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    deferred_at INTEGER NOT NULL,
+    PRIMARY KEY (feed_url, item_key)
+);
+
+-- Feed offsets: highest Telegram message ID fetched per channel
+CREATE TABLE IF NOT EXISTS feed_offset (
+    url TEXT PRIMARY KEY,
+    message_id INTEGER NOT NULL
+);
+
+-- Archive items: full history of feeds with backfill enabled
+CREATE TABLE IF NOT EXISTS archive_item (
+    feed_url TEXT NOT NULL,
+    guid TEXT NOT NULL,
+    title TEXT NOT NULL,
+    link TEXT NOT NULL,
+    description TEXT NOT NULL,
+    published_at INTEGER NOT NULL,
+    archived_at INTEGER NOT NULL,
+    PRIMARY KEY (feed_url, guid)
+);
+
+-- High-water marks: newest item of every resource at each generation
+CREATE TABLE IF NOT EXISTS high_water_mark (
+    feed_url TEXT NOT NULL,
+    guid TEXT NOT NULL,
+    published_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (feed_url, created_at)
+);
+
+-- Sent emails: delivery log of issues sent over SMTP
+CREATE TABLE IF NOT EXISTS sent_email (
+    message_id TEXT PRIMARY KEY,
+    subject TEXT NOT NULL,
+    recipients TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    attempts INTEGER NOT NULL,
+    error TEXT NOT NULL,
+    sent_at INTEGER NOT NULL
+);
+
+-- Push leases: feeds pushed to the daemon by a WebSub hub or Telegram updates, not polled until the lease expires
+CREATE TABLE IF NOT EXISTS push_lease (
+    feed_url TEXT PRIMARY KEY,
+    expires_at INTEGER NOT NULL
+);
+
+-- Drafts: issues of the daemon held back until they are approved, rejected or auto-approved
+CREATE TABLE IF NOT EXISTS draft (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    issue_path TEXT NOT NULL,
+    status TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    decided_at INTEGER NOT NULL
+);
+
+-- Draft emails: rendered messages of a draft, sent once it is approved
+CREATE TABLE IF NOT EXISTS draft_email (
+    draft_id INTEGER NOT NULL,
+    part INTEGER NOT NULL,
+    recipients TEXT NOT NULL,
+    message TEXT NOT NULL,
+    PRIMARY KEY (draft_id, part)
+);
+
+-- Reading events: links clicked in issues served by the daemon and items starred while curating, for source suggestions
+CREATE TABLE IF NOT EXISTS reading_event (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    url TEXT NOT NULL,
+    item_url TEXT NOT NULL,
+    author TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+-- Pins: notes and links pinned into the next issue with `myfeed pin` or the dashboard
+CREATE TABLE IF NOT EXISTS pin (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    text TEXT NOT NULL,
+    section TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    issued_at INTEGER NOT NULL
+);
+
+-- Agent metrics: answers of agents per run and model, to notice quality drift
+CREATE TABLE IF NOT EXISTS agent_metric (
+    agent TEXT NOT NULL,
+    model TEXT NOT NULL,
+    calls INTEGER NOT NULL,
+    input_chars INTEGER NOT NULL,
+    output_chars INTEGER NOT NULL,
+    language_mismatches INTEGER NOT NULL,
+    contract_violations INTEGER NOT NULL,
+    failures INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (agent, model, created_at)
+);
+
+-- Issues: newsletters of runs before rendering, to render them again with `myfeed rerender`
+CREATE TABLE IF NOT EXISTS issue (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    output_path TEXT NOT NULL,
+    file_name TEXT NOT NULL,
+    newsletter TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
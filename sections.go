@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// groupPages regroups the issue into sections by publication time in loc,
+// ordered chronologically with undated pages last. Pages keep the feed they
+// came from as their source, pinned sections stay first as they are. Issues
+// grouped by resource are returned as is.
+func groupPages(n Newsletter, by config.GroupBy, loc *time.Location) Newsletter {
+	if by != config.GroupByTimeOfDay && by != config.GroupByDay {
+		return n
+	}
+
+	var pinned []Resource
+	var pages []Page
+	for _, res := range n.Resources {
+		if res.Pinned {
+			pinned = append(pinned, res)
+			continue
+		}
+		for _, page := range res.Pages {
+			page.Source = res.Name
+			pages = append(pages, page)
+		}
+	}
+	sort.SliceStable(pages, func(i, j int) bool {
+		a, b := pages[i].Published, pages[j].Published
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.Before(b)
+	})
+
+	multiDay := spansDays(pages, loc)
+	grouped := n
+	grouped.Resources = pinned
+	for _, page := range pages {
+		name := sectionName(page.Published, by, multiDay, loc)
+		if last := len(grouped.Resources) - 1; last >= 0 && grouped.Resources[last].Name == name && !grouped.Resources[last].Pinned {
+			grouped.Resources[last].Pages = append(grouped.Resources[last].Pages, page)
+			continue
+		}
+		grouped.Resources = append(grouped.Resources, Resource{Name: name, Pages: []Page{page}})
+	}
+	return grouped
+}
+
+// sectionName names the time window of t, the day is added to time of day
+// sections when the issue spans several days
+func sectionName(t time.Time, by config.GroupBy, multiDay bool, loc *time.Location) string {
+	if t.IsZero() {
+		return "Undated"
+	}
+	t = t.In(loc)
+	day := t.Format("Monday, January 2")
+	if by == config.GroupByDay {
+		return day
+	}
+
+	var window string
+	switch h := t.Hour(); {
+	case h < 5:
+		window = "Night"
+	case h < 12:
+		window = "Morning"
+	case h < 17:
+		window = "Afternoon"
+	default:
+		window = "Evening"
+	}
+	if multiDay {
+		return day + " · " + window
+	}
+	return window
+}
+
+func spansDays(pages []Page, loc *time.Location) bool {
+	var first string
+	for _, page := range pages {
+		if page.Published.IsZero() {
+			continue
+		}
+		day := page.Published.In(loc).Format(time.DateOnly)
+		if first == "" {
+			first = day
+		} else if day != first {
+			return true
+		}
+	}
+	return false
+}
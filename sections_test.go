@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// sections lists the sections of the issue with the titles and sources of
+// their pages, e.g., "Morning: A (Blog), B (News)"
+func sections(n Newsletter) []string {
+	var out []string
+	for _, res := range n.Resources {
+		var pages []string
+		for _, page := range res.Pages {
+			if page.Source != "" {
+				pages = append(pages, page.Title+" ("+page.Source+")")
+			} else {
+				pages = append(pages, page.Title)
+			}
+		}
+		out = append(out, res.Name+": "+strings.Join(pages, ", "))
+	}
+	return out
+}
+
+func TestGroupPages(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, time.March, day, hour, 30, 0, 0, loc)
+	}
+	issue := func(days ...int) Newsletter {
+		return Newsletter{Title: "Issue", Resources: []Resource{
+			{Name: "Pinned", Pinned: true, Pages: []Page{{Title: "Note", Published: at(days[0], 9)}}},
+			{Name: "Blog", Pages: []Page{
+				{Title: "Evening post", Published: at(days[len(days)-1], 20)},
+				{Title: "Undated post"},
+				{Title: "Morning post", Published: at(days[0], 8)},
+			}},
+			{Name: "News", Pages: []Page{
+				{Title: "Night news", Published: at(days[0], 2)},
+				{Title: "Undated news"},
+				{Title: "Noon news", Published: at(days[0], 12)},
+				{Title: "Early news", Published: at(days[0], 7)},
+			}},
+		}}
+	}
+
+	tests := []struct {
+		name  string
+		issue Newsletter
+		by    config.GroupBy
+		want  []string
+	}{
+		{
+			name:  "time of day",
+			issue: issue(10),
+			by:    config.GroupByTimeOfDay,
+			want: []string{
+				"Pinned: Note",
+				"Night: Night news (News)",
+				"Morning: Early news (News), Morning post (Blog)",
+				"Afternoon: Noon news (News)",
+				"Evening: Evening post (Blog)",
+				"Undated: Undated post (Blog), Undated news (News)",
+			},
+		},
+		{
+			name:  "time of day over several days",
+			issue: issue(10, 11),
+			by:    config.GroupByTimeOfDay,
+			want: []string{
+				"Pinned: Note",
+				"Tuesday, March 10 · Night: Night news (News)",
+				"Tuesday, March 10 · Morning: Early news (News), Morning post (Blog)",
+				"Tuesday, March 10 · Afternoon: Noon news (News)",
+				"Wednesday, March 11 · Evening: Evening post (Blog)",
+				"Undated: Undated post (Blog), Undated news (News)",
+			},
+		},
+		{
+			name:  "day",
+			issue: issue(10, 11),
+			by:    config.GroupByDay,
+			want: []string{
+				"Pinned: Note",
+				"Tuesday, March 10: Night news (News), Early news (News), Morning post (Blog), Noon news (News)",
+				"Wednesday, March 11: Evening post (Blog)",
+				"Undated: Undated post (Blog), Undated news (News)",
+			},
+		},
+		{
+			name:  "resource",
+			issue: issue(10),
+			by:    config.GroupByResource,
+			want: []string{
+				"Pinned: Note",
+				"Blog: Evening post, Undated post, Morning post",
+				"News: Night news, Undated news, Noon news, Early news",
+			},
+		},
+		{
+			name: "only undated pages",
+			issue: Newsletter{Resources: []Resource{
+				{Name: "Blog", Pages: []Page{{Title: "A"}, {Title: "B"}}},
+				{Name: "News", Pages: []Page{{Title: "C"}}},
+			}},
+			by:   config.GroupByTimeOfDay,
+			want: []string{"Undated: A (Blog), B (Blog), C (News)"},
+		},
+		{
+			name:  "empty issue",
+			issue: Newsletter{},
+			by:    config.GroupByDay,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grouped := groupPages(tt.issue, tt.by, loc)
+			if got := sections(grouped); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got sections\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if grouped.Title != tt.issue.Title {
+				t.Errorf("expected the title to be kept, got %q", grouped.Title)
+			}
+		})
+	}
+}
+
+func TestSectionName_Location(t *testing.T) {
+	// 23:30 UTC is already the next morning in UTC+8
+	published := time.Date(2026, time.March, 10, 23, 30, 0, 0, time.UTC)
+	if got := sectionName(published, config.GroupByTimeOfDay, false, time.UTC); got != "Evening" {
+		t.Errorf("expected Evening in UTC, got %q", got)
+	}
+	if got := sectionName(published, config.GroupByTimeOfDay, true, time.FixedZone("UTC+8", 8*60*60)); got != "Wednesday, March 11 · Morning" {
+		t.Errorf("expected the next morning in UTC+8, got %q", got)
+	}
+}
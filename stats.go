@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/agent/usage"
+)
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// IssueStats documents the production cost of an issue
+type IssueStats struct {
+	Sources      int
+	Items        int
+	Words        int
+	Filtered     int // Items dropped by filters
+	CacheHits    int // Items served from the parser or agent cache
+	CacheLookups int
+	Deferred     int // Items left for the next run because a provider kept failing
+	Merged       int // Items merged into an item of another source about the same story
+	Tokens       usage.Tokens
+	Duration     time.Duration
+}
+
+// CacheHitRate returns the share of cache hits in percent
+func (s IssueStats) CacheHitRate() float64 {
+	if s.CacheLookups == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(s.CacheLookups) * 100
+}
+
+// Elapsed returns the generation time rounded for display
+func (s IssueStats) Elapsed() string {
+	return s.Duration.Round(time.Second).String()
+}
+
+// String renders the stats as a single line
+func (s IssueStats) String() string {
+	deferred := ""
+	if s.Deferred > 0 {
+		deferred = fmt.Sprintf("%d deferred · ", s.Deferred)
+	}
+	merged := ""
+	if s.Merged > 0 {
+		merged = fmt.Sprintf("%d merged · ", s.Merged)
+	}
+	return fmt.Sprintf("%d sources · %d items · %d words · %d filtered · %s%s%.0f%% cache hits · %d tokens · %s",
+		s.Sources, s.Items, s.Words, s.Filtered, merged, deferred, s.CacheHitRate(), s.Tokens.Sum(), s.Elapsed())
+}
+
+// collectStats fills counts derived from the newsletter content
+func collectStats(n Newsletter, stats IssueStats, started time.Time) IssueStats {
+	stats = countContent(n, stats)
+	stats.Tokens = usage.Total()
+	stats.Duration = time.Since(started)
+	return stats
+}
+
+// countContent counts sources, items and words of the newsletter
+func countContent(n Newsletter, stats IssueStats) IssueStats {
+	stats.Sources = len(n.Resources)
+	stats.Items = n.pageCount()
+	stats.Words = 0
+	for _, res := range n.Resources {
+		for _, page := range res.Pages {
+			stats.Words += countWords(string(page.Content)) + countWords(string(page.Discussion))
+		}
+	}
+	return stats
+}
+
+// countWords counts words of rendered content ignoring markup
+func countWords(content string) int {
+	return len(strings.Fields(html.UnescapeString(htmlTagRe.ReplaceAllString(content, " "))))
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+	"github.com/scipunch/myfeed/urlnorm"
+)
+
+// Kinds of reading events
+const (
+	eventClick = "click"
+	eventStar  = "star"
+)
+
+// starWeight is how many clicks a starred item counts as
+const starWeight = 3
+
+// suggestion is a site read often without being subscribed to
+type suggestion struct {
+	domain  string
+	score   int
+	authors []string // Most read first
+	feed    string   // Discovered feed, empty if the site advertises none
+}
+
+// saveReadingEvent records a clicked link or a starred item
+func saveReadingEvent(ctx context.Context, queries *db.Queries, kind, link, item, author string) error {
+	return queries.SaveReadingEvent(ctx, db.SaveReadingEventParams{
+		Kind:      kind,
+		Url:       link,
+		ItemUrl:   item,
+		Author:    author,
+		CreatedAt: time.Now().Unix(),
+	})
+}
+
+// recordStars records the items starred while curating the issue
+func recordStars(ctx context.Context, queries *db.Queries, n Newsletter) {
+	for _, res := range n.Resources {
+		for _, page := range res.Pages {
+			if !page.Starred {
+				continue
+			}
+			if err := saveReadingEvent(ctx, queries, eventStar, page.Link, page.Link, page.Author); err != nil {
+				slog.Warn("failed to record starred item", "error", err, "url", page.Link)
+			}
+		}
+	}
+}
+
+// suggestSources ranks sites by the clicks and stars since, discovers the
+// feeds of the top limit ones and prints them
+func suggestSources(ctx context.Context, conf config.Config, queries *db.Queries, since time.Time, limit int) error {
+	events, err := queries.ListReadingEvents(ctx, since.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to load reading events with %w", err)
+	}
+	if len(events) == 0 {
+		fmt.Println("No clicks or stars recorded yet, enable track_clicks of the daemon or star items with `myfeed curate`")
+		return nil
+	}
+
+	subscribed := make(map[string]bool)
+	for _, r := range conf.Resources {
+		subscribed[linkDomain(r.FeedURL)] = true
+	}
+	suggestions := rankSources(events, subscribed)
+
+	rss := fetcher.NewRSSFetcher(nil)
+	opts := fetcher.FetchOptions{UserAgent: conf.UserAgent, Proxy: conf.Proxy}
+	var found []suggestion
+	for _, s := range suggestions {
+		if len(found) == limit {
+			break
+		}
+		feed, err := rss.DiscoverFeed(ctx, "https://"+s.domain+"/", opts)
+		if err != nil {
+			slog.Debug("no feed discovered", "domain", s.domain, "error", err)
+		}
+		// The site may publish its feed on a subscribed host, e.g., FeedBurner
+		if feed != "" && slices.ContainsFunc(conf.Resources, func(r config.ResourceConfig) bool { return r.FeedURL == feed }) {
+			continue
+		}
+		s.feed = feed
+		found = append(found, s)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCORE\tSITE\tFEED\tAUTHORS")
+	for _, s := range found {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.score, s.domain, cmp.Or(s.feed, "-"), strings.Join(s.authors, ", "))
+	}
+	return w.Flush()
+}
+
+// rankSources scores the sites of reading events which aren't subscribed,
+// best first
+func rankSources(events []db.ReadingEvent, subscribed map[string]bool) []suggestion {
+	scores := make(map[string]int)
+	authors := make(map[string]map[string]int)
+	for _, e := range events {
+		domain := linkDomain(e.Url)
+		if domain == "" || subscribed[domain] {
+			continue
+		}
+		weight := 1
+		if e.Kind == eventStar {
+			weight = starWeight
+		}
+		scores[domain] += weight
+		if e.Author != "" {
+			if authors[domain] == nil {
+				authors[domain] = make(map[string]int)
+			}
+			authors[domain][e.Author] += weight
+		}
+	}
+
+	suggestions := make([]suggestion, 0, len(scores))
+	for domain, score := range scores {
+		s := suggestion{domain: domain, score: score}
+		for author := range authors[domain] {
+			s.authors = append(s.authors, author)
+		}
+		slices.SortFunc(s.authors, func(a, b string) int {
+			return cmp.Or(authors[domain][b]-authors[domain][a], strings.Compare(a, b))
+		})
+		suggestions = append(suggestions, s)
+	}
+	slices.SortFunc(suggestions, func(a, b suggestion) int {
+		return cmp.Or(b.score-a.score, strings.Compare(a.domain, b.domain))
+	})
+	return suggestions
+}
+
+// linkDomain returns the host of a link without "www.", empty if it has none
+func linkDomain(link string) string {
+	return urlnorm.Host(link)
+}
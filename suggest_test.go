@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/scipunch/myfeed/db"
+)
+
+func TestRankSources(t *testing.T) {
+	events := []db.ReadingEvent{
+		{Kind: eventClick, Url: "https://www.blog.example/a", Author: "Ann"},
+		{Kind: eventClick, Url: "https://blog.example/b", Author: "Bob"},
+		{Kind: eventClick, Url: "https://blog.example/c", Author: "Bob"},
+		{Kind: eventStar, Url: "https://other.example/1", Author: "Eve"},
+		{Kind: eventStar, Url: "https://subscribed.example/1"},
+		{Kind: eventClick, Url: "not a link"},
+	}
+	got := rankSources(events, map[string]bool{"subscribed.example": true})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 suggestions, got %+v", got)
+	}
+	// A star counts as 3 clicks, ties are ordered by site
+	if got[0].domain != "blog.example" || got[0].score != 3 || got[1].domain != "other.example" || got[1].score != 3 {
+		t.Errorf("expected blog.example and other.example with 3 points each, got %+v", got)
+	}
+	if len(got[0].authors) != 2 || got[0].authors[0] != "Bob" {
+		t.Errorf("expected Bob read most on blog.example, got %v", got[0].authors)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// Built-in templates, the layouts of the issue and the email and the
+// partials of the issue they are made of
+const (
+	layoutTemplates  = "templates/*.html"
+	partialTemplates = "templates/partials/*.html"
+)
+
+// loadTemplates parses the built-in templates, then the templates in dir
+// whose definitions replace the partials of the same name, e.g., "item"
+func loadTemplates(dir string) (*template.Template, error) {
+	t, err := template.ParseGlob(layoutTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates with %w", err)
+	}
+	if t, err = t.ParseGlob(partialTemplates); err != nil {
+		return nil, fmt.Errorf("failed to parse template partials with %w", err)
+	}
+	if dir == "" {
+		return t, nil
+	}
+
+	overrides, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if len(overrides) == 0 {
+		slog.Warn("no templates to override partials with", "dir", dir)
+		return t, nil
+	}
+	if t, err = t.ParseFiles(overrides...); err != nil {
+		return nil, fmt.Errorf("failed to parse templates in '%s' with %w", dir, err)
+	}
+	slog.Info("overriding template partials", "dir", dir, "files", len(overrides))
+	return t, nil
+}
+
+// templatesDir returns the directory of template overrides of the config,
+// empty when there are none
+func templatesDir(cfgPath string, conf config.Config) string {
+	if conf.Templates == "" || filepath.IsAbs(conf.Templates) {
+		return conf.Templates
+	}
+	return filepath.Join(filepath.Dir(cfgPath), conf.Templates)
+}
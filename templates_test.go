@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+func TestLoadTemplates_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	item := `{{define "item"}}<section class="custom">{{.Title}}</section>{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "item.html"), []byte(item), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := loadTemplates(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	n := Newsletter{Title: "Issue", Resources: []Resource{{Name: "Blog", Pages: []Page{{Title: "Post", Link: "https://example.com/1", ID: "p1"}}}}}
+	var out strings.Builder
+	if err := tmpl.ExecuteTemplate(&out, "index", n); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	html := out.String()
+	if !strings.Contains(html, `<section class="custom">Post</section>`) || strings.Contains(html, `class="article-title"`) {
+		t.Error("expected the item partial to be replaced")
+	}
+	// Other partials stay the built-in ones
+	if !strings.Contains(html, `<a href="#p1">Post</a>`) || !strings.Contains(html, "<style>") {
+		t.Error("expected the built-in table of contents and styles")
+	}
+}
+
+func TestLoadTemplates_BadOverride(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "item.html"), []byte(`{{define "item"}}{{.Title}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTemplates(dir); err == nil || !strings.Contains(err.Error(), dir) {
+		t.Errorf("expected an error naming the directory, got %v", err)
+	}
+}
+
+func TestTemplatesDir(t *testing.T) {
+	cfgPath := filepath.Join("home", "myfeed", "config.toml")
+	for templates, want := range map[string]string{
+		"":               "",
+		"templates":      filepath.Join("home", "myfeed", "templates"),
+		"/srv/templates": "/srv/templates",
+	} {
+		if got := templatesDir(cfgPath, config.Config{Templates: templates}); got != want {
+			t.Errorf("templatesDir(%q) = %q, want %q", templates, got, want)
+		}
+	}
+}
+
+func TestTemplates_EscapeFeedFields(t *testing.T) {
+	tmpl, err := loadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := Newsletter{Title: "Issue", Resources: []Resource{{Name: `<b>Feed</b>`, Pages: []Page{{
+		Title:    `<script>alert(1)</script>`,
+		Author:   `" onmouseover="x()`,
+		Link:     "javascript:alert(1)",
+		Content:  "<p>Kept</p>",
+		ID:       "p1",
+		Coverage: []Coverage{{Source: "<i>Other</i>", Title: "<img src=x onerror=y()>", Link: "https://example.com/2"}},
+	}}}}}
+	var out strings.Builder
+	if err := tmpl.ExecuteTemplate(&out, "index", n); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	html := out.String()
+	for _, raw := range []string{"<script>alert(1)", `" onmouseover="x()`, "<b>Feed", "<i>Other", "<img src=x", `href="javascript:`} {
+		if strings.Contains(html, raw) {
+			t.Errorf("expected %q to be escaped", raw)
+		}
+	}
+	if !strings.Contains(html, "<p>Kept</p>") || !strings.Contains(html, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("expected content as is and the title escaped")
+	}
+}
+
+func TestTemplates_ClickTracking(t *testing.T) {
+	tmpl, err := loadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := Newsletter{Title: "Issue", Resources: []Resource{{Name: "Blog", Pages: []Page{{
+		Title:  "Post",
+		Link:   `https://example.com/?q="x" onclick="y()"`,
+		Author: `Jane "J" <Doe>`,
+		ID:     "p1",
+	}}}}}
+	var out strings.Builder
+	if err := tmpl.ExecuteTemplate(&out, "index", n); err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	html := out.String()
+	if !strings.Contains(html, `data-author="Jane &#34;J&#34; &lt;Doe&gt;"`) || strings.Contains(html, `onclick="y()"`) {
+		t.Error("expected quotes in data attributes to be escaped")
+	}
+	// Plain HTTP has no clipboard API, clicks are still reported
+	tracking, guard := strings.Index(html, "/api/clicks"), strings.Index(html, "if (!navigator.clipboard) return;")
+	if tracking < 0 || guard < 0 || tracking > guard {
+		t.Error("expected click tracking to be registered before the clipboard check")
+	}
+}
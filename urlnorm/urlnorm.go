@@ -0,0 +1,86 @@
+// Package urlnorm canonicalizes links, so the same page reached through
+// different URLs maps to one key in the cache, history, dedup and anchors
+package urlnorm
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingPrefixes start query parameters which do not change the linked content
+var trackingPrefixes = []string{"utm_", "ref_"}
+
+// trackingParams are query parameters which do not change the linked content
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"yclid":   true,
+	"msclkid": true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"mkt_tok": true,
+	"ref":     true,
+}
+
+// Canonical normalizes a link: the scheme becomes https, the host is
+// lowercased without "www." and default ports, the trailing slash and
+// tracking parameters are dropped and the rest of the query is sorted.
+// Fragments are dropped unless they are routes of a single-page app,
+// e.g., "#!/post/1". Anything but web links is returned trimmed.
+func Canonical(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return raw
+	}
+
+	if port := u.Port(); port == "80" || port == "443" {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Scheme = "https"
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.User = nil
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = strings.TrimSuffix(u.RawPath, "/")
+	if !strings.HasPrefix(u.Fragment, "!") && !strings.HasPrefix(u.Fragment, "/") {
+		u.Fragment, u.RawFragment = "", ""
+	}
+
+	query := u.Query()
+	for key := range query {
+		if isTracking(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+func isTracking(key string) bool {
+	key = strings.ToLower(key)
+	if trackingParams[key] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Host returns the host of a link in lower case without "www." and the
+// port, empty if it has none
+func Host(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/scipunch/myfeed/db"
+	"github.com/scipunch/myfeed/fetcher"
+)
+
+// validatorStore provides feed validators saved by previous runs
+type validatorStore struct {
+	queries *db.Queries
+}
+
+func (s validatorStore) GetValidators(ctx context.Context, url string) (fetcher.Validators, error) {
+	v, err := s.queries.GetFeedValidator(ctx, url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fetcher.Validators{}, nil
+	}
+	if err != nil {
+		return fetcher.Validators{}, err
+	}
+	return fetcher.Validators{ETag: v.Etag, LastModified: v.LastModified}, nil
+}
+
+// offsetStore provides feed offsets saved by previous runs
+type offsetStore struct {
+	queries *db.Queries
+}
+
+func (s offsetStore) GetOffset(ctx context.Context, url string) (int64, error) {
+	offset, err := s.queries.GetFeedOffset(ctx, url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return offset, err
+}
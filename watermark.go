@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/scipunch/myfeed/fetcher"
+)
+
+// itemKey identifies an item within its resource
+func itemKey(item fetcher.FeedItem) string {
+	if item.GUID != "" {
+		return item.GUID
+	}
+	return item.Link
+}
+
+// newestItem returns the item with the latest published date.
+// Feeds without dates are assumed to list the newest item first.
+func newestItem(items []fetcher.FeedItem) fetcher.FeedItem {
+	newest := items[0]
+	for _, item := range items[1:] {
+		if item.Published.After(newest.Published) {
+			newest = item
+		}
+	}
+	return newest
+}
+
+// markPosition returns the index of the high-water mark item in the feed, -1 if it is gone
+func markPosition(items []fetcher.FeedItem, guid string) int {
+	if guid == "" {
+		return -1
+	}
+	for i, item := range items {
+		if itemKey(item) == guid {
+			return i
+		}
+	}
+	return -1
+}
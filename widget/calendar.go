@@ -0,0 +1,372 @@
+package widget
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+const (
+	defaultCalendarCache = 15 * time.Minute
+
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+
+	// maxOccurrences bounds the occurrences of a recurring event looked at,
+	// e.g., a daily event is found for about 50 years
+	maxOccurrences = 20000
+)
+
+var (
+	icsUnescaper = strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n")
+
+	weekdays = map[string]time.Weekday{
+		"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+		"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+	}
+)
+
+// calendar lists the events of the day from an iCalendar (RFC 5545) feed
+type calendar struct {
+	cfg config.CalendarWidget
+	src loader
+}
+
+func (c calendar) Name() string { return "calendar" }
+
+func (c calendar) CacheKey(now time.Time) string {
+	return c.cfg.URL + " " + period(now, cmp.Or(c.cfg.Cache.Duration, defaultCalendarCache))
+}
+
+func (c calendar) Render(ctx context.Context, now time.Time) (Block, error) {
+	data, err := c.src.load(ctx, c.cfg.URL)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to load calendar with %w", err)
+	}
+	events, err := parseICS(data, now.Location())
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to parse calendar with %w", err)
+	}
+
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	to := from.AddDate(0, 0, 1)
+	var today []event
+	for _, e := range events {
+		if start, ok := e.on(from, to); ok {
+			e.end = start.Add(e.duration())
+			e.start = start
+			today = append(today, e)
+		}
+	}
+	slices.SortFunc(today, func(a, b event) int {
+		if a.allDay != b.allDay {
+			if a.allDay {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(a.start.Compare(b.start), strings.Compare(a.summary, b.summary))
+	})
+
+	var b strings.Builder
+	if len(today) == 0 {
+		b.WriteString("<p>No events</p>")
+	} else {
+		b.WriteString("<ul>")
+		for _, e := range today {
+			fmt.Fprintf(&b, `<li><span class="widget-detail">%s</span> %s`, e.times(from, to), html.EscapeString(e.summary))
+			if e.location != "" {
+				fmt.Fprintf(&b, `, <span class="widget-detail">%s</span>`, html.EscapeString(e.location))
+			}
+			b.WriteString("</li>")
+		}
+		b.WriteString("</ul>")
+	}
+	return Block{Title: cmp.Or(c.cfg.Title, "Today"), HTML: template.HTML(b.String())}, nil
+}
+
+// event is a VEVENT of the calendar
+type event struct {
+	uid        string
+	summary    string
+	location   string
+	start, end time.Time
+	allDay     bool
+	cancelled  bool
+	rule       *rrule         // Recurrence, nil for single events
+	exdates    map[int64]bool // Starts of occurrences left out, by Unix time
+	recurrence time.Time      // Start of the occurrence this event replaces, zero if none
+}
+
+// rrule is the supported part of a recurrence rule
+type rrule struct {
+	freq     string // DAILY, WEEKLY, MONTHLY or YEARLY
+	interval int
+	count    int       // Occurrences in total, 0 if unlimited
+	until    time.Time // Last possible start, zero if unlimited
+	byDay    []time.Weekday
+}
+
+// duration returns how long the event lasts, all-day events without an end take a day
+func (e event) duration() time.Duration {
+	if e.end.After(e.start) {
+		return e.end.Sub(e.start)
+	}
+	if e.allDay {
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// on returns the start of the occurrence overlapping [from, to), false if none does
+func (e event) on(from, to time.Time) (time.Time, bool) {
+	if e.cancelled || e.start.IsZero() {
+		return time.Time{}, false
+	}
+	d := e.duration()
+	overlaps := func(start time.Time) bool {
+		end := start.Add(d)
+		return start.Before(to) && (end.After(from) || d == 0 && !start.Before(from))
+	}
+	if e.rule == nil {
+		return e.start, overlaps(e.start)
+	}
+
+	n := 0
+	for i := 0; i < maxOccurrences; i++ {
+		for _, start := range e.rule.period(e.start, i) {
+			if start.Before(e.start) {
+				continue
+			}
+			n++
+			if !start.Before(to) || e.rule.count > 0 && n > e.rule.count ||
+				!e.rule.until.IsZero() && start.After(e.rule.until) {
+				return time.Time{}, false
+			}
+			if !e.exdates[start.Unix()] && overlaps(start) {
+				return start, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// period returns the starts of the i-th period of the rule, in order
+func (r rrule) period(start time.Time, i int) []time.Time {
+	n := i * r.interval
+	switch r.freq {
+	case "DAILY":
+		return []time.Time{start.AddDate(0, 0, n)}
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*n)}
+		}
+		// Weeks start on Monday
+		monday := start.AddDate(0, 0, -(int(start.Weekday())+6)%7+7*n)
+		starts := make([]time.Time, len(r.byDay))
+		for j, day := range r.byDay {
+			starts[j] = monday.AddDate(0, 0, (int(day)+6)%7)
+		}
+		return starts
+	case "MONTHLY", "YEARLY":
+		next := start.AddDate(0, n, 0)
+		if r.freq == "YEARLY" {
+			next = start.AddDate(n, 0, 0)
+		}
+		// Months without the day are skipped, e.g., the 31st
+		if next.Day() != start.Day() {
+			return nil
+		}
+		return []time.Time{next}
+	}
+	if i == 0 {
+		return []time.Time{start}
+	}
+	return nil
+}
+
+// times describes when the event takes place on the day [from, to)
+func (e event) times(from, to time.Time) string {
+	start, end := e.start.In(from.Location()), e.end.In(from.Location())
+	switch {
+	case e.allDay || !start.After(from) && !end.Before(to):
+		return "All day"
+	case start.Before(from):
+		return "Until " + end.Format("15:04")
+	case end.After(to):
+		return "From " + start.Format("15:04")
+	case end.After(start):
+		return start.Format("15:04") + "–" + end.Format("15:04")
+	}
+	return start.Format("15:04")
+}
+
+// parseICS reads the events of the calendar, floating times and dates are in loc
+func parseICS(data []byte, loc *time.Location) ([]event, error) {
+	var events []event
+	var e *event
+	nested := 0 // Depth of components inside the event, e.g., VALARM
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, maxSourceSize)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// Continuation lines start with a space or a tab
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		prop, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, rawParams, _ := strings.Cut(prop, ";")
+		name = strings.ToUpper(name)
+		params := make(map[string]string)
+		for _, p := range strings.Split(rawParams, ";") {
+			if k, v, ok := strings.Cut(p, "="); ok {
+				params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+			}
+		}
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			e, nested = &event{exdates: make(map[int64]bool)}, 0
+			continue
+		case e == nil:
+			continue
+		case name == "BEGIN":
+			nested++
+			continue
+		case name == "END" && nested > 0:
+			nested--
+			continue
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			events = append(events, *e)
+			e = nil
+			continue
+		case nested > 0:
+			continue
+		}
+
+		var err error
+		switch name {
+		case "UID":
+			e.uid = value
+		case "SUMMARY":
+			e.summary = icsUnescaper.Replace(value)
+		case "LOCATION":
+			e.location = icsUnescaper.Replace(value)
+		case "STATUS":
+			e.cancelled = strings.EqualFold(value, "CANCELLED")
+		case "DTSTART":
+			e.start, e.allDay, err = parseICSTime(value, params, loc)
+		case "DTEND":
+			e.end, _, err = parseICSTime(value, params, loc)
+		case "RECURRENCE-ID":
+			e.recurrence, _, err = parseICSTime(value, params, loc)
+		case "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				var t time.Time
+				if t, _, err = parseICSTime(v, params, loc); err != nil {
+					break
+				}
+				e.exdates[t.Unix()] = true
+			}
+		case "RRULE":
+			e.rule, err = parseRRule(value, params, loc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s '%s' with %w", name, value, err)
+		}
+	}
+
+	// Occurrences replaced by an event of their own are left out of the series
+	masters := make(map[string]*event)
+	for i := range events {
+		if events[i].rule != nil && events[i].uid != "" {
+			masters[events[i].uid] = &events[i]
+		}
+	}
+	for _, e := range events {
+		if master, ok := masters[e.uid]; ok && !e.recurrence.IsZero() {
+			master.exdates[e.recurrence.Unix()] = true
+		}
+	}
+	return events, nil
+}
+
+// parseICSTime parses a DATE or DATE-TIME value, which is in UTC with a "Z"
+// suffix, in the zone of TZID or otherwise in loc
+func parseICSTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if len(value) == len(icsDate) {
+		t, err := time.ParseInLocation(icsDate, value, loc)
+		return t, true, err
+	}
+	if v, ok := strings.CutSuffix(value, "Z"); ok {
+		t, err := time.ParseInLocation(icsDateTime, v, time.UTC)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	t, err := time.ParseInLocation(icsDateTime, value, loc)
+	return t, false, err
+}
+
+// parseRRule parses the frequency, interval, count, end and weekdays of a
+// rule, other parts are ignored
+func parseRRule(value string, params map[string]string, loc *time.Location) (*rrule, error) {
+	r := &rrule{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		k, v, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.freq = strings.ToUpper(v)
+		case "INTERVAL":
+			if r.interval, err = strconv.Atoi(v); err == nil && r.interval < 1 {
+				err = fmt.Errorf("interval must be positive")
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(v)
+		case "UNTIL":
+			r.until, _, err = parseICSTime(v, params, loc)
+			if err == nil && len(v) == len(icsDate) {
+				r.until = r.until.AddDate(0, 0, 1).Add(-time.Second) // The whole last day
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				// Ordinals of monthly rules, e.g., "1MO", are not supported
+				if wd, ok := weekdays[strings.ToUpper(day)]; ok {
+					r.byDay = append(r.byDay, wd)
+				}
+			}
+			slices.SortFunc(r.byDay, func(a, b time.Weekday) int { return (int(a)+6)%7 - (int(b)+6)%7 })
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
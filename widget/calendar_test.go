@@ -0,0 +1,117 @@
+package widget
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20250505T093000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20250505T094500\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR\r\n" +
+	"EXDATE;TZID=Europe/Berlin:20250602T093000\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"SUMMARY:Not an event\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Standup\\, moved\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20250604T093000\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20250604T140000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20250604T141500\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Conference\r\n" +
+	"LOCATION:Hall 1\\, Berlin\r\n" +
+	"DTSTART;VALUE=DATE:20250603\r\n" +
+	"DTEND;VALUE=DATE:20250605\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Birthday <3\r\n" +
+	"DTSTART;VALUE=DATE:20200604\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Late call\r\n" +
+	"DTSTART:20250603T210000Z\r\n" +
+	"DTEND:20250603T230000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Cancelled\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART;VALUE=DATE:20250604\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Sprint review\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20250409T100000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20250409T110000\r\n" +
+	"RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=3\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalendar(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "me.ics"), []byte(testCalendar), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := calendar{cfg: config.CalendarWidget{URL: "me.ics"}, src: loader{dir: dir}}
+
+	tests := []struct {
+		name string
+		day  time.Time
+		want string
+	}{
+		{
+			name: "recurring event replaced by another one",
+			day:  time.Date(2025, 6, 4, 7, 0, 0, 0, berlin),
+			want: `<ul><li><span class="widget-detail">All day</span> Conference, <span class="widget-detail">Hall 1, Berlin</span></li>` +
+				`<li><span class="widget-detail">All day</span> Birthday &lt;3</li>` +
+				`<li><span class="widget-detail">Until 01:00</span> Late call</li>` +
+				`<li><span class="widget-detail">14:00–14:15</span> Standup, moved</li></ul>`,
+		},
+		{
+			name: "excluded occurrence",
+			day:  time.Date(2025, 6, 2, 7, 0, 0, 0, berlin),
+			want: "<p>No events</p>",
+		},
+		{
+			name: "weekly occurrence",
+			day:  time.Date(2025, 6, 6, 7, 0, 0, 0, berlin),
+			want: `<ul><li><span class="widget-detail">09:30–09:45</span> Standup</li></ul>`,
+		},
+		{
+			name: "every other week",
+			day:  time.Date(2025, 5, 7, 7, 0, 0, 0, berlin),
+			want: `<ul><li><span class="widget-detail">09:30–09:45</span> Standup</li><li><span class="widget-detail">10:00–11:00</span> Sprint review</li></ul>`,
+		},
+		{
+			name: "after the last occurrence",
+			day:  time.Date(2025, 5, 21, 7, 0, 0, 0, berlin),
+			want: `<ul><li><span class="widget-detail">09:30–09:45</span> Standup</li></ul>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := c.Render(context.Background(), tt.day)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if block.Title != "Today" || string(block.HTML) != tt.want {
+				t.Errorf("got %q\n%s\nwant\n%s", block.Title, block.HTML, tt.want)
+			}
+		})
+	}
+}
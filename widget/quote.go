@@ -0,0 +1,80 @@
+package widget
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// quote shows a quote of a collection, a different one every day
+type quote struct {
+	cfg config.QuoteWidget
+	src loader
+}
+
+func (q quote) Name() string { return "quote" }
+
+func (q quote) CacheKey(now time.Time) string {
+	return q.cfg.Source + " " + now.Format(time.DateOnly)
+}
+
+func (q quote) Render(ctx context.Context, now time.Time) (Block, error) {
+	data, err := q.src.load(ctx, q.cfg.Source)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to load quotes with %w", err)
+	}
+	quotes := splitQuotes(string(data))
+	if len(quotes) == 0 {
+		return Block{}, fmt.Errorf("no quotes in '%s'", q.cfg.Source)
+	}
+
+	// Days since the epoch walk through the collection in order
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+	lines := quotes[int(day%int64(len(quotes)))]
+
+	var author string
+	if last := lines[len(lines)-1]; len(lines) > 1 && (strings.HasPrefix(last, "—") || strings.HasPrefix(last, "--")) {
+		author = strings.TrimSpace(strings.TrimLeft(last, "—-"))
+		lines = lines[:len(lines)-1]
+	}
+	var b strings.Builder
+	b.WriteString("<blockquote><p>")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("<br>")
+		}
+		b.WriteString(html.EscapeString(line))
+	}
+	b.WriteString("</p>")
+	if author != "" {
+		fmt.Fprintf(&b, `<p class="widget-detail">— %s</p>`, html.EscapeString(author))
+	}
+	b.WriteString("</blockquote>")
+	return Block{Title: cmp.Or(q.cfg.Title, "Quote of the day"), HTML: template.HTML(b.String())}, nil
+}
+
+// splitQuotes returns the lines of the quotes, which are separated by blank lines
+func splitQuotes(text string) [][]string {
+	var quotes [][]string
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+			continue
+		}
+		if len(lines) > 0 {
+			quotes = append(quotes, lines)
+			lines = nil
+		}
+	}
+	if len(lines) > 0 {
+		quotes = append(quotes, lines)
+	}
+	return quotes
+}
@@ -0,0 +1,142 @@
+package widget
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+const (
+	openMeteoURL        = "https://api.open-meteo.com/v1/forecast"
+	defaultWeatherCache = time.Hour
+)
+
+// weatherCodes describes WMO weather interpretation codes used by Open-Meteo
+var weatherCodes = map[int]string{
+	0: "clear sky", 1: "mainly clear", 2: "partly cloudy", 3: "overcast",
+	45: "fog", 48: "rime fog",
+	51: "light drizzle", 53: "drizzle", 55: "dense drizzle", 56: "freezing drizzle", 57: "dense freezing drizzle",
+	61: "light rain", 63: "rain", 65: "heavy rain", 66: "freezing rain", 67: "heavy freezing rain",
+	71: "light snow", 73: "snow", 75: "heavy snow", 77: "snow grains",
+	80: "light showers", 81: "showers", 82: "violent showers", 85: "snow showers", 86: "heavy snow showers",
+	95: "thunderstorm", 96: "thunderstorm with hail", 99: "thunderstorm with heavy hail",
+}
+
+// weather shows the forecast of Open-Meteo, which needs no API key
+type weather struct {
+	cfg      config.WeatherWidget
+	src      loader
+	endpoint string
+}
+
+func newWeather(cfg config.WeatherWidget, src loader) (weather, error) {
+	if cfg.Latitude == 0 && cfg.Longitude == 0 {
+		return weather{}, fmt.Errorf("latitude and longitude are required")
+	}
+	if math.Abs(cfg.Latitude) > 90 || math.Abs(cfg.Longitude) > 180 {
+		return weather{}, fmt.Errorf("invalid location %g, %g", cfg.Latitude, cfg.Longitude)
+	}
+	switch cfg.Units {
+	case "", "metric", "imperial":
+	default:
+		return weather{}, fmt.Errorf("invalid units '%s', expected 'metric' or 'imperial'", cfg.Units)
+	}
+	return weather{cfg: cfg, src: src, endpoint: openMeteoURL}, nil
+}
+
+func (w weather) Name() string { return "weather" }
+
+func (w weather) CacheKey(now time.Time) string {
+	ttl := cmp.Or(w.cfg.Cache.Duration, defaultWeatherCache)
+	return fmt.Sprintf("%g,%g,%s %s", w.cfg.Latitude, w.cfg.Longitude, w.cfg.Units, period(now, ttl))
+}
+
+// forecast is the part of the Open-Meteo response the widget shows
+type forecast struct {
+	CurrentUnits struct {
+		Temperature string `json:"temperature_2m"`
+	} `json:"current_units"`
+	Current struct {
+		Temperature float64 `json:"temperature_2m"`
+		Code        int     `json:"weather_code"`
+	} `json:"current"`
+	Daily struct {
+		Code          []int     `json:"weather_code"`
+		Max           []float64 `json:"temperature_2m_max"`
+		Min           []float64 `json:"temperature_2m_min"`
+		Precipitation []float64 `json:"precipitation_probability_max"`
+		Sunrise       []string  `json:"sunrise"`
+		Sunset        []string  `json:"sunset"`
+	} `json:"daily"`
+}
+
+func (w weather) Render(ctx context.Context, now time.Time) (Block, error) {
+	query := url.Values{
+		"latitude":      {strconv.FormatFloat(w.cfg.Latitude, 'f', -1, 64)},
+		"longitude":     {strconv.FormatFloat(w.cfg.Longitude, 'f', -1, 64)},
+		"current":       {"temperature_2m,weather_code"},
+		"daily":         {"weather_code,temperature_2m_max,temperature_2m_min,precipitation_probability_max,sunrise,sunset"},
+		"timezone":      {"auto"},
+		"forecast_days": {"1"},
+	}
+	if w.cfg.Units == "imperial" {
+		query.Set("temperature_unit", "fahrenheit")
+	}
+	data, err := w.src.load(ctx, w.endpoint+"?"+query.Encode())
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to request forecast with %w", err)
+	}
+	var f forecast
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Block{}, fmt.Errorf("failed to decode forecast with %w", err)
+	}
+	if len(f.Daily.Max) == 0 || len(f.Daily.Min) == 0 || len(f.Daily.Code) == 0 {
+		return Block{}, fmt.Errorf("forecast has no daily values")
+	}
+
+	unit := html.EscapeString(cmp.Or(f.CurrentUnits.Temperature, "°C"))
+	var b strings.Builder
+	fmt.Fprintf(&b, "<p>Now %.0f%s, %s. Today %s, %.0f–%.0f%s",
+		f.Current.Temperature, unit, describe(f.Current.Code),
+		describe(f.Daily.Code[0]), f.Daily.Min[0], f.Daily.Max[0], unit)
+	if len(f.Daily.Precipitation) > 0 && f.Daily.Precipitation[0] > 0 {
+		fmt.Fprintf(&b, ", %.0f%% chance of precipitation", f.Daily.Precipitation[0])
+	}
+	b.WriteString(".</p>")
+	if len(f.Daily.Sunrise) > 0 && len(f.Daily.Sunset) > 0 {
+		fmt.Fprintf(&b, `<p class="widget-detail">Sunrise %s, sunset %s</p>`,
+			html.EscapeString(clock(f.Daily.Sunrise[0])), html.EscapeString(clock(f.Daily.Sunset[0])))
+	}
+
+	title := "Weather"
+	if w.cfg.Location != "" {
+		title += " in " + w.cfg.Location
+	}
+	return Block{Title: title, HTML: template.HTML(b.String())}, nil
+}
+
+// describe names a weather code
+func describe(code int) string {
+	if d, ok := weatherCodes[code]; ok {
+		return d
+	}
+	return "unknown weather"
+}
+
+// clock returns the time of day of an ISO 8601 local time, e.g., "2025-06-01T04:45"
+func clock(t string) string {
+	if _, tod, ok := strings.Cut(t, "T"); ok {
+		return tod
+	}
+	return t
+}
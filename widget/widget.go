@@ -0,0 +1,164 @@
+// Package widget renders blocks shown above the items of the issue which
+// don't come from feeds, e.g., today's weather or calendar events.
+package widget
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+const (
+	// renderTimeout bounds a single widget so a slow API can't hold back the issue
+	renderTimeout = 30 * time.Second
+
+	// maxSourceSize caps files and responses widgets read
+	maxSourceSize = 5 * 1024 * 1024
+)
+
+// Widget renders a block of the issue
+type Widget interface {
+	// Name identifies the widget in logs and cache entries, e.g., "weather"
+	Name() string
+	// CacheKey identifies the block for the time, blocks with the same key are reused
+	CacheKey(now time.Time) string
+	// Render builds the block for the day of now
+	Render(ctx context.Context, now time.Time) (Block, error)
+}
+
+// Block is the rendered content of a widget
+type Block struct {
+	Name  string        // Name of the widget, used for the CSS class of the block
+	Title string        // Title as plain text, escaped by the templates
+	HTML  template.HTML // Content with everything from outside escaped
+}
+
+// Cache keeps rendered blocks, implemented by the parser cache
+type Cache interface {
+	GetParserOutput(url, parserType string) ([]byte, bool, error)
+	SetParserOutput(url, parserType string, output []byte) error
+}
+
+// New builds the configured widgets in the order they are shown. Files are
+// resolved against dir, URLs are requested with the client.
+func New(cfg config.Widgets, client *http.Client, userAgent, dir string) ([]Widget, error) {
+	src := loader{client: client, userAgent: userAgent, dir: dir}
+	var widgets []Widget
+	if cfg.Weather != nil {
+		w, err := newWeather(*cfg.Weather, src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weather widget: %w", err)
+		}
+		widgets = append(widgets, w)
+	}
+	if cfg.Calendar != nil {
+		if cfg.Calendar.URL == "" {
+			return nil, fmt.Errorf("invalid calendar widget: url is required")
+		}
+		widgets = append(widgets, calendar{cfg: *cfg.Calendar, src: src})
+	}
+	if cfg.Quote != nil {
+		if cfg.Quote.Source == "" {
+			return nil, fmt.Errorf("invalid quote widget: source is required")
+		}
+		widgets = append(widgets, quote{cfg: *cfg.Quote, src: src})
+	}
+	return widgets, nil
+}
+
+// Render renders the widgets for now, reusing cached blocks. Failing widgets
+// are logged and left out, they never hold back the issue.
+func Render(ctx context.Context, widgets []Widget, c Cache, now time.Time) []Block {
+	var blocks []Block
+	for _, w := range widgets {
+		block, err := render(ctx, w, c, now)
+		if err != nil {
+			slog.Warn("failed to render widget", "widget", w.Name(), "error", err)
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func render(ctx context.Context, w Widget, c Cache, now time.Time) (Block, error) {
+	kind, key := "widget:"+w.Name(), w.CacheKey(now)
+	if data, ok, _ := c.GetParserOutput(key, kind); ok {
+		var block Block
+		if err := json.Unmarshal(data, &block); err == nil {
+			return block, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, renderTimeout)
+	defer cancel()
+	block, err := w.Render(ctx, now)
+	if err != nil {
+		return Block{}, err
+	}
+	block.Name = w.Name()
+	if data, err := json.Marshal(block); err == nil {
+		_ = c.SetParserOutput(key, kind, data)
+	}
+	return block, nil
+}
+
+// period identifies the cache period of now: the day, split into periods of
+// ttl, so blocks are never reused on the next day
+func period(now time.Time, ttl time.Duration) string {
+	return now.Format(time.DateOnly) + "/" + strconv.FormatInt(now.Truncate(ttl).Unix(), 10)
+}
+
+// loader reads the files and URLs widgets take their data from
+type loader struct {
+	client    *http.Client
+	userAgent string
+	dir       string
+}
+
+// load reads an http(s) URL or a file, relative files are resolved against dir
+func (l loader) load(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(l.dir, source)
+		}
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxSourceSize))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	if l.userAgent != "" {
+		req.Header.Set("User-Agent", l.userAgent)
+	}
+	client := l.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSourceSize))
+}
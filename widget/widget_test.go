@@ -0,0 +1,151 @@
+package widget
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scipunch/myfeed/config"
+)
+
+// memoryCache keeps blocks in memory
+type memoryCache map[string][]byte
+
+func (c memoryCache) GetParserOutput(url, parserType string) ([]byte, bool, error) {
+	data, ok := c[parserType+" "+url]
+	return data, ok, nil
+}
+
+func (c memoryCache) SetParserOutput(url, parserType string, output []byte) error {
+	c[parserType+" "+url] = output
+	return nil
+}
+
+// counter renders a block with the number of renders so far
+type counter struct {
+	name    string
+	renders *int
+	err     error
+}
+
+func (c counter) Name() string                  { return c.name }
+func (c counter) CacheKey(now time.Time) string { return period(now, time.Hour) }
+func (c counter) Render(context.Context, time.Time) (Block, error) {
+	*c.renders++
+	return Block{Title: "Counter", HTML: template.HTML(strings.Repeat("x", *c.renders))}, c.err
+}
+
+func TestRender(t *testing.T) {
+	renders := 0
+	widgets := []Widget{counter{name: "counter", renders: &renders}, counter{name: "broken", renders: new(int), err: errors.New("offline")}}
+	cache := make(memoryCache)
+	morning := time.Date(2025, 6, 1, 9, 10, 0, 0, time.UTC)
+
+	blocks := Render(context.Background(), widgets, cache, morning)
+	if len(blocks) != 1 || blocks[0].Name != "counter" || blocks[0].HTML != "x" {
+		t.Fatalf("expected only the working widget, got %+v", blocks)
+	}
+	// Blocks are reused within the cache period only
+	Render(context.Background(), widgets[:1], cache, morning.Add(30*time.Minute))
+	if renders != 1 {
+		t.Errorf("expected the cached block to be reused, got %d renders", renders)
+	}
+	Render(context.Background(), widgets[:1], cache, morning.Add(time.Hour))
+	if renders != 2 {
+		t.Errorf("expected the block to be rendered again in the next period, got %d renders", renders)
+	}
+}
+
+func TestNew(t *testing.T) {
+	widgets, err := New(config.Widgets{
+		Weather: &config.WeatherWidget{Latitude: 52.52, Longitude: 13.41},
+		Quote:   &config.QuoteWidget{Source: "quotes.txt"},
+	}, nil, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(widgets) != 2 || widgets[0].Name() != "weather" || widgets[1].Name() != "quote" {
+		t.Errorf("expected weather and quote widgets, got %v", widgets)
+	}
+
+	for name, cfg := range map[string]config.Widgets{
+		"no location":   {Weather: &config.WeatherWidget{}},
+		"bad units":     {Weather: &config.WeatherWidget{Latitude: 1, Longitude: 1, Units: "kelvin"}},
+		"no calendar":   {Calendar: &config.CalendarWidget{}},
+		"no quote file": {Quote: &config.QuoteWidget{}},
+	} {
+		if _, err := New(cfg, nil, "", ""); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWeather(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(`{
+			"current_units": {"temperature_2m": "°F"},
+			"current": {"temperature_2m": 57.2, "weather_code": 3},
+			"daily": {
+				"weather_code": [61], "temperature_2m_max": [63.9], "temperature_2m_min": [48.1],
+				"precipitation_probability_max": [80], "sunrise": ["2025-06-01T04:45"], "sunset": ["2025-06-01T21:20"]
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	w, err := newWeather(config.WeatherWidget{Latitude: 52.52, Longitude: 13.41, Location: "Berlin", Units: "imperial"}, loader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.endpoint = srv.URL
+	block, err := w.Render(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(query, "latitude=52.52") || !strings.Contains(query, "temperature_unit=fahrenheit") {
+		t.Errorf("unexpected query %s", query)
+	}
+	if block.Title != "Weather in Berlin" {
+		t.Errorf("unexpected title %q", block.Title)
+	}
+	want := "<p>Now 57°F, overcast. Today light rain, 48–64°F, 80% chance of precipitation.</p>" +
+		`<p class="widget-detail">Sunrise 04:45, sunset 21:20</p>`
+	if string(block.HTML) != want {
+		t.Errorf("got\n%s\nwant\n%s", block.HTML, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	dir := t.TempDir()
+	quotes := "Simple is better\nthan complex.\n— The Zen of <Python>\n\n\nNo author here\n"
+	if err := os.WriteFile(filepath.Join(dir, "quotes.txt"), []byte(quotes), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	q := quote{cfg: config.QuoteWidget{Source: "quotes.txt"}, src: loader{dir: dir}}
+
+	// 2025-06-01 is day 20240 since the epoch, the first of the two quotes
+	day := time.Date(2025, 6, 1, 7, 0, 0, 0, time.Local)
+	block, err := q.Render(context.Background(), day)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `<blockquote><p>Simple is better<br>than complex.</p><p class="widget-detail">— The Zen of &lt;Python&gt;</p></blockquote>`
+	if string(block.HTML) != want || block.Title != "Quote of the day" {
+		t.Errorf("got %q %q", block.Title, block.HTML)
+	}
+	if block, _ = q.Render(context.Background(), day.AddDate(0, 0, 1)); block.HTML != "<blockquote><p>No author here</p></blockquote>" {
+		t.Errorf("expected the next quote on the next day, got %s", block.HTML)
+	}
+	if q.CacheKey(day) == q.CacheKey(day.AddDate(0, 0, 1)) {
+		t.Error("expected a quote per day")
+	}
+}